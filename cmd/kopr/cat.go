@@ -0,0 +1,91 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// runCat implements `kopr cat [--html] [--inline-embeds] [-o file] <note>`:
+// it writes a note to stdout or a file, for piping into mail, chat or pandoc.
+func runCat(vaultPath string, args []string) error {
+	fs := flag.NewFlagSet("cat", flag.ContinueOnError)
+	html := fs.Bool("html", false, "render to HTML instead of printing the raw markdown")
+	inline := fs.Bool("inline-embeds", false, "replace ![[note]] embeds with the embedded notes, recursively")
+	output := fs.String("o", "", "write to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kopr cat [--html] [--inline-embeds] [-o file] <note>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one note, got %d", fs.NArg())
+	}
+
+	v := vault.New(vaultPath)
+	path, err := resolveNote(v, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if *inline {
+		content = markdown.InlineEmbeds(path, content, markdown.MaxEmbedDepth, func(target string) (string, []byte, bool) {
+			embedded, err := resolveNote(v, target)
+			if err != nil {
+				return "", nil, false
+			}
+			data, err := os.ReadFile(embedded)
+			return embedded, data, err == nil
+		})
+	}
+	if *html {
+		if content, err = markdown.RenderHTML(content, markdown.CodeColors{}); err != nil {
+			return fmt.Errorf("render %s: %w", path, err)
+		}
+	}
+
+	if *output == "" {
+		_, err = os.Stdout.Write(content)
+		return err
+	}
+	return os.WriteFile(*output, content, 0644)
+}
+
+// resolveNote finds a note by vault-relative path or by basename (with or
+// without .md, case-insensitive) and returns its absolute path.
+func resolveNote(v *vault.Vault, name string) (string, error) {
+	rel := name
+	if !strings.HasSuffix(rel, ".md") {
+		rel += ".md"
+	}
+	if abs := filepath.Join(v.Root, rel); fileExists(abs) {
+		return abs, nil
+	}
+
+	notes, err := v.ListNotes()
+	if err != nil {
+		return "", err
+	}
+	for _, n := range notes {
+		if strings.EqualFold(n.Name, filepath.Base(rel)) {
+			return filepath.Join(v.Root, n.Path), nil
+		}
+	}
+	return "", fmt.Errorf("note %q not found in %s", name, v.Root)
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+
+	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/index"
+)
+
+// runDoctor implements `kopr doctor [--check]`: it compares the index with
+// the vault, reports any drift and repairs it. An index SQLite reports as
+// corrupt, or cannot open at all, is deleted and rebuilt.
+func runDoctor(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	check := flags.Bool("check", false, "only report drift, exiting non-zero if there is any")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: kopr doctor [--check]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	if info, err := os.Stat(cfg.VaultPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("vault %s is not a directory", cfg.VaultPath)
+	}
+	dbPath := indexPath(cfg)
+	if _, err := os.Stat(dbPath); errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("no index at %s; run kopr index to build one", dbPath)
+	}
+
+	// Only read here: applying the configured tokenizer or scope could
+	// rewrite the index before it has been checked.
+	var drift index.Drift
+	var idx *index.Indexer
+	db, err := index.Open(dbPath)
+	if err != nil {
+		drift.Corrupt = []string{err.Error()}
+	} else {
+		defer db.Close() //nolint:errcheck // closed early before a rebuild
+		if idx, err = newIndexer(cfg, db); err != nil {
+			return err
+		}
+		if drift, err = idx.Check(); err != nil {
+			return err
+		}
+	}
+
+	printDrift(drift)
+	if drift.Clean() {
+		fmt.Println("index is healthy")
+		return nil
+	}
+	if *check {
+		return errors.New("index has drifted from the vault; run kopr doctor to repair it")
+	}
+
+	if len(drift.Corrupt) == 0 {
+		if err := idx.Repair(drift); err != nil {
+			return fmt.Errorf("repair: %w", err)
+		}
+		fmt.Println("repaired index")
+		return nil
+	}
+
+	if db != nil {
+		if err := db.Close(); err != nil {
+			return err
+		}
+	}
+	for _, p := range []string{dbPath, dbPath + "-wal", dbPath + "-shm"} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	fresh, idx, _, err := openIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer fresh.Close() //nolint:errcheck // nothing left to flush
+	stats, err := idx.Rebuild(nil)
+	if err != nil {
+		return fmt.Errorf("rebuild: %w", err)
+	}
+	fmt.Printf("rebuilt index: %d files, %d links\n", stats.Files, stats.Links)
+	return nil
+}
+
+func printDrift(d index.Drift) {
+	for _, msg := range d.Corrupt {
+		fmt.Println("corrupt:", msg)
+	}
+	for _, p := range d.Unindexed {
+		fmt.Println("not indexed:", p)
+	}
+	for _, p := range d.Stale {
+		fmt.Println("out of date:", p)
+	}
+	for _, p := range d.Missing {
+		fmt.Println("file missing:", p)
+	}
+	for _, p := range d.MissingFTS {
+		fmt.Println("not searchable:", p)
+	}
+	if d.OrphanFTS > 0 {
+		fmt.Printf("orphaned search rows: %d\n", d.OrphanFTS)
+	}
+}
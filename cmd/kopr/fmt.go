@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pfassina/kopr/internal/markdown"
+)
+
+// runFmt implements `kopr fmt [--check] [path ...]`: it formats notes in
+// place with the formatter format-on-save uses, walking directories for .md
+// files and skipping hidden ones such as .git. With --check it only lists
+// the files that need formatting and fails if there are any, for pre-commit
+// hooks. Without paths it formats standard input to standard output.
+func runFmt(args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	check := flags.Bool("check", false, "list files that need formatting instead of rewriting them, exiting non-zero if there are any")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: kopr fmt [--check] [path ...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() == 0 {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return err
+		}
+		formatted := markdown.Format(content)
+		if *check {
+			if !bytes.Equal(formatted, content) {
+				return errors.New("standard input needs formatting")
+			}
+			return nil
+		}
+		_, err = os.Stdout.Write(formatted)
+		return err
+	}
+
+	unformatted := 0
+	for _, root := range flags.Args() {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			// Paths given by name are formatted whatever their extension.
+			if path != root {
+				if d.IsDir() && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				if !strings.EqualFold(filepath.Ext(path), ".md") {
+					return nil
+				}
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			changed, err := formatFile(path, !*check)
+			if err != nil {
+				return err
+			}
+			if changed {
+				unformatted++
+				if *check {
+					fmt.Println(path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	if *check && unformatted > 0 {
+		return fmt.Errorf("%d files need formatting", unformatted)
+	}
+	return nil
+}
+
+// formatFile formats the note at path, rewriting it if write is set, and
+// reports whether formatting changes it.
+func formatFile(path string, write bool) (bool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	formatted := markdown.Format(content)
+	if bytes.Equal(formatted, content) {
+		return false, nil
+	}
+	if write {
+		if err := os.WriteFile(path, formatted, info.Mode().Perm()); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pfassina/kopr/internal/config"
+)
+
+// runGraph implements `kopr graph [-format dot|json] [-o file]`: it writes
+// the vault's note link graph for Graphviz or other tools, to stdout unless
+// a file is given.
+func runGraph(cfg config.Config, args []string) (err error) {
+	fs := flag.NewFlagSet("graph", flag.ContinueOnError)
+	format := fs.String("format", "dot", "output format: dot|json")
+	out := fs.String("o", "", "write to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kopr graph [-format dot|json] [-o file]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+	if *format != "dot" && *format != "json" {
+		return fmt.Errorf("unknown format %q (want dot or json)", *format)
+	}
+
+	if info, err := os.Stat(cfg.VaultPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("vault %s is not a directory", cfg.VaultPath)
+	}
+	db, idx, rebuild, err := openIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close() //nolint:errcheck // nothing left to flush
+
+	update := idx.Update
+	if rebuild {
+		update = idx.Rebuild
+	}
+	if _, err := update(nil); err != nil {
+		return err
+	}
+	g, err := db.LinkGraph()
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+		}()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(g)
+	}
+	return g.WriteDOT(w)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/index"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// runIndex implements `kopr index [--full] [--optimize]`: it brings the
+// vault's index up to date without starting the TUI, e.g. from cron after a
+// sync, and prints what it did. `kopr index stats` shows how much space the
+// index takes and `kopr index compact` compacts it, both without indexing.
+func runIndex(cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("index", flag.ContinueOnError)
+	full := fs.Bool("full", false, "rebuild the whole index instead of only changed notes")
+	optimize := fs.Bool("optimize", false, "then compact the index: merge search segments and vacuum")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kopr index [--full] [--optimize] | kopr index stats | kopr index compact")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	sub := fs.Arg(0)
+	if (sub == "stats" || sub == "compact") && fs.NArg() == 1 {
+		if *full || *optimize {
+			fs.Usage()
+			return fmt.Errorf("index %s takes no flags", sub)
+		}
+		if sub == "stats" {
+			return runIndexStats(cfg)
+		}
+		return runIndexCompact(cfg)
+	}
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+
+	if info, err := os.Stat(cfg.VaultPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("vault %s is not a directory", cfg.VaultPath)
+	}
+	db, idx, rebuild, err := openIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close() //nolint:errcheck // nothing left to flush
+
+	start := time.Now()
+	run := idx.Update
+	if *full || rebuild {
+		run = idx.Rebuild
+	}
+	stats, err := run(nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("indexed %d of %d files, removed %d, %d links, %d attachments in %s\n",
+		stats.Indexed, stats.Files, stats.Removed, stats.Links, stats.Attachments, time.Since(start).Round(time.Millisecond))
+	if stats.Encrypted > 0 {
+		fmt.Printf("skipped %d encrypted files; unlock the vault to index them\n", stats.Encrypted)
+	}
+	if *optimize {
+		opt, err := db.Optimize(true)
+		if err != nil {
+			return fmt.Errorf("optimize: %w", err)
+		}
+		fmt.Printf("optimized index: %d KB to %d KB\n", opt.SizeBefore/1024, opt.SizeAfter/1024)
+	}
+	return nil
+}
+
+// runIndexStats prints the index's size, free space, search index size and
+// contents.
+func runIndexStats(cfg config.Config) error {
+	db, err := openExistingIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close() //nolint:errcheck // read only
+
+	s, err := db.SizeStats()
+	if err != nil {
+		return err
+	}
+	vacuum := "full vacuum"
+	if s.Incremental {
+		vacuum = "incremental vacuum"
+	}
+	fmt.Printf("%s: %d KB, %d KB free (%s)\n", indexPath(cfg), s.Size/1024, s.Free/1024, vacuum)
+	if info, err := os.Stat(indexPath(cfg) + "-wal"); err == nil && info.Size() > 0 {
+		fmt.Printf("write-ahead log: %d KB\n", info.Size()/1024)
+	}
+	fmt.Printf("search index: %d KB in %d segments\n", s.Search/1024, s.Segments)
+	fmt.Printf("%d notes, %d links, %d attachments\n", s.Notes, s.Links, s.Attachments)
+	return nil
+}
+
+// runIndexCompact merges the search index's segments and vacuums the
+// index, as Space i o does.
+func runIndexCompact(cfg config.Config) error {
+	db, err := openExistingIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close() //nolint:errcheck // nothing left to flush
+
+	opt, err := db.Optimize(true)
+	if err != nil {
+		return fmt.Errorf("compact: %w", err)
+	}
+	fmt.Printf("compacted index: %d KB to %d KB\n", opt.SizeBefore/1024, opt.SizeAfter/1024)
+	return nil
+}
+
+// openExistingIndex opens the vault's index as it is, without creating one
+// or applying the configured tokenizer and basename scope.
+func openExistingIndex(cfg config.Config) (*index.DB, error) {
+	if _, err := os.Stat(indexPath(cfg)); err != nil {
+		return nil, fmt.Errorf("no index at %s; run kopr index first", indexPath(cfg))
+	}
+	db, err := index.Open(indexPath(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("open index: %w", err)
+	}
+	return db, nil
+}
+
+// indexPath returns the vault's index database.
+func indexPath(cfg config.Config) string {
+	return filepath.Join(cfg.VaultPath, ".kopr", "index.db")
+}
+
+// openIndex opens the vault's index with the configured tokenizer and
+// basename scope, as the app does. rebuild reports that applying them
+// rewrote the index, which only a full rebuild refills.
+func openIndex(cfg config.Config) (db *index.DB, idx *index.Indexer, rebuild bool, err error) {
+	dbPath := indexPath(cfg)
+	if err := os.MkdirAll(filepath.Dir(dbPath), 0755); err != nil {
+		return nil, nil, false, err
+	}
+	if db, err = index.Open(dbPath); err != nil {
+		return nil, nil, false, fmt.Errorf("open index: %w", err)
+	}
+	retokenized, err := db.SetTokenizer(cfg.FTSTokenizer)
+	if err != nil {
+		return nil, nil, false, errors.Join(fmt.Errorf("fts_tokenizer: %w", err), db.Close())
+	}
+	rescoped, err := db.SetBasenameScope(cfg.BasenameUniqueness)
+	if err != nil {
+		return nil, nil, false, errors.Join(fmt.Errorf("basename_uniqueness: %w", err), db.Close())
+	}
+	if idx, err = newIndexer(cfg, db); err != nil {
+		return nil, nil, false, errors.Join(err, db.Close())
+	}
+	return db, idx, retokenized || rescoped, nil
+}
+
+// newIndexer returns an indexer for the vault and its external sources
+// that skips templates unless they are shown, and whatever the vault's
+// ignore file lists, as the app does.
+func newIndexer(cfg config.Config, db *index.DB) (*index.Indexer, error) {
+	idx := index.NewIndexer(db, cfg.VaultPath)
+	if !cfg.ShowTemplates {
+		idx.SetSkipDir(vault.New(cfg.VaultPath).TemplatesRel())
+	}
+	ignore, err := vault.ReadIgnore(cfg.VaultPath)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", vault.IgnoreFile, err)
+	}
+	idx.SetIgnore(ignore)
+	idx.SetSources(externalSources(cfg))
+	return idx, nil
+}
+
+// externalSources returns the configured external sources, as the app
+// reads them.
+func externalSources(cfg config.Config) vault.Sources {
+	var sources vault.Sources
+	for _, src := range cfg.ExternalSources {
+		sources = append(sources, vault.Source{Name: src.Name, Root: filepath.Clean(src.Path)})
+	}
+	return sources
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pfassina/kopr/internal/config"
+)
+
+// runLint implements `kopr lint [vault]`: it brings the index of the vault
+// (the configured one unless a directory is given, as in CI checkouts) up
+// to date and lists links to missing notes or sections and leftover merge
+// conflicts, one "path:line:col: message" per line, failing if there are
+// any.
+func runLint(cfg config.Config, args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: kopr lint [vault]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return fmt.Errorf("unexpected arguments %q", flags.Args()[1:])
+	}
+	if flags.NArg() == 1 {
+		root, err := filepath.Abs(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		cfg.VaultPath = root
+	}
+
+	if info, err := os.Stat(cfg.VaultPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("vault %s is not a directory", cfg.VaultPath)
+	}
+	db, idx, rebuild, err := openIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close() //nolint:errcheck // nothing left to flush
+
+	update := idx.Update
+	if rebuild {
+		update = idx.Rebuild
+	}
+	if _, err := update(nil); err != nil {
+		return err
+	}
+	problems, err := idx.Lint()
+	if err != nil {
+		return err
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%d problems", len(problems))
+	}
+	return nil
+}
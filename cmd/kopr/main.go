@@ -3,13 +3,14 @@ package main
 import (
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 
 	"github.com/pfassina/kopr/internal/app"
 	"github.com/pfassina/kopr/internal/config"
@@ -28,26 +29,49 @@ func main() {
 	vault := flag.String("vault", cfg.VaultPath, "path to vault directory")
 	serve := flag.Bool("serve", cfg.Serve, "run in SSH server mode")
 	listen := flag.String("listen", cfg.Listen, "listen address for --serve (e.g. :2222)")
+	metricsListen := flag.String("metrics-listen", cfg.MetricsListen, "serve Prometheus metrics on this address in --serve mode (e.g. 127.0.0.1:9464)")
 	colorscheme := flag.String("colorscheme", cfg.Colorscheme, "vim colorscheme name")
 	nvimMode := flag.String("nvim-mode", cfg.NvimMode, "neovim config mode: managed|user")
 	leaderKey := flag.String("leader-key", cfg.LeaderKey, "leader key (default: space)")
 	leaderTimeout := flag.Int("leader-timeout", cfg.LeaderTimeout, "leader timeout in ms")
 	resetNvimConfig := flag.Bool("reset-nvim-config", false, "reset managed Neovim config to defaults")
+	readOnly := flag.Bool("read-only", false, "browse notes in a built-in viewer without Neovim; nothing can be edited")
+	pprofListen := flag.String("pprof", "", "serve runtime profiles on this address (e.g. :6060)")
 
+	flag.Usage = usage
 	flag.Parse()
 
+	if *pprofListen != "" {
+		if err := startPprof(*pprofListen); err != nil {
+			fmt.Fprintln(os.Stderr, "pprof:", err)
+			os.Exit(1)
+		}
+	}
+
 	// Normalize vault path: expand ~ and make absolute so Neovim cwd + :w use stable paths.
 	cfg.VaultPath = config.ExpandHome(*vault)
 	if abs, err := filepath.Abs(cfg.VaultPath); err == nil {
 		cfg.VaultPath = abs
 	}
+
+	if name := flag.Arg(0); name != "" {
+		if handled, err := runSubcommand(cfg, name, flag.Args()[1:]); handled {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "kopr %s: %v\n", name, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 	cfg.Serve = *serve
 	cfg.Listen = *listen
+	cfg.MetricsListen = *metricsListen
 	cfg.Colorscheme = *colorscheme
 	cfg.NvimMode = *nvimMode
 	cfg.LeaderKey = *leaderKey
 	cfg.LeaderTimeout = *leaderTimeout
 	cfg.ResetNvimConfig = *resetNvimConfig
+	cfg.ReadOnly = *readOnly
 
 	// First-run: if no config file exists and vault wasn't explicitly provided,
 	// prompt for a vault path and persist it.
@@ -66,6 +90,23 @@ func main() {
 		}
 	}
 
+	// The vault path changed since the last run: offer to bring the old vault
+	// along rather than silently opening an empty one.
+	if last := config.LastVault(); config.NeedsMigration(last, cfg.VaultPath) {
+		if !isTerminal(os.Stdin) {
+			fmt.Fprintf(os.Stderr, "note: opening empty vault %s; previous vault is %s\n", cfg.VaultPath, last)
+		} else {
+			choice, err := config.RunMigrate(last, cfg.VaultPath)
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			if choice == config.MigrateCancel {
+				os.Exit(0)
+			}
+		}
+	}
+
 	if err := os.MkdirAll(cfg.VaultPath, 0755); err != nil {
 		fmt.Fprintln(os.Stderr, "error creating vault dir:", err)
 		os.Exit(1)
@@ -74,10 +115,16 @@ func main() {
 		fmt.Fprintln(os.Stderr, "error creating .kopr dir:", err)
 		os.Exit(1)
 	}
+	if err := config.RecordVault(cfg.VaultPath); err != nil {
+		fmt.Fprintln(os.Stderr, "warning: recording vault path:", err)
+	}
 
-	if err := editor.CheckNvimVersion(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	// Without a usable Neovim, still let the vault be browsed.
+	if !cfg.ReadOnly {
+		if err := editor.CheckNvimVersion(); err != nil {
+			fmt.Fprintf(os.Stderr, "%v; starting read-only\n", err)
+			cfg.ReadOnly = true
+		}
 	}
 
 	if cfg.ResetNvimConfig {
@@ -88,28 +135,67 @@ func main() {
 		fmt.Fprintln(os.Stderr, "reset Neovim config")
 	}
 
-	if err := editor.EnsureProfile(editor.ProfileMode(cfg.NvimMode)); err != nil {
-		fmt.Fprintln(os.Stderr, "neovim profile:", err)
+	if !cfg.ReadOnly {
+		if err := editor.EnsureProfile(editor.ProfileMode(cfg.NvimMode)); err != nil {
+			fmt.Fprintln(os.Stderr, "neovim profile:", err)
+			os.Exit(1)
+		}
+		if err := editor.EnsureThemePlugin(cfg.ColorschemeRepo); err != nil {
+			fmt.Fprintln(os.Stderr, "colorscheme plugin:", err)
+			os.Exit(1)
+		}
+	}
+
+	relock, err := unlockVault(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "unlock vault:", err)
 		os.Exit(1)
 	}
-	if err := editor.EnsureThemePlugin(cfg.ColorschemeRepo); err != nil {
-		fmt.Fprintln(os.Stderr, "colorscheme plugin:", err)
+	run := runLocal
+	if cfg.Serve {
+		run = runServe
+	}
+	// Relock before exiting either way, so a failed run doesn't leave the
+	// vault decrypted on disk.
+	err = run(cfg)
+	relock()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+}
 
-	if cfg.Serve {
-		runServe(cfg)
-		return
+// runSubcommand runs the CLI subcommand called name, reporting false if
+// there is none by that name.
+func runSubcommand(cfg config.Config, name string, args []string) (bool, error) {
+	switch name {
+	case "cat":
+		return true, runCat(cfg.VaultPath, args)
+	case "index":
+		return true, runIndex(cfg, args)
+	case "doctor":
+		return true, runDoctor(cfg, args)
+	case "tags":
+		return true, runTags(cfg, args)
+	case "graph":
+		return true, runGraph(cfg, args)
+	case "fmt":
+		return true, runFmt(args)
+	case "lint":
+		return true, runLint(cfg, args)
+	case "uid":
+		return true, runUID(cfg, args)
+	case "update":
+		return true, runUpdate(args)
 	}
-	runLocal(cfg)
+	return false, nil
 }
 
-func runLocal(cfg config.Config) {
+func runLocal(cfg config.Config) error {
 	// Ensure lipgloss/termenv uses truecolor so extracted colorscheme colors
 	// render accurately instead of being approximated to the 256-color palette.
 	if err := os.Setenv("COLORTERM", "truecolor"); err != nil {
-		fmt.Fprintln(os.Stderr, "error setting COLORTERM:", err)
-		os.Exit(1)
+		return fmt.Errorf("error setting COLORTERM: %w", err)
 	}
 
 	a := app.New(cfg)
@@ -117,14 +203,36 @@ func runLocal(cfg config.Config) {
 	p := tea.NewProgram(&a, tea.WithAltScreen(), tea.WithMouseAllMotion())
 	a.SetProgram(p)
 	if _, err := p.Run(); err != nil {
-		log.Fatal(err)
+		return err
 	}
+	if out := a.ExitOutput(); len(out) > 0 {
+		if _, err := os.Stdout.Write(out); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func runServe(cfg config.Config) {
+func runServe(cfg config.Config) error {
+	// Styles are written to each session's connection, not the server's
+	// stdout. Render at full depth; each session downgrades its own theme
+	// to the profile its client negotiated.
+	lipgloss.SetColorProfile(termenv.TrueColor)
+
 	s, err := ssh.New(cfg)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+
+	// Print the fingerprint so users can check it against what ssh shows on
+	// their first connection.
+	key := s.HostKey()
+	if key.Generated {
+		fmt.Fprintf(os.Stderr, "generated host key %s\n", key.Path)
+	}
+	fmt.Fprintf(os.Stderr, "listening on %s\nhost key %s %s (%s)\n", cfg.Listen, key.Type, key.Fingerprint, key.Path)
+	if cfg.CheckForUpdates {
+		go reportUpdate()
 	}
 
 	// Graceful shutdown on SIGINT/SIGTERM.
@@ -137,9 +245,12 @@ func runServe(cfg config.Config) {
 		}
 	}()
 
-	if err := s.ListenAndServe(); err != nil {
-		log.Fatal(err)
-	}
+	return s.ListenAndServe()
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 func argHas(name string) bool {
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/index"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// runTags implements `kopr tags [rename [--dry-run] <old> <new>]`: with no
+// arguments it prints the vault's frontmatter tags as a tree with their note
+// counts, subtags included; rename renames a tag and its subtags in every
+// note carrying them, or with --dry-run prints the lines it would change.
+func runTags(cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("tags", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "rename: print the notes and lines that would change, without writing them")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kopr tags [rename [--dry-run] <old> <new>]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	rename := fs.Arg(0) == "rename"
+	if rename {
+		// Flags may follow the subcommand.
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return err
+		}
+	}
+	if (rename && fs.NArg() != 2) || (!rename && (fs.NArg() != 0 || *dryRun)) {
+		fs.Usage()
+		return fmt.Errorf("unexpected arguments %q", fs.Args())
+	}
+
+	if info, err := os.Stat(cfg.VaultPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("vault %s is not a directory", cfg.VaultPath)
+	}
+	db, idx, rebuild, err := openIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close() //nolint:errcheck // nothing left to flush
+
+	// Counts and renames go by the index, so bring it up to date first.
+	update := idx.Update
+	if rebuild {
+		update = idx.Rebuild
+	}
+	if _, err := update(nil); err != nil {
+		return err
+	}
+
+	if rename && *dryRun {
+		rewrites, err := idx.PlanTagRename(fs.Arg(0), fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		for _, rw := range rewrites {
+			original, err := os.ReadFile(rw.Path)
+			if err != nil {
+				return err
+			}
+			rel, err := filepath.Rel(cfg.VaultPath, rw.Path)
+			if err != nil {
+				return err
+			}
+			fmt.Println(rel)
+			for _, c := range vault.ChangedLines(original, rw.Content) {
+				fmt.Printf("%5d - %s\n%5d + %s\n", c.Line, c.Old, c.Line, c.New)
+			}
+		}
+		fmt.Printf("would rename %s to %s in %d notes\n", fs.Arg(0), fs.Arg(1), len(rewrites))
+		return nil
+	}
+	if rename {
+		changed, err := idx.RenameTag(fs.Arg(0), fs.Arg(1))
+		if err != nil {
+			return err
+		}
+		fmt.Printf("renamed %s to %s in %d notes\n", fs.Arg(0), fs.Arg(1), len(changed))
+		return nil
+	}
+
+	tags, err := db.ListTags()
+	if err != nil {
+		return err
+	}
+	index.SortTagTree(tags)
+	for _, t := range tags {
+		depth := strings.Count(t.Name, "/")
+		fmt.Printf("%5d  %s%s\n", t.Total, strings.Repeat("  ", depth), t.Name[strings.LastIndex(t.Name, "/")+1:])
+	}
+	return nil
+}
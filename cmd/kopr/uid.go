@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// runUID implements `kopr uid <note>` and `kopr uid --resolve <uid>`: the
+// first prints a note's uid, giving it one if it has none, for a task app or
+// bookmark to keep; the second prints the vault-relative path of the note
+// with that uid, wherever it has since moved.
+func runUID(cfg config.Config, args []string) error {
+	fs := flag.NewFlagSet("uid", flag.ContinueOnError)
+	resolve := fs.Bool("resolve", false, "print the path of the note with this uid")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kopr uid [--resolve] <note|uid>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("expected one argument, got %d", fs.NArg())
+	}
+	if info, err := os.Stat(cfg.VaultPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("vault %s is not a directory", cfg.VaultPath)
+	}
+
+	if !*resolve {
+		v := vault.New(cfg.VaultPath)
+		path, err := resolveNote(v, fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(v.Root, path)
+		if err != nil {
+			return err
+		}
+		uid, err := v.EnsureUID(rel)
+		if err != nil {
+			return err
+		}
+		fmt.Println(uid)
+		return nil
+	}
+
+	db, idx, rebuild, err := openIndex(cfg)
+	if err != nil {
+		return err
+	}
+	defer db.Close() //nolint:errcheck // nothing left to flush
+
+	// The note may have moved since it was last indexed.
+	update := idx.Update
+	if rebuild {
+		update = idx.Rebuild
+	}
+	if _, err := update(nil); err != nil {
+		return err
+	}
+	path, err := db.NoteByUID(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return fmt.Errorf("no note has uid %q", fs.Arg(0))
+	}
+	fmt.Println(filepath.ToSlash(path))
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// unlockVault runs unlock_command when notes in the vault are still
+// encrypted by git-crypt or age, and returns the function that runs
+// lock_command on exit if it did. Without an unlock command, or when it
+// fails, kopr starts anyway and leaves the encrypted notes out of the index.
+func unlockVault(cfg config.Config) (relock func(), err error) {
+	relock = func() {}
+	encrypted, err := vault.New(cfg.VaultPath).EncryptedNotes()
+	if err != nil {
+		return relock, err
+	}
+	if len(encrypted) == 0 {
+		return relock, nil
+	}
+	args := strings.Fields(cfg.UnlockCommand)
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "note: %d encrypted notes are left out of the index; set unlock_command to decrypt them\n", len(encrypted))
+		return relock, nil
+	}
+	if err := runInVault(cfg.VaultPath, args); err != nil {
+		fmt.Fprintf(os.Stderr, "unlock vault: %v; %d encrypted notes are left out of the index\n", err, len(encrypted))
+		return relock, nil
+	}
+	lock := strings.Fields(cfg.LockCommand)
+	if len(lock) == 0 {
+		return relock, nil
+	}
+	return func() {
+		if err := runInVault(cfg.VaultPath, lock); err != nil {
+			fmt.Fprintln(os.Stderr, "lock vault:", err)
+		}
+	}, nil
+}
+
+// runInVault runs a command in the vault with the terminal attached, so it
+// can ask for a passphrase.
+func runInVault(dir string, args []string) error {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Dir = dir
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pfassina/kopr/internal/update"
+)
+
+// runUpdate implements `kopr update [--check]`: it replaces the running binary
+// with the latest GitHub release after verifying its checksum.
+func runUpdate(args []string) error {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	check := fs.Bool("check", false, "only report whether a newer release exists")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: kopr update [--check]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+	client := &http.Client{}
+
+	current := update.Current()
+	rel, err := update.Latest(ctx, client)
+	if err != nil {
+		return err
+	}
+	if !update.IsRelease(current) {
+		return fmt.Errorf("%s is a development build; the latest release is %s", current, rel.Tag)
+	}
+	if !update.Newer(rel.Tag, current) {
+		fmt.Printf("kopr %s is up to date\n", current)
+		return nil
+	}
+	if *check {
+		fmt.Printf("kopr %s is available (running %s)\n", rel.Tag, current)
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+	if err := update.Apply(ctx, client, rel, exe); err != nil {
+		return err
+	}
+	fmt.Printf("updated kopr %s -> %s\n", current, rel.Tag)
+	return nil
+}
+
+// reportUpdate prints a notice to stderr when a newer release exists, or why
+// the check failed. Serve mode uses it instead of the status bar so sessions
+// don't each query GitHub.
+func reportUpdate() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rel, err := update.Latest(ctx, http.DefaultClient)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "update check:", err)
+		return
+	}
+	if !update.Newer(rel.Tag, update.Current()) {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "kopr %s is available (running %s); run kopr update\n", rel.Tag, update.Current())
+}
@@ -1,24 +1,34 @@
 package app
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	osc52 "github.com/aymanbagabas/go-osc52/v2"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
 
 	"github.com/pfassina/kopr/internal/config"
 	"github.com/pfassina/kopr/internal/editor"
+	"github.com/pfassina/kopr/internal/git"
 	"github.com/pfassina/kopr/internal/index"
 	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/metrics"
 	"github.com/pfassina/kopr/internal/panel"
 	"github.com/pfassina/kopr/internal/session"
 	"github.com/pfassina/kopr/internal/theme"
+	"github.com/pfassina/kopr/internal/update"
 	"github.com/pfassina/kopr/internal/vault"
 )
 
@@ -29,12 +39,27 @@ const (
 	focusTree
 	focusInfo
 	focusFinder
+	focusTerminal
 )
 
 type promptAction struct {
-	kind  string   // "save", "close", "create-note", "delete-note", "delete-notes", "rename-note"
-	path  string   // target file path for delete/rename
-	paths []string // multiple paths for multi-delete
+	kind    string     // "save", "close", "create-note", "delete-note", "delete-notes", "rename-note", "autolink", "replace-find", "replace-with", "restore-revision", "resolve-conflicts", "triage-move", "triage-tag", "disk-change", "purge-trash"
+	path    string            // target file path for delete/rename
+	paths   []string          // multiple paths for multi-delete
+	targets []string          // note names to link for autolink
+	find    string            // text to replace for vault-wide replace, or a followed link's title
+	commit  git.Commit        // revision to restore the note at path to
+	section string            // heading a followed link points to
+	trash   []vault.TrashItem // notes to purge from the trash
+}
+
+// pendingChanges tracks the bulk operation awaiting the change preview.
+type pendingChanges struct {
+	kind    string // "rename", "replace"
+	oldPath string
+	newRel  string
+	find    string // replace: text to find
+	replace string // replace: replacement text
 }
 
 type App struct {
@@ -48,12 +73,22 @@ type App struct {
 	finder   panel.Finder
 	prompt      panel.Prompt
 	contextMenu panel.ContextMenu
+	habits      panel.HabitTracker
+	changes     panel.ChangePreview
+	noteHistory panel.History
+	trash       panel.Trash
+	triage      panel.Triage
+	review      panel.Review
+	console     panel.QueryConsole
+	preview     panel.Preview
 	vault    *vault.Vault
 	db       *index.DB
 	indexer  *index.Indexer
 	watcher  *index.Watcher
 	store    *session.Store
+	history  *session.HistoryStore
 	theme    theme.Theme
+	styles   theme.StyleSet // built from theme, shared by the panels
 	width    int
 	height   int
 	focused  focusedPanel
@@ -61,6 +96,28 @@ type App struct {
 	showInfo bool
 	zenMode  bool
 
+	// showPreview puts a rendering of the current note in the info panel's
+	// place.
+	showPreview bool
+
+	// terminal runs a shell in the vault in a pane under the panels, shown
+	// with showTerminal.
+	terminal     editor.Terminal
+	showTerminal bool
+
+	// showGraph adds the current note's local link graph to the info panel.
+	showGraph bool
+
+	// showBlame annotates the editor's lines with the commit that last
+	// changed them. blameFile is the note the annotations were made for.
+	showBlame bool
+	blameFile string
+
+	// conflicts is the number of merge conflicts last found in
+	// conflictFile, the note open at the time.
+	conflicts    int
+	conflictFile string
+
 	// Leader key system
 	bindings map[string]*Binding
 	leader   LeaderState
@@ -68,6 +125,40 @@ type App struct {
 	// pendingPrompt tracks which action the overlay prompt is serving.
 	pendingPrompt promptAction
 
+	// pickingTemplate is set while the finder lists templates, so a selection
+	// creates a note from the template instead of opening it.
+	pickingTemplate bool
+
+	// pickingAttachment is set while the finder lists attachments, so a
+	// selection opens the file externally instead of in the editor.
+	pickingAttachment bool
+
+	// quickfix holds the search results sent from the finder with Ctrl+Q;
+	// quickfixIdx is the entry ]q/[q last jumped to (-1 before the first).
+	quickfix    []panel.FinderItem
+	quickfixIdx int
+
+	// indexRuns counts full indexes in progress; the latest progress
+	// report shows in the status bar while it is non-zero.
+	indexRuns     int
+	indexProgress indexProgressMsg
+	indexSpinner  spinner.Model
+
+	// watcherQueued and watcherPaused mirror the file watcher's queue of
+	// changed notes waiting to be reindexed, for the status bar.
+	watcherQueued int
+	watcherPaused bool
+
+	// indexPaused is set while the user has paused the watcher (Space c i).
+	indexPaused bool
+
+	// pendingChanges tracks which bulk operation the change preview is serving.
+	pendingChanges pendingChanges
+
+	// fileOps logs this session's renames, moves and deletes, oldest
+	// first, for Space u to undo.
+	fileOps []fileOp
+
 	// currentFile caches the open file's relative path for use in View().
 	// Never call RPC from View() — it can hang if the connection is dead.
 	currentFile string
@@ -75,6 +166,10 @@ type App struct {
 	// prevFile stores the previously opened note for gb (go back) navigation.
 	prevFile string
 
+	// autolinkDeclined records auto-link targets declined per note (relative
+	// path -> note name) so autolink-on-save doesn't ask twice.
+	autolinkDeclined map[string]map[string]bool
+
 	// output is the terminal writer for OSC 52 clipboard sequences.
 	// os.Stdout for local mode, the SSH session for SSH mode.
 	output io.Writer
@@ -83,6 +178,28 @@ type App struct {
 	// Used for context menu paste since we can't read the remote clipboard.
 	clipboardText string
 
+	// metrics records serve-mode statistics; nil in local mode.
+	metrics *metrics.Registry
+
+	// lowBandwidth trims redraws for slow SSH links: plain borders, no
+	// which-key popup and no full repaints on resize.
+	lowBandwidth bool
+
+	// colorProfile is what the client terminal can display; theme colors
+	// are downgraded to it. Always TrueColor in local mode.
+	colorProfile termenv.Profile
+
+	// lastInput is when the user last pressed a key or used the mouse; an
+	// SSH session idle for cfg.IdleTimeout is closed.
+	lastInput time.Time
+
+	// closed is set once Close has run, so it is safe to call again when an
+	// SSH session ends after the user quit.
+	closed bool
+
+	// exitOutput is printed to stdout after the TUI exits (local mode only).
+	exitOutput []byte
+
 	// doubleClick tracks timing/position for double-click detection.
 	doubleClick doubleClickTracker
 
@@ -91,6 +208,9 @@ type App struct {
 
 	// contextMenuInfoIdx stores the info row index that was right-clicked.
 	contextMenuInfoIdx int
+
+	// finderActionItem is the finder result the action menu was opened on.
+	finderActionItem panel.FinderItem
 }
 
 // navigateTo opens a note and updates the navigation history.
@@ -98,16 +218,32 @@ func (a *App) navigateTo(relPath string) {
 	if a.currentFile != "" && a.currentFile != relPath {
 		a.prevFile = a.currentFile
 	}
-	fullPath := filepath.Join(a.cfg.VaultPath, relPath)
-	a.openInEditor(fullPath)
+	fullPath := a.vault.AbsPath(relPath)
+	if vault.IsExternal(relPath) {
+		a.openReadOnly(fullPath)
+	} else {
+		a.openInEditor(fullPath)
+	}
 	a.status.ClearError()
 	a.status.SetFile(relPath)
 	a.currentFile = relPath
 	a.updateInfoPanel(relPath)
+	a.refreshPreview()
+	if a.db != nil {
+		if err := a.db.RecordVisit(relPath, time.Now()); err != nil {
+			a.status.SetError(fmt.Sprintf("record visit: %v", err))
+		}
+	}
 }
 
 func New(cfg config.Config) App {
 	v := vault.New(cfg.VaultPath)
+	v.TemplateDir = cfg.TemplateDir
+	v.ShowTemplates = cfg.ShowTemplates
+	v.AssignUIDs = cfg.NoteUIDs
+	v.Sources = externalSources(cfg)
+	ignore, ignoreErr := vault.ReadIgnore(cfg.VaultPath)
+	v.Ignore = ignore
 	t := panel.NewTree(v)
 	t.Refresh()
 
@@ -128,22 +264,52 @@ func New(cfg config.Config) App {
 		finder:   f,
 		prompt:      panel.NewPrompt(),
 		contextMenu: panel.NewContextMenu(),
+		habits:      panel.NewHabitTracker(),
+		changes:     panel.NewChangePreview(),
+		noteHistory: panel.NewHistory(),
+		trash:       panel.NewTrash(),
+		triage:      panel.NewTriage(),
+		review:      panel.NewReview(),
+		console:     panel.NewQueryConsole(),
+		preview:     panel.NewPreview(),
+		terminal:    editor.NewTerminal(cfg.VaultPath),
 		vault:    v,
 		store:    store,
+		history:  session.NewHistoryStore(cfg.VaultPath),
 		theme:    theme.DefaultTheme(),
+		colorProfile: termenv.TrueColor,
+		indexSpinner: spinner.New(spinner.WithSpinner(spinner.MiniDot)),
 		focused:  focusEditor,
 		showTree: state.ShowTree,
 		showInfo: state.ShowInfo,
 	}
 	a.initLeader()
-	a.tree.SetTheme(&a.theme)
-	a.info.SetTheme(&a.theme)
-	a.finder.SetTheme(&a.theme)
-	a.prompt.SetTheme(&a.theme)
-	a.status.SetTheme(&a.theme)
-	a.whichKey.SetTheme(&a.theme)
-	a.editor.SetTheme(&a.theme)
-	a.contextMenu.SetTheme(&a.theme)
+	a.applyTheme()
+	a.finder.SetGroupByFolder(cfg.FinderGroupByFolder)
+	a.finder.SetSort(panel.ParseFinderSort(state.FinderSort))
+	a.editor.SetReadOnly(cfg.ReadOnly)
+	a.editor.SetUIProtocol(cfg.EditorBackend == "ui")
+	a.editor.SetScrollback(cfg.ScrollbackLines)
+	a.habits.SetHeading(cfg.HabitsHeading)
+	a.info.SetSavedSearches(savedSearchItems(cfg.SavedSearches))
+	if cfg.ReadOnly {
+		a.status.SetMode("READ-ONLY")
+		a.finder.SetCanCreate(false)
+	}
+
+	if cfg.EditorBackend != "pty" && cfg.EditorBackend != "ui" {
+		a.status.SetError(fmt.Sprintf("editor_backend: unknown backend %q, using pty", cfg.EditorBackend))
+	}
+
+	if ignoreErr != nil {
+		a.status.SetError(fmt.Sprintf("%s: %v", vault.IgnoreFile, ignoreErr))
+	}
+
+	if history, err := a.history.Load(); err != nil {
+		a.status.SetError(fmt.Sprintf("load finder history: %v", err))
+	} else {
+		a.finder.SetHistory(history)
+	}
 
 	// Initialize index
 	dbPath := filepath.Join(cfg.VaultPath, ".kopr", "index.db")
@@ -154,8 +320,20 @@ func New(cfg config.Config) App {
 		a.status.SetError(fmt.Sprintf("index open failed: %v", err))
 	} else {
 		a.db = db
+		// The startup IndexAll refills the table if this recreates it.
+		if _, err := db.SetTokenizer(cfg.FTSTokenizer); err != nil {
+			a.status.SetError(fmt.Sprintf("fts_tokenizer: %v", err))
+		}
+		if _, err := db.SetBasenameScope(cfg.BasenameUniqueness); err != nil {
+			a.status.SetError(fmt.Sprintf("basename_uniqueness: %v", err))
+		}
 		a.indexer = index.NewIndexer(db, cfg.VaultPath)
-		a.finder.SetSearchFunc(a.searchNotes)
+		if !cfg.ShowTemplates {
+			a.indexer.SetSkipDir(v.TemplatesRel())
+		}
+		a.indexer.SetIgnore(v.Ignore)
+		a.indexer.SetSources(v.Sources)
+		a.finder.SetPagedSearchFunc(a.searchNotes)
 		a.finder.SetPreviewFunc(a.previewNote)
 	}
 
@@ -172,6 +350,54 @@ func (a *App) SetOutput(w io.Writer) {
 	a.output = w
 }
 
+// SetMetrics records this session's searches and editor RPC failures in m.
+func (a *App) SetMetrics(m *metrics.Registry) {
+	a.metrics = m
+	a.editor.SetRPCErrorHook(m.RPCError)
+}
+
+// SetColorProfile limits the UI and the embedded Neovim to the colors the
+// client terminal supports. Call before the program starts.
+func (a *App) SetColorProfile(p termenv.Profile) {
+	a.colorProfile = p
+	a.editor.SetTrueColor(p == termenv.TrueColor)
+	a.theme = a.theme.ForProfile(p)
+	a.applyTheme()
+}
+
+// applyTheme rebuilds the shared styles from a.theme and hands both to the
+// panels. Call it whenever a.theme changes.
+func (a *App) applyTheme() {
+	a.styles = theme.NewStyleSet(a.theme)
+	a.tree.SetStyles(&a.styles)
+	a.info.SetStyles(&a.styles)
+	a.finder.SetStyles(&a.styles)
+	a.status.SetStyles(&a.styles)
+	a.preview.SetTheme(&a.theme)
+	a.prompt.SetTheme(&a.theme)
+	a.whichKey.SetTheme(&a.theme)
+	a.editor.SetTheme(&a.theme)
+	a.contextMenu.SetTheme(&a.theme)
+	a.habits.SetTheme(&a.theme)
+	a.changes.SetTheme(&a.theme)
+	a.noteHistory.SetTheme(&a.theme)
+	a.trash.SetTheme(&a.theme)
+	a.triage.SetTheme(&a.theme)
+	a.review.SetTheme(&a.theme)
+	a.console.SetTheme(&a.theme)
+}
+
+// SetLowBandwidth switches the session to the low-bandwidth rendering
+// profile. Call before the program starts.
+func (a *App) SetLowBandwidth(on bool) {
+	a.lowBandwidth = on
+}
+
+// ExitOutput returns the note content queued to print once the TUI exits.
+func (a *App) ExitOutput() []byte {
+	return a.exitOutput
+}
+
 // writeClipboard returns a Cmd that writes text to the system clipboard via OSC 52.
 func (a *App) writeClipboard(text string) tea.Cmd {
 	w := a.output
@@ -189,17 +415,77 @@ func (a *App) Init() tea.Cmd {
 	if a.indexer != nil {
 		cmds = append(cmds, a.initIndex())
 	}
+	if a.cfg.Serve && a.cfg.IdleTimeout > 0 {
+		a.lastInput = time.Now()
+		cmds = append(cmds, a.idleCheck())
+	}
+	if a.cfg.CheckForUpdates && !a.cfg.Serve {
+		cmds = append(cmds, checkForUpdate)
+	}
 	return tea.Batch(cmds...)
 }
 
+// updateAvailableMsg reports a release newer than the running binary.
+type updateAvailableMsg struct{ version string }
+
+// checkForUpdate looks up the latest release. Failures are silent: the
+// check is a courtesy and must not get in the way offline.
+func checkForUpdate() tea.Msg {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	rel, err := update.Latest(ctx, http.DefaultClient)
+	if err != nil || !update.Newer(rel.Tag, update.Current()) {
+		return nil
+	}
+	return updateAvailableMsg{version: rel.Tag}
+}
+
+// idleCheckMsg asks the app to close the session if it has been idle for
+// cfg.IdleTimeout.
+type idleCheckMsg struct{}
+
+func (a *App) idleCheck() tea.Cmd {
+	return tea.Tick(min(a.cfg.IdleTimeout, time.Minute), func(time.Time) tea.Msg {
+		return idleCheckMsg{}
+	})
+}
+
+// closeIdle writes modified buffers and quits, freeing the session's Neovim
+// process. Session state is saved by Close.
+func (a *App) closeIdle() tea.Cmd {
+	if rpc := a.editor.GetRPC(); rpc != nil {
+		if err := rpc.ExecCommand("silent! wall"); err != nil {
+			fmt.Fprintln(os.Stderr, "idle timeout: write buffers:", err)
+		}
+	}
+	a.Close()
+	return tea.Quit
+}
+
 func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg.(type) {
+	case tea.KeyMsg, tea.MouseMsg:
+		a.lastInput = time.Now()
+	}
+
 	switch msg := msg.(type) {
+	case updateAvailableMsg:
+		a.status.SetNotice(msg.version + " available (kopr update)")
+		return a, nil
+
+	case idleCheckMsg:
+		if time.Since(a.lastInput) >= a.cfg.IdleTimeout {
+			return a, a.closeIdle()
+		}
+		return a, a.idleCheck()
+
 	case editor.YankMsg:
 		a.clipboardText = msg.Text
 		return a, a.writeClipboard(msg.Text)
 
 	case tea.KeyMsg:
-		if msg.String() == "ctrl+c" {
+		// In the terminal pane Ctrl+C interrupts the shell's command.
+		if msg.String() == "ctrl+c" && a.focused != focusTerminal {
 			a.Close()
 			return a, tea.Quit
 		}
@@ -211,6 +497,62 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, cmd
 		}
 
+		// Context menu captures keys while open
+		if a.contextMenu.Visible() {
+			var cmd tea.Cmd
+			a.contextMenu, cmd = a.contextMenu.Update(msg)
+			return a, cmd
+		}
+
+		// Habit tracker overlay captures keys while open
+		if a.habits.Visible() {
+			var cmd tea.Cmd
+			a.habits, cmd = a.habits.Update(msg)
+			return a, cmd
+		}
+
+		// Change preview captures keys until applied or cancelled
+		if a.changes.Visible() {
+			var cmd tea.Cmd
+			a.changes, cmd = a.changes.Update(msg)
+			return a, cmd
+		}
+
+		// Note history captures keys until closed
+		if a.noteHistory.Visible() {
+			var cmd tea.Cmd
+			a.noteHistory, cmd = a.noteHistory.Update(msg)
+			return a, cmd
+		}
+
+		// Trash captures keys until closed
+		if a.trash.Visible() {
+			var cmd tea.Cmd
+			a.trash, cmd = a.trash.Update(msg)
+			return a, cmd
+		}
+
+		// Inbox triage captures keys until closed
+		if a.triage.Visible() {
+			var cmd tea.Cmd
+			a.triage, cmd = a.triage.Update(msg)
+			return a, cmd
+		}
+
+		// Stale note review captures keys until closed
+		if a.review.Visible() {
+			var cmd tea.Cmd
+			a.review, cmd = a.review.Update(msg)
+			return a, cmd
+		}
+
+		// Query console captures keys until closed
+		if a.console.Visible() {
+			var cmd tea.Cmd
+			a.console, cmd = a.console.Update(msg)
+			return a, cmd
+		}
+
 		// Finder takes priority when visible
 		if a.finder.Visible() {
 			var cmd tea.Cmd
@@ -238,14 +580,29 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, a.copySelectedText()
 		}
 
-		// Ctrl+h/l to switch panel focus
+		// Ctrl+h/j/k/l to switch panel focus. In the terminal pane only
+		// Ctrl+k is taken, so the shell keeps Ctrl+h, Ctrl+j and Ctrl+l.
 		switch msg.String() {
 		case "ctrl+h":
-			a.focusLeft()
-			return a, nil
+			if a.focused != focusTerminal {
+				a.focusLeft()
+				return a, nil
+			}
 		case "ctrl+l":
-			a.focusRight()
-			return a, nil
+			if a.focused != focusTerminal {
+				a.focusRight()
+				return a, nil
+			}
+		case "ctrl+j":
+			if a.focused != focusTerminal && a.showTerminal {
+				a.setFocus(focusTerminal)
+				return a, nil
+			}
+		case "ctrl+k":
+			if a.focused == focusTerminal {
+				a.setFocus(focusEditor)
+				return a, nil
+			}
 		}
 
 		// Escape returns from side panels to editor (unless tree help is showing)
@@ -257,9 +614,10 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, nil
 		}
 
-		// Try leader key system (works from editor and side panels)
+		// Try leader key system (works from editor and side panels, but not
+		// the terminal, where Space is typed)
 		// Skip when tree help is showing so any key dismisses help first
-		if a.focused != focusTree || !a.tree.ShowingHelp() {
+		if a.focused != focusTerminal && (a.focused != focusTree || !a.tree.ShowingHelp()) {
 			if consumed, cmd := a.handleLeaderKey(msg.String()); consumed {
 				a.updateWhichKey()
 				return a, cmd
@@ -363,6 +721,7 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					{Label: "Open", Action: "tree-open", Disabled: !isFile},
 					{Label: "New Note", Action: "tree-new"},
 					{Label: "Rename", Action: "tree-rename", Disabled: !isFile},
+					{Label: "Duplicate", Action: "tree-duplicate", Disabled: !isFile},
 					{Label: "Delete", Action: "tree-delete", Disabled: !hasEntry},
 				})
 				return a, nil
@@ -370,6 +729,13 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, nil
 
 		case mouseTargetInfo:
+			if a.showPreview {
+				if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+					a.setFocus(focusInfo)
+				}
+				a.preview, _ = a.preview.Update(msg)
+				return a, nil
+			}
 			if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
 				a.setFocus(focusInfo)
 				// Move info cursor to clicked row
@@ -399,6 +765,12 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		case mouseTargetStatus:
 			return a, nil
+
+		case mouseTargetTerminal:
+			if msg.Action == tea.MouseActionPress && msg.Button == tea.MouseButtonLeft {
+				a.setFocus(focusTerminal)
+			}
+			return a, nil
 		}
 		return a, nil
 
@@ -406,6 +778,57 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return a, a.handleContextMenuResult(msg.Action)
 
 	case panel.ContextMenuClosedMsg:
+		if a.focused == focusFinder {
+			a.setFocus(focusEditor)
+		}
+		return a, nil
+
+	case panel.HabitClosedMsg:
+		return a, nil
+
+	case panel.ChangePreviewApplyMsg:
+		return a, a.applyPendingChanges(msg.Included)
+
+	case panel.ChangePreviewCancelledMsg:
+		a.pendingChanges = pendingChanges{}
+		return a, nil
+
+	case panel.HistoryDiffMsg:
+		var cmd tea.Cmd
+		a.noteHistory, cmd = a.noteHistory.Update(msg)
+		return a, cmd
+
+	case panel.HistoryRestoreMsg:
+		a.confirmRestore(msg.Commit)
+		return a, nil
+
+	case panel.HistoryClosedMsg:
+		return a, nil
+
+	case panel.TrashRestoreMsg:
+		a.restoreTrash(msg.Item)
+		return a, nil
+
+	case panel.TrashPurgeMsg:
+		a.confirmPurgeTrash(msg.Items)
+		return a, nil
+
+	case panel.TrashClosedMsg:
+		return a, nil
+
+	case panel.TriageActionMsg:
+		return a, a.handleTriageAction(msg)
+
+	case panel.ReviewActionMsg:
+		return a, a.handleReviewAction(msg)
+
+	case panel.ConsoleRunMsg:
+		a.runConsoleQuery(msg.Query)
+		return a, nil
+
+	case panel.ConsoleOpenMsg:
+		a.navigateTo(msg.Path)
+		a.setFocus(focusEditor)
 		return a, nil
 
 	case leaderTimeoutMsg:
@@ -416,6 +839,15 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case fatalErrorMsg:
 		return a, tea.Batch(tea.Printf("fatal: %v\n", msg.err), tea.Quit)
 
+	case watcherPollingMsg:
+		a.status.SetMessage(fmt.Sprintf("File watching unavailable, polling for changes: %v", msg.err))
+		return a, nil
+
+	case watcherQueueMsg:
+		a.watcherQueued, a.watcherPaused = msg.queued, msg.paused
+		a.showActivity()
+		return a, nil
+
 	case tea.WindowSizeMsg:
 		// Some terminals send transient 0x0 sizes during live resizes; ignore them.
 		if msg.Width <= 0 || msg.Height <= 0 {
@@ -424,6 +856,13 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.width = msg.Width
 		a.height = msg.Height
 		a.finder.SetSize(msg.Width, msg.Height)
+		a.habits.SetWidth(msg.Width)
+		a.changes.SetSize(msg.Width, msg.Height)
+		a.noteHistory.SetSize(msg.Width, msg.Height)
+		a.trash.SetSize(msg.Width, msg.Height)
+		a.triage.SetSize(msg.Width, msg.Height)
+		a.review.SetSize(msg.Width, msg.Height)
+		a.console.SetSize(msg.Width, msg.Height)
 
 		minW, minH := a.minWindowSize()
 		if a.width < minW || a.height < minH {
@@ -432,7 +871,7 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 		// Size prompt relative to the center/editor panel (Neovim buffer area), not the full screen.
 		showTree, showInfo := a.panelsVisible()
-		layout := ComputeLayout(a.width, a.height, showTree, showInfo, a.cfg.TreeWidth, a.cfg.InfoWidth)
+		layout := ComputeLayout(a.width, a.height, showTree, showInfo, a.showTerminal, a.cfg.TreeWidth, a.rightPanelWidth())
 		promptW := int(float64(layout.EditorWidth) * 0.80)
 		// Clamp to a sane modal width; 80% of a wide terminal is still too wide.
 		if promptW > 100 {
@@ -447,6 +886,10 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.prompt.SetSize(promptW, layout.Height)
 
 		cmd := a.updateLayout()
+		if a.lowBandwidth {
+			// Rely on the renderer's line diffing instead of a full repaint.
+			return a, cmd
+		}
 		// Force a full terminal repaint on resize; some terminals/bubbletea render
 		// paths can end up visually blank without an explicit clear.
 		if cmd != nil {
@@ -461,11 +904,17 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.updateWhichKey()
 		}
 
+	case editor.TerminalExitedMsg:
+		a.handleTerminalExited(msg)
+		return a, a.updateLayout()
+
+	case editor.StatusLineMsg:
+		a.status.SetCmdline(msg.Cmdline, msg.Cursor)
+		a.status.SetEditorMessage(msg.Message, msg.Error)
+		return a, nil
+
 	case panel.InfoGotoLineMsg:
-		rpc := a.editor.GetRPC()
-		if rpc != nil {
-			rpc.SetCursorPosition(msg.Line, 0) //nolint:errcheck // best-effort cursor jump
-		}
+		a.editor.GotoLine(msg.Line)
 		a.setFocus(focusEditor)
 		return a, nil
 
@@ -474,30 +923,131 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.setFocus(focusEditor)
 
 	case panel.FinderResultMsg:
+		a.saveFinderHistory()
+		if a.pickingTemplate {
+			a.pickingTemplate = false
+			a.createFromTemplate(msg.Path)
+			a.setFocus(focusEditor)
+			return a, nil
+		}
+		if a.pickingAttachment {
+			a.pickingAttachment = false
+			a.setFocus(focusEditor)
+			return a, a.openAttachment(msg.Path)
+		}
 		a.handleFinderResult(msg.Path, msg.Line)
 		a.setFocus(focusEditor)
 
+	case panel.FinderMultiResultMsg:
+		a.saveFinderHistory()
+		a.pickingTemplate = false
+		if a.pickingAttachment {
+			a.pickingAttachment = false
+			a.setFocus(focusEditor)
+			var cmds []tea.Cmd
+			for _, p := range msg.Paths {
+				cmds = append(cmds, a.openAttachment(p))
+			}
+			return a, tea.Batch(cmds...)
+		}
+		a.handleFinderMultiResult(msg.Paths)
+		a.setFocus(focusEditor)
+
+	case panel.FinderBatchMsg:
+		a.pickingTemplate = false
+		if a.pickingAttachment {
+			a.pickingAttachment = false
+			a.status.SetError("bulk actions work on notes only")
+			a.setFocus(focusEditor)
+			return a, nil
+		}
+		return a, a.handleFinderBatch(msg)
+
+	case panel.FinderQuickfixMsg:
+		if a.pickingTemplate || a.pickingAttachment {
+			a.pickingTemplate = false
+			a.pickingAttachment = false
+			a.setFocus(focusEditor)
+			return a, nil
+		}
+		a.saveFinderHistory()
+		a.setQuickfix(msg.Query, msg.Items)
+		return a, a.updateLayout()
+
+	case panel.QuickfixSelectedMsg:
+		a.jumpToQuickfix(msg.Index)
+		return a, nil
+
+	case panel.FinderActionMsg:
+		a.showFinderActions(msg.Item)
+		return a, nil
+
+	case panel.SavedSearchSelectedMsg:
+		a.saveFinderHistory()
+		return a, a.runSavedSearch(msg.Name, msg.Query)
+
 	case panel.FinderCreateRequestMsg:
+		a.saveFinderHistory()
+		if a.refuseEdit() {
+			return a, nil
+		}
 		// Keep finder visible so cancel returns the user to the same query.
 		a.pendingPrompt = promptAction{kind: "finder-create", path: msg.Name}
 		a.prompt.ShowConfirm(fmt.Sprintf("Create note %q?", msg.Name))
 		return a, nil
 
+	case autolinkFoundMsg:
+		a.handleAutolinkFound(msg)
+		return a, nil
+
+	case panel.FinderPreviewMsg:
+		var cmd tea.Cmd
+		a.finder, cmd = a.finder.Update(msg)
+		return a, cmd
+
 	case panel.FinderClosedMsg:
-		a.finder.SetSearchFunc(a.searchNotes)
+		a.pickingTemplate = false
+		a.pickingAttachment = false
+		a.finder.SetPagedSearchFunc(a.searchNotes)
 		a.finder.SetPreviewFunc(a.previewNote)
 		a.finder.SetTitle("Find Note")
-		a.finder.SetCanCreate(true)
+		a.finder.SetCanCreate(!a.cfg.ReadOnly)
 		a.setFocus(focusEditor)
 
 	case editor.FollowLinkMsg:
-		a.FollowLink()
+		if msg.Target != "" {
+			a.followLinkTarget(msg.Target, msg.Section)
+		} else {
+			a.FollowLink()
+		}
 		return a, nil
 
 	case editor.GoBackMsg:
 		a.GoBack()
 		return a, nil
 
+	case editor.CursorLineMsg:
+		if a.showPreview {
+			a.preview.SyncToLine(msg.Line)
+		}
+		return a, nil
+
+	case editor.TextChangedMsg:
+		a.refreshPreview()
+		a.checkConflicts()
+		if a.blameFile != a.currentFile {
+			return a, a.refreshBlame()
+		}
+		return a, nil
+
+	case blameLoadedMsg:
+		a.applyBlame(msg)
+		return a, nil
+
+	case editor.QuickfixStepMsg:
+		a.stepQuickfix(msg.Delta)
+		return a, nil
+
 	case editor.NoteClosedMsg:
 		// If prompt is already active, upgrade the pending action to "close"
 		// instead of interrupting (e.g. :wq on unnamed sends both
@@ -513,35 +1063,67 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		a.prompt.Show("Save as", "my-note.md")
 		return a, nil
 
+	case editor.BufferModifiedMsg:
+		a.status.SetModified(msg.Modified)
+		return a, nil
+
 	case editor.BufferWrittenMsg:
 		return a, a.handleBufferWritten(msg.Path)
 
+	case editor.FileChangedOnDiskMsg:
+		a.handleFileChangedOnDisk(msg)
+		return a, nil
+
+	case noteWrittenMsg:
+		a.checkOpenNoteOnDisk(msg.path)
+		return a, nil
+
 	case panel.TreeNewNoteMsg:
+		if a.refuseEdit() {
+			return a, nil
+		}
 		a.pendingPrompt = promptAction{kind: "create-note"}
 		a.prompt.Show("New note", "my-note.md")
 		return a, nil
 
 	case panel.TreeDeleteNoteMsg:
+		if a.refuseEdit() || a.refuseExternal(msg.Path) {
+			return a, nil
+		}
 		a.pendingPrompt = promptAction{kind: "delete-note", path: msg.Path}
-		a.prompt.ShowConfirm("Delete " + msg.Name + "?")
+		a.prompt.ShowConfirm("Move " + msg.Name + " to the trash?")
 		return a, nil
 
+	case panel.TreeDuplicateNoteMsg:
+		return a, a.DuplicateNote(msg.Path)
+
 	case panel.TreeRenameNoteMsg:
+		if a.refuseEdit() || a.refuseExternal(msg.Path) {
+			return a, nil
+		}
 		a.pendingPrompt = promptAction{kind: "rename-note", path: msg.Path}
 		a.prompt.Show("Rename", msg.Name)
 		return a, nil
 
 	case panel.TreeDeleteNotesMsg:
+		if a.refuseEdit() || a.refuseExternal(msg.Paths...) {
+			return a, nil
+		}
 		names := make([]string, len(msg.Paths))
 		for i, p := range msg.Paths {
 			names[i] = filepath.Base(p)
 		}
-		label := fmt.Sprintf("Delete %d files (%s)?", len(msg.Paths), strings.Join(names, ", "))
+		label := fmt.Sprintf("Move %d files to the trash (%s)?", len(msg.Paths), strings.Join(names, ", "))
 		a.pendingPrompt = promptAction{kind: "delete-notes", paths: msg.Paths}
 		a.prompt.ShowConfirm(label)
 		return a, nil
 
 	case panel.TreePasteMsg:
+		// Notes may be copied out of an external source, never into one.
+		if a.refuseEdit() || a.refuseExternal(msg.DestDir) ||
+			(msg.Op == panel.ClipboardCut && a.refuseExternal(msg.Sources...)) {
+			return a, nil
+		}
 		return a, a.handlePaste(msg)
 
 	case panel.TreeClipboardChangedMsg:
@@ -554,6 +1136,51 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case panel.PromptCancelledMsg:
 		return a, a.handlePromptCancelled()
 
+	case externalEditDoneMsg:
+		if msg.err != nil {
+			a.status.SetError(fmt.Sprintf("external editor: %v", msg.err))
+		}
+		return a, a.reloadAfterExternalEdit(msg.relPath)
+
+	case indexReconciledMsg:
+		if msg.err != nil {
+			a.status.SetError(fmt.Sprintf("scan for changes: %v", msg.err))
+			return a, nil
+		}
+		if msg.stats.Indexed+msg.stats.Removed == 0 {
+			a.status.SetMessage(fmt.Sprintf("Index up to date (%d notes checked)", msg.stats.Files))
+			return a, nil
+		}
+		a.status.SetMessage(fmt.Sprintf("Scan re-indexed %d and removed %d notes", msg.stats.Indexed, msg.stats.Removed))
+		return a, nil
+
+	case imagePastedMsg:
+		return a, a.handleImagePasted(msg)
+
+	case optimizeTickMsg:
+		return a, a.tidyIndex()
+
+	case indexTidiedMsg:
+		if msg.err != nil {
+			a.status.SetError(fmt.Sprintf("optimize index: %v", msg.err))
+		}
+		return a, a.scheduleOptimize()
+
+	case indexOptimizedMsg:
+		if msg.err != nil {
+			a.status.SetError(fmt.Sprintf("optimize index: %v", msg.err))
+			return a, nil
+		}
+		a.status.SetMessage(fmt.Sprintf("Index optimized: %s to %s",
+			formatSize(msg.stats.SizeBefore), formatSize(msg.stats.SizeAfter)))
+		return a, nil
+
+	case fileManagerDoneMsg:
+		if msg.err != nil {
+			a.status.SetError(fmt.Sprintf("open externally: %v", msg.err))
+		}
+		return a, nil
+
 	case noteIndexedMsg:
 		if msg.err != nil {
 			return a, fatalCmd(fmt.Errorf("index note: %w", msg.err))
@@ -570,21 +1197,36 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return a, nil
 		}
 		if msg.Colors != nil {
-			updated := theme.FromExtracted(msg.Colors, a.theme)
-			a.theme = updated
-			// Re-set pointers since we replaced the struct value.
-			a.tree.SetTheme(&a.theme)
-			a.info.SetTheme(&a.theme)
-			a.finder.SetTheme(&a.theme)
-			a.prompt.SetTheme(&a.theme)
-			a.status.SetTheme(&a.theme)
-			a.whichKey.SetTheme(&a.theme)
-			a.editor.SetTheme(&a.theme)
-			a.contextMenu.SetTheme(&a.theme)
+			a.theme = theme.FromExtracted(msg.Colors, a.theme).ForProfile(a.colorProfile)
+			a.applyTheme()
+		}
+		return a, nil
+
+	case indexProgressMsg:
+		a.indexProgress = msg
+		a.showActivity()
+		return a, waitIndexUpdate(msg.updates)
+
+	case spinner.TickMsg:
+		if a.indexRuns == 0 {
+			return a, nil // stop ticking
+		}
+		var cmd tea.Cmd
+		a.indexSpinner, cmd = a.indexSpinner.Update(msg)
+		a.showActivity()
+		return a, cmd
+
+	case indexRebuiltMsg:
+		a.finishIndexProgress()
+		if msg.err != nil {
+			a.status.SetError(fmt.Sprintf("rebuild search index: %v", msg.err))
+			return a, nil
 		}
+		a.status.SetMessage("Search index rebuilt")
 		return a, nil
 
 	case indexInitDoneMsg:
+		a.finishIndexProgress()
 		if msg.err != nil {
 			// Fail fast and loud: indexing is a core feature.
 			return a, tea.Batch(tea.Printf("fatal: indexing failed: %v\n", msg.err), tea.Quit)
@@ -601,10 +1243,32 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			if err != nil {
 				return a, tea.Batch(tea.Printf("fatal: watcher init failed: %v\n", err), tea.Quit)
 			}
+			w.SetPollingHook(func(err error) {
+				if a.program != nil {
+					a.program.Send(watcherPollingMsg{err: err})
+				}
+			})
+			w.SetQueueHook(func(queued int, paused bool) {
+				if a.program != nil {
+					a.program.Send(watcherQueueMsg{queued: queued, paused: paused})
+				}
+			})
+			w.SetWriteHook(func(path string) {
+				if a.program != nil {
+					a.program.Send(noteWrittenMsg{path: path})
+				}
+			})
+			w.SetReconcileInterval(a.cfg.ReconcileInterval)
+			// A missing source shouldn't stop the vault from being watched.
+			for _, src := range a.vault.Sources {
+				if err := w.Watch(src.Root); err != nil {
+					a.status.SetError(fmt.Sprintf("watch external source %s: %v", src.Name, err))
+				}
+			}
 			a.watcher = w
 			go w.Start()
 		}
-		return a, nil
+		return a, a.scheduleOptimize()
 	}
 
 	// Route key events based on focus
@@ -616,14 +1280,24 @@ func (a *App) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.tree, cmd = a.tree.Update(msg)
 			return a, cmd
 		case focusInfo:
+			if a.showPreview {
+				a.preview, cmd = a.preview.Update(msg)
+				return a, cmd
+			}
 			a.info, cmd = a.info.Update(msg)
 			return a, cmd
+		case focusTerminal:
+			a.terminal, cmd = a.terminal.Update(msg)
+			return a, cmd
 		default:
 			a.editor, cmd = a.editor.Update(msg)
 			return a, cmd
 		}
 	default:
 		a.editor, cmd = a.editor.Update(msg)
+		var termCmd tea.Cmd
+		a.terminal, termCmd = a.terminal.Update(msg)
+		cmd = tea.Batch(cmd, termCmd)
 	}
 
 	return a, cmd
@@ -653,7 +1327,7 @@ func (a *App) View() string {
 	}
 
 	showTree, showInfo := a.panelsVisible()
-	layout := ComputeLayout(a.width, a.height, showTree, showInfo, a.cfg.TreeWidth, a.cfg.InfoWidth)
+	layout := ComputeLayout(a.width, a.height, showTree, showInfo, a.showTerminal, a.cfg.TreeWidth, a.rightPanelWidth())
 
 	// Editor title row
 	editorTitle := a.editorTitle()
@@ -675,9 +1349,7 @@ func (a *App) View() string {
 			if tw < 0 {
 				tw = 0
 			}
-			borderStyle := lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder(), false, true, false, false).
-				BorderForeground(a.theme.Border).
+			borderStyle := a.panelBorderStyle(false, true).
 				Width(tw).
 				Height(layout.Height)
 			columns = append(columns, borderStyle.Render(a.tree.View()))
@@ -693,17 +1365,27 @@ func (a *App) View() string {
 			if iw < 0 {
 				iw = 0
 			}
-			borderStyle := lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder(), false, false, false, true).
-				BorderForeground(a.theme.Border).
+			borderStyle := a.panelBorderStyle(true, false).
 				Width(iw).
 				Height(layout.Height)
-			columns = append(columns, borderStyle.Render(a.info.View()))
+			right := a.info.View()
+			if a.showPreview {
+				right = a.preview.View()
+			}
+			columns = append(columns, borderStyle.Render(right))
 		}
 
 		main = lipgloss.JoinHorizontal(lipgloss.Top, columns...)
 	}
 
+	if layout.TerminalHeight > 0 {
+		main += "\n" + lipgloss.NewStyle().
+			Width(a.width).
+			Height(layout.TerminalHeight).
+			MaxHeight(layout.TerminalHeight).
+			Render(a.paneTitle("Terminal", a.focused == focusTerminal)+"\n"+a.terminal.View())
+	}
+
 	result := main + "\n" + a.status.View()
 
 	// Overlay which-key popup
@@ -714,6 +1396,62 @@ func (a *App) View() string {
 		}
 	}
 
+	// Overlay habit tracker
+	if a.habits.Visible() {
+		habitView := a.habits.View()
+		if habitView != "" {
+			result = overlayCenter(result, habitView, a.width, a.height)
+		}
+	}
+
+	// Overlay change preview
+	if a.changes.Visible() {
+		changesView := a.changes.View()
+		if changesView != "" {
+			result = overlayCenter(result, changesView, a.width, a.height)
+		}
+	}
+
+	// Overlay note history
+	if a.noteHistory.Visible() {
+		historyView := a.noteHistory.View()
+		if historyView != "" {
+			result = overlayCenter(result, historyView, a.width, a.height)
+		}
+	}
+
+	// Overlay trash
+	if a.trash.Visible() {
+		trashView := a.trash.View()
+		if trashView != "" {
+			result = overlayCenter(result, trashView, a.width, a.height)
+		}
+	}
+
+	// Overlay inbox triage
+	if a.triage.Visible() {
+		triageView := a.triage.View()
+		if triageView != "" {
+			result = overlayCenter(result, triageView, a.width, a.height)
+		}
+	}
+
+	// Overlay stale note review
+	if a.review.Visible() {
+		reviewView := a.review.View()
+		if reviewView != "" {
+			result = overlayCenter(result, reviewView, a.width, a.height)
+		}
+	}
+
+	// Overlay query console
+	if a.console.Visible() {
+		consoleView := a.console.View()
+		if consoleView != "" {
+			result = overlayCenter(result, consoleView, a.width, a.height)
+		}
+	}
+
 	// Overlay finder
 	if a.finder.Visible() {
 		finderView := a.finder.View()
@@ -743,13 +1481,19 @@ func (a *App) View() string {
 }
 
 func (a *App) Close() {
+	if a.closed {
+		return
+	}
+	a.closed = true
+
 	// Save session state
 	if a.store != nil {
 		state := session.State{
-			ShowTree:  a.showTree,
-			ShowInfo:  a.showInfo,
-			TreeWidth: a.cfg.TreeWidth,
-			InfoWidth: a.cfg.InfoWidth,
+			ShowTree:   a.showTree,
+			ShowInfo:   a.showInfo,
+			TreeWidth:  a.cfg.TreeWidth,
+			InfoWidth:  a.cfg.InfoWidth,
+			FinderSort: a.finder.Sort().String(),
 		}
 		if err := a.store.Save(state); err != nil {
 			fmt.Fprintln(os.Stderr, "fatal: save session state:", err)
@@ -757,12 +1501,17 @@ func (a *App) Close() {
 	}
 
 	a.editor.Close()
+	a.terminal.Close()
 	if a.watcher != nil {
 		if err := a.watcher.Stop(); err != nil {
 			fmt.Fprintln(os.Stderr, "fatal: stop watcher:", err)
 		}
 	}
 	if a.db != nil {
+		// A light pass keeps a long-lived index from fragmenting and growing.
+		if _, err := a.db.Optimize(false); err != nil {
+			fmt.Fprintln(os.Stderr, "optimize index:", err)
+		}
 		if err := a.db.Close(); err != nil {
 			fmt.Fprintln(os.Stderr, "fatal: close db:", err)
 		}
@@ -801,6 +1550,18 @@ func (a *App) handleBufferWritten(path string) tea.Cmd {
 	if a.indexer != nil && strings.HasSuffix(strings.ToLower(path), ".md") {
 		cmds = append(cmds, a.indexFile(path))
 	}
+	if a.currentFile != "" && path == filepath.Join(a.cfg.VaultPath, a.currentFile) {
+		if cmd := a.refreshBlame(); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
+
+	// Optional: offer to link plain-text mentions of other notes.
+	if a.cfg.AutoLinkOnSave && a.currentFile != "" && path == filepath.Join(a.cfg.VaultPath, a.currentFile) {
+		if cmd := a.findMentions(true); cmd != nil {
+			cmds = append(cmds, cmd)
+		}
+	}
 
 	// Optional: format on save (scoped to the active buffer).
 	if !a.cfg.AutoFormatOnSave {
@@ -827,59 +1588,24 @@ func (a *App) handleBufferWritten(path string) tea.Cmd {
 	// Only format the currently active file.
 	cur, err := rpc.CurrentFile()
 	if err != nil {
-		return fatalCmd(fmt.Errorf("nvim current file: %w", err))
-	}
-	if cur != path {
-		if len(cmds) == 0 {
-			return nil
-		}
-		return tea.Batch(cmds...)
-	}
-
-	formatCmd := func() tea.Msg {
-		// Capture cursor so we can keep the user's position.
-		line, col, err := rpc.CursorPosition()
-		if err != nil {
-			return fatalErrorMsg{err: fmt.Errorf("nvim cursor position: %w", err)}
-		}
-
-		content, err := rpc.BufferContent()
-		if err != nil {
-			return fatalErrorMsg{err: fmt.Errorf("nvim buffer content: %w", err)}
-		}
-
-		var b strings.Builder
-		for i, ln := range content {
-			b.Write(ln)
-			if i < len(content)-1 {
-				b.WriteByte('\n')
-			}
-		}
-
-		formatted := markdown.Format([]byte(b.String()))
-		if string(formatted) == b.String()+"\n" || string(formatted) == b.String() {
+		return fatalCmd(fmt.Errorf("nvim current file: %w", err))
+	}
+	if cur != path {
+		if len(cmds) == 0 {
 			return nil
 		}
+		return tea.Batch(cmds...)
+	}
 
-		// Apply formatted text back into the buffer.
-		text := strings.TrimRight(string(formatted), "\n")
-		lines := []string{}
-		if text != "" {
-			lines = strings.Split(text, "\n")
-		}
-		if err := rpc.SetBufferLines(lines); err != nil {
-			return fatalErrorMsg{err: fmt.Errorf("nvim set buffer lines: %w", err)}
-		}
-
-		// Restore cursor (best-effort; clamp line to buffer length).
-		if line < 1 {
-			line = 1
+	scope := a.cfg.AutoFormatScope
+	formatCmd := func() tea.Msg {
+		changed, err := formatBuffer(rpc, scope)
+		if err != nil {
+			return fatalErrorMsg{err: err}
 		}
-		if len(lines) > 0 && line > len(lines) {
-			line = len(lines)
+		if !changed {
+			return nil
 		}
-		// Best-effort cursor restoration after format.
-		rpc.SetCursorPosition(line, col) //nolint:errcheck
 
 		// Write without triggering autocommands to avoid infinite loops.
 		if err := rpc.ExecCommand("noautocmd write"); err != nil {
@@ -892,6 +1618,58 @@ func (a *App) handleBufferWritten(path string) tea.Cmd {
 	return tea.Batch(cmds...)
 }
 
+// formatBuffer runs the Markdown formatter over the current buffer and
+// reports whether it changed anything. With scope "buffer" the formatted
+// text replaces the whole buffer; otherwise only the changed text is
+// replaced, so marks, folds and extmarks elsewhere stay put.
+func formatBuffer(rpc *editor.RPC, scope string) (bool, error) {
+	content, err := rpc.BufferContent()
+	if err != nil {
+		return false, fmt.Errorf("nvim buffer content: %w", err)
+	}
+	old := make([]string, len(content))
+	for i, ln := range content {
+		old[i] = string(ln)
+	}
+	text := strings.Join(old, "\n")
+
+	formatted := markdown.Format([]byte(text))
+	if string(formatted) == text+"\n" || string(formatted) == text {
+		return false, nil
+	}
+	text = strings.TrimRight(string(formatted), "\n")
+	lines := []string{}
+	if text != "" {
+		lines = strings.Split(text, "\n")
+	}
+
+	if scope != "buffer" {
+		if err := rpc.ApplyEdits(markdown.Edits(old, lines)); err != nil {
+			return false, fmt.Errorf("nvim apply formatting: %w", err)
+		}
+		return true, nil
+	}
+
+	// Capture cursor so we can keep the user's position.
+	line, col, err := rpc.CursorPosition()
+	if err != nil {
+		return false, fmt.Errorf("nvim cursor position: %w", err)
+	}
+	if err := rpc.SetBufferLines(lines); err != nil {
+		return false, fmt.Errorf("nvim set buffer lines: %w", err)
+	}
+
+	// Restore cursor (best-effort; clamp line to buffer length).
+	if line < 1 {
+		line = 1
+	}
+	if len(lines) > 0 && line > len(lines) {
+		line = len(lines)
+	}
+	rpc.SetCursorPosition(line, col) //nolint:errcheck
+	return true, nil
+}
+
 // showSplash transitions the editor to the splash screen.
 func (a *App) showSplash() {
 	rpc := a.editor.GetRPC()
@@ -907,10 +1685,21 @@ func (a *App) showSplash() {
 	a.status.SetFile("")
 	a.currentFile = ""
 	a.info.Clear()
+	a.preview.Clear()
 	a.setFocus(focusEditor)
 	a.updateLayout()
 }
 
+// refuseEdit reports whether the session is read-only, showing why the
+// change the user asked for was not made.
+func (a *App) refuseEdit() bool {
+	if !a.cfg.ReadOnly {
+		return false
+	}
+	a.status.SetError("read-only mode: notes can't be changed")
+	return true
+}
+
 // openInEditor opens a file and recalculates layout since splash is dismissed.
 func (a *App) openInEditor(path string) {
 	if err := a.editor.OpenFile(path); err != nil {
@@ -922,6 +1711,30 @@ func (a *App) openInEditor(path string) {
 	a.updateLayout()
 }
 
+// openReadOnly is openInEditor for notes in external sources, which
+// can't be modified.
+func (a *App) openReadOnly(path string) {
+	if err := a.editor.OpenFileReadOnly(path); err != nil {
+		if a.program != nil {
+			a.program.Send(fatalErrorMsg{err: err})
+		}
+		return
+	}
+	a.updateLayout()
+}
+
+// refuseExternal reports an error and returns true when any of paths lies
+// in a read-only external source.
+func (a *App) refuseExternal(paths ...string) bool {
+	for _, p := range paths {
+		if vault.IsExternal(p) {
+			a.status.SetError(fmt.Sprintf("%s is in a read-only external source", p))
+			return true
+		}
+	}
+	return false
+}
+
 // handlePromptCancelled handles Esc/empty input on the overlay prompt.
 func (a *App) handlePromptCancelled() tea.Cmd {
 	action := a.pendingPrompt
@@ -930,6 +1743,9 @@ func (a *App) handlePromptCancelled() tea.Cmd {
 	if action.kind == "close" {
 		a.showSplash()
 	}
+	if action.kind == "autolink" {
+		a.declineAutolinks(action.path, action.targets)
+	}
 	return nil
 }
 
@@ -983,6 +1799,73 @@ func (a *App) handlePromptResult(value string) tea.Cmd {
 		a.finder.Hide()
 		a.setFocus(focusEditor)
 		return nil
+	case "follow-pick":
+		a.pendingPrompt = promptAction{}
+		a.prompt.Hide()
+		a.handleFollowPick(action, value)
+		return nil
+	case "follow-create":
+		a.pendingPrompt = promptAction{}
+		a.prompt.Hide()
+		a.handleFollowCreate(action, value)
+		return nil
+	case "random-scope":
+		if a.openRandomNote(strings.TrimSpace(value)) {
+			a.prompt.Hide()
+			a.pendingPrompt = promptAction{}
+		}
+		return nil
+	case "replace-find":
+		a.pendingPrompt = promptAction{kind: "replace-with", find: value}
+		a.prompt.Show(fmt.Sprintf("Replace %q with", value), "replacement text")
+		return nil
+	case "replace-with":
+		if cmd, ok := a.handleReplacePrompt(action.find, value); ok {
+			a.prompt.Hide()
+			a.pendingPrompt = promptAction{}
+			return cmd
+		}
+		return nil
+	case "triage-move", "triage-tag":
+		if cmd, ok := a.handleTriagePrompt(action, value); ok {
+			a.prompt.Hide()
+			a.pendingPrompt = promptAction{}
+			return cmd
+		}
+		return nil
+	case "resolve-conflicts":
+		a.pendingPrompt = promptAction{}
+		a.prompt.Hide()
+		a.resolveAllConflicts(value)
+		return nil
+	case "restore-revision":
+		a.pendingPrompt = promptAction{}
+		a.prompt.Hide()
+		if strings.ToLower(strings.TrimSpace(value)) != "yes" {
+			return nil
+		}
+		return a.restoreRevision(action.path, action.commit)
+	case "disk-change":
+		a.pendingPrompt = promptAction{}
+		a.prompt.Hide()
+		a.handleDiskChangePrompt(action, value)
+		return nil
+	case "purge-trash":
+		a.pendingPrompt = promptAction{}
+		a.prompt.Hide()
+		if strings.ToLower(strings.TrimSpace(value)) != "yes" {
+			return nil
+		}
+		a.purgeTrash(action.trash)
+		return nil
+	case "autolink":
+		a.pendingPrompt = promptAction{}
+		a.prompt.Hide()
+		if strings.ToLower(strings.TrimSpace(value)) != "yes" {
+			a.declineAutolinks(action.path, action.targets)
+			return nil
+		}
+		return a.applyAutolinks(action.path, action.targets)
 	}
 	return nil
 }
@@ -1092,14 +1975,29 @@ func (a *App) handleCreateNotePrompt(name string) (cmd tea.Cmd, ok bool) {
 
 // handlePaste performs copy or move for files in the clipboard.
 func (a *App) handlePaste(msg panel.TreePasteMsg) tea.Cmd {
-	// Copy is disallowed because it would violate the vault-wide basename uniqueness invariant.
-	if msg.Op == panel.ClipboardCopy {
+	// Copy is disallowed with vault-wide basenames because the copy would
+	// share its source's name.
+	if msg.Op == panel.ClipboardCopy && !a.folderScoped() {
 		a.status.SetError("copy not allowed: vault requires unique basenames")
 		return nil
 	}
 
+	// Notes moved before a failure are still logged, so undo can put them back.
+	var moved []fileMove
+	defer func() { a.recordMoves(moved) }()
 	for _, src := range msg.Sources {
 		newRel := filepath.Join(msg.DestDir, filepath.Base(src))
+		if msg.Op == panel.ClipboardCopy {
+			if m := a.checkUniqueBasename(newRel); m != "" {
+				a.status.SetError(m)
+				return nil
+			}
+			if err := a.vault.CopyNote(src, msg.DestDir); err != nil {
+				a.status.SetError(err.Error())
+				return nil
+			}
+			continue
+		}
 		if m := a.checkUniqueBasenameExcept(newRel, src); m != "" {
 			a.status.SetError(m)
 			return nil
@@ -1110,20 +2008,10 @@ func (a *App) handlePaste(msg panel.TreePasteMsg) tea.Cmd {
 			a.status.SetError(err.Error())
 			return nil
 		}
+		moved = append(moved, fileMove{from: src, to: newRel})
 
-		if a.currentFile == src {
-			fullPath := filepath.Join(a.cfg.VaultPath, newRel)
-			rpc := a.editor.GetRPC()
-			if rpc != nil {
-				if err := rpc.SetBufferName(fullPath); err != nil {
-					return fatalCmd(err)
-				}
-				if err := rpc.WriteBuffer(); err != nil {
-					return fatalCmd(err)
-				}
-			}
-			a.status.SetFile(newRel)
-			a.currentFile = newRel
+		if cmd := a.followMovedBuffer(src, newRel); cmd != nil {
+			return cmd
 		}
 	}
 
@@ -1156,9 +2044,11 @@ func (a *App) handleDeleteNote(confirmation, relPath string) tea.Cmd {
 		a.showSplash()
 	}
 
-	if err := a.vault.DeleteNote(relPath); err != nil {
+	item, err := a.vault.DeleteNote(relPath)
+	if err != nil {
 		return fatalCmd(err)
 	}
+	a.recordFileOp(fileOp{kind: "delete", trashed: []vault.TrashItem{item}})
 	a.tree.ClearSelected()
 	a.tree.Refresh()
 	return nil
@@ -1170,14 +2060,18 @@ func (a *App) handleDeleteNotes(confirmation string, paths []string) tea.Cmd {
 		return nil
 	}
 
+	var trashed []vault.TrashItem
 	for _, p := range paths {
 		if a.currentFile == p {
 			a.showSplash()
 		}
-		if err := a.vault.DeleteNote(p); err != nil {
+		item, err := a.vault.DeleteNote(p)
+		if err != nil {
 			return fatalCmd(err)
 		}
+		trashed = append(trashed, item)
 	}
+	a.recordFileOp(fileOp{kind: "delete", trashed: trashed})
 
 	a.tree.ClearSelected()
 	a.tree.Refresh()
@@ -1203,9 +2097,30 @@ func (a *App) handleRenameNotePrompt(newName, oldPath string) (cmd tea.Cmd, ok b
 		return nil, false
 	}
 
-	// Reuse the existing implementation (it already handles link rewriting and editor updates).
-	cmd = a.handleRenameNote(newName, oldPath)
-	// If the underlying rename failed, it currently returns nil without surfacing an error.
+	// Preview link rewrites before touching anything; the rename is applied
+	// from the change preview overlay.
+	oldBasename := strings.TrimSuffix(filepath.Base(oldPath), ".md")
+	newBasename := strings.TrimSuffix(filepath.Base(newRel), ".md")
+	if oldBasename != newBasename {
+		rewrites, err := a.planRenameRewrites(oldPath, newBasename)
+		if err != nil {
+			a.prompt.SetError(err.Error())
+			return nil, false
+		}
+		if len(rewrites) > 0 {
+			files, err := a.changeFiles(rewrites)
+			if err != nil {
+				a.prompt.SetError(err.Error())
+				return nil, false
+			}
+			a.pendingChanges = pendingChanges{kind: "rename", oldPath: oldPath, newRel: newRel}
+			a.changes.Show(fmt.Sprintf("Rename %s → %s", oldPath, newRel), files)
+			return nil, true
+		}
+	}
+
+	cmd = a.commitRename(oldPath, newRel, nil)
+	// If the underlying rename failed, it returns without surfacing an error.
 	// Detect obvious failure by checking filesystem state.
 	if _, err := os.Stat(filepath.Join(a.cfg.VaultPath, newRel)); err != nil {
 		a.prompt.SetError("rename failed")
@@ -1214,63 +2129,66 @@ func (a *App) handleRenameNotePrompt(newName, oldPath string) (cmd tea.Cmd, ok b
 	return cmd, true
 }
 
-// handleRenameNote renames a note to the given name.
-func (a *App) handleRenameNote(newName, oldPath string) tea.Cmd {
-	newRel := newName
-	if !strings.HasSuffix(newRel, ".md") {
-		newRel += ".md"
-	}
-
-	// Keep the same directory
-	dir := filepath.Dir(oldPath)
-	if dir != "." {
-		newRel = filepath.Join(dir, newRel)
-	}
-
-	if msg := a.checkUniqueBasename(newRel); msg != "" {
-		a.status.SetError(msg)
-		return nil
+// commitRename renames oldPath to newRel and rewrites links to the old name.
+// include limits the rewrite to the given (post-rename) relative paths; nil
+// rewrites every referring note.
+func (a *App) commitRename(oldPath, newRel string, include map[string]bool) tea.Cmd {
+	cmd, ok := a.renameNote(oldPath, newRel, include)
+	if ok {
+		a.recordFileOp(fileOp{kind: "rename", moves: []fileMove{{from: oldPath, to: newRel}}})
 	}
+	return cmd
+}
 
+// renameNote does the work of commitRename, reporting whether the rename
+// happened. Undo uses it to rename back without logging another operation.
+func (a *App) renameNote(oldPath, newRel string, include map[string]bool) (tea.Cmd, bool) {
 	// Capture old basename for link rewriting before rename
 	oldBasename := strings.TrimSuffix(filepath.Base(oldPath), ".md")
 	newBasename := strings.TrimSuffix(filepath.Base(newRel), ".md")
 
-	// Get backlinks before rename (while DB still has old data)
-	var backlinkPaths []string
-	if oldBasename != newBasename && a.db != nil {
-		backlinks, err := a.db.GetBacklinks(oldPath)
-		if err == nil {
-			for _, bl := range backlinks {
-				backlinkPaths = append(backlinkPaths, bl.SourcePath)
-			}
-		}
-	}
-
 	if err := a.vault.RenameNote(oldPath, newRel); err != nil {
-		return nil
+		a.status.SetError(fmt.Sprintf("rename failed: %v", err))
+		return nil, false
 	}
 
-	// Rewrite wiki links in all notes that linked to the old name
+	// Rewrite wiki links, embeds and markdown links in every note that refers
+	// to the old name. Markdown links aren't indexed as backlinks, so scan the
+	// vault. All rewrites commit together; on failure they are rolled back and
+	// the rename is undone so the vault is left as it was.
+	newAbs := filepath.Join(a.cfg.VaultPath, newRel)
+	changed := []string{newAbs}
 	if oldBasename != newBasename {
-		for _, srcPath := range backlinkPaths {
-			absPath := filepath.Join(a.cfg.VaultPath, srcPath)
-			if _, err := vault.RewriteLinksInNote(absPath, oldBasename, newBasename); err != nil {
-				return fatalCmd(err)
+		rewrites, err := a.planRenameRewrites(oldPath, newBasename)
+		if err == nil && include != nil {
+			rewrites = a.filterRewrites(rewrites, include)
+		}
+		if err == nil {
+			err = vault.ApplyRewrites(rewrites)
+		}
+		if err != nil {
+			if undoErr := a.vault.RenameNote(newRel, oldPath); undoErr != nil {
+				return fatalCmd(errors.Join(fmt.Errorf("rewrite links: %w", err), fmt.Errorf("undo rename: %w", undoErr))), false
+			}
+			a.status.SetError(fmt.Sprintf("rename failed, vault unchanged: %v", err))
+			return nil, false
+		}
+		for _, rw := range rewrites {
+			if rw.Path != newAbs {
+				changed = append(changed, rw.Path)
 			}
 		}
 	}
 
 	// If the renamed file is currently open, update the editor
 	if a.currentFile == oldPath {
-		fullPath := filepath.Join(a.cfg.VaultPath, newRel)
 		rpc := a.editor.GetRPC()
 		if rpc != nil {
-			if err := rpc.SetBufferName(fullPath); err != nil {
-				return fatalCmd(err)
+			if err := rpc.SetBufferName(newAbs); err != nil {
+				return fatalCmd(err), false
 			}
 			if err := rpc.WriteBuffer(); err != nil {
-				return fatalCmd(err)
+				return fatalCmd(err), false
 			}
 		}
 		a.status.SetFile(newRel)
@@ -1278,9 +2196,177 @@ func (a *App) handleRenameNote(newName, oldPath string) tea.Cmd {
 	}
 
 	a.tree.Refresh()
+	return a.reindexFiles(a.currentFile, []string{filepath.Join(a.cfg.VaultPath, oldPath)}, changed), true
+}
+
+// filterRewrites keeps the rewrites whose vault-relative path is in include.
+func (a *App) filterRewrites(rewrites []vault.FileRewrite, include map[string]bool) []vault.FileRewrite {
+	var kept []vault.FileRewrite
+	for _, rw := range rewrites {
+		rel, err := filepath.Rel(a.cfg.VaultPath, rw.Path)
+		if err == nil && include[rel] {
+			kept = append(kept, rw)
+		}
+	}
+	return kept
+}
+
+// changeFiles builds the change preview for planned rewrites.
+func (a *App) changeFiles(rewrites []vault.FileRewrite) ([]panel.ChangeFile, error) {
+	files := make([]panel.ChangeFile, 0, len(rewrites))
+	for _, rw := range rewrites {
+		original, err := os.ReadFile(rw.Path)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(a.cfg.VaultPath, rw.Path)
+		if err != nil {
+			rel = rw.Path
+		}
+		f := panel.ChangeFile{Path: rel}
+		for _, c := range vault.ChangedLines(original, rw.Content) {
+			f.Lines = append(f.Lines, panel.ChangeLine{Line: c.Line, Old: c.Old, New: c.New})
+		}
+		files = append(files, f)
+	}
+	return files, nil
+}
+
+// applyPendingChanges runs the bulk operation confirmed in the change preview
+// on the files the user kept.
+func (a *App) applyPendingChanges(included []string) tea.Cmd {
+	pending := a.pendingChanges
+	a.pendingChanges = pendingChanges{}
+
+	switch pending.kind {
+	case "rename":
+		// The preview listed pre-rename paths; the renamed note moves.
+		include := make(map[string]bool, len(included))
+		for _, p := range included {
+			if p == pending.oldPath {
+				p = pending.newRel
+			}
+			include[p] = true
+		}
+		return a.commitRename(pending.oldPath, pending.newRel, include)
+	case "replace":
+		include := make(map[string]bool, len(included))
+		for _, p := range included {
+			include[p] = true
+		}
+		return a.commitReplace(pending.find, pending.replace, include)
+	}
 	return nil
 }
 
+// OpenRandomNoteIn asks for a tag or folder and opens a random note in it.
+func (a *App) OpenRandomNoteIn() {
+	a.pendingPrompt = promptAction{kind: "random-scope"}
+	a.prompt.Show("Random note from", "tag:name, #tag or folder/")
+}
+
+// FindReplace starts a vault-wide find & replace: it asks for the text and
+// its replacement, then shows every affected line in the change preview.
+func (a *App) FindReplace() {
+	a.pendingPrompt = promptAction{kind: "replace-find"}
+	a.prompt.Show("Find in vault", "text to replace")
+}
+
+// handleReplacePrompt previews replacing find with replace across the vault.
+// Returns ok=false when there is nothing to replace and the prompt should
+// remain visible.
+func (a *App) handleReplacePrompt(find, replace string) (cmd tea.Cmd, ok bool) {
+	// Write the open note first so the preview and the rewrite see what is
+	// in the editor.
+	if rpc := a.editor.GetRPC(); rpc != nil && a.currentFile != "" {
+		if err := rpc.ExecCommand("silent update"); err != nil {
+			a.prompt.SetError(fmt.Sprintf("save %s: %v", a.currentFile, err))
+			return nil, false
+		}
+	}
+
+	rewrites, err := a.planReplaceRewrites(find, replace)
+	if err != nil {
+		a.prompt.SetError(err.Error())
+		return nil, false
+	}
+	if len(rewrites) == 0 {
+		a.prompt.SetError(fmt.Sprintf("%q not found", find))
+		return nil, false
+	}
+	files, err := a.changeFiles(rewrites)
+	if err != nil {
+		a.prompt.SetError(err.Error())
+		return nil, false
+	}
+	a.pendingChanges = pendingChanges{kind: "replace", find: find, replace: replace}
+	a.changes.Show(fmt.Sprintf("Replace %q → %q", find, replace), files)
+	return nil, true
+}
+
+// commitReplace replaces find with replace in the included notes (relative
+// paths) as one atomic rewrite, reloads the open note if it changed and
+// reindexes the rest.
+func (a *App) commitReplace(find, replace string, include map[string]bool) tea.Cmd {
+	rewrites, err := a.planReplaceRewrites(find, replace)
+	if err == nil {
+		rewrites = a.filterRewrites(rewrites, include)
+		err = vault.ApplyRewrites(rewrites)
+	}
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("replace failed, vault unchanged: %v", err))
+		return nil
+	}
+
+	changed := make([]string, 0, len(rewrites))
+	currentAbs := filepath.Join(a.cfg.VaultPath, a.currentFile)
+	for _, rw := range rewrites {
+		changed = append(changed, rw.Path)
+		if a.currentFile != "" && rw.Path == currentAbs {
+			a.reloadCurrentNote()
+		}
+	}
+	a.status.SetMessage(fmt.Sprintf("Replaced %q in %d note(s)", find, len(rewrites)))
+	return a.reindexFiles(a.currentFile, nil, changed)
+}
+
+// planReplaceRewrites computes the replacement for every note in the vault.
+func (a *App) planReplaceRewrites(find, replace string) ([]vault.FileRewrite, error) {
+	notes, err := a.vault.ListNotes()
+	if err != nil {
+		return nil, fmt.Errorf("list notes: %w", err)
+	}
+	paths := make([]string, len(notes))
+	for i, n := range notes {
+		paths[i] = filepath.Join(a.cfg.VaultPath, n.Path)
+	}
+	return vault.PlanReplace(paths, find, replace)
+}
+
+// planRenameRewrites computes, for every note in the vault, the link
+// rewrites for renaming the note at oldPath to newBasename. Call it before
+// the index sees the rename.
+func (a *App) planRenameRewrites(oldPath, newBasename string) ([]vault.FileRewrite, error) {
+	notes, err := a.vault.ListNotes()
+	if err != nil {
+		return nil, fmt.Errorf("list notes: %w", err)
+	}
+	paths := make([]string, len(notes))
+	for i, n := range notes {
+		paths[i] = filepath.Join(a.cfg.VaultPath, n.Path)
+	}
+	oldBasename := strings.TrimSuffix(filepath.Base(oldPath), ".md")
+	if a.folderScoped() {
+		// Bare [[name]] links may mean another note sharing the name.
+		resolved, err := a.db.ResolveLink(oldBasename + ".md")
+		if err != nil {
+			return nil, fmt.Errorf("resolve %s: %w", oldBasename, err)
+		}
+		return vault.PlanScopedLinkRewrites(a.cfg.VaultPath, paths, oldPath, newBasename, resolved == oldPath)
+	}
+	return vault.PlanLinkRewrites(paths, oldBasename, newBasename)
+}
+
 // handleContextMenuResult dispatches context menu actions to the appropriate handlers.
 func (a *App) handleContextMenuResult(action string) tea.Cmd {
 	switch action {
@@ -1307,6 +2393,10 @@ func (a *App) handleContextMenuResult(action string) tea.Cmd {
 				return panel.TreeRenameNoteMsg{Path: entry.Path, Name: entry.Name}
 			}
 		}
+	case "tree-duplicate":
+		if entry, ok := a.tree.EntryAt(a.contextMenuTreeIdx); ok && !entry.IsDir {
+			return a.DuplicateNote(entry.Path)
+		}
 	case "tree-delete":
 		if entry, ok := a.tree.EntryAt(a.contextMenuTreeIdx); ok {
 			path := entry.Path
@@ -1319,6 +2409,11 @@ func (a *App) handleContextMenuResult(action string) tea.Cmd {
 	// Info panel operations
 	case "info-open", "info-goto":
 		return a.info.ActivateRow(a.contextMenuInfoIdx)
+
+	// Finder result operations
+	case "finder-open", "finder-rename", "finder-duplicate", "finder-move", "finder-delete",
+		"finder-copy-path", "finder-copy-link", "finder-reveal", "finder-backlinks":
+		return a.handleFinderAction(action, a.finderActionItem)
 	}
 	return nil
 }
@@ -1380,7 +2475,7 @@ func (a *App) selectAllText() tea.Cmd {
 
 func (a *App) panelsVisible() (bool, bool) {
 	splash := a.editor.ShowSplash()
-	return a.showTree && !a.zenMode && !splash, a.showInfo && !a.zenMode && !splash
+	return a.showTree && !a.zenMode && !splash, (a.showInfo || a.showPreview) && !a.zenMode && !splash
 }
 
 func (a *App) minWindowSize() (minW, minH int) {
@@ -1393,10 +2488,11 @@ func (a *App) minWindowSize() (minW, minH int) {
 
 func (a *App) updateLayout() tea.Cmd {
 	showTree, showInfo := a.panelsVisible()
-	layout := ComputeLayout(a.width, a.height, showTree, showInfo, a.cfg.TreeWidth, a.cfg.InfoWidth)
+	layout := ComputeLayout(a.width, a.height, showTree, showInfo, a.showTerminal, a.cfg.TreeWidth, a.rightPanelWidth())
 
 	a.tree.SetSize(layout.TreeWidth, layout.Height)
 	a.info.SetSize(layout.InfoWidth, layout.Height)
+	a.preview.SetSize(layout.InfoWidth, layout.Height)
 	a.status.SetWidth(a.width)
 	a.whichKey.SetWidth(a.width / 2)
 
@@ -1408,11 +2504,28 @@ func (a *App) updateLayout() tea.Cmd {
 		Width:  layout.EditorWidth,
 		Height: editorHeight,
 	}
+	if layout.TerminalHeight > 0 {
+		if err := a.terminal.SetSize(a.width, layout.TerminalHeight-1); err != nil {
+			a.status.SetError(fmt.Sprintf("resize terminal: %v", err))
+		}
+	}
 	var cmd tea.Cmd
 	a.editor, cmd = a.editor.Update(editorSize)
 	return cmd
 }
 
+// panelBorderStyle returns the divider style for a side panel with a border
+// on its left and/or right. Low-bandwidth sessions get an uncolored ASCII
+// divider, which is cheaper to redraw than a colored box-drawing one.
+func (a *App) panelBorderStyle(left, right bool) lipgloss.Style {
+	if a.lowBandwidth {
+		return lipgloss.NewStyle().Border(lipgloss.ASCIIBorder(), false, right, false, left)
+	}
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder(), false, right, false, left).
+		BorderForeground(a.theme.Border)
+}
+
 func (a *App) updateWhichKey() {
 	if !a.leader.showHelp || a.leader.node == nil {
 		a.whichKey.Clear()
@@ -1434,9 +2547,14 @@ func (a *App) editorTitle() string {
 	if !a.editor.ShowSplash() && a.currentFile != "" {
 		title = filepath.Base(a.currentFile)
 	}
+	return a.paneTitle(title, a.focused == focusEditor)
+}
 
+// paneTitle renders the title row of the editor or the terminal pane,
+// highlighted while the pane has focus.
+func (a *App) paneTitle(title string, focused bool) string {
 	var style lipgloss.Style
-	if a.focused == focusEditor {
+	if focused {
 		style = lipgloss.NewStyle().
 			Bold(true).
 			Foreground(a.theme.Accent).
@@ -1455,7 +2573,9 @@ func (a *App) editorTitle() string {
 func (a *App) setFocus(target focusedPanel) {
 	a.tree.SetFocused(target == focusTree)
 	a.info.SetFocused(target == focusInfo)
+	a.preview.SetFocused(target == focusInfo)
 	a.editor.SetFocused(target == focusEditor)
+	a.terminal.SetFocused(target == focusTerminal)
 	a.focused = target
 }
 
@@ -1473,7 +2593,7 @@ func (a *App) focusLeft() {
 func (a *App) focusRight() {
 	switch a.focused {
 	case focusEditor:
-		if a.showInfo && !a.zenMode {
+		if (a.showInfo || a.showPreview) && !a.zenMode {
 			a.setFocus(focusInfo)
 		}
 	case focusTree:
@@ -1490,13 +2610,96 @@ func (a *App) ToggleTree() {
 }
 
 func (a *App) ToggleInfo() {
-	a.showInfo = !a.showInfo
+	if a.showPreview {
+		// The info panel takes the preview's place.
+		a.showPreview = false
+		a.showInfo = true
+	} else {
+		a.showInfo = !a.showInfo
+	}
 	if !a.showInfo && a.focused == focusInfo {
 		a.setFocus(focusEditor)
 	}
 	a.updateLayout()
 }
 
+// ToggleLocalGraph shows or hides the notes within two links of the current
+// note in the info panel. Showing it brings up the info panel and focuses
+// the graph.
+func (a *App) ToggleLocalGraph() {
+	a.showGraph = !a.showGraph
+	if !a.showGraph {
+		a.info.ShowGraph(false)
+		a.info.SetGraph(nil)
+		return
+	}
+	if a.currentFile != "" && a.db != nil {
+		a.info.SetGraph(a.localGraph(a.currentFile))
+	}
+	a.info.ShowGraph(true)
+	if a.showPreview || !a.showInfo {
+		a.showPreview = false
+		a.showInfo = true
+		a.updateLayout()
+	}
+	a.setFocus(focusInfo)
+}
+
+// TogglePreview shows or hides a rendering of the current note in the info
+// panel's place.
+func (a *App) TogglePreview() {
+	a.showPreview = !a.showPreview
+	if a.showPreview {
+		a.refreshPreview()
+	} else if !a.showInfo && a.focused == focusInfo {
+		a.setFocus(focusEditor)
+	}
+	a.updateLayout()
+}
+
+// rightPanelWidth is the width asked of the right column: the info panel's
+// configured width, or for the preview as much as the layout allows.
+func (a *App) rightPanelWidth() int {
+	if a.showPreview {
+		return a.width
+	}
+	return a.cfg.InfoWidth
+}
+
+// refreshPreview renders the note in the editor into the preview, from
+// Neovim's buffer so unsaved edits show, or from disk without Neovim.
+func (a *App) refreshPreview() {
+	if !a.showPreview {
+		return
+	}
+	if a.editor.ShowSplash() {
+		a.preview.Clear()
+		return
+	}
+	rpc := a.editor.GetRPC()
+	if rpc == nil {
+		if a.currentFile == "" {
+			a.preview.Clear()
+			return
+		}
+		content, err := os.ReadFile(a.vault.AbsPath(a.currentFile))
+		if err != nil {
+			a.status.SetError(fmt.Sprintf("preview: %v", err))
+			return
+		}
+		a.preview.SetContent(content)
+		return
+	}
+	lines, err := rpc.BufferContent()
+	if err != nil {
+		return
+	}
+	a.preview.SetContent(bytes.Join(lines, []byte("\n")))
+	if line, _, err := rpc.CursorPosition(); err == nil {
+		a.preview.SyncToLine(line)
+	}
+}
+
 func (a *App) ToggleZen() {
 	a.zenMode = !a.zenMode
 	if a.zenMode && (a.focused == focusTree || a.focused == focusInfo) {
@@ -1629,7 +2832,8 @@ func overlayAt(base, overlay string, x, y, width, height int) string {
 }
 
 // checkUniqueBasename returns an error message if a different note with the same
-// basename already exists in the vault. Returns "" if the name is available.
+// basename already exists in the vault (or, with folder-scoped basenames, in
+// the same folder). Returns "" if the name is available.
 func (a *App) checkUniqueBasename(relPath string) string {
 	return a.checkUniqueBasenameExcept(relPath, "")
 }
@@ -1640,12 +2844,47 @@ func (a *App) checkUniqueBasenameExcept(relPath, exceptPath string) string {
 	if a.db == nil {
 		return ""
 	}
-	basename := filepath.Base(relPath)
-	existing, err := a.db.FindNoteByBasename(basename)
+	existing, err := a.db.ConflictingNote(relPath)
 	if err != nil || existing == "" || existing == relPath || (exceptPath != "" && existing == exceptPath) {
 		return ""
 	}
-	return fmt.Sprintf("%q already exists at %s", basename, existing)
+	return fmt.Sprintf("%q already exists at %s", filepath.Base(relPath), existing)
+}
+
+// folderScoped reports whether notes in different folders may share a
+// basename (basename_uniqueness = "folder").
+func (a *App) folderScoped() bool {
+	return a.db != nil && a.db.BasenameScope() == index.ScopeFolder
+}
+
+// linkName returns the wiki link target naming the note at relPath: its
+// basename, qualified with folders when other notes share it.
+func (a *App) linkName(relPath string) string {
+	if a.folderScoped() {
+		if notes, err := a.db.ListLinkTargets(); err == nil {
+			paths := make([]string, len(notes))
+			for i, n := range notes {
+				paths[i] = n.Path
+			}
+			if name, ok := index.LinkNames(paths)[relPath]; ok {
+				return name
+			}
+		}
+	}
+	// Notes in external sources are linked by path.
+	if vault.IsExternal(relPath) {
+		return strings.TrimSuffix(filepath.ToSlash(relPath), ".md")
+	}
+	return strings.TrimSuffix(filepath.Base(relPath), ".md")
+}
+
+// externalSources returns the configured external sources.
+func externalSources(cfg config.Config) vault.Sources {
+	var sources vault.Sources
+	for _, src := range cfg.ExternalSources {
+		sources = append(sources, vault.Source{Name: src.Name, Root: filepath.Clean(src.Path)})
+	}
+	return sources
 }
 
 func ensureDir(path string) {
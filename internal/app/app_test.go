@@ -0,0 +1,155 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/panel"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+func TestOverlayCenter(t *testing.T) {
+	base := strings.Repeat(strings.Repeat(".", 10)+"\n", 4) + strings.Repeat(".", 10)
+	got := strings.Split(overlayCenter(base, "ab\ncd", 10, 5), "\n")
+	want := []string{"..........", "....ab....", "....cd....", "..........", ".........."}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("row %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+// BenchmarkOverlayCenter draws a finder-sized overlay over a full screen of
+// styled text, as every frame with an overlay open does.
+func BenchmarkOverlayCenter(b *testing.B) {
+	const width, height = 240, 70
+	styled := lipgloss.NewStyle().Foreground(lipgloss.Color("#cdd6f4")).Bold(true)
+	row := styled.Render(strings.Repeat("lorem ipsum ", width/12))
+	base := strings.TrimSuffix(strings.Repeat(row+"\n", height), "\n")
+	overlayRow := styled.Render(strings.Repeat("x", width*9/10))
+	overlay := strings.TrimSuffix(strings.Repeat(overlayRow+"\n", height*8/10), "\n")
+	if ansi.StringWidth(row) != width {
+		b.Fatalf("base row is %d wide, want %d", ansi.StringWidth(row), width)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		overlayCenter(base, overlay, width, height)
+	}
+}
+
+func TestRecordFileOp(t *testing.T) {
+	a := &App{}
+	a.recordMoves(nil)
+	if len(a.fileOps) != 0 {
+		t.Errorf("an empty paste was logged: %+v", a.fileOps)
+	}
+	for i := range maxFileOps + 2 {
+		a.recordMoves([]fileMove{{from: fmt.Sprintf("n%d.md", i), to: "done"}})
+	}
+	if len(a.fileOps) != maxFileOps {
+		t.Fatalf("log holds %d operations, want %d", len(a.fileOps), maxFileOps)
+	}
+	if got := a.fileOps[0].String(); got != "move of n2.md" {
+		t.Errorf("oldest operation = %q, want move of n2.md", got)
+	}
+
+	op := fileOp{kind: "delete", trashed: []vault.TrashItem{{Path: "a.md"}, {Path: "b.md"}}}
+	if got := op.String(); got != "delete of 2 notes" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestUndoFileOpPartial(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "moved"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "moved", "b.md"), []byte("# B\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v := vault.New(dir)
+	a := &App{vault: v, tree: panel.NewTree(v), status: panel.NewStatus(dir)}
+	// Undone last to first: b.md goes back, then missing.md can't.
+	a.recordMoves([]fileMove{{from: "a.md", to: "missing.md"}, {from: "b.md", to: "moved/b.md"}})
+
+	a.UndoFileOp()
+	if _, err := os.Stat(filepath.Join(dir, "b.md")); err != nil {
+		t.Errorf("b.md not moved back: %v", err)
+	}
+	if len(a.fileOps) != 1 || len(a.fileOps[0].moves) != 1 || a.fileOps[0].moves[0].to != "missing.md" {
+		t.Fatalf("log after a partial undo = %+v, want only the move not undone", a.fileOps)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "missing.md"), []byte("# A\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	a.UndoFileOp()
+	if len(a.fileOps) != 0 {
+		t.Errorf("log after undoing the rest = %+v, want empty", a.fileOps)
+	}
+}
+
+func TestArchived(t *testing.T) {
+	a := &App{}
+	a.cfg.ArchiveDir = "archive"
+	for path, want := range map[string]bool{
+		"archive/old.md":      true,
+		"archive/2025/old.md": true,
+		"archive.md":          false,
+		"archived/old.md":     false,
+		"notes/archive/x.md":  false,
+	} {
+		if got := a.archived(path); got != want {
+			t.Errorf("archived(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if q := a.parseQuery("is:archived plan"); !q.Archived || q.ArchiveDir != "archive" || q.Text != "plan" {
+		t.Errorf("parseQuery() = %+v", q)
+	}
+}
+
+func TestDuplicateName(t *testing.T) {
+	taken := map[string]bool{"notes/plan-copy.md": true, "notes/plan-copy-2.md": true}
+	isTaken := func(p string) bool { return taken[p] }
+	if got := duplicateName("notes/plan.md", isTaken); got != "notes/plan-copy-3.md" {
+		t.Errorf("duplicateName() = %q, want notes/plan-copy-3.md", got)
+	}
+	if got := duplicateName("idea.md", isTaken); got != "idea-copy.md" {
+		t.Errorf("duplicateName() = %q, want idea-copy.md", got)
+	}
+	if got := duplicateName("plan.md", func(string) bool { return true }); got != "" {
+		t.Errorf("duplicateName() with every name taken = %q", got)
+	}
+}
+
+func TestPastedImageName(t *testing.T) {
+	now := time.Date(2026, 10, 16, 15, 4, 5, 0, time.UTC)
+	taken := map[string]bool{filepath.FromSlash("attachments/pasted-20261016-150405.png"): true}
+	got := pastedImageName("attachments", now, func(p string) bool { return taken[p] })
+	if want := filepath.FromSlash("attachments/pasted-20261016-150405-2.png"); got != want {
+		t.Errorf("pastedImageName() = %q, want %q", got, want)
+	}
+
+	img := filepath.FromSlash("attachments/shot.png")
+	for _, tc := range []struct{ note, style, want string }{
+		{"daily/today.md", "wiki", "![[shot.png]]"},
+		{"daily/today.md", "markdown", "![](../attachments/shot.png)"},
+		{"today.md", "markdown", "![](attachments/shot.png)"},
+		{"my notes/today.md", "markdown", "![](../attachments/shot.png)"},
+	} {
+		if got := imageLink(filepath.FromSlash(tc.note), img, tc.style); got != tc.want {
+			t.Errorf("imageLink(%q, %q) = %q, want %q", tc.note, tc.style, got, tc.want)
+		}
+	}
+	if got := imageLink("a.md", filepath.FromSlash("my shots/x.png"), "markdown"); got != "![](<my shots/x.png>)" {
+		t.Errorf("imageLink with a space = %q", got)
+	}
+}
@@ -0,0 +1,80 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/index"
+)
+
+// ArchiveNotes moves the notes selected in the tree, or else the open note,
+// into archive_dir. They stay indexed, but the finder leaves them out
+// unless asked for with is:archived. Space u moves them back.
+func (a *App) ArchiveNotes() tea.Cmd {
+	var paths []string
+	if a.focused == focusTree {
+		paths = a.tree.Selected()
+	}
+	if len(paths) == 0 && a.currentFile != "" {
+		paths = []string{a.currentFile}
+	}
+	if len(paths) == 0 {
+		a.status.SetError("archive: no note open or selected")
+		return nil
+	}
+	if a.refuseExternal(paths...) {
+		return nil
+	}
+
+	var moved []fileMove
+	defer func() { a.recordFileOp(fileOp{kind: "archive", moves: moved}) }()
+	for _, p := range paths {
+		if a.archived(p) {
+			a.status.SetError(p + " is already archived")
+			return nil
+		}
+		newRel := filepath.Join(a.cfg.ArchiveDir, filepath.Base(p))
+		if m := a.checkUniqueBasenameExcept(newRel, p); m != "" {
+			a.status.SetError(m)
+			return nil
+		}
+		if _, err := os.Stat(filepath.Join(a.cfg.VaultPath, newRel)); err == nil {
+			a.status.SetError(fmt.Sprintf("archive: %s already exists", newRel))
+			return nil
+		}
+		if err := a.vault.MoveNote(p, a.cfg.ArchiveDir); err != nil {
+			a.status.SetError(fmt.Sprintf("archive: %v", err))
+			return nil
+		}
+		moved = append(moved, fileMove{from: p, to: newRel})
+		if cmd := a.followMovedBuffer(p, newRel); cmd != nil {
+			return cmd
+		}
+	}
+
+	a.tree.ClearSelected()
+	a.tree.Refresh()
+	if len(moved) == 1 {
+		a.status.SetMessage("Archived " + filepath.Base(moved[0].from))
+	} else {
+		a.status.SetMessage(fmt.Sprintf("Archived %d notes", len(moved)))
+	}
+	return nil
+}
+
+// archived reports whether a note is in archive_dir.
+func (a *App) archived(relPath string) bool {
+	return strings.HasPrefix(relPath, a.cfg.ArchiveDir+string(filepath.Separator))
+}
+
+// parseQuery parses finder input, leaving archived notes out unless the
+// query asks for them.
+func (a *App) parseQuery(raw string) index.Query {
+	q := index.ParseQuery(raw)
+	q.ArchiveDir = a.cfg.ArchiveDir
+	return q
+}
@@ -1,19 +1,49 @@
 package app
 
 import (
+	"bytes"
 	"fmt"
+	"mime"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 
+	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/editor"
+	"github.com/pfassina/kopr/internal/index"
+	"github.com/pfassina/kopr/internal/markdown"
 	"github.com/pfassina/kopr/internal/panel"
+	"github.com/pfassina/kopr/internal/vault"
 )
 
 // indexInitDoneMsg signals indexing is complete.
 type indexInitDoneMsg struct{ err error }
 
+// indexRebuiltMsg signals a full reindex after a settings change finished.
+type indexRebuiltMsg struct{ err error }
+
+// indexReconciledMsg reports the outcome of ReconcileIndex.
+type indexReconciledMsg struct {
+	stats index.IndexStats
+	err   error
+}
+
+// indexOptimizedMsg reports the outcome of OptimizeIndex.
+type indexOptimizedMsg struct {
+	stats index.OptimizeStats
+	err   error
+}
+
+// optimizeTickMsg asks for the periodic light pass over the index.
+type optimizeTickMsg struct{}
+
+// indexTidiedMsg reports the outcome of the periodic light pass.
+type indexTidiedMsg struct{ err error }
+
 // noteIndexedMsg signals a single file was (re)indexed.
 // relPath is relative to the vault root.
 type noteIndexedMsg struct {
@@ -21,16 +51,178 @@ type noteIndexedMsg struct {
 	err     error
 }
 
+// indexProgressMsg reports how far a full index has got. updates delivers
+// that index's next message.
+type indexProgressMsg struct {
+	done, total int
+	updates     <-chan tea.Msg
+}
+
 // initIndex starts the indexer in a goroutine.
 func (a *App) initIndex() tea.Cmd {
-	return func() tea.Msg {
-		if a.indexer != nil {
-			if err := a.indexer.IndexAll(); err != nil {
-				return indexInitDoneMsg{err: err}
+	return a.indexAll(func(err error) tea.Msg { return indexInitDoneMsg{err: err} })
+}
+
+// rebuildIndex reindexes the whole vault in a goroutine, e.g. after the FTS
+// table was recreated. The watcher holds its reindexes until it's done.
+func (a *App) rebuildIndex() tea.Cmd {
+	if a.indexer == nil {
+		return func() tea.Msg { return indexRebuiltMsg{} }
+	}
+	w := a.watcher
+	if w != nil {
+		w.Pause()
+	}
+	return a.indexAll(func(err error) tea.Msg {
+		if w != nil {
+			w.Resume()
+		}
+		return indexRebuiltMsg{err: err}
+	})
+}
+
+// indexAll runs IndexAll in a goroutine and shows its progress in the
+// status bar. The returned command delivers an indexProgressMsg per percent
+// done, each of which asks for the next, and finally done(err).
+func (a *App) indexAll(done func(error) tea.Msg) tea.Cmd {
+	updates := make(chan tea.Msg, 1)
+	idx := a.indexer
+	go func() {
+		last := -1
+		err := idx.IndexAllWithProgress(func(n, total int) {
+			if pct := n * 100 / total; pct != last {
+				last = pct
+				select {
+				case updates <- indexProgressMsg{done: n, total: total, updates: updates}:
+				default: // the app has not caught up; skip this one
+				}
 			}
+		})
+		updates <- done(err)
+	}()
+
+	a.indexRuns++
+	a.indexProgress = indexProgressMsg{}
+	a.showActivity()
+	cmds := []tea.Cmd{waitIndexUpdate(updates)}
+	if a.indexRuns == 1 && !a.lowBandwidth {
+		cmds = append(cmds, a.indexSpinner.Tick)
+	}
+	return tea.Batch(cmds...)
+}
+
+// OptimizeIndex compacts the index in the background: it merges the search
+// index's segments and vacuums the database. Exiting does a lighter pass.
+func (a *App) OptimizeIndex() tea.Cmd {
+	if a.db == nil {
+		return nil
+	}
+	db := a.db
+	a.status.SetMessage("Optimizing index...")
+	return func() tea.Msg {
+		stats, err := db.Optimize(true)
+		return indexOptimizedMsg{stats: stats, err: err}
+	}
+}
+
+// scheduleOptimize asks for the next periodic light pass over the index, so
+// a session that runs for days tidies it as an exit would.
+func (a *App) scheduleOptimize() tea.Cmd {
+	if a.db == nil || a.cfg.OptimizeInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(a.cfg.OptimizeInterval, func(time.Time) tea.Msg {
+		return optimizeTickMsg{}
+	})
+}
+
+// tidyIndex runs a light Optimize in the background, unless a full index
+// is running, which would only make it wait.
+func (a *App) tidyIndex() tea.Cmd {
+	if a.indexRuns > 0 {
+		return a.scheduleOptimize()
+	}
+	db := a.db
+	return func() tea.Msg {
+		_, err := db.Optimize(false)
+		return indexTidiedMsg{err: err}
+	}
+}
+
+// ReconcileIndex scans the vault in the background for changes the file
+// watcher missed and re-indexes them, as the watcher does every
+// reconcile_interval.
+func (a *App) ReconcileIndex() tea.Cmd {
+	if a.watcher == nil {
+		a.status.SetError("the index is still loading")
+		return nil
+	}
+	w := a.watcher
+	a.status.SetMessage("Scanning for changes...")
+	return func() tea.Msg {
+		stats, err := w.Reconcile()
+		return indexReconciledMsg{stats: stats, err: err}
+	}
+}
+
+// ToggleIndexing pauses the file watcher, e.g. during a large sync or git
+// checkout, or resumes it with a scan that catches up on what changed in
+// the meantime.
+func (a *App) ToggleIndexing() tea.Cmd {
+	if a.watcher == nil {
+		a.status.SetError("the index is still loading")
+		return nil
+	}
+	w := a.watcher
+	if !a.indexPaused {
+		a.indexPaused = true
+		w.Pause()
+		a.status.SetMessage("Indexing paused; Space c i resumes")
+		return nil
+	}
+	a.indexPaused = false
+	a.status.SetMessage("Indexing resumed, scanning for changes...")
+	return func() tea.Msg {
+		stats, err := w.ResumeAndReconcile()
+		return indexReconciledMsg{stats: stats, err: err}
+	}
+}
+
+func waitIndexUpdate(updates <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg { return <-updates }
+}
+
+// showActivity renders background work in the status bar: the running
+// index's progress, a paused watcher and the reindexes it has queued, which
+// is why search can lag behind fresh edits.
+func (a *App) showActivity() {
+	var parts []string
+	if a.indexRuns > 0 {
+		text := "Indexing"
+		if p := a.indexProgress; p.total > 0 {
+			text = fmt.Sprintf("Indexing %d%%", p.done*100/p.total)
+		}
+		if !a.lowBandwidth {
+			text = a.indexSpinner.View() + " " + text
 		}
-		return indexInitDoneMsg{}
+		parts = append(parts, text)
+	}
+	if a.watcherPaused {
+		parts = append(parts, "watcher paused")
+	}
+	if a.watcherQueued > 0 {
+		parts = append(parts, fmt.Sprintf("%d queued", a.watcherQueued))
+	}
+	a.status.SetProgress(strings.Join(parts, " · "))
+}
+
+// finishIndexProgress drops the progress indicator once the last running
+// IndexAll returns.
+func (a *App) finishIndexProgress() {
+	if a.indexRuns > 0 {
+		a.indexRuns--
 	}
+	a.showActivity()
 }
 
 func (a *App) indexFile(absPath string) tea.Cmd {
@@ -51,50 +243,333 @@ func (a *App) indexFile(absPath string) tea.Cmd {
 	}
 }
 
-// searchNotes returns finder items for a query.
-func (a *App) searchNotes(query string) []panel.FinderItem {
+// reindexFiles updates the index once for a batch of absolute paths, e.g.
+// after a vault-wide rewrite. relPath is reported back so the info panel can
+// refresh when it is the open note.
+func (a *App) reindexFiles(relPath string, removed, changed []string) tea.Cmd {
+	idx := a.indexer
+	return func() tea.Msg {
+		if idx == nil {
+			return noteIndexedMsg{}
+		}
+		if err := idx.Reindex(removed, changed); err != nil {
+			return noteIndexedMsg{err: err}
+		}
+		return noteIndexedMsg{relPath: relPath}
+	}
+}
+
+// searchNotes returns up to limit finder items for a query.
+func (a *App) searchNotes(query string, limit int) []panel.FinderItem {
 	if a.db == nil {
 		return nil
 	}
+	defer func(start time.Time) { a.metrics.ObserveSearch(time.Since(start)) }(time.Now())
 
 	if query == "" {
-		results, err := a.db.ListAllNotes(50)
+		// Frequently and recently opened notes first.
+		results, err := a.db.ListNotesByFrecency(time.Now(), limit)
 		if err != nil {
 			return nil
 		}
-		items := make([]panel.FinderItem, len(results))
-		for i, r := range results {
-			items[i] = panel.FinderItem{
-				Title: r.Title,
-				Path:  r.Path,
+		items := make([]panel.FinderItem, 0, len(results))
+		for _, r := range results {
+			if a.archived(r.Path) {
+				continue
 			}
+			items = append(items, panel.FinderItem{
+				Title:   r.Title,
+				Path:    r.Path,
+				Extra:   r.Summary,
+				ModTime: r.Modified(),
+				Created: r.Created,
+			})
 		}
 		return items
 	}
 
-	// Try FTS search first
-	results, err := a.db.Search(query, 50)
-	if err != nil || len(results) == 0 {
-		// Fallback to file search
-		results, err = a.db.SearchFiles(query, 50)
-		if err != nil {
-			return nil
-		}
+	// Operators (tag:, path:, status:) filter; free text tries FTS first,
+	// then falls back to fuzzy file search.
+	results, err := a.db.SearchQuery(a.parseQuery(query), limit)
+	if err != nil {
+		return nil
 	}
 
 	items := make([]panel.FinderItem, len(results))
 	for i, r := range results {
 		items[i] = panel.FinderItem{
-			Title: r.Title,
-			Path:  r.Path,
+			Title:        r.Title,
+			Path:         r.Path,
+			Extra:        r.Snippet,
+			TitleMatches: r.TitleMatches,
+			ExtraMatches: r.SnippetMatches,
+			ModTime:      r.Modified(),
+			Created:      r.Created,
+		}
+		// A body match says more about the hit than the summary does.
+		if r.Snippet == "" {
+			items[i].Extra = r.Summary
+		}
+	}
+	return items
+}
+
+// searchRecent returns previously opened notes ranked by frecency, narrowed by
+// fuzzy matching the query against title or path.
+func (a *App) searchRecent(query string) []panel.FinderItem {
+	if a.db == nil {
+		return nil
+	}
+
+	results, err := a.db.ListRecentNotes(time.Now(), 200)
+	if err != nil {
+		return nil
+	}
+
+	terms := strings.Fields(query)
+	var items []panel.FinderItem
+	for _, r := range results {
+		if !matchesAllTerms(terms, r.Path, r.Title) {
+			continue
+		}
+		items = append(items, panel.FinderItem{Title: r.Title, Path: r.Path, Extra: r.Summary, ModTime: r.Modified(), Created: r.Created})
+		if len(items) == 50 {
+			break
+		}
+	}
+	return items
+}
+
+// searchModified returns notes newest-modified first, narrowed by fuzzy
+// matching the query against title or path, with how long ago each changed.
+func (a *App) searchModified(query string) []panel.FinderItem {
+	if a.db == nil {
+		return nil
+	}
+
+	results, err := a.db.ListNotesByModTime(200)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	terms := strings.Fields(query)
+	var items []panel.FinderItem
+	for _, r := range results {
+		if !matchesAllTerms(terms, r.Path, r.Title) {
+			continue
+		}
+		items = append(items, panel.FinderItem{Title: r.Title, Path: r.Path, Extra: formatAge(now.Sub(r.ModTime)), ModTime: r.ModTime, Created: r.Created})
+		if len(items) == 50 {
+			break
+		}
+	}
+	return items
+}
+
+// formatAge renders a duration coarsely, e.g. "just now", "5m ago", "3d ago".
+func formatAge(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d/time.Minute))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d/time.Hour))
+	case d < 30*24*time.Hour:
+		return fmt.Sprintf("%dd ago", int(d/(24*time.Hour)))
+	case d < 365*24*time.Hour:
+		return fmt.Sprintf("%dmo ago", int(d/(30*24*time.Hour)))
+	default:
+		return fmt.Sprintf("%dy ago", int(d/(365*24*time.Hour)))
+	}
+}
+
+// searchAttachments returns the vault's non-note files whose path
+// fuzzy-matches the query, with each one's size and age.
+func (a *App) searchAttachments(query string) []panel.FinderItem {
+	if a.db == nil {
+		return nil
+	}
+	attachments, err := a.db.ListAttachments()
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	terms := strings.Fields(query)
+	var items []panel.FinderItem
+	for _, at := range attachments {
+		if !matchesAllTerms(terms, at.Path, filepath.Base(at.Path)) {
+			continue
+		}
+		items = append(items, panel.FinderItem{
+			Title:   at.Path,
+			Path:    at.Path,
+			Extra:   formatSize(at.Size) + ", " + formatAge(now.Sub(at.ModTime)),
+			ModTime: at.ModTime,
+		})
+	}
+	return items
+}
+
+// previewAttachment describes an attachment for the finder preview, which
+// can't show images or PDFs.
+func (a *App) previewAttachment(relPath string) string {
+	info, err := os.Stat(filepath.Join(a.cfg.VaultPath, relPath))
+	if err != nil {
+		return ""
+	}
+	kind := mime.TypeByExtension(filepath.Ext(relPath))
+	if kind == "" {
+		kind = "unknown type"
+	}
+	return fmt.Sprintf("%s\n\n%s, %s\nModified %s\n\nEnter opens it with the file manager.",
+		relPath, kind, formatSize(info.Size()), info.ModTime().Format("2006-01-02 15:04"))
+}
+
+// formatSize renders a byte count, e.g. "512 B", "1.2 KB", "3.4 MB".
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	size, prefix := float64(n)/unit, 0
+	for size >= unit && prefix < 3 {
+		size /= unit
+		prefix++
+	}
+	return fmt.Sprintf("%.1f %cB", size, "KMGT"[prefix])
+}
+
+// searchBacklinks returns the links to relPath whose source note
+// fuzzy-matches the query.
+func (a *App) searchBacklinks(relPath, query string) []panel.FinderItem {
+	if a.db == nil {
+		return nil
+	}
+	backlinks, err := a.db.GetBacklinks(relPath)
+	if err != nil {
+		return nil
+	}
+
+	terms := strings.Fields(query)
+	var items []panel.FinderItem
+	for _, bl := range backlinks {
+		if !matchesAllTerms(terms, bl.SourcePath, bl.SourceTitle) {
+			continue
+		}
+		items = append(items, panel.FinderItem{
+			Title: fmt.Sprintf("%s:%d", bl.SourcePath, bl.Line),
+			Path:  bl.SourcePath,
+			Line:  bl.Line,
+		})
+	}
+	return items
+}
+
+// searchSavedSearches returns the configured saved searches whose name
+// fuzzy-matches the query.
+func (a *App) searchSavedSearches(query string) []panel.FinderItem {
+	var items []panel.FinderItem
+	for _, ss := range a.cfg.SavedSearches {
+		_, positions, ok := index.FuzzyMatch(query, ss.Name)
+		if !ok {
+			continue
 		}
+		items = append(items, panel.FinderItem{
+			Title:        ss.Name,
+			Extra:        ss.Query,
+			Query:        ss.Query,
+			TitleMatches: positions,
+		})
 	}
 	return items
 }
 
+// searchTags lists the vault's tags for the tag browser. With no query they
+// form a tree, each subtag indented under its parent; otherwise the tags
+// whose full name fuzzy-matches are listed flat, most used first. Picking
+// one finds the notes with the tag or its subtags.
+func (a *App) searchTags(query string) []panel.FinderItem {
+	if a.db == nil {
+		return nil
+	}
+	tags, err := a.db.ListTags()
+	if err != nil {
+		return nil
+	}
+
+	query = strings.TrimPrefix(strings.TrimSpace(query), "#")
+	var items []panel.FinderItem
+	if query != "" {
+		for _, t := range tags {
+			_, positions, ok := index.FuzzyMatch(query, t.Name)
+			if !ok {
+				continue
+			}
+			items = append(items, panel.FinderItem{
+				Title:        t.Name,
+				Extra:        tagCount(t),
+				Query:        tagQuery(t.Name),
+				TitleMatches: positions,
+			})
+		}
+		return items
+	}
+
+	index.SortTagTree(tags)
+	for _, t := range tags {
+		items = append(items, panel.FinderItem{
+			Title: t.Name[strings.LastIndex(t.Name, "/")+1:],
+			Extra: tagCount(t),
+			Query: tagQuery(t.Name),
+			Depth: strings.Count(t.Name, "/"),
+		})
+	}
+	return items
+}
+
+// tagCount describes how many notes a tag covers, counting its subtags.
+func tagCount(t index.TagResult) string {
+	if t.Total == 1 {
+		return "1 note"
+	}
+	return fmt.Sprintf("%d notes", t.Total)
+}
+
+// tagQuery returns the finder query for notes with tag.
+func tagQuery(tag string) string {
+	if strings.ContainsAny(tag, " \t") {
+		return `tag:"` + tag + `"`
+	}
+	return "tag:" + tag
+}
+
+// savedSearchItems lists saved searches for the info panel.
+func savedSearchItems(searches []config.SavedSearch) []panel.InfoItem {
+	items := make([]panel.InfoItem, len(searches))
+	for i, ss := range searches {
+		items[i] = panel.InfoItem{Title: ss.Name, Query: ss.Query}
+	}
+	return items
+}
+
+// matchesAllTerms reports whether every term fuzzy-matches path or title.
+func matchesAllTerms(terms []string, path, title string) bool {
+	for _, term := range terms {
+		_, _, inPath := index.FuzzyMatch(term, path)
+		_, _, inTitle := index.FuzzyMatch(term, title)
+		if !inPath && !inTitle {
+			return false
+		}
+	}
+	return true
+}
+
 // previewNote returns the raw content of a note for the finder preview pane.
 func (a *App) previewNote(relPath string) string {
-	absPath := filepath.Join(a.cfg.VaultPath, relPath)
+	absPath := a.vault.AbsPath(relPath)
 	data, err := os.ReadFile(absPath)
 	if err != nil {
 		return ""
@@ -102,13 +577,50 @@ func (a *App) previewNote(relPath string) string {
 	return string(data)
 }
 
-// searchNoteContent returns finder items matching a substring in note content.
-func (a *App) searchNoteContent(query string) []panel.FinderItem {
+// previewFile returns the content of a file by absolute path, for previews of
+// files that may live outside the vault (e.g. templates).
+func previewFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// searchTemplates returns finder items for templates whose name or group
+// fuzzy-matches the query. The group is shown as the item's extra text so
+// templates from the same subfolder read as a group.
+func searchTemplates(templates []vault.Template, query string) []panel.FinderItem {
+	terms := strings.Fields(query)
+	var items []panel.FinderItem
+	for _, t := range templates {
+		if !matchesAllTerms(terms, t.Group, t.Name) {
+			continue
+		}
+		var positions []int
+		for _, term := range terms {
+			_, pos, _ := index.FuzzyMatch(term, t.Name)
+			positions = append(positions, pos...)
+		}
+		slices.Sort(positions)
+		items = append(items, panel.FinderItem{
+			Title:        t.Name,
+			Path:         t.Path,
+			Extra:        t.Group,
+			TitleMatches: slices.Compact(positions),
+		})
+	}
+	return items
+}
+
+// searchNoteContent returns up to limit finder items matching a substring in
+// note content, scanning recently modified notes first.
+func (a *App) searchNoteContent(query string, limit int) []panel.FinderItem {
 	if query == "" || a.db == nil {
 		return nil
 	}
 
-	notes, err := a.db.ListAllNotes(200)
+	notes, err := a.db.ListAllNotes(0)
 	if err != nil {
 		return nil
 	}
@@ -117,7 +629,7 @@ func (a *App) searchNoteContent(query string) []panel.FinderItem {
 	var items []panel.FinderItem
 
 	for _, note := range notes {
-		absPath := filepath.Join(a.cfg.VaultPath, note.Path)
+		absPath := a.vault.AbsPath(note.Path)
 		data, err := os.ReadFile(absPath)
 		if err != nil {
 			continue
@@ -127,12 +639,14 @@ func (a *App) searchNoteContent(query string) []panel.FinderItem {
 		for i, line := range lines {
 			if strings.Contains(strings.ToLower(line), lowerQuery) {
 				items = append(items, panel.FinderItem{
-					Title: fmt.Sprintf("%s:%d", note.Path, i+1),
-					Path:  note.Path,
-					Line:  i + 1,
-					Extra: strings.TrimSpace(line),
+					Title:   fmt.Sprintf("%s:%d", note.Path, i+1),
+					Path:    note.Path,
+					Line:    i + 1,
+					Extra:   strings.TrimSpace(line),
+					ModTime: note.Modified(),
+					Created: note.Created,
 				})
-				if len(items) >= 50 {
+				if len(items) >= limit {
 					return items
 				}
 			}
@@ -142,6 +656,116 @@ func (a *App) searchNoteContent(query string) []panel.FinderItem {
 	return items
 }
 
+// searchBrokenLinks returns the unresolved links whose source note or
+// target fuzzy-matches the query.
+func (a *App) searchBrokenLinks(query string) []panel.FinderItem {
+	if a.db == nil {
+		return nil
+	}
+	links, err := a.db.GetBrokenLinks()
+	if err != nil {
+		return nil
+	}
+
+	terms := strings.Fields(query)
+	var items []panel.FinderItem
+	for _, l := range links {
+		target := l.Target
+		if !l.Markdown {
+			target = strings.TrimSuffix(target, ".md")
+		}
+		if l.Section != "" {
+			target += "#" + l.Section
+		}
+		if !matchesAllTerms(terms, l.SourcePath, target) {
+			continue
+		}
+		extra := "[[" + target + "]]"
+		if l.Markdown {
+			extra = "(" + target + ")"
+		}
+		items = append(items, panel.FinderItem{
+			Title: fmt.Sprintf("%s:%d", l.SourcePath, l.Line),
+			Path:  l.SourcePath,
+			Line:  l.Line,
+			Extra: extra,
+		})
+	}
+	return items
+}
+
+// searchTasks returns the open checklist items whose text, note or section
+// fuzzy-matches the query.
+func (a *App) searchTasks(query string) []panel.FinderItem {
+	if a.db == nil {
+		return nil
+	}
+	tasks, err := a.db.GetOpenTasks()
+	if err != nil {
+		return nil
+	}
+
+	terms := strings.Fields(query)
+	var items []panel.FinderItem
+	for _, t := range tasks {
+		if !matchesAllTerms(terms, t.NotePath, t.Section+" "+t.Text) {
+			continue
+		}
+		items = append(items, panel.FinderItem{
+			Title: fmt.Sprintf("%s:%d", t.NotePath, t.Line),
+			Path:  t.NotePath,
+			Line:  t.Line,
+			Extra: "[ ] " + t.Text,
+		})
+	}
+	return items
+}
+
+// openRandomNote opens a note picked uniformly at random from those in
+// scope, a finder filter such as "tag:review" or "path:projects/"; a bare
+// "#tag" or folder name works too. An empty scope covers the whole vault.
+// Returns false when no note is in scope.
+func (a *App) openRandomNote(scope string) bool {
+	if a.db == nil {
+		return false
+	}
+	q := a.parseQuery(scope)
+	for _, word := range strings.Fields(q.Text) {
+		if tag, ok := strings.CutPrefix(word, "#"); ok {
+			q.Tags = append(q.Tags, tag)
+		} else {
+			q.Paths = append(q.Paths, strings.TrimSuffix(word, "/")+"/")
+		}
+	}
+	path, err := a.db.RandomNotePath(q)
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("random note: %v", err))
+		return false
+	}
+	if path == "" {
+		if scope == "" {
+			a.status.SetError("No notes in the vault")
+		} else {
+			a.status.SetError(fmt.Sprintf("No notes in %s", scope))
+		}
+		return false
+	}
+	a.navigateTo(path)
+	a.setFocus(focusEditor)
+	return true
+}
+
+// saveFinderHistory persists the finder's query history after a query was
+// accepted.
+func (a *App) saveFinderHistory() {
+	if a.history == nil {
+		return
+	}
+	if err := a.history.Save(a.finder.History()); err != nil {
+		a.status.SetError(fmt.Sprintf("save finder history: %v", err))
+	}
+}
+
 // handleFinderResult handles a file selection from the finder.
 func (a *App) handleFinderResult(path string, line int) tea.Cmd {
 	a.navigateTo(path)
@@ -155,6 +779,156 @@ func (a *App) handleFinderResult(path string, line int) tea.Cmd {
 	return nil
 }
 
+// setQuickfix shows items as the search results list in the info panel and
+// focuses it. ]q then jumps to the first result.
+func (a *App) setQuickfix(query string, items []panel.FinderItem) {
+	a.quickfix = items
+	a.quickfixIdx = -1
+	infoItems := make([]panel.InfoItem, len(items))
+	for i, item := range items {
+		title := item.Title
+		if item.Line > 0 && item.Extra != "" {
+			title += "  " + item.Extra
+		}
+		infoItems[i] = panel.InfoItem{Title: title, Path: item.Path, Line: item.Line}
+	}
+	a.info.SetSearchResults(query, infoItems)
+	a.showInfo = true
+	a.showPreview = false
+	if a.zenMode {
+		a.setFocus(focusEditor)
+	} else {
+		a.setFocus(focusInfo)
+	}
+	a.status.SetMessage(fmt.Sprintf("%d search result(s); ]q/[q to step through", len(items)))
+}
+
+// stepQuickfix jumps to the next (delta 1) or previous (delta -1) entry in
+// the search results list.
+func (a *App) stepQuickfix(delta int) {
+	if len(a.quickfix) == 0 {
+		a.status.SetError("No search results")
+		return
+	}
+	next := a.quickfixIdx + delta
+	if a.quickfixIdx < 0 && delta < 0 {
+		next = len(a.quickfix) - 1
+	}
+	if next < 0 || next >= len(a.quickfix) {
+		a.status.SetMessage("No more search results")
+		return
+	}
+	a.jumpToQuickfix(next)
+}
+
+// jumpToQuickfix opens the search result at idx in the editor and makes it
+// the current entry.
+func (a *App) jumpToQuickfix(idx int) {
+	if idx < 0 || idx >= len(a.quickfix) {
+		return
+	}
+	a.quickfixIdx = idx
+	a.info.SetCurrentResult(idx)
+	item := a.quickfix[idx]
+	a.handleFinderResult(item.Path, item.Line)
+	a.setFocus(focusEditor)
+	a.status.SetMessage(fmt.Sprintf("Result %d of %d: %s", idx+1, len(a.quickfix), item.Title))
+}
+
+// handleFinderMultiResult opens every marked note. They are opened last to
+// first so each becomes a Neovim buffer and the first marked note ends up in
+// the window.
+func (a *App) handleFinderMultiResult(paths []string) {
+	for _, p := range slices.Backward(paths) {
+		a.navigateTo(p)
+	}
+	a.focused = focusEditor
+}
+
+// handleFinderBatch routes a bulk action on marked finder notes to the same
+// flows the tree uses: delete asks for confirmation, move cuts the notes to
+// the tree clipboard so they can be pasted into a folder.
+func (a *App) handleFinderBatch(msg panel.FinderBatchMsg) tea.Cmd {
+	switch msg.Op {
+	case panel.FinderBatchDelete:
+		a.setFocus(focusEditor)
+		if len(msg.Paths) == 1 {
+			return func() tea.Msg {
+				return panel.TreeDeleteNoteMsg{Path: msg.Paths[0], Name: filepath.Base(msg.Paths[0])}
+			}
+		}
+		return func() tea.Msg { return panel.TreeDeleteNotesMsg{Paths: msg.Paths} }
+	case panel.FinderBatchMove:
+		a.tree.SetClipboard(msg.Paths, panel.ClipboardCut)
+		a.updateClipboardStatus(panel.ClipboardCut, len(msg.Paths))
+		if showTree, _ := a.panelsVisible(); showTree {
+			a.setFocus(focusTree)
+		} else {
+			a.setFocus(focusEditor)
+		}
+	}
+	return nil
+}
+
+// showFinderActions opens the action menu for a finder result.
+func (a *App) showFinderActions(item panel.FinderItem) {
+	// Template picker entries are addressed by absolute path and are not
+	// vault notes; attachments aren't notes either.
+	if a.pickingTemplate || a.pickingAttachment || filepath.IsAbs(item.Path) {
+		a.pickingTemplate = false
+		a.pickingAttachment = false
+		a.status.SetError("no actions for this item")
+		a.setFocus(focusEditor)
+		return
+	}
+	a.finderActionItem = item
+	a.contextMenu.Show(0, 0, []panel.ContextMenuItem{
+		{Label: "Open", Action: "finder-open"},
+		{Label: "Rename", Action: "finder-rename"},
+		{Label: "Duplicate", Action: "finder-duplicate"},
+		{Label: "Move to folder", Action: "finder-move"},
+		{Label: "Delete", Action: "finder-delete"},
+		{Label: "Copy path", Action: "finder-copy-path"},
+		{Label: "Copy link", Action: "finder-copy-link"},
+		{Label: "Reveal in tree", Action: "finder-reveal"},
+		{Label: "Backlinks", Action: "finder-backlinks", Disabled: a.db == nil},
+	})
+	a.contextMenu.Center(a.width, a.height)
+}
+
+// handleFinderAction runs an action picked from the finder result menu.
+func (a *App) handleFinderAction(action string, item panel.FinderItem) tea.Cmd {
+	a.setFocus(focusEditor)
+	switch action {
+	case "finder-open":
+		a.handleFinderResult(item.Path, item.Line)
+	case "finder-rename":
+		return func() tea.Msg {
+			return panel.TreeRenameNoteMsg{Path: item.Path, Name: filepath.Base(item.Path)}
+		}
+	case "finder-duplicate":
+		return a.DuplicateNote(item.Path)
+	case "finder-move":
+		return a.handleFinderBatch(panel.FinderBatchMsg{Op: panel.FinderBatchMove, Paths: []string{item.Path}})
+	case "finder-delete":
+		return a.handleFinderBatch(panel.FinderBatchMsg{Op: panel.FinderBatchDelete, Paths: []string{item.Path}})
+	case "finder-copy-path":
+		a.clipboardText = item.Path
+		a.status.SetMessage(fmt.Sprintf("Copied %s", item.Path))
+		return a.writeClipboard(item.Path)
+	case "finder-copy-link":
+		link := "[[" + a.linkName(item.Path) + "]]"
+		a.clipboardText = link
+		a.status.SetMessage(fmt.Sprintf("Copied %s", link))
+		return a.writeClipboard(link)
+	case "finder-reveal":
+		a.revealPath(item.Path)
+	case "finder-backlinks":
+		return a.OpenBacklinksFinder(item.Path)
+	}
+	return nil
+}
+
 // createNoteFromFinder creates a new note from a finder query string.
 func (a *App) createNoteFromFinder(name string) {
 	// Sanitize: add .md extension if missing
@@ -228,4 +1002,233 @@ func (a *App) updateInfoPanel(relPath string) {
 		hdItems[i] = panel.InfoItem{Title: h.Text, Line: h.Line, Level: h.Level}
 	}
 	a.info.SetOutline(hdItems)
+
+	if a.showGraph {
+		a.info.SetGraph(a.localGraph(relPath))
+	}
+}
+
+// localGraph lists the notes within two links of relPath, in either
+// direction: each neighbor, then indented under it the notes it links with
+// that aren't shown yet. Arrows mark which way the links go.
+func (a *App) localGraph(relPath string) []panel.InfoItem {
+	neighbors, err := a.db.Neighbors(relPath)
+	if err != nil {
+		return nil
+	}
+	shown := map[string]bool{relPath: true}
+	for _, n := range neighbors {
+		shown[n.Path] = true
+	}
+	var items []panel.InfoItem
+	for _, n := range neighbors {
+		items = append(items, panel.InfoItem{Title: graphArrow(n) + n.Title, Path: n.Path, Level: 1})
+		second, err := a.db.Neighbors(n.Path)
+		if err != nil {
+			continue
+		}
+		for _, m := range second {
+			if shown[m.Path] {
+				continue
+			}
+			shown[m.Path] = true
+			items = append(items, panel.InfoItem{Title: graphArrow(m) + m.Title, Path: m.Path, Level: 2})
+		}
+	}
+	return items
+}
+
+// graphArrow marks a neighbor as linked to (->), linking back (<-) or both
+// (<->).
+func graphArrow(n index.Neighbor) string {
+	switch {
+	case n.Out && n.In:
+		return "<-> "
+	case n.In:
+		return "<-  "
+	}
+	return "->  "
+}
+
+// habitEntries loads the habit checklist items from dated daily notes.
+func (a *App) habitEntries() ([]panel.HabitEntry, error) {
+	tasks, err := a.db.GetSectionTasks(vault.DailyDir+"/", a.cfg.HabitsHeading)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]panel.HabitEntry, 0, len(tasks))
+	for _, t := range tasks {
+		name := strings.TrimSuffix(filepath.Base(t.NotePath), ".md")
+		date, err := time.Parse(vault.DailyDateFormat, name)
+		if err != nil {
+			continue // not a dated daily note (e.g. daily/README.md)
+		}
+		entries = append(entries, panel.HabitEntry{Date: date, Habit: t.Text, Done: t.Done})
+	}
+	return entries, nil
+}
+
+// autolinkFoundMsg carries unlinked mentions found in the open note.
+type autolinkFoundMsg struct {
+	relPath     string
+	suggestions []markdown.LinkSuggestion
+	onSave      bool
+	err         error
+}
+
+// bufferText returns the active Neovim buffer joined into one document.
+func bufferText(rpc *editor.RPC) ([]byte, error) {
+	lines, err := rpc.BufferContent()
+	if err != nil {
+		return nil, err
+	}
+	return bytes.Join(lines, []byte("\n")), nil
+}
+
+// linkTargets returns every indexed note except relPath, named by its
+// link name and mentioned by its title or aliases.
+func (a *App) linkTargets(relPath string) ([]markdown.LinkTarget, error) {
+	notes, err := a.db.ListLinkTargets()
+	if err != nil {
+		return nil, err
+	}
+	paths := make([]string, len(notes))
+	for i, n := range notes {
+		paths[i] = n.Path
+	}
+	names := index.LinkNames(paths)
+	targets := make([]markdown.LinkTarget, 0, len(notes))
+	for _, n := range notes {
+		if strings.EqualFold(n.Path, relPath) {
+			continue
+		}
+		targets = append(targets, markdown.LinkTarget{
+			Name:  names[n.Path],
+			Terms: append([]string{n.Title}, n.Aliases...),
+		})
+	}
+	return targets, nil
+}
+
+// unlinkedMentions scans content for mentions of the given targets, honoring
+// the note's autolink_ignore frontmatter list.
+func unlinkedMentions(content []byte, targets []markdown.LinkTarget) []markdown.LinkSuggestion {
+	var ignore []string
+	if fm := markdown.ExtractFrontmatter(content); fm != nil {
+		ignore = fm.AutolinkIgnore
+	}
+	return markdown.FindUnlinkedMentions(content, targets, ignore)
+}
+
+// findMentions returns a command that scans the open note for plain-text
+// mentions of other notes.
+func (a *App) findMentions(onSave bool) tea.Cmd {
+	rpc := a.editor.GetRPC()
+	if rpc == nil || a.db == nil || a.currentFile == "" {
+		return nil
+	}
+	relPath := a.currentFile
+	return func() tea.Msg {
+		content, err := bufferText(rpc)
+		if err != nil {
+			return fatalErrorMsg{err: fmt.Errorf("nvim buffer content: %w", err)}
+		}
+		targets, err := a.linkTargets(relPath)
+		if err != nil {
+			return autolinkFoundMsg{relPath: relPath, onSave: onSave, err: err}
+		}
+		return autolinkFoundMsg{
+			relPath:     relPath,
+			suggestions: unlinkedMentions(content, targets),
+			onSave:      onSave,
+		}
+	}
+}
+
+// handleAutolinkFound asks the user to confirm linking the found mentions.
+// On-save checks skip targets the user already declined for this note.
+func (a *App) handleAutolinkFound(msg autolinkFoundMsg) {
+	if msg.err != nil {
+		a.status.SetError(fmt.Sprintf("auto-link: %v", msg.err))
+		return
+	}
+	if msg.relPath != a.currentFile || a.prompt.Visible() {
+		return // user moved on
+	}
+
+	var names []string
+	for _, s := range msg.suggestions {
+		if msg.onSave && a.autolinkDeclined[msg.relPath][s.Target] {
+			continue
+		}
+		names = append(names, s.Target)
+	}
+	if len(names) == 0 {
+		if !msg.onSave {
+			a.status.SetMessage("No unlinked mentions")
+		}
+		return
+	}
+
+	shown := strings.Join(names, ", ")
+	if len(names) > 3 {
+		shown = strings.Join(names[:3], ", ") + fmt.Sprintf(" +%d", len(names)-3)
+	}
+	a.pendingPrompt = promptAction{kind: "autolink", path: msg.relPath, targets: names}
+	a.prompt.ShowConfirm(fmt.Sprintf("Link mentions of %s?", shown))
+}
+
+// applyAutolinks links the first mention of each accepted target in the open
+// buffer. Mentions are recomputed from the current buffer so edits made by
+// format-on-save in the meantime can't misplace a link.
+func (a *App) applyAutolinks(relPath string, names []string) tea.Cmd {
+	rpc := a.editor.GetRPC()
+	if rpc == nil || relPath != a.currentFile {
+		return nil
+	}
+	content, err := bufferText(rpc)
+	if err != nil {
+		return fatalCmd(fmt.Errorf("nvim buffer content: %w", err))
+	}
+	all, err := a.linkTargets(relPath)
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("auto-link: %v", err))
+		return nil
+	}
+	accepted := make(map[string]bool, len(names))
+	for _, n := range names {
+		accepted[n] = true
+	}
+	var targets []markdown.LinkTarget
+	for _, t := range all {
+		if accepted[t.Name] {
+			targets = append(targets, t)
+		}
+	}
+
+	suggestions := unlinkedMentions(content, targets)
+	if len(suggestions) == 0 {
+		return nil
+	}
+	updated := markdown.ApplyLinkSuggestions(content, suggestions)
+	if err := rpc.SetBufferLines(strings.Split(string(updated), "\n")); err != nil {
+		return fatalCmd(fmt.Errorf("nvim set buffer lines: %w", err))
+	}
+	a.status.SetMessage(fmt.Sprintf("Linked %d mention(s)", len(suggestions)))
+	return nil
+}
+
+// declineAutolinks remembers declined targets so save doesn't ask again for
+// the same mentions during this session.
+func (a *App) declineAutolinks(relPath string, names []string) {
+	if a.autolinkDeclined == nil {
+		a.autolinkDeclined = make(map[string]map[string]bool)
+	}
+	if a.autolinkDeclined[relPath] == nil {
+		a.autolinkDeclined[relPath] = make(map[string]bool)
+	}
+	for _, n := range names {
+		a.autolinkDeclined[relPath][n] = true
+	}
 }
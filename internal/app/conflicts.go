@@ -0,0 +1,201 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pfassina/kopr/internal/markdown"
+)
+
+// Neovim highlight groups for each part of a merge conflict.
+const (
+	conflictMarkerGroup = "DiffText"
+	conflictOursGroup   = "DiffAdd"
+	conflictBaseGroup   = "DiffDelete"
+	conflictTheirsGroup = "DiffChange"
+)
+
+// conflictBuffer returns the lines of the note in the editor and the
+// cursor's 0-based line.
+func (a *App) conflictBuffer() ([]string, int, bool) {
+	rpc := a.editor.GetRPC()
+	if rpc == nil || a.currentFile == "" {
+		return nil, 0, false
+	}
+	content, err := rpc.BufferContent()
+	if err != nil {
+		return nil, 0, false
+	}
+	lines := make([]string, len(content))
+	for i, l := range content {
+		lines[i] = string(l)
+	}
+	row, _, err := rpc.CursorPosition()
+	if err != nil {
+		return nil, 0, false
+	}
+	return lines, row - 1, true
+}
+
+// checkConflicts highlights the merge conflicts in the open note and says
+// how to resolve them when a note with conflicts is opened.
+func (a *App) checkConflicts() {
+	lines, _, ok := a.conflictBuffer()
+	if !ok {
+		return
+	}
+	conflicts := markdown.FindConflicts(lines)
+	if len(conflicts) == 0 && a.conflicts == 0 {
+		a.conflictFile = a.currentFile
+		return
+	}
+	groups := make(map[int]string)
+	for _, c := range conflicts {
+		for i := c.Start; i <= c.End; i++ {
+			switch {
+			case i == c.Start || i == c.Base || i == c.Mid || i == c.End:
+				groups[i+1] = conflictMarkerGroup
+			case i < c.Mid && (c.Base < 0 || i < c.Base):
+				groups[i+1] = conflictOursGroup
+			case i < c.Mid:
+				groups[i+1] = conflictBaseGroup
+			default:
+				groups[i+1] = conflictTheirsGroup
+			}
+		}
+	}
+	if err := a.editor.GetRPC().SetLineHighlights(groups); err != nil {
+		a.status.SetError(fmt.Sprintf("highlight conflicts: %v", err))
+	}
+	if len(conflicts) > 0 && (a.conflicts == 0 || a.conflictFile != a.currentFile) {
+		a.status.SetMessage(fmt.Sprintf("%s has %d merge conflicts: Space g o/t/a keeps ours/theirs/both, Space g f finishes",
+			a.currentFile, len(conflicts)))
+	}
+	a.conflicts = len(conflicts)
+	a.conflictFile = a.currentFile
+}
+
+// conflictAt returns the conflict the cursor is in, else the next one
+// after it, wrapping to the first.
+func conflictAt(conflicts []markdown.Conflict, row int) markdown.Conflict {
+	for _, c := range conflicts {
+		if c.End >= row {
+			return c
+		}
+	}
+	return conflicts[0]
+}
+
+// ResolveConflict keeps one side, or both, of the conflict under or after
+// the cursor and removes its markers.
+func (a *App) ResolveConflict(r markdown.Resolution) {
+	lines, row, ok := a.conflictBuffer()
+	if !ok {
+		return
+	}
+	conflicts := markdown.FindConflicts(lines)
+	if len(conflicts) == 0 {
+		a.status.SetError("no merge conflicts in this note")
+		return
+	}
+	c := conflictAt(conflicts, row)
+	keep := c.Resolve(lines, r)
+	if keep == nil {
+		keep = []string{}
+	}
+	rpc := a.editor.GetRPC()
+	if err := rpc.ReplaceLines(c.Start, c.End+1, keep); err != nil {
+		a.status.SetError(fmt.Sprintf("resolve conflict: %v", err))
+		return
+	}
+	if total := len(lines) - (c.End + 1 - c.Start) + len(keep); total > 0 {
+		rpc.SetCursorPosition(min(c.Start+1, total), 0) //nolint:errcheck // cursor placement is cosmetic
+	}
+	a.checkConflicts()
+	a.status.SetMessage(fmt.Sprintf("Kept %s; %d conflicts left", resolutionName(r), len(conflicts)-1))
+}
+
+// NextConflict moves the cursor to the start of the next conflict, wrapping
+// to the first.
+func (a *App) NextConflict() {
+	lines, row, ok := a.conflictBuffer()
+	if !ok {
+		return
+	}
+	conflicts := markdown.FindConflicts(lines)
+	if len(conflicts) == 0 {
+		a.status.SetError("no merge conflicts in this note")
+		return
+	}
+	next := conflicts[0]
+	for _, c := range conflicts {
+		if c.Start > row {
+			next = c
+			break
+		}
+	}
+	a.editor.GetRPC().SetCursorPosition(next.Start+1, 0) //nolint:errcheck // cursor placement is cosmetic
+}
+
+// FinishConflicts saves the note once its conflicts are resolved, first
+// asking which side to keep for any still left.
+func (a *App) FinishConflicts() {
+	lines, _, ok := a.conflictBuffer()
+	if !ok {
+		return
+	}
+	n := len(markdown.FindConflicts(lines))
+	if n == 0 {
+		a.saveResolved("No merge conflicts left")
+		return
+	}
+	a.pendingPrompt = promptAction{kind: "resolve-conflicts"}
+	a.prompt.ShowChoices(fmt.Sprintf("%d merge conflicts left. Keep for all of them:", n),
+		[]string{"Ours", "Theirs", "Both"})
+}
+
+// resolveAllConflicts resolves every conflict left in the note the same
+// way, then saves it.
+func (a *App) resolveAllConflicts(choice string) {
+	var r markdown.Resolution
+	switch strings.ToLower(choice) {
+	case "ours":
+		r = markdown.KeepOurs
+	case "theirs":
+		r = markdown.KeepTheirs
+	case "both":
+		r = markdown.KeepBoth
+	default:
+		return
+	}
+	lines, _, ok := a.conflictBuffer()
+	if !ok {
+		return
+	}
+	n := len(markdown.FindConflicts(lines))
+	if err := a.editor.GetRPC().SetBufferLines(markdown.ResolveAll(lines, r)); err != nil {
+		a.status.SetError(fmt.Sprintf("resolve conflicts: %v", err))
+		return
+	}
+	a.checkConflicts()
+	a.saveResolved(fmt.Sprintf("Kept %s in %d conflicts", resolutionName(r), n))
+}
+
+// saveResolved writes the note and reports msg.
+func (a *App) saveResolved(msg string) {
+	if err := a.editor.GetRPC().ExecCommand("write"); err != nil {
+		a.status.SetError(fmt.Sprintf("save: %v", err))
+		return
+	}
+	a.status.SetMessage(msg + "; saved")
+}
+
+func resolutionName(r markdown.Resolution) string {
+	switch r {
+	case markdown.KeepTheirs:
+		return "theirs"
+	case markdown.KeepBoth:
+		return "both"
+	}
+	return "ours"
+}
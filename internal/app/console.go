@@ -0,0 +1,71 @@
+package app
+
+import (
+	"strings"
+
+	"github.com/pfassina/kopr/internal/index"
+	"github.com/pfassina/kopr/internal/panel"
+)
+
+// maxConsoleRows caps how many rows one console query loads.
+const maxConsoleRows = 500
+
+// OpenQueryConsole shows the query console, for one-off questions about the
+// vault asked with finder operators or read-only SQL.
+func (a *App) OpenQueryConsole() {
+	if a.db == nil {
+		return
+	}
+	a.console.Show()
+}
+
+// runConsoleQuery runs query against the index and shows the rows in the
+// console. Input starting with SELECT or WITH is SQL; anything else is a
+// finder query, listed as path, title and modification date.
+func (a *App) runConsoleQuery(query string) {
+	if index.IsSQL(query) {
+		res, err := a.db.QueryReadOnly(query, maxConsoleRows)
+		if err != nil {
+			a.console.SetError(err.Error())
+			return
+		}
+		a.console.SetResult(consoleTable(res))
+		return
+	}
+
+	results, err := a.db.SearchQuery(a.parseQuery(query), maxConsoleRows+1)
+	if err != nil {
+		a.console.SetError(err.Error())
+		return
+	}
+	r := panel.ConsoleResult{Columns: []string{"path", "title", "modified"}}
+	if len(results) > maxConsoleRows {
+		results, r.Truncated = results[:maxConsoleRows], true
+	}
+	for _, n := range results {
+		r.Rows = append(r.Rows, []string{n.Path, n.Title, n.Modified().Format("2006-01-02")})
+		r.Paths = append(r.Paths, n.Path)
+	}
+	a.console.SetResult(r)
+}
+
+// consoleTable turns SQL rows into console rows. Rows open the note named
+// in their path column, when the query selected one.
+func consoleTable(res index.TableResult) panel.ConsoleResult {
+	r := panel.ConsoleResult{Columns: res.Columns, Rows: res.Rows, Truncated: res.Truncated}
+	pathCol := -1
+	for i, col := range res.Columns {
+		if strings.EqualFold(col, "path") {
+			pathCol = i
+			break
+		}
+	}
+	if pathCol < 0 {
+		return r
+	}
+	r.Paths = make([]string, len(res.Rows))
+	for i, row := range res.Rows {
+		r.Paths[i] = row[pathCol]
+	}
+	return r
+}
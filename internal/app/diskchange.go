@@ -0,0 +1,68 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/pfassina/kopr/internal/editor"
+)
+
+// Choices offered when the open note changed on disk while it has unsaved
+// changes.
+const (
+	diskChangeReload = "Reload from disk"
+	diskChangeKeep   = "Keep my version"
+)
+
+// checkOpenNoteOnDisk asks Neovim to check the open note's file when the
+// watcher saw path written to. Neovim compares the file's modification
+// time with the buffer's, so Kopr's own saves pass unnoticed.
+func (a *App) checkOpenNoteOnDisk(path string) {
+	rpc := a.editor.GetRPC()
+	if rpc == nil || a.currentFile == "" || path != filepath.Join(a.cfg.VaultPath, a.currentFile) {
+		return
+	}
+	if err := rpc.ExecCommand("checktime"); err != nil {
+		a.status.SetError(fmt.Sprintf("check %s on disk: %v", a.currentFile, err))
+	}
+}
+
+// handleFileChangedOnDisk reports a file Neovim found changed on disk and,
+// for the open note with unsaved changes, asks whether to reload it.
+func (a *App) handleFileChangedOnDisk(msg editor.FileChangedOnDiskMsg) {
+	name := filepath.Base(msg.Path)
+	switch {
+	case msg.Deleted:
+		a.status.SetError(name + " was deleted on disk; saving writes it back")
+	case msg.Reloaded:
+		a.status.SetMessage("Reloaded " + name + ": changed on disk")
+		if a.currentFile != "" && msg.Path == filepath.Join(a.cfg.VaultPath, a.currentFile) {
+			a.checkConflicts()
+		}
+	case a.currentFile == "" || msg.Path != filepath.Join(a.cfg.VaultPath, a.currentFile) || a.prompt.Visible():
+		// Not something to interrupt with a prompt: say how to resolve it.
+		a.status.SetError(name + " changed on disk; :e! reloads it, :w keeps your version")
+	default:
+		a.pendingPrompt = promptAction{kind: "disk-change", path: a.currentFile}
+		a.prompt.ShowChoices(name+" changed on disk, and you have unsaved changes", []string{diskChangeReload, diskChangeKeep})
+	}
+}
+
+// handleDiskChangePrompt carries out the choice made in
+// handleFileChangedOnDisk.
+func (a *App) handleDiskChangePrompt(action promptAction, choice string) {
+	if action.path != a.currentFile {
+		return
+	}
+	rpc := a.editor.GetRPC()
+	if choice != diskChangeReload || rpc == nil {
+		a.status.SetMessage("Kept your version of " + filepath.Base(action.path) + "; saving overwrites the file on disk")
+		return
+	}
+	if err := rpc.ExecCommand("edit!"); err != nil {
+		a.status.SetError(fmt.Sprintf("reload %s: %v", action.path, err))
+		return
+	}
+	a.status.SetMessage("Reloaded " + filepath.Base(action.path) + " from disk")
+	a.checkConflicts()
+}
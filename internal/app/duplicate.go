@@ -0,0 +1,56 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxDuplicates bounds the search for a free name-copy-N.md.
+const maxDuplicates = 100
+
+// DuplicateNote copies a note next to itself as name-copy.md, or
+// name-copy-2.md and so on when that is taken, here or, with vault-wide
+// basenames, anywhere in the vault.
+func (a *App) DuplicateNote(relPath string) tea.Cmd {
+	if a.refuseEdit() || a.refuseExternal(relPath) {
+		return nil
+	}
+	newRel := duplicateName(relPath, func(p string) bool {
+		if _, err := os.Stat(filepath.Join(a.cfg.VaultPath, p)); err == nil {
+			return true
+		}
+		return a.checkUniqueBasename(p) != ""
+	})
+	if newRel == "" {
+		a.status.SetError(fmt.Sprintf("duplicate: no free name for a copy of %s", filepath.Base(relPath)))
+		return nil
+	}
+	if err := a.vault.CopyNoteTo(relPath, newRel); err != nil {
+		a.status.SetError(fmt.Sprintf("duplicate: %v", err))
+		return nil
+	}
+	a.tree.Refresh()
+	a.status.SetMessage(fmt.Sprintf("Duplicated %s as %s", filepath.Base(relPath), filepath.Base(newRel)))
+	return nil
+}
+
+// duplicateName returns the first of name-copy.md, name-copy-2.md, ... in
+// relPath's folder that isn't taken, or "" if they all are.
+func duplicateName(relPath string, taken func(string) bool) string {
+	ext := filepath.Ext(relPath)
+	stem := strings.TrimSuffix(relPath, ext) + "-copy"
+	for n := 1; n <= maxDuplicates; n++ {
+		p := stem + ext
+		if n > 1 {
+			p = fmt.Sprintf("%s-%d%s", stem, n, ext)
+		}
+		if !taken(p) {
+			return p
+		}
+	}
+	return ""
+}
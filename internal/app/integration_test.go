@@ -0,0 +1,373 @@
+//go:build integration
+
+package app
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/editor"
+	"github.com/pfassina/kopr/internal/index"
+	"github.com/pfassina/kopr/internal/panel"
+)
+
+// These tests run app flows end to end against a real Neovim started with
+// editor.StartHeadless. Run them with make test-integration; they are
+// skipped when nvim isn't on PATH.
+
+// sink collects the messages Neovim sends the app.
+type sink chan tea.Msg
+
+func (s sink) Send(msg tea.Msg) { s <- msg }
+
+// startApp builds an App on a temp vault holding files, indexed and with a
+// headless Neovim as its editor.
+func startApp(t *testing.T, files map[string]string) (*App, sink) {
+	t.Helper()
+	if _, err := exec.LookPath("nvim"); err != nil {
+		t.Skip("nvim not installed")
+	}
+	root := t.TempDir()
+	for rel, content := range files {
+		path := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.VaultPath = root
+	a := New(cfg)
+	if a.indexer == nil {
+		t.Fatal("index failed to open")
+	}
+	if err := a.indexer.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Buffered so Neovim's notifications never wait on the test; await
+	// skips the ones it isn't looking for.
+	s := make(sink, 1024)
+	ed, err := editor.StartHeadless(root, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.editor = ed
+	t.Cleanup(a.Close)
+	return &a, s
+}
+
+// await returns the next message of type T that Neovim sends.
+func await[T any](t *testing.T, s sink) T {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case msg := <-s:
+			if m, ok := msg.(T); ok {
+				return m
+			}
+		case <-timeout:
+			var zero T
+			t.Fatalf("timed out waiting for %T", zero)
+			return zero
+		}
+	}
+}
+
+// step runs msg through the app and then the message its command
+// returns, as the Bubble Tea runtime would.
+func step(a *App, msg tea.Msg) {
+	_, cmd := a.Update(msg)
+	if cmd != nil {
+		if next := cmd(); next != nil {
+			a.Update(next)
+		}
+	}
+}
+
+// currentBuffer returns the vault-relative path of the buffer open in
+// Neovim.
+func currentBuffer(t *testing.T, a *App) string {
+	t.Helper()
+	name, err := a.editor.GetRPC().CurrentFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rel, err := filepath.Rel(a.cfg.VaultPath, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func readFile(t *testing.T, a *App, rel string) string {
+	t.Helper()
+	content, err := os.ReadFile(filepath.Join(a.cfg.VaultPath, rel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(content)
+}
+
+func TestIntegrationOpenNote(t *testing.T) {
+	a, _ := startApp(t, map[string]string{
+		"inbox.md":         "# Inbox\n\n- call back\n",
+		"projects/plan.md": "# Plan\n",
+	})
+
+	a.navigateTo(filepath.FromSlash("projects/plan.md"))
+	if got := currentBuffer(t, a); got != "projects/plan.md" {
+		t.Errorf("buffer = %q, want projects/plan.md", got)
+	}
+
+	a.navigateTo("inbox.md")
+	if got := currentBuffer(t, a); got != "inbox.md" {
+		t.Errorf("buffer = %q, want inbox.md", got)
+	}
+	lines, err := a.editor.GetRPC().BufferContent()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 || string(lines[2]) != "- call back" {
+		t.Errorf("buffer content = %q", lines)
+	}
+	if a.prevFile != filepath.FromSlash("projects/plan.md") {
+		t.Errorf("prevFile = %q, want projects/plan.md", a.prevFile)
+	}
+}
+
+func TestIntegrationSaveAs(t *testing.T) {
+	a, s := startApp(t, map[string]string{"inbox.md": "# Inbox\n"})
+	rpc := a.editor.GetRPC()
+	if err := rpc.NewBuffer(); err != nil {
+		t.Fatal(err)
+	}
+	if err := rpc.SetBufferLines([]string{"# Idea", "", "Worth a note."}); err != nil {
+		t.Fatal(err)
+	}
+
+	// :w on an unnamed buffer asks for a name instead of failing.
+	if err := rpc.Input(":w<CR>"); err != nil {
+		t.Fatal(err)
+	}
+	step(a, await[editor.SaveUnnamedMsg](t, s))
+	if !a.prompt.Visible() {
+		t.Fatal("save-as prompt not shown")
+	}
+	a.handlePromptResult("ideas/idea")
+	if a.prompt.Visible() {
+		t.Fatal("save-as prompt still shown")
+	}
+
+	if got := readFile(t, a, "ideas/idea.md"); got != "# Idea\n\nWorth a note.\n" {
+		t.Errorf("ideas/idea.md = %q", got)
+	}
+	if got := currentBuffer(t, a); got != "ideas/idea.md" {
+		t.Errorf("buffer = %q, want ideas/idea.md", got)
+	}
+	if a.currentFile != filepath.FromSlash("ideas/idea.md") {
+		t.Errorf("currentFile = %q", a.currentFile)
+	}
+}
+
+func TestIntegrationRenameRewritesLinks(t *testing.T) {
+	a, _ := startApp(t, map[string]string{
+		"target.md": "# Target\n",
+		"source.md": "See [[target]] and [[target#Target|this]].\n",
+	})
+	a.navigateTo("target.md")
+
+	step(a, panel.TreeRenameNoteMsg{Path: "target.md", Name: "target.md"})
+	a.handlePromptResult("renamed")
+	if !a.changes.Visible() {
+		t.Fatal("link rewrites not previewed")
+	}
+	step(a, panel.ChangePreviewApplyMsg{Included: []string{"source.md", "target.md"}})
+
+	if _, err := os.Stat(filepath.Join(a.cfg.VaultPath, "target.md")); !os.IsNotExist(err) {
+		t.Errorf("target.md still exists: %v", err)
+	}
+	if got := readFile(t, a, "renamed.md"); got != "# Target\n" {
+		t.Errorf("renamed.md = %q", got)
+	}
+	if got, want := readFile(t, a, "source.md"), "See [[renamed]] and [[renamed#Target|this]].\n"; got != want {
+		t.Errorf("source.md = %q, want %q", got, want)
+	}
+	// The open buffer follows the rename.
+	if got := currentBuffer(t, a); got != "renamed.md" {
+		t.Errorf("buffer = %q, want renamed.md", got)
+	}
+	if a.currentFile != "renamed.md" {
+		t.Errorf("currentFile = %q", a.currentFile)
+	}
+	backlinks, err := a.db.GetBacklinks("renamed.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(backlinks, func(b index.BacklinkResult) bool { return b.SourcePath == "source.md" }) {
+		t.Errorf("backlinks of renamed.md = %v, want source.md", backlinks)
+	}
+}
+
+func TestIntegrationUndoFileOps(t *testing.T) {
+	a, _ := startApp(t, map[string]string{
+		"target.md":      "# Target\n",
+		"source.md":      "See [[target]].\n",
+		"notes/draft.md": "# Draft\n",
+	})
+	a.navigateTo("target.md")
+
+	step(a, panel.TreeRenameNoteMsg{Path: "target.md", Name: "target.md"})
+	a.handlePromptResult("renamed")
+	step(a, panel.ChangePreviewApplyMsg{Included: []string{"source.md", "target.md"}})
+	a.handleDeleteNote("yes", "notes/draft.md")
+
+	// Undo goes back through the log, newest first.
+	a.UndoFileOp()
+	if got := readFile(t, a, "notes/draft.md"); got != "# Draft\n" {
+		t.Errorf("notes/draft.md = %q", got)
+	}
+	a.UndoFileOp()
+	if got := readFile(t, a, "target.md"); got != "# Target\n" {
+		t.Errorf("target.md = %q", got)
+	}
+	if got := readFile(t, a, "source.md"); got != "See [[target]].\n" {
+		t.Errorf("source.md = %q, want the link rewritten back", got)
+	}
+	if got := currentBuffer(t, a); got != "target.md" {
+		t.Errorf("buffer = %q, want target.md", got)
+	}
+	if len(a.fileOps) != 0 {
+		t.Errorf("undo logged %d operations", len(a.fileOps))
+	}
+}
+
+func TestIntegrationFollowLink(t *testing.T) {
+	a, s := startApp(t, map[string]string{
+		"source.md":       "# Source\n\nRead [[target]] next.\n",
+		"deep/target.md":  "# Target\n\n## Details\n\nMore.\n",
+		"other/readme.md": "# Readme\n",
+	})
+	a.navigateTo("source.md")
+	rpc := a.editor.GetRPC()
+	if err := rpc.SetCursorPosition(3, strings.Index("Read [[target]] next.", "target")); err != nil {
+		t.Fatal(err)
+	}
+
+	// gf is mapped to notify the app, which resolves the link.
+	if err := rpc.Input("gf"); err != nil {
+		t.Fatal(err)
+	}
+	step(a, await[editor.FollowLinkMsg](t, s))
+	if got := currentBuffer(t, a); got != "deep/target.md" {
+		t.Errorf("buffer = %q, want deep/target.md", got)
+	}
+
+	// gb goes back to the note the link was followed from.
+	if err := rpc.Input("gb"); err != nil {
+		t.Fatal(err)
+	}
+	step(a, await[editor.GoBackMsg](t, s))
+	if got := currentBuffer(t, a); got != "source.md" {
+		t.Errorf("buffer after gb = %q, want source.md", got)
+	}
+}
+
+func TestIntegrationExternalChange(t *testing.T) {
+	a, s := startApp(t, map[string]string{
+		"plan.md":  "# Plan\n",
+		"other.md": "# Other\n",
+	})
+	rpc := a.editor.GetRPC()
+	abs := filepath.Join(a.cfg.VaultPath, "plan.md")
+	writes := 0
+	writeExternally := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Neovim compares modification times in seconds: move each write
+		// past the last one.
+		writes++
+		later := time.Now().Add(time.Duration(writes) * time.Second)
+		if err := os.Chtimes(abs, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	bufferText := func() string {
+		t.Helper()
+		lines, err := rpc.BufferContent()
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := make([]string, len(lines))
+		for i, l := range lines {
+			text[i] = string(l)
+		}
+		return strings.Join(text, "\n")
+	}
+	a.navigateTo("plan.md")
+
+	// Without unsaved changes the buffer follows the file.
+	writeExternally("# Plan\n\nPulled from elsewhere.\n")
+	a.checkOpenNoteOnDisk(abs)
+	msg := await[editor.FileChangedOnDiskMsg](t, s)
+	if !msg.Reloaded {
+		t.Fatalf("unmodified buffer not reloaded: %+v", msg)
+	}
+	step(a, msg)
+	if got := bufferText(); got != "# Plan\n\nPulled from elsewhere." {
+		t.Errorf("buffer after reload = %q", got)
+	}
+
+	// With unsaved changes the user picks.
+	if err := rpc.SetBufferLines([]string{"# Plan", "", "Mine."}); err != nil {
+		t.Fatal(err)
+	}
+	writeExternally("# Plan\n\nTheirs, a second time.\n")
+	a.checkOpenNoteOnDisk(abs)
+	msg = await[editor.FileChangedOnDiskMsg](t, s)
+	if msg.Reloaded || msg.Deleted {
+		t.Fatalf("modified buffer reloaded: %+v", msg)
+	}
+	step(a, msg)
+	if !a.prompt.Visible() {
+		t.Fatal("reload prompt not shown")
+	}
+	if got := bufferText(); got != "# Plan\n\nMine." {
+		t.Errorf("buffer changed before the choice: %q", got)
+	}
+	a.handlePromptResult(diskChangeReload)
+	if got := bufferText(); got != "# Plan\n\nTheirs, a second time." {
+		t.Errorf("buffer after choosing reload = %q", got)
+	}
+
+	// Saving from Kopr isn't an external change.
+	if err := rpc.ExecCommand("write"); err != nil {
+		t.Fatal(err)
+	}
+	a.checkOpenNoteOnDisk(abs)
+	timeout := time.After(200 * time.Millisecond)
+	for {
+		select {
+		case m := <-s:
+			if m, ok := m.(editor.FileChangedOnDiskMsg); ok {
+				t.Errorf("own save reported as an external change: %+v", m)
+			}
+		case <-timeout:
+			return
+		}
+	}
+}
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -12,8 +14,11 @@ import (
 
 	"github.com/pfassina/kopr/internal/config"
 	"github.com/pfassina/kopr/internal/editor"
+	"github.com/pfassina/kopr/internal/git"
 	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/panel"
 	"github.com/pfassina/kopr/internal/theme"
+	"github.com/pfassina/kopr/internal/vault"
 )
 
 // Binding represents a leader key binding.
@@ -22,6 +27,7 @@ type Binding struct {
 	Label    string
 	Action   func(a *App) tea.Cmd
 	Children map[string]*Binding
+	Edits    bool // changes notes, so unavailable in read-only mode
 }
 
 // LeaderState tracks the leader key sequence.
@@ -35,24 +41,63 @@ type LeaderState struct {
 // leaderTimeoutMsg signals leader key timeout.
 type leaderTimeoutMsg struct{}
 
+// fileManagerDoneMsg reports the outcome of opening a folder externally.
+type fileManagerDoneMsg struct{ err error }
+
+// externalEditDoneMsg is sent when the external editor exits.
+type externalEditDoneMsg struct {
+	relPath string
+	err     error
+}
+
 func newBindings() map[string]*Binding {
 	return map[string]*Binding{
 		" ": {
 			Key: "Space", Label: "Fuzzy finder",
 			Action: func(a *App) tea.Cmd {
-				a.ToggleFinder()
-				return nil
+				return a.ToggleFinder()
 			},
 		},
 		"f": {
 			Key: "f", Label: "+find",
 			Children: map[string]*Binding{
 				"n": {Key: "n", Label: "Find/create note", Action: func(a *App) tea.Cmd {
-					a.ToggleFinder()
-					return nil
+					return a.ToggleFinder()
 				}},
 				"/": {Key: "/", Label: "Find in notes", Action: func(a *App) tea.Cmd {
-					a.OpenGrepFinder()
+					return a.OpenGrepFinder()
+				}},
+				"r": {Key: "r", Label: "Recent notes", Action: func(a *App) tea.Cmd {
+					return a.OpenRecentFinder()
+				}},
+				"s": {Key: "s", Label: "Saved searches", Action: func(a *App) tea.Cmd {
+					return a.OpenSavedSearchFinder()
+				}},
+				"t": {Key: "t", Label: "Tags", Action: func(a *App) tea.Cmd {
+					return a.OpenTagFinder()
+				}},
+				"m": {Key: "m", Label: "Recently modified", Action: func(a *App) tea.Cmd {
+					return a.OpenModifiedFinder()
+				}},
+				"b": {Key: "b", Label: "Broken links", Action: func(a *App) tea.Cmd {
+					return a.OpenBrokenLinksFinder()
+				}},
+				"a": {Key: "a", Label: "Attachments", Action: func(a *App) tea.Cmd {
+					return a.OpenAttachmentFinder()
+				}},
+				"d": {Key: "d", Label: "Random note", Action: func(a *App) tea.Cmd {
+					a.openRandomNote("")
+					return nil
+				}},
+				"D": {Key: "D", Label: "Random note in tag/folder", Action: func(a *App) tea.Cmd {
+					a.OpenRandomNoteIn()
+					return nil
+				}},
+				"x": {Key: "x", Label: "Open tasks", Action: func(a *App) tea.Cmd {
+					return a.OpenTasksFinder()
+				}},
+				"R": {Key: "R", Label: "Find & replace in vault", Edits: true, Action: func(a *App) tea.Cmd {
+					a.FindReplace()
 					return nil
 				}},
 			},
@@ -60,18 +105,29 @@ func newBindings() map[string]*Binding {
 		"n": {
 			Key: "n", Label: "+note",
 			Children: map[string]*Binding{
-				"n": {Key: "n", Label: "New note", Action: func(a *App) tea.Cmd {
+				"n": {Key: "n", Label: "New note", Edits: true, Action: func(a *App) tea.Cmd {
 					a.CreateBlankNote()
 					return nil
 				}},
-				"d": {Key: "d", Label: "Daily note", Action: func(a *App) tea.Cmd {
+				"d": {Key: "d", Label: "Daily note", Edits: true, Action: func(a *App) tea.Cmd {
 					a.CreateDailyNote()
 					return nil
 				}},
-				"i": {Key: "i", Label: "Inbox capture", Action: func(a *App) tea.Cmd {
+				"i": {Key: "i", Label: "Inbox capture", Edits: true, Action: func(a *App) tea.Cmd {
 					a.CreateInboxNote()
 					return nil
 				}},
+				"t": {Key: "t", Label: "Triage inbox", Edits: true, Action: func(a *App) tea.Cmd {
+					a.OpenInboxTriage()
+					return nil
+				}},
+				"a": {Key: "a", Label: "Archive note", Edits: true, Action: func(a *App) tea.Cmd {
+					return a.ArchiveNotes()
+				}},
+				"x": {Key: "x", Label: "Trash", Edits: true, Action: func(a *App) tea.Cmd {
+					a.OpenTrash()
+					return nil
+				}},
 				"r": {Key: "r", Label: "Rename note", Action: func(a *App) tea.Cmd {
 					return nil // TODO
 				}},
@@ -80,9 +136,8 @@ func newBindings() map[string]*Binding {
 		"t": {
 			Key: "t", Label: "+template",
 			Children: map[string]*Binding{
-				"i": {Key: "i", Label: "Insert template", Action: func(a *App) tea.Cmd {
-					a.InsertTemplate()
-					return nil
+				"i": {Key: "i", Label: "Insert template", Edits: true, Action: func(a *App) tea.Cmd {
+					return a.InsertTemplate()
 				}},
 			},
 		},
@@ -100,6 +155,81 @@ func newBindings() map[string]*Binding {
 				"s": {Key: "s", Label: "Toggle status", Action: func(a *App) tea.Cmd {
 					return nil // TODO
 				}},
+				"g": {Key: "g", Label: "Local graph", Action: func(a *App) tea.Cmd {
+					a.ToggleLocalGraph()
+					return nil
+				}},
+				"h": {Key: "h", Label: "Habit tracker", Action: func(a *App) tea.Cmd {
+					a.OpenHabitTracker()
+					return nil
+				}},
+				"f": {Key: "f", Label: "Reveal in tree", Action: func(a *App) tea.Cmd {
+					a.RevealInTree()
+					return nil
+				}},
+				"F": {Key: "F", Label: "Open folder externally", Action: func(a *App) tea.Cmd {
+					return a.OpenFolderExternally()
+				}},
+				"x": {Key: "x", Label: "Toggle terminal", Action: func(a *App) tea.Cmd {
+					return a.ToggleTerminal()
+				}},
+				"c": {Key: "c", Label: "Copy mode", Action: func(a *App) tea.Cmd {
+					a.EnterCopyMode()
+					return nil
+				}},
+			},
+		},
+		"o": {
+			Key: "o", Label: "+open",
+			Children: map[string]*Binding{
+				"e": {Key: "e", Label: "External editor", Edits: true, Action: func(a *App) tea.Cmd {
+					return a.OpenInExternalEditor()
+				}},
+			},
+		},
+		"e": {
+			Key: "e", Label: "+export",
+			Children: map[string]*Binding{
+				"c": {Key: "c", Label: "Copy note", Action: func(a *App) tea.Cmd {
+					return a.ExportNote(exportClipboard, false)
+				}},
+				"h": {Key: "h", Label: "Copy note as HTML", Action: func(a *App) tea.Cmd {
+					return a.ExportNote(exportClipboard, true)
+				}},
+				"p": {Key: "p", Label: "Print note on exit", Action: func(a *App) tea.Cmd {
+					return a.ExportNote(exportStdout, false)
+				}},
+			},
+		},
+		"g": {
+			Key: "g", Label: "+git",
+			Children: map[string]*Binding{
+				"h": {Key: "h", Label: "Note history", Action: func(a *App) tea.Cmd {
+					return a.OpenNoteHistory()
+				}},
+				"b": {Key: "b", Label: "Blame annotations", Action: func(a *App) tea.Cmd {
+					return a.ToggleBlame()
+				}},
+				"o": {Key: "o", Label: "Conflict: keep ours", Edits: true, Action: func(a *App) tea.Cmd {
+					a.ResolveConflict(markdown.KeepOurs)
+					return nil
+				}},
+				"t": {Key: "t", Label: "Conflict: keep theirs", Edits: true, Action: func(a *App) tea.Cmd {
+					a.ResolveConflict(markdown.KeepTheirs)
+					return nil
+				}},
+				"a": {Key: "a", Label: "Conflict: keep both", Edits: true, Action: func(a *App) tea.Cmd {
+					a.ResolveConflict(markdown.KeepBoth)
+					return nil
+				}},
+				"n": {Key: "n", Label: "Next conflict", Action: func(a *App) tea.Cmd {
+					a.NextConflict()
+					return nil
+				}},
+				"f": {Key: "f", Label: "Finish conflicts", Edits: true, Action: func(a *App) tea.Cmd {
+					a.FinishConflicts()
+					return nil
+				}},
 			},
 		},
 		"z": {
@@ -123,18 +253,60 @@ func newBindings() map[string]*Binding {
 		"m": {
 			Key: "m", Label: "+markdown",
 			Children: map[string]*Binding{
-				"f": {Key: "f", Label: "Format document", Action: func(a *App) tea.Cmd {
+				"f": {Key: "f", Label: "Format document", Edits: true, Action: func(a *App) tea.Cmd {
 					a.FormatDocument()
 					return nil
 				}},
+				"l": {Key: "l", Label: "Link mentions", Edits: true, Action: func(a *App) tea.Cmd {
+					return a.LinkMentions()
+				}},
+				"p": {Key: "p", Label: "Toggle preview", Action: func(a *App) tea.Cmd {
+					a.TogglePreview()
+					return nil
+				}},
+				"i": {Key: "i", Label: "Paste image", Edits: true, Action: func(a *App) tea.Cmd {
+					return a.PasteImage()
+				}},
+			},
+		},
+		"r": {
+			Key: "r", Label: "+review",
+			Children: map[string]*Binding{
+				"s": {Key: "s", Label: "Stale notes", Edits: true, Action: func(a *App) tea.Cmd {
+					a.OpenStaleReview()
+					return nil
+				}},
+			},
+		},
+		"i": {
+			Key: "i", Label: "+index",
+			Children: map[string]*Binding{
+				"o": {Key: "o", Label: "Optimize index", Action: func(a *App) tea.Cmd {
+					return a.OptimizeIndex()
+				}},
+				"q": {Key: "q", Label: "Query console", Action: func(a *App) tea.Cmd {
+					a.OpenQueryConsole()
+					return nil
+				}},
+				"s": {Key: "s", Label: "Scan for missed changes", Action: func(a *App) tea.Cmd {
+					return a.ReconcileIndex()
+				}},
+			},
+		},
+		"u": {
+			Key: "u", Label: "Undo file operation", Edits: true,
+			Action: func(a *App) tea.Cmd {
+				return a.UndoFileOp()
 			},
 		},
 		"c": {
 			Key: "c", Label: "+config",
 			Children: map[string]*Binding{
 				"r": {Key: "r", Label: "Reload config", Action: func(a *App) tea.Cmd {
-					a.ReloadConfig()
-					return nil
+					return a.ReloadConfig()
+				}},
+				"i": {Key: "i", Label: "Pause/resume indexing", Action: func(a *App) tea.Cmd {
+					return a.ToggleIndexing()
 				}},
 			},
 		},
@@ -155,17 +327,14 @@ func (a *App) handleLeaderKey(key string) (consumed bool, cmd tea.Cmd) {
 			return false, nil
 		}
 		// Only check Neovim mode when editor is focused
-		if a.focused == focusEditor && a.editor.Mode() != editor.ModeNormal {
+		if a.focused == focusEditor && (a.editor.Mode() != editor.ModeNormal || a.editor.InCopyMode()) {
 			return false, nil
 		}
 		a.leader.active = true
 		a.leader.keys = ""
 		a.leader.node = a.bindings
 		a.leader.showHelp = false
-		// Start timeout for which-key popup
-		return true, tea.Tick(time.Duration(a.cfg.LeaderTimeout)*time.Millisecond, func(time.Time) tea.Msg {
-			return leaderTimeoutMsg{}
-		})
+		return true, a.leaderTimeout()
 	}
 
 	// We're in leader mode - accumulate the key
@@ -176,13 +345,14 @@ func (a *App) handleLeaderKey(key string) (consumed bool, cmd tea.Cmd) {
 			// This is a group - wait for next key
 			a.leader.node = binding.Children
 			a.leader.showHelp = false
-			return true, tea.Tick(time.Duration(a.cfg.LeaderTimeout)*time.Millisecond, func(time.Time) tea.Msg {
-				return leaderTimeoutMsg{}
-			})
+			return true, a.leaderTimeout()
 		}
 		// Leaf binding - execute
 		a.leader.active = false
 		a.leader.showHelp = false
+		if binding.Edits && a.refuseEdit() {
+			return true, nil
+		}
 		if binding.Action != nil {
 			return true, binding.Action(a)
 		}
@@ -195,6 +365,17 @@ func (a *App) handleLeaderKey(key string) (consumed bool, cmd tea.Cmd) {
 	return true, nil
 }
 
+// leaderTimeout starts the timer that pops up which-key. Low-bandwidth
+// sessions skip it: the popup is a large redraw the user rarely waits for.
+func (a *App) leaderTimeout() tea.Cmd {
+	if a.lowBandwidth {
+		return nil
+	}
+	return tea.Tick(time.Duration(a.cfg.LeaderTimeout)*time.Millisecond, func(time.Time) tea.Msg {
+		return leaderTimeoutMsg{}
+	})
+}
+
 func (a *App) handleLeaderTimeout() {
 	if a.leader.active {
 		a.leader.showHelp = true
@@ -206,26 +387,520 @@ func (a *App) cancelLeader() {
 	a.leader.showHelp = false
 }
 
-func (a *App) ToggleFinder() {
+func (a *App) ToggleFinder() tea.Cmd {
 	if a.finder.Visible() {
 		a.finder.Hide()
 		a.focused = focusEditor
-	} else {
-		a.finder.Show()
-		a.focused = focusFinder
+		return nil
 	}
+	a.focused = focusFinder
+	return a.finder.Show()
 }
 
-func (a *App) OpenGrepFinder() {
+func (a *App) OpenGrepFinder() tea.Cmd {
 	if a.finder.Visible() {
-		return
+		return nil
 	}
 	a.finder.SetTitle("Find in Notes")
 	a.finder.SetCanCreate(false)
-	a.finder.SetSearchFunc(a.searchNoteContent)
+	a.finder.SetPagedSearchFunc(a.searchNoteContent)
+	a.finder.SetPreviewFunc(a.previewNote)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// OpenRecentFinder opens the finder over previously opened notes, ranked by
+// frecency (open count weighted by recency).
+func (a *App) OpenRecentFinder() tea.Cmd {
+	if a.finder.Visible() {
+		return nil
+	}
+	a.finder.SetTitle("Recent Notes")
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(a.searchRecent)
 	a.finder.SetPreviewFunc(a.previewNote)
-	a.finder.Show()
 	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// OpenModifiedFinder lists notes by modification time, newest first.
+func (a *App) OpenModifiedFinder() tea.Cmd {
+	if a.finder.Visible() {
+		return nil
+	}
+	a.finder.SetTitle("Recently Modified")
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(a.searchModified)
+	a.finder.SetPreviewFunc(a.previewNote)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// OpenBacklinksFinder lists the links to relPath, jumping to the linking line
+// on selection.
+func (a *App) OpenBacklinksFinder(relPath string) tea.Cmd {
+	if a.finder.Visible() || a.db == nil {
+		return nil
+	}
+	a.finder.SetTitle("Backlinks: " + filepath.Base(relPath))
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(func(query string) []panel.FinderItem {
+		return a.searchBacklinks(relPath, query)
+	})
+	a.finder.SetPreviewFunc(a.previewNote)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// OpenSavedSearchFinder lists the saved searches from config; picking one
+// runs its query in the note finder.
+func (a *App) OpenSavedSearchFinder() tea.Cmd {
+	if a.finder.Visible() {
+		return nil
+	}
+	if len(a.cfg.SavedSearches) == 0 {
+		a.status.SetError("no saved searches (add [[saved_search]] to config.toml)")
+		return nil
+	}
+	a.finder.SetTitle("Saved Searches")
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(a.searchSavedSearches)
+	a.finder.SetPreviewFunc(nil)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// OpenTagFinder browses the vault's tags as a tree; picking one runs a
+// tag: search in the note finder.
+func (a *App) OpenTagFinder() tea.Cmd {
+	if a.finder.Visible() || a.db == nil {
+		return nil
+	}
+	a.finder.SetTitle("Tags")
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(a.searchTags)
+	a.finder.SetPreviewFunc(nil)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// OpenBrokenLinksFinder lists wiki links that point at no note, by source
+// note and line; picking one jumps to the link.
+func (a *App) OpenBrokenLinksFinder() tea.Cmd {
+	if a.finder.Visible() || a.db == nil {
+		return nil
+	}
+	a.finder.SetTitle("Broken Links")
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(a.searchBrokenLinks)
+	a.finder.SetPreviewFunc(a.previewNote)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// OpenAttachmentFinder lists the vault's images, PDFs and other non-note
+// files; picking one opens it with the file manager command.
+func (a *App) OpenAttachmentFinder() tea.Cmd {
+	if a.finder.Visible() || a.db == nil {
+		return nil
+	}
+	a.pickingAttachment = true
+	a.finder.SetTitle("Attachments")
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(a.searchAttachments)
+	a.finder.SetPreviewFunc(a.previewAttachment)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// OpenTasksFinder lists the open checklist items across the vault by note
+// and line; picking one jumps to the task.
+func (a *App) OpenTasksFinder() tea.Cmd {
+	if a.finder.Visible() || a.db == nil {
+		return nil
+	}
+	a.finder.SetTitle("Open Tasks")
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(a.searchTasks)
+	a.finder.SetPreviewFunc(a.previewNote)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// runSavedSearch opens the note finder with a saved query typed in.
+func (a *App) runSavedSearch(name, query string) tea.Cmd {
+	a.finder.SetTitle(name)
+	a.finder.SetCanCreate(false)
+	a.finder.SetPagedSearchFunc(a.searchNotes)
+	a.finder.SetPreviewFunc(a.previewNote)
+	a.setFocus(focusFinder)
+	return a.finder.ShowQuery(query)
+}
+
+// OpenHabitTracker shows the month grid of habit checklists from daily notes.
+func (a *App) OpenHabitTracker() {
+	if a.db == nil {
+		return
+	}
+	entries, err := a.habitEntries()
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("habit tracker: %v", err))
+		return
+	}
+	a.habits.Show(entries, time.Now())
+}
+
+// OpenNoteHistory shows the commits that changed the current note, when the
+// vault is in a git repository, with each commit's diff.
+func (a *App) OpenNoteHistory() tea.Cmd {
+	if a.currentFile == "" {
+		a.status.SetError("no note open")
+		return nil
+	}
+	if !git.IsRepo(a.cfg.VaultPath) {
+		a.status.SetError("vault is not in a git repository")
+		return nil
+	}
+	commits, err := git.Log(a.cfg.VaultPath, a.currentFile)
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("history: %v", err))
+		return nil
+	}
+	if len(commits) == 0 {
+		a.status.SetError(fmt.Sprintf("%s has no commits", a.currentFile))
+		return nil
+	}
+	dir := a.cfg.VaultPath
+	return a.noteHistory.Show("History of "+a.currentFile, commits, func(c git.Commit) string {
+		diff, err := git.Diff(dir, c)
+		if err != nil {
+			return err.Error()
+		}
+		return diff
+	})
+}
+
+// blameLoadedMsg carries the annotations for relPath's lines.
+type blameLoadedMsg struct {
+	relPath string
+	notes   map[int]string
+	err     error
+}
+
+// ToggleBlame turns on or off annotating the current note's lines, as
+// Neovim virtual text, with the commit that last changed them.
+func (a *App) ToggleBlame() tea.Cmd {
+	rpc := a.editor.GetRPC()
+	if rpc == nil {
+		a.status.SetError("blame annotations need the editor")
+		return nil
+	}
+	if a.showBlame {
+		a.showBlame = false
+		a.blameFile = ""
+		if err := rpc.ClearLineAnnotations(); err != nil {
+			a.status.SetError(fmt.Sprintf("blame: %v", err))
+		}
+		return nil
+	}
+	if !git.IsRepo(a.cfg.VaultPath) {
+		a.status.SetError("vault is not in a git repository")
+		return nil
+	}
+	a.showBlame = true
+	return a.refreshBlame()
+}
+
+// refreshBlame blames the current note's buffer, unsaved edits included,
+// in the background.
+func (a *App) refreshBlame() tea.Cmd {
+	a.blameFile = a.currentFile
+	rpc := a.editor.GetRPC()
+	if !a.showBlame || a.currentFile == "" || rpc == nil {
+		return nil
+	}
+	lines, err := rpc.BufferContent()
+	if err != nil {
+		return nil
+	}
+	content := append(bytes.Join(lines, []byte("\n")), '\n')
+	dir, relPath := a.cfg.VaultPath, a.currentFile
+	return func() tea.Msg {
+		commits, err := git.Blame(dir, relPath, content)
+		return blameLoadedMsg{relPath: relPath, notes: blameNotes(commits), err: err}
+	}
+}
+
+// applyBlame shows loaded annotations if they are still for the open note.
+func (a *App) applyBlame(msg blameLoadedMsg) {
+	rpc := a.editor.GetRPC()
+	if !a.showBlame || msg.relPath != a.currentFile || rpc == nil {
+		return
+	}
+	if msg.err != nil {
+		a.status.SetError(fmt.Sprintf("blame: %v", msg.err))
+		return
+	}
+	if err := rpc.SetLineAnnotations(msg.notes); err != nil {
+		a.status.SetError(fmt.Sprintf("blame: %v", err))
+	}
+}
+
+// blameNotes labels the first line of each run of lines last changed by
+// the same commit, keyed by 1-based line number.
+func blameNotes(commits []git.Commit) map[int]string {
+	notes := make(map[int]string)
+	for i, c := range commits {
+		if i > 0 && commits[i-1].Hash == c.Hash {
+			continue
+		}
+		if c.Hash == "" {
+			notes[i+1] = "not committed yet"
+			continue
+		}
+		notes[i+1] = fmt.Sprintf("%s %s · %s", c.Time.Format("2006-01-02"), c.Author, c.Subject)
+	}
+	return notes
+}
+
+// confirmRestore asks before restoring the current note to c, since it
+// replaces the note and any unsaved edits.
+func (a *App) confirmRestore(c git.Commit) {
+	if a.refuseEdit() || a.currentFile == "" {
+		return
+	}
+	a.pendingPrompt = promptAction{kind: "restore-revision", path: a.currentFile, commit: c}
+	a.prompt.ShowConfirm(fmt.Sprintf("Restore %s to %s (%s)? Unsaved edits are lost.",
+		a.currentFile, c.Short, c.Time.Format("2006-01-02")))
+}
+
+// restoreRevision overwrites the note at relPath with its content as of c,
+// reloads it if open and reindexes it. The restore is left uncommitted.
+func (a *App) restoreRevision(relPath string, c git.Commit) tea.Cmd {
+	content, err := git.Show(a.cfg.VaultPath, c)
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("restore: %v", err))
+		return nil
+	}
+	absPath := filepath.Join(a.cfg.VaultPath, relPath)
+	if err := vault.ApplyRewrites([]vault.FileRewrite{{Path: absPath, Content: content}}); err != nil {
+		a.status.SetError(fmt.Sprintf("restore: %v", err))
+		return nil
+	}
+	if relPath == a.currentFile {
+		a.reloadCurrentNote()
+	}
+	a.status.SetMessage(fmt.Sprintf("Restored %s to %s", relPath, c.Short))
+	return tea.Batch(a.indexFile(absPath), a.refreshBlame())
+}
+
+// RevealInTree shows the tree with the current note selected, expanding its
+// parent folders.
+func (a *App) RevealInTree() {
+	if a.currentFile == "" {
+		a.status.SetError("no note open")
+		return
+	}
+	a.revealPath(a.currentFile)
+}
+
+// revealPath selects relPath in the tree and focuses it.
+func (a *App) revealPath(relPath string) {
+	if !a.tree.Reveal(relPath) {
+		a.status.SetError(fmt.Sprintf("%s is not in the tree", relPath))
+		return
+	}
+	a.showTree = true
+	a.zenMode = false
+	a.setFocus(focusTree)
+	a.updateLayout()
+}
+
+// EnterCopyMode shows the editor's scrollback for browsing and yanking,
+// like tmux's copy mode.
+func (a *App) EnterCopyMode() {
+	if !a.editor.EnterCopyMode() {
+		a.status.SetError("copy mode needs the pty editor backend and Neovim running")
+		return
+	}
+	a.setFocus(focusEditor)
+	a.status.SetMode("COPY")
+}
+
+// OpenFolderExternally opens the current note's folder (or the vault root
+// when no note is open) with the configured file manager. In server mode the
+// file manager would run on the server, so remote_file_manager is used
+// instead.
+func (a *App) OpenFolderExternally() tea.Cmd {
+	dir := a.cfg.VaultPath
+	if a.currentFile != "" {
+		dir = filepath.Join(dir, filepath.Dir(a.currentFile))
+	}
+	return a.openExternally(dir)
+}
+
+// openAttachment opens a vault file with the file manager command, which
+// on most systems opens files in their default application too.
+func (a *App) openAttachment(relPath string) tea.Cmd {
+	return a.openExternally(filepath.Join(a.cfg.VaultPath, relPath))
+}
+
+// openExternally runs the file manager command on path.
+func (a *App) openExternally(path string) tea.Cmd {
+	command := a.cfg.FileManager
+	if a.cfg.Serve {
+		command = a.cfg.RemoteFileManager
+	}
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		a.status.SetError("no file manager configured")
+		return nil
+	}
+	return func() tea.Msg {
+		cmd := exec.Command(args[0], append(args[1:], path)...)
+		if err := cmd.Run(); err != nil {
+			return fileManagerDoneMsg{err: fmt.Errorf("%s: %w", args[0], err)}
+		}
+		return fileManagerDoneMsg{}
+	}
+}
+
+// exportTarget is where ExportNote sends the note.
+type exportTarget int
+
+const (
+	exportClipboard exportTarget = iota
+	exportStdout                 // printed after the TUI exits
+)
+
+// ExportNote copies the current buffer (raw, or rendered to HTML) to the
+// clipboard, or queues it to print on stdout when Kopr exits so it can be
+// piped into another program. With export_inline_embeds, embedded notes
+// are inlined first.
+func (a *App) ExportNote(target exportTarget, html bool) tea.Cmd {
+	if a.currentFile == "" {
+		a.status.SetError("no note open")
+		return nil
+	}
+	if target == exportStdout && a.cfg.Serve {
+		a.status.SetError("print on exit is not available over SSH")
+		return nil
+	}
+
+	content, err := a.currentContent()
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("export: %v", err))
+		return nil
+	}
+	if a.cfg.ExportInlineEmbeds {
+		content = markdown.InlineEmbeds(a.currentFile, content, markdown.MaxEmbedDepth, a.loadEmbed)
+	}
+	if html {
+		if content, err = markdown.RenderHTML(content, a.theme.CodeColors()); err != nil {
+			a.status.SetError(fmt.Sprintf("export: %v", err))
+			return nil
+		}
+	}
+
+	if target == exportStdout {
+		a.exitOutput = content
+		a.status.SetMessage(fmt.Sprintf("%s will print on exit", a.currentFile))
+		return nil
+	}
+	a.clipboardText = string(content)
+	a.status.SetMessage(fmt.Sprintf("Copied %s", a.currentFile))
+	return a.writeClipboard(string(content))
+}
+
+// loadEmbed reads the note an embed target names for
+// markdown.InlineEmbeds, resolving it like a followed link. Ambiguous
+// targets take the best match.
+func (a *App) loadEmbed(target string) (string, []byte, bool) {
+	if a.db == nil {
+		return "", nil, false
+	}
+	relPath, err := a.db.ResolveLink(markdown.ResolveWikiLinkTarget(target))
+	if err == nil && relPath == "" {
+		relPath, err = a.db.ResolveLinkLoosely(target)
+	}
+	if err != nil || relPath == "" {
+		return "", nil, false
+	}
+	content, err := os.ReadFile(a.vault.AbsPath(relPath))
+	return relPath, content, err == nil
+}
+
+// currentContent returns the open note as shown in the editor, including
+// unsaved changes, falling back to the file on disk.
+func (a *App) currentContent() ([]byte, error) {
+	if rpc := a.editor.GetRPC(); rpc != nil {
+		if lines, err := rpc.BufferContent(); err == nil {
+			return append(bytes.Join(lines, []byte("\n")), '\n'), nil
+		}
+	}
+	return os.ReadFile(a.vault.AbsPath(a.currentFile))
+}
+
+// OpenInExternalEditor saves the current note and opens it in the configured
+// GUI editor, or suspends the TUI and runs $EDITOR. When the editor exits the
+// buffer is reloaded from disk and the note reindexed.
+func (a *App) OpenInExternalEditor() tea.Cmd {
+	if a.currentFile == "" {
+		a.status.SetError("no note open")
+		return nil
+	}
+	if a.refuseExternal(a.currentFile) {
+		return nil
+	}
+	rpc := a.editor.GetRPC()
+	if rpc != nil {
+		if err := rpc.WriteBuffer(); err != nil {
+			a.status.SetError(fmt.Sprintf("save before external edit: %v", err))
+			return nil
+		}
+	}
+
+	relPath := a.currentFile
+	absPath := filepath.Join(a.cfg.VaultPath, relPath)
+	done := func(err error) tea.Msg {
+		return externalEditDoneMsg{relPath: relPath, err: err}
+	}
+
+	if args := strings.Fields(a.cfg.ExternalEditor); len(args) > 0 {
+		return func() tea.Msg {
+			return done(exec.Command(args[0], append(args[1:], absPath)...).Run())
+		}
+	}
+	args := strings.Fields(os.Getenv("EDITOR"))
+	if len(args) == 0 {
+		a.status.SetError("set $EDITOR or external_editor in config.toml")
+		return nil
+	}
+	return tea.ExecProcess(exec.Command(args[0], append(args[1:], absPath)...), done)
+}
+
+// reloadAfterExternalEdit rereads the note into Neovim, keeping the cursor
+// where it was when possible, and reindexes it.
+func (a *App) reloadAfterExternalEdit(relPath string) tea.Cmd {
+	if relPath == a.currentFile {
+		a.reloadCurrentNote()
+	}
+	return a.indexFile(filepath.Join(a.cfg.VaultPath, relPath))
+}
+
+// reloadCurrentNote rereads the open note from disk after it was changed
+// outside Neovim, keeping the cursor where it was.
+func (a *App) reloadCurrentNote() {
+	rpc := a.editor.GetRPC()
+	if rpc == nil {
+		return
+	}
+	line, col, posErr := rpc.CursorPosition()
+	if err := rpc.ExecCommand("edit!"); err != nil {
+		a.status.SetError(fmt.Sprintf("reload %s: %v", a.currentFile, err))
+	} else if posErr == nil {
+		rpc.SetCursorPosition(line, col) //nolint:errcheck // the note may have shrunk
+	}
 }
 
 func (a *App) CreateBlankNote() {
@@ -275,26 +950,61 @@ func (a *App) CreateInboxNote() {
 	a.tree.Refresh()
 }
 
-func (a *App) InsertTemplate() {
+// InsertTemplate opens the finder over the templates directory; picking one
+// creates a new note from it. Templates in subfolders are grouped by folder.
+func (a *App) InsertTemplate() tea.Cmd {
+	if a.finder.Visible() {
+		return nil
+	}
+	templates, err := a.vault.LoadTemplates()
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("load templates: %v", err))
+		return nil
+	}
+	if len(templates) == 0 {
+		a.status.SetError(fmt.Sprintf("no templates in %s", a.vault.TemplatesPath()))
+		return nil
+	}
+	a.pickingTemplate = true
+	a.finder.SetTitle("Insert Template")
+	a.finder.SetCanCreate(false)
+	a.finder.SetSearchFunc(func(query string) []panel.FinderItem {
+		return searchTemplates(templates, query)
+	})
+	a.finder.SetPreviewFunc(previewFile)
+	a.focused = focusFinder
+	return a.finder.Show()
+}
+
+// createFromTemplate creates a note from the template at path and opens it.
+func (a *App) createFromTemplate(path string) {
 	templates, err := a.vault.LoadTemplates()
-	if err != nil || len(templates) == 0 {
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("load templates: %v", err))
 		return
 	}
-	// For now, use the first template. A template picker UI can be added later.
-	if len(templates) > 0 {
-		path, err := a.vault.CreateFromTemplate(templates[0], "New Note")
-		if err != nil {
-			return
-		}
-		a.openInEditor(path)
-		rel, err := filepath.Rel(a.cfg.VaultPath, path)
-		if err != nil {
-			rel = filepath.Base(path)
-		}
-		a.status.SetFile(rel)
-		a.currentFile = rel
-		a.tree.Refresh()
+	idx := slices.IndexFunc(templates, func(t vault.Template) bool { return t.Path == path })
+	if idx < 0 {
+		a.status.SetError(fmt.Sprintf("template %s no longer exists", filepath.Base(path)))
+		return
 	}
+	abs, err := a.vault.CreateFromTemplate(templates[idx], vault.TemplateContext{
+		Title:     "New Note",
+		Now:       time.Now(),
+		Clipboard: a.clipboardText,
+	})
+	if err != nil {
+		a.status.SetError(err.Error())
+		return
+	}
+	a.openInEditor(abs)
+	rel, err := filepath.Rel(a.cfg.VaultPath, abs)
+	if err != nil {
+		rel = filepath.Base(abs)
+	}
+	a.status.SetFile(rel)
+	a.currentFile = rel
+	a.tree.Refresh()
 }
 
 // FollowLink navigates to the wiki link under the cursor.
@@ -331,35 +1041,145 @@ func (a *App) FollowLink() {
 	if link == nil || link.Target == "" {
 		return
 	}
+	a.followLinkTarget(link.Target, link.Section)
+}
 
-	// Resolve the link target — try DB lookup by basename first
-	basename := filepath.Base(markdown.ResolveWikiLinkTarget(link.Target))
+// followLinkTarget opens the note a wiki link's target names, letting the
+// user pick when several match and confirming before creating a missing one.
+// A non-empty section moves the cursor to that heading once it is open.
+func (a *App) followLinkTarget(linkTarget, section string) {
+	// Resolve the link target — try DB lookup by name first
+	target := markdown.ResolveWikiLinkTarget(linkTarget)
 	targetPath := ""
 	if a.db != nil {
-		resolved, err := a.db.FindNoteByBasename(basename)
-		if err == nil && resolved != "" {
-			targetPath = resolved
+		candidates, err := a.db.LinkCandidates(target)
+		if err == nil && len(candidates) == 0 {
+			// No exact name match: try case, spacing, titles and aliases
+			candidates, err = a.db.LooseLinkCandidates(linkTarget)
+		}
+		if err == nil && len(candidates) > 1 {
+			a.pickLinkTarget(linkTarget, section, candidates)
+			return
+		}
+		if err == nil && len(candidates) == 1 {
+			targetPath = candidates[0]
 		}
 	}
-	// Fallback: use basename as root-level path
+	// Fallback: use basename as root-level path, or with folder-scoped
+	// basenames the qualified target as written
 	if targetPath == "" {
-		targetPath = basename
+		targetPath = filepath.Base(target)
+		if a.folderScoped() {
+			targetPath = filepath.Clean(strings.TrimPrefix(target, "/"))
+		}
 	}
 
-	// Create the target note if it doesn't exist
-	if _, err := os.Stat(filepath.Join(a.cfg.VaultPath, targetPath)); err != nil {
+	// The target doesn't exist: confirm before creating it, offering
+	// near-matching notes in case the link is a typo
+	if _, err := os.Stat(a.vault.AbsPath(targetPath)); err != nil {
 		if msg := a.checkUniqueBasename(targetPath); msg != "" {
 			a.status.SetError(msg)
 			return
 		}
-		frontmatter := fmt.Sprintf("---\ntitle: %s\n---\n\n", link.Target)
-		if _, err := a.vault.CreateNote(targetPath, frontmatter); err != nil {
+		if a.cfg.ReadOnly {
+			a.status.SetError(fmt.Sprintf("no note %s", targetPath))
 			return
 		}
-		a.tree.Refresh()
+		a.confirmFollowCreate(targetPath, linkTarget, section)
+		return
 	}
 
 	a.navigateTo(targetPath)
+	a.gotoSection(targetPath, section)
+	a.setFocus(focusEditor)
+}
+
+// gotoSection moves the cursor to the heading in relPath that a link's
+// #section names, using the index's headings.
+func (a *App) gotoSection(relPath, section string) {
+	if section == "" || a.db == nil {
+		return
+	}
+	headings, err := a.db.GetHeadingsForNote(relPath)
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("headings: %v", err))
+		return
+	}
+	for _, h := range headings {
+		if markdown.HeadingMatches(h.Text, section) {
+			a.editor.GotoLine(h.Line)
+			return
+		}
+	}
+	a.status.SetMessage(fmt.Sprintf("No heading %q in %s", section, relPath))
+}
+
+// pickLinkTarget lets the user choose which of several notes a followed
+// link means, best match first, rather than silently taking that one.
+func (a *App) pickLinkTarget(target, section string, candidates []string) {
+	a.status.SetMessage(fmt.Sprintf("[[%s]] matches %d notes", target, len(candidates)))
+	a.pendingPrompt = promptAction{kind: "follow-pick", paths: candidates, section: section}
+	a.prompt.ShowChoices(fmt.Sprintf("Open which [[%s]]?", target), candidates)
+}
+
+// maxLinkSuggestions caps the "did you mean" list offered before following
+// a link creates a note.
+const maxLinkSuggestions = 5
+
+// confirmFollowCreate asks before creating targetPath for a followed link
+// that resolves to no note, listing existing notes with similar names.
+// title is the link target as written, used for the new note's title.
+func (a *App) confirmFollowCreate(targetPath, title, section string) {
+	var similar []string
+	if a.db != nil {
+		var err error
+		if similar, err = a.db.SimilarNotes(title, maxLinkSuggestions); err != nil {
+			a.status.SetError(fmt.Sprintf("similar notes: %v", err))
+			return
+		}
+	}
+	options := []string{"Create " + targetPath}
+	for _, p := range similar {
+		options = append(options, "Open "+p)
+	}
+	options = append(options, "Cancel")
+
+	heading := fmt.Sprintf("Create %q?", targetPath)
+	if len(similar) > 0 {
+		heading = fmt.Sprintf("Create %q? Did you mean:", targetPath)
+	}
+	a.pendingPrompt = promptAction{kind: "follow-create", path: targetPath, paths: similar, find: title, section: section}
+	a.prompt.ShowChoices(heading, options)
+}
+
+// handleFollowPick opens the note chosen in pickLinkTarget.
+func (a *App) handleFollowPick(action promptAction, choice string) {
+	if !slices.Contains(action.paths, choice) {
+		return
+	}
+	a.navigateTo(choice)
+	a.gotoSection(choice, action.section)
+	a.setFocus(focusEditor)
+}
+
+// handleFollowCreate carries out the choice made in confirmFollowCreate.
+func (a *App) handleFollowCreate(action promptAction, choice string) {
+	if open, ok := strings.CutPrefix(choice, "Open "); ok && slices.Contains(action.paths, open) {
+		a.navigateTo(open)
+		a.gotoSection(open, action.section)
+		a.setFocus(focusEditor)
+		return
+	}
+	if choice != "Create "+action.path {
+		return
+	}
+	frontmatter := fmt.Sprintf("---\ntitle: %s\n---\n\n", action.find)
+	if _, err := a.vault.CreateNote(action.path, frontmatter); err != nil {
+		a.status.SetError(fmt.Sprintf("create note: %v", err))
+		return
+	}
+	a.tree.Refresh()
+	a.navigateTo(action.path)
 	a.setFocus(focusEditor)
 }
 
@@ -369,7 +1189,7 @@ func (a *App) GoBack() {
 		return
 	}
 
-	if _, err := os.Stat(filepath.Join(a.cfg.VaultPath, a.prevFile)); err != nil {
+	if _, err := os.Stat(a.vault.AbsPath(a.prevFile)); err != nil {
 		a.prevFile = ""
 		return
 	}
@@ -382,13 +1202,56 @@ func (a *App) GoBack() {
 	a.setFocus(focusEditor)
 }
 
-func (a *App) ReloadConfig() {
+func (a *App) ReloadConfig() tea.Cmd {
+	var cmd tea.Cmd
+
 	// Reload TOML config
 	cfg := config.Default()
 	if _, err := config.LoadFile(&cfg); err == nil {
 		a.cfg.Colorscheme = cfg.Colorscheme
 		a.cfg.ColorschemeRepo = cfg.ColorschemeRepo
 		a.cfg.LeaderTimeout = cfg.LeaderTimeout
+		a.cfg.HabitsHeading = cfg.HabitsHeading
+		a.habits.SetHeading(cfg.HabitsHeading)
+		a.cfg.ReviewAfterDays = cfg.ReviewAfterDays
+		a.cfg.ArchiveDir = cfg.ArchiveDir
+		a.cfg.AttachmentDir = cfg.AttachmentDir
+		a.cfg.AttachmentLinks = cfg.AttachmentLinks
+		a.cfg.NoteUIDs = cfg.NoteUIDs
+		a.vault.AssignUIDs = cfg.NoteUIDs
+		a.cfg.AutoLinkOnSave = cfg.AutoLinkOnSave
+		a.cfg.ExportInlineEmbeds = cfg.ExportInlineEmbeds
+		a.cfg.SavedSearches = cfg.SavedSearches
+		a.info.SetSavedSearches(savedSearchItems(cfg.SavedSearches))
+		a.cfg.FinderGroupByFolder = cfg.FinderGroupByFolder
+		a.finder.SetGroupByFolder(cfg.FinderGroupByFolder)
+		a.cfg.FileManager = cfg.FileManager
+		a.cfg.RemoteFileManager = cfg.RemoteFileManager
+		a.cfg.ExternalEditor = cfg.ExternalEditor
+		if a.db != nil && cfg.FTSTokenizer != a.cfg.FTSTokenizer {
+			rebuilt, err := a.db.SetTokenizer(cfg.FTSTokenizer)
+			if err != nil {
+				a.status.SetError(fmt.Sprintf("fts_tokenizer: %v", err))
+			} else {
+				a.cfg.FTSTokenizer = cfg.FTSTokenizer
+				if rebuilt {
+					a.status.SetMessage("Rebuilding search index...")
+					cmd = a.rebuildIndex()
+				}
+			}
+		}
+		if a.db != nil && cfg.BasenameUniqueness != a.cfg.BasenameUniqueness {
+			rekeyed, err := a.db.SetBasenameScope(cfg.BasenameUniqueness)
+			if err != nil {
+				a.status.SetError(fmt.Sprintf("basename_uniqueness: %v", err))
+			} else {
+				a.cfg.BasenameUniqueness = cfg.BasenameUniqueness
+				if rekeyed && cmd == nil {
+					a.status.SetMessage("Rebuilding search index...")
+					cmd = a.rebuildIndex()
+				}
+			}
+		}
 	}
 
 	// Reload Neovim config and re-apply colorscheme
@@ -398,7 +1261,7 @@ func (a *App) ReloadConfig() {
 			if a.program != nil {
 				a.program.Send(fatalErrorMsg{err: err})
 			}
-			return
+			return cmd
 		}
 		// Re-apply colorscheme and extract new colors
 		if a.cfg.Colorscheme != "" {
@@ -406,19 +1269,20 @@ func (a *App) ReloadConfig() {
 				a.status.SetError(fmt.Sprintf("colorscheme %q: %v", a.cfg.Colorscheme, err))
 			} else {
 				if colors, err := rpc.ExtractColors(); err == nil && colors != nil {
-					a.theme = theme.FromExtracted(colors, a.theme)
-					a.tree.SetTheme(&a.theme)
-					a.info.SetTheme(&a.theme)
-					a.finder.SetTheme(&a.theme)
-					a.prompt.SetTheme(&a.theme)
-					a.status.SetTheme(&a.theme)
-					a.whichKey.SetTheme(&a.theme)
-					a.editor.SetTheme(&a.theme)
+					a.theme = theme.FromExtracted(colors, a.theme).ForProfile(a.colorProfile)
+					a.applyTheme()
 				}
 				rpc.ClearHighlightBgs()
 			}
 		}
 	}
+	return cmd
+}
+
+// LinkMentions offers to turn plain-text mentions of other notes' titles and
+// aliases in the open note into [[links]].
+func (a *App) LinkMentions() tea.Cmd {
+	return a.findMentions(false)
 }
 
 func (a *App) FormatDocument() {
@@ -426,46 +1290,9 @@ func (a *App) FormatDocument() {
 	if rpc == nil {
 		return
 	}
-
-	// Get current buffer content
-	content, err := rpc.BufferContent()
-	if err != nil {
-		return
-	}
-
-	// Join lines
-	var buf bytes.Buffer
-	for i, line := range content {
-		buf.Write(line)
-		if i < len(content)-1 {
-			buf.WriteByte('\n')
-		}
-	}
-
-	// Format
-	formatted := markdown.Format(buf.Bytes())
-
-	// Write back via RPC - use Neovim's command to replace buffer
-	lines := strings.Split(string(formatted), "\n")
-	// Remove trailing empty line added by Format()
-	if len(lines) > 0 && lines[len(lines)-1] == "" {
-		lines = lines[:len(lines)-1]
-	}
-
-	// Build a Lua command to set buffer lines
-	luaLines := make([]string, len(lines))
-	for i, l := range lines {
-		// Escape for Lua string
-		l = strings.ReplaceAll(l, "\\", "\\\\")
-		l = strings.ReplaceAll(l, "'", "\\'")
-		luaLines[i] = "'" + l + "'"
-	}
-
-	lua := fmt.Sprintf("vim.api.nvim_buf_set_lines(0, 0, -1, false, {%s})", strings.Join(luaLines, ","))
-	if err := rpc.ExecLua(lua, nil); err != nil {
+	if _, err := formatBuffer(rpc, a.cfg.AutoFormatScope); err != nil {
 		if a.program != nil {
 			a.program.Send(fatalErrorMsg{err: err})
 		}
-		return
 	}
 }
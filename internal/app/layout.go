@@ -7,11 +7,20 @@ type Layout struct {
 	InfoWidth    int
 	Height       int
 	StatusHeight int
+
+	// TerminalHeight is the terminal pane's height under the panels,
+	// including its title row; 0 when it is hidden.
+	TerminalHeight int
 }
 
+// minPanelHeight is the height the panels keep before the terminal pane
+// gets any room.
+const minPanelHeight = 8
+
 // ComputeLayout calculates panel dimensions based on total width/height
-// and whether each panel is visible.
-func ComputeLayout(totalWidth, totalHeight int, showTree, showInfo bool, treeWidth, infoWidth int) Layout {
+// and whether each panel is visible. The terminal pane takes a third of the
+// height, across the full width.
+func ComputeLayout(totalWidth, totalHeight int, showTree, showInfo, showTerminal bool, treeWidth, infoWidth int) Layout {
 	// During live resizes some terminals momentarily report 0 (or even negative)
 	// dimensions; clamp to avoid propagating invalid sizes into panels.
 	if totalWidth < 1 {
@@ -25,6 +34,10 @@ func ComputeLayout(totalWidth, totalHeight int, showTree, showInfo bool, treeWid
 		StatusHeight: 1,
 		Height:       totalHeight - 1, // reserve 1 row for status bar
 	}
+	if showTerminal && l.Height-l.Height/3 >= minPanelHeight {
+		l.TerminalHeight = max(l.Height/3, 2)
+		l.Height -= l.TerminalHeight
+	}
 
 	remaining := totalWidth
 
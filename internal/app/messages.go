@@ -9,3 +9,18 @@ type fatalErrorMsg struct{ err error }
 func fatalCmd(err error) tea.Cmd {
 	return tea.Batch(tea.Printf("fatal: %v\n", err), tea.Quit)
 }
+
+// watcherPollingMsg is sent when the file watcher falls back to polling
+// because fsnotify failed.
+type watcherPollingMsg struct{ err error }
+
+// watcherQueueMsg is sent when the number of changed files the watcher has
+// yet to reindex changes, or it is paused or resumed.
+type watcherQueueMsg struct {
+	queued int
+	paused bool
+}
+
+// noteWrittenMsg is sent when the file watcher sees a note written to, by
+// Kopr or anything else. Path is absolute.
+type noteWrittenMsg struct{ path string }
@@ -41,6 +41,7 @@ const (
 	mouseTargetEditor
 	mouseTargetInfo
 	mouseTargetStatus
+	mouseTargetTerminal
 )
 
 // mouseHitResult contains the result of hit-testing a mouse event.
@@ -62,18 +63,22 @@ type mouseHitResult struct {
 // coordinates for the editor panel.
 func (a *App) hitTestMouse(msg tea.MouseMsg) mouseHitResult {
 	showTree, showInfo := a.panelsVisible()
-	layout := ComputeLayout(a.width, a.height, showTree, showInfo, a.cfg.TreeWidth, a.cfg.InfoWidth)
+	layout := ComputeLayout(a.width, a.height, showTree, showInfo, a.showTerminal, a.cfg.TreeWidth, a.rightPanelWidth())
 
 	result := mouseHitResult{
 		screenX: msg.X,
 		screenY: msg.Y,
 	}
 
-	// Status bar occupies the last row(s)
-	if msg.Y >= layout.Height {
+	// Status bar occupies the last row(s), under the terminal pane
+	if msg.Y >= layout.Height+layout.TerminalHeight {
 		result.target = mouseTargetStatus
 		return result
 	}
+	if msg.Y >= layout.Height {
+		result.target = mouseTargetTerminal
+		return result
+	}
 
 	// Determine editor column boundaries
 	editorStartX := 0
@@ -151,3 +151,30 @@ func TestHitTestMouseTreeOnlyNoInfo(t *testing.T) {
 		t.Errorf("editorCol: got %d, want 65", result.editorCol)
 	}
 }
+
+func TestHitTestMouseTerminal(t *testing.T) {
+	a := App{
+		cfg:          config.Config{TreeWidth: 25, InfoWidth: 25},
+		width:        100,
+		height:       30,
+		showTree:     true,
+		showTerminal: true,
+	}
+
+	// 29 rows above the status bar: a third for the terminal pane.
+	for y, want := range map[int]mouseTarget{
+		19: mouseTargetEditor,
+		20: mouseTargetTerminal,
+		28: mouseTargetTerminal,
+		29: mouseTargetStatus,
+	} {
+		x := 50
+		if result := a.hitTestMouse(newMouseMsg(x, y)); result.target != want {
+			t.Errorf("(%d, %d): got %d, want %d", x, y, result.target, want)
+		}
+	}
+	// Across the full width, under the tree too.
+	if result := a.hitTestMouse(newMouseMsg(5, 25)); result.target != mouseTargetTerminal {
+		t.Errorf("under the tree: got %d, want mouseTargetTerminal", result.target)
+	}
+}
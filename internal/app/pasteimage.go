@@ -0,0 +1,156 @@
+package app
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pngMagic starts every PNG file.
+var pngMagic = []byte("\x89PNG\r\n\x1a\n")
+
+// imagePastedMsg reports an image written from the clipboard, relative to
+// the vault, for its link to be pasted into the note notePath.
+type imagePastedMsg struct {
+	notePath, relPath string
+	err               error
+}
+
+// PasteImage writes the image on the system clipboard to attachment_dir
+// and pastes a link to it at the cursor.
+func (a *App) PasteImage() tea.Cmd {
+	if a.currentFile == "" {
+		a.status.SetError("paste image: no note open")
+		return nil
+	}
+	if a.refuseExternal(a.currentFile) {
+		return nil
+	}
+	if a.cfg.Serve {
+		// The clipboard would be the server's, not the user's.
+		a.status.SetError("paste image is not available over SSH")
+		return nil
+	}
+	notePath, dir := a.currentFile, a.cfg.AttachmentDir
+	root := a.cfg.VaultPath
+	return func() tea.Msg {
+		data, err := readClipboardImage()
+		if err != nil {
+			return imagePastedMsg{err: err}
+		}
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			return imagePastedMsg{err: err}
+		}
+		relPath := pastedImageName(dir, time.Now(), func(p string) bool {
+			_, err := os.Stat(filepath.Join(root, p))
+			return err == nil
+		})
+		if err := os.WriteFile(filepath.Join(root, relPath), data, 0644); err != nil {
+			return imagePastedMsg{err: err}
+		}
+		return imagePastedMsg{notePath: notePath, relPath: relPath}
+	}
+}
+
+// handleImagePasted pastes the link to an image PasteImage wrote, if its
+// note is still open.
+func (a *App) handleImagePasted(msg imagePastedMsg) tea.Cmd {
+	if msg.err != nil {
+		a.status.SetError(fmt.Sprintf("paste image: %v", msg.err))
+		return nil
+	}
+	link := imageLink(msg.notePath, msg.relPath, a.cfg.AttachmentLinks)
+	rpc := a.editor.GetRPC()
+	if rpc == nil || a.currentFile != msg.notePath {
+		a.clipboardText = link
+		a.status.SetMessage(fmt.Sprintf("Saved %s; its link is on the clipboard", msg.relPath))
+		return a.writeClipboard(link)
+	}
+	if err := rpc.PasteText(link); err != nil {
+		a.status.SetError(fmt.Sprintf("paste image: %v", err))
+		return nil
+	}
+	a.status.SetMessage("Pasted " + msg.relPath)
+	return nil
+}
+
+// readClipboardImage returns the PNG image on the system clipboard, read
+// with wl-paste, xclip or pngpaste, trying each that applies here in turn.
+func readClipboardImage() ([]byte, error) {
+	var commands [][]string
+	if runtime.GOOS == "darwin" {
+		commands = append(commands, []string{"pngpaste", "-"})
+	}
+	if os.Getenv("WAYLAND_DISPLAY") != "" {
+		commands = append(commands, []string{"wl-paste", "--no-newline", "--type", "image/png"})
+	}
+	if os.Getenv("DISPLAY") != "" {
+		commands = append(commands, []string{"xclip", "-selection", "clipboard", "-t", "image/png", "-o"})
+	}
+	var errs []error
+	found, noImage := false, false
+	for _, args := range commands {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+		found = true
+		data, err := exec.Command(args[0], args[1:]...).Output()
+		if err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) && len(bytes.TrimSpace(exitErr.Stderr)) > 0 {
+				err = fmt.Errorf("%w: %s", err, bytes.TrimSpace(exitErr.Stderr))
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", args[0], err))
+			continue
+		}
+		if !bytes.HasPrefix(data, pngMagic) {
+			noImage = true
+			continue
+		}
+		return data, nil
+	}
+	switch {
+	case noImage:
+		return nil, errors.New("no image on the clipboard")
+	case !found:
+		return nil, errors.New("no clipboard tool found; install wl-clipboard, xclip or pngpaste")
+	}
+	return nil, errors.Join(errs...)
+}
+
+// pastedImageName returns a free path in dir for an image pasted at now:
+// pasted-20060102-150405.png, with -2, -3, ... added if taken.
+func pastedImageName(dir string, now time.Time, taken func(string) bool) string {
+	stem := filepath.Join(dir, "pasted-"+now.Format("20060102-150405"))
+	p := stem + ".png"
+	for n := 2; taken(p); n++ {
+		p = fmt.Sprintf("%s-%d.png", stem, n)
+	}
+	return p
+}
+
+// imageLink returns the embed for the image at relPath in the note at
+// notePath: ![[name.png]], or with style "markdown" ![](path) relative to
+// the note's folder.
+func imageLink(notePath, relPath, style string) string {
+	if style != "markdown" {
+		return "![[" + filepath.Base(relPath) + "]]"
+	}
+	rel, err := filepath.Rel(filepath.Dir(notePath), relPath)
+	if err != nil {
+		rel = relPath
+	}
+	rel = filepath.ToSlash(rel)
+	if strings.ContainsAny(rel, " ()") {
+		rel = "<" + rel + ">"
+	}
+	return "![](" + rel + ")"
+}
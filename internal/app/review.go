@@ -0,0 +1,99 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/panel"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// maxReviewNotes caps how many stale notes one review session loads.
+const maxReviewNotes = 1000
+
+// OpenStaleReview steps through the notes neither modified nor reviewed in
+// the last review_after_days days in the review overlay, least recently
+// touched first.
+func (a *App) OpenStaleReview() {
+	if a.db == nil {
+		return
+	}
+	if a.cfg.ReviewAfterDays <= 0 {
+		a.status.SetError("review: review_after_days must be at least 1")
+		return
+	}
+	// The review reads notes from disk, so it should see unsaved edits.
+	a.saveCurrentNote()
+	before := time.Now().AddDate(0, 0, -a.cfg.ReviewAfterDays)
+	results, err := a.db.StaleNotes(before, a.cfg.ArchiveDir+string(filepath.Separator), maxReviewNotes)
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("review: %v", err))
+		return
+	}
+	var notes []panel.ReviewNote
+	for _, r := range results {
+		// External sources are read-only, so there's nothing to do there.
+		if vault.IsExternal(r.Path) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(a.cfg.VaultPath, r.Path))
+		if err != nil {
+			continue
+		}
+		notes = append(notes, panel.ReviewNote{Path: r.Path, Title: r.Title, Touched: r.Touched, Content: string(content)})
+	}
+	if len(notes) == 0 {
+		a.status.SetMessage(fmt.Sprintf("No notes untouched for %d days", a.cfg.ReviewAfterDays))
+		return
+	}
+	a.review.Show(notes)
+}
+
+// handleReviewAction carries out an action picked in the review overlay.
+func (a *App) handleReviewAction(msg panel.ReviewActionMsg) tea.Cmd {
+	if a.refuseEdit() && msg.Action != panel.ReviewUpdate {
+		return nil
+	}
+	name := filepath.Base(msg.Path)
+	switch msg.Action {
+	case panel.ReviewUpdate:
+		a.navigateTo(msg.Path)
+		a.setFocus(focusEditor)
+	case panel.ReviewArchive:
+		cmd, err := a.setNoteStatus(msg.Path, "archived")
+		if err != nil {
+			a.status.SetError(fmt.Sprintf("archive: %v", err))
+			return nil
+		}
+		a.reviewed(msg.Path, "Archived "+name)
+		return cmd
+	case panel.ReviewSnooze:
+		today := time.Now().Format("2006-01-02")
+		cmd, err := a.rewriteNoteFile(msg.Path, func(content []byte) []byte {
+			return markdown.SetFrontmatterField(content, "reviewed", today)
+		})
+		if err != nil {
+			a.status.SetError(fmt.Sprintf("snooze: %v", err))
+			return nil
+		}
+		a.reviewed(msg.Path, fmt.Sprintf("Snoozed %s for %d days", name, a.cfg.ReviewAfterDays))
+		return cmd
+	}
+	return nil
+}
+
+// reviewed drops a note from the review and reports msg, closing the
+// overlay once no stale notes are left.
+func (a *App) reviewed(relPath, msg string) {
+	a.review.Remove(relPath)
+	if a.review.Len() == 0 {
+		a.review.Hide()
+		msg += "; review done"
+	}
+	a.status.SetMessage(msg)
+}
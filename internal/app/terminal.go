@@ -0,0 +1,45 @@
+package app
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/editor"
+)
+
+// ToggleTerminal shows the terminal pane, starting a shell in the vault the
+// first time, or hides it. A hidden shell keeps running, so its history and
+// jobs are there when the pane comes back.
+func (a *App) ToggleTerminal() tea.Cmd {
+	if a.showTerminal {
+		a.showTerminal = false
+		if a.focused == focusTerminal {
+			a.setFocus(focusEditor)
+		}
+		return a.updateLayout()
+	}
+	// A shell in server mode would run on the server for the client.
+	if a.cfg.Serve {
+		a.status.SetError("the terminal pane is not available in server mode")
+		return nil
+	}
+	a.showTerminal = true
+	cmd := a.updateLayout()
+	a.setFocus(focusTerminal)
+	return tea.Batch(cmd, a.terminal.Start())
+}
+
+// handleTerminalExited hides the terminal pane once its shell is gone.
+func (a *App) handleTerminalExited(msg editor.TerminalExitedMsg) {
+	a.terminal, _ = a.terminal.Update(msg)
+	if msg.Err != nil {
+		a.status.SetError(fmt.Sprintf("terminal: %v", msg.Err))
+	} else {
+		a.status.SetMessage("Shell exited")
+	}
+	a.showTerminal = false
+	if a.focused == focusTerminal {
+		a.setFocus(focusEditor)
+	}
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// OpenTrash shows the notes deleted from the vault, to restore or purge.
+func (a *App) OpenTrash() {
+	if a.refuseEdit() {
+		return
+	}
+	items, err := a.vault.ListTrash()
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("trash: %v", err))
+		return
+	}
+	if len(items) == 0 {
+		a.status.SetMessage("The trash is empty")
+		return
+	}
+	root := a.cfg.VaultPath
+	a.trash.Show(items, func(item vault.TrashItem) string {
+		data, err := os.ReadFile(filepath.Join(root, item.Trashed))
+		if err != nil {
+			return err.Error()
+		}
+		return string(data)
+	})
+}
+
+// restoreTrash moves a note out of the trash to where it was deleted from.
+// The watcher indexes it again.
+func (a *App) restoreTrash(item vault.TrashItem) {
+	if a.refuseEdit() {
+		return
+	}
+	if err := a.vault.RestoreTrash(item); err != nil {
+		a.status.SetError(fmt.Sprintf("restore: %v", err))
+		return
+	}
+	a.status.SetMessage("Restored " + item.Path)
+	a.tree.Refresh()
+	a.refreshTrash()
+}
+
+// confirmPurgeTrash asks before deleting notes in the trash for good.
+func (a *App) confirmPurgeTrash(items []vault.TrashItem) {
+	if a.refuseEdit() || len(items) == 0 {
+		return
+	}
+	label := fmt.Sprintf("Delete %s permanently?", items[0].Path)
+	if len(items) > 1 {
+		label = fmt.Sprintf("Delete all %d notes in the trash permanently?", len(items))
+	}
+	a.pendingPrompt = promptAction{kind: "purge-trash", trash: items}
+	a.prompt.ShowConfirm(label)
+}
+
+// purgeTrash deletes notes in the trash for good.
+func (a *App) purgeTrash(items []vault.TrashItem) {
+	for _, item := range items {
+		if err := a.vault.PurgeTrash(item); err != nil {
+			a.status.SetError(fmt.Sprintf("purge: %v", err))
+			a.refreshTrash()
+			return
+		}
+	}
+	if len(items) == 1 {
+		a.status.SetMessage("Purged " + items[0].Path)
+	} else {
+		a.status.SetMessage(fmt.Sprintf("Purged %d notes", len(items)))
+	}
+	a.refreshTrash()
+}
+
+// refreshTrash lists the trash again in the open overlay.
+func (a *App) refreshTrash() {
+	items, err := a.vault.ListTrash()
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("trash: %v", err))
+		return
+	}
+	a.trash.SetItems(items)
+}
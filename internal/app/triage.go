@@ -0,0 +1,231 @@
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/index"
+	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/panel"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// projectsDir is where triage files notes converted to projects.
+const projectsDir = "projects"
+
+// maxTriageNotes caps how many inbox notes one triage session loads.
+const maxTriageNotes = 1000
+
+// OpenInboxTriage steps through the notes with status: inbox in the triage
+// overlay, in path order so timestamped captures come oldest first.
+func (a *App) OpenInboxTriage() {
+	if a.db == nil {
+		return
+	}
+	// The triage reads notes from disk, so it should see unsaved edits.
+	a.saveCurrentNote()
+	results, err := a.db.SearchQuery(index.ParseQuery("status:inbox"), maxTriageNotes)
+	if err != nil {
+		a.status.SetError(fmt.Sprintf("inbox: %v", err))
+		return
+	}
+	var notes []panel.TriageNote
+	for _, r := range results {
+		if note, ok := a.triageNote(r.Path); ok {
+			notes = append(notes, note)
+		}
+	}
+	if len(notes) == 0 {
+		a.status.SetMessage("Inbox is empty")
+		return
+	}
+	a.triage.Show(notes)
+}
+
+// triageNote reads the note at relPath for the triage overlay. Notes in
+// read-only external sources can't be triaged.
+func (a *App) triageNote(relPath string) (panel.TriageNote, bool) {
+	if vault.IsExternal(relPath) {
+		return panel.TriageNote{}, false
+	}
+	content, err := os.ReadFile(filepath.Join(a.cfg.VaultPath, relPath))
+	if err != nil {
+		return panel.TriageNote{}, false
+	}
+	title := strings.TrimSuffix(filepath.Base(relPath), ".md")
+	if fm := markdown.ExtractFrontmatter(content); fm != nil && fm.Title != "" {
+		title = fm.Title
+	}
+	return panel.TriageNote{Path: relPath, Title: title, Content: string(content)}, true
+}
+
+// saveCurrentNote writes the note open in the editor if it has changes.
+func (a *App) saveCurrentNote() {
+	if rpc := a.editor.GetRPC(); rpc != nil && a.currentFile != "" {
+		if err := rpc.ExecCommand("silent update"); err != nil {
+			a.status.SetError(fmt.Sprintf("save %s: %v", a.currentFile, err))
+		}
+	}
+}
+
+// handleTriageAction carries out an action picked in the triage overlay.
+// Actions that need a folder or tags ask for them first.
+func (a *App) handleTriageAction(msg panel.TriageActionMsg) tea.Cmd {
+	if a.refuseEdit() && msg.Action != panel.TriageOpen {
+		return nil
+	}
+	name := filepath.Base(msg.Path)
+	switch msg.Action {
+	case panel.TriageArchive:
+		cmd, err := a.setNoteStatus(msg.Path, "archived")
+		if err != nil {
+			a.status.SetError(fmt.Sprintf("archive: %v", err))
+			return nil
+		}
+		a.triaged(msg.Path, "Archived "+name)
+		return cmd
+	case panel.TriageMove:
+		a.pendingPrompt = promptAction{kind: "triage-move", path: msg.Path}
+		a.prompt.Show("Move "+name+" to folder", "notes")
+	case panel.TriageTag:
+		a.pendingPrompt = promptAction{kind: "triage-tag", path: msg.Path}
+		a.prompt.Show("Add tags to "+name, "work, ideas")
+	case panel.TriageProject:
+		cmd, ok := a.fileTriaged(msg.Path, projectsDir, "active")
+		if ok {
+			a.triaged(msg.Path, "Made "+name+" a project")
+		}
+		return cmd
+	case panel.TriageDelete:
+		if a.currentFile == msg.Path {
+			a.showSplash()
+		}
+		item, err := a.vault.DeleteNote(msg.Path)
+		if err != nil {
+			a.status.SetError(fmt.Sprintf("delete: %v", err))
+			return nil
+		}
+		a.recordFileOp(fileOp{kind: "delete", trashed: []vault.TrashItem{item}})
+		a.tree.Refresh()
+		a.triaged(msg.Path, "Moved "+name+" to the trash")
+	case panel.TriageOpen:
+		a.navigateTo(msg.Path)
+	}
+	return nil
+}
+
+// handleTriagePrompt finishes a move or tag action with the folder or tags
+// typed into the prompt. Returns ok=false to keep the prompt open.
+func (a *App) handleTriagePrompt(action promptAction, value string) (cmd tea.Cmd, ok bool) {
+	value = strings.TrimSpace(value)
+	if action.kind == "triage-move" {
+		dir := filepath.Clean(strings.Trim(value, "/"))
+		if !filepath.IsLocal(dir) {
+			a.prompt.SetError("folder must be inside the vault")
+			return nil, false
+		}
+		cmd, ok := a.fileTriaged(action.path, dir, "")
+		if ok {
+			a.triaged(action.path, fmt.Sprintf("Moved %s to %s", filepath.Base(action.path), dir))
+		}
+		return cmd, ok
+	}
+
+	tags := strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+	cmd, err := a.rewriteNoteFile(action.path, func(content []byte) []byte {
+		content, _ = markdown.AddTags(content, tags)
+		return content
+	})
+	if err != nil {
+		a.prompt.SetError(err.Error())
+		return nil, false
+	}
+	// Tagging doesn't file the note; it stays up with its new tags.
+	if note, ok := a.triageNote(action.path); ok {
+		a.triage.Replace(note)
+	}
+	return cmd, true
+}
+
+// fileTriaged sets the note's status, removing it if status is empty, and
+// moves it into dir. It reports failures in the status bar.
+func (a *App) fileTriaged(relPath, dir, status string) (tea.Cmd, bool) {
+	newRel := filepath.Join(dir, filepath.Base(relPath))
+	if m := a.checkUniqueBasenameExcept(newRel, relPath); m != "" {
+		a.status.SetError(m)
+		return nil, false
+	}
+	if _, err := os.Stat(filepath.Join(a.cfg.VaultPath, newRel)); err == nil {
+		a.status.SetError(fmt.Sprintf("%s already exists", newRel))
+		return nil, false
+	}
+	if _, err := a.setNoteStatus(relPath, status); err != nil {
+		a.status.SetError(fmt.Sprintf("move: %v", err))
+		return nil, false
+	}
+	if err := a.vault.MoveNote(relPath, dir); err != nil {
+		a.status.SetError(fmt.Sprintf("move: %v", err))
+		return nil, false
+	}
+	if a.currentFile == relPath {
+		fullPath := filepath.Join(a.cfg.VaultPath, newRel)
+		if rpc := a.editor.GetRPC(); rpc != nil {
+			if err := rpc.SetBufferName(fullPath); err != nil {
+				return fatalCmd(err), false
+			}
+			if err := rpc.WriteBuffer(); err != nil {
+				return fatalCmd(err), false
+			}
+		}
+		a.status.SetFile(newRel)
+		a.currentFile = newRel
+	}
+	a.tree.Refresh()
+	return a.indexFile(filepath.Join(a.cfg.VaultPath, newRel)), true
+}
+
+// setNoteStatus sets the note's frontmatter status, or removes it.
+func (a *App) setNoteStatus(relPath, status string) (tea.Cmd, error) {
+	return a.rewriteNoteFile(relPath, func(content []byte) []byte {
+		return markdown.SetFrontmatterField(content, "status", status)
+	})
+}
+
+// rewriteNoteFile rewrites the note at relPath with edit, reloading it if
+// it is open, and returns the command that reindexes it.
+func (a *App) rewriteNoteFile(relPath string, edit func([]byte) []byte) (tea.Cmd, error) {
+	if a.currentFile == relPath {
+		a.saveCurrentNote()
+	}
+	absPath := filepath.Join(a.cfg.VaultPath, relPath)
+	content, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, err
+	}
+	updated := edit(content)
+	if string(updated) == string(content) {
+		return nil, nil
+	}
+	if err := vault.ApplyRewrites([]vault.FileRewrite{{Path: absPath, Content: updated}}); err != nil {
+		return nil, err
+	}
+	if a.currentFile == relPath {
+		a.reloadCurrentNote()
+	}
+	return a.indexFile(absPath), nil
+}
+
+// triaged drops a filed note from the triage and reports msg, closing the
+// overlay once the inbox is empty.
+func (a *App) triaged(relPath, msg string) {
+	a.triage.Remove(relPath)
+	if a.triage.Len() == 0 {
+		a.triage.Hide()
+		msg += "; inbox cleared"
+	}
+	a.status.SetMessage(msg)
+}
@@ -0,0 +1,129 @@
+package app
+
+import (
+	"fmt"
+	"path/filepath"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// maxFileOps is how many file operations Space u can go back through.
+const maxFileOps = 50
+
+// fileMove is a note or folder moved from one vault path to another.
+type fileMove struct {
+	from, to string
+}
+
+// fileOp is a rename, move or delete made from Kopr, with what it takes to
+// undo it. A paste or a multi-note delete is one operation.
+type fileOp struct {
+	kind    string // "rename", "move", "archive" or "delete"
+	moves   []fileMove
+	trashed []vault.TrashItem
+}
+
+// String describes the operation for the status bar, e.g. "rename of a.md".
+func (op fileOp) String() string {
+	switch n := len(op.moves) + len(op.trashed); {
+	case len(op.moves) == 1 && n == 1:
+		return op.kind + " of " + op.moves[0].from
+	case len(op.trashed) == 1 && n == 1:
+		return op.kind + " of " + op.trashed[0].Path
+	default:
+		return fmt.Sprintf("%s of %d notes", op.kind, n)
+	}
+}
+
+// recordFileOp logs an operation for undo, forgetting the oldest past
+// maxFileOps.
+func (a *App) recordFileOp(op fileOp) {
+	if len(op.moves)+len(op.trashed) == 0 {
+		return
+	}
+	a.fileOps = append(a.fileOps, op)
+	if len(a.fileOps) > maxFileOps {
+		a.fileOps = a.fileOps[len(a.fileOps)-maxFileOps:]
+	}
+}
+
+// recordMoves logs the notes a paste moved.
+func (a *App) recordMoves(moves []fileMove) {
+	a.recordFileOp(fileOp{kind: "move", moves: moves})
+}
+
+// UndoFileOp reverts the last rename, move or delete: notes go back where
+// they were, links a rename rewrote are rewritten to the old name, and
+// deleted notes come out of the trash. The operation is forgotten only once
+// fully undone; if undoing stops partway, what is left stays to be undone.
+func (a *App) UndoFileOp() tea.Cmd {
+	if len(a.fileOps) == 0 {
+		a.status.SetMessage("No file operation to undo")
+		return nil
+	}
+	last := len(a.fileOps) - 1
+	op := a.fileOps[last]
+	// remaining narrows the logged operation to what is still done.
+	remaining := func(moves []fileMove, trashed []vault.TrashItem) {
+		a.fileOps[last] = fileOp{kind: op.kind, moves: moves, trashed: trashed}
+	}
+
+	var cmds []tea.Cmd
+	for i := len(op.moves) - 1; i >= 0; i-- {
+		m := op.moves[i]
+		if op.kind == "rename" {
+			cmd, ok := a.renameNote(m.to, m.from, nil)
+			cmds = append(cmds, cmd)
+			if !ok {
+				remaining(op.moves[:i+1], op.trashed)
+				return tea.Batch(cmds...)
+			}
+			continue
+		}
+		// Moves keep the basename, so no links need rewriting; the
+		// watcher reindexes, as it does for the paste.
+		if err := a.vault.RenameNote(m.to, m.from); err != nil {
+			remaining(op.moves[:i+1], op.trashed)
+			a.status.SetError(fmt.Sprintf("undo %s: %v", op, err))
+			a.tree.Refresh()
+			return tea.Batch(cmds...)
+		}
+		if cmd := a.followMovedBuffer(m.to, m.from); cmd != nil {
+			remaining(op.moves[:i], op.trashed)
+			return tea.Batch(append(cmds, cmd)...)
+		}
+	}
+	for j, item := range op.trashed {
+		if err := a.vault.RestoreTrash(item); err != nil {
+			remaining(nil, op.trashed[j:])
+			a.status.SetError(fmt.Sprintf("undo %s: %v", op, err))
+			a.tree.Refresh()
+			return tea.Batch(cmds...)
+		}
+	}
+	a.fileOps = a.fileOps[:last]
+	a.tree.Refresh()
+	a.status.SetMessage("Undid " + op.String())
+	return tea.Batch(cmds...)
+}
+
+// followMovedBuffer points the open buffer at a note's new path after it
+// moved, saving it there.
+func (a *App) followMovedBuffer(oldRel, newRel string) tea.Cmd {
+	if a.currentFile != oldRel {
+		return nil
+	}
+	if rpc := a.editor.GetRPC(); rpc != nil {
+		if err := rpc.SetBufferName(filepath.Join(a.cfg.VaultPath, newRel)); err != nil {
+			return fatalCmd(err)
+		}
+		if err := rpc.WriteBuffer(); err != nil {
+			return fatalCmd(err)
+		}
+	}
+	a.status.SetFile(newRel)
+	a.currentFile = newRel
+	return nil
+}
@@ -3,6 +3,8 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"runtime"
+	"time"
 )
 
 type Config struct {
@@ -21,9 +23,70 @@ type Config struct {
 	NvimMode        string
 	ResetNvimConfig bool
 
+	// ReadOnly shows notes in a built-in viewer instead of Neovim and
+	// disables everything that changes the vault. Set by --read-only, or
+	// when no usable Neovim is installed.
+	ReadOnly bool
+
+	// MetricsListen, when set in server mode, serves Prometheus metrics over
+	// HTTP on this address (e.g. "127.0.0.1:9464"). Empty disables it.
+	MetricsListen string
+
+	// HostKeyPath is the SSH server's private host key, generated on first
+	// --serve if missing. Empty means ssh_host_key in the config directory.
+	HostKeyPath string
+
+	// HostKeyType is the type of key to generate: "ed25519", "rsa" or
+	// "ecdsa". An existing key is kept whatever its type.
+	HostKeyType string
+
+	// MaxSessionsPerKey caps concurrent SSH sessions per client identity
+	// (public key, or user and host without key auth). 0 means no limit.
+	MaxSessionsPerKey int
+
+	// MaxAuthTries is the number of SSH authentication attempts allowed per
+	// connection before it is dropped.
+	MaxAuthTries int
+
+	// IdleTimeout ends an SSH session after this long without keyboard or
+	// mouse input, saving open buffers first. 0 disables it.
+	IdleTimeout time.Duration
+
+	// LowBandwidth selects the low-bandwidth rendering profile for SSH
+	// sessions: "on", "off", or "auto" to use it when the measured round
+	// trip exceeds LowBandwidthRTT. A client can also ask for it per
+	// connection.
+	LowBandwidth    string
+	LowBandwidthRTT time.Duration
+
 	// AutoFormatOnSave enables Kopr's deterministic Markdown formatter after save.
 	AutoFormatOnSave bool
 
+	// AutoFormatScope selects what formatting rewrites: "changed" replaces
+	// only the text the formatter changed, keeping marks, folds and the
+	// cursor on the rest; "buffer" replaces the whole buffer.
+	AutoFormatScope string
+
+	// EditorBackend selects how Neovim is drawn: "pty" runs it in a
+	// pseudo-terminal and reads the screen through a VT emulator; "ui"
+	// attaches to its UI protocol and draws the grid directly, with the
+	// command line and messages rendered by kopr.
+	EditorBackend string
+
+	// ReconcileInterval is how often the vault is scanned for changes the
+	// file watcher missed (after a git pull, rsync or sleep). 0 disables
+	// the background scan; Space i s still runs one.
+	ReconcileInterval time.Duration
+
+	// OptimizeInterval is how often a running session tidies the index:
+	// merging search segments and giving free pages back. 0 leaves it to
+	// exit and Space i o.
+	OptimizeInterval time.Duration
+
+	// ScrollbackLines is how many rows that scroll off the embedded screen
+	// are kept for copy mode (pty backend only); 0 keeps none.
+	ScrollbackLines int
+
 	// RenderMath enables LaTeX math rendering via render-markdown.nvim's latex module.
 	RenderMath bool
 
@@ -32,6 +95,110 @@ type Config struct {
 	// to Neovim's runtimepath so fenced code blocks get syntax highlighting for
 	// languages beyond those bundled with Neovim.
 	TreesitterParsers string
+
+	// HabitsHeading names the checklist section in daily notes that the
+	// habit tracker reads (e.g. "## Habits").
+	HabitsHeading string
+
+	// ReviewAfterDays is how many days a note can go unmodified and
+	// unreviewed before the review queue lists it as stale.
+	ReviewAfterDays int
+
+	// AutoLinkOnSave offers to turn plain-text mentions of other notes'
+	// titles/aliases into [[links]] after save.
+	AutoLinkOnSave bool
+
+	// ExportInlineEmbeds replaces ![[note]] embeds with the embedded
+	// notes' content, recursively, when a note is exported.
+	ExportInlineEmbeds bool
+
+	// CheckForUpdates looks up the latest GitHub release at startup and
+	// shows it in the status bar when it is newer than the running binary.
+	CheckForUpdates bool
+
+	// SavedSearches are named finder queries listed in the saved-search
+	// finder and the info panel.
+	SavedSearches []SavedSearch
+
+	// ExternalSources are read-only directories of markdown notes outside
+	// the vault (e.g. a work repo's docs/) that are indexed, searchable and
+	// linkable, and listed under "External" in the tree. Read at startup.
+	ExternalSources []ExternalSource
+
+	// TemplateDir holds note templates; relative paths are inside the vault.
+	TemplateDir string
+
+	// ArchiveDir is the vault folder Space n a moves notes into. Notes there
+	// stay indexed but are left out of the finder unless asked for with
+	// is:archived.
+	ArchiveDir string
+
+	// AttachmentDir is the vault folder images pasted from the clipboard
+	// are written to.
+	AttachmentDir string
+
+	// AttachmentLinks is how a pasted image is linked: "wiki" (![[name.png]])
+	// or "markdown" (![](path/to/name.png), relative to the note).
+	AttachmentLinks string
+
+	// NoteUIDs gives every new note a uid: frontmatter key, a random id
+	// that stays the same when the note is renamed or moved, for task apps
+	// and bookmarks to refer to it by.
+	NoteUIDs bool
+
+	// ShowTemplates lists the template directory in the tree, finder and
+	// index like any other notes.
+	ShowTemplates bool
+
+	// FinderGroupByFolder clusters finder results under folder headers
+	// instead of showing each note's folder after its title.
+	FinderGroupByFolder bool
+
+	// FileManager is the command that opens a folder in the system file
+	// manager; the folder is appended as the last argument.
+	FileManager string
+
+	// RemoteFileManager replaces FileManager in SSH server mode, where the
+	// local file manager would open on the server. Empty disables it.
+	RemoteFileManager string
+
+	// ExternalEditor is a GUI editor command run alongside the TUI for the
+	// "open in external editor" action. Empty suspends the TUI and runs
+	// $EDITOR in the terminal instead.
+	ExternalEditor string
+
+	// UnlockCommand decrypts a vault encrypted at rest, e.g.
+	// "git-crypt unlock". It runs in the vault, in the terminal, before the
+	// TUI starts when notes are still encrypted. Empty leaves encrypted
+	// notes out of the index.
+	UnlockCommand string
+
+	// LockCommand encrypts the vault again, e.g. "git-crypt lock". It runs
+	// on exit when UnlockCommand unlocked the vault.
+	LockCommand string
+
+	// FTSTokenizer selects how full-text search splits words: "default"
+	// (stemmed words) or "trigram" (substrings, for CJK text). Changing it
+	// rebuilds the search index.
+	FTSTokenizer string
+
+	// BasenameUniqueness is "vault" (every note name unique, [[name]] links
+	// by name alone) or "folder" (names unique per folder, links qualified
+	// with folders as needed, e.g. [[a/notes]]).
+	BasenameUniqueness string
+}
+
+// SavedSearch is a named finder query, e.g. "Inbox" = "status:inbox".
+type SavedSearch struct {
+	Name  string `toml:"name"`
+	Query string `toml:"query"`
+}
+
+// ExternalSource is a read-only notes directory, e.g. "work" =
+// "~/src/work/docs". Its notes are addressed as @external/<name>/<path>.
+type ExternalSource struct {
+	Name string `toml:"name"`
+	Path string `toml:"path"`
 }
 
 func Default() Config {
@@ -54,6 +221,37 @@ func Default() Config {
 		LeaderTimeout:    500,
 		NvimMode:         "managed",
 		AutoFormatOnSave: true,
+		AutoFormatScope:  "changed",
 		RenderMath:       true,
+		EditorBackend:    "pty",
+		ScrollbackLines:  1000,
+		ReconcileInterval: 5 * time.Minute,
+		OptimizeInterval:  time.Hour,
+		HabitsHeading:    "Habits",
+		ReviewAfterDays:  90,
+		TemplateDir:      "templates",
+		ArchiveDir:       "archive",
+		AttachmentDir:    "attachments",
+		AttachmentLinks:  "wiki",
+		FileManager:      defaultFileManager(),
+		FTSTokenizer:     "default",
+		BasenameUniqueness: "vault",
+		HostKeyType:       "ed25519",
+		MaxSessionsPerKey: 3,
+		MaxAuthTries:      6,
+		LowBandwidth:      "auto",
+		LowBandwidthRTT:   150 * time.Millisecond,
+	}
+}
+
+// defaultFileManager returns the platform's "open this folder" command.
+func defaultFileManager() string {
+	switch runtime.GOOS {
+	case "darwin":
+		return "open"
+	case "windows":
+		return "explorer"
+	default:
+		return "xdg-open"
 	}
 }
@@ -1,9 +1,11 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/BurntSushi/toml"
 )
@@ -18,8 +20,42 @@ type fileConfig struct {
 	LeaderKey         *string `toml:"leader_key"`
 	LeaderTimeout     *int    `toml:"leader_timeout"`
 	AutoFormatOnSave    *bool   `toml:"auto_format_on_save"`
+	AutoFormatScope     *string `toml:"auto_format_scope"`
 	RenderMath          *bool   `toml:"render_math"`
+	EditorBackend       *string `toml:"editor_backend"`
+	ScrollbackLines     *int    `toml:"scrollback_lines"`
+	ReconcileInterval   *string `toml:"reconcile_interval"`
+	OptimizeInterval    *string `toml:"optimize_interval"`
 	TreesitterParsers   *string `toml:"treesitter_parsers"`
+	HabitsHeading       *string `toml:"habits_heading"`
+	ReviewAfterDays     *int    `toml:"review_after_days"`
+	AutoLinkOnSave      *bool   `toml:"autolink_on_save"`
+	CheckForUpdates     *bool   `toml:"check_for_updates"`
+	ExportInlineEmbeds  *bool   `toml:"export_inline_embeds"`
+	SavedSearches       []SavedSearch `toml:"saved_search"`
+	ExternalSources     []ExternalSource `toml:"external_source"`
+	TemplateDir         *string `toml:"template_dir"`
+	ArchiveDir          *string `toml:"archive_dir"`
+	AttachmentDir       *string `toml:"attachment_dir"`
+	AttachmentLinks     *string `toml:"attachment_links"`
+	NoteUIDs            *bool   `toml:"note_uids"`
+	ShowTemplates       *bool   `toml:"show_templates"`
+	FinderGroupByFolder *bool   `toml:"finder_group_by_folder"`
+	FileManager         *string `toml:"file_manager"`
+	RemoteFileManager   *string `toml:"remote_file_manager"`
+	ExternalEditor      *string `toml:"external_editor"`
+	UnlockCommand       *string `toml:"unlock_command"`
+	LockCommand         *string `toml:"lock_command"`
+	FTSTokenizer        *string `toml:"fts_tokenizer"`
+	BasenameUniqueness  *string `toml:"basename_uniqueness"`
+	MetricsListen       *string `toml:"metrics_listen"`
+	HostKeyPath         *string `toml:"host_key_path"`
+	HostKeyType         *string `toml:"host_key_type"`
+	MaxSessionsPerKey   *int    `toml:"max_sessions_per_key"`
+	MaxAuthTries        *int    `toml:"max_auth_tries"`
+	IdleTimeout         *string `toml:"idle_timeout"`
+	LowBandwidth        *string `toml:"low_bandwidth"`
+	LowBandwidthRTT     *string `toml:"low_bandwidth_rtt"`
 }
 
 // ConfigDir returns the kopr config directory, respecting XDG_CONFIG_HOME.
@@ -77,12 +113,150 @@ func LoadFile(cfg *Config) (bool, error) {
 	if fc.AutoFormatOnSave != nil {
 		cfg.AutoFormatOnSave = *fc.AutoFormatOnSave
 	}
+	if fc.AutoFormatScope != nil {
+		if *fc.AutoFormatScope != "changed" && *fc.AutoFormatScope != "buffer" {
+			return true, fmt.Errorf("auto_format_scope: %q is not \"changed\" or \"buffer\"", *fc.AutoFormatScope)
+		}
+		cfg.AutoFormatScope = *fc.AutoFormatScope
+	}
 	if fc.RenderMath != nil {
 		cfg.RenderMath = *fc.RenderMath
 	}
+	if fc.EditorBackend != nil {
+		cfg.EditorBackend = *fc.EditorBackend
+	}
+	if fc.ScrollbackLines != nil {
+		cfg.ScrollbackLines = *fc.ScrollbackLines
+	}
+	if fc.ReconcileInterval != nil {
+		d, err := time.ParseDuration(*fc.ReconcileInterval)
+		if err != nil {
+			return true, fmt.Errorf("reconcile_interval: %w", err)
+		}
+		cfg.ReconcileInterval = d
+	}
+	if fc.OptimizeInterval != nil {
+		d, err := time.ParseDuration(*fc.OptimizeInterval)
+		if err != nil {
+			return true, fmt.Errorf("optimize_interval: %w", err)
+		}
+		cfg.OptimizeInterval = d
+	}
 	if fc.TreesitterParsers != nil {
 		cfg.TreesitterParsers = ExpandHome(*fc.TreesitterParsers)
 	}
+	if fc.HabitsHeading != nil {
+		cfg.HabitsHeading = *fc.HabitsHeading
+	}
+	if fc.ReviewAfterDays != nil {
+		cfg.ReviewAfterDays = *fc.ReviewAfterDays
+	}
+	if fc.AutoLinkOnSave != nil {
+		cfg.AutoLinkOnSave = *fc.AutoLinkOnSave
+	}
+	if fc.CheckForUpdates != nil {
+		cfg.CheckForUpdates = *fc.CheckForUpdates
+	}
+	if fc.ExportInlineEmbeds != nil {
+		cfg.ExportInlineEmbeds = *fc.ExportInlineEmbeds
+	}
+	if fc.SavedSearches != nil {
+		cfg.SavedSearches = fc.SavedSearches
+	}
+	if fc.ExternalSources != nil {
+		cfg.ExternalSources = make([]ExternalSource, len(fc.ExternalSources))
+		for i, src := range fc.ExternalSources {
+			if src.Name == "" || strings.ContainsAny(src.Name, `/\`) || src.Path == "" {
+				return true, fmt.Errorf("external_source: need a name without slashes and a path, got %+v", src)
+			}
+			cfg.ExternalSources[i] = ExternalSource{Name: src.Name, Path: ExpandHome(src.Path)}
+		}
+	}
+	if fc.TemplateDir != nil {
+		cfg.TemplateDir = ExpandHome(*fc.TemplateDir)
+	}
+	if fc.ArchiveDir != nil {
+		dir := filepath.Clean(*fc.ArchiveDir)
+		if !filepath.IsLocal(dir) {
+			return true, fmt.Errorf("archive_dir: %q is not a folder inside the vault", *fc.ArchiveDir)
+		}
+		cfg.ArchiveDir = dir
+	}
+	if fc.AttachmentDir != nil {
+		dir := filepath.Clean(*fc.AttachmentDir)
+		if !filepath.IsLocal(dir) {
+			return true, fmt.Errorf("attachment_dir: %q is not a folder inside the vault", *fc.AttachmentDir)
+		}
+		cfg.AttachmentDir = dir
+	}
+	if fc.AttachmentLinks != nil {
+		if *fc.AttachmentLinks != "wiki" && *fc.AttachmentLinks != "markdown" {
+			return true, fmt.Errorf("attachment_links: %q is not \"wiki\" or \"markdown\"", *fc.AttachmentLinks)
+		}
+		cfg.AttachmentLinks = *fc.AttachmentLinks
+	}
+	if fc.NoteUIDs != nil {
+		cfg.NoteUIDs = *fc.NoteUIDs
+	}
+	if fc.ShowTemplates != nil {
+		cfg.ShowTemplates = *fc.ShowTemplates
+	}
+	if fc.FinderGroupByFolder != nil {
+		cfg.FinderGroupByFolder = *fc.FinderGroupByFolder
+	}
+	if fc.FileManager != nil {
+		cfg.FileManager = *fc.FileManager
+	}
+	if fc.RemoteFileManager != nil {
+		cfg.RemoteFileManager = *fc.RemoteFileManager
+	}
+	if fc.MetricsListen != nil {
+		cfg.MetricsListen = *fc.MetricsListen
+	}
+	if fc.HostKeyPath != nil {
+		cfg.HostKeyPath = ExpandHome(*fc.HostKeyPath)
+	}
+	if fc.HostKeyType != nil {
+		cfg.HostKeyType = *fc.HostKeyType
+	}
+	if fc.MaxSessionsPerKey != nil {
+		cfg.MaxSessionsPerKey = *fc.MaxSessionsPerKey
+	}
+	if fc.MaxAuthTries != nil {
+		cfg.MaxAuthTries = *fc.MaxAuthTries
+	}
+	if fc.IdleTimeout != nil {
+		d, err := time.ParseDuration(*fc.IdleTimeout)
+		if err != nil {
+			return true, fmt.Errorf("idle_timeout: %w", err)
+		}
+		cfg.IdleTimeout = d
+	}
+	if fc.LowBandwidth != nil {
+		cfg.LowBandwidth = *fc.LowBandwidth
+	}
+	if fc.LowBandwidthRTT != nil {
+		d, err := time.ParseDuration(*fc.LowBandwidthRTT)
+		if err != nil {
+			return true, fmt.Errorf("low_bandwidth_rtt: %w", err)
+		}
+		cfg.LowBandwidthRTT = d
+	}
+	if fc.ExternalEditor != nil {
+		cfg.ExternalEditor = *fc.ExternalEditor
+	}
+	if fc.UnlockCommand != nil {
+		cfg.UnlockCommand = *fc.UnlockCommand
+	}
+	if fc.LockCommand != nil {
+		cfg.LockCommand = *fc.LockCommand
+	}
+	if fc.FTSTokenizer != nil {
+		cfg.FTSTokenizer = *fc.FTSTokenizer
+	}
+	if fc.BasenameUniqueness != nil {
+		cfg.BasenameUniqueness = *fc.BasenameUniqueness
+	}
 
 	return true, nil
 }
@@ -3,7 +3,9 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+	"time"
 )
 
 func TestExpandHome(t *testing.T) {
@@ -72,6 +74,24 @@ func TestLoadFile_Partial(t *testing.T) {
 	}
 }
 
+func TestLoadFile_InvalidAutoFormatScope(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", tmp)
+
+	dir := filepath.Join(tmp, "kopr")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(`auto_format_scope = "file"`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := Default()
+	if _, err := LoadFile(&cfg); err == nil {
+		t.Error("LoadFile should reject an unknown auto_format_scope")
+	}
+}
+
 func TestLoadFile_Full(t *testing.T) {
 	tmp := t.TempDir()
 	t.Setenv("XDG_CONFIG_HOME", tmp)
@@ -87,8 +107,52 @@ nvim_mode = "user"
 leader_key = ","
 leader_timeout = 300
 auto_format_on_save = false
+auto_format_scope = "buffer"
 render_math = false
 treesitter_parsers = "~/.local/share/nvim/site"
+habits_heading = "Routines"
+review_after_days = 30
+autolink_on_save = true
+check_for_updates = true
+export_inline_embeds = true
+template_dir = "_templates"
+archive_dir = "old/"
+attachment_dir = "assets/images"
+attachment_links = "markdown"
+note_uids = true
+show_templates = true
+finder_group_by_folder = true
+file_manager = "thunar"
+remote_file_manager = "notify-send"
+external_editor = "code --wait"
+unlock_command = "git-crypt unlock"
+lock_command = "git-crypt lock"
+fts_tokenizer = "trigram"
+editor_backend = "ui"
+scrollback_lines = 250
+reconcile_interval = "90s"
+optimize_interval = "30m"
+basename_uniqueness = "folder"
+metrics_listen = "127.0.0.1:9464"
+host_key_path = "~/keys/kopr_host"
+host_key_type = "rsa"
+max_sessions_per_key = 1
+max_auth_tries = 3
+idle_timeout = "45m"
+low_bandwidth = "on"
+low_bandwidth_rtt = "300ms"
+
+[[saved_search]]
+name = "Inbox"
+query = "status:inbox"
+
+[[saved_search]]
+name = "This week"
+query = "modified:7d"
+
+[[external_source]]
+name = "work"
+path = "~/src/work/docs"
 `
 	if err := os.WriteFile(filepath.Join(dir, "config.toml"), []byte(content), 0644); err != nil {
 		t.Fatal(err)
@@ -126,6 +190,9 @@ treesitter_parsers = "~/.local/share/nvim/site"
 	if cfg.AutoFormatOnSave != false {
 		t.Errorf("AutoFormatOnSave = %v, want %v", cfg.AutoFormatOnSave, false)
 	}
+	if cfg.AutoFormatScope != "buffer" {
+		t.Errorf("AutoFormatScope = %q, want %q", cfg.AutoFormatScope, "buffer")
+	}
 	if cfg.RenderMath != false {
 		t.Errorf("RenderMath = %v, want %v", cfg.RenderMath, false)
 	}
@@ -133,6 +200,107 @@ treesitter_parsers = "~/.local/share/nvim/site"
 	if cfg.TreesitterParsers != wantParsers {
 		t.Errorf("TreesitterParsers = %q, want %q", cfg.TreesitterParsers, wantParsers)
 	}
+	if cfg.HabitsHeading != "Routines" {
+		t.Errorf("HabitsHeading = %q, want %q", cfg.HabitsHeading, "Routines")
+	}
+	if cfg.ReviewAfterDays != 30 {
+		t.Errorf("ReviewAfterDays = %d, want %d", cfg.ReviewAfterDays, 30)
+	}
+	if cfg.AutoLinkOnSave != true {
+		t.Errorf("AutoLinkOnSave = %v, want %v", cfg.AutoLinkOnSave, true)
+	}
+	if cfg.CheckForUpdates != true {
+		t.Errorf("CheckForUpdates = %v, want %v", cfg.CheckForUpdates, true)
+	}
+	if cfg.ExportInlineEmbeds != true {
+		t.Errorf("ExportInlineEmbeds = %v, want %v", cfg.ExportInlineEmbeds, true)
+	}
+	if cfg.TemplateDir != "_templates" {
+		t.Errorf("TemplateDir = %q, want %q", cfg.TemplateDir, "_templates")
+	}
+	if cfg.ArchiveDir != "old" {
+		t.Errorf("ArchiveDir = %q, want %q", cfg.ArchiveDir, "old")
+	}
+	if cfg.AttachmentDir != filepath.FromSlash("assets/images") {
+		t.Errorf("AttachmentDir = %q, want %q", cfg.AttachmentDir, "assets/images")
+	}
+	if cfg.AttachmentLinks != "markdown" {
+		t.Errorf("AttachmentLinks = %q, want %q", cfg.AttachmentLinks, "markdown")
+	}
+	if cfg.NoteUIDs != true {
+		t.Errorf("NoteUIDs = %v, want %v", cfg.NoteUIDs, true)
+	}
+	if cfg.ShowTemplates != true {
+		t.Errorf("ShowTemplates = %v, want %v", cfg.ShowTemplates, true)
+	}
+	if cfg.FinderGroupByFolder != true {
+		t.Errorf("FinderGroupByFolder = %v, want %v", cfg.FinderGroupByFolder, true)
+	}
+	if cfg.FileManager != "thunar" {
+		t.Errorf("FileManager = %q, want %q", cfg.FileManager, "thunar")
+	}
+	if cfg.RemoteFileManager != "notify-send" {
+		t.Errorf("RemoteFileManager = %q, want %q", cfg.RemoteFileManager, "notify-send")
+	}
+	if cfg.ExternalEditor != "code --wait" {
+		t.Errorf("ExternalEditor = %q, want %q", cfg.ExternalEditor, "code --wait")
+	}
+	if cfg.UnlockCommand != "git-crypt unlock" {
+		t.Errorf("UnlockCommand = %q, want %q", cfg.UnlockCommand, "git-crypt unlock")
+	}
+	if cfg.LockCommand != "git-crypt lock" {
+		t.Errorf("LockCommand = %q, want %q", cfg.LockCommand, "git-crypt lock")
+	}
+	if cfg.FTSTokenizer != "trigram" {
+		t.Errorf("FTSTokenizer = %q, want %q", cfg.FTSTokenizer, "trigram")
+	}
+	if cfg.EditorBackend != "ui" {
+		t.Errorf("EditorBackend = %q, want %q", cfg.EditorBackend, "ui")
+	}
+	if cfg.ScrollbackLines != 250 {
+		t.Errorf("ScrollbackLines = %d, want 250", cfg.ScrollbackLines)
+	}
+	if cfg.ReconcileInterval != 90*time.Second {
+		t.Errorf("ReconcileInterval = %v, want 90s", cfg.ReconcileInterval)
+	}
+	if cfg.OptimizeInterval != 30*time.Minute {
+		t.Errorf("OptimizeInterval = %v, want 30m", cfg.OptimizeInterval)
+	}
+	if cfg.BasenameUniqueness != "folder" {
+		t.Errorf("BasenameUniqueness = %q, want %q", cfg.BasenameUniqueness, "folder")
+	}
+	if cfg.MetricsListen != "127.0.0.1:9464" {
+		t.Errorf("MetricsListen = %q, want %q", cfg.MetricsListen, "127.0.0.1:9464")
+	}
+	if want := filepath.Join(home, "keys", "kopr_host"); cfg.HostKeyPath != want {
+		t.Errorf("HostKeyPath = %q, want %q", cfg.HostKeyPath, want)
+	}
+	if cfg.HostKeyType != "rsa" {
+		t.Errorf("HostKeyType = %q, want %q", cfg.HostKeyType, "rsa")
+	}
+	if cfg.MaxSessionsPerKey != 1 {
+		t.Errorf("MaxSessionsPerKey = %d, want %d", cfg.MaxSessionsPerKey, 1)
+	}
+	if cfg.MaxAuthTries != 3 {
+		t.Errorf("MaxAuthTries = %d, want %d", cfg.MaxAuthTries, 3)
+	}
+	if cfg.IdleTimeout != 45*time.Minute {
+		t.Errorf("IdleTimeout = %v, want %v", cfg.IdleTimeout, 45*time.Minute)
+	}
+	if cfg.LowBandwidth != "on" {
+		t.Errorf("LowBandwidth = %q, want %q", cfg.LowBandwidth, "on")
+	}
+	if cfg.LowBandwidthRTT != 300*time.Millisecond {
+		t.Errorf("LowBandwidthRTT = %v, want %v", cfg.LowBandwidthRTT, 300*time.Millisecond)
+	}
+	wantSearches := []SavedSearch{{Name: "Inbox", Query: "status:inbox"}, {Name: "This week", Query: "modified:7d"}}
+	if !slices.Equal(cfg.SavedSearches, wantSearches) {
+		t.Errorf("SavedSearches = %+v, want %+v", cfg.SavedSearches, wantSearches)
+	}
+	wantSources := []ExternalSource{{Name: "work", Path: ExpandHome("~/src/work/docs")}}
+	if !slices.Equal(cfg.ExternalSources, wantSources) {
+		t.Errorf("ExternalSources = %+v, want %+v", cfg.ExternalSources, wantSources)
+	}
 }
 
 func TestSaveFile(t *testing.T) {
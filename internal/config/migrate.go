@@ -0,0 +1,216 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// MigrateChoice is what to do with the previous vault when the vault path
+// changes.
+type MigrateChoice int
+
+const (
+	MigrateFresh  MigrateChoice = iota // leave the old vault, start empty
+	MigrateMove                        // move the old vault to the new path
+	MigrateCopy                        // copy the old vault, keeping the original
+	MigrateCancel                      // quit without touching anything
+)
+
+// lastVaultPath returns the file recording the vault kopr last opened.
+func lastVaultPath() string {
+	return filepath.Join(ConfigDir(), "last_vault")
+}
+
+// LastVault returns the vault kopr last opened, or "" if none is recorded.
+func LastVault() string {
+	data, err := os.ReadFile(lastVaultPath())
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// RecordVault remembers path as the vault kopr last opened.
+func RecordVault(path string) error {
+	if err := os.MkdirAll(ConfigDir(), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(lastVaultPath(), []byte(path+"\n"), 0644)
+}
+
+// NeedsMigration reports whether opening vault to would appear to lose the
+// notes in from: from is a different, non-empty directory and to is missing
+// or empty.
+func NeedsMigration(from, to string) bool {
+	if from == "" || filepath.Clean(from) == filepath.Clean(to) {
+		return false
+	}
+	if empty, err := dirEmpty(from); err != nil || empty {
+		return false
+	}
+	empty, err := dirEmpty(to)
+	return errors.Is(err, fs.ErrNotExist) || (err == nil && empty)
+}
+
+func dirEmpty(path string) (bool, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return false, err
+	}
+	return len(entries) == 0, nil
+}
+
+// MigrateVault moves or copies the vault at from, including its .kopr state,
+// to the missing or empty directory to. MigrateFresh does nothing.
+func MigrateVault(from, to string, choice MigrateChoice) error {
+	switch choice {
+	case MigrateMove:
+		// Rename needs the target gone; it is empty if it exists at all.
+		if err := os.Remove(to); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(from, to); err == nil {
+			return nil
+		}
+		// Different filesystems: copy, then remove the original.
+		if err := copyTree(from, to); err != nil {
+			return err
+		}
+		return os.RemoveAll(from)
+	case MigrateCopy:
+		return copyTree(from, to)
+	}
+	return nil
+}
+
+// copyTree copies the directory tree at src to dst, preserving file modes
+// and symlinks.
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(path, target, info.Mode().Perm())
+		}
+		return nil
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close() //nolint:errcheck // read-only
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		return errors.Join(err, out.Close())
+	}
+	return out.Close()
+}
+
+type migrateModel struct {
+	from, to string
+	choice   MigrateChoice
+	done     bool
+}
+
+func (m migrateModel) Init() tea.Cmd {
+	return nil
+}
+
+func (m migrateModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	switch key.String() {
+	case "m":
+		m.choice = MigrateMove
+	case "c":
+		m.choice = MigrateCopy
+	case "f":
+		m.choice = MigrateFresh
+	case "esc", "ctrl+c", "q":
+		m.choice = MigrateCancel
+	default:
+		return m, nil
+	}
+	m.done = true
+	return m, tea.Quit
+}
+
+func (m migrateModel) View() string {
+	title := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(lipgloss.Color("212")).
+		Render("Vault path changed")
+	key := lipgloss.NewStyle().Bold(true)
+
+	var s string
+	s += "\n " + title + "\n\n"
+	s += " Your notes are in " + m.from + "\n"
+	s += " but kopr is opening " + m.to + ", which is empty.\n\n"
+	s += "   " + key.Render("m") + "  move the vault there\n"
+	s += "   " + key.Render("c") + "  copy the vault there, keeping the original\n"
+	s += "   " + key.Render("f") + "  start fresh with an empty vault\n\n"
+
+	dim := lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+	s += " " + dim.Render("Press Esc to quit without changes") + "\n"
+
+	return s
+}
+
+// RunMigrate asks what to do with the vault at from now that kopr is opening
+// to, and carries out the choice.
+func RunMigrate(from, to string) (MigrateChoice, error) {
+	p := tea.NewProgram(migrateModel{from: from, to: to})
+	final, err := p.Run()
+	if err != nil {
+		return MigrateCancel, err
+	}
+	fm, ok := final.(migrateModel)
+	if !ok {
+		return MigrateCancel, fmt.Errorf("unexpected model type from migration prompt")
+	}
+	if !fm.done || fm.choice == MigrateCancel {
+		return MigrateCancel, nil
+	}
+	if err := MigrateVault(from, to, fm.choice); err != nil {
+		return fm.choice, fmt.Errorf("migrating vault: %w", err)
+	}
+	return fm.choice, nil
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeVault(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, ".kopr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "note.md"), []byte("# Note\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".kopr", "index.db"), []byte("db"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLastVault(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	if got := LastVault(); got != "" {
+		t.Errorf("LastVault() = %q before recording, want empty", got)
+	}
+	if err := RecordVault("/home/user/notes"); err != nil {
+		t.Fatal(err)
+	}
+	if got := LastVault(); got != "/home/user/notes" {
+		t.Errorf("LastVault() = %q, want /home/user/notes", got)
+	}
+}
+
+func TestNeedsMigration(t *testing.T) {
+	tmp := t.TempDir()
+	old := filepath.Join(tmp, "old")
+	writeVault(t, old)
+	empty := filepath.Join(tmp, "empty")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	full := filepath.Join(tmp, "full")
+	writeVault(t, full)
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     bool
+	}{
+		{"no previous vault", "", empty, false},
+		{"same vault", old, old + "/", false},
+		{"missing target", old, filepath.Join(tmp, "new"), true},
+		{"empty target", old, empty, true},
+		{"populated target", old, full, false},
+		{"previous vault gone", filepath.Join(tmp, "gone"), empty, false},
+		{"previous vault empty", empty, filepath.Join(tmp, "new"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NeedsMigration(tt.from, tt.to); got != tt.want {
+				t.Errorf("NeedsMigration(%q, %q) = %v, want %v", tt.from, tt.to, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrateVault(t *testing.T) {
+	for _, choice := range []MigrateChoice{MigrateMove, MigrateCopy} {
+		tmp := t.TempDir()
+		from := filepath.Join(tmp, "old")
+		writeVault(t, from)
+		to := filepath.Join(tmp, "sub", "new")
+
+		if err := MigrateVault(from, to, choice); err != nil {
+			t.Fatalf("choice %d: %v", choice, err)
+		}
+		for _, rel := range []string{"note.md", ".kopr/index.db"} {
+			if _, err := os.Stat(filepath.Join(to, rel)); err != nil {
+				t.Errorf("choice %d: %s not migrated: %v", choice, rel, err)
+			}
+		}
+		_, err := os.Stat(from)
+		if kept := err == nil; kept != (choice == MigrateCopy) {
+			t.Errorf("choice %d: original kept = %v", choice, kept)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// copyMode browses the VT screen's scrollback and the screen as it was on
+// entry, like tmux's copy mode, to review and yank output that scrolled
+// off. Keys follow Vim: hjkl, 0 and $, Ctrl-U/D/B/F, gg and G move; v and V
+// start a character or line selection; y or Enter yanks it; Esc clears it
+// and q or Esc leaves.
+type copyMode struct {
+	lines         []scrollLine // scrollback, then the screen
+	width, height int
+	top           int // first line shown
+	row, col      int // cursor
+
+	selecting            bool
+	linewise             bool
+	anchorRow, anchorCol int
+
+	pending string // first key of a two-key command
+}
+
+// newCopyMode starts copy mode with the cursor on the last line, so the
+// view matches the screen it replaces.
+func newCopyMode(lines []scrollLine, width, height int) *copyMode {
+	c := &copyMode{lines: lines, width: width, height: max(height, 1), row: len(lines) - 1}
+	c.top = len(lines) - c.height
+	c.clamp()
+	return c
+}
+
+// handleKey applies a key. It returns the text to yank, if any, and
+// whether copy mode is over.
+func (c *copyMode) handleKey(msg tea.KeyMsg) (yank string, done bool) {
+	key := msg.String()
+	if c.pending == "g" {
+		c.pending = ""
+		if key == "g" {
+			c.row = 0
+			c.clamp()
+		}
+		return "", false
+	}
+
+	half := max(1, c.height/2)
+	switch key {
+	case "h", "left":
+		c.col--
+	case "l", "right":
+		c.col++
+	case "j", "down":
+		c.row++
+	case "k", "up":
+		c.row--
+	case "0", "home":
+		c.col = 0
+	case "$", "end":
+		c.col = max(ansi.StringWidth(c.lines[c.row].plain)-1, 0)
+	case "ctrl+d":
+		c.scroll(half)
+	case "ctrl+u":
+		c.scroll(-half)
+	case "ctrl+f", "pgdown":
+		c.scroll(c.height)
+	case "ctrl+b", "pgup":
+		c.scroll(-c.height)
+	case "G":
+		c.row = len(c.lines) - 1
+	case "g":
+		c.pending = "g"
+	case "v", "V":
+		linewise := key == "V"
+		if c.selecting && c.linewise == linewise {
+			c.selecting = false
+		} else {
+			if !c.selecting {
+				c.anchorRow, c.anchorCol = c.row, c.col
+			}
+			c.selecting, c.linewise = true, linewise
+		}
+	case "y", "enter":
+		if c.selecting {
+			return c.selection(), true
+		}
+		if key == "y" {
+			return c.lines[c.row].plain, true
+		}
+		return "", true
+	case "esc":
+		if c.selecting {
+			c.selecting = false
+			return "", false
+		}
+		return "", true
+	case "q":
+		return "", true
+	}
+	c.clamp()
+	return "", false
+}
+
+// resize fits the view to a new editor size. Lines already captured keep
+// their width.
+func (c *copyMode) resize(width, height int) {
+	c.width, c.height = width, max(height, 1)
+	c.clamp()
+}
+
+// scroll moves the view by delta lines, taking the cursor along.
+func (c *copyMode) scroll(delta int) {
+	c.top += delta
+	c.row += delta
+	c.clamp()
+}
+
+// clamp keeps the cursor on a line and the view on the cursor.
+func (c *copyMode) clamp() {
+	c.row = max(0, min(c.row, len(c.lines)-1))
+	c.col = max(0, min(c.col, c.width-1))
+	if c.row < c.top {
+		c.top = c.row
+	}
+	if c.row >= c.top+c.height {
+		c.top = c.row - c.height + 1
+	}
+	c.top = max(0, min(c.top, len(c.lines)-c.height))
+}
+
+// bounds returns the selection's first and last line, and its first and
+// past-the-end columns on those lines.
+func (c *copyMode) bounds() (startRow, startCol, endRow, endCol int) {
+	startRow, startCol, endRow, endCol = c.anchorRow, c.anchorCol, c.row, c.col
+	if endRow < startRow || (endRow == startRow && endCol < startCol) {
+		startRow, startCol, endRow, endCol = endRow, endCol, startRow, startCol
+	}
+	if c.linewise {
+		return startRow, 0, endRow, c.width
+	}
+	return startRow, startCol, endRow, endCol + 1
+}
+
+// selection returns the selected text, trailing spaces trimmed from each
+// line.
+func (c *copyMode) selection() string {
+	startRow, startCol, endRow, endCol := c.bounds()
+	out := make([]string, 0, endRow-startRow+1)
+	for r := startRow; r <= endRow; r++ {
+		from, to := 0, c.width
+		if r == startRow {
+			from = startCol
+		}
+		if r == endRow {
+			to = endCol
+		}
+		out = append(out, strings.TrimRight(ansi.Cut(c.lines[r].plain, from, to), " "))
+	}
+	return strings.Join(out, "\n")
+}
+
+// view draws the lines in view, the selection in reverse video and the
+// position in the scrollback in the top-right corner.
+func (c *copyMode) view() string {
+	rows := make([]string, c.height)
+	startRow, startCol, endRow, endCol := c.bounds()
+	for i := range rows {
+		r := c.top + i
+		switch {
+		case r >= len(c.lines):
+		case c.selecting && r >= startRow && r <= endRow:
+			from, to := 0, c.width
+			if r == startRow {
+				from = startCol
+			}
+			if r == endRow {
+				to = endCol
+			}
+			line := padLine(c.lines[r].plain, c.width)
+			rows[i] = ansi.Cut(line, 0, from) + "\x1b[7m" + ansi.Cut(line, from, to) + "\x1b[27m" + ansi.Cut(line, to, c.width)
+		case r == c.row:
+			rows[i] = insertCursor(c.lines[r].styled, c.col)
+		default:
+			rows[i] = c.lines[r].styled
+		}
+	}
+
+	tag := fmt.Sprintf("[%d/%d]", max(len(c.lines)-c.height, 0)-c.top, max(len(c.lines)-c.height, 0))
+	if w := ansi.StringWidth(tag); w < c.width {
+		first := ansi.Truncate(rows[0], c.width-w, "")
+		rows[0] = first + "\x1b[0m" + strings.Repeat(" ", c.width-w-ansi.StringWidth(first)) + "\x1b[7m" + tag + "\x1b[27m"
+	}
+	return strings.Join(rows, "\n")
+}
@@ -14,13 +14,13 @@ import (
 // Messages
 type vtOutputMsg struct {
 	data []byte
-	pty  *nvimPTY
+	pty  *ptyProcess
 }
 
 type vtClosedMsg struct{ err error }
 
 type editorStartedMsg struct {
-	nvim   *nvimPTY
+	nvim   *ptyProcess
 	screen *vtScreen
 	socket string
 }
@@ -43,18 +43,57 @@ type NoteClosedMsg struct {
 // SaveUnnamedMsg is sent when :w is used on an unnamed buffer.
 type SaveUnnamedMsg struct{}
 
+// BufferModifiedMsg is sent when the current buffer gains or loses unsaved
+// changes, or another buffer is entered.
+type BufferModifiedMsg struct {
+	Modified bool
+}
+
 // BufferWrittenMsg is sent when Neovim writes a buffer to disk.
 // Path is the absolute path of the written file.
 type BufferWrittenMsg struct {
 	Path string
 }
 
-// FollowLinkMsg is sent when the user presses gf on a wiki link.
-type FollowLinkMsg struct{}
+// FileChangedOnDiskMsg is sent when Neovim finds, on :checktime, that a
+// buffer's file changed on disk since it was read or written, as after a git
+// pull or an edit in another program. Path is absolute. Reloaded is set when
+// the buffer had no unsaved changes and Neovim reloaded it; otherwise the
+// buffer is left as it was. Deleted is set when the file is gone.
+type FileChangedOnDiskMsg struct {
+	Path     string
+	Reloaded bool
+	Deleted  bool
+}
+
+// FollowLinkMsg is sent when the user presses gf on a wiki link. Target is
+// the link followed in the read-only viewer; from Neovim it is empty and
+// the link is read from under the cursor. Section is the link's #section,
+// if any.
+type FollowLinkMsg struct {
+	Target  string
+	Section string
+}
 
 // GoBackMsg is sent when the user presses gb to go back to the previous note.
 type GoBackMsg struct{}
 
+// QuickfixStepMsg is sent when the user presses ]q or [q to move through the
+// search results list. Delta is 1 for the next result and -1 for the previous.
+type QuickfixStepMsg struct {
+	Delta int
+}
+
+// CursorLineMsg is sent when the Neovim cursor moves to another line.
+// Line is 1-based.
+type CursorLineMsg struct {
+	Line int
+}
+
+// TextChangedMsg is sent shortly after the buffer's text changes or another
+// buffer is entered.
+type TextChangedMsg struct{}
+
 // YankMsg is sent when text is yanked in Neovim (via TextYankPost autocmd).
 type YankMsg struct {
 	Text string
@@ -68,7 +107,8 @@ type ColorsReadyMsg struct {
 }
 
 // Editor is a Bubble Tea model that embeds Neovim in a PTY
-// and renders it via a VT emulator, with RPC for programmatic control.
+// and renders it via a VT emulator, or with SetUIProtocol draws the grid
+// Neovim sends over its UI protocol, with RPC for programmatic control.
 type Editor struct {
 	width       int
 	height      int
@@ -79,9 +119,14 @@ type Editor struct {
 	renderMath         bool
 	treesitterParsers  string
 	theme       *theme.Theme
-	nvim        *nvimPTY
+	nvim        *ptyProcess
 	rpc         *RPC
 	screen      *vtScreen
+	scrollback  *scrollback // rows scrolled off screen, kept across resizes
+	copy        *copyMode   // set while copy mode is shown
+	grid        *uiGrid // set instead of nvim and screen with the UI protocol backend
+	uiProtocol  bool
+	statusLine  StatusLineMsg // last sent to the app
 	started     bool
 	mode        NvimMode
 	err         error
@@ -89,10 +134,57 @@ type Editor struct {
 	focused        bool
 	showSplash     bool
 	lastMouseButton tea.MouseButton
+	onRPCError      func(error)
+	noTrueColor     bool // client can't show 24-bit color; Neovim uses cterm colors
+	readOnly        bool // no Neovim: notes are shown in viewer
+	viewer          viewer
 }
 
 // SetTheme sets the color theme for the editor splash screen.
-func (e *Editor) SetTheme(th *theme.Theme) { e.theme = th }
+func (e *Editor) SetTheme(th *theme.Theme) {
+	e.theme = th
+	e.viewer.theme = th
+}
+
+// SetTrueColor tells Neovim whether the terminal it is shown on supports
+// 24-bit color. Without it Neovim runs with notermguicolors and the
+// colorscheme's 256-color palette. Call before Start.
+func (e *Editor) SetTrueColor(on bool) { e.noTrueColor = !on }
+
+// SetReadOnly shows notes in a built-in read-only viewer instead of
+// starting Neovim, for hosts without a usable nvim. Call before Start.
+func (e *Editor) SetReadOnly(on bool) { e.readOnly = on }
+
+// SetUIProtocol draws Neovim from its UI protocol (nvim_ui_attach) instead
+// of running it in a PTY behind a VT emulator. Call before Start.
+func (e *Editor) SetUIProtocol(on bool) { e.uiProtocol = on }
+
+// SetRPCErrorHook registers fn to be called for every failed RPC call,
+// including a failed connection. Call before Start.
+func (e *Editor) SetRPCErrorHook(fn func(error)) { e.onRPCError = fn }
+
+// SetScrollback keeps up to lines rows that scroll off the VT screen for
+// copy mode; 0 keeps none. Call before Start.
+func (e *Editor) SetScrollback(lines int) { e.scrollback = newScrollback(lines) }
+
+// EnterCopyMode shows the scrollback and the screen for browsing and
+// yanking until the user leaves with q or Esc. It reports false when there
+// is no VT screen to copy from, as with the UI protocol backend.
+func (e *Editor) EnterCopyMode() bool {
+	if e.screen == nil || e.showSplash || e.readOnly {
+		return false
+	}
+	var lines []scrollLine
+	if e.scrollback != nil {
+		lines = append(lines, e.scrollback.lines...)
+	}
+	lines = append(lines, e.screen.rows()...)
+	e.copy = newCopyMode(lines, e.width, e.height)
+	return true
+}
+
+// InCopyMode reports whether copy mode is shown.
+func (e Editor) InCopyMode() bool { return e.copy != nil }
 
 func New(vaultPath string, profileMode ProfileMode, colorscheme string, renderMath bool, treesitterParsers string) Editor {
 	return Editor{
@@ -104,6 +196,7 @@ func New(vaultPath string, profileMode ProfileMode, colorscheme string, renderMa
 		mode:              ModeNormal,
 		focused:           true,
 		showSplash:        true,
+		viewer:            newViewer(),
 	}
 }
 
@@ -118,6 +211,8 @@ func (e Editor) Init() tea.Cmd {
 // start spawns Neovim and returns resources via message.
 func (e Editor) start() tea.Cmd {
 	width, height, vaultPath, profileMode, tsParsers := e.width, e.height, e.vaultPath, e.profileMode, e.treesitterParsers
+	trueColor := !e.noTrueColor
+	sb := e.scrollback
 	return func() tea.Msg {
 		if err := EnsureProfile(profileMode); err != nil {
 			return editorErrorMsg{fmt.Errorf("nvim profile: %w", err)}
@@ -128,18 +223,18 @@ func (e Editor) start() tea.Cmd {
 			return editorErrorMsg{fmt.Errorf("remove socket %s: %w", socketPath, err)}
 		}
 
-		nvim, err := startNvim(width, height, socketPath, vaultPath, tsParsers)
+		nvim, err := startNvim(width, height, socketPath, vaultPath, tsParsers, trueColor)
 		if err != nil {
 			return editorErrorMsg{err}
 		}
-		screen := newVTScreen(width, height, nvim.file)
+		screen := newVTScreen(width, height, nvim.file, sb)
 		return editorStartedMsg{nvim: nvim, screen: screen, socket: socketPath}
 	}
 }
 
 // connectRPC dials the socket and returns the client via message.
 func (e Editor) connectRPC(program *tea.Program) tea.Cmd {
-	socketPath := e.socketPath
+	socketPath, onError := e.socketPath, e.onRPCError
 	return func() tea.Msg {
 		rpc, err := ConnectRPC(socketPath, func(mode NvimMode) {
 			if program != nil {
@@ -147,14 +242,18 @@ func (e Editor) connectRPC(program *tea.Program) tea.Cmd {
 			}
 		})
 		if err != nil {
+			if onError != nil {
+				onError(err)
+			}
 			return editorErrorMsg{err}
 		}
+		rpc.SetErrorHook(onError)
 		return rpcConnectedMsg{rpc: rpc}
 	}
 }
 
 // waitForOutput reads from the PTY and returns the output as a message.
-func waitForOutput(nvim *nvimPTY) tea.Cmd {
+func waitForOutput(nvim *ptyProcess) tea.Cmd {
 	return func() tea.Msg {
 		buf := make([]byte, 32*1024)
 		n, err := nvim.file.Read(buf)
@@ -178,10 +277,28 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 		debugf("WindowSizeMsg: %dx%d started=%v splash=%v rpc=%v", msg.Width, msg.Height, e.started, e.showSplash, e.rpc != nil)
 		e.width = msg.Width
 		e.height = msg.Height
+		if e.copy != nil {
+			e.copy.resize(e.width, e.height)
+		}
+		if e.readOnly {
+			e.started = true
+			e.viewer.setSize(e.width, e.height)
+			return e, nil
+		}
 		if !e.started {
 			e.started = true
+			if e.uiProtocol {
+				return e, e.startUI()
+			}
 			return e, e.start()
 		}
+		if e.grid != nil {
+			if err := e.rpc.ResizeUI(e.width, e.height); err != nil {
+				e.err = err
+				return e, tea.Quit
+			}
+			return e, nil
+		}
 		if e.nvim != nil {
 			if err := e.nvim.resize(e.width, e.height); err != nil {
 				e.err = err
@@ -196,7 +313,7 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 					return e, tea.Quit
 				}
 			}
-			e.screen = newVTScreen(e.width, e.height, e.nvim.file)
+			e.screen = newVTScreen(e.width, e.height, e.nvim.file, e.scrollback)
 
 			// Defensive: after some resize sequences terminals can end up with a blank
 			// frame until Neovim repaints. Force a redraw when dimensions change.
@@ -217,22 +334,30 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 		e.socketPath = msg.socket
 		return e, tea.Batch(waitForOutput(e.nvim), e.connectRPC(e.program))
 
+	case uiStartedMsg:
+		e.grid = msg.grid
+		rpc := msg.rpc
+		return e, tea.Batch(waitForUIExit(msg.done), func() tea.Msg { return rpcConnectedMsg{rpc: rpc} })
+
+	case uiRedrawMsg:
+		if e.grid == nil {
+			return e, nil
+		}
+		e.grid.redrawn()
+		if line := e.grid.statusLine(); line != e.statusLine {
+			e.statusLine = line
+			return e, func() tea.Msg { return line }
+		}
+		return e, nil
+
+	case uiClosedMsg:
+		debugf("uiClosedMsg: %v", msg.err)
+		return e, tea.Quit
+
 	case rpcConnectedMsg:
 		e.rpc = msg.rpc
 		if e.program != nil {
-			if err := e.rpc.SetupQuitSaveIntercept(e.program); err != nil {
-				e.err = err
-				return e, tea.Quit
-			}
-			if err := e.rpc.SetupSaveNotify(e.program); err != nil {
-				e.err = err
-				return e, tea.Quit
-			}
-			if err := e.rpc.SetupLinkNavigation(e.program); err != nil {
-				e.err = err
-				return e, tea.Quit
-			}
-			if err := e.rpc.SetupYankClipboard(e.program); err != nil {
+			if err := e.rpc.setupNotifications(e.program); err != nil {
 				e.err = err
 				return e, tea.Quit
 			}
@@ -284,13 +409,38 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 		return e, tea.Quit
 
 	case EditorMouseMsg:
-		if e.nvim == nil || e.showSplash {
+		if e.copy != nil {
+			switch msg.Button {
+			case tea.MouseButtonWheelUp:
+				e.copy.scroll(-3)
+			case tea.MouseButtonWheelDown:
+				e.copy.scroll(3)
+			}
+			return e, nil
+		}
+		if e.readOnly && !e.showSplash {
+			e.viewer.handleMouse(msg.MouseMsg)
+			return e, nil
+		}
+		if e.showSplash || (e.nvim == nil && e.grid == nil) {
 			return e, nil
 		}
 		// Track pressed button so we can encode releases correctly
 		if msg.Action == tea.MouseActionPress {
 			e.lastMouseButton = msg.Button
 		}
+		if e.grid != nil {
+			if button, action, modifier, ok := mouseMsgToInput(msg.MouseMsg, e.lastMouseButton); ok {
+				if err := e.rpc.InputMouse(button, action, modifier, msg.Row, msg.Col); err != nil {
+					e.err = err
+					return e, tea.Quit
+				}
+			}
+			if msg.Action == tea.MouseActionRelease {
+				e.lastMouseButton = tea.MouseButtonNone
+			}
+			return e, nil
+		}
 		raw := mouseMsgToBytes(msg.MouseMsg, msg.Col, msg.Row, e.lastMouseButton)
 		if raw != nil {
 			if _, err := e.nvim.file.Write(raw); err != nil {
@@ -304,7 +454,22 @@ func (e Editor) Update(msg tea.Msg) (Editor, tea.Cmd) {
 		return e, nil
 
 	case tea.KeyMsg:
-		if e.nvim == nil || e.showSplash {
+		if e.copy != nil {
+			return e, e.copyModeKey(msg)
+		}
+		if e.readOnly && !e.showSplash {
+			return e, e.viewer.handleKey(msg)
+		}
+		if e.showSplash || (e.nvim == nil && e.grid == nil) {
+			return e, nil
+		}
+		if e.grid != nil {
+			if keys := keyMsgToNotation(msg); keys != "" {
+				if err := e.rpc.Input(keys); err != nil {
+					e.err = err
+					return e, tea.Quit
+				}
+			}
 			return e, nil
 		}
 		raw := keyMsgToBytes(msg)
@@ -324,15 +489,40 @@ func (e Editor) View() string {
 	if e.err != nil {
 		return fmt.Sprintf("Editor error: %v", e.err)
 	}
-	if e.screen == nil {
+	if e.readOnly && !e.showSplash {
+		return e.viewer.view()
+	}
+	if e.screen == nil && e.grid == nil && !e.readOnly {
 		return "Starting Neovim..."
 	}
 	if e.showSplash {
 		return e.renderSplash()
 	}
+	if e.grid != nil {
+		return e.grid.render(e.focused)
+	}
+	if e.copy != nil {
+		return e.copy.view()
+	}
 	return e.screen.render()
 }
 
+// copyModeKey passes a key to copy mode. Leaving it yanks the selection,
+// if any, and restores the status bar's Neovim mode.
+func (e *Editor) copyModeKey(msg tea.KeyMsg) tea.Cmd {
+	text, done := e.copy.handleKey(msg)
+	if !done {
+		return nil
+	}
+	e.copy = nil
+	mode := e.mode
+	cmds := []tea.Cmd{func() tea.Msg { return ModeChangedMsg{Mode: mode} }}
+	if text != "" {
+		cmds = append(cmds, func() tea.Msg { return YankMsg{Text: text} })
+	}
+	return tea.Batch(cmds...)
+}
+
 func (e Editor) renderSplash() string {
 	th := e.theme
 	dim := lipgloss.NewStyle().Foreground(th.Dim)
@@ -349,6 +539,14 @@ func (e Editor) renderSplash() string {
 		{"Ctrl+h/l", "Navigate panels"},
 		{"Space q q", "Quit"},
 	}
+	if e.readOnly {
+		shortcuts = []struct{ key, desc string }{
+			{"Space Space", "Find note"},
+			{"Space f r", "Recent notes"},
+			{"Ctrl+h/l", "Navigate panels"},
+			{"Space q q", "Quit"},
+		}
+	}
 
 	// Find the widest key for right-alignment
 	maxKeyWidth := 0
@@ -380,6 +578,9 @@ func (e Editor) renderSplash() string {
 
 	// Title
 	title := accent.Render("Kopr")
+	if e.readOnly {
+		title += dim.Render(" (read-only)")
+	}
 	titlePad := (e.width - lipgloss.Width(title)) / 2
 	if titlePad < 0 {
 		titlePad = 0
@@ -435,6 +636,15 @@ func (e *Editor) SetFocused(focused bool) {
 }
 
 func (e *Editor) OpenFile(path string) error {
+	if e.readOnly {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		e.viewer.setContent(path, content)
+		e.showSplash = false
+		return nil
+	}
 	if e.rpc == nil {
 		return fmt.Errorf("RPC not connected")
 	}
@@ -442,6 +652,27 @@ func (e *Editor) OpenFile(path string) error {
 	return e.rpc.OpenFile(path)
 }
 
+// OpenFileReadOnly opens a file that must not be changed, such as a note
+// in an external source.
+func (e *Editor) OpenFileReadOnly(path string) error {
+	if err := e.OpenFile(path); err != nil || e.readOnly {
+		return err
+	}
+	return e.rpc.ExecCommand("setlocal readonly nomodifiable")
+}
+
+// GotoLine moves the cursor to the 1-based line, or in the read-only viewer
+// scrolls it to the top.
+func (e *Editor) GotoLine(line int) {
+	if e.readOnly {
+		e.viewer.scrollToLine(line)
+		return
+	}
+	if e.rpc != nil {
+		e.rpc.SetCursorPosition(line, 0) //nolint:errcheck // best-effort cursor jump
+	}
+}
+
 // applyColorscheme applies the configured colorscheme via RPC and returns a
 // command that extracts colors and sends ColorsReadyMsg to the app.
 func (e Editor) applyColorscheme() tea.Cmd {
@@ -450,7 +681,15 @@ func (e Editor) applyColorscheme() tea.Cmd {
 	}
 	rpc := e.rpc
 	cs := e.colorscheme
+	trueColor := !e.noTrueColor
 	return func() tea.Msg {
+		// The managed init.lua already reads KOPR_TRUECOLOR; a user config
+		// may force termguicolors on.
+		if !trueColor {
+			if err := rpc.ExecCommand("set notermguicolors"); err != nil {
+				debugf("set notermguicolors failed: %v", err)
+			}
+		}
 		if err := rpc.ApplyColorscheme(cs); err != nil {
 			debugf("apply colorscheme %q failed: %v", cs, err)
 			return ColorsReadyMsg{Err: fmt.Errorf("colorscheme %q: %w", cs, err)}
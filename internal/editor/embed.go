@@ -0,0 +1,94 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/neovim/go-client/nvim"
+)
+
+type uiStartedMsg struct {
+	rpc  *RPC
+	grid *uiGrid
+	done <-chan error
+}
+
+// uiRedrawMsg is sent when Neovim has flushed a redraw of the grid.
+type uiRedrawMsg struct{}
+
+type uiClosedMsg struct{ err error }
+
+// StatusLineMsg is sent with the UI protocol backend when Neovim's command
+// line or one-line message changes, for the app to show in its status bar.
+// All fields are empty when there is nothing to show.
+type StatusLineMsg struct {
+	Cmdline string // prompt and input while the command line is shown
+	Cursor  int    // cursor column within Cmdline
+	Message string
+	Error   bool // Message is an error
+}
+
+// startUI spawns `nvim --embed` and attaches to its UI protocol, the
+// alternative to running it in a PTY: Neovim sends the grid as cells and
+// highlights instead of escape sequences, and the command line and messages
+// as events that kopr draws itself.
+func (e Editor) startUI() tea.Cmd {
+	width, height, vaultPath, profileMode, tsParsers := e.width, e.height, e.vaultPath, e.profileMode, e.treesitterParsers
+	trueColor := !e.noTrueColor
+	program, onError := e.program, e.onRPCError
+	return func() tea.Msg {
+		if err := EnsureProfile(profileMode); err != nil {
+			return editorErrorMsg{fmt.Errorf("nvim profile: %w", err)}
+		}
+
+		client, err := nvim.NewChildProcess(
+			nvim.ChildProcessArgs("--embed"),
+			nvim.ChildProcessDir(vaultPath),
+			nvim.ChildProcessEnv(nvimProcessEnv(tsParsers, trueColor)),
+			nvim.ChildProcessServe(false),
+			nvim.ChildProcessLogf(debugf),
+		)
+		if err != nil {
+			return editorErrorMsg{fmt.Errorf("start nvim: %w", err)}
+		}
+		done := make(chan error, 1)
+		go func() { done <- client.Serve() }()
+
+		grid := newUIGrid(width, height, trueColor)
+		if err := client.RegisterHandler("redraw", func(updates ...[]interface{}) {
+			if grid.apply(updates) && program != nil {
+				grid.notify(program)
+			}
+		}); err != nil {
+			return editorErrorMsg{errors.Join(fmt.Errorf("register redraw: %w", err), client.Close())}
+		}
+
+		rpc, err := newRPC(client, func(mode NvimMode) {
+			if program != nil {
+				program.Send(ModeChangedMsg{Mode: mode})
+			}
+		})
+		if err != nil {
+			return editorErrorMsg{err}
+		}
+		rpc.SetErrorHook(onError)
+
+		if err := client.AttachUI(width, height, map[string]interface{}{
+			"rgb":          trueColor,
+			"ext_linegrid": true,
+			"ext_cmdline":  true,
+			"ext_messages": true,
+		}); err != nil {
+			return editorErrorMsg{errors.Join(fmt.Errorf("attach ui: %w", err), client.Close())}
+		}
+		return uiStartedMsg{rpc: rpc, grid: grid, done: done}
+	}
+}
+
+// waitForUIExit reports when the embedded Neovim's connection ends.
+func waitForUIExit(done <-chan error) tea.Cmd {
+	return func() tea.Msg {
+		return uiClosedMsg{err: <-done}
+	}
+}
@@ -0,0 +1,514 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// uiCell is one cell of Neovim's grid.
+type uiCell struct {
+	text string // "" for the right half of a double-width character
+	hl   int
+}
+
+// uiAttr is a highlight defined by hl_attr_define. Colors are -1 when unset,
+// leaving the terminal's default.
+type uiAttr struct {
+	fg, bg                                                     int
+	bold, italic, underline, undercurl, strikethrough, reverse bool
+}
+
+var noAttr = uiAttr{fg: -1, bg: -1}
+
+// uiCmdline is the command line while Neovim shows one (ext_cmdline).
+type uiCmdline struct {
+	visible bool
+	lead    string // firstc or prompt, and indent
+	content string
+	pos     int // cursor, as a byte offset into content
+}
+
+// maxMessageLines caps how much of the grid messages may cover.
+const maxMessageLines = 10
+
+// errorMessageKinds are the msg_show kinds that report an error.
+var errorMessageKinds = map[string]bool{
+	"emsg":      true,
+	"echoerr":   true,
+	"lua_error": true,
+	"rpc_error": true,
+}
+
+// uiGrid is the screen Neovim draws through its UI protocol
+// (nvim_ui_attach with ext_linegrid). Redraw batches from the RPC goroutine
+// update it under mu; the editor renders it in View. The command line and
+// messages are externalized (ext_cmdline, ext_messages): the command line
+// and one-line messages go to kopr's status bar (see statusLine), and longer
+// messages are drawn over the grid's bottom rows.
+type uiGrid struct {
+	mu        sync.Mutex
+	width     int
+	height    int
+	cells     [][]uiCell
+	attrs     map[int]uiAttr
+	defaults  uiAttr // Normal's colors, from default_colors_set
+	trueColor bool   // 24-bit colors; otherwise cterm's 256-color palette
+	cursorRow int
+	cursorCol int
+	busy      bool
+	cmdline   uiCmdline
+	messages  []string // lines of the messages shown
+	msgError  bool     // the last message shown was an error
+	showmode  string   // e.g. "recording @q"
+
+	// styles caches the escape sequence of each highlight; rendered caches
+	// the last render, for showCursor renderedCursor, until the next event.
+	styles         map[int]string
+	rendered       string
+	renderedCursor bool
+	renderedOK     bool
+
+	// pending is set while a redraw message is on its way to the program,
+	// so a burst of flushes sends one.
+	pending atomic.Bool
+}
+
+func newUIGrid(width, height int, trueColor bool) *uiGrid {
+	g := &uiGrid{
+		attrs:     map[int]uiAttr{},
+		styles:    map[int]string{},
+		defaults:  noAttr,
+		trueColor: trueColor,
+	}
+	g.resize(width, height)
+	return g
+}
+
+// notify sends one redraw message to program for any number of flushes
+// until the editor handles it.
+func (g *uiGrid) notify(program Sender) {
+	if program != nil && g.pending.CompareAndSwap(false, true) {
+		program.Send(uiRedrawMsg{})
+	}
+}
+
+// resize changes the grid's size, keeping what fits.
+func (g *uiGrid) resize(width, height int) {
+	cells := make([][]uiCell, height)
+	for r := range cells {
+		cells[r] = blankRow(width)
+		if r < len(g.cells) {
+			copy(cells[r], g.cells[r])
+		}
+	}
+	g.width, g.height, g.cells = width, height, cells
+	g.cursorRow = min(g.cursorRow, max(height-1, 0))
+	g.cursorCol = min(g.cursorCol, max(width-1, 0))
+}
+
+func blankRow(width int) []uiCell {
+	row := make([]uiCell, width)
+	for i := range row {
+		row[i] = uiCell{text: " "}
+	}
+	return row
+}
+
+// apply applies a batch of redraw events and reports whether it ended in
+// a flush, when the screen is consistent and worth drawing.
+func (g *uiGrid) apply(updates [][]interface{}) (flushed bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.renderedOK = false
+	for _, update := range updates {
+		if len(update) == 0 {
+			continue
+		}
+		name, _ := update[0].(string)
+		for _, a := range update[1:] {
+			args, _ := a.([]interface{})
+			if g.event(name, args) {
+				flushed = true
+			}
+		}
+	}
+	return flushed
+}
+
+// event applies one redraw event; it reports whether the event was flush.
+func (g *uiGrid) event(name string, args []interface{}) bool {
+	switch name {
+	case "flush":
+		return true
+	case "grid_resize":
+		if len(args) >= 3 {
+			g.resize(uiInt(args[1]), uiInt(args[2]))
+		}
+	case "default_colors_set":
+		if len(args) >= 5 {
+			if g.trueColor {
+				g.defaults = uiAttr{fg: uiInt(args[0]), bg: uiInt(args[1])}
+			} else {
+				// cterm defaults are 1-based, 0 meaning unset.
+				g.defaults = uiAttr{fg: uiInt(args[3]) - 1, bg: uiInt(args[4]) - 1}
+			}
+			clear(g.styles)
+		}
+	case "hl_attr_define":
+		if len(args) >= 3 {
+			spec := args[1]
+			if !g.trueColor {
+				spec = args[2]
+			}
+			m, _ := spec.(map[string]interface{})
+			g.attrs[uiInt(args[0])] = parseUIAttr(m)
+			delete(g.styles, uiInt(args[0]))
+		}
+	case "grid_line":
+		if len(args) >= 4 {
+			cells, _ := args[3].([]interface{})
+			g.line(uiInt(args[1]), uiInt(args[2]), cells)
+		}
+	case "grid_clear":
+		for r := range g.cells {
+			g.cells[r] = blankRow(g.width)
+		}
+	case "grid_cursor_goto":
+		if len(args) >= 3 {
+			g.cursorRow, g.cursorCol = uiInt(args[1]), uiInt(args[2])
+		}
+	case "grid_scroll":
+		if len(args) >= 7 {
+			g.scroll(uiInt(args[1]), uiInt(args[2]), uiInt(args[3]), uiInt(args[4]), uiInt(args[5]))
+		}
+	case "busy_start":
+		g.busy = true
+	case "busy_stop":
+		g.busy = false
+	case "cmdline_show":
+		if len(args) >= 6 {
+			g.cmdline = uiCmdline{
+				visible: true,
+				lead:    uiString(args[2]) + uiString(args[3]) + strings.Repeat(" ", uiInt(args[4])),
+				content: chunksText(args[0]),
+				pos:     uiInt(args[1]),
+			}
+		}
+	case "cmdline_pos":
+		if len(args) >= 1 {
+			g.cmdline.pos = uiInt(args[0])
+		}
+	case "cmdline_hide":
+		g.cmdline = uiCmdline{}
+	case "msg_show":
+		if len(args) >= 2 {
+			lines := strings.Split(chunksText(args[1]), "\n")
+			if replace, _ := uiArg(args, 2).(bool); replace && len(g.messages) > 0 {
+				g.messages = g.messages[:len(g.messages)-1]
+			}
+			g.messages = append(g.messages, lines...)
+			g.msgError = errorMessageKinds[uiString(args[0])]
+		}
+	case "msg_history_show":
+		if len(args) >= 1 {
+			entries, _ := args[0].([]interface{})
+			g.messages, g.msgError = nil, false
+			for _, e := range entries {
+				entry, _ := e.([]interface{})
+				if len(entry) >= 2 {
+					g.messages = append(g.messages, strings.Split(chunksText(entry[1]), "\n")...)
+				}
+			}
+		}
+	case "msg_clear":
+		g.messages, g.msgError = nil, false
+	case "msg_showmode":
+		if len(args) >= 1 {
+			g.showmode = chunksText(args[0])
+		}
+	}
+	return false
+}
+
+// line applies a grid_line event: cells are [text, hl_id, repeat] with the
+// highlight and repeat count left out when unchanged or 1.
+func (g *uiGrid) line(row, col int, cells []interface{}) {
+	if row < 0 || row >= g.height {
+		return
+	}
+	hl := 0
+	for _, c := range cells {
+		cell, _ := c.([]interface{})
+		if len(cell) == 0 {
+			continue
+		}
+		text := uiString(cell[0])
+		if len(cell) >= 2 {
+			hl = uiInt(cell[1])
+		}
+		repeat := 1
+		if len(cell) >= 3 {
+			repeat = uiInt(cell[2])
+		}
+		for range repeat {
+			if col >= 0 && col < g.width {
+				g.cells[row][col] = uiCell{text: text, hl: hl}
+			}
+			col++
+		}
+	}
+}
+
+// scroll applies a grid_scroll event: the region from rows top to bot and
+// columns left to right moves up by rows (down when negative). Neovim
+// redraws the rows uncovered.
+func (g *uiGrid) scroll(top, bot, left, right, rows int) {
+	bot, right = min(bot, g.height), min(right, g.width)
+	if top < 0 || left < 0 || top >= bot || left >= right {
+		return
+	}
+	if rows > 0 {
+		for r := top; r < bot-rows; r++ {
+			copy(g.cells[r][left:right], g.cells[r+rows][left:right])
+		}
+	} else if rows < 0 {
+		for r := bot - 1; r >= top-rows; r-- {
+			copy(g.cells[r][left:right], g.cells[r+rows][left:right])
+		}
+	}
+}
+
+// redrawn clears pending once the editor has handled a redraw message.
+func (g *uiGrid) redrawn() {
+	g.pending.Store(false)
+}
+
+// render draws the grid with messages longer than a line over its bottom
+// rows, and the cursor as a reverse-video cell when showCursor.
+func (g *uiGrid) render(showCursor bool) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.renderedOK && g.renderedCursor == showCursor {
+		return g.rendered
+	}
+
+	cursorRow, cursorCol := g.cursorRow, g.cursorCol
+	if !showCursor || g.busy || g.cmdline.visible {
+		cursorRow = -1
+	}
+	rows := make([]string, g.height)
+	for r := range g.height {
+		col := -1
+		if r == cursorRow {
+			col = cursorCol
+		}
+		rows[r] = g.renderRow(r, col)
+	}
+
+	// A message the status bar can't hold ends on the last row, cut to its
+	// latest lines.
+	if len(g.messages) > 1 {
+		n := min(len(g.messages), maxMessageLines, g.height)
+		for i, msg := range g.messages[len(g.messages)-n:] {
+			rows[g.height-n+i] = padLine(msg, g.width)
+		}
+	}
+	g.rendered, g.renderedCursor, g.renderedOK = strings.Join(rows, "\n"), showCursor, true
+	return g.rendered
+}
+
+// renderRow draws one row, marking the cell at cursorCol (if not -1).
+func (g *uiGrid) renderRow(row, cursorCol int) string {
+	var b strings.Builder
+	b.Grow(g.width * 2)
+	current, styled := "", false
+	for col, cell := range g.cells[row] {
+		if cell.text == "" {
+			continue
+		}
+		sgr, ok := g.styles[cell.hl]
+		if col == cursorCol {
+			sgr = g.sgr(g.attr(cell.hl), true)
+		} else if !ok {
+			sgr = g.sgr(g.attr(cell.hl), false)
+			g.styles[cell.hl] = sgr
+		}
+		if sgr != current {
+			if styled {
+				b.WriteString("\x1b[0m")
+			}
+			b.WriteString(sgr)
+			current, styled = sgr, sgr != ""
+		}
+		b.WriteString(cell.text)
+	}
+	if styled {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// statusLine returns what goes to the status bar: the command line while
+// Neovim shows one, and a one-line message (or the showmode text, such as
+// "recording @q") otherwise.
+func (g *uiGrid) statusLine() StatusLineMsg {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if c := g.cmdline; c.visible {
+		pos := min(max(c.pos, 0), len(c.content))
+		return StatusLineMsg{
+			Cmdline: strings.ReplaceAll(c.lead+c.content, "\t", "    "),
+			Cursor:  ansi.StringWidth(c.lead) + ansi.StringWidth(strings.ReplaceAll(c.content[:pos], "\t", "    ")),
+		}
+	}
+	switch len(g.messages) {
+	case 0:
+		return StatusLineMsg{Message: g.showmode}
+	case 1:
+		return StatusLineMsg{Message: g.messages[0], Error: g.msgError}
+	}
+	return StatusLineMsg{}
+}
+
+// padLine cuts or pads s to width cells.
+func padLine(s string, width int) string {
+	s = ansi.Truncate(strings.ReplaceAll(s, "\t", "    "), width, "…")
+	return s + strings.Repeat(" ", max(width-ansi.StringWidth(s), 0))
+}
+
+// attr returns highlight hl, or no attributes for one not defined.
+func (g *uiGrid) attr(hl int) uiAttr {
+	if attr, ok := g.attrs[hl]; ok {
+		return attr
+	}
+	return noAttr
+}
+
+// sgr returns the escape sequence that selects attr, reversed for the
+// cursor cell, or "" for the terminal's defaults.
+func (g *uiGrid) sgr(attr uiAttr, cursor bool) string {
+	fg, bg := attr.fg, attr.bg
+	if fg < 0 {
+		fg = g.defaults.fg
+	}
+	if bg < 0 {
+		bg = g.defaults.bg
+	}
+	var params []string
+	if attr.bold {
+		params = append(params, "1")
+	}
+	if attr.italic {
+		params = append(params, "3")
+	}
+	switch {
+	case attr.undercurl:
+		params = append(params, "4:3")
+	case attr.underline:
+		params = append(params, "4")
+	}
+	if attr.reverse != cursor {
+		params = append(params, "7")
+	}
+	if attr.strikethrough {
+		params = append(params, "9")
+	}
+	if fg >= 0 {
+		params = append(params, g.color(38, fg))
+	}
+	if bg >= 0 {
+		params = append(params, g.color(48, bg))
+	}
+	if len(params) == 0 {
+		return ""
+	}
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// color is the SGR parameter setting the foreground (38) or background (48)
+// to c, a 24-bit RGB value or a 256-color palette index.
+func (g *uiGrid) color(base, c int) string {
+	if g.trueColor {
+		return fmt.Sprintf("%d;2;%d;%d;%d", base, c>>16&0xff, c>>8&0xff, c&0xff)
+	}
+	return fmt.Sprintf("%d;5;%d", base, c)
+}
+
+func parseUIAttr(m map[string]interface{}) uiAttr {
+	attr := noAttr
+	if v, ok := m["foreground"]; ok {
+		attr.fg = uiInt(v)
+	}
+	if v, ok := m["background"]; ok {
+		attr.bg = uiInt(v)
+	}
+	attr.bold, _ = m["bold"].(bool)
+	attr.italic, _ = m["italic"].(bool)
+	attr.underline, _ = m["underline"].(bool)
+	attr.undercurl, _ = m["undercurl"].(bool)
+	attr.strikethrough, _ = m["strikethrough"].(bool)
+	attr.reverse, _ = m["reverse"].(bool)
+	return attr
+}
+
+// chunksText joins the text of message or command line chunks, each
+// [attr, text, ...].
+func chunksText(v interface{}) string {
+	chunks, _ := v.([]interface{})
+	var b strings.Builder
+	for _, c := range chunks {
+		chunk, _ := c.([]interface{})
+		if len(chunk) >= 2 {
+			b.WriteString(uiString(chunk[1]))
+		}
+	}
+	return b.String()
+}
+
+func uiArg(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+func uiString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// uiInt converts a msgpack-decoded number.
+func uiInt(v interface{}) int {
+	switch n := v.(type) {
+	case int64:
+		return int(n)
+	case uint64:
+		return int(n)
+	case int:
+		return n
+	case int32:
+		return int(n)
+	case uint32:
+		return int(n)
+	case int8:
+		return int(n)
+	case uint8:
+		return int(n)
+	case int16:
+		return int(n)
+	case uint16:
+		return int(n)
+	case float64:
+		return int(n)
+	}
+	return 0
+}
@@ -0,0 +1,183 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+)
+
+// ev builds a redraw update: the event name followed by one argument list
+// per call.
+func ev(name string, calls ...[]interface{}) []interface{} {
+	update := []interface{}{name}
+	for _, c := range calls {
+		update = append(update, c)
+	}
+	return update
+}
+
+func args(a ...interface{}) []interface{} { return a }
+
+// cells builds grid_line cells from text, one cell per rune, all in hl.
+func cells(text string, hl int) []interface{} {
+	var out []interface{}
+	for i, r := range text {
+		if i == 0 {
+			out = append(out, args(string(r), int64(hl)))
+		} else {
+			out = append(out, args(string(r)))
+		}
+	}
+	return out
+}
+
+func plainRows(g *uiGrid, showCursor bool) []string {
+	return strings.Split(ansi.Strip(g.render(showCursor)), "\n")
+}
+
+func TestUIGridLines(t *testing.T) {
+	g := newUIGrid(10, 3, true)
+	flushed := g.apply([][]interface{}{
+		ev("grid_resize", args(int64(1), int64(8), int64(3))),
+		ev("hl_attr_define", args(int64(1), map[string]interface{}{"foreground": int64(0xff0000), "bold": true}, map[string]interface{}{}, args())),
+		ev("grid_line",
+			args(int64(1), int64(0), int64(0), cells("# Title", 1)),
+			// "-" repeated four times, then the default highlight.
+			args(int64(1), int64(1), int64(0), args(args("-", int64(0), int64(4)), args("x"))),
+		),
+		ev("grid_cursor_goto", args(int64(1), int64(1), int64(2))),
+	})
+	if flushed {
+		t.Error("apply reported a flush without one")
+	}
+	if !g.apply([][]interface{}{ev("flush", args())}) {
+		t.Error("apply missed the flush")
+	}
+
+	rows := plainRows(g, false)
+	if want := []string{"# Title ", "----x   ", "        "}; strings.Join(rows, "|") != strings.Join(want, "|") {
+		t.Errorf("rows = %q, want %q", rows, want)
+	}
+	out := g.render(false)
+	if !strings.Contains(out, "\x1b[1;38;2;255;0;0m# Title") {
+		t.Errorf("title not bold red: %q", out)
+	}
+	if strings.Contains(out, "\x1b[7m") {
+		t.Errorf("cursor drawn while hidden: %q", out)
+	}
+	if out := g.render(true); !strings.Contains(out, "--\x1b[7m-") {
+		t.Errorf("cursor not at row 1, col 2: %q", out)
+	}
+}
+
+func TestUIGridScroll(t *testing.T) {
+	g := newUIGrid(3, 4, true)
+	g.apply([][]interface{}{ev("grid_line",
+		args(int64(1), int64(0), int64(0), cells("aaa", 0)),
+		args(int64(1), int64(1), int64(0), cells("bbb", 0)),
+		args(int64(1), int64(2), int64(0), cells("ccc", 0)),
+		args(int64(1), int64(3), int64(0), cells("ddd", 0)),
+	)})
+
+	// Scroll rows 0-2 up by one: b and c move up, row 2 keeps c until
+	// Neovim redraws it.
+	g.apply([][]interface{}{ev("grid_scroll", args(int64(1), int64(0), int64(3), int64(0), int64(3), int64(1), int64(0)))})
+	if got := strings.Join(plainRows(g, false), "|"); got != "bbb|ccc|ccc|ddd" {
+		t.Errorf("after scroll up = %q", got)
+	}
+	g.apply([][]interface{}{ev("grid_scroll", args(int64(1), int64(1), int64(4), int64(0), int64(3), int64(-2), int64(0)))})
+	if got := strings.Join(plainRows(g, false), "|"); got != "bbb|ccc|ccc|ccc" {
+		t.Errorf("after scroll down = %q", got)
+	}
+}
+
+func TestUIGridCmdlineAndMessages(t *testing.T) {
+	g := newUIGrid(20, 4, true)
+
+	// A one-line message goes to the status bar, not over the grid.
+	g.apply([][]interface{}{ev("msg_show", args("emsg", args(args(int64(0), "E492: Not an editor command")), false))})
+	if got, want := g.statusLine(), (StatusLineMsg{Message: "E492: Not an editor command", Error: true}); got != want {
+		t.Errorf("statusLine = %+v, want %+v", got, want)
+	}
+	if got := strings.TrimSpace(strings.Join(plainRows(g, false), "")); got != "" {
+		t.Errorf("one-line message drawn over the grid: %q", got)
+	}
+	g.apply([][]interface{}{ev("msg_show", args("", args(args(int64(0), "written")), true))})
+	if got, want := g.statusLine(), (StatusLineMsg{Message: "written"}); got != want {
+		t.Errorf("after replace_last statusLine = %+v, want %+v", got, want)
+	}
+
+	// Longer ones are drawn over the bottom rows instead.
+	g.apply([][]interface{}{
+		ev("msg_clear", args()),
+		ev("msg_show", args("echo", args(args(int64(0), "first\nsecond")), false)),
+		ev("msg_show", args("echo", args(args(int64(0), "third")), false)),
+	})
+	if got := strings.Join(plainRows(g, false), "|"); !strings.HasSuffix(got, "|first               |second              |third               ") {
+		t.Errorf("messages not at the bottom: %q", got)
+	}
+	if got := g.statusLine(); got != (StatusLineMsg{}) {
+		t.Errorf("statusLine with a long message = %+v", got)
+	}
+
+	g.apply([][]interface{}{
+		ev("msg_clear", args()),
+		ev("cmdline_show", args(args(args(map[string]interface{}{}, "edit note")), int64(4), ":", "", int64(0), int64(1))),
+	})
+	if got, want := g.statusLine(), (StatusLineMsg{Cmdline: ":edit note", Cursor: 5}); got != want {
+		t.Errorf("cmdline statusLine = %+v, want %+v", got, want)
+	}
+	if out := g.render(true); strings.Contains(out, "\x1b[7m") || strings.TrimSpace(ansi.Strip(out)) != "" {
+		t.Errorf("grid drawn with the cmdline or its cursor: %q", out)
+	}
+
+	g.apply([][]interface{}{ev("cmdline_hide", args(int64(1)))})
+	if got := g.statusLine(); got != (StatusLineMsg{}) {
+		t.Errorf("statusLine after cmdline_hide = %+v", got)
+	}
+	g.apply([][]interface{}{ev("msg_showmode", args(args(args(int64(0), "recording @q"))))})
+	if got := g.statusLine(); got.Message != "recording @q" {
+		t.Errorf("showmode statusLine = %+v", got)
+	}
+}
+
+func TestUIGridCtermColors(t *testing.T) {
+	g := newUIGrid(4, 1, false)
+	g.apply([][]interface{}{
+		// cterm defaults are 1-based: 0 leaves the background unset.
+		ev("default_colors_set", args(int64(-1), int64(-1), int64(-1), int64(8), int64(0))),
+		ev("hl_attr_define", args(int64(2), map[string]interface{}{"foreground": int64(0x00ff00)}, map[string]interface{}{"foreground": int64(2), "reverse": true}, args())),
+		ev("grid_line", args(int64(1), int64(0), int64(0), args(args("a", int64(0)), args("b", int64(2))))),
+	})
+	out := g.render(false)
+	if !strings.Contains(out, "\x1b[38;5;7ma") {
+		t.Errorf("default foreground not palette color 7: %q", out)
+	}
+	if !strings.Contains(out, "\x1b[7;38;5;2mb") {
+		t.Errorf("highlight 2 not reversed palette color 2: %q", out)
+	}
+}
+
+// BenchmarkUIGridRender draws a full editor pane from the UI protocol grid,
+// for comparison with BenchmarkVTRender. The cached frame is dropped each
+// time, as after every redraw.
+func BenchmarkUIGridRender(b *testing.B) {
+	const width, height = 180, 60
+	g := newUIGrid(width, height, true)
+	var updates [][]interface{}
+	for row := range height {
+		hl := int64(row + 1)
+		updates = append(updates,
+			ev("hl_attr_define", args(hl, map[string]interface{}{"foreground": int64(row * 3 % 256 << 16)}, map[string]interface{}{}, args())),
+			ev("grid_line", args(int64(1), int64(row), int64(0), cells(strings.Repeat("lorem ipsum ", width/12), int(hl)))),
+		)
+	}
+	g.apply(updates)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		g.renderedOK = false
+		g.render(true)
+	}
+}
@@ -0,0 +1,44 @@
+//go:build integration
+
+package editor
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/neovim/go-client/nvim"
+)
+
+// StartHeadless starts `nvim --headless --embed` in vaultPath and returns an
+// Editor connected to it over the child's stdin and stdout, for end-to-end
+// tests of app flows. There is no PTY or VT screen, so nothing is drawn;
+// notifications that would go to the Bubble Tea program (save-as, follow
+// link, buffer written, ...) go to send instead.
+//
+// Neovim runs with --clean, so the tests don't depend on the kopr profile
+// or the user's config. Close the editor to stop it.
+func StartHeadless(vaultPath string, send Sender) (Editor, error) {
+	e := New(vaultPath, ProfileUser, "", false, "")
+	e.started = true
+	e.showSplash = false
+
+	client, err := nvim.NewChildProcess(
+		nvim.ChildProcessArgs("--headless", "--embed", "--clean", "-n"),
+		nvim.ChildProcessDir(vaultPath),
+		nvim.ChildProcessEnv(os.Environ()),
+		nvim.ChildProcessLogf(func(string, ...interface{}) {}),
+	)
+	if err != nil {
+		return e, fmt.Errorf("start headless nvim: %w", err)
+	}
+	rpc, err := newRPC(client, func(mode NvimMode) { send.Send(ModeChangedMsg{Mode: mode}) })
+	if err != nil {
+		return e, err
+	}
+	e.rpc = rpc
+	if err := rpc.setupNotifications(send); err != nil {
+		e.Close()
+		return e, fmt.Errorf("setup notifications: %w", err)
+	}
+	return e, nil
+}
@@ -1,6 +1,10 @@
 package editor
 
-import tea "github.com/charmbracelet/bubbletea"
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
 
 // keyMsgToBytes converts a Bubble Tea key message back to raw terminal
 // escape sequences suitable for writing to a PTY.
@@ -130,3 +134,97 @@ func keyMsgToBytes(msg tea.KeyMsg) []byte {
 
 	return nil
 }
+
+// keyNames maps keys without a printable form to their names in Neovim's
+// key notation.
+var keyNames = map[tea.KeyType]string{
+	tea.KeyEnter:      "CR",
+	tea.KeyBackspace:  "BS",
+	tea.KeyTab:        "Tab",
+	tea.KeyEsc:        "Esc",
+	tea.KeySpace:      "Space",
+	tea.KeyUp:         "Up",
+	tea.KeyDown:       "Down",
+	tea.KeyRight:      "Right",
+	tea.KeyLeft:       "Left",
+	tea.KeyHome:       "Home",
+	tea.KeyEnd:        "End",
+	tea.KeyPgUp:       "PageUp",
+	tea.KeyPgDown:     "PageDown",
+	tea.KeyDelete:     "Del",
+	tea.KeyInsert:     "Insert",
+	tea.KeyShiftTab:   "S-Tab",
+	tea.KeyCtrlUp:     "C-Up",
+	tea.KeyCtrlDown:   "C-Down",
+	tea.KeyCtrlRight:  "C-Right",
+	tea.KeyCtrlLeft:   "C-Left",
+	tea.KeyShiftUp:    "S-Up",
+	tea.KeyShiftDown:  "S-Down",
+	tea.KeyShiftRight: "S-Right",
+	tea.KeyShiftLeft:  "S-Left",
+	tea.KeyF1:         "F1",
+	tea.KeyF2:         "F2",
+	tea.KeyF3:         "F3",
+	tea.KeyF4:         "F4",
+	tea.KeyF5:         "F5",
+	tea.KeyF6:         "F6",
+	tea.KeyF7:         "F7",
+	tea.KeyF8:         "F8",
+	tea.KeyF9:         "F9",
+	tea.KeyF10:        "F10",
+	tea.KeyF11:        "F11",
+	tea.KeyF12:        "F12",
+}
+
+// keyMsgToNotation converts a Bubble Tea key message to Neovim's key
+// notation for nvim_input, used by the UI protocol backend.
+func keyMsgToNotation(msg tea.KeyMsg) string {
+	mod := ""
+	if msg.Alt {
+		mod = "M-"
+	}
+	if msg.Type == tea.KeyRunes {
+		var b strings.Builder
+		for _, r := range msg.Runes {
+			switch {
+			case mod != "":
+				b.WriteString("<" + mod + runeNotation(r) + ">")
+			case r == '<':
+				b.WriteString("<lt>")
+			default:
+				b.WriteRune(r)
+			}
+		}
+		return b.String()
+	}
+	if name, ok := keyNames[msg.Type]; ok {
+		return "<" + mod + name + ">"
+	}
+
+	// Ctrl+key: C0 control codes (0-31)
+	t := int(msg.Type)
+	switch {
+	case t == 0:
+		return "<" + mod + "C-@>"
+	case t >= 1 && t <= 26:
+		return "<" + mod + "C-" + string(rune('a'+t-1)) + ">"
+	case t >= 28 && t <= 31:
+		return "<" + mod + "C-" + runeNotation(rune('@'+t)) + ">"
+	}
+	return ""
+}
+
+// runeNotation spells r for use inside <...> key notation.
+func runeNotation(r rune) string {
+	switch r {
+	case '<':
+		return "lt"
+	case '>':
+		return "gt"
+	case '\\':
+		return "Bslash"
+	case '|':
+		return "Bar"
+	}
+	return string(r)
+}
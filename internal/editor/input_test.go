@@ -0,0 +1,34 @@
+package editor
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestKeyMsgToNotation(t *testing.T) {
+	tests := []struct {
+		msg  tea.KeyMsg
+		want string
+	}{
+		{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("dd")}, "dd"},
+		{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("a<b")}, "a<lt>b"},
+		{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("x"), Alt: true}, "<M-x>"},
+		{tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("<"), Alt: true}, "<M-lt>"},
+		{tea.KeyMsg{Type: tea.KeyEnter}, "<CR>"},
+		{tea.KeyMsg{Type: tea.KeyEsc}, "<Esc>"},
+		{tea.KeyMsg{Type: tea.KeySpace, Runes: []rune(" ")}, "<Space>"},
+		{tea.KeyMsg{Type: tea.KeyShiftTab}, "<S-Tab>"},
+		{tea.KeyMsg{Type: tea.KeyUp, Alt: true}, "<M-Up>"},
+		{tea.KeyMsg{Type: tea.KeyF5}, "<F5>"},
+		{tea.KeyMsg{Type: tea.KeyCtrlW}, "<C-w>"},
+		{tea.KeyMsg{Type: tea.KeyCtrlBackslash}, "<C-Bslash>"},
+		{tea.KeyMsg{Type: tea.KeyCtrlCloseBracket}, "<C-]>"},
+		{tea.KeyMsg{Type: tea.KeyCtrlAt}, "<C-@>"},
+	}
+	for _, tt := range tests {
+		if got := keyMsgToNotation(tt.msg); got != tt.want {
+			t.Errorf("keyMsgToNotation(%q) = %q, want %q", tt.msg.String(), got, tt.want)
+		}
+	}
+}
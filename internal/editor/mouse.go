@@ -80,3 +80,58 @@ func mouseMsgToBytes(msg tea.MouseMsg, col, row int, lastButton tea.MouseButton)
 
 	return fmt.Appendf(nil, "\x1b[<%d;%d;%d%c", button, sgrCol, sgrRow, suffix)
 }
+
+// mouseMsgToInput converts a Bubble Tea mouse message to the button, action
+// and modifier arguments of nvim_input_mouse, for the UI protocol backend.
+// ok is false for events Neovim has no use for, such as motion with no
+// button held.
+func mouseMsgToInput(msg tea.MouseMsg, lastButton tea.MouseButton) (button, action, modifier string, ok bool) {
+	if msg.Shift {
+		modifier += "S"
+	}
+	if msg.Ctrl {
+		modifier += "C"
+	}
+	if msg.Alt {
+		modifier += "A"
+	}
+
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		return "wheel", "up", modifier, true
+	case tea.MouseButtonWheelDown:
+		return "wheel", "down", modifier, true
+	case tea.MouseButtonWheelLeft:
+		return "wheel", "left", modifier, true
+	case tea.MouseButtonWheelRight:
+		return "wheel", "right", modifier, true
+	}
+
+	b := msg.Button
+	if msg.Action == tea.MouseActionRelease || b == tea.MouseButtonNone {
+		// On release, Bubble Tea reports MouseButtonNone.
+		b = lastButton
+	}
+	switch b {
+	case tea.MouseButtonLeft:
+		button = "left"
+	case tea.MouseButtonMiddle:
+		button = "middle"
+	case tea.MouseButtonRight:
+		button = "right"
+	default:
+		return "", "", "", false
+	}
+
+	switch msg.Action {
+	case tea.MouseActionPress:
+		action = "press"
+	case tea.MouseActionMotion:
+		action = "drag"
+	case tea.MouseActionRelease:
+		action = "release"
+	default:
+		return "", "", "", false
+	}
+	return button, action, modifier, true
+}
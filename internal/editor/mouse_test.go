@@ -115,3 +115,31 @@ func TestMouseMsgToBytes(t *testing.T) {
 		})
 	}
 }
+
+func TestMouseMsgToInput(t *testing.T) {
+	tests := []struct {
+		name       string
+		msg        tea.MouseMsg
+		lastButton tea.MouseButton
+		want       string // button/action/modifier, or "" when dropped
+	}{
+		{"left press", tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonLeft}, tea.MouseButtonNone, "left/press/"},
+		{"release uses last button", tea.MouseMsg{Action: tea.MouseActionRelease, Button: tea.MouseButtonNone}, tea.MouseButtonRight, "right/release/"},
+		{"drag", tea.MouseMsg{Action: tea.MouseActionMotion, Button: tea.MouseButtonLeft}, tea.MouseButtonLeft, "left/drag/"},
+		{"wheel with ctrl", tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonWheelDown, Ctrl: true}, tea.MouseButtonNone, "wheel/down/C"},
+		{"shift alt middle", tea.MouseMsg{Action: tea.MouseActionPress, Button: tea.MouseButtonMiddle, Shift: true, Alt: true}, tea.MouseButtonNone, "middle/press/SA"},
+		{"motion without button", tea.MouseMsg{Action: tea.MouseActionMotion, Button: tea.MouseButtonNone}, tea.MouseButtonNone, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			button, action, modifier, ok := mouseMsgToInput(tt.msg, tt.lastButton)
+			got := ""
+			if ok {
+				got = button + "/" + action + "/" + modifier
+			}
+			if got != tt.want {
+				t.Errorf("mouseMsgToInput = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
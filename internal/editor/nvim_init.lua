@@ -40,7 +40,8 @@ vim.opt.undofile = true
 -- Colors
 -- Kopr applies the configured colorscheme via RPC on connect.
 -- This is a fallback for the brief moment before RPC is ready.
-vim.opt.termguicolors = true
+-- KOPR_TRUECOLOR=0 is set for SSH clients limited to 256 colors.
+vim.opt.termguicolors = os.getenv("KOPR_TRUECOLOR") ~= "0"
 pcall(vim.cmd, "colorscheme no-clown-fiesta")
 
 -- Disable unused built-in plugins
@@ -4,27 +4,26 @@ import (
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"syscall"
 
 	"github.com/creack/pty"
 )
 
-type nvimPTY struct {
+// ptyProcess is a program running in a pseudo-terminal: Neovim, or the
+// shell in the terminal pane.
+type ptyProcess struct {
 	cmd    *exec.Cmd
 	file   *os.File
-	socket string
+	socket string // Neovim's RPC socket, removed on close
 }
 
-func startNvim(width, height int, socketPath, vaultPath, treesitterParsers string) (*nvimPTY, error) {
+func startNvim(width, height int, socketPath, vaultPath, treesitterParsers string, trueColor bool) (*ptyProcess, error) {
 	cmd := exec.Command("nvim",
 		"--listen", socketPath,
 	)
 	cmd.Dir = vaultPath
-	env := append(os.Environ(), NvimEnv()...)
-	if treesitterParsers != "" {
-		env = append(env, "KOPR_TREESITTER_PARSERS="+treesitterParsers)
-	}
-	cmd.Env = env
+	cmd.Env = nvimProcessEnv(treesitterParsers, trueColor)
 
 	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{
 		Rows: uint16(height),
@@ -34,14 +33,46 @@ func startNvim(width, height int, socketPath, vaultPath, treesitterParsers strin
 		return nil, fmt.Errorf("start nvim: %w", err)
 	}
 
-	return &nvimPTY{
+	return &ptyProcess{
 		cmd:    cmd,
 		file:   ptmx,
 		socket: socketPath,
 	}, nil
 }
 
-func (n *nvimPTY) resize(width, height int) error {
+// startShell runs the user's $SHELL, or /bin/sh, in dir.
+func startShell(width, height int, dir string) (*ptyProcess, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		shell = "/bin/sh"
+	}
+	cmd := exec.Command(shell)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "TERM=xterm-256color", "COLORTERM=truecolor")
+
+	ptmx, err := pty.StartWithSize(cmd, &pty.Winsize{
+		Rows: uint16(height),
+		Cols: uint16(width),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start %s: %w", shell, err)
+	}
+	return &ptyProcess{cmd: cmd, file: ptmx}, nil
+}
+
+// nvimProcessEnv is the environment kopr starts Neovim with.
+func nvimProcessEnv(treesitterParsers string, trueColor bool) []string {
+	env := append(os.Environ(), NvimEnv()...)
+	if treesitterParsers != "" {
+		env = append(env, "KOPR_TREESITTER_PARSERS="+treesitterParsers)
+	}
+	if !trueColor {
+		env = append(env, "COLORTERM=", "KOPR_TRUECOLOR=0")
+	}
+	return env
+}
+
+func (n *ptyProcess) resize(width, height int) error {
 	if err := pty.Setsize(n.file, &pty.Winsize{
 		Rows: uint16(height),
 		Cols: uint16(width),
@@ -55,7 +86,7 @@ func (n *nvimPTY) resize(width, height int) error {
 		if err := syscall.Kill(n.cmd.Process.Pid, syscall.SIGWINCH); err != nil {
 			// If the process is already gone, there's nothing to signal.
 			if err != syscall.ESRCH {
-				return fmt.Errorf("sigwinch nvim: %w", err)
+				return fmt.Errorf("sigwinch %s: %w", filepath.Base(n.cmd.Path), err)
 			}
 		}
 	}
@@ -63,11 +94,14 @@ func (n *nvimPTY) resize(width, height int) error {
 	return nil
 }
 
-func (n *nvimPTY) close() error {
+func (n *ptyProcess) close() error {
 	if err := n.file.Close(); err != nil {
 		return err
 	}
 	err := n.cmd.Wait()
+	if n.socket == "" {
+		return err
+	}
 	if rmErr := os.Remove(n.socket); rmErr != nil && !os.IsNotExist(rmErr) {
 		return rmErr
 	}
@@ -8,6 +8,7 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/neovim/go-client/nvim"
+	"github.com/pfassina/kopr/internal/markdown"
 )
 
 // NvimMode represents Neovim's current mode.
@@ -24,12 +25,20 @@ const (
 	ModeTermnl  NvimMode = "t"
 )
 
+// Sender delivers messages from Neovim to the app. *tea.Program is the
+// usual one.
+type Sender interface {
+	Send(msg tea.Msg)
+}
+
 // RPC manages the Neovim RPC connection.
 type RPC struct {
 	client *nvim.Nvim
 	mu     sync.RWMutex
 	mode   NvimMode
 	onMode func(NvimMode) // callback when mode changes
+
+	onError func(error) // called for every failed buffer/command call
 }
 
 // ConnectRPC dials the Neovim socket and sets up event subscriptions.
@@ -49,6 +58,12 @@ func ConnectRPC(socketPath string, onMode func(NvimMode)) (*RPC, error) {
 		return nil, fmt.Errorf("connect to nvim socket: %w", err)
 	}
 
+	return newRPC(client, onMode)
+}
+
+// newRPC wraps a connected client and subscribes to mode changes. The
+// client is closed if that fails.
+func newRPC(client *nvim.Nvim, onMode func(NvimMode)) (*RPC, error) {
 	rpc := &RPC{
 		client: client,
 		mode:   ModeNormal,
@@ -62,6 +77,20 @@ func ConnectRPC(socketPath string, onMode func(NvimMode)) (*RPC, error) {
 	return rpc, nil
 }
 
+// SetErrorHook registers fn to be called with every error returned by the
+// buffer and command helpers, e.g. to count them for serve-mode metrics.
+func (r *RPC) SetErrorHook(fn func(error)) {
+	r.onError = fn
+}
+
+// check reports err to the error hook and returns it unchanged.
+func (r *RPC) check(err error) error {
+	if err != nil && r.onError != nil {
+		r.onError(err)
+	}
+	return err
+}
+
 func (r *RPC) setupModeChanged() error {
 	if err := r.client.RegisterHandler("mode_changed", func(args ...interface{}) {
 		if len(args) < 2 {
@@ -106,35 +135,37 @@ func (r *RPC) Mode() NvimMode {
 
 // OpenFile opens a file in Neovim.
 func (r *RPC) OpenFile(path string) error {
-	return r.client.ExecLua("vim.cmd('edit ' .. vim.fn.fnameescape(...))", nil, path)
+	return r.check(r.client.ExecLua("vim.cmd('edit ' .. vim.fn.fnameescape(...))", nil, path))
 }
 
 // CurrentFile returns the current buffer's file path.
 func (r *RPC) CurrentFile() (string, error) {
 	buf, err := r.client.CurrentBuffer()
 	if err != nil {
-		return "", err
+		return "", r.check(err)
 	}
-	return r.client.BufferName(buf)
+	name, err := r.client.BufferName(buf)
+	return name, r.check(err)
 }
 
 // BufferContent returns all lines of the current buffer.
 func (r *RPC) BufferContent() ([][]byte, error) {
 	buf, err := r.client.CurrentBuffer()
 	if err != nil {
-		return nil, err
+		return nil, r.check(err)
 	}
-	return r.client.BufferLines(buf, 0, -1, false)
+	lines, err := r.client.BufferLines(buf, 0, -1, false)
+	return lines, r.check(err)
 }
 
 // ExecCommand runs an Ex command in Neovim.
 func (r *RPC) ExecCommand(cmd string) error {
-	return r.client.Command(cmd)
+	return r.check(r.client.Command(cmd))
 }
 
 // ExecLua runs Lua code in Neovim.
 func (r *RPC) ExecLua(code string, result interface{}, args ...interface{}) error {
-	return r.client.ExecLua(code, result, args...)
+	return r.check(r.client.ExecLua(code, result, args...))
 }
 
 // FormatBuffer formats the current buffer using Neovim's built-in formatter.
@@ -148,9 +179,53 @@ func (r *RPC) InsertText(text string) error {
 	return err
 }
 
+// Input sends keys to Neovim as if typed, in the notation of nvim_input
+// ("<CR>", "<C-w>"). Unlike ExecCommand it goes through mappings and
+// abbreviations.
+func (r *RPC) Input(keys string) error {
+	_, err := r.client.Input(keys)
+	return r.check(err)
+}
+
+// InputMouse sends a mouse event at a 0-based grid position, for the UI
+// protocol backend. See nvim_input_mouse for button, action and modifier.
+func (r *RPC) InputMouse(button, action, modifier string, row, col int) error {
+	return r.check(r.client.InputMouse(button, action, modifier, 0, row, col))
+}
+
+// ResizeUI tells Neovim the attached UI's grid is now width x height.
+func (r *RPC) ResizeUI(width, height int) error {
+	return r.check(r.client.TryResizeUI(width, height))
+}
+
+// setupNotifications installs the autocmds and keymaps that report editor
+// events (closing and saving notes, following links, yanks, cursor moves,
+// files changed on disk) to program.
+func (r *RPC) setupNotifications(program Sender) error {
+	if err := r.SetupQuitSaveIntercept(program); err != nil {
+		return err
+	}
+	if err := r.SetupSaveNotify(program); err != nil {
+		return err
+	}
+	if err := r.SetupLinkNavigation(program); err != nil {
+		return err
+	}
+	if err := r.SetupYankClipboard(program); err != nil {
+		return err
+	}
+	if err := r.SetupExternalChange(program); err != nil {
+		return err
+	}
+	if err := r.SetupModifiedNotify(program); err != nil {
+		return err
+	}
+	return r.SetupPreviewSync(program)
+}
+
 // SetupQuitSaveIntercept remaps quit/save commands in neovim to send
 // RPC notifications instead of actually quitting. This keeps the app alive.
-func (r *RPC) SetupQuitSaveIntercept(program *tea.Program) error {
+func (r *RPC) SetupQuitSaveIntercept(program Sender) error {
 	if err := r.client.RegisterHandler("kopr:close-note", func(args ...interface{}) {
 		save := false
 		if len(args) > 0 {
@@ -214,7 +289,7 @@ vim.cmd([[cnoreabbrev <expr> x  getcmdtype()==':' && getcmdline()=='x'  && bufna
 
 // SetupSaveNotify installs an autocmd that notifies Kopr after a buffer is written.
 // Used for features like auto-format-on-save.
-func (r *RPC) SetupSaveNotify(program *tea.Program) error {
+func (r *RPC) SetupSaveNotify(program Sender) error {
 	if err := r.client.RegisterHandler("kopr:buf-written", func(args ...interface{}) {
 		if program == nil {
 			return
@@ -251,9 +326,152 @@ vim.api.nvim_create_autocmd('BufWritePost', {
 	return r.client.ExecLua(lua, nil)
 }
 
+// SetupExternalChange installs a FileChangedShell autocmd that hands files
+// changed on disk to Kopr instead of Neovim's own prompt. A buffer without
+// unsaved changes is reloaded; one with changes is left for Kopr to ask
+// about. Changes to only the timestamp or permissions are ignored.
+func (r *RPC) SetupExternalChange(program Sender) error {
+	if err := r.client.RegisterHandler("kopr:file-changed", func(args ...interface{}) {
+		if program == nil || len(args) < 2 {
+			return
+		}
+		path, _ := args[0].(string)
+		reason, _ := args[1].(string)
+		if path == "" {
+			return
+		}
+		program.Send(FileChangedOnDiskMsg{
+			Path:     path,
+			Reloaded: reason == "changed",
+			Deleted:  reason == "deleted",
+		})
+	}); err != nil {
+		return err
+	}
+	if err := r.client.Subscribe("kopr:file-changed"); err != nil {
+		return err
+	}
+
+	cid := r.client.ChannelID()
+	lua := fmt.Sprintf(`
+vim.api.nvim_create_augroup('KoprFileChanged', {clear=true})
+vim.api.nvim_create_autocmd('FileChangedShell', {
+  group = 'KoprFileChanged',
+  callback = function(args)
+    -- 'changed': the buffer is unmodified, 'conflict': it has unsaved
+    -- changes, 'deleted', or 'mode'/'time' for metadata only.
+    local reason = vim.v.fcs_reason
+    vim.v.fcs_choice = reason == 'changed' and 'reload' or ''
+    if reason == 'changed' or reason == 'conflict' or reason == 'deleted' then
+      vim.rpcnotify(%d, 'kopr:file-changed', vim.fn.fnamemodify(args.file, ':p'), reason)
+    end
+  end,
+})
+`, cid)
+	return r.client.ExecLua(lua, nil)
+}
+
+// SetupModifiedNotify installs autocmds that tell Kopr whether the current
+// buffer has unsaved changes, whenever that changes or another buffer is
+// entered.
+func (r *RPC) SetupModifiedNotify(program Sender) error {
+	if err := r.client.RegisterHandler("kopr:modified", func(args ...interface{}) {
+		if program == nil || len(args) < 1 {
+			return
+		}
+		modified, _ := args[0].(bool)
+		program.Send(BufferModifiedMsg{Modified: modified})
+	}); err != nil {
+		return err
+	}
+	if err := r.client.Subscribe("kopr:modified"); err != nil {
+		return err
+	}
+
+	cid := r.client.ChannelID()
+	lua := fmt.Sprintf(`
+vim.api.nvim_create_augroup('KoprModified', {clear=true})
+vim.api.nvim_create_autocmd({'BufModifiedSet', 'BufEnter', 'BufWritePost'}, {
+  group = 'KoprModified',
+  callback = function()
+    -- BufModifiedSet can fire for a buffer that isn't current.
+    vim.rpcnotify(%d, 'kopr:modified', vim.bo[vim.api.nvim_get_current_buf()].modified)
+  end,
+})
+`, cid)
+	return r.client.ExecLua(lua, nil)
+}
+
+// SetupPreviewSync installs autocmds that tell Kopr when the cursor moves
+// to another line and, at most every 150ms, when the buffer's text changes
+// or another buffer is entered. They keep the preview pane in step.
+func (r *RPC) SetupPreviewSync(program Sender) error {
+	if err := r.client.RegisterHandler("kopr:cursor-line", func(args ...interface{}) {
+		if program == nil || len(args) < 1 {
+			return
+		}
+		// msgpack decodes positive integers as uint64.
+		var line int
+		switch n := args[0].(type) {
+		case int64:
+			line = int(n)
+		case uint64:
+			line = int(n)
+		default:
+			return
+		}
+		program.Send(CursorLineMsg{Line: line})
+	}); err != nil {
+		return err
+	}
+	if err := r.client.RegisterHandler("kopr:text-changed", func(args ...interface{}) {
+		if program != nil {
+			program.Send(TextChangedMsg{})
+		}
+	}); err != nil {
+		return err
+	}
+
+	if err := r.client.Subscribe("kopr:cursor-line"); err != nil {
+		return err
+	}
+	if err := r.client.Subscribe("kopr:text-changed"); err != nil {
+		return err
+	}
+
+	cid := r.client.ChannelID()
+	lua := fmt.Sprintf(`
+vim.api.nvim_create_augroup('KoprPreviewSync', {clear=true})
+local last_line = -1
+vim.api.nvim_create_autocmd({'CursorMoved', 'CursorMovedI'}, {
+  group = 'KoprPreviewSync',
+  callback = function()
+    local line = vim.api.nvim_win_get_cursor(0)[1]
+    if line == last_line then return end
+    last_line = line
+    vim.rpcnotify(%d, 'kopr:cursor-line', line)
+  end,
+})
+local pending = false
+vim.api.nvim_create_autocmd({'TextChanged', 'TextChangedI', 'BufEnter'}, {
+  group = 'KoprPreviewSync',
+  callback = function()
+    last_line = -1
+    if pending then return end
+    pending = true
+    vim.defer_fn(function()
+      pending = false
+      vim.rpcnotify(%d, 'kopr:text-changed')
+    end, 150)
+  end,
+})
+`, cid, cid)
+	return r.client.ExecLua(lua, nil)
+}
+
 // SetupYankClipboard installs a TextYankPost autocmd that sends yanked text
 // back to the Go side via RPC, so it can be forwarded to the system clipboard.
-func (r *RPC) SetupYankClipboard(program *tea.Program) error {
+func (r *RPC) SetupYankClipboard(program Sender) error {
 	if err := r.client.RegisterHandler("kopr:yank", func(args ...interface{}) {
 		if program == nil || len(args) < 1 {
 			return
@@ -289,7 +507,7 @@ func (r *RPC) CursorPosition() (int, int, error) {
 	var pos [2]int
 	err := r.client.ExecLua("return vim.api.nvim_win_get_cursor(0)", &pos)
 	if err != nil {
-		return 0, 0, err
+		return 0, 0, r.check(err)
 	}
 	return pos[0], pos[1], nil
 }
@@ -297,21 +515,109 @@ func (r *RPC) CursorPosition() (int, int, error) {
 // SetCursorPosition sets the current window cursor position.
 // Line is 1-based, col is 0-based.
 func (r *RPC) SetCursorPosition(line, col int) error {
-	return r.client.ExecLua("vim.api.nvim_win_set_cursor(0, {...})", nil, line, col)
+	return r.check(r.client.ExecLua("vim.api.nvim_win_set_cursor(0, {...})", nil, line, col))
 }
 
 // SetBufferLines replaces the entire contents of the current buffer.
 func (r *RPC) SetBufferLines(lines []string) error {
-	return r.client.ExecLua(`
+	return r.check(r.client.ExecLua(`
 local lines = ...
 local buf = vim.api.nvim_get_current_buf()
 vim.api.nvim_buf_set_lines(buf, 0, -1, false, lines)
-`, nil, lines)
+`, nil, lines))
+}
+
+// SetLineAnnotations shows text as virtual text at the end of lines of the
+// current buffer, keyed by 1-based line number, replacing any shown before.
+// Lines past the end of the buffer are skipped.
+func (r *RPC) SetLineAnnotations(notes map[int]string) error {
+	return r.check(r.client.ExecLua(`
+local notes = ...
+local buf = vim.api.nvim_get_current_buf()
+local ns = vim.api.nvim_create_namespace('kopr-annotations')
+vim.api.nvim_buf_clear_namespace(buf, ns, 0, -1)
+local count = vim.api.nvim_buf_line_count(buf)
+for line, text in pairs(notes) do
+  line = tonumber(line)
+  if line and line >= 1 and line <= count then
+    vim.api.nvim_buf_set_extmark(buf, ns, line - 1, 0, {
+      virt_text = {{text, 'Comment'}},
+      virt_text_pos = 'eol',
+      hl_mode = 'combine',
+    })
+  end
+end
+`, nil, notes))
+}
+
+// SetLineHighlights highlights lines of the current buffer, keyed by
+// 1-based line number, with the named highlight groups, replacing any
+// highlighted before. An empty map clears them.
+func (r *RPC) SetLineHighlights(groups map[int]string) error {
+	return r.check(r.client.ExecLua(`
+local groups = ...
+local buf = vim.api.nvim_get_current_buf()
+local ns = vim.api.nvim_create_namespace('kopr-highlights')
+vim.api.nvim_buf_clear_namespace(buf, ns, 0, -1)
+local count = vim.api.nvim_buf_line_count(buf)
+for line, group in pairs(groups) do
+  line = tonumber(line)
+  if line and line >= 1 and line <= count then
+    vim.api.nvim_buf_set_extmark(buf, ns, line - 1, 0, {line_hl_group = group})
+  end
+end
+`, nil, groups))
+}
+
+// ReplaceLines replaces lines start to end (0-based, end exclusive) of the
+// current buffer, as one undoable change.
+func (r *RPC) ReplaceLines(start, end int, lines []string) error {
+	return r.check(r.client.ExecLua(`
+local start, stop, lines = ...
+vim.api.nvim_buf_set_lines(0, start, stop, false, lines)
+`, nil, start, end, lines))
+}
+
+// ApplyEdits applies text edits, in buffer order and not overlapping, to
+// the current buffer as one undoable change. Marks and folds outside the
+// edited text stay put, and the cursor is carried on an extmark so it stays
+// on the same text.
+func (r *RPC) ApplyEdits(edits []markdown.TextEdit) error {
+	args := make([][]any, len(edits))
+	for i, e := range edits {
+		args[i] = []any{e.StartRow, e.StartCol, e.EndRow, e.EndCol, e.Text}
+	}
+	return r.check(r.client.ExecLua(`
+local edits = ...
+local buf = vim.api.nvim_get_current_buf()
+local ns = vim.api.nvim_create_namespace('kopr-format')
+local pos = vim.api.nvim_win_get_cursor(0)
+local mark = vim.api.nvim_buf_set_extmark(buf, ns, pos[1] - 1, pos[2], {})
+for i = #edits, 1, -1 do
+  local e = edits[i]
+  vim.api.nvim_buf_set_text(buf, e[1], e[2], e[3], e[4], e[5])
+end
+local at = vim.api.nvim_buf_get_extmark_by_id(buf, ns, mark, {})
+vim.api.nvim_buf_del_extmark(buf, ns, mark)
+if at[1] then
+  pcall(vim.api.nvim_win_set_cursor, 0, {at[1] + 1, at[2]})
+end
+`, nil, args))
+}
+
+// ClearLineAnnotations removes the annotations from every buffer.
+func (r *RPC) ClearLineAnnotations() error {
+	return r.check(r.client.ExecLua(`
+local ns = vim.api.nvim_create_namespace('kopr-annotations')
+for _, buf in ipairs(vim.api.nvim_list_bufs()) do
+  vim.api.nvim_buf_clear_namespace(buf, ns, 0, -1)
+end
+`, nil))
 }
 
 // SetupLinkNavigation maps gf/gb in normal mode to send RPC notifications
 // for following wiki links and navigating back.
-func (r *RPC) SetupLinkNavigation(program *tea.Program) error {
+func (r *RPC) SetupLinkNavigation(program Sender) error {
 	if err := r.client.RegisterHandler("kopr:follow-link", func(args ...interface{}) {
 		if program != nil {
 			program.Send(FollowLinkMsg{})
@@ -328,12 +634,34 @@ func (r *RPC) SetupLinkNavigation(program *tea.Program) error {
 		return err
 	}
 
+	if err := r.client.RegisterHandler("kopr:quickfix", func(args ...interface{}) {
+		if program == nil || len(args) < 1 {
+			return
+		}
+		// msgpack decodes positive integers as uint64.
+		var delta int
+		switch n := args[0].(type) {
+		case int64:
+			delta = int(n)
+		case uint64:
+			delta = int(n)
+		default:
+			return
+		}
+		program.Send(QuickfixStepMsg{Delta: delta})
+	}); err != nil {
+		return err
+	}
+
 	if err := r.client.Subscribe("kopr:follow-link"); err != nil {
 		return err
 	}
 	if err := r.client.Subscribe("kopr:go-back"); err != nil {
 		return err
 	}
+	if err := r.client.Subscribe("kopr:quickfix"); err != nil {
+		return err
+	}
 
 	cid := r.client.ChannelID()
 	lua := fmt.Sprintf(`
@@ -343,7 +671,13 @@ end, {noremap=true, desc='Follow wiki link'})
 vim.keymap.set('n', 'gb', function()
   vim.rpcnotify(%d, 'kopr:go-back')
 end, {noremap=true, desc='Go back to previous note'})
-`, cid, cid)
+vim.keymap.set('n', ']q', function()
+  vim.rpcnotify(%d, 'kopr:quickfix', 1)
+end, {noremap=true, desc='Next search result'})
+vim.keymap.set('n', '[q', function()
+  vim.rpcnotify(%d, 'kopr:quickfix', -1)
+end, {noremap=true, desc='Previous search result'})
+`, cid, cid, cid, cid)
 
 	return r.client.ExecLua(lua, nil)
 }
@@ -352,24 +686,24 @@ end, {noremap=true, desc='Go back to previous note'})
 func (r *RPC) SetBufferName(name string) error {
 	buf, err := r.client.CurrentBuffer()
 	if err != nil {
-		return err
+		return r.check(err)
 	}
-	return r.client.SetBufferName(buf, name)
+	return r.check(r.client.SetBufferName(buf, name))
 }
 
 // WriteBuffer writes the current buffer to disk.
 func (r *RPC) WriteBuffer() error {
-	return r.client.Command("w!")
+	return r.check(r.client.Command("w!"))
 }
 
 // NewBuffer creates a new empty editable buffer.
 func (r *RPC) NewBuffer() error {
-	return r.client.Command("enew!")
+	return r.check(r.client.Command("enew!"))
 }
 
 // LoadSplashBuffer creates a scratch buffer for the splash screen.
 func (r *RPC) LoadSplashBuffer() error {
-	return r.client.Command("enew! | setlocal buftype=nofile bufhidden=wipe nomodifiable noswapfile")
+	return r.check(r.client.Command("enew! | setlocal buftype=nofile bufhidden=wipe nomodifiable noswapfile"))
 }
 
 // Quit tells Neovim to exit by clearing the quit intercept and running qa!.
@@ -0,0 +1,102 @@
+package editor
+
+import (
+	"strings"
+
+	uv "github.com/charmbracelet/ultraviolet"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/charmbracelet/x/vt"
+)
+
+// scrollLine is a row of the VT screen kept after it scrolled off.
+type scrollLine struct {
+	styled string // with SGR sequences, for display
+	plain  string // for yanking
+}
+
+// scrollback keeps the rows that scroll off the top of the VT screen, such
+// as long :messages or :!cmd output, up to max lines. It outlives the
+// emulator, which is recreated on resize. Rows are pushed from the
+// emulator's Write and read from View, both on the Bubble Tea goroutine.
+type scrollback struct {
+	lines []scrollLine
+	max   int
+}
+
+func newScrollback(max int) *scrollback {
+	return &scrollback{max: max}
+}
+
+// push appends a row, dropping the oldest beyond max.
+func (s *scrollback) push(line scrollLine) {
+	if s == nil || s.max <= 0 {
+		return
+	}
+	s.lines = append(s.lines, line)
+	if over := len(s.lines) - s.max; over > 0 {
+		s.lines = append(s.lines[:0], s.lines[over:]...)
+	}
+}
+
+// trackScrollback records in sb the rows that leave the top of term's
+// screen. x/vt has no scrollback of its own, so this hooks the sequences
+// Neovim's TUI scrolls with: delete line (DL) at the top of a scroll region
+// and scroll up (SU). Only a region spanning the whole screen counts;
+// windows scroll inside smaller ones, and their rows are still in the
+// buffer. The hooks run before the emulator's own handlers, which do the
+// scrolling.
+func trackScrollback(term *vt.SafeEmulator, sb *scrollback) {
+	if sb == nil {
+		return
+	}
+	// The handlers run inside term.Write, under its lock, so they use the
+	// unlocked Emulator.
+	emu := term.Emulator
+	top, bottom := 0, emu.Height()
+	fullScreen := func() bool { return top == 0 && bottom == emu.Height() }
+	save := func(n int) {
+		for y := range min(n, emu.Height()) {
+			sb.push(screenRow(emu, y))
+		}
+	}
+
+	emu.RegisterCsiHandler('r', func(params ansi.Params) bool {
+		// Set Top and Bottom Margins [ansi.DECSTBM], as the emulator
+		// applies it.
+		t, _, _ := params.Param(0, 1)
+		b, _, _ := params.Param(1, emu.Height())
+		t, b = max(t, 1), min(b, emu.Height())
+		if b < 1 {
+			b = emu.Height()
+		}
+		if t < b {
+			top, bottom = t-1, b
+		}
+		return false
+	})
+	emu.RegisterCsiHandler('M', func(params ansi.Params) bool {
+		if n, _, _ := params.Param(0, 1); fullScreen() && emu.CursorPosition().Y == 0 {
+			save(max(n, 1))
+		}
+		return false
+	})
+	emu.RegisterCsiHandler('S', func(params ansi.Params) bool {
+		if n, _, _ := params.Param(0, 1); fullScreen() {
+			save(max(n, 1))
+		}
+		return false
+	})
+}
+
+// screenRow copies row y of the emulator's screen.
+func screenRow(emu *vt.Emulator, y int) scrollLine {
+	line := make(uv.Line, emu.Width())
+	for x := range line {
+		if c := emu.CellAt(x, y); c != nil {
+			line[x] = *c
+		} else {
+			line[x] = uv.EmptyCell
+		}
+	}
+	return scrollLine{styled: line.Render(), plain: strings.TrimRight(line.String(), " ")}
+}
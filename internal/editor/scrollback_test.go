@@ -0,0 +1,86 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/vt"
+)
+
+func TestScrollbackKeepsRowsScrolledOff(t *testing.T) {
+	term := vt.NewSafeEmulator(20, 4)
+	sb := newScrollback(3)
+	trackScrollback(term, sb)
+	write := func(s string) {
+		t.Helper()
+		if _, err := term.Write([]byte(s)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A window scrolling inside its own region keeps its rows in the buffer.
+	write("\x1b[1;1Hone\x1b[2;1Htwo\x1b[3;1Hthree\x1b[4;1Hfour")
+	write("\x1b[1;3r\x1b[1;1H\x1b[M\x1b[r")
+	if len(sb.lines) != 0 {
+		t.Fatalf("scrollback after a window scroll = %v, want none", sb.lines)
+	}
+
+	// :messages scrolling the whole screen pushes rows off the top.
+	write("\x1b[1;4r\x1b[1;1H\x1b[2M")
+	write("\x1b[S\x1b[S")
+	var got []string
+	for _, l := range sb.lines {
+		got = append(got, l.plain)
+	}
+	// Four rows left the screen; the oldest is dropped beyond max.
+	if want := []string{"three", "", "four"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("scrollback = %q, want %q", got, want)
+	}
+}
+
+func TestCopyModeYank(t *testing.T) {
+	lines := []scrollLine{{plain: "first line"}, {plain: "second line"}, {plain: "third"}}
+	c := newCopyMode(lines, 20, 2)
+	if c.row != 2 || c.top != 1 {
+		t.Fatalf("start at row %d top %d, want the last line in view", c.row, c.top)
+	}
+	keys := func(ks ...string) (string, bool) {
+		var text string
+		var done bool
+		for _, k := range ks {
+			msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+			switch k {
+			case "enter":
+				msg = tea.KeyMsg{Type: tea.KeyEnter}
+			case "esc":
+				msg = tea.KeyMsg{Type: tea.KeyEsc}
+			}
+			text, done = c.handleKey(msg)
+		}
+		return text, done
+	}
+
+	if text, done := keys("g", "g", "w"); done || text != "" || c.row != 0 || c.top != 0 {
+		t.Fatalf("gg: row %d top %d", c.row, c.top)
+	}
+	if !strings.Contains(c.view(), "[1/1]") {
+		t.Errorf("view at the top lacks its position:\n%q", c.view())
+	}
+	text, done := keys("l", "l", "l", "l", "l", "l", "v", "j", "0", "l", "l", "l", "l", "l", "y")
+	if !done || text != "line\nsecond" {
+		t.Errorf("yank = %q, %v; want %q", text, done, "line\nsecond")
+	}
+
+	c = newCopyMode(lines, 20, 2)
+	if text, done := keys("k", "V", "k", "enter"); !done || text != "first line\nsecond line" {
+		t.Errorf("linewise yank = %q, %v", text, done)
+	}
+	c = newCopyMode(lines, 20, 2)
+	if _, done := keys("v", "esc"); done || c.selecting {
+		t.Error("esc with a selection should clear it, not leave")
+	}
+	if _, done := keys("esc"); !done {
+		t.Error("esc without a selection should leave copy mode")
+	}
+}
@@ -0,0 +1,174 @@
+package editor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TerminalExitedMsg is sent when the terminal pane's shell exits, or fails
+// to start with Err set.
+type TerminalExitedMsg struct {
+	Err error
+}
+
+type terminalStartedMsg struct {
+	proc   *ptyProcess
+	screen *vtScreen
+}
+
+type terminalOutputMsg struct {
+	data []byte
+	proc *ptyProcess
+}
+
+type terminalClosedMsg struct{ proc *ptyProcess }
+
+// Terminal is a Bubble Tea model running the user's shell in its own PTY
+// behind a VT emulator, like the editor's, for the terminal pane. The shell
+// starts in dir and keeps running while the pane is hidden.
+type Terminal struct {
+	dir      string
+	width    int
+	height   int
+	proc     *ptyProcess
+	screen   *vtScreen
+	starting bool
+	focused  bool
+}
+
+func NewTerminal(dir string) Terminal {
+	return Terminal{dir: dir}
+}
+
+// Running reports whether the shell is running or being started.
+func (t Terminal) Running() bool {
+	return t.proc != nil || t.starting
+}
+
+// Start starts the shell at the pane's size, unless it is already running.
+func (t *Terminal) Start() tea.Cmd {
+	if t.Running() {
+		return nil
+	}
+	t.starting = true
+	width, height, dir := max(t.width, 1), max(t.height, 1), t.dir
+	return func() tea.Msg {
+		proc, err := startShell(width, height, dir)
+		if err != nil {
+			return TerminalExitedMsg{Err: err}
+		}
+		return terminalStartedMsg{proc: proc, screen: newVTScreen(width, height, proc.file, nil)}
+	}
+}
+
+// SetSize resizes the pane. Unlike the editor's, the emulator is resized in
+// place: a shell doesn't repaint its screen, so a new one would come up
+// blank.
+func (t *Terminal) SetSize(width, height int) error {
+	if width <= 0 || height <= 0 || (width == t.width && height == t.height) {
+		return nil
+	}
+	t.width, t.height = width, height
+	if t.proc == nil {
+		return nil
+	}
+	t.screen.resize(width, height)
+	return t.proc.resize(width, height)
+}
+
+// SetFocused shows the cursor while the pane has focus.
+func (t *Terminal) SetFocused(focused bool) {
+	t.focused = focused
+	if t.screen != nil {
+		t.screen.setShowCursor(focused)
+	}
+}
+
+func waitForTerminalOutput(proc *ptyProcess) tea.Cmd {
+	return func() tea.Msg {
+		buf := make([]byte, 32*1024)
+		n, err := proc.file.Read(buf)
+		if err != nil {
+			return terminalClosedMsg{proc}
+		}
+		return terminalOutputMsg{data: buf[:n], proc: proc}
+	}
+}
+
+func (t Terminal) Update(msg tea.Msg) (Terminal, tea.Cmd) {
+	switch msg := msg.(type) {
+	case terminalStartedMsg:
+		t.starting = false
+		t.proc, t.screen = msg.proc, msg.screen
+		t.screen.setShowCursor(t.focused)
+		// The pane may have been resized while the shell started.
+		if err := t.proc.resize(t.width, t.height); err != nil {
+			t.Close()
+			return t, func() tea.Msg { return TerminalExitedMsg{Err: err} }
+		}
+		t.screen.resize(t.width, t.height)
+		return t, waitForTerminalOutput(t.proc)
+
+	case TerminalExitedMsg:
+		t.starting = false
+
+	case terminalOutputMsg:
+		if msg.proc != t.proc {
+			return t, nil
+		}
+		if _, err := t.screen.write(msg.data); err != nil {
+			t.Close()
+			return t, func() tea.Msg { return TerminalExitedMsg{Err: fmt.Errorf("terminal: %w", err)} }
+		}
+		return t, waitForTerminalOutput(t.proc)
+
+	case terminalClosedMsg:
+		if msg.proc != t.proc {
+			return t, nil
+		}
+		t.Close()
+		return t, func() tea.Msg { return TerminalExitedMsg{} }
+
+	case tea.KeyMsg:
+		if t.proc == nil {
+			return t, nil
+		}
+		if raw := keyMsgToBytes(msg); raw != nil {
+			if _, err := t.proc.file.Write(raw); err != nil {
+				t.Close()
+				return t, func() tea.Msg { return TerminalExitedMsg{Err: fmt.Errorf("terminal: %w", err)} }
+			}
+		}
+	}
+	return t, nil
+}
+
+func (t Terminal) View() string {
+	if t.screen == nil {
+		return strings.Repeat("\n", max(t.height-1, 0))
+	}
+	return t.screen.render()
+}
+
+// Close stops the shell. Its exit status is of no interest: a shell killed
+// by the closed PTY, or left with a failed last command, exits non-zero.
+func (t *Terminal) Close() {
+	if t.screen != nil {
+		if err := t.screen.close(); err != nil {
+			fmt.Fprintln(os.Stderr, "close terminal screen:", err)
+		}
+		t.screen = nil
+	}
+	if t.proc != nil {
+		var exitErr *exec.ExitError
+		if err := t.proc.close(); err != nil && !errors.As(err, &exitErr) {
+			fmt.Fprintln(os.Stderr, "close terminal:", err)
+		}
+		t.proc = nil
+	}
+}
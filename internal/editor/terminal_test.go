@@ -0,0 +1,67 @@
+package editor
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+func TestTerminalRunsShell(t *testing.T) {
+	t.Setenv("SHELL", "/bin/sh")
+	dir := t.TempDir()
+	term := NewTerminal(dir)
+	if err := term.SetSize(120, 6); err != nil {
+		t.Fatal(err)
+	}
+	msg := term.Start()()
+	if exited, ok := msg.(TerminalExitedMsg); ok {
+		t.Skipf("no shell: %v", exited.Err)
+	}
+	var cmd tea.Cmd
+	term, cmd = term.Update(msg)
+	defer term.Close()
+	if term.Start() != nil {
+		t.Error("Start() started a second shell")
+	}
+
+	typeLine := func(line string) {
+		t.Helper()
+		for _, r := range line {
+			term, _ = term.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+		}
+		term, _ = term.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	}
+	// Reads the shell's output until the screen shows want, and reports
+	// whether the shell exited first.
+	waitFor := func(want string) (exited bool) {
+		t.Helper()
+		for {
+			msg := cmd()
+			if exit, ok := msg.(TerminalExitedMsg); ok {
+				term, _ = term.Update(exit)
+				return true
+			}
+			term, cmd = term.Update(msg)
+			if want != "" && strings.Contains(term.View(), want) {
+				return false
+			}
+		}
+	}
+
+	// The typed line doesn't contain the output, so these wait for the
+	// commands to run.
+	typeLine("echo kopr-$((20+22))")
+	if waitFor("kopr-42") {
+		t.Fatalf("shell exited:\n%s", term.View())
+	}
+	typeLine("pwd")
+	if waitFor(dir) {
+		t.Fatalf("shell exited:\n%s", term.View())
+	}
+
+	typeLine("exit")
+	if !waitFor("") || term.Running() {
+		t.Error("shell still running after exit")
+	}
+}
@@ -0,0 +1,298 @@
+package editor
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+// lineKind classifies a note line for styling in the viewer.
+type lineKind int
+
+const (
+	lineText lineKind = iota
+	lineFrontmatter
+	lineCode
+	lineHeading
+	lineQuote
+)
+
+// viewer shows a note read-only when Neovim is unavailable. Lines are
+// styled by kind and word-wrapped to the pane; wiki links can be selected
+// with Tab and followed with Enter.
+type viewer struct {
+	width    int
+	height   int
+	path     string
+	source   []string   // the note's lines
+	kinds    []lineKind // kind of each source line
+	links    []markdown.WikiLink
+	selected int      // index into links, -1 for none
+	lines    []string // rendered, wrapped rows
+	starts   []int    // rendered row each source line starts on
+	top      int      // first row shown
+	pending  string   // first key of a two-key command
+	theme    *theme.Theme
+}
+
+func newViewer() viewer {
+	return viewer{selected: -1}
+}
+
+// setContent shows content, read from path. Reloading the note already
+// shown keeps the scroll position.
+func (v *viewer) setContent(path string, content []byte) {
+	text := strings.ReplaceAll(string(content), "\r\n", "\n")
+	v.source = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	v.kinds = classifyLines(v.source)
+	v.links = nil
+	for _, l := range markdown.ExtractWikiLinks(content) {
+		if l.Target != "" && v.kinds[l.Line-1] != lineCode {
+			v.links = append(v.links, l)
+		}
+	}
+	v.selected = -1
+	v.pending = ""
+	if path != v.path {
+		v.top = 0
+	}
+	v.path = path
+	v.render()
+}
+
+// classifyLines tags each line as frontmatter, fenced code, a heading, a
+// quote or plain text.
+func classifyLines(lines []string) []lineKind {
+	kinds := make([]lineKind, len(lines))
+	front := len(lines) > 0 && strings.TrimSpace(lines[0]) == "---"
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case front:
+			kinds[i] = lineFrontmatter
+			if i > 0 && trimmed == "---" {
+				front = false
+			}
+		case fence != "":
+			kinds[i] = lineCode
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			kinds[i] = lineCode
+			fence = trimmed[:3]
+		case isHeading(line):
+			kinds[i] = lineHeading
+		case strings.HasPrefix(trimmed, ">"):
+			kinds[i] = lineQuote
+		}
+	}
+	return kinds
+}
+
+// isHeading reports whether line is an ATX heading: one to six #s followed
+// by a space or the end of the line.
+func isHeading(line string) bool {
+	hashes := len(line) - len(strings.TrimLeft(line, "#"))
+	return hashes >= 1 && hashes <= 6 && (hashes == len(line) || line[hashes] == ' ')
+}
+
+func (v *viewer) setSize(width, height int) {
+	v.width = width
+	v.height = height
+	v.render()
+}
+
+// render styles and wraps every source line to the current width.
+func (v *viewer) render() {
+	if v.width <= 0 || v.theme == nil {
+		return
+	}
+	th := v.theme
+	styles := map[lineKind]lipgloss.Style{
+		lineText:        lipgloss.NewStyle().Foreground(th.Text),
+		lineFrontmatter: lipgloss.NewStyle().Foreground(th.Dim),
+		lineCode:        lipgloss.NewStyle().Foreground(th.Subtle),
+		lineHeading:     lipgloss.NewStyle().Foreground(th.Accent).Bold(true),
+		lineQuote:       lipgloss.NewStyle().Foreground(th.Subtle).Italic(true),
+	}
+	linkStyle := lipgloss.NewStyle().Foreground(th.Accent2).Underline(true)
+
+	v.lines, v.starts = nil, nil
+	next := 0 // first link not yet rendered
+	for i, line := range v.source {
+		base := styles[v.kinds[i]]
+		var b strings.Builder
+		col := 0
+		for ; next < len(v.links) && v.links[next].Line == i+1; next++ {
+			l := v.links[next]
+			end := l.Col + 2 + l.InnerLen + 2
+			b.WriteString(base.Render(expandTabs(line[col:l.Col])))
+			style := linkStyle
+			if next == v.selected {
+				style = style.Reverse(true)
+			}
+			b.WriteString(style.Render(line[l.Col:end]))
+			col = end
+		}
+		b.WriteString(base.Render(expandTabs(line[col:])))
+
+		v.starts = append(v.starts, len(v.lines))
+		v.lines = append(v.lines, strings.Split(ansi.Wrap(b.String(), v.width, ""), "\n")...)
+	}
+	v.clampTop()
+}
+
+func expandTabs(s string) string {
+	return strings.ReplaceAll(s, "\t", "    ")
+}
+
+func (v viewer) view() string {
+	rows := make([]string, v.height)
+	for i := range rows {
+		if v.top+i < len(v.lines) {
+			rows[i] = v.lines[v.top+i]
+		}
+	}
+	return strings.Join(rows, "\n")
+}
+
+// handleKey scrolls with the usual pager keys and selects and follows
+// links: Tab/Shift+Tab move between links, Enter or gf follows the
+// selected one, and gb or Backspace goes back.
+func (v *viewer) handleKey(msg tea.KeyMsg) tea.Cmd {
+	key := msg.String()
+	if v.pending == "g" {
+		v.pending = ""
+		switch key {
+		case "g":
+			v.top = 0
+		case "f":
+			return v.follow()
+		case "b":
+			return goBack
+		}
+		return nil
+	}
+
+	half := max(1, v.height/2)
+	switch key {
+	case "j", "down":
+		v.scroll(1)
+	case "k", "up":
+		v.scroll(-1)
+	case "ctrl+d":
+		v.scroll(half)
+	case "ctrl+u":
+		v.scroll(-half)
+	case "ctrl+f", "pgdown":
+		v.scroll(v.height)
+	case "ctrl+b", "pgup":
+		v.scroll(-v.height)
+	case "G", "end":
+		v.top = len(v.lines)
+		v.clampTop()
+	case "home":
+		v.top = 0
+	case "g":
+		v.pending = "g"
+	case "tab":
+		v.selectLink(1)
+	case "shift+tab":
+		v.selectLink(-1)
+	case "enter":
+		return v.follow()
+	case "backspace":
+		return goBack
+	}
+	return nil
+}
+
+func (v *viewer) handleMouse(msg tea.MouseMsg) {
+	switch msg.Button {
+	case tea.MouseButtonWheelUp:
+		v.scroll(-3)
+	case tea.MouseButtonWheelDown:
+		v.scroll(3)
+	}
+}
+
+func goBack() tea.Msg { return GoBackMsg{} }
+
+func (v *viewer) follow() tea.Cmd {
+	if v.selected < 0 {
+		return nil
+	}
+	link := v.links[v.selected]
+	return func() tea.Msg { return FollowLinkMsg{Target: link.Target, Section: link.Section} }
+}
+
+// selectLink moves the selection delta links on, wrapping around. With
+// nothing selected it starts from the first or last link on screen.
+func (v *viewer) selectLink(delta int) {
+	n := len(v.links)
+	if n == 0 {
+		return
+	}
+	if v.selected < 0 {
+		v.selected = 0
+		if delta < 0 {
+			v.selected = n - 1
+		}
+		for i := range n {
+			j := i
+			if delta < 0 {
+				j = n - 1 - i
+			}
+			if row := v.linkRow(j); row >= v.top && row < v.top+v.height {
+				v.selected = j
+				break
+			}
+		}
+	} else {
+		v.selected = ((v.selected+delta)%n + n) % n
+	}
+	v.render()
+
+	row := v.linkRow(v.selected)
+	if row < v.top {
+		v.top = row
+	} else if row >= v.top+v.height {
+		v.top = row - v.height + 1
+	}
+	v.clampTop()
+}
+
+// linkRow estimates the rendered row link i starts on.
+func (v viewer) linkRow(i int) int {
+	l := v.links[i]
+	if l.Line-1 >= len(v.starts) || v.width <= 0 {
+		return 0
+	}
+	before := ansi.StringWidth(expandTabs(v.source[l.Line-1][:l.Col]))
+	return v.starts[l.Line-1] + before/v.width
+}
+
+// scrollToLine scrolls the 1-based source line to the top of the view.
+func (v *viewer) scrollToLine(line int) {
+	if line < 1 || line > len(v.starts) {
+		return
+	}
+	v.top = v.starts[line-1]
+	v.clampTop()
+}
+
+func (v *viewer) scroll(delta int) {
+	v.top += delta
+	v.clampTop()
+}
+
+func (v *viewer) clampTop() {
+	v.top = max(0, min(v.top, len(v.lines)-v.height))
+}
@@ -0,0 +1,132 @@
+package editor
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+func TestClassifyLines(t *testing.T) {
+	lines := []string{
+		"---",
+		"title: x",
+		"---",
+		"# Heading",
+		"#hashtag",
+		"> quote",
+		"```go",
+		"# not a heading",
+		"```",
+		"text",
+	}
+	want := []lineKind{
+		lineFrontmatter, lineFrontmatter, lineFrontmatter,
+		lineHeading, lineText, lineQuote,
+		lineCode, lineCode, lineCode,
+		lineText,
+	}
+	if got := classifyLines(lines); !slices.Equal(got, want) {
+		t.Errorf("classifyLines = %v, want %v", got, want)
+	}
+}
+
+func newTestViewer(width, height int, content string) viewer {
+	th := theme.DefaultTheme()
+	v := newViewer()
+	v.theme = &th
+	v.setSize(width, height)
+	v.setContent("/vault/note.md", []byte(content))
+	return v
+}
+
+func TestViewerWrapsAndScrolls(t *testing.T) {
+	v := newTestViewer(10, 3, "one two three four five\nsix\nseven\neight")
+	if len(v.lines) < 6 {
+		t.Fatalf("got %d rows, want the first line wrapped", len(v.lines))
+	}
+	for _, row := range v.lines {
+		if w := ansi.StringWidth(row); w > 10 {
+			t.Errorf("row %q is %d wide", row, w)
+		}
+	}
+	if got := strings.Count(v.view(), "\n") + 1; got != 3 {
+		t.Errorf("view has %d rows, want 3", got)
+	}
+
+	v.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	if want := len(v.lines) - 3; v.top != want {
+		t.Errorf("G: top = %d, want %d", v.top, want)
+	}
+	v.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("j")})
+	if want := len(v.lines) - 3; v.top != want {
+		t.Errorf("j past the end: top = %d, want %d", v.top, want)
+	}
+	v.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	v.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	if v.top != 0 {
+		t.Errorf("gg: top = %d, want 0", v.top)
+	}
+
+	// Reloading the same note keeps the position; another note starts at
+	// the top.
+	v.scroll(2)
+	v.setContent("/vault/note.md", []byte("one two three four five\nsix\nseven\neight"))
+	if v.top != 2 {
+		t.Errorf("reload: top = %d, want 2", v.top)
+	}
+	v.setContent("/vault/other.md", []byte("one two three four five\nsix\nseven\neight"))
+	if v.top != 0 {
+		t.Errorf("other note: top = %d, want 0", v.top)
+	}
+}
+
+func TestViewerFollowsLinks(t *testing.T) {
+	content := "---\nup: [[meta]]\n---\nSee [[alpha]] and [[beta|b]].\n```\n[[code]]\n```\n\nlast [[gamma#sec]]"
+	v := newTestViewer(40, 20, content)
+
+	var targets []string
+	for range 4 {
+		v.handleKey(tea.KeyMsg{Type: tea.KeyTab})
+		cmd := v.handleKey(tea.KeyMsg{Type: tea.KeyEnter})
+		if cmd == nil {
+			t.Fatal("enter on a selected link returned no command")
+		}
+		msg, ok := cmd().(FollowLinkMsg)
+		if !ok {
+			t.Fatalf("enter sent %T, want FollowLinkMsg", cmd())
+		}
+		targets = append(targets, msg.Target)
+	}
+	// Frontmatter and code links are skipped; selection wraps around.
+	if want := []string{"alpha", "beta", "gamma", "alpha"}; !slices.Equal(targets, want) {
+		t.Errorf("followed %v, want %v", targets, want)
+	}
+
+	v.handleKey(tea.KeyMsg{Type: tea.KeyShiftTab})
+	if got := v.links[v.selected].Target; got != "gamma" {
+		t.Errorf("shift+tab selected %q, want gamma", got)
+	}
+
+	v.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	cmd := v.handleKey(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("b")})
+	if cmd == nil {
+		t.Fatal("gb returned no command")
+	}
+	if _, ok := cmd().(GoBackMsg); !ok {
+		t.Errorf("gb sent %T, want GoBackMsg", cmd())
+	}
+}
+
+func TestViewerSelectScrollsIntoView(t *testing.T) {
+	content := strings.Repeat("filler\n", 30) + "[[far]]"
+	v := newTestViewer(20, 5, content)
+	v.handleKey(tea.KeyMsg{Type: tea.KeyTab})
+	if row := v.linkRow(v.selected); row < v.top || row >= v.top+v.height {
+		t.Errorf("selected link on row %d, view shows %d-%d", row, v.top, v.top+v.height-1)
+	}
+}
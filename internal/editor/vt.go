@@ -17,8 +17,10 @@ type vtScreen struct {
 // newVTScreen creates a VT emulator and starts a goroutine that drains
 // terminal responses (DA1, DECRQM, etc.) back to the PTY. Without this,
 // the emulator's internal io.Pipe blocks on Write when nvim sends queries.
-func newVTScreen(width, height int, ptyFile *os.File) *vtScreen {
+// Rows scrolling off the screen are kept in sb, which may be nil.
+func newVTScreen(width, height int, ptyFile *os.File, sb *scrollback) *vtScreen {
 	term := vt.NewSafeEmulator(width, height)
+	trackScrollback(term, sb)
 	done := make(chan struct{})
 
 	go func() {
@@ -48,14 +50,22 @@ func (v *vtScreen) write(p []byte) (int, error) {
 	return v.term.Write(p)
 }
 
-// resize is kept for clarity in case we stop recreating the emulator on
-// WindowSizeMsg; currently we recreate the emulator, so this is unused.
-//
-//nolint:unused
+// resize resizes the emulator in place. The editor recreates its emulator
+// instead (see the WindowSizeMsg handling); the terminal pane resizes.
 func (v *vtScreen) resize(width, height int) {
 	v.term.Resize(width, height)
 }
 
+// rows copies the screen's rows, for copy mode.
+func (v *vtScreen) rows() []scrollLine {
+	emu := v.term.Emulator
+	rows := make([]scrollLine, emu.Height())
+	for y := range rows {
+		rows[y] = screenRow(emu, y)
+	}
+	return rows
+}
+
 func (v *vtScreen) render() string {
 	rendered := v.term.Render()
 	// Render() uses \r\n; Bubble Tea expects \n
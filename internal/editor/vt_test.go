@@ -0,0 +1,48 @@
+package editor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/vt"
+)
+
+// fillScreen writes a screenful of colored text, like Neovim redrawing a
+// note with syntax highlighting.
+func fillScreen(tb testing.TB, term *vt.SafeEmulator, width, height int) {
+	var b strings.Builder
+	for row := range height {
+		fmt.Fprintf(&b, "\x1b[%d;1H\x1b[38;2;%d;180;250m", row+1, row*3%256)
+		b.WriteString(strings.Repeat("lorem ipsum ", width/12))
+		b.WriteString("\x1b[0m")
+	}
+	if _, err := term.Write([]byte(b.String())); err != nil {
+		tb.Fatal(err)
+	}
+}
+
+func TestVTRenderShowsCursor(t *testing.T) {
+	v := &vtScreen{term: vt.NewSafeEmulator(24, 3), showCursor: true}
+	fillScreen(t, v.term, 24, 3)
+	out := v.render()
+	if strings.Count(out, "\n") != 2 {
+		t.Errorf("render() has %d line breaks, want 2:\n%q", strings.Count(out, "\n"), out)
+	}
+	if !strings.Contains(out, "\x1b[7m") {
+		t.Errorf("render() shows no cursor:\n%q", out)
+	}
+}
+
+// BenchmarkVTRender renders a full editor pane, which the app does on every
+// frame while Neovim is on screen.
+func BenchmarkVTRender(b *testing.B) {
+	const width, height = 180, 60
+	v := &vtScreen{term: vt.NewSafeEmulator(width, height), showCursor: true}
+	fillScreen(b, v.term, width, height)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		v.render()
+	}
+}
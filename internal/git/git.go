@@ -0,0 +1,177 @@
+// Package git reads a note's history from the git repository the vault is
+// in, by running the git command.
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Commit is a commit that changed a note.
+type Commit struct {
+	Hash    string
+	Short   string
+	Time    time.Time
+	Author  string
+	Subject string
+	// Path is the note's path at this commit, relative to the repository
+	// root. It differs from the current path when the note was renamed.
+	Path string
+}
+
+// IsRepo reports whether dir is inside a git work tree and git is
+// installed.
+func IsRepo(dir string) bool {
+	out, err := run(dir, "rev-parse", "--is-inside-work-tree")
+	return err == nil && strings.TrimSpace(string(out)) == "true"
+}
+
+// Log returns the commits that changed the note at relPath (relative to
+// dir), newest first, following it across renames.
+func Log(dir, relPath string) ([]Commit, error) {
+	// Each commit is a record-separated header line followed by the name
+	// of the file at that commit.
+	out, err := run(dir, "log", "--follow", "--name-only",
+		"--format=%x1e%H%x1f%h%x1f%at%x1f%an%x1f%s", "--", relPath)
+	if err != nil {
+		return nil, err
+	}
+	var commits []Commit
+	for _, rec := range strings.Split(string(out), "\x1e") {
+		header, names, _ := strings.Cut(rec, "\n")
+		fields := strings.Split(header, "\x1f")
+		if len(fields) != 5 {
+			continue
+		}
+		sec, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("commit %s: bad time %q", fields[1], fields[2])
+		}
+		c := Commit{
+			Hash:    fields[0],
+			Short:   fields[1],
+			Time:    time.Unix(sec, 0),
+			Author:  fields[3],
+			Subject: fields[4],
+		}
+		for _, name := range strings.Split(names, "\n") {
+			if name = strings.TrimSpace(name); name != "" {
+				c.Path = name
+				break
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits, nil
+}
+
+// Diff returns the patch c made to the note.
+func Diff(dir string, c Commit) (string, error) {
+	out, err := run(dir, "show", "--format=", "--patch", "--find-renames", c.Hash, "--", ":(top)"+c.Path)
+	return string(out), err
+}
+
+// Show returns the note's content as of c.
+func Show(dir string, c Commit) ([]byte, error) {
+	return run(dir, "show", c.Hash+":"+c.Path)
+}
+
+// Blame returns the commit that last changed each line of content, the
+// current text of the note at relPath, which may have unsaved edits. The
+// result has one entry per line. Lines that aren't committed yet have a
+// zero Commit.
+func Blame(dir, relPath string, content []byte) ([]Commit, error) {
+	out, err := runInput(dir, content, "blame", "--porcelain", "--contents", "-", "--", relPath)
+	if err != nil {
+		return nil, err
+	}
+	// The porcelain format starts each line with "<hash> <orig> <final>"
+	// and gives a commit's author, time, summary and filename the first
+	// time it appears. The line's text follows, prefixed by a tab.
+	seen := make(map[string]*Commit)
+	var lines []Commit
+	var cur *Commit
+	var line int
+	for _, l := range strings.Split(string(out), "\n") {
+		if strings.HasPrefix(l, "\t") {
+			if cur != nil && line > 0 {
+				for len(lines) < line {
+					lines = append(lines, Commit{})
+				}
+				if strings.Trim(cur.Hash, "0") != "" {
+					lines[line-1] = *cur
+				}
+			}
+			continue
+		}
+		key, value, _ := strings.Cut(l, " ")
+		if cur == nil || len(key) >= 40 && isHex(key) {
+			fields := strings.Fields(value)
+			if len(fields) < 2 {
+				continue
+			}
+			if line, err = strconv.Atoi(fields[1]); err != nil {
+				return nil, fmt.Errorf("blame: bad line number %q", fields[1])
+			}
+			if cur = seen[key]; cur == nil {
+				cur = &Commit{Hash: key, Short: key[:7]}
+				seen[key] = cur
+			}
+			continue
+		}
+		switch key {
+		case "author":
+			cur.Author = value
+		case "author-time":
+			sec, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("commit %s: bad time %q", cur.Short, value)
+			}
+			cur.Time = time.Unix(sec, 0)
+		case "summary":
+			cur.Subject = value
+		case "filename":
+			cur.Path = value
+		}
+	}
+	return lines, nil
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// run runs git in dir and returns its standard output. A failure is
+// reported with git's own error message.
+func run(dir string, args ...string) ([]byte, error) {
+	return runInput(dir, nil, args...)
+}
+
+// runInput is run with stdin as git's standard input.
+func runInput(dir string, stdin []byte, args ...string) ([]byte, error) {
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "core.quotepath=off"}, args...)...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return nil, fmt.Errorf("git %s: %s", args[0], strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
@@ -0,0 +1,117 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// gitRepo makes a repository with the vault in a subfolder and returns the
+// vault's path.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	vault := filepath.Join(root, "notes")
+	if err := os.Mkdir(vault, 0755); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, root, "init", "-q")
+	return vault
+}
+
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-c", "user.name=Test", "-c", "user.email=test@example.com"}, args...)...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func commitFile(t *testing.T, dir, rel, content, msg string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, rel), []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, dir, "add", "-A")
+	gitIn(t, dir, "commit", "-q", "-m", msg)
+}
+
+func TestLogFollowsRenames(t *testing.T) {
+	vault := gitRepo(t)
+	if IsRepo(t.TempDir()) {
+		t.Error("IsRepo is true outside a repository")
+	}
+	if !IsRepo(vault) {
+		t.Fatal("IsRepo is false inside a repository")
+	}
+
+	commitFile(t, vault, "old.md", "one\n", "add note")
+	commitFile(t, vault, "other.md", "x\n", "unrelated")
+	gitIn(t, vault, "mv", "old.md", "new.md")
+	gitIn(t, vault, "commit", "-q", "-m", "rename")
+	commitFile(t, vault, "new.md", "one\ntwo\n", "edit note")
+
+	commits, err := Log(vault, "new.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var subjects, paths []string
+	for _, c := range commits {
+		subjects = append(subjects, c.Subject)
+		paths = append(paths, c.Path)
+	}
+	if got := strings.Join(subjects, ","); got != "edit note,rename,add note" {
+		t.Errorf("subjects = %s", got)
+	}
+	if got := strings.Join(paths, ","); got != "notes/new.md,notes/new.md,notes/old.md" {
+		t.Errorf("paths = %s", got)
+	}
+	if commits[0].Author != "Test" || commits[0].Time.IsZero() || len(commits[0].Short) >= len(commits[0].Hash) {
+		t.Errorf("commit = %+v", commits[0])
+	}
+
+	diff, err := Diff(vault, commits[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(diff, "+two") || strings.Contains(diff, "other.md") {
+		t.Errorf("diff of the edit:\n%s", diff)
+	}
+
+	content, err := Show(vault, commits[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "one\n" {
+		t.Errorf("content at first commit = %q", content)
+	}
+}
+
+func TestBlame(t *testing.T) {
+	vault := gitRepo(t)
+	commitFile(t, vault, "note.md", "one\ntwo\n", "first")
+	commitFile(t, vault, "note.md", "one\n2\n", "second")
+
+	lines, err := Blame(vault, "note.md", []byte("one\n2\nunsaved\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(lines))
+	}
+	if lines[0].Subject != "first" || lines[1].Subject != "second" {
+		t.Errorf("subjects = %q, %q", lines[0].Subject, lines[1].Subject)
+	}
+	if lines[0].Author != "Test" || lines[0].Time.IsZero() || lines[0].Path != "notes/note.md" {
+		t.Errorf("line 1 = %+v", lines[0])
+	}
+	if lines[2] != (Commit{}) {
+		t.Errorf("unsaved line = %+v, want zero", lines[2])
+	}
+}
@@ -0,0 +1,108 @@
+package index
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// Attachment is a file in the vault that isn't a note, such as an image or
+// a PDF.
+type Attachment struct {
+	Path    string // vault-relative
+	Size    int64
+	ModTime time.Time
+}
+
+// isAttachment reports whether a file name is indexed as an attachment:
+// anything but notes and hidden files.
+func isAttachment(name string) bool {
+	return !strings.HasSuffix(name, ".md") && !strings.HasPrefix(name, ".")
+}
+
+// ReplaceAttachments makes the attachments table hold exactly attachments.
+func (db *DB) ReplaceAttachments(attachments []Attachment) error {
+	if _, err := db.q.Exec("DELETE FROM attachments"); err != nil {
+		return err
+	}
+	for _, a := range attachments {
+		if err := db.UpsertAttachment(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UpsertAttachment adds an attachment or updates its size and mod time.
+func (db *DB) UpsertAttachment(a Attachment) error {
+	_, err := db.q.Exec(`
+		INSERT INTO attachments (path, size, mod_time) VALUES (?, ?, ?)
+		ON CONFLICT(path) DO UPDATE SET size = excluded.size, mod_time = excluded.mod_time
+	`, a.Path, a.Size, a.ModTime.Unix())
+	return err
+}
+
+// DeleteAttachment removes the attachment at path, if indexed.
+func (db *DB) DeleteAttachment(path string) error {
+	_, err := db.q.Exec("DELETE FROM attachments WHERE path = ?", path)
+	return err
+}
+
+// MoveAttachment changes the path of the attachment at oldPath, if indexed.
+func (db *DB) MoveAttachment(oldPath, newPath string) error {
+	_, err := db.q.Exec("UPDATE attachments SET path = ? WHERE path = ?", newPath, oldPath)
+	return err
+}
+
+// ListAttachments returns every attachment in the vault, by path.
+func (db *DB) ListAttachments() ([]Attachment, error) {
+	rows, err := db.q.Query("SELECT path, size, mod_time FROM attachments ORDER BY path")
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Attachment
+	for rows.Next() {
+		var a Attachment
+		var modTime int64
+		if err := rows.Scan(&a.Path, &a.Size, &modTime); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		a.ModTime = time.Unix(modTime, 0)
+		results = append(results, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// IndexAttachment records the attachment at absPath, or drops it from the
+// index if the file is gone. Files in the skipped directory and external
+// sources are ignored.
+func (idx *Indexer) IndexAttachment(absPath string) error {
+	relPath := idx.relPath(absPath)
+	if filepath.IsAbs(relPath) || vault.IsExternal(relPath) || idx.skipped(relPath, false) {
+		return nil
+	}
+	info, err := os.Stat(absPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return idx.db.DeleteAttachment(relPath)
+	}
+	if err != nil || info.IsDir() {
+		return err
+	}
+	return idx.db.UpsertAttachment(Attachment{Path: relPath, Size: info.Size(), ModTime: info.ModTime()})
+}
+
+// RemoveAttachment drops the attachment at absPath from the index.
+func (idx *Indexer) RemoveAttachment(absPath string) error {
+	return idx.db.DeleteAttachment(idx.relPath(absPath))
+}
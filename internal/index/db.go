@@ -6,8 +6,11 @@ import (
 	"fmt"
 	"path/filepath"
 	"strings"
+	"time"
 
 	_ "modernc.org/sqlite"
+
+	"github.com/pfassina/kopr/internal/vault"
 )
 
 const schema = `
@@ -18,20 +21,32 @@ CREATE TABLE IF NOT EXISTS notes (
     title TEXT NOT NULL DEFAULT '',
     slug TEXT NOT NULL DEFAULT '',
     status TEXT NOT NULL DEFAULT '',
+    summary TEXT NOT NULL DEFAULT '',
     mod_time INTEGER NOT NULL,
     size INTEGER NOT NULL DEFAULT 0,
-    hash TEXT NOT NULL DEFAULT ''
+    hash TEXT NOT NULL DEFAULT '',
+    created INTEGER NOT NULL DEFAULT 0,
+    updated INTEGER NOT NULL DEFAULT 0,
+    reviewed INTEGER NOT NULL DEFAULT 0,
+    words INTEGER NOT NULL DEFAULT 0,
+    uid TEXT NOT NULL DEFAULT ''
 );
 
 CREATE VIRTUAL TABLE IF NOT EXISTS notes_fts USING fts5(
-    title, content, tags, headings,
-    content=notes, content_rowid=id,
+    title, content, tags, headings, keywords,
     tokenize='porter unicode61 remove_diacritics 2'
 );
 
+-- notes_fts isn't tied to notes, so deleting a note must delete its
+-- full-text row too, whichever statement deletes it.
+CREATE TRIGGER IF NOT EXISTS notes_fts_delete AFTER DELETE ON notes BEGIN
+    DELETE FROM notes_fts WHERE rowid = old.id;
+END;
+
 CREATE TABLE IF NOT EXISTS tags (
     id INTEGER PRIMARY KEY AUTOINCREMENT,
-    name TEXT NOT NULL UNIQUE
+    name TEXT NOT NULL UNIQUE,
+    parent_id INTEGER REFERENCES tags(id)
 );
 
 CREATE TABLE IF NOT EXISTS note_tags (
@@ -48,7 +63,15 @@ CREATE TABLE IF NOT EXISTS links (
     section TEXT DEFAULT '',
     alias TEXT DEFAULT '',
     line INTEGER NOT NULL,
-    col INTEGER NOT NULL
+    col INTEGER NOT NULL,
+    markdown INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS attachments (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL UNIQUE,
+    size INTEGER NOT NULL DEFAULT 0,
+    mod_time INTEGER NOT NULL
 );
 
 CREATE TABLE IF NOT EXISTS headings (
@@ -58,33 +81,115 @@ CREATE TABLE IF NOT EXISTS headings (
     text TEXT NOT NULL,
     line INTEGER NOT NULL
 );
+
+CREATE TABLE IF NOT EXISTS tasks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    text TEXT NOT NULL,
+    done INTEGER NOT NULL DEFAULT 0,
+    section TEXT NOT NULL DEFAULT '',
+    line INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS note_visits (
+    note_id INTEGER PRIMARY KEY REFERENCES notes(id) ON DELETE CASCADE,
+    open_count INTEGER NOT NULL DEFAULT 0,
+    last_opened INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS note_opens (
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    opened_at INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_note_opens_note ON note_opens(note_id);
+CREATE INDEX IF NOT EXISTS idx_note_opens_opened_at ON note_opens(opened_at);
+
+CREATE TABLE IF NOT EXISTS aliases (
+    note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    alias TEXT NOT NULL,
+    PRIMARY KEY (note_id, alias)
+);
+
+-- Per-note rows are cleared and links resolved on every IndexFile; without
+-- these each of those statements scans the whole table.
+CREATE INDEX IF NOT EXISTS idx_links_source_id ON links(source_id);
+CREATE INDEX IF NOT EXISTS idx_links_target_id ON links(target_id);
+CREATE INDEX IF NOT EXISTS idx_links_target_path ON links(target_path);
+CREATE INDEX IF NOT EXISTS idx_headings_note_id ON headings(note_id);
+CREATE INDEX IF NOT EXISTS idx_tasks_note_id ON tasks(note_id);
+CREATE INDEX IF NOT EXISTS idx_tags_parent ON tags(parent_id);
+CREATE INDEX IF NOT EXISTS idx_notes_uid ON notes(uid);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notes_basename_key ON notes(basename_key);
 `
 
+// FTS tokenizers selectable with the fts_tokenizer setting.
+const (
+	// TokenizerDefault splits on word boundaries and stems English words.
+	TokenizerDefault = "default"
+	// TokenizerTrigram matches any three-character substring, which works
+	// for scripts without spaces between words (Chinese, Japanese, Korean).
+	// Search terms shorter than three characters find nothing through FTS
+	// and fall back to fuzzy title matching.
+	TokenizerTrigram = "trigram"
+)
+
+// ftsTokenize maps tokenizer names to the notes_fts tokenize option.
+var ftsTokenize = map[string]string{
+	TokenizerDefault: "porter unicode61 remove_diacritics 2",
+	TokenizerTrigram: "trigram",
+}
+
+// Basename scopes selectable with the basename_uniqueness setting.
+const (
+	// ScopeVault requires every note's basename to be unique across the
+	// vault; [[name]] links resolve by basename alone.
+	ScopeVault = "vault"
+	// ScopeFolder only requires paths to be unique, so notes in different
+	// folders may share a name. Links can be qualified with trailing folders
+	// ([[a/notes]]); a link matches every note whose path ends with its
+	// target, and the shortest such path wins.
+	ScopeFolder = "folder"
+)
+
+// querier runs statements on either the connection pool or a transaction.
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
 // DB wraps the SQLite database connection.
 type DB struct {
-	conn *sql.DB
+	conn     *sql.DB
+	q        querier // conn, or the transaction passed to an InTx callback
+	readOnly *sql.DB // opened with mode=ro for QueryReadOnly; nil in memory
+
+	folderScoped bool // basename scope is ScopeFolder
 }
 
 // Open opens or creates the database at the given path.
 func Open(path string) (*DB, error) {
-	conn, err := sql.Open("sqlite", path+"?_pragma=journal_mode(wal)&_pragma=foreign_keys(on)")
+	// busy_timeout lets a writer wait out another connection's transaction
+	// (e.g. an IndexAll batch) instead of failing with SQLITE_BUSY.
+	// Incremental auto_vacuum lets Optimize give free pages back without
+	// rewriting the file; an index created before takes it on its next
+	// VACUUM.
+	conn, err := sql.Open("sqlite", path+"?_pragma=auto_vacuum(incremental)&_pragma=journal_mode(wal)&_pragma=foreign_keys(on)&_pragma=busy_timeout(5000)")
 	if err != nil {
 		return nil, fmt.Errorf("open db: %w", err)
 	}
 
-	if _, err := conn.Exec(schema); err != nil {
+	db := &DB{conn: conn, q: conn}
+	if err := db.initSchema(); err != nil {
 		if closeErr := conn.Close(); closeErr != nil {
 			return nil, fmt.Errorf("init schema: %w (close: %v)", err, closeErr)
 		}
 		return nil, fmt.Errorf("init schema: %w", err)
 	}
-
-	db := &DB{conn: conn}
-	if err := db.migrate(); err != nil {
-		if closeErr := conn.Close(); closeErr != nil {
-			return nil, fmt.Errorf("migrate db: %w (close: %v)", err, closeErr)
-		}
-		return nil, fmt.Errorf("migrate db: %w", err)
+	// Console queries run on their own connection that SQL can't make
+	// writable, unlike query_only.
+	if db.readOnly, err = sql.Open("sqlite", "file:"+path+"?mode=ro&_pragma=busy_timeout(5000)"); err != nil {
+		return nil, errors.Join(fmt.Errorf("open read-only db: %w", err), conn.Close())
 	}
 	return db, nil
 }
@@ -95,25 +200,21 @@ func OpenMemory() (*DB, error) {
 	if err != nil {
 		return nil, err
 	}
-	if _, err := conn.Exec(schema); err != nil {
+	db := &DB{conn: conn, q: conn}
+	if err := db.initSchema(); err != nil {
 		if closeErr := conn.Close(); closeErr != nil {
 			return nil, fmt.Errorf("init schema: %w (close: %v)", err, closeErr)
 		}
 		return nil, fmt.Errorf("init schema: %w", err)
 	}
-	// In-memory DB still runs migrations for consistent behavior.
-	db := &DB{conn: conn}
-	if err := db.migrate(); err != nil {
-		if closeErr := conn.Close(); closeErr != nil {
-			return nil, fmt.Errorf("migrate db: %w (close: %v)", err, closeErr)
-		}
-		return nil, fmt.Errorf("migrate db: %w", err)
-	}
 	return db, nil
 }
 
 // Close closes the database connection.
 func (db *DB) Close() error {
+	if db.readOnly != nil {
+		return errors.Join(db.readOnly.Close(), db.conn.Close())
+	}
 	return db.conn.Close()
 }
 
@@ -122,10 +223,27 @@ func (db *DB) Conn() *sql.DB {
 	return db.conn
 }
 
+// InTx runs fn with a DB whose statements all go through one transaction,
+// committing if fn returns nil and rolling back otherwise. Inside a
+// transaction already, fn joins it.
+func (db *DB) InTx(fn func(tx *DB) error) error {
+	if _, ok := db.q.(*sql.Tx); ok {
+		return fn(db)
+	}
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("begin: %w", err)
+	}
+	if err := fn(&DB{conn: db.conn, q: tx, readOnly: db.readOnly, folderScoped: db.folderScoped}); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	return tx.Commit()
+}
+
 // UpsertNote inserts or updates a note and returns its ID.
 func (db *DB) UpsertNote(path, title, slug, status, hash string, modTime, size int64) (int64, error) {
-	basenameKey := canonicalBasenameKey(path)
-	res, err := db.conn.Exec(`
+	basenameKey := db.noteKey(path)
+	res, err := db.q.Exec(`
 		INSERT INTO notes (path, basename_key, title, slug, status, mod_time, size, hash)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 		ON CONFLICT(path) DO UPDATE SET
@@ -143,7 +261,7 @@ func (db *DB) UpsertNote(path, title, slug, status, hash string, modTime, size i
 
 	// Get the ID (either inserted or existing)
 	var id int64
-	err = db.conn.QueryRow("SELECT id FROM notes WHERE path = ?", path).Scan(&id)
+	err = db.q.QueryRow("SELECT id FROM notes WHERE path = ?", path).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
@@ -152,163 +270,446 @@ func (db *DB) UpsertNote(path, title, slug, status, hash string, modTime, size i
 	return id, nil
 }
 
-// UpdateFTS updates the FTS index for a note.
-func (db *DB) UpdateFTS(noteID int64, title, content, tags, headings string) error {
-	// Delete old FTS entry; ignore errors for new entries that have no prior row.
-	db.conn.Exec("INSERT INTO notes_fts(notes_fts, rowid, title, content, tags, headings) VALUES('delete', ?, '', '', '', '')", noteID) //nolint:errcheck
+// SetTokenizer makes notes_fts use the named tokenizer, recreating the table
+// when it was built with another one. It reports whether the table was
+// recreated; the caller must then run IndexAll to refill it.
+func (db *DB) SetTokenizer(name string) (bool, error) {
+	tokenize, ok := ftsTokenize[name]
+	if !ok {
+		return false, fmt.Errorf("unknown FTS tokenizer %q", name)
+	}
+	var ftsSQL string
+	if err := db.q.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'notes_fts'").Scan(&ftsSQL); err != nil {
+		return false, fmt.Errorf("read notes_fts schema: %w", err)
+	}
+	if strings.Contains(ftsSQL, "tokenize='"+tokenize+"'") {
+		return false, nil
+	}
+
+	if _, err := db.q.Exec("DROP TABLE notes_fts"); err != nil {
+		return false, fmt.Errorf("drop notes_fts: %w", err)
+	}
+	if _, err := db.q.Exec(`
+		CREATE VIRTUAL TABLE notes_fts USING fts5(
+			title, content, tags, headings, keywords,
+			tokenize='` + tokenize + `'
+		)`); err != nil {
+		return false, fmt.Errorf("create notes_fts: %w", err)
+	}
+	return true, nil
+}
+
+// SetBasenameScope switches the index to ScopeVault or ScopeFolder,
+// rewriting the stored note keys when they were built for the other scope.
+// It reports whether they were rewritten; the caller must then run IndexAll
+// to rebuild the links. Switching to ScopeVault fails while two notes still
+// share a basename; the index then keeps the scope its keys were built for.
+func (db *DB) SetBasenameScope(scope string) (bool, error) {
+	var folder bool
+	switch scope {
+	case ScopeVault:
+	case ScopeFolder:
+		folder = true
+	default:
+		return false, fmt.Errorf("unknown basename scope %q", scope)
+	}
+
+	target := &DB{conn: db.conn, q: db.q, folderScoped: folder}
+	rows, err := db.q.Query("SELECT path, basename_key FROM notes")
+	if err != nil {
+		return false, fmt.Errorf("read note keys: %w", err)
+	}
+	keys := map[string]string{}
+	stale := false
+	for rows.Next() {
+		var path, key string
+		if err := rows.Scan(&path, &key); err != nil {
+			return false, errors.Join(fmt.Errorf("scan note key: %w", err), rows.Close())
+		}
+		keys[path] = target.noteKey(path)
+		stale = stale || keys[path] != key
+	}
+	if err := rows.Err(); err != nil {
+		return false, errors.Join(fmt.Errorf("read note keys: %w", err), rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return false, err
+	}
+	if !stale {
+		db.folderScoped = folder
+		return false, nil
+	}
+
+	seen := map[string]string{}
+	for path, key := range keys {
+		if other, ok := seen[key]; ok {
+			db.folderScoped = !folder
+			return false, fmt.Errorf("%q and %q share a basename", other, path)
+		}
+		seen[key] = path
+	}
+	err = db.InTx(func(tx *DB) error {
+		// Keys may swap between rows, so drop uniqueness while rewriting.
+		if _, err := tx.q.Exec("DROP INDEX IF EXISTS idx_notes_basename_key"); err != nil {
+			return err
+		}
+		for path, key := range keys {
+			if _, err := tx.q.Exec("UPDATE notes SET basename_key = ? WHERE path = ?", key, path); err != nil {
+				return fmt.Errorf("rewrite key for %q: %w", path, err)
+			}
+		}
+		_, err := tx.q.Exec("CREATE UNIQUE INDEX idx_notes_basename_key ON notes(basename_key)")
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+	db.folderScoped = folder
+	return true, nil
+}
+
+// BasenameScope returns the scope the index currently uses.
+func (db *DB) BasenameScope() string {
+	if db.folderScoped {
+		return ScopeFolder
+	}
+	return ScopeVault
+}
+
+// SetNoteSummary stores the one-line summary shown next to a note in the finder.
+func (db *DB) SetNoteSummary(noteID int64, summary string) error {
+	_, err := db.q.Exec("UPDATE notes SET summary = ? WHERE id = ?", summary, noteID)
+	return err
+}
+
+// SetNoteDates stores the note's frontmatter created, updated and reviewed
+// dates. A zero time is stored as 0, meaning unset.
+func (db *DB) SetNoteDates(noteID int64, created, updated, reviewed time.Time) error {
+	_, err := db.q.Exec("UPDATE notes SET created = ?, updated = ?, reviewed = ? WHERE id = ?",
+		unixOrZero(created), unixOrZero(updated), unixOrZero(reviewed), noteID)
+	return err
+}
+
+// SetNoteWordCount stores the number of words in the note's body.
+func (db *DB) SetNoteWordCount(noteID int64, words int) error {
+	_, err := db.q.Exec("UPDATE notes SET words = ? WHERE id = ?", words, noteID)
+	return err
+}
+
+// SetNoteUID stores the note's frontmatter uid, "" when it has none.
+func (db *DB) SetNoteUID(noteID int64, uid string) error {
+	_, err := db.q.Exec("UPDATE notes SET uid = ? WHERE id = ?", uid, noteID)
+	return err
+}
+
+// NoteByUID returns the path of the note with the given uid, or "" if no
+// indexed note has it. Should copies share a uid, the first path wins.
+func (db *DB) NoteByUID(uid string) (string, error) {
+	if uid == "" {
+		return "", nil
+	}
+	var path string
+	err := db.q.QueryRow("SELECT path FROM notes WHERE uid = ? ORDER BY path LIMIT 1", uid).Scan(&path)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return path, err
+}
 
-	// Insert new FTS entry
-	_, err := db.conn.Exec("INSERT INTO notes_fts(rowid, title, content, tags, headings) VALUES(?, ?, ?, ?, ?)",
-		noteID, title, content, tags, headings)
+// WordCount returns the number of words in the note at path, as of when it
+// was last indexed. A note not in the index has 0.
+func (db *DB) WordCount(path string) (int, error) {
+	var n int
+	err := db.q.QueryRow("SELECT words FROM notes WHERE path = ?", path).Scan(&n)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return n, err
+}
+
+// TotalWordCount returns the number of words across all indexed notes.
+func (db *DB) TotalWordCount() (int, error) {
+	var n int
+	err := db.q.QueryRow("SELECT COALESCE(SUM(words), 0) FROM notes").Scan(&n)
+	return n, err
+}
+
+// unixOrZero returns t as Unix seconds, or 0 for the zero time.
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// timeOrZero is the inverse of unixOrZero.
+func timeOrZero(sec int64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// UpdateFTS updates the FTS index for a note.
+func (db *DB) UpdateFTS(noteID int64, title, content, tags, headings, keywords string) error {
+	if _, err := db.q.Exec("DELETE FROM notes_fts WHERE rowid = ?", noteID); err != nil {
+		return err
+	}
+	_, err := db.q.Exec("INSERT INTO notes_fts(rowid, title, content, tags, headings, keywords) VALUES(?, ?, ?, ?, ?, ?)",
+		noteID, title, content, tags, headings, keywords)
 	return err
 }
 
-// UpsertTag ensures a tag exists and returns its ID.
+// UpsertTag ensures a tag exists and returns its ID. A nested tag such as
+// project/alpha also records its parent segments, each as a tag its child
+// points at through parent_id.
 func (db *DB) UpsertTag(name string) (int64, error) {
-	_, err := db.conn.Exec("INSERT OR IGNORE INTO tags (name) VALUES (?)", name)
+	var parent any // NULL for a top-level tag
+	if i := strings.LastIndex(name, "/"); i > 0 {
+		id, err := db.UpsertTag(name[:i])
+		if err != nil {
+			return 0, err
+		}
+		parent = id
+	}
+	_, err := db.q.Exec(`
+		INSERT INTO tags (name, parent_id) VALUES (?, ?)
+		ON CONFLICT(name) DO UPDATE SET parent_id = excluded.parent_id
+	`, name, parent)
 	if err != nil {
 		return 0, err
 	}
 	var id int64
-	err = db.conn.QueryRow("SELECT id FROM tags WHERE name = ?", name).Scan(&id)
+	err = db.q.QueryRow("SELECT id FROM tags WHERE name = ?", name).Scan(&id)
 	return id, err
 }
 
 // LinkNoteTag associates a tag with a note.
 func (db *DB) LinkNoteTag(noteID, tagID int64) error {
-	_, err := db.conn.Exec("INSERT OR IGNORE INTO note_tags (note_id, tag_id) VALUES (?, ?)", noteID, tagID)
+	_, err := db.q.Exec("INSERT OR IGNORE INTO note_tags (note_id, tag_id) VALUES (?, ?)", noteID, tagID)
 	return err
 }
 
 // ClearNoteTags removes all tag associations for a note.
 func (db *DB) ClearNoteTags(noteID int64) error {
-	_, err := db.conn.Exec("DELETE FROM note_tags WHERE note_id = ?", noteID)
+	_, err := db.q.Exec("DELETE FROM note_tags WHERE note_id = ?", noteID)
 	return err
 }
 
 // InsertLink adds a wiki link record.
 func (db *DB) InsertLink(sourceID int64, targetPath, section, alias string, line, col int) error {
-	_, err := db.conn.Exec(`
+	_, err := db.q.Exec(`
 		INSERT INTO links (source_id, target_path, section, alias, line, col)
 		VALUES (?, ?, ?, ?, ?, ?)
 	`, sourceID, targetPath, section, alias, line, col)
 	return err
 }
 
+// InsertMarkdownLink adds a [text](path.md) link, with its text as the
+// alias.
+func (db *DB) InsertMarkdownLink(sourceID int64, targetPath, section, text string, line, col int) error {
+	_, err := db.q.Exec(`
+		INSERT INTO links (source_id, target_path, section, alias, line, col, markdown)
+		VALUES (?, ?, ?, ?, ?, ?, 1)
+	`, sourceID, targetPath, section, text, line, col)
+	return err
+}
+
 // ClearNoteLinks removes all links from a note.
 func (db *DB) ClearNoteLinks(noteID int64) error {
-	_, err := db.conn.Exec("DELETE FROM links WHERE source_id = ?", noteID)
+	_, err := db.q.Exec("DELETE FROM links WHERE source_id = ?", noteID)
 	return err
 }
 
 // InsertHeading adds a heading record.
 func (db *DB) InsertHeading(noteID int64, level int, text string, line int) error {
-	_, err := db.conn.Exec("INSERT INTO headings (note_id, level, text, line) VALUES (?, ?, ?, ?)",
+	_, err := db.q.Exec("INSERT INTO headings (note_id, level, text, line) VALUES (?, ?, ?, ?)",
 		noteID, level, text, line)
 	return err
 }
 
 // ClearNoteHeadings removes all headings for a note.
 func (db *DB) ClearNoteHeadings(noteID int64) error {
-	_, err := db.conn.Exec("DELETE FROM headings WHERE note_id = ?", noteID)
+	_, err := db.q.Exec("DELETE FROM headings WHERE note_id = ?", noteID)
+	return err
+}
+
+// InsertTask adds a checklist item record.
+func (db *DB) InsertTask(noteID int64, text string, done bool, section string, line int) error {
+	_, err := db.q.Exec("INSERT INTO tasks (note_id, text, done, section, line) VALUES (?, ?, ?, ?, ?)",
+		noteID, text, done, section, line)
+	return err
+}
+
+// ClearNoteTasks removes all checklist items for a note.
+func (db *DB) ClearNoteTasks(noteID int64) error {
+	_, err := db.q.Exec("DELETE FROM tasks WHERE note_id = ?", noteID)
+	return err
+}
+
+// InsertAlias adds a frontmatter alias for a note.
+func (db *DB) InsertAlias(noteID int64, alias string) error {
+	_, err := db.q.Exec("INSERT OR IGNORE INTO aliases (note_id, alias) VALUES (?, ?)", noteID, alias)
+	return err
+}
+
+// ClearNoteAliases removes all aliases for a note.
+func (db *DB) ClearNoteAliases(noteID int64) error {
+	_, err := db.q.Exec("DELETE FROM aliases WHERE note_id = ?", noteID)
 	return err
 }
 
 // GetNoteHash returns the stored hash for a note path.
 func (db *DB) GetNoteHash(path string) (string, error) {
 	var hash string
-	err := db.conn.QueryRow("SELECT hash FROM notes WHERE path = ?", path).Scan(&hash)
+	err := db.q.QueryRow("SELECT hash FROM notes WHERE path = ?", path).Scan(&hash)
 	if err == sql.ErrNoRows {
 		return "", nil
 	}
 	return hash, err
 }
 
-// DeleteNote removes a note and all its related data.
-func (db *DB) DeleteNote(path string) error {
-	_, err := db.conn.Exec("DELETE FROM notes WHERE path = ?", path)
-	return err
-}
+// NoteHashes returns the content hash of every indexed note, keyed by path.
+func (db *DB) NoteHashes() (map[string]string, error) {
+	rows, err := db.q.Query("SELECT path, hash FROM notes")
+	if err != nil {
+		return nil, err
+	}
 
-func canonicalBasenameKey(path string) string {
-	// Basename uniqueness in Kopr is case-insensitive.
-	return strings.ToLower(filepath.Base(path))
+	hashes := make(map[string]string)
+	for rows.Next() {
+		var path, hash string
+		if err := rows.Scan(&path, &hash); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		hashes[path] = hash
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return hashes, nil
 }
 
-func (db *DB) migrate() error {
-	// notes.basename_key (case-insensitive basename uniqueness)
-	hasBasenameKey, err := db.hasColumn("notes", "basename_key")
+// NoteStamps returns each note's modification time (Unix seconds) and size
+// as of its last indexing, by path.
+func (db *DB) NoteStamps() (map[string]noteStamp, error) {
+	rows, err := db.q.Query("SELECT path, mod_time, size FROM notes")
 	if err != nil {
-		return err
+		return nil, err
 	}
-	if !hasBasenameKey {
-		if _, err := db.conn.Exec("ALTER TABLE notes ADD COLUMN basename_key TEXT NOT NULL DEFAULT ''"); err != nil {
-			return fmt.Errorf("add notes.basename_key: %w", err)
+
+	stamps := make(map[string]noteStamp)
+	for rows.Next() {
+		var path string
+		var st noteStamp
+		if err := rows.Scan(&path, &st.modTime, &st.size); err != nil {
+			return nil, errors.Join(err, rows.Close())
 		}
+		stamps[path] = st
 	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return stamps, nil
+}
+
+// SetNoteStamp records a note's modification time and size without
+// rewriting it, for a file touched but not changed.
+func (db *DB) SetNoteStamp(path string, modTime, size int64) error {
+	_, err := db.q.Exec("UPDATE notes SET mod_time = ?, size = ? WHERE path = ?", modTime, size, path)
+	return err
+}
+
+// CountLinks returns the number of links in the index.
+func (db *DB) CountLinks() (int, error) {
+	var n int
+	err := db.q.QueryRow("SELECT COUNT(*) FROM links").Scan(&n)
+	return n, err
+}
+
+// DeleteNote removes a note and all its related data. The notes_fts_delete
+// trigger removes its full-text row.
+func (db *DB) DeleteNote(path string) error {
+	_, err := db.q.Exec("DELETE FROM notes WHERE path = ?", path)
+	return err
+}
+
+// DeleteNotesUnder removes every note whose path starts with prefix.
+func (db *DB) DeleteNotesUnder(prefix string) error {
+	_, err := db.q.Exec(`DELETE FROM notes WHERE path LIKE ? ESCAPE '\'`, escapeLike(prefix)+"%")
+	return err
+}
 
-	// Backfill basename_key for all existing rows.
-	rows, err := db.conn.Query("SELECT path FROM notes")
+// NotePathsUnder returns the paths of the notes whose path starts with
+// prefix.
+func (db *DB) NotePathsUnder(prefix string) ([]string, error) {
+	rows, err := db.q.Query(`SELECT path FROM notes WHERE path LIKE ? ESCAPE '\' ORDER BY path`, escapeLike(prefix)+"%")
 	if err != nil {
-		return fmt.Errorf("read note paths: %w", err)
+		return nil, err
 	}
+
 	var paths []string
 	for rows.Next() {
 		var p string
 		if err := rows.Scan(&p); err != nil {
-			return fmt.Errorf("scan note path: %w", err)
+			return nil, errors.Join(err, rows.Close())
 		}
 		paths = append(paths, p)
 	}
 	if err := rows.Err(); err != nil {
-		return fmt.Errorf("read note paths: %w", err)
+		return nil, errors.Join(err, rows.Close())
 	}
 	if err := rows.Close(); err != nil {
-		return fmt.Errorf("close note paths: %w", err)
+		return nil, err
 	}
+	return paths, nil
+}
 
-	seen := map[string]string{}
-	for _, p := range paths {
-		key := canonicalBasenameKey(p)
-		if other, ok := seen[key]; ok && other != p {
-			return fmt.Errorf("basename conflict during migration: %q and %q", other, p)
-		}
-		seen[key] = p
-		if _, err := db.conn.Exec("UPDATE notes SET basename_key = ? WHERE path = ?", key, p); err != nil {
-			return fmt.Errorf("backfill basename_key for %q: %w", p, err)
-		}
+// MoveNote changes the path of the note at oldPath to newPath, keeping its
+// ID and so its visits, opens and the links resolved to it. It returns the
+// note's ID, or 0 if no note is at oldPath.
+func (db *DB) MoveNote(oldPath, newPath string) (int64, error) {
+	var id int64
+	err := db.q.QueryRow("UPDATE notes SET path = ?, basename_key = ? WHERE path = ? RETURNING id",
+		newPath, db.noteKey(newPath), oldPath).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
 	}
+	return id, err
+}
 
-	if _, err := db.conn.Exec("CREATE UNIQUE INDEX IF NOT EXISTS idx_notes_basename_key ON notes(basename_key)"); err != nil {
-		return fmt.Errorf("create idx_notes_basename_key: %w", err)
-	}
+func canonicalBasenameKey(path string) string {
+	// Basename uniqueness in Kopr is case-insensitive.
+	return strings.ToLower(filepath.Base(path))
+}
 
-	// Normalize existing stored wiki-link targets to the canonical key.
-	if _, err := db.conn.Exec("UPDATE links SET target_path = lower(target_path)"); err != nil {
-		return fmt.Errorf("normalize links.target_path: %w", err)
+// noteKey returns the notes.basename_key for a note path: its canonical
+// basename, or with folder scope its whole canonical path. Notes in
+// external sources always use their path, so they never clash with vault
+// notes sharing their name.
+func (db *DB) noteKey(path string) string {
+	if db.folderScoped || vault.IsExternal(path) {
+		return canonicalPathKey(path)
 	}
-
-	return nil
+	return canonicalBasenameKey(path)
 }
 
-func (db *DB) hasColumn(table, col string) (has bool, err error) {
-	rows, err := db.conn.Query("PRAGMA table_info(" + table + ")")
-	if err != nil {
-		return false, err
+// linkKey returns the links.target_path for a wiki link target resolved to
+// a file name: the part of it that notes are matched on. Targets in
+// external sources, such as [[@external/work/guide]], keep their path.
+func (db *DB) linkKey(target string) string {
+	if db.folderScoped || vault.IsExternal(strings.TrimPrefix(filepath.ToSlash(target), "/")) {
+		return canonicalPathKey(strings.TrimPrefix(filepath.ToSlash(target), "/"))
 	}
-	defer func() { err = errors.Join(err, rows.Close()) }()
-	for rows.Next() {
-		var cid int
-		var name, ctype string
-		var notnull int
-		var dflt sql.NullString
-		var pk int
-		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
-			return false, err
-		}
-		if name == col {
-			return true, nil
-		}
-	}
-	return false, rows.Err()
+	return canonicalBasenameKey(target)
+}
+
+func canonicalPathKey(path string) string {
+	return strings.ToLower(filepath.ToSlash(filepath.Clean(path)))
 }
@@ -1,6 +1,16 @@
 package index
 
-import "testing"
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
 
 func TestOpenMemory(t *testing.T) {
 	db, err := OpenMemory()
@@ -23,7 +33,7 @@ func TestOpenMemory(t *testing.T) {
 	}
 
 	// Update FTS
-	err = db.UpdateFTS(id, "Test", "Hello world content", "tag1 tag2", "Heading 1")
+	err = db.UpdateFTS(id, "Test", "Hello world content", "tag1 tag2", "Heading 1", "")
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -41,6 +51,407 @@ func TestOpenMemory(t *testing.T) {
 	}
 }
 
+func TestSchemaVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := db.schemaVersion(); err != nil || v != len(migrations) {
+		t.Errorf("new index at version %d, %v; want %d", v, err, len(migrations))
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening the current version changes nothing.
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	if v, err := db.schemaVersion(); err != nil || v != len(migrations) {
+		t.Errorf("reopened index at version %d, %v; want %d", v, err, len(migrations))
+	}
+}
+
+func TestMigrateUnversionedIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The index as first released: no schema_version, and none of the
+	// columns added since.
+	_, err = conn.Exec(`
+		CREATE TABLE notes (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			path TEXT NOT NULL UNIQUE,
+			title TEXT NOT NULL DEFAULT '',
+			slug TEXT NOT NULL DEFAULT '',
+			status TEXT NOT NULL DEFAULT '',
+			mod_time INTEGER NOT NULL,
+			size INTEGER NOT NULL DEFAULT 0,
+			hash TEXT NOT NULL DEFAULT ''
+		);
+		CREATE VIRTUAL TABLE notes_fts USING fts5(title, content, tags, headings, content=notes);
+		CREATE TABLE tags (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL UNIQUE);
+		CREATE TABLE links (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			source_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+			target_path TEXT NOT NULL,
+			target_id INTEGER REFERENCES notes(id) ON DELETE SET NULL,
+			section TEXT DEFAULT '',
+			alias TEXT DEFAULT '',
+			line INTEGER NOT NULL,
+			col INTEGER NOT NULL
+		);
+		INSERT INTO notes (path, mod_time, hash) VALUES ('a/Alpha.md', 1, 'abc');
+		INSERT INTO links (source_id, target_path, line, col) VALUES (1, 'Beta', 1, 1);
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := conn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	if v, err := db.schemaVersion(); err != nil || v != len(migrations) {
+		t.Errorf("migrated index at version %d, %v; want %d", v, err, len(migrations))
+	}
+	for _, col := range []string{"basename_key", "summary", "created", "updated", "reviewed", "words", "uid"} {
+		if has, err := db.hasColumn("notes", col); err != nil || !has {
+			t.Errorf("notes.%s missing after migration (%v)", col, err)
+		}
+	}
+	var key, hash, target string
+	if err := db.q.QueryRow("SELECT basename_key, hash FROM notes").Scan(&key, &hash); err != nil {
+		t.Fatal(err)
+	}
+	if key != canonicalBasenameKey("a/Alpha.md") || hash != "" {
+		t.Errorf("basename_key, hash = %q, %q; want backfilled key and a cleared hash", key, hash)
+	}
+	if err := db.q.QueryRow("SELECT target_path FROM links").Scan(&target); err != nil || target != "beta" {
+		t.Errorf("target_path = %q, %v; want lowercased", target, err)
+	}
+	if err := db.UpdateFTS(1, "Alpha", "body", "", "", ""); err != nil {
+		t.Errorf("notes_fts not recreated: %v", err)
+	}
+}
+
+func TestDeleteNoteDropsFTS(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range []string{"gone.md", "dir/a.md", "dir/b.md", "kept.md"} {
+		id, err := db.UpsertNote(rel, "shared", rel, "", "h", 1, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateFTS(id, "shared", "shared words", "", "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.DeleteNote("gone.md"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DeleteNotesUnder("dir/"); err != nil {
+		t.Fatal(err)
+	}
+	// Deleted without DeleteNote, as in an older index where only
+	// DeleteNote dropped full-text rows.
+	if _, err := db.conn.Exec("DELETE FROM notes WHERE path = 'kept.md'"); err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM notes_fts").Scan(&n); err != nil || n != 0 {
+		t.Fatalf("notes_fts has %d rows after deletes, %v; want 0", n, err)
+	}
+
+	// An index from before the trigger is reconciled when it is migrated.
+	id, err := db.UpsertNote("stale.md", "Stale", "stale", "", "h", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateFTS(id, "stale", "stale words", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec("DROP TRIGGER notes_fts_delete; DELETE FROM notes WHERE id = ?", id); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM notes_fts").Scan(&n); err != nil || n != 1 {
+		t.Fatalf("notes_fts has %d rows without the trigger, %v; want the orphaned one", n, err)
+	}
+	if err := db.setSchemaVersion(slices.IndexFunc(migrations, func(m migration) bool { return m.name == "notes_fts delete trigger" })); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM notes_fts").Scan(&n); err != nil || n != 0 {
+		t.Errorf("notes_fts has %d rows after migrating, %v; want 0", n, err)
+	}
+	id, err = db.UpsertNote("new.md", "New", "new", "", "h", 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.UpdateFTS(id, "new", "new words", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.conn.Exec("DELETE FROM notes"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.conn.QueryRow("SELECT COUNT(*) FROM notes_fts").Scan(&n); err != nil || n != 0 {
+		t.Errorf("notes_fts has %d rows with the migrated trigger, %v; want 0", n, err)
+	}
+}
+
+func TestMigrateSeedsNoteOpens(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertNote("a.md", "A", "a", "", "h", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	// An index from before note_opens, with a visit already counted.
+	if _, err := db.conn.Exec(`
+		DROP TABLE note_opens;
+		INSERT INTO note_visits (note_id, open_count, last_opened) VALUES (1, 3, 500);
+	`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.setSchemaVersion(slices.IndexFunc(migrations, func(m migration) bool { return m.name == "note_opens" })); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	recent, err := db.RecentNotes(10)
+	if err != nil || len(recent) != 1 || recent[0].Path != "a.md" {
+		t.Errorf("RecentNotes = %v, %v; want the visited a.md", recent, err)
+	}
+}
+
+func TestOpenRebuildsNewerIndex(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "index.db")
+	db, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertNote("a.md", "A", "a", "", "h", 1, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.setSchemaVersion(len(migrations) + 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	db, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	if v, err := db.schemaVersion(); err != nil || v != len(migrations) {
+		t.Errorf("rebuilt index at version %d, %v; want %d", v, err, len(migrations))
+	}
+	if hashes, err := db.NoteHashes(); err != nil || len(hashes) != 0 {
+		t.Errorf("rebuilt index holds %v, %v; want no notes", hashes, err)
+	}
+}
+
+func TestOptimize(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	body := strings.Repeat("lorem ipsum dolor sit amet ", 200)
+	err = db.InTx(func(tx *DB) error {
+		for i := range 200 {
+			id, err := tx.UpsertNote(fmt.Sprintf("n%d.md", i), "Note", "note", "", "h", 1, 1)
+			if err != nil {
+				return err
+			}
+			if err := tx.UpdateFTS(id, "Note", body, "", "", ""); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Little is free yet, so a light pass leaves the file alone.
+	stats, err := db.Optimize(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Vacuumed {
+		t.Errorf("light Optimize vacuumed a compact index: %+v", stats)
+	}
+
+	for i := range 190 {
+		if err := db.DeleteNote(fmt.Sprintf("n%d.md", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stats, err = db.Optimize(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Vacuumed || stats.SizeAfter >= stats.SizeBefore {
+		t.Errorf("light Optimize after deleting most notes = %+v, want a vacuum that shrinks the file", stats)
+	}
+
+	stats, err = db.Optimize(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !stats.Vacuumed {
+		t.Errorf("full Optimize = %+v, want a vacuum", stats)
+	}
+	if results, err := db.Search("lorem", 20); err != nil || len(results) != 10 {
+		t.Errorf("Search after Optimize = %d results, %v; want 10", len(results), err)
+	}
+}
+
+func TestSizeStats(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	// Each transaction flushes its own search segment.
+	body := strings.Repeat("lorem ipsum dolor sit amet ", 200)
+	for i := range 40 {
+		err := db.InTx(func(tx *DB) error {
+			id, err := tx.UpsertNote(fmt.Sprintf("n%d.md", i), "Note", "note", "", "h", 1, 1)
+			if err != nil {
+				return err
+			}
+			return tx.UpdateFTS(id, "Note", body, "", "", "")
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := db.SizeStats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Notes != 40 || s.Size == 0 || s.Search == 0 || s.Search > s.Size || s.Segments < 2 || !s.Incremental {
+		t.Errorf("SizeStats() = %+v", s)
+	}
+
+	if _, err := db.Optimize(true); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = db.SizeStats(); err != nil || s.Segments != 1 || s.Free != 0 {
+		t.Errorf("after a full Optimize: %+v, %v; want one segment and no free pages", s, err)
+	}
+
+	// Too little free for a VACUUM: the light pass gives the pages back
+	// incrementally.
+	for i := range 5 {
+		if err := db.DeleteNote(fmt.Sprintf("n%d.md", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s, err = db.SizeStats(); err != nil || s.Free == 0 {
+		t.Fatalf("after deletes: %+v, %v; want free pages", s, err)
+	}
+	stats, err := db.Optimize(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Vacuumed || stats.SizeAfter >= stats.SizeBefore {
+		t.Errorf("light Optimize = %+v, want an incremental vacuum that shrinks the file", stats)
+	}
+	if s, err = db.SizeStats(); err != nil || s.Free != 0 {
+		t.Errorf("after a light Optimize: %+v, %v; want no free pages", s, err)
+	}
+}
+
+func TestSearchSanitizesInput(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, n := range []struct{ path, content string }{
+		{"plan.md", "Quarterly planning for the budget"},
+		{"draft.md", "Budget draft, not final"},
+	} {
+		id, err := db.UpsertNote(n.path, n.path, n.path, "", "h", 0, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.UpdateFTS(id, n.path, n.content, "", "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"quart", []string{"plan.md"}},
+		{"budget -draft", []string{"plan.md"}},
+		{`"budget draft"`, []string{"draft.md"}},
+		{"quarterly OR final", []string{"draft.md", "plan.md"}},
+		{"budget (", []string{"draft.md", "plan.md"}},
+		{`budget"`, []string{"draft.md", "plan.md"}},
+		{"-budget", nil},
+		{`"`, nil},
+	}
+	for _, tt := range tests {
+		results, err := db.Search(tt.query, 10)
+		if err != nil {
+			t.Errorf("Search(%q): %v", tt.query, err)
+			continue
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.Path)
+		}
+		sort.Strings(got)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
 func TestSearchFiles(t *testing.T) {
 	db, err := OpenMemory()
 	if err != nil {
@@ -52,23 +463,446 @@ func TestSearchFiles(t *testing.T) {
 		}
 	}()
 
-	if _, err := db.UpsertNote("daily/2024-01-01.md", "2024-01-01", "2024-01-01", "", "a", 1000, 10); err != nil {
+	if _, err := db.UpsertNote("daily/2024-01-01.md", "2024-01-01", "2024-01-01", "", "a", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertNote("inbox/note.md", "Quick Note", "quick-note", "", "b", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.SearchFiles("daily", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(results))
+	}
+
+	// Fuzzy subsequence matching: not a substring of anything.
+	results, err = db.SearchFiles("dly0101", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "daily/2024-01-01.md" {
+		t.Fatalf("expected fuzzy match on daily note, got %+v", results)
+	}
+
+	// Multiple terms must all match; title matches count too.
+	results, err = db.SearchFiles("qk nt", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "inbox/note.md" {
+		t.Fatalf("expected multi-term match on inbox note, got %+v", results)
+	}
+}
+
+func TestResolveLink(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := db.UpsertNote("projects/My-Note.md", "My Note", "my-note", "", "a", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertNote("daily/2024-01-01.md", "2024-01-01", "2024-01-01", "", "b", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertNote("root-note.md", "Root Note", "root-note", "", "c", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		basename string
+		want     string
+	}{
+		{"my-note.md", "projects/My-Note.md"},
+		{"MY-NOTE.MD", "projects/My-Note.md"},
+		{"2024-01-01.md", "daily/2024-01-01.md"},
+		{"root-note.md", "root-note.md"},
+		{"nonexistent.md", ""},
+	}
+
+	for _, tt := range tests {
+		got, err := db.ResolveLink(tt.basename)
+		if err != nil {
+			t.Errorf("ResolveLink(%q): %v", tt.basename, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ResolveLink(%q) = %q, want %q", tt.basename, got, tt.want)
+		}
+	}
+
+	// Case-insensitive uniqueness should be enforced at the DB level.
+	if _, err := db.UpsertNote("otherdir/my-note.md", "Dup", "dup", "", "z", 1000, 10); err == nil {
+		t.Fatalf("expected duplicate basename insert to fail")
+	}
+}
+
+func TestResolveLinkLoosely(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := db.UpsertNote("projects/my-note.md", "Something Else", "something-else", "", "a", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertNote("ideas/2024-plan.md", "What's Next?", "whats-next", "", "b", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+	id, err := db.UpsertNote("people/jdoe.md", "J. Doe", "j-doe", "", "c", 1000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertAlias(id, "Jane Doe"); err != nil {
+		t.Fatal(err)
+	}
+	// A title matching another note's basename loses to the basename.
+	if _, err := db.UpsertNote("misc.md", "My Note", "my-note", "", "d", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{"My Note", "projects/my-note.md"},
+		{"my_note.md", "projects/my-note.md"},
+		{"  MY   NOTE ", "projects/my-note.md"},
+		{"what's next?", "ideas/2024-plan.md"},
+		{"Whats Next", "ideas/2024-plan.md"},
+		{"jane doe", "people/jdoe.md"},
+		{"elsewhere/Jane-Doe", "people/jdoe.md"}, // qualifiers only count with folder scope
+		{"Nobody", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		got, err := db.ResolveLinkLoosely(tt.target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("ResolveLinkLoosely(%q) = %q, want %q", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarNotes(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, n := range []struct{ path, title string }{
+		{"receive-notes.md", "Receive Notes"},
+		{"inbox/receiver.md", "Receiver"},
+		{"meetings/weekly.md", "Weekly Sync"},
+		{"todo.md", "Todo"},
+	} {
+		if _, err := db.UpsertNote(n.path, n.title, slugify(n.title), "", n.path, 1000, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		target string
+		want   []string
+	}{
+		{"recieve-notes", []string{"receive-notes.md"}},
+		{"Recieve Notes.md", []string{"receive-notes.md"}},
+		{"recever", []string{"inbox/receiver.md"}},
+		{"weekly snyc", []string{"meetings/weekly.md"}}, // by title
+		{"receive", []string{"inbox/receiver.md", "receive-notes.md"}},
+		{"tood", []string{"todo.md"}},
+		{"unrelated", nil},
+	}
+	for _, tt := range tests {
+		got, err := db.SimilarNotes(tt.target, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("SimilarNotes(%q) = %v, want %v", tt.target, got, tt.want)
+		}
+	}
+}
+
+func TestBacklinks(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	id1, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertNote("projects/b.md", "Note B", "b", "", "b", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Links store basenames
+	if err := db.InsertLink(id1, "b.md", "", "", 5, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// GetBacklinks extracts basename from the target path
+	backlinks, err := db.GetBacklinks("projects/b.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 1 {
+		t.Fatalf("expected 1 backlink, got %d", len(backlinks))
+	}
+	if backlinks[0].SourcePath != "a.md" {
+		t.Errorf("backlink source: got %q, want %q", backlinks[0].SourcePath, "a.md")
+	}
+}
+
+func TestGetOutgoingLinks(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	idA, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idB, err := db.UpsertNote("b.md", "Note B", "b", "", "b", 1000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Resolved link: a -> b (target_id set via direct SQL since InsertLink doesn't set it)
+	if err := db.InsertLink(idA, "b.md", "", "", 3, 0); err != nil {
+		t.Fatal(err)
+	}
+	// Manually resolve the link's target_id
+	if _, err := db.conn.Exec("UPDATE links SET target_id = ? WHERE source_id = ? AND target_path = ?", idB, idA, "b.md"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unresolved link: a -> nonexistent
+	if err := db.InsertLink(idA, "nonexistent.md", "", "", 5, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.GetOutgoingLinks("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 outgoing links, got %d", len(results))
+	}
+
+	// First link: resolved
+	if results[0].TargetTitle != "Note B" {
+		t.Errorf("first link title: got %q, want %q", results[0].TargetTitle, "Note B")
+	}
+	if !results[0].Resolved {
+		t.Error("first link should be resolved")
+	}
+
+	// Second link: unresolved (falls back to target_path)
+	if results[1].TargetTitle != "nonexistent.md" {
+		t.Errorf("second link title: got %q, want %q", results[1].TargetTitle, "nonexistent.md")
+	}
+	if results[1].Resolved {
+		t.Error("second link should not be resolved")
+	}
+}
+
+func TestGetOutgoingLinksEmpty(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.GetOutgoingLinks("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected 0 outgoing links, got %d", len(results))
+	}
+}
+
+func TestGetHeadingsForNote(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	id, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.InsertHeading(id, 1, "Introduction", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertHeading(id, 2, "Details", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertHeading(id, 3, "Sub-details", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.GetHeadingsForNote("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("expected 3 headings, got %d", len(results))
+	}
+
+	if results[0].Text != "Introduction" || results[0].Level != 1 || results[0].Line != 1 {
+		t.Errorf("heading 0: got %+v", results[0])
+	}
+	if results[1].Text != "Details" || results[1].Level != 2 || results[1].Line != 5 {
+		t.Errorf("heading 1: got %+v", results[1])
+	}
+	if results[2].Text != "Sub-details" || results[2].Level != 3 || results[2].Line != 10 {
+		t.Errorf("heading 2: got %+v", results[2])
+	}
+}
+
+func TestGetHeadingsForNoteEmpty(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	if _, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.GetHeadingsForNote("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected 0 headings, got %d", len(results))
+	}
+}
+
+func TestGetHeadingsForNoteNonexistent(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	results, err := db.GetHeadingsForNote("nonexistent.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Fatalf("expected 0 headings for nonexistent note, got %d", len(results))
+	}
+}
+
+func TestGetSectionTasks(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	d1, err := db.UpsertNote("daily/2024-01-01.md", "2024-01-01", "2024-01-01", "", "a", 1000, 10)
+	if err != nil {
 		t.Fatal(err)
 	}
-	if _, err := db.UpsertNote("inbox/note.md", "Quick Note", "quick-note", "", "b", 1000, 10); err != nil {
+	other, err := db.UpsertNote("projects/plan.md", "Plan", "plan", "", "b", 1000, 10)
+	if err != nil {
 		t.Fatal(err)
 	}
 
-	results, err := db.SearchFiles("daily", 10)
+	if err := db.InsertTask(d1, "Exercise", true, "Habits", 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertTask(d1, "Read", false, "habits", 6); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertTask(d1, "Call mom", false, "Todo", 9); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertTask(other, "Exercise", true, "Habits", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.GetSectionTasks("daily/", "Habits")
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(results) != 1 {
-		t.Fatalf("expected 1 result, got %d", len(results))
+	if len(results) != 2 {
+		t.Fatalf("expected 2 tasks, got %d: %+v", len(results), results)
+	}
+	if results[0].Text != "Exercise" || !results[0].Done || results[0].NotePath != "daily/2024-01-01.md" {
+		t.Errorf("task 0: got %+v", results[0])
+	}
+	if results[1].Text != "Read" || results[1].Done {
+		t.Errorf("task 1: got %+v", results[1])
 	}
 }
 
-func TestFindNoteByBasename(t *testing.T) {
+func TestInTx(t *testing.T) {
 	db, err := OpenMemory()
 	if err != nil {
 		t.Fatal(err)
@@ -79,45 +913,40 @@ func TestFindNoteByBasename(t *testing.T) {
 		}
 	}()
 
-	if _, err := db.UpsertNote("projects/My-Note.md", "My Note", "my-note", "", "a", 1000, 10); err != nil {
-		t.Fatal(err)
-	}
-	if _, err := db.UpsertNote("daily/2024-01-01.md", "2024-01-01", "2024-01-01", "", "b", 1000, 10); err != nil {
-		t.Fatal(err)
-	}
-	if _, err := db.UpsertNote("root-note.md", "Root Note", "root-note", "", "c", 1000, 10); err != nil {
-		t.Fatal(err)
-	}
-
-	tests := []struct {
-		basename string
-		want     string
-	}{
-		{"my-note.md", "projects/My-Note.md"},
-		{"MY-NOTE.MD", "projects/My-Note.md"},
-		{"2024-01-01.md", "daily/2024-01-01.md"},
-		{"root-note.md", "root-note.md"},
-		{"nonexistent.md", ""},
-	}
-
-	for _, tt := range tests {
-		got, err := db.FindNoteByBasename(tt.basename)
-		if err != nil {
-			t.Errorf("FindNoteByBasename(%q): %v", tt.basename, err)
-			continue
+	errBoom := errors.New("boom")
+	err = db.InTx(func(tx *DB) error {
+		if _, err := tx.UpsertNote("a.md", "A", "a", "", "h", 1000, 10); err != nil {
+			return err
 		}
-		if got != tt.want {
-			t.Errorf("FindNoteByBasename(%q) = %q, want %q", tt.basename, got, tt.want)
+		// Nested calls join the outer transaction.
+		return tx.InTx(func(inner *DB) error {
+			if _, err := inner.UpsertNote("b.md", "B", "b", "", "h", 1000, 10); err != nil {
+				return err
+			}
+			return errBoom
+		})
+	})
+	if !errors.Is(err, errBoom) {
+		t.Fatalf("InTx() error = %v, want %v", err, errBoom)
+	}
+	for _, path := range []string{"a.md", "b.md"} {
+		if id, err := db.GetNoteIDByPath(path); err != nil || id != 0 {
+			t.Errorf("%s survived the rollback (id %d, err %v)", path, id, err)
 		}
 	}
 
-	// Case-insensitive uniqueness should be enforced at the DB level.
-	if _, err := db.UpsertNote("otherdir/my-note.md", "Dup", "dup", "", "z", 1000, 10); err == nil {
-		t.Fatalf("expected duplicate basename insert to fail")
+	if err := db.InTx(func(tx *DB) error {
+		_, err := tx.UpsertNote("a.md", "A", "a", "", "h", 1000, 10)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := db.GetNoteIDByPath("a.md"); id == 0 {
+		t.Error("committed note not found")
 	}
 }
 
-func TestBacklinks(t *testing.T) {
+func TestGetOpenTasks(t *testing.T) {
 	db, err := OpenMemory()
 	if err != nil {
 		t.Fatal(err)
@@ -128,33 +957,38 @@ func TestBacklinks(t *testing.T) {
 		}
 	}()
 
-	id1, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10)
+	b, err := db.UpsertNote("b.md", "B", "b", "", "b", 1000, 10)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if _, err := db.UpsertNote("projects/b.md", "Note B", "b", "", "b", 1000, 10); err != nil {
+	a, err := db.UpsertNote("a.md", "A", "a", "", "a", 1000, 10)
+	if err != nil {
 		t.Fatal(err)
 	}
-
-	// Links store basenames
-	if err := db.InsertLink(id1, "b.md", "", "", 5, 10); err != nil {
+	if err := db.InsertTask(b, "Write report", false, "", 4); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertTask(a, "Ship it", true, "", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.InsertTask(a, "Book flight", false, "Travel", 7); err != nil {
 		t.Fatal(err)
 	}
 
-	// GetBacklinks extracts basename from the target path
-	backlinks, err := db.GetBacklinks("projects/b.md")
+	results, err := db.GetOpenTasks()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(backlinks) != 1 {
-		t.Fatalf("expected 1 backlink, got %d", len(backlinks))
+	want := []TaskResult{
+		{NotePath: "a.md", Text: "Book flight", Section: "Travel", Line: 7},
+		{NotePath: "b.md", Text: "Write report", Line: 4},
 	}
-	if backlinks[0].SourcePath != "a.md" {
-		t.Errorf("backlink source: got %q, want %q", backlinks[0].SourcePath, "a.md")
+	if !slices.Equal(results, want) {
+		t.Errorf("GetOpenTasks() = %+v, want %+v", results, want)
 	}
 }
 
-func TestGetOutgoingLinks(t *testing.T) {
+func TestListLinkTargets(t *testing.T) {
 	db, err := OpenMemory()
 	if err != nil {
 		t.Fatal(err)
@@ -165,55 +999,120 @@ func TestGetOutgoingLinks(t *testing.T) {
 		}
 	}()
 
-	idA, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10)
+	k8s, err := db.UpsertNote("tech/kubernetes.md", "Kubernetes", "kubernetes", "", "a", 1000, 10)
 	if err != nil {
 		t.Fatal(err)
 	}
-	idB, err := db.UpsertNote("b.md", "Note B", "b", "", "b", 1000, 10)
-	if err != nil {
+	if _, err := db.UpsertNote("alpha.md", "Alpha", "alpha", "", "b", 1000, 10); err != nil {
 		t.Fatal(err)
 	}
-
-	// Resolved link: a -> b (target_id set via direct SQL since InsertLink doesn't set it)
-	if err := db.InsertLink(idA, "b.md", "", "", 3, 0); err != nil {
+	if err := db.InsertAlias(k8s, "k8s"); err != nil {
 		t.Fatal(err)
 	}
-	// Manually resolve the link's target_id
-	if _, err := db.conn.Exec("UPDATE links SET target_id = ? WHERE source_id = ? AND target_path = ?", idB, idA, "b.md"); err != nil {
+	if err := db.InsertAlias(k8s, "kube"); err != nil {
 		t.Fatal(err)
 	}
 
-	// Unresolved link: a -> nonexistent
-	if err := db.InsertLink(idA, "nonexistent.md", "", "", 5, 0); err != nil {
+	results, err := db.ListLinkTargets()
+	if err != nil {
 		t.Fatal(err)
 	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 targets, got %d: %+v", len(results), results)
+	}
+	if results[0].Path != "alpha.md" || len(results[0].Aliases) != 0 {
+		t.Errorf("unexpected first target: %+v", results[0])
+	}
+	if results[1].Title != "Kubernetes" || len(results[1].Aliases) != 2 || results[1].Aliases[0] != "k8s" {
+		t.Errorf("unexpected second target: %+v", results[1])
+	}
 
-	results, err := db.GetOutgoingLinks("a.md")
+	if err := db.ClearNoteAliases(k8s); err != nil {
+		t.Fatal(err)
+	}
+	results, err = db.ListLinkTargets()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(results) != 2 {
-		t.Fatalf("expected 2 outgoing links, got %d", len(results))
+	if len(results[1].Aliases) != 0 {
+		t.Errorf("expected aliases cleared, got %v", results[1].Aliases)
 	}
+}
 
-	// First link: resolved
-	if results[0].TargetTitle != "Note B" {
-		t.Errorf("first link title: got %q, want %q", results[0].TargetTitle, "Note B")
+func TestSearchQuery(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !results[0].Resolved {
-		t.Error("first link should be resolved")
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	notes := []struct {
+		path, title, status, content string
+		tags                         []string
+	}{
+		{"projects/budget.md", "Budget", "draft", "quarterly budget review", []string{"work"}},
+		{"projects/launch.md", "Launch", "done", "launch budget and timeline", []string{"work", "urgent"}},
+		{"personal/home-budget.md", "Home Budget", "draft", "groceries budget", []string{"home"}},
+	}
+	for _, n := range notes {
+		id, err := db.UpsertNote(n.path, n.title, n.title, n.status, "h", 1000, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Insert FTS rows directly: UpdateFTS's delete-before-insert on a fresh
+		// row skews the bm25 document count, which makes rank NULL.
+		if _, err := db.conn.Exec("INSERT INTO notes_fts(rowid, title, content, tags, headings) VALUES(?, ?, ?, '', '')",
+			id, n.title, n.content); err != nil {
+			t.Fatal(err)
+		}
+		for _, tag := range n.tags {
+			tagID, err := db.UpsertTag(tag)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := db.LinkNoteTag(id, tagID); err != nil {
+				t.Fatal(err)
+			}
+		}
 	}
 
-	// Second link: unresolved (falls back to target_path)
-	if results[1].TargetTitle != "nonexistent.md" {
-		t.Errorf("second link title: got %q, want %q", results[1].TargetTitle, "nonexistent.md")
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"budget", []string{"personal/home-budget.md", "projects/budget.md", "projects/launch.md"}},
+		{"budget tag:work", []string{"projects/budget.md", "projects/launch.md"}},
+		{"tag:work tag:urgent", []string{"projects/launch.md"}},
+		{"status:draft", []string{"personal/home-budget.md", "projects/budget.md"}},
+		{"path:projects/ status:DRAFT", []string{"projects/budget.md"}},
+		{"path:personal/ path:projects/launch", []string{"personal/home-budget.md", "projects/launch.md"}},
+		{"hmbdg status:draft", []string{"personal/home-budget.md"}}, // fuzzy fallback keeps filters
+		{"tag:missing", nil},
 	}
-	if results[1].Resolved {
-		t.Error("second link should not be resolved")
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, err := db.SearchQuery(ParseQuery(tt.query), 50)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Path)
+			}
+			sort.Strings(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
 	}
 }
 
-func TestGetOutgoingLinksEmpty(t *testing.T) {
+func TestSearchQueryArchived(t *testing.T) {
 	db, err := OpenMemory()
 	if err != nil {
 		t.Fatal(err)
@@ -224,20 +1123,108 @@ func TestGetOutgoingLinksEmpty(t *testing.T) {
 		}
 	}()
 
-	if _, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10); err != nil {
+	for _, path := range []string{"plan.md", "archive/old-plan.md", "archive/2025/older-plan.md", "archived-ideas.md"} {
+		id, err := db.UpsertNote(path, "Plan", "Plan", "", "h", 1000, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.conn.Exec("INSERT INTO notes_fts(rowid, title, content, tags, headings) VALUES(?, ?, 'plan', '', '')", id, "Plan"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"plan", []string{"archived-ideas.md", "plan.md"}},
+		{"plan is:archived", []string{"archive/2025/older-plan.md", "archive/old-plan.md"}},
+		{"path:archive/2025", []string{"archive/2025/older-plan.md"}},
+		{"is:archived", []string{"archive/2025/older-plan.md", "archive/old-plan.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := ParseQuery(tt.query)
+			q.ArchiveDir = "archive"
+			results, err := db.SearchQuery(q, 50)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Path)
+			}
+			sort.Strings(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Without an archive folder nothing is hidden, and nothing is archived.
+	if results, err := db.SearchQuery(ParseQuery("plan"), 50); err != nil || len(results) != 4 {
+		t.Errorf("plan without ArchiveDir = %d results, %v; want 4", len(results), err)
+	}
+	if results, err := db.SearchQuery(ParseQuery("is:archived"), 50); err != nil || len(results) != 0 {
+		t.Errorf("is:archived without ArchiveDir = %d results, %v; want none", len(results), err)
+	}
+}
+
+func TestSearchTitleMatches(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
 		t.Fatal(err)
 	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
 
-	results, err := db.GetOutgoingLinks("a.md")
+	id, err := db.UpsertNote("projects/home-budget.md", "Home Budget", "Home Budget", "", "h", 1000, 10)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(results) != 0 {
-		t.Fatalf("expected 0 outgoing links, got %d", len(results))
+	if _, err := db.conn.Exec("INSERT INTO notes_fts(rowid, title, content, tags, headings) VALUES(?, ?, ?, '', '')",
+		id, "Home Budget", "weekly groceries"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query       string
+		want        []int
+		wantSnippet string
+	}{
+		{"budget", []int{5, 6, 7, 8, 9, 10}, ""}, // FTS highlight
+		{"hmbdg", []int{0, 2, 5, 7, 8}, ""},      // fuzzy fallback
+		{"groceries", nil, "weekly groceries"},   // content-only match
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, err := db.SearchQuery(ParseQuery(tt.query), 50)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != 1 {
+				t.Fatalf("got %d results, want 1", len(results))
+			}
+			if results[0].Title != "Home Budget" {
+				t.Errorf("Title = %q, want %q", results[0].Title, "Home Budget")
+			}
+			if !slices.Equal(results[0].TitleMatches, tt.want) {
+				t.Errorf("TitleMatches = %v, want %v", results[0].TitleMatches, tt.want)
+			}
+			if results[0].Snippet != tt.wantSnippet {
+				t.Errorf("Snippet = %q, want %q", results[0].Snippet, tt.wantSnippet)
+			}
+			if tt.wantSnippet != "" && !slices.Equal(results[0].SnippetMatches, []int{7, 8, 9, 10, 11, 12, 13, 14, 15}) {
+				t.Errorf("SnippetMatches = %v", results[0].SnippetMatches)
+			}
+		})
 	}
 }
 
-func TestGetHeadingsForNote(t *testing.T) {
+func TestSearchQueryModified(t *testing.T) {
 	db, err := OpenMemory()
 	if err != nil {
 		t.Fatal(err)
@@ -248,41 +1235,112 @@ func TestGetHeadingsForNote(t *testing.T) {
 		}
 	}()
 
-	id, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10)
+	if _, err := db.UpsertNote("old.md", "Old", "old", "", "h", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.UpsertNote("fresh.md", "Fresh", "fresh", "", "h", time.Now().Add(-48*time.Hour).Unix(), 10); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.SearchQuery(ParseQuery("modified:7d"), 50)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(results) != 1 || results[0].Path != "fresh.md" {
+		t.Errorf("got %+v, want only fresh.md", results)
+	}
 
-	if err := db.InsertHeading(id, 1, "Introduction", 1); err != nil {
+	results, err = db.SearchQuery(ParseQuery("modified:>7d"), 50)
+	if err != nil {
 		t.Fatal(err)
 	}
-	if err := db.InsertHeading(id, 2, "Details", 5); err != nil {
+	if len(results) != 1 || results[0].Path != "old.md" {
+		t.Errorf("older than 7d: got %+v, want only old.md", results)
+	}
+
+	// old.md's mod time (1000s after the epoch) is before 1970-01-03 in any
+	// time zone.
+	results, err = db.SearchQuery(ParseQuery("modified:..1970-01-02"), 50)
+	if err != nil {
 		t.Fatal(err)
 	}
-	if err := db.InsertHeading(id, 3, "Sub-details", 10); err != nil {
+	if len(results) != 1 || results[0].Path != "old.md" {
+		t.Errorf("range: got %+v, want only old.md", results)
+	}
+
+	results, err = db.ListNotesByModTime(0)
+	if err != nil {
 		t.Fatal(err)
 	}
+	if len(results) != 2 || results[0].Path != "fresh.md" || results[1].ModTime.Unix() != 1000 {
+		t.Errorf("ListNotesByModTime() = %+v, want fresh.md then old.md", results)
+	}
+}
 
-	results, err := db.GetHeadingsForNote("a.md")
+func TestSearchQueryAuthoredDates(t *testing.T) {
+	db, err := OpenMemory()
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(results) != 3 {
-		t.Fatalf("expected 3 headings, got %d", len(results))
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 12, 0, 0, 0, time.Local) }
+	now := time.Now().Unix()
+	for _, n := range []struct {
+		path             string
+		created, updated time.Time
+	}{
+		{"journal.md", day(2026, 1, 31), day(2026, 2, 3)},
+		{"plan.md", day(2025, 6, 1), time.Time{}},
+		{"undated.md", time.Time{}, time.Time{}},
+	} {
+		id, err := db.UpsertNote(n.path, n.path, n.path, "", "h", now, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetNoteDates(id, n.created, n.updated, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
 	}
 
-	if results[0].Text != "Introduction" || results[0].Level != 1 || results[0].Line != 1 {
-		t.Errorf("heading 0: got %+v", results[0])
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"created:2026-01-31", []string{"journal.md"}},
+		{"created:<2026-01-01", []string{"plan.md"}},
+		{"created:>1d", []string{"journal.md", "plan.md"}}, // undated notes never match created:
+		{"updated:2026-02-03", []string{"journal.md"}},
+		{"updated:1d", []string{"plan.md", "undated.md"}}, // falls back to the file's mod time
 	}
-	if results[1].Text != "Details" || results[1].Level != 2 || results[1].Line != 5 {
-		t.Errorf("heading 1: got %+v", results[1])
+	for _, tt := range tests {
+		results, err := db.SearchQuery(ParseQuery(tt.query), 50)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range results {
+			got = append(got, r.Path)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
 	}
-	if results[2].Text != "Sub-details" || results[2].Level != 3 || results[2].Line != 10 {
-		t.Errorf("heading 2: got %+v", results[2])
+
+	results, err := db.SearchQuery(ParseQuery("path:journal"), 50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].Created.Equal(day(2026, 1, 31)) || !results[0].Modified().Equal(day(2026, 2, 3)) {
+		t.Errorf("journal.md dates = %+v", results)
 	}
 }
 
-func TestGetHeadingsForNoteEmpty(t *testing.T) {
+func TestStaleNotes(t *testing.T) {
 	db, err := OpenMemory()
 	if err != nil {
 		t.Fatal(err)
@@ -293,20 +1351,54 @@ func TestGetHeadingsForNoteEmpty(t *testing.T) {
 		}
 	}()
 
-	if _, err := db.UpsertNote("a.md", "Note A", "a", "", "a", 1000, 10); err != nil {
+	now := time.Now()
+	old := now.AddDate(0, 0, -100).Unix()
+	for _, n := range []struct {
+		path, status string
+		modTime      int64
+		reviewed     time.Time
+	}{
+		{"older.md", "", old - 10, time.Time{}},
+		{"old.md", "", old, time.Time{}},
+		{"snoozed.md", "", old, now.AddDate(0, 0, -1)},
+		{"done.md", "archived", old, time.Time{}},
+		{"archive/kept.md", "", old, time.Time{}},
+		{"fresh.md", "", now.Unix(), time.Time{}},
+	} {
+		id, err := db.UpsertNote(n.path, n.path, n.path, n.status, "h", n.modTime, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.SetNoteDates(id, time.Time{}, time.Time{}, n.reviewed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := db.StaleNotes(now.AddDate(0, 0, -90), "archive/", 50)
+	if err != nil {
 		t.Fatal(err)
 	}
+	var got []string
+	for _, r := range results {
+		got = append(got, r.Path)
+	}
+	if want := []string{"older.md", "old.md"}; !slices.Equal(got, want) {
+		t.Errorf("StaleNotes = %v, want %v", got, want)
+	}
+	if results[1].Touched.Unix() != old {
+		t.Errorf("Touched = %v, want %v", results[1].Touched, time.Unix(old, 0))
+	}
 
-	results, err := db.GetHeadingsForNote("a.md")
+	results, err = db.StaleNotes(now.AddDate(0, 0, -90), "", 50)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(results) != 0 {
-		t.Fatalf("expected 0 headings, got %d", len(results))
+	if len(results) != 3 {
+		t.Errorf("StaleNotes without skipDir = %+v, want 3 notes", results)
 	}
 }
 
-func TestGetHeadingsForNoteNonexistent(t *testing.T) {
+func TestSetTokenizerTrigram(t *testing.T) {
 	db, err := OpenMemory()
 	if err != nil {
 		t.Fatal(err)
@@ -317,11 +1409,58 @@ func TestGetHeadingsForNoteNonexistent(t *testing.T) {
 		}
 	}()
 
-	results, err := db.GetHeadingsForNote("nonexistent.md")
+	if rebuilt, err := db.SetTokenizer(TokenizerDefault); err != nil || rebuilt {
+		t.Fatalf("SetTokenizer(default) on a new db = %v, %v; want no rebuild", rebuilt, err)
+	}
+	if _, err := db.SetTokenizer("klingon"); err == nil {
+		t.Error("unknown tokenizer should be rejected")
+	}
+	rebuilt, err := db.SetTokenizer(TokenizerTrigram)
+	if err != nil || !rebuilt {
+		t.Fatalf("SetTokenizer(trigram) = %v, %v; want rebuild", rebuilt, err)
+	}
+	if rebuilt, _ := db.SetTokenizer(TokenizerTrigram); rebuilt {
+		t.Error("setting the same tokenizer again should not rebuild")
+	}
+
+	id, err := db.UpsertNote("tokyo.md", "旅行", "tokyo", "", "h", 0, 0)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if len(results) != 0 {
-		t.Fatalf("expected 0 headings for nonexistent note, got %d", len(results))
+	if err := db.UpdateFTS(id, "旅行", "東京の美術館に行きました", "", "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.Search("美術館", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "tokyo.md" {
+		t.Errorf("trigram search = %+v, want tokyo.md", results)
+	}
+}
+
+// BenchmarkSearch runs finder queries against a 500-note index: plain
+// words, a prefix being typed and a filtered query.
+func BenchmarkSearch(b *testing.B) {
+	root := writeBenchVault(b, 500)
+	db, err := OpenMemory()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	if _, err := NewIndexer(db, root).Rebuild(nil); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, query := range []string{"quick fox", "foll", "tag:project lazy"} {
+		b.Run(query, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := db.SearchQuery(ParseQuery(query), 50); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
 	}
 }
@@ -0,0 +1,173 @@
+package index
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+// maxOpenHistory bounds note_opens; the oldest opens are dropped past it.
+const maxOpenHistory = 10000
+
+// RecordVisit counts an open of the note at path and adds it to the open
+// history. Notes that aren't indexed yet are ignored; they start
+// accumulating visits once indexed.
+func (db *DB) RecordVisit(path string, at time.Time) error {
+	return db.InTx(func(tx *DB) error {
+		res, err := tx.q.Exec(`
+			INSERT INTO note_visits (note_id, open_count, last_opened)
+			SELECT id, 1, ? FROM notes WHERE path = ?
+			ON CONFLICT(note_id) DO UPDATE SET
+				open_count = open_count + 1,
+				last_opened = excluded.last_opened
+		`, at.Unix(), path)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return err
+		}
+		if _, err := tx.q.Exec(`
+			INSERT INTO note_opens (note_id, opened_at)
+			SELECT id, ? FROM notes WHERE path = ?
+		`, at.Unix(), path); err != nil {
+			return err
+		}
+		_, err = tx.q.Exec(`
+			DELETE FROM note_opens
+			WHERE rowid <= (SELECT max(rowid) FROM note_opens) - ?
+		`, maxOpenHistory)
+		return err
+	})
+}
+
+// RecentNotes returns the notes opened most recently, newest first, each
+// once. Notes never opened are left out.
+func (db *DB) RecentNotes(limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+	rows, err := db.q.Query(`
+		SELECT n.id, n.path, n.title, n.summary, n.mod_time, n.created, n.updated
+		FROM note_opens o
+		JOIN notes n ON n.id = o.note_id
+		GROUP BY n.id
+		ORDER BY max(o.opened_at) DESC, max(o.rowid) DESC
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var modTime, created, updated int64
+		if err := rows.Scan(&r.ID, &r.Path, &r.Title, &r.Summary, &modTime, &created, &updated); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		r.ModTime = time.Unix(modTime, 0)
+		r.Created, r.Updated = timeOrZero(created), timeOrZero(updated)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	return results, rows.Close()
+}
+
+// FrecencyScore weighs how often a note was opened by how recently, in the
+// spirit of Firefox's frecency: recent opens count for much more than old ones.
+func FrecencyScore(openCount int, lastOpened, now time.Time) float64 {
+	if openCount <= 0 {
+		return 0
+	}
+	age := now.Sub(lastOpened)
+	var weight float64
+	switch {
+	case age < 4*24*time.Hour:
+		weight = 100
+	case age < 14*24*time.Hour:
+		weight = 70
+	case age < 31*24*time.Hour:
+		weight = 50
+	case age < 90*24*time.Hour:
+		weight = 30
+	default:
+		weight = 10
+	}
+	return float64(openCount) * weight
+}
+
+// ListRecentNotes returns notes that have been opened, best frecency first.
+// Rank holds the negated score so lower is better, as with Search.
+func (db *DB) ListRecentNotes(now time.Time, limit int) ([]SearchResult, error) {
+	return db.listByFrecency(now, limit, true)
+}
+
+// ListNotesByFrecency returns all notes with opened ones first by frecency
+// and the rest in path order.
+func (db *DB) ListNotesByFrecency(now time.Time, limit int) ([]SearchResult, error) {
+	return db.listByFrecency(now, limit, false)
+}
+
+func (db *DB) listByFrecency(now time.Time, limit int, visitedOnly bool) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 200
+	}
+
+	join := "LEFT JOIN"
+	if visitedOnly {
+		join = "JOIN"
+	}
+	rows, err := db.q.Query(`
+		SELECT n.id, n.path, n.title, n.summary, n.mod_time, n.created, n.updated, COALESCE(v.open_count, 0), COALESCE(v.last_opened, 0)
+		FROM notes n
+		` + join + ` note_visits v ON v.note_id = n.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	type scored struct {
+		r    SearchResult
+		last int64
+	}
+	var all []scored
+	for rows.Next() {
+		var s scored
+		var count int
+		var modTime, created, updated int64
+		if err := rows.Scan(&s.r.ID, &s.r.Path, &s.r.Title, &s.r.Summary, &modTime, &created, &updated, &count, &s.last); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		s.r.ModTime = time.Unix(modTime, 0)
+		s.r.Created, s.r.Updated = timeOrZero(created), timeOrZero(updated)
+		s.r.Rank = -FrecencyScore(count, time.Unix(s.last, 0), now)
+		all = append(all, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].r.Rank != all[j].r.Rank {
+			return all[i].r.Rank < all[j].r.Rank
+		}
+		if all[i].last != all[j].last {
+			return all[i].last > all[j].last
+		}
+		return all[i].r.Path < all[j].r.Path
+	})
+	if len(all) > limit {
+		all = all[:limit]
+	}
+
+	results := make([]SearchResult, len(all))
+	for i, s := range all {
+		results[i] = s.r
+	}
+	return results, nil
+}
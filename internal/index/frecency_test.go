@@ -0,0 +1,160 @@
+package index
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestFrecencyScore(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		count int
+		age   time.Duration
+		want  float64
+	}{
+		{"never opened", 0, 0, 0},
+		{"today", 2, time.Hour, 200},
+		{"last week", 2, 7 * 24 * time.Hour, 140},
+		{"last month", 2, 20 * 24 * time.Hour, 100},
+		{"this quarter", 2, 60 * 24 * time.Hour, 60},
+		{"long ago", 2, 365 * 24 * time.Hour, 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FrecencyScore(tt.count, now.Add(-tt.age), now); got != tt.want {
+				t.Errorf("FrecencyScore = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListByFrecency(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, p := range []string{"a.md", "b.md", "c.md", "d.md"} {
+		if _, err := db.UpsertNote(p, p, p, "", "h", 1000, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	visit := func(path string, at time.Time, n int) {
+		t.Helper()
+		for range n {
+			if err := db.RecordVisit(path, at); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	visit("c.md", now.Add(-200*24*time.Hour), 5) // 5 old opens: 50
+	visit("b.md", now.Add(-time.Hour), 1)        // 1 recent open: 100
+	visit("d.md", now.Add(-2*time.Hour), 1)      // same score as b, older
+	visit("missing.md", now, 3)                  // not indexed: ignored
+
+	recent, err := db.ListRecentNotes(now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPaths(t, recent, []string{"b.md", "d.md", "c.md"})
+
+	all, err := db.ListNotesByFrecency(now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPaths(t, all, []string{"b.md", "d.md", "c.md", "a.md"})
+
+	// Deleting a note drops its visits.
+	if err := db.DeleteNote("b.md"); err != nil {
+		t.Fatal(err)
+	}
+	recent, err = db.ListRecentNotes(now, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertPaths(t, recent, []string{"d.md", "c.md"})
+}
+
+func assertPaths(t *testing.T, results []SearchResult, want []string) {
+	t.Helper()
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %v", len(results), want)
+	}
+	for i, r := range results {
+		if r.Path != want[i] {
+			t.Errorf("[%d] got %q, want %q", i, r.Path, want[i])
+		}
+	}
+}
+
+func TestRecentNotes(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	for _, p := range []string{"a.md", "b.md", "c.md"} {
+		if _, err := db.UpsertNote(p, p, p, "", "h", 1000, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i, p := range []string{"a.md", "b.md", "a.md", "c.md", "missing.md"} {
+		if err := db.RecordVisit(p, now.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var opens int
+	if err := db.conn.QueryRow("SELECT count(*) FROM note_opens").Scan(&opens); err != nil {
+		t.Fatal(err)
+	}
+	if opens != 4 {
+		t.Errorf("recorded %d opens, want 4", opens)
+	}
+
+	recent, err := db.RecentNotes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range recent {
+		got = append(got, r.Path)
+	}
+	if want := []string{"c.md", "a.md", "b.md"}; !slices.Equal(got, want) {
+		t.Errorf("RecentNotes = %v, want %v", got, want)
+	}
+
+	recent, err = db.RecentNotes(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 || recent[0].Path != "c.md" {
+		t.Errorf("RecentNotes(1) = %v, want [c.md]", recent)
+	}
+
+	if err := db.DeleteNote("c.md"); err != nil {
+		t.Fatal(err)
+	}
+	recent, err = db.RecentNotes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 2 || recent[0].Path != "a.md" {
+		t.Errorf("after delete, RecentNotes = %v, want a.md first of 2", recent)
+	}
+}
@@ -0,0 +1,164 @@
+package index
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Scoring weights for FuzzyMatch. Loosely modelled on fzf's v1 algorithm:
+// every matched rune scores, runes that start a word or path segment earn a
+// bonus, consecutive runes compound, and gaps between matches cost a little.
+const (
+	scoreMatch        = 16
+	scoreGapStart     = -3
+	scoreGapExtension = -1
+
+	bonusBoundary        = 8  // after space, '-', '_', '.'
+	bonusPathSegment     = 10 // after '/'
+	bonusCamel           = 7  // lower→upper transition
+	bonusFirstChar       = 10 // match on the very first rune
+	bonusConsecutive     = 4
+	bonusFirstCharFactor = 2 // multiplier for the bonus on the pattern's first rune
+)
+
+// FuzzyMatch reports whether pattern matches text as a case-insensitive
+// subsequence and, if so, returns a score (higher is better) and the rune
+// offsets of the matched characters in text.
+//
+// The matcher first finds the earliest subsequence, then walks backwards from
+// its end to find the tightest window, so "dlynt" against "daily-notes.md"
+// prefers adjacent matches over scattered ones.
+func FuzzyMatch(pattern, text string) (score int, positions []int, ok bool) {
+	if pattern == "" {
+		return 0, nil, true
+	}
+
+	pat := lowerRunes(pattern)
+	runes := []rune(text)
+	lower := lowerRunes(text)
+
+	// Forward pass: earliest end of a subsequence match.
+	pi := 0
+	end := -1
+	for i, r := range lower {
+		if r == pat[pi] {
+			pi++
+			if pi == len(pat) {
+				end = i
+				break
+			}
+		}
+	}
+	if end < 0 {
+		return 0, nil, false
+	}
+
+	// Backward pass: latest start that still matches, giving the tightest window.
+	pi = len(pat) - 1
+	start := end
+	for i := end; i >= 0; i-- {
+		if lower[i] == pat[pi] {
+			pi--
+			if pi < 0 {
+				start = i
+				break
+			}
+		}
+	}
+
+	// Score the window left-to-right, preferring boundary positions.
+	positions = make([]int, 0, len(pat))
+	pi = 0
+	consecutive := 0
+	inGap := false
+	for i := start; i <= end && pi < len(pat); i++ {
+		if lower[i] != pat[pi] {
+			if inGap {
+				score += scoreGapExtension
+			} else if len(positions) > 0 {
+				score += scoreGapStart
+				inGap = true
+			}
+			consecutive = 0
+			continue
+		}
+
+		bonus := positionBonus(runes, i)
+		if pi == 0 {
+			bonus *= bonusFirstCharFactor
+		}
+		if consecutive > 0 {
+			bonus += bonusConsecutive * consecutive
+		}
+		score += scoreMatch + bonus
+		positions = append(positions, i)
+		consecutive++
+		inGap = false
+		pi++
+	}
+
+	// Prefer shorter candidates when everything else is equal.
+	score -= len(runes) / 16
+
+	return score, positions, true
+}
+
+// positionBonus returns the bonus for a match at runes[i] based on the
+// preceding character.
+func positionBonus(runes []rune, i int) int {
+	if i == 0 {
+		return bonusFirstChar
+	}
+	prev, cur := runes[i-1], runes[i]
+	switch {
+	case prev == '/':
+		return bonusPathSegment
+	case prev == ' ' || prev == '-' || prev == '_' || prev == '.':
+		return bonusBoundary
+	case unicode.IsLower(prev) && unicode.IsUpper(cur):
+		return bonusCamel
+	case !unicode.IsLetter(prev) && !unicode.IsDigit(prev) && (unicode.IsLetter(cur) || unicode.IsDigit(cur)):
+		return bonusBoundary
+	}
+	return 0
+}
+
+// lowerRunes lowercases s rune by rune so offsets stay aligned with []rune(s)
+// (strings.ToLower may change the rune count).
+func lowerRunes(s string) []rune {
+	buf := make([]rune, 0, utf8.RuneCountInString(s))
+	for _, r := range s {
+		buf = append(buf, unicode.ToLower(r))
+	}
+	return buf
+}
+
+// EditDistance returns the case-insensitive optimal string alignment
+// distance between a and b: the number of rune insertions, deletions,
+// substitutions and adjacent transpositions turning one into the other.
+// Unlike FuzzyMatch it tolerates typos such as "recieve" for "receive".
+func EditDistance(a, b string) int {
+	ra, rb := lowerRunes(a), lowerRunes(b)
+	// Three rolling rows: two back (for transpositions), previous, current.
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,81 @@
+package index
+
+import "testing"
+
+func TestFuzzyMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		want    bool
+	}{
+		{"dlynt", "daily-notes.md", true},
+		{"DLY", "daily-notes.md", true},
+		{"", "anything", true},
+		{"notes", "daily-notes.md", true},
+		{"xyz", "daily-notes.md", false},
+		{"tn", "daily-notes.md", false}, // order matters
+	}
+
+	for _, tt := range tests {
+		_, _, ok := FuzzyMatch(tt.pattern, tt.text)
+		if ok != tt.want {
+			t.Errorf("FuzzyMatch(%q, %q) ok = %v, want %v", tt.pattern, tt.text, ok, tt.want)
+		}
+	}
+}
+
+func TestFuzzyMatchPositions(t *testing.T) {
+	_, pos, ok := FuzzyMatch("dn", "daily-notes.md")
+	if !ok {
+		t.Fatal("expected match")
+	}
+	want := []int{0, 6}
+	if len(pos) != len(want) || pos[0] != want[0] || pos[1] != want[1] {
+		t.Errorf("positions = %v, want %v", pos, want)
+	}
+}
+
+func TestFuzzyMatchRanking(t *testing.T) {
+	tests := []struct {
+		pattern string
+		better  string
+		worse   string
+	}{
+		// Consecutive beats scattered.
+		{"note", "notes.md", "n-o-t-e.md"},
+		// Word/segment boundaries beat mid-word matches.
+		{"dn", "daily-notes.md", "addenda.md"},
+		{"pn", "projects/notes.md", "happenings.md"},
+	}
+
+	for _, tt := range tests {
+		better, _, ok1 := FuzzyMatch(tt.pattern, tt.better)
+		worse, _, ok2 := FuzzyMatch(tt.pattern, tt.worse)
+		if !ok1 || !ok2 {
+			t.Fatalf("%q: expected both candidates to match", tt.pattern)
+		}
+		if better <= worse {
+			t.Errorf("%q: score(%q)=%d should beat score(%q)=%d", tt.pattern, tt.better, better, tt.worse, worse)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"receive", "receive", 0},
+		{"recieve", "receive", 1}, // transposition
+		{"Receive", "receive", 0},
+		{"recive", "receive", 1},
+		{"kitten", "sitting", 3},
+		{"", "abc", 3},
+		{"über", "uber", 1},
+	}
+	for _, tt := range tests {
+		if got := EditDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("EditDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package index
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Graph is the vault's link graph: every note, and an edge for each pair of
+// notes where one links to the other.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a note in the link graph.
+type GraphNode struct {
+	Path  string `json:"path"`
+	Title string `json:"title"`
+}
+
+// GraphEdge is a link from one note to another. Count is how many links
+// the source has to the target.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Count  int    `json:"count"`
+}
+
+// LinkGraph returns the link graph between indexed notes, ordered by path.
+// Links whose target matches no note are left out.
+func (db *DB) LinkGraph() (Graph, error) {
+	// Empty, not nil, so an empty vault encodes as [] in JSON.
+	g := Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	rows, err := db.q.Query(`SELECT path, title FROM notes ORDER BY path`)
+	if err != nil {
+		return g, err
+	}
+	for rows.Next() {
+		var n GraphNode
+		if err := rows.Scan(&n.Path, &n.Title); err != nil {
+			return g, errors.Join(err, rows.Close())
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+	if err := rows.Err(); err != nil {
+		return g, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return g, err
+	}
+
+	rows, err = db.q.Query(`
+		SELECT s.path, t.path, COUNT(*)
+		FROM links l
+		JOIN notes s ON s.id = l.source_id
+		JOIN notes t ON t.id = l.target_id
+		GROUP BY s.path, t.path
+		ORDER BY s.path, t.path
+	`)
+	if err != nil {
+		return g, err
+	}
+	for rows.Next() {
+		var e GraphEdge
+		if err := rows.Scan(&e.Source, &e.Target, &e.Count); err != nil {
+			return g, errors.Join(err, rows.Close())
+		}
+		g.Edges = append(g.Edges, e)
+	}
+	if err := rows.Err(); err != nil {
+		return g, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return g, err
+	}
+	return g, nil
+}
+
+// Neighbor is a note linked to or from another note.
+type Neighbor struct {
+	Path  string
+	Title string
+	Out   bool // the note links to it
+	In    bool // it links to the note
+}
+
+// Neighbors returns the notes relPath links to or is linked from, ordered
+// by title. A note linking to itself is not its own neighbor.
+func (db *DB) Neighbors(relPath string) ([]Neighbor, error) {
+	rows, err := db.q.Query(`
+		WITH self AS (SELECT id FROM notes WHERE path = ?),
+		edges(id, out) AS (
+			SELECT l.target_id, 1 FROM links l, self
+			WHERE l.source_id = self.id AND l.target_id IS NOT NULL
+			UNION
+			SELECT l.source_id, 0 FROM links l, self
+			WHERE l.target_id = self.id
+		)
+		SELECT n.path, n.title, MAX(e.out), MIN(e.out) = 0
+		FROM edges e
+		JOIN notes n ON n.id = e.id
+		WHERE n.id NOT IN (SELECT id FROM self)
+		GROUP BY n.id
+		ORDER BY n.title COLLATE NOCASE, n.path
+	`, relPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []Neighbor
+	for rows.Next() {
+		var n Neighbor
+		if err := rows.Scan(&n.Path, &n.Title, &n.Out, &n.In); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		results = append(results, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// WriteDOT writes g as a Graphviz digraph. Notes are identified by path
+// and labelled with their title; an edge carrying several links gets that
+// count as its weight.
+func (g Graph) WriteDOT(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph kopr {")
+	for _, n := range g.Nodes {
+		label := n.Title
+		if label == "" {
+			label = n.Path
+		}
+		fmt.Fprintf(bw, "  %s [label=%s];\n", dotQuote(n.Path), dotQuote(label))
+	}
+	for _, e := range g.Edges {
+		if e.Count > 1 {
+			fmt.Fprintf(bw, "  %s -> %s [weight=%d];\n", dotQuote(e.Source), dotQuote(e.Target), e.Count)
+		} else {
+			fmt.Fprintf(bw, "  %s -> %s;\n", dotQuote(e.Source), dotQuote(e.Target))
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote returns s as a DOT quoted string.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -2,12 +2,17 @@ package index
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/vault"
 )
 
 // Indexer manages the note indexing pipeline.
@@ -15,6 +20,9 @@ type Indexer struct {
 	db        *DB
 	parser    *markdown.Parser
 	vaultRoot string
+	skipDir   string // vault-relative directory left out of the index
+	ignore    *vault.Ignore
+	sources   vault.Sources
 }
 
 func NewIndexer(db *DB, vaultRoot string) *Indexer {
@@ -25,18 +33,279 @@ func NewIndexer(db *DB, vaultRoot string) *Indexer {
 	}
 }
 
+// SetSkipDir leaves the vault-relative directory dir (e.g. templates) out of
+// the index. Empty indexes everything.
+func (idx *Indexer) SetSkipDir(dir string) {
+	if dir != "" {
+		dir = filepath.Clean(dir)
+	}
+	idx.skipDir = dir
+}
+
+// SetIgnore leaves the vault paths matched by ig, the vault's IgnoreFile,
+// out of the index. Notes already indexed there are dropped by the next
+// IndexAll.
+func (idx *Indexer) SetIgnore(ig *vault.Ignore) {
+	idx.ignore = ig
+}
+
+// SetSources indexes the notes of read-only external sources along with
+// the vault's, under their virtual paths (see vault.ExternalDir).
+func (idx *Indexer) SetSources(sources vault.Sources) {
+	idx.sources = sources
+}
+
+// relPath returns the path a file is indexed under: its virtual path if it
+// lies in an external source, otherwise its path relative to the vault.
+func (idx *Indexer) relPath(absPath string) string {
+	if rel, ok := idx.sources.Rel(absPath); ok {
+		return rel
+	}
+	rel, err := filepath.Rel(idx.vaultRoot, absPath)
+	if err != nil {
+		return absPath
+	}
+	return rel
+}
+
+// skipped reports whether relPath, a directory when isDir, lies in the
+// skipped directory or is ignored by the vault's IgnoreFile.
+func (idx *Indexer) skipped(relPath string, isDir bool) bool {
+	if idx.skipDir != "" &&
+		(relPath == idx.skipDir || strings.HasPrefix(relPath, idx.skipDir+string(filepath.Separator))) {
+		return true
+	}
+	return !vault.IsExternal(relPath) && idx.ignore.Ignored(relPath, isDir)
+}
+
+// indexBatchSize is the number of files IndexAll writes per transaction.
+// One transaction per file makes SQLite sync the journal for every note,
+// which dominates the initial index of a large vault.
+const indexBatchSize = 500
+
 // IndexAll performs a full index of all markdown files in the vault.
 func (idx *Indexer) IndexAll() error {
-	// Clear links and hashes so all files get fully re-indexed.
-	// Links are derived data rebuilt from source on each IndexFile call.
-	if _, err := idx.db.Conn().Exec("DELETE FROM links"); err != nil {
-		return fmt.Errorf("clear links: %w", err)
+	return idx.IndexAllWithProgress(nil)
+}
+
+// IndexAllWithProgress is IndexAll, calling progress (if not nil) after each
+// file with the number of files done so far and the total. progress runs on
+// the calling goroutine.
+func (idx *Indexer) IndexAllWithProgress(progress func(done, total int)) error {
+	_, err := idx.Rebuild(progress)
+	return err
+}
+
+// IndexStats summarizes an index run.
+type IndexStats struct {
+	Files       int // markdown files found in the vault and its sources
+	Indexed     int // files parsed and written to the index
+	Removed     int // notes dropped because their file is gone
+	Links       int // links in the index afterwards
+	Attachments int // other files found in the vault
+	Encrypted   int // files skipped because they are still encrypted
+}
+
+// Rebuild is IndexAllWithProgress, also reporting what it did.
+func (idx *Indexer) Rebuild(progress func(done, total int)) (IndexStats, error) {
+	return idx.indexVault(true, progress)
+}
+
+// Update brings the index up to date without a full rebuild: only files whose
+// content changed since they were indexed are parsed and written, and notes
+// whose file is gone are removed. progress is as for IndexAllWithProgress.
+func (idx *Indexer) Update(progress func(done, total int)) (IndexStats, error) {
+	return idx.indexVault(false, progress)
+}
+
+// indexVault indexes every markdown file in the vault and its external
+// sources. With full set it
+// first clears links and hashes so every file is re-indexed; otherwise files
+// whose hash matches the index are skipped without parsing.
+func (idx *Indexer) indexVault(full bool, progress func(done, total int)) (IndexStats, error) {
+	var stats IndexStats
+	paths, attachments, err := idx.vaultFiles()
+	if err != nil {
+		return stats, err
+	}
+	external, err := idx.sourceFiles()
+	if err != nil {
+		return stats, err
+	}
+	paths = append(paths, external...)
+	stats.Files = len(paths)
+	stats.Attachments = len(attachments)
+
+	var known map[string]string
+	err = idx.db.InTx(func(tx *DB) error {
+		if full {
+			// Clear links and hashes so all files get fully re-indexed.
+			// Links are derived data rebuilt from source on each IndexFile call.
+			if _, err := tx.q.Exec("DELETE FROM links"); err != nil {
+				return fmt.Errorf("clear links: %w", err)
+			}
+			if _, err := tx.q.Exec("UPDATE notes SET hash = ''"); err != nil {
+				return fmt.Errorf("clear hashes: %w", err)
+			}
+		}
+		// Drop notes indexed before their directory was skipped.
+		if idx.skipDir != "" {
+			if err := tx.DeleteNotesUnder(idx.skipDir + string(filepath.Separator)); err != nil {
+				return fmt.Errorf("drop skipped notes: %w", err)
+			}
+		}
+		// An index from before the notes_fts_delete trigger can have
+		// full-text rows of deleted notes, which searches still find.
+		if _, err := tx.reconcileFTS(); err != nil {
+			return err
+		}
+		// Attachments are cheap to list, so they are rewritten every time.
+		if err := tx.ReplaceAttachments(attachments); err != nil {
+			return fmt.Errorf("index attachments: %w", err)
+		}
+		// Drop notes whose file was deleted while nothing was watching.
+		var err error
+		if known, err = tx.NoteHashes(); err != nil {
+			return err
+		}
+		present := make(map[string]bool, len(paths))
+		for _, p := range paths {
+			present[idx.relPath(p)] = true
+		}
+		for rel := range known {
+			if present[rel] {
+				continue
+			}
+			if err := tx.DeleteNote(rel); err != nil {
+				return fmt.Errorf("remove %s: %w", rel, err)
+			}
+			stats.Removed++
+		}
+		if stats.Removed > 0 {
+			return resolveDangling(tx)
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
 	}
-	if _, err := idx.db.Conn().Exec("UPDATE notes SET hash = ''"); err != nil {
-		return fmt.Errorf("clear hashes: %w", err)
+	if full {
+		known = nil
 	}
 
-	return filepath.Walk(idx.vaultRoot, func(path string, info os.FileInfo, err error) error {
+	done := make(chan struct{})
+	defer close(done)
+	batch := make([]*noteFile, 0, indexBatchSize)
+	written := 0
+	report := func() {
+		written++
+		if progress != nil {
+			progress(written, len(paths))
+		}
+	}
+	flush := func() error {
+		err := idx.db.InTx(func(tx *DB) error {
+			for _, n := range batch {
+				if n.encrypted {
+					if err := tx.DeleteNote(n.relPath); err != nil {
+						return err
+					}
+					stats.Encrypted++
+				} else if !idx.unchanged(tx, n) {
+					if err := idx.writeNote(tx, n); err != nil {
+						return err
+					}
+					stats.Indexed++
+				}
+				report()
+			}
+			return nil
+		})
+		batch = batch[:0]
+		return err
+	}
+	for r := range idx.readNotes(paths, known, done) {
+		if r.err != nil {
+			return stats, r.err
+		}
+		if r.note == nil || (r.note.parsed == nil && !r.note.encrypted) {
+			report()
+			continue
+		}
+		batch = append(batch, r.note)
+		if len(batch) == indexBatchSize {
+			if err := flush(); err != nil {
+				return stats, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+	stats.Links, err = idx.db.CountLinks()
+	return stats, err
+}
+
+// readResult is one file read by readNotes; note is nil for skipped files
+// and unparsed for unchanged ones.
+type readResult struct {
+	note *noteFile
+	err  error
+}
+
+// readNotes reads and parses paths on up to GOMAXPROCS worker goroutines,
+// delivering them in no particular order. Parsing is most of the work of
+// indexing a note and needs no database, so it runs in parallel while the
+// caller serializes the writes. Notes whose hash matches known (relative
+// path to hash) are delivered unparsed. Closing done stops the workers early.
+func (idx *Indexer) readNotes(paths []string, known map[string]string, done <-chan struct{}) <-chan readResult {
+	workers := min(runtime.GOMAXPROCS(0), len(paths))
+	jobs := make(chan string)
+	out := make(chan readResult, workers)
+
+	go func() {
+		defer close(jobs)
+		for _, p := range paths {
+			select {
+			case jobs <- p:
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Go(func() {
+			// goldmark parsers are not documented as safe to share.
+			parser := markdown.NewParser()
+			for p := range jobs {
+				n, err := idx.readNote(p)
+				if n != nil && !n.encrypted && known[n.relPath] != n.hash {
+					n.parse(parser)
+				}
+				select {
+				case out <- readResult{note: n, err: err}:
+				case <-done:
+					return
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// vaultFiles returns the absolute paths of the vault's markdown files and
+// its other files as attachments, leaving out hidden directories and
+// skipped or ignored paths.
+func (idx *Indexer) vaultFiles() ([]string, []Attachment, error) {
+	var paths []string
+	var attachments []Attachment
+	err := filepath.Walk(idx.vaultRoot, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return nil
 		}
@@ -45,129 +314,548 @@ func (idx *Indexer) IndexAll() error {
 		if info.IsDir() && strings.HasPrefix(info.Name(), ".") {
 			return filepath.SkipDir
 		}
+		if path == idx.vaultRoot {
+			return nil
+		}
+		rel, err := filepath.Rel(idx.vaultRoot, path)
+		if err != nil {
+			return nil
+		}
+		if idx.skipped(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
-		if info.IsDir() || !strings.HasSuffix(info.Name(), ".md") {
+		if info.IsDir() {
+			return nil
+		}
+		if !strings.HasSuffix(info.Name(), ".md") {
+			if isAttachment(info.Name()) && info.Mode().IsRegular() {
+				attachments = append(attachments, Attachment{Path: rel, Size: info.Size(), ModTime: info.ModTime()})
+			}
 			return nil
 		}
 
-		return idx.IndexFile(path)
+		paths = append(paths, path)
+		return nil
 	})
+	return paths, attachments, err
 }
 
-// IndexFile indexes a single markdown file.
+// sourceFiles returns the absolute paths of the markdown files in the
+// external sources, leaving out hidden files and directories.
+func (idx *Indexer) sourceFiles() ([]string, error) {
+	var paths []string
+	for _, src := range idx.sources {
+		err := filepath.Walk(src.Root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if strings.HasPrefix(info.Name(), ".") && path != src.Root {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() && strings.HasSuffix(info.Name(), ".md") {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("source %s: %w", src.Name, err)
+		}
+	}
+	return paths, nil
+}
+
+// IndexFile indexes a single markdown file in one transaction.
 func (idx *Indexer) IndexFile(absPath string) error {
-	content, err := os.ReadFile(absPath)
-	if err != nil {
-		return fmt.Errorf("read %s: %w", absPath, err)
+	return idx.db.InTx(func(tx *DB) error {
+		return idx.indexFile(tx, absPath)
+	})
+}
+
+// noteFile is a markdown file read, and once parsed, ready to write to the
+// index.
+type noteFile struct {
+	relPath string
+	hash    string
+	modTime int64
+	size    int64
+	content []byte
+	// encrypted is set for notes still encrypted by git-crypt or age,
+	// which are dropped from the index instead of indexing ciphertext.
+	encrypted bool
+
+	parsed                  *markdown.ParsedNote
+	plain                   string // content without frontmatter
+	title, slug, status     string
+	summary, uid            string
+	created, updated        time.Time
+	reviewed                time.Time
+	words                   int
+	tags, aliases, keywords []string
+}
+
+// indexFile indexes a markdown file through db, normally a transaction.
+func (idx *Indexer) indexFile(db *DB, absPath string) error {
+	n, err := idx.readNote(absPath)
+	if err != nil || n == nil {
+		return err
+	}
+	if n.encrypted {
+		return db.DeleteNote(n.relPath)
 	}
+	if idx.unchanged(db, n) {
+		return nil
+	}
+	n.parse(idx.parser)
+	return idx.writeNote(db, n)
+}
 
-	info, err := os.Stat(absPath)
+// unchanged reports whether the index already holds this version of the note.
+func (idx *Indexer) unchanged(db *DB, n *noteFile) bool {
+	existingHash, err := db.GetNoteHash(n.relPath)
 	if err != nil {
-		return fmt.Errorf("stat %s: %w", absPath, err)
+		existingHash = "" // treat as changed; will re-index
 	}
+	return n.hash == existingHash
+}
 
-	relPath, err := filepath.Rel(idx.vaultRoot, absPath)
+// readNote reads the file at absPath. It returns nil for files in the
+// skipped directory.
+func (idx *Indexer) readNote(absPath string) (*noteFile, error) {
+	content, err := os.ReadFile(absPath)
 	if err != nil {
-		relPath = absPath
+		return nil, fmt.Errorf("read %s: %w", absPath, err)
 	}
 
-	// Check if file has changed
-	hash := fmt.Sprintf("%x", sha256.Sum256(content))
-	existingHash, err := idx.db.GetNoteHash(relPath)
+	info, err := os.Stat(absPath)
 	if err != nil {
-		existingHash = "" // treat as changed; will re-index
+		return nil, fmt.Errorf("stat %s: %w", absPath, err)
 	}
-	if hash == existingHash {
-		return nil // unchanged
+
+	relPath := idx.relPath(absPath)
+	if idx.skipped(relPath, false) {
+		return nil, nil
 	}
 
-	// Parse the markdown
-	parsed := idx.parser.Parse(content)
+	return &noteFile{
+		relPath:   relPath,
+		hash:      fmt.Sprintf("%x", sha256.Sum256(content)),
+		modTime:   info.ModTime().Unix(),
+		size:      info.Size(),
+		content:   content,
+		encrypted: vault.IsEncrypted(content),
+	}, nil
+}
 
-	// Extract metadata
-	title := titleFromPath(relPath)
-	status := ""
-	var tags []string
+// parse parses the note's markdown and extracts its metadata.
+func (n *noteFile) parse(p *markdown.Parser) {
+	parsed := p.Parse(n.content)
+	n.parsed = parsed
+	n.plain = parsed.PlainContent()
 
+	n.title = titleFromPath(n.relPath)
 	if parsed.Frontmatter != nil {
 		if parsed.Frontmatter.Title != "" {
-			title = parsed.Frontmatter.Title
+			n.title = parsed.Frontmatter.Title
 		}
-		status = parsed.Frontmatter.Status
-		tags = parsed.Frontmatter.Tags
+		n.status = parsed.Frontmatter.Status
+		n.summary = parsed.Frontmatter.Summary
+		n.uid = parsed.Frontmatter.UID
+		n.tags = parsed.Frontmatter.Tags
+		n.aliases = parsed.Frontmatter.Aliases
+		n.keywords = parsed.Frontmatter.Keywords
+		n.created = parsed.Frontmatter.Created
+		n.updated = parsed.Frontmatter.Updated
+		n.reviewed = parsed.Frontmatter.Reviewed
+	}
+
+	if n.summary == "" {
+		n.summary = firstBodyLine(n.plain)
 	}
 
-	slug := slugify(title)
+	n.slug = slugify(n.title)
+	n.words = markdown.WordCount(n.plain)
+}
 
+// writeNote writes a parsed note to the index through db.
+func (idx *Indexer) writeNote(db *DB, n *noteFile) error {
 	// Upsert the note
-	noteID, err := idx.db.UpsertNote(relPath, title, slug, status, hash, info.ModTime().Unix(), info.Size())
+	noteID, err := db.UpsertNote(n.relPath, n.title, n.slug, n.status, n.hash, n.modTime, n.size)
 	if err != nil {
 		return fmt.Errorf("upsert note: %w", err)
 	}
 
+	// Links written before this note existed (or before it was reached by
+	// IndexAll) point at it now.
+	if err := resolveLinksTo(db, noteID, n.relPath); err != nil {
+		return fmt.Errorf("resolve links to note: %w", err)
+	}
+
+	if err := db.SetNoteSummary(noteID, n.summary); err != nil {
+		return fmt.Errorf("set summary: %w", err)
+	}
+	if err := db.SetNoteDates(noteID, n.created, n.updated, n.reviewed); err != nil {
+		return fmt.Errorf("set dates: %w", err)
+	}
+	if err := db.SetNoteWordCount(noteID, n.words); err != nil {
+		return fmt.Errorf("set word count: %w", err)
+	}
+	if err := db.SetNoteUID(noteID, n.uid); err != nil {
+		return fmt.Errorf("set uid: %w", err)
+	}
+
 	// Update FTS
-	headingTexts := make([]string, len(parsed.Headings))
-	for i, h := range parsed.Headings {
+	headingTexts := make([]string, len(n.parsed.Headings))
+	for i, h := range n.parsed.Headings {
 		headingTexts[i] = h.Text
 	}
-	tagStr := strings.Join(tags, " ")
+	tagStr := strings.Join(n.tags, " ")
 	headingStr := strings.Join(headingTexts, " ")
 
-	if err := idx.db.UpdateFTS(noteID, title, parsed.PlainContent(), tagStr, headingStr); err != nil {
+	if err := db.UpdateFTS(noteID, n.title, n.plain, tagStr, headingStr, strings.Join(n.keywords, " ")); err != nil {
 		return fmt.Errorf("update FTS: %w", err)
 	}
 
 	// Update tags
-	if err := idx.db.ClearNoteTags(noteID); err != nil {
+	if err := db.ClearNoteTags(noteID); err != nil {
 		return fmt.Errorf("clear note tags: %w", err)
 	}
-	for _, tag := range tags {
-		tagID, err := idx.db.UpsertTag(tag)
+	for _, tag := range n.tags {
+		tagID, err := db.UpsertTag(tag)
 		if err != nil {
 			return fmt.Errorf("upsert tag %q: %w", tag, err)
 		}
-		if err := idx.db.LinkNoteTag(noteID, tagID); err != nil {
+		if err := db.LinkNoteTag(noteID, tagID); err != nil {
 			return fmt.Errorf("link note tag %q: %w", tag, err)
 		}
 	}
 
 	// Update headings
-	if err := idx.db.ClearNoteHeadings(noteID); err != nil {
+	if err := db.ClearNoteHeadings(noteID); err != nil {
 		return fmt.Errorf("clear note headings: %w", err)
 	}
-	for _, h := range parsed.Headings {
-		if err := idx.db.InsertHeading(noteID, h.Level, h.Text, h.Line); err != nil {
+	for _, h := range n.parsed.Headings {
+		if err := db.InsertHeading(noteID, h.Level, h.Text, h.Line); err != nil {
 			return fmt.Errorf("insert heading %q: %w", h.Text, err)
 		}
 	}
 
-	// Update links (store basenames for name-based resolution)
-	if err := idx.db.ClearNoteLinks(noteID); err != nil {
+	// Update tasks
+	if err := db.ClearNoteTasks(noteID); err != nil {
+		return fmt.Errorf("clear note tasks: %w", err)
+	}
+	for _, t := range n.parsed.Tasks {
+		if err := db.InsertTask(noteID, t.Text, t.Done, t.Section, t.Line); err != nil {
+			return fmt.Errorf("insert task %q: %w", t.Text, err)
+		}
+	}
+
+	// Update aliases
+	if err := db.ClearNoteAliases(noteID); err != nil {
+		return fmt.Errorf("clear note aliases: %w", err)
+	}
+	for _, alias := range n.aliases {
+		if err := db.InsertAlias(noteID, alias); err != nil {
+			return fmt.Errorf("insert alias %q: %w", alias, err)
+		}
+	}
+
+	// Update links (store basenames, or paths with folder scope, for
+	// name-based resolution)
+	if err := db.ClearNoteLinks(noteID); err != nil {
 		return fmt.Errorf("clear note links: %w", err)
 	}
-	for _, link := range parsed.WikiLinks {
-		targetPath := markdown.ResolveWikiLinkTarget(link.Target)
-		targetPath = canonicalBasenameKey(targetPath) // store canonical, case-insensitive basename
-		if err := idx.db.InsertLink(noteID, targetPath, link.Section, link.Alias, link.Line, link.Col); err != nil {
+	for _, link := range n.parsed.WikiLinks {
+		targetPath := db.linkKey(markdown.ResolveWikiLinkTarget(link.Target))
+		if err := db.InsertLink(noteID, targetPath, link.Section, link.Alias, link.Line, link.Col); err != nil {
+			return fmt.Errorf("insert link to %q: %w", targetPath, err)
+		}
+	}
+	// Markdown links name a path relative to the note, so they are keyed
+	// the same way once resolved against it.
+	for _, link := range n.parsed.MarkdownLinks {
+		resolved := markdown.ResolveMarkdownLinkTarget(n.relPath, link.Target)
+		if resolved == "" {
+			continue
+		}
+		targetPath := db.linkKey(resolved)
+		if err := db.InsertMarkdownLink(noteID, targetPath, link.Section, link.Text, link.Line, link.Col); err != nil {
 			return fmt.Errorf("insert link to %q: %w", targetPath, err)
 		}
 	}
 
 	// Resolve link target IDs
-	if err := idx.resolveLinks(noteID); err != nil {
+	if err := resolveLinks(db, noteID); err != nil {
 		return fmt.Errorf("resolve links: %w", err)
 	}
 
 	return nil
 }
 
+// Reindex applies a batch of vault changes in one pass: removed files are
+// dropped from the index first, then changed files are (re)indexed. Used after
+// vault-wide rewrites instead of waiting for one watcher event per file.
+func (idx *Indexer) Reindex(removed, changed []string) error {
+	return idx.db.InTx(func(tx *DB) error {
+		for _, p := range removed {
+			if err := idx.removeFile(tx, p); err != nil {
+				return fmt.Errorf("remove %s: %w", p, err)
+			}
+		}
+		if len(removed) > 0 {
+			if err := resolveDangling(tx); err != nil {
+				return err
+			}
+		}
+		for _, p := range changed {
+			if err := idx.indexFile(tx, p); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// PlanTagRename returns the rewrites RenameTag would make, without
+// touching any note, so they can be previewed first.
+func (idx *Indexer) PlanTagRename(oldTag, newTag string) ([]vault.FileRewrite, error) {
+	oldTag = strings.TrimPrefix(strings.TrimSpace(oldTag), "#")
+	newTag = strings.TrimPrefix(strings.TrimSpace(newTag), "#")
+	for _, tag := range []string{oldTag, newTag} {
+		if tag == "" || strings.ContainsAny(tag, ",[]\"'") {
+			return nil, fmt.Errorf("invalid tag %q", tag)
+		}
+	}
+
+	paths, _, err := idx.vaultFiles()
+	if err != nil {
+		return nil, err
+	}
+	var rewrites []vault.FileRewrite
+	for _, p := range paths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if updated, ok := markdown.RenameTag(data, oldTag, newTag); ok {
+			rewrites = append(rewrites, vault.FileRewrite{Path: p, Content: updated})
+		}
+	}
+	return rewrites, nil
+}
+
+// RenameTag renames the tag oldTag (matched case-insensitively, as the tag:
+// operator does) to newTag in the tags: frontmatter line of every note in
+// the vault, writing the notes as one atomic rewrite and reindexing them.
+// It returns the absolute paths of the notes changed.
+func (idx *Indexer) RenameTag(oldTag, newTag string) ([]string, error) {
+	rewrites, err := idx.PlanTagRename(oldTag, newTag)
+	if err != nil {
+		return nil, err
+	}
+	if err := vault.ApplyRewrites(rewrites); err != nil {
+		return nil, err
+	}
+
+	changed := make([]string, len(rewrites))
+	for i, rw := range rewrites {
+		changed[i] = rw.Path
+	}
+	if err := idx.Reindex(nil, changed); err != nil {
+		return changed, fmt.Errorf("reindex: %w", err)
+	}
+	return changed, nil
+}
+
 // RemoveFile removes a file from the index.
 func (idx *Indexer) RemoveFile(absPath string) error {
-	relPath, err := filepath.Rel(idx.vaultRoot, absPath)
+	return idx.db.InTx(func(tx *DB) error {
+		if err := idx.removeFile(tx, absPath); err != nil {
+			return err
+		}
+		return resolveDangling(tx)
+	})
+}
+
+func (idx *Indexer) removeFile(db *DB, absPath string) error {
+	return db.DeleteNote(idx.relPath(absPath))
+}
+
+// IndexDir indexes the notes and attachments in the directory absDir, such
+// as one moved into the vault.
+func (idx *Indexer) IndexDir(absDir string) error {
+	return filepath.Walk(absDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if strings.HasPrefix(info.Name(), ".") || idx.skipped(idx.relPath(path), true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if strings.HasSuffix(info.Name(), ".md") {
+			return idx.IndexFile(path)
+		}
+		if isAttachment(info.Name()) && info.Mode().IsRegular() {
+			return idx.IndexAttachment(path)
+		}
+		return nil
+	})
+}
+
+// RemoveDir drops the notes and attachments under the directory absDir
+// from the index, after it was deleted or moved out of the vault.
+func (idx *Indexer) RemoveDir(absDir string) error {
+	prefix := idx.relPath(absDir) + string(filepath.Separator)
+	return idx.db.InTx(func(tx *DB) error {
+		if err := tx.DeleteNotesUnder(prefix); err != nil {
+			return err
+		}
+		if err := resolveDangling(tx); err != nil {
+			return err
+		}
+		attachments, err := tx.ListAttachments()
+		if err != nil {
+			return err
+		}
+		for _, a := range attachments {
+			if strings.HasPrefix(a.Path, prefix) {
+				if err := tx.DeleteAttachment(a.Path); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// MoveDir re-paths the notes and attachments under the directory oldAbs to
+// newAbs after the directory was moved or renamed. Notes keep their IDs, so
+// their visits and the links resolved to them carry over; those the new
+// location skips, or whose files aren't there, are dropped, so pairing a
+// directory moved away with an unrelated new one leaves no ghost rows. The
+// new directory is then indexed, for files changed on the way.
+func (idx *Indexer) MoveDir(oldAbs, newAbs string) error {
+	sep := string(filepath.Separator)
+	oldPrefix, newPrefix := idx.relPath(oldAbs)+sep, idx.relPath(newAbs)+sep
+	err := idx.db.InTx(func(tx *DB) error {
+		paths, err := tx.NotePathsUnder(oldPrefix)
+		if err != nil {
+			return err
+		}
+		dropped := false
+		for _, p := range paths {
+			np := newPrefix + strings.TrimPrefix(p, oldPrefix)
+			gone, err := missing(filepath.Join(newAbs, strings.TrimPrefix(p, oldPrefix)))
+			if err != nil {
+				return err
+			}
+			if gone || idx.skipped(np, false) {
+				if err := tx.DeleteNote(p); err != nil {
+					return err
+				}
+				dropped = true
+				continue
+			}
+			if err := moveNote(tx, p, np); err != nil {
+				return fmt.Errorf("move %s: %w", p, err)
+			}
+		}
+		if dropped {
+			if err := resolveDangling(tx); err != nil {
+				return err
+			}
+		}
+
+		attachments, err := tx.ListAttachments()
+		if err != nil {
+			return err
+		}
+		for _, a := range attachments {
+			if !strings.HasPrefix(a.Path, oldPrefix) {
+				continue
+			}
+			np := newPrefix + strings.TrimPrefix(a.Path, oldPrefix)
+			gone, err := missing(filepath.Join(newAbs, strings.TrimPrefix(a.Path, oldPrefix)))
+			if err != nil {
+				return err
+			}
+			if gone || vault.IsExternal(np) || idx.skipped(np, false) {
+				err = tx.DeleteAttachment(a.Path)
+			} else {
+				err = tx.MoveAttachment(a.Path, np)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		relPath = absPath
+		return err
 	}
-	return idx.db.DeleteNote(relPath)
+	return idx.IndexDir(newAbs)
+}
+
+// missing reports whether nothing exists at path.
+func missing(path string) (bool, error) {
+	_, err := os.Lstat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, nil
+	}
+	return false, err
+}
+
+// moveNote moves the note at oldPath to newPath. When that changes the key
+// links are matched on (with folder scope), links to the note are resolved
+// again.
+func moveNote(db *DB, oldPath, newPath string) error {
+	// A row left at the destination would clash with the moved one.
+	if err := db.DeleteNote(newPath); err != nil {
+		return err
+	}
+	rekeyed := db.noteKey(oldPath) != db.noteKey(newPath)
+	id, err := db.MoveNote(oldPath, newPath)
+	if err != nil || id == 0 || !rekeyed {
+		return err
+	}
+
+	rows, err := db.q.Query("SELECT DISTINCT source_id FROM links WHERE target_id = ?", id)
+	if err != nil {
+		return err
+	}
+	var sources []int64
+	for rows.Next() {
+		var src int64
+		if err := rows.Scan(&src); err != nil {
+			return errors.Join(err, rows.Close())
+		}
+		sources = append(sources, src)
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if _, err := db.q.Exec("UPDATE links SET target_id = NULL WHERE target_id = ?", id); err != nil {
+		return err
+	}
+	// Links that pointed here go to their best match first; only then may
+	// the note take the links left unresolved that its new path matches.
+	for _, src := range sources {
+		if err := resolveLinks(db, src); err != nil {
+			return err
+		}
+	}
+	return resolveLinksTo(db, id, newPath)
 }
 
 func titleFromPath(path string) string {
@@ -180,9 +868,42 @@ func titleFromPath(path string) string {
 	return name
 }
 
-// resolveLinks attempts to set target_id for links whose target_path (basename) matches a known note.
-func (idx *Indexer) resolveLinks(sourceID int64) error {
-	_, err := idx.db.Conn().Exec(`
+// firstBodyLine returns the first non-blank line of body that is not a
+// heading, trimmed. Headings usually repeat the title, so they make a poor
+// summary.
+func firstBodyLine(body string) string {
+	for line := range strings.Lines(body) {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		return line
+	}
+	return ""
+}
+
+// folderTargetSQL selects the note a link's target_path means with folder
+// scope: the shortest note path ending in it, so a link naming a full path
+// gets that note.
+const folderTargetSQL = `
+	SELECT id FROM notes
+	WHERE basename_key = links.target_path
+		OR substr(basename_key, -length(links.target_path) - 1) = '/' || links.target_path
+	ORDER BY length(basename_key), basename_key
+	LIMIT 1`
+
+// resolveLinks attempts to set target_id for links whose target_path matches
+// a known note: by basename, or with folder scope the shortest note path
+// ending in target_path.
+func resolveLinks(db *DB, sourceID int64) error {
+	if db.folderScoped {
+		_, err := db.q.Exec(`
+			UPDATE links SET target_id = (`+folderTargetSQL+`)
+			WHERE source_id = ? AND target_id IS NULL
+		`, sourceID)
+		return err
+	}
+	_, err := db.q.Exec(`
 		UPDATE links SET target_id = (
 			SELECT id FROM notes WHERE basename_key = links.target_path
 		) WHERE source_id = ? AND target_id IS NULL
@@ -190,6 +911,45 @@ func (idx *Indexer) resolveLinks(sourceID int64) error {
 	return err
 }
 
+// resolveDangling re-resolves links left without a target after notes were
+// removed. Only with folder scope can another note match them. Call it once
+// per batch of removals: it checks every unresolved link.
+func resolveDangling(db *DB) error {
+	if !db.folderScoped {
+		return nil
+	}
+	_, err := db.q.Exec(`
+		UPDATE links SET target_id = (` + folderTargetSQL + `)
+		WHERE target_id IS NULL
+	`)
+	return err
+}
+
+// resolveLinksTo sets target_id on unresolved links whose target_path matches
+// the note at relPath. With folder scope it also takes links resolved to a
+// worse match, one with a longer path, which IndexAll may have reached
+// first.
+func resolveLinksTo(db *DB, noteID int64, relPath string) error {
+	key := db.noteKey(relPath)
+	if db.folderScoped {
+		_, err := db.q.Exec(`
+			UPDATE links SET target_id = ?
+			WHERE (target_path = ? OR substr(?, -length(target_path) - 1) = '/' || target_path)
+				AND (target_id IS NULL OR EXISTS (
+					SELECT 1 FROM notes t WHERE t.id = links.target_id
+						AND (length(t.basename_key) > length(?)
+							OR (length(t.basename_key) = length(?) AND t.basename_key > ?))
+				))
+		`, noteID, key, key, key, key, key)
+		return err
+	}
+	_, err := db.q.Exec(`
+		UPDATE links SET target_id = ?
+		WHERE target_path = ? AND target_id IS NULL
+	`, noteID, key)
+	return err
+}
+
 func slugify(title string) string {
 	s := strings.ToLower(title)
 	s = strings.ReplaceAll(s, " ", "-")
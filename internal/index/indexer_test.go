@@ -0,0 +1,1412 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+func TestIndexAllSkipDir(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for _, rel := range []string{"note.md", "templates/daily.md", "templates/work/standup.md", "templates-archive/old.md"} {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte("# "+rel+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := db.GetNoteIDByPath("templates/daily.md"); id == 0 {
+		t.Fatal("templates should be indexed without a skip dir")
+	}
+
+	// Skipping drops previously indexed templates and ignores new writes.
+	idx.SetSkipDir("templates")
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexFile(filepath.Join(root, "templates", "work", "standup.md")); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"note.md":                   true,
+		"templates-archive/old.md":  true,
+		"templates/daily.md":        false,
+		"templates/work/standup.md": false,
+	} {
+		id, err := db.GetNoteIDByPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (id != 0) != want {
+			t.Errorf("%s indexed = %v, want %v", path, id != 0, want)
+		}
+	}
+}
+
+func TestIndexAllIgnore(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for _, rel := range []string{"note.md", "archive/old.md", "web/node_modules/pkg/readme.md", "drafts/wip.md", "drafts/keep.md", "archive/diagram.png"} {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte("# "+rel+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := db.GetNoteIDByPath("archive/old.md"); id == 0 {
+		t.Fatal("archive should be indexed without an ignore file")
+	}
+
+	// Ignoring drops previously indexed notes and ignores new writes.
+	idx.SetIgnore(vault.ParseIgnore("archive/\nnode_modules\ndrafts/*.md\n!drafts/keep.md\n"))
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexFile(filepath.Join(root, "drafts", "wip.md")); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"note.md":                        true,
+		"drafts/keep.md":                 true,
+		"archive/old.md":                 false,
+		"web/node_modules/pkg/readme.md": false,
+		"drafts/wip.md":                  false,
+	} {
+		id, err := db.GetNoteIDByPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (id != 0) != want {
+			t.Errorf("%s indexed = %v, want %v", path, id != 0, want)
+		}
+	}
+	if err := idx.IndexAttachment(filepath.Join(root, "archive", "diagram.png")); err != nil {
+		t.Fatal(err)
+	}
+	if attachments, err := db.ListAttachments(); err != nil || len(attachments) != 0 {
+		t.Errorf("attachments = %v, %v; want the ignored diagram left out", attachments, err)
+	}
+}
+
+func TestIndexAllParallel(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	// More notes than one batch, each linking to the next, so links must
+	// resolve whichever order the workers finish in.
+	const n = indexBatchSize + 20
+	root := t.TempDir()
+	for i := range n {
+		content := fmt.Sprintf("---\ntags: [t%d]\n---\n# Note %d\n\nSee [[note-%d]].\n", i%3, i, (i+1)%n)
+		if err := os.WriteFile(filepath.Join(root, fmt.Sprintf("note-%d.md", i)), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var calls, last, lastTotal int
+	err = NewIndexer(db, root).IndexAllWithProgress(func(done, total int) {
+		calls++
+		if done != last+1 {
+			t.Errorf("progress went from %d to %d", last, done)
+		}
+		last, lastTotal = done, total
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != n || last != n || lastTotal != n {
+		t.Errorf("progress: %d calls ending at %d/%d, want %d ending at %d/%d", calls, last, lastTotal, n, n, n)
+	}
+	var notes, resolved int
+	if err := db.Conn().QueryRow("SELECT count(*) FROM notes").Scan(&notes); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Conn().QueryRow("SELECT count(*) FROM links WHERE target_id IS NOT NULL").Scan(&resolved); err != nil {
+		t.Fatal(err)
+	}
+	if notes != n || resolved != n {
+		t.Errorf("indexed %d notes with %d resolved links, want %d of each", notes, resolved, n)
+	}
+	backlinks, err := db.GetBacklinks("note-0.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 1 || backlinks[0].SourcePath != fmt.Sprintf("note-%d.md", n-1) {
+		t.Errorf("note-0 backlinks = %+v", backlinks)
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.md", "# A\n\nSee [[b]] and [[c]].\n")
+	write("b.md", "# B\n")
+	write("c.md", "# C\n")
+
+	idx := NewIndexer(db, root)
+	stats, err := idx.Rebuild(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (IndexStats{Files: 3, Indexed: 3, Links: 2}); stats != want {
+		t.Errorf("Rebuild() = %+v, want %+v", stats, want)
+	}
+
+	// Nothing changed: nothing is rewritten.
+	if stats, err = idx.Update(nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := (IndexStats{Files: 3, Links: 2}); stats != want {
+		t.Errorf("Update() unchanged = %+v, want %+v", stats, want)
+	}
+
+	// One note edited, one deleted behind the index's back.
+	write("b.md", "# B\n\nBack to [[a]].\n")
+	if err := os.Remove(filepath.Join(root, "c.md")); err != nil {
+		t.Fatal(err)
+	}
+	if stats, err = idx.Update(nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := (IndexStats{Files: 2, Indexed: 1, Removed: 1, Links: 3}); stats != want {
+		t.Errorf("Update() = %+v, want %+v", stats, want)
+	}
+	if id, _ := db.GetNoteIDByPath("c.md"); id != 0 {
+		t.Error("deleted note still indexed")
+	}
+	backlinks, err := db.GetBacklinks("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 1 || backlinks[0].SourcePath != "b.md" {
+		t.Errorf("a.md backlinks = %+v", backlinks)
+	}
+}
+
+func TestNoteByUID(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.md"), []byte("---\nuid: 7f3e\n---\n# A\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "b.md"), []byte("# B\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(db, root)
+	if _, err := idx.Rebuild(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.NoteByUID("7f3e"); err != nil || got != "a.md" {
+		t.Errorf("NoteByUID = %q, %v; want a.md", got, err)
+	}
+
+	// The uid follows the note to its new path.
+	if err := os.MkdirAll(filepath.Join(root, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(root, "a.md"), filepath.Join(root, "sub", "renamed.md")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := idx.Update(nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.NoteByUID("7f3e"); err != nil || got != filepath.Join("sub", "renamed.md") {
+		t.Errorf("NoteByUID after rename = %q, %v; want sub/renamed.md", got, err)
+	}
+	for _, uid := range []string{"", "nope"} {
+		if got, err := db.NoteByUID(uid); err != nil || got != "" {
+			t.Errorf("NoteByUID(%q) = %q, %v; want none", uid, got, err)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	later := time.Now().Add(time.Hour)
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(root, rel)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// Changes within the second of indexing must still show.
+		if err := os.Chtimes(path, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, "a.md"), []byte("# A\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "b.md"), []byte("# B\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "c.md"), []byte("# C\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(db, root)
+	if _, err := idx.Rebuild(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// Behind the watcher's back: one note edited, one touched, one deleted
+	// and one added.
+	write("a.md", "# A\n\nSee [[b]].\n")
+	write("b.md", "# B\n")
+	if err := os.Remove(filepath.Join(root, "c.md")); err != nil {
+		t.Fatal(err)
+	}
+	write("d.md", "# D\n")
+
+	var written []string
+	stats, err := idx.Reconcile(func(path string) { written = append(written, filepath.Base(path)) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 3 || stats.Indexed != 2 || stats.Removed != 1 {
+		t.Errorf("Reconcile() = %+v, want 3 files, 2 indexed, 1 removed", stats)
+	}
+	slices.Sort(written)
+	if !slices.Equal(written, []string{"a.md", "d.md"}) {
+		t.Errorf("Reconcile() wrote %v, want [a.md d.md]", written)
+	}
+	if id, _ := db.GetNoteIDByPath("c.md"); id != 0 {
+		t.Error("deleted note still indexed")
+	}
+	backlinks, err := db.GetBacklinks("b.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 1 || backlinks[0].SourcePath != "a.md" {
+		t.Errorf("b.md backlinks = %+v", backlinks)
+	}
+
+	// The touched note's new stamp was recorded, so nothing drifts now.
+	if stats, err = idx.Reconcile(func(path string) { t.Errorf("second Reconcile() wrote %s", path) }); err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 0 || stats.Removed != 0 {
+		t.Errorf("second Reconcile() = %+v, want nothing done", stats)
+	}
+}
+
+func TestIndexFileSummary(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"body.md":    "---\ntitle: Body\n---\n\n# Body\n\n  First real line.\nSecond line.\n",
+		"summary.md": "---\nsummary: \"Set in frontmatter\"\ndate: 2026-01-31\n---\nIgnored body line.\n",
+		"empty.md":   "# Only a heading\n",
+	}
+	for rel, content := range files {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.ListAllNotes(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, r := range results {
+		got[r.Path] = r.Summary
+	}
+	want := map[string]string{
+		"body.md":    "First real line.",
+		"summary.md": "Set in frontmatter",
+		"empty.md":   "",
+	}
+	for path, w := range want {
+		if got[path] != w {
+			t.Errorf("summary of %s = %q, want %q", path, got[path], w)
+		}
+	}
+	for _, r := range results {
+		wantCreated := time.Time{}
+		if r.Path == "summary.md" {
+			wantCreated = time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local)
+		}
+		if !r.Created.Equal(wantCreated) || !r.Updated.IsZero() {
+			t.Errorf("dates of %s = %v, %v, want created %v", r.Path, r.Created, r.Updated, wantCreated)
+		}
+	}
+
+	// Frontmatter isn't counted.
+	for path, want := range map[string]int{"body.md": 6, "summary.md": 3, "empty.md": 3, "missing.md": 0} {
+		if got, err := db.WordCount(path); err != nil || got != want {
+			t.Errorf("WordCount(%s) = %d, %v, want %d", path, got, err, want)
+		}
+	}
+	if total, err := db.TotalWordCount(); err != nil || total != 12 {
+		t.Errorf("TotalWordCount = %d, %v, want 12", total, err)
+	}
+}
+
+func TestListAndRenameTags(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"a.md": "---\ntags: [work, home]\n---\n",
+		"b.md": "---\ntags: [Work]\n---\n",
+		"c.md": "---\ntags: [job, work]\n---\n",
+		"d.md": "no tags, but mentions work\n",
+		"e.md": "---\ntags: [work/alpha/x, home]\n---\n",
+	}
+	for rel, content := range files {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	tags, err := db.ListTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Parent segments of nested tags are recorded, counting their subtags'
+	// notes.
+	want := []TagResult{
+		{"work", 2, 3}, {"home", 2, 2}, {"Work", 1, 1}, {"job", 1, 1},
+		{"work/alpha", 0, 1}, {"work/alpha/x", 1, 1},
+	}
+	if !slices.Equal(tags, want) {
+		t.Errorf("ListTags = %v, want %v", tags, want)
+	}
+	results, err := db.SearchQuery(ParseQuery("tag:Work/Alpha"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "e.md" {
+		t.Errorf("tag:Work/Alpha found %v, want e.md", results)
+	}
+
+	planned, err := idx.PlanTagRename("#work", "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(planned) != 4 {
+		t.Errorf("PlanTagRename planned %d rewrites, want 4", len(planned))
+	}
+	if data, err := os.ReadFile(filepath.Join(root, "a.md")); err != nil || string(data) != files["a.md"] {
+		t.Errorf("PlanTagRename touched a.md: %q, %v", data, err)
+	}
+
+	changed, err := idx.RenameTag("#work", "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(changed)
+	wantChanged := []string{filepath.Join(root, "a.md"), filepath.Join(root, "b.md"), filepath.Join(root, "c.md"), filepath.Join(root, "e.md")}
+	if !slices.Equal(changed, wantChanged) {
+		t.Errorf("RenameTag changed %v, want %v", changed, wantChanged)
+	}
+	data, err := os.ReadFile(filepath.Join(root, "c.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(data); got != "---\ntags: [job]\n---\n" {
+		t.Errorf("c.md = %q", got)
+	}
+
+	tags, err = db.ListTags()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []TagResult{{"job", 3, 4}, {"home", 2, 2}, {"job/alpha", 0, 1}, {"job/alpha/x", 1, 1}}
+	if !slices.Equal(tags, want) {
+		t.Errorf("ListTags after rename = %v, want %v", tags, want)
+	}
+
+	if _, err := idx.RenameTag("job", "a, b"); err == nil {
+		t.Error("RenameTag to a list should fail")
+	}
+}
+
+func TestGetBrokenLinks(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// "a.md" is walked before "zeta.md", so its link to zeta resolves only
+	// once zeta is indexed.
+	write("a.md", "[[zeta]]\n[[missing#Intro]]\n")
+	write("b.md", "text\n\n[[Nowhere]]\n")
+	write("zeta.md", "# Zeta\n")
+
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := db.GetBrokenLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BrokenLinkResult{
+		{SourcePath: "a.md", SourceTitle: "a", Target: "missing.md", Section: "Intro", Line: 2, Col: 0},
+		{SourcePath: "b.md", SourceTitle: "b", Target: "nowhere.md", Line: 3, Col: 0},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("GetBrokenLinks() = %+v, want %+v", got, want)
+	}
+
+	// Creating the missing note fixes the link.
+	write("nowhere.md", "")
+	if err := idx.IndexFile(filepath.Join(root, "nowhere.md")); err != nil {
+		t.Fatal(err)
+	}
+	got, err = db.GetBrokenLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Target != "missing.md" {
+		t.Errorf("after creating nowhere.md, GetBrokenLinks() = %+v", got)
+	}
+}
+
+func TestMarkdownLinks(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"notes/a.md":       "See [plan](../projects/plan.md#Goals) and [gone](missing.md).\n",
+		"projects/plan.md": "# Plan\n",
+		"b.md":             "[web](https://example.com/plan.md) [[plan]]\n",
+	} {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewIndexer(db, root).IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	backlinks, err := db.GetBacklinks("projects/plan.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sources []string
+	for _, b := range backlinks {
+		sources = append(sources, b.SourcePath)
+	}
+	if want := []string{"b.md", "notes/a.md"}; !slices.Equal(sources, want) {
+		t.Errorf("backlinks to plan = %v, want %v", sources, want)
+	}
+
+	broken, err := db.GetBrokenLinks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []BrokenLinkResult{
+		{SourcePath: "notes/a.md", SourceTitle: "a", Target: "missing.md", Line: 1, Col: 42, Markdown: true},
+	}
+	if !slices.Equal(broken, want) {
+		t.Errorf("GetBrokenLinks() = %+v, want %+v", broken, want)
+	}
+}
+
+func TestAttachments(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"note.md":           "![diagram](img/diagram.png)\n",
+		"img/diagram.png":   "png",
+		"docs/paper.pdf":    "pdf!",
+		".DS_Store":         "",
+		".git/objects/x":    "",
+		"templates/tpl.txt": "",
+	} {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := NewIndexer(db, root)
+	idx.SetSkipDir("templates")
+	stats, err := idx.Rebuild(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 1 || stats.Attachments != 2 {
+		t.Errorf("Rebuild() = %+v, want 1 file and 2 attachments", stats)
+	}
+
+	paths := func() []string {
+		t.Helper()
+		attachments, err := db.ListAttachments()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var paths []string
+		for _, a := range attachments {
+			paths = append(paths, filepath.ToSlash(a.Path))
+		}
+		return paths
+	}
+	if got, want := paths(), []string{"docs/paper.pdf", "img/diagram.png"}; !slices.Equal(got, want) {
+		t.Fatalf("ListAttachments = %v, want %v", got, want)
+	}
+
+	// The watcher path: a new file is added, a deleted one dropped.
+	add := filepath.Join(root, "img", "photo.jpg")
+	if err := os.WriteFile(add, []byte("jpg"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexAttachment(add); err != nil {
+		t.Fatal(err)
+	}
+	gone := filepath.Join(root, "docs", "paper.pdf")
+	if err := os.Remove(gone); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexAttachment(gone); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := paths(), []string{"img/diagram.png", "img/photo.jpg"}; !slices.Equal(got, want) {
+		t.Errorf("after changes, ListAttachments = %v, want %v", got, want)
+	}
+}
+
+func TestExternalSources(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	src := t.TempDir()
+	for abs, content := range map[string]string{
+		filepath.Join(root, "guide.md"):          "See [[@external/work/guide]] and [[auth]].\n",
+		filepath.Join(src, "guide.md"):           "# Work guide\n\nSee [auth](api/auth.md).\n",
+		filepath.Join(src, "api", "auth.md"):     "# Auth\n",
+		filepath.Join(src, "api", "diagram.png"): "png",
+		filepath.Join(src, ".drafts", "x.md"):    "hidden",
+	} {
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := NewIndexer(db, root)
+	idx.SetSources(vault.Sources{{Name: "work", Root: src}})
+	stats, err := idx.Rebuild(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 3 || stats.Attachments != 0 {
+		t.Errorf("Rebuild() = %+v, want 3 files and no attachments", stats)
+	}
+
+	// The external guide.md shares its name with the vault's without
+	// clashing, and is linked by path.
+	guide := filepath.FromSlash("@external/work/guide.md")
+	if got, err := db.ResolveLink("@external/work/guide.md"); err != nil || got != guide {
+		t.Errorf("ResolveLink(@external/work/guide) = %q, %v, want %q", got, err, guide)
+	}
+	if got, err := db.ResolveLink("guide.md"); err != nil || got != "guide.md" {
+		t.Errorf("ResolveLink(guide) = %q, %v, want guide.md", got, err)
+	}
+	backlinks, err := db.GetBacklinks(guide)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 1 || backlinks[0].SourcePath != "guide.md" {
+		t.Errorf("GetBacklinks(%s) = %+v, want the vault guide", guide, backlinks)
+	}
+
+	// Markdown links inside a source resolve within it.
+	auth := filepath.FromSlash("@external/work/api/auth.md")
+	backlinks, err = db.GetBacklinks(auth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 1 || backlinks[0].SourcePath != guide {
+		t.Errorf("GetBacklinks(%s) = %+v, want the external guide", auth, backlinks)
+	}
+
+	// The watcher path for a source: edits are indexed under the virtual
+	// path and removals drop the note.
+	if err := os.WriteFile(filepath.Join(src, "api", "auth.md"), []byte("# Authentication\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.IndexFile(filepath.Join(src, "api", "auth.md")); err != nil {
+		t.Fatal(err)
+	}
+	results, err := db.Search("Authentication", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != auth {
+		t.Errorf("Search(Authentication) = %+v, want %s", results, auth)
+	}
+	if err := os.Remove(filepath.Join(src, "api", "auth.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.RemoveFile(filepath.Join(src, "api", "auth.md")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := db.ResolveLink("@external/work/api/auth.md"); err != nil || got != "" {
+		t.Errorf("after removal, ResolveLink(auth) = %q, %v, want none", got, err)
+	}
+
+	// Without the source, the next update drops its notes.
+	idx.SetSources(nil)
+	stats, err = idx.Update(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Removed != 1 {
+		t.Errorf("Update() without sources removed %d notes, want 1", stats.Removed)
+	}
+}
+
+func TestEncryptedNotesAreSkipped(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	secret := filepath.Join(root, "secret.md")
+	write := func(path, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(filepath.Join(root, "plain.md"), "# Plain\n")
+	write(secret, "\x00GITCRYPT\x00\x8fciphertext")
+
+	idx := NewIndexer(db, root)
+	stats, err := idx.Rebuild(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Files != 2 || stats.Indexed != 1 || stats.Encrypted != 1 {
+		t.Errorf("Rebuild() = %+v, want 1 of 2 files indexed and 1 encrypted", stats)
+	}
+	if hash, err := db.GetNoteHash("secret.md"); err != nil || hash != "" {
+		t.Errorf("encrypted note indexed with hash %q, %v", hash, err)
+	}
+
+	// Unlocking the vault indexes the note; locking it again drops it.
+	write(secret, "# Secret\n\nlaunch codes\n")
+	if err := idx.IndexFile(secret); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := db.Search("launch", 10); err != nil || len(results) != 1 {
+		t.Errorf("Search(launch) after unlock = %v, %v; want the note", results, err)
+	}
+	write(secret, "-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n")
+	if err := idx.IndexFile(secret); err != nil {
+		t.Fatal(err)
+	}
+	if results, err := db.Search("launch", 10); err != nil || len(results) != 0 {
+		t.Errorf("Search(launch) after lock = %v, %v; want nothing", results, err)
+	}
+}
+
+func TestLinkGraph(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"a.md":    "[[zeta]] and [[zeta|again]]\n[[b]] [[missing]]\n",
+		"b.md":    "[[a]]\n",
+		"zeta.md": "---\ntitle: Zeta \"Z\"\n---\n",
+		"lone.md": "",
+	} {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewIndexer(db, root).IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	g, err := db.LinkGraph()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(g.Nodes) != 4 {
+		t.Errorf("nodes = %+v, want all 4 notes", g.Nodes)
+	}
+	wantEdges := []GraphEdge{
+		{Source: "a.md", Target: "b.md", Count: 1},
+		{Source: "a.md", Target: "zeta.md", Count: 2},
+		{Source: "b.md", Target: "a.md", Count: 1},
+	}
+	if !slices.Equal(g.Edges, wantEdges) {
+		t.Errorf("edges = %+v, want %+v", g.Edges, wantEdges)
+	}
+
+	neighbors, err := db.Neighbors("a.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNeighbors := []Neighbor{
+		{Path: "b.md", Title: "b", Out: true, In: true},
+		{Path: "zeta.md", Title: `Zeta "Z"`, Out: true},
+	}
+	if !slices.Equal(neighbors, wantNeighbors) {
+		t.Errorf("Neighbors(a.md) = %+v, want %+v", neighbors, wantNeighbors)
+	}
+	if neighbors, err = db.Neighbors("zeta.md"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []Neighbor{{Path: "a.md", Title: "a", In: true}}; !slices.Equal(neighbors, want) {
+		t.Errorf("Neighbors(zeta.md) = %+v, want %+v", neighbors, want)
+	}
+
+	var dot strings.Builder
+	if err := g.WriteDOT(&dot); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`"zeta.md" [label="Zeta \"Z\""];`,
+		`"a.md" -> "zeta.md" [weight=2];`,
+		`"b.md" -> "a.md";`,
+	} {
+		if !strings.Contains(dot.String(), want) {
+			t.Errorf("DOT output missing %s:\n%s", want, dot.String())
+		}
+	}
+}
+
+func TestKeywordsBoostRanking(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"mentions.md": "Notes on the kbd layout, kbd firmware and kbd cases.\n",
+		"keyboard.md": "---\nkeywords: [kbd, qmk]\n---\nMy split keyboard build log.\n",
+	}
+	for rel, content := range files {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewIndexer(db, root).IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := db.Search("kbd", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0].Path != "keyboard.md" {
+		t.Errorf("Search(kbd) = %+v, want keyboard.md first", results)
+	}
+	if results, _ := db.Search("qmk", 10); len(results) != 1 || results[0].Path != "keyboard.md" {
+		t.Errorf("Search(qmk) = %+v, want keyboard.md", results)
+	}
+}
+
+func TestRandomNotePath(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	notes := map[string]string{
+		"a.md":          "---\ntags: [review]\n---\n# A\n",
+		"b.md":          "# B\n",
+		"projects/c.md": "---\ntags: [review]\n---\n# C\n",
+	}
+	for rel, content := range notes {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewIndexer(db, root).IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := map[string]bool{}
+	for range 50 {
+		path, err := db.RandomNotePath(ParseQuery("tag:review"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		seen[path] = true
+	}
+	if len(seen) != 2 || !seen["a.md"] || !seen["projects/c.md"] {
+		t.Errorf("tag:review picked %v, want a.md and projects/c.md", seen)
+	}
+
+	path, err := db.RandomNotePath(ParseQuery("path:projects/"))
+	if err != nil || path != "projects/c.md" {
+		t.Errorf("path:projects/ = %q, %v; want projects/c.md", path, err)
+	}
+	path, err = db.RandomNotePath(ParseQuery("path:archive/"))
+	if err != nil || path != "" {
+		t.Errorf("path:archive/ = %q, %v; want no note", path, err)
+	}
+}
+
+func TestFolderScope(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"index.md":            "[[a/notes]] and [[notes]]\n",
+		"projects/a/notes.md": "# A\n",
+		"projects/b/notes.md": "# B\n",
+	} {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if _, err := db.SetBasenameScope(ScopeFolder); err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	for target, want := range map[string]string{
+		"a/notes.md":          "projects/a/notes.md",
+		"B/Notes.md":          "projects/b/notes.md",
+		"projects/b/notes.md": "projects/b/notes.md",
+		"notes.md":            "projects/a/notes.md", // shortest path, then alphabetical
+		"c/notes.md":          "",
+		"tes.md":              "",
+	} {
+		got, err := db.ResolveLink(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("ResolveLink(%q) = %q, want %q", target, got, want)
+		}
+	}
+	for target, want := range map[string][]string{
+		"notes.md":   {"projects/a/notes.md", "projects/b/notes.md"},
+		"a/notes.md": {"projects/a/notes.md"},
+		"c/notes.md": nil,
+	} {
+		got, err := db.LinkCandidates(target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("LinkCandidates(%q) = %v, want %v", target, got, want)
+		}
+	}
+	if got, err := db.LooseLinkCandidates("Notes"); err != nil || !slices.Equal(got, []string{"projects/a/notes.md", "projects/b/notes.md"}) {
+		t.Errorf("LooseLinkCandidates(Notes) = %v, %v", got, err)
+	}
+
+	backlinks, err := db.GetBacklinks("projects/b/notes.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 0 {
+		t.Errorf("b/notes backlinks = %+v, want none", backlinks)
+	}
+	if backlinks, err = db.GetBacklinks("projects/a/notes.md"); err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 2 {
+		t.Errorf("a/notes backlinks = %+v, want both links", backlinks)
+	}
+
+	if got, _ := db.ConflictingNote("projects/c/notes.md"); got != "" {
+		t.Errorf("ConflictingNote(c/notes) = %q, want none", got)
+	}
+	if got, _ := db.ConflictingNote("Projects/A/Notes.md"); got != "projects/a/notes.md" {
+		t.Errorf("ConflictingNote(A/Notes) = %q, want projects/a/notes.md", got)
+	}
+
+	// Vault scope cannot hold two notes named notes.md.
+	if _, err := db.SetBasenameScope(ScopeVault); err == nil {
+		t.Error("switching to vault scope with shared basenames should fail")
+	}
+	if got, _ := db.ConflictingNote("projects/c/notes.md"); got != "" {
+		t.Errorf("failed switch changed scope: ConflictingNote = %q", got)
+	}
+
+	if err := os.Remove(filepath.Join(root, "projects/b/notes.md")); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.RemoveFile(filepath.Join(root, "projects/b/notes.md")); err != nil {
+		t.Fatal(err)
+	}
+	changed, err := db.SetBasenameScope(ScopeVault)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("switching scope should report rewritten keys")
+	}
+	if got, _ := db.ConflictingNote("projects/c/notes.md"); got != "projects/a/notes.md" {
+		t.Errorf("vault scope ConflictingNote = %q, want projects/a/notes.md", got)
+	}
+}
+
+// TestFolderScopeFullPath checks that a link naming a note's full path, as
+// Obsidian writes them in vaults with duplicate names, picks that note over
+// deeper ones ending in the same path.
+func TestFolderScopeFullPath(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"index.md":                  "[[projects/notes]] and [[Archive/Projects/Notes|old]]\n",
+		"notes.md":                  "# Root\n",
+		"projects/notes.md":         "# Current\n",
+		"archive/projects/notes.md": "# Old\n",
+	} {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.SetBasenameScope(ScopeFolder); err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	for target, want := range map[string][]string{
+		"projects/notes.md":         {"projects/notes.md", "archive/projects/notes.md"},
+		"archive/projects/notes.md": {"archive/projects/notes.md"},
+		"notes.md":                  {"notes.md", "projects/notes.md", "archive/projects/notes.md"},
+	} {
+		got, err := db.LinkCandidates(filepath.FromSlash(target))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range got {
+			got[i] = filepath.ToSlash(got[i])
+		}
+		if !slices.Equal(got, want) {
+			t.Errorf("LinkCandidates(%q) = %v, want %v", target, got, want)
+		}
+	}
+	for path, want := range map[string]int{"projects/notes.md": 1, "archive/projects/notes.md": 1, "notes.md": 0} {
+		backlinks, err := db.GetBacklinks(filepath.FromSlash(path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backlinks) != want {
+			t.Errorf("backlinks of %s = %+v, want %d", path, backlinks, want)
+		}
+	}
+
+	// Deleting the best match hands its links to the next one.
+	if err := idx.RemoveFile(filepath.Join(root, "projects", "notes.md")); err != nil {
+		t.Fatal(err)
+	}
+	if backlinks, err := db.GetBacklinks(filepath.FromSlash("archive/projects/notes.md")); err != nil || len(backlinks) != 2 {
+		t.Errorf("backlinks of archive/projects/notes.md after the delete = %+v, %v; want both links", backlinks, err)
+	}
+}
+
+func TestMoveDirFolderScope(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"index.md":            "[[a/notes]] and [[notes]]\n",
+		"projects/a/notes.md": "# A\n",
+		"projects/b/notes.md": "# B\n",
+	} {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.SetBasenameScope(ScopeFolder); err != nil {
+		t.Fatal(err)
+	}
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+	oldID, err := db.GetNoteIDByPath("projects/a/notes.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldDir, newDir := filepath.Join(root, "projects", "a"), filepath.Join(root, "projects", "c")
+	if err := os.Rename(oldDir, newDir); err != nil {
+		t.Fatal(err)
+	}
+	if err := idx.MoveDir(oldDir, newDir); err != nil {
+		t.Fatal(err)
+	}
+
+	if id, err := db.GetNoteIDByPath("projects/c/notes.md"); err != nil || id != oldID {
+		t.Errorf("moved note id = %d, %v, want %d", id, err, oldID)
+	}
+	// [[a/notes]] no longer matches a path, and [[notes]] now resolves to
+	// b/notes.md, first of the two equally short paths.
+	for path, want := range map[string]int{"projects/b/notes.md": 1, "projects/c/notes.md": 0} {
+		backlinks, err := db.GetBacklinks(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(backlinks) != want {
+			t.Errorf("backlinks of %s = %v, want %d", path, backlinks, want)
+		}
+	}
+}
+
+func TestLinkNames(t *testing.T) {
+	got := LinkNames([]string{
+		"notes.md",
+		"projects/a/notes.md",
+		"projects/b/notes.md",
+		"archive/a/notes.md",
+		"todo.md",
+	})
+	want := map[string]string{
+		"notes.md":            "notes",
+		"projects/a/notes.md": "projects/a/notes",
+		"projects/b/notes.md": "b/notes",
+		"archive/a/notes.md":  "archive/a/notes",
+		"todo.md":             "todo",
+	}
+	for p, w := range want {
+		if got[p] != w {
+			t.Errorf("LinkNames[%q] = %q, want %q", p, got[p], w)
+		}
+	}
+}
+
+// writeBenchVault writes n linked, tagged notes with a few paragraphs each,
+// spread over folders like a real vault.
+func writeBenchVault(tb testing.TB, n int) string {
+	tb.Helper()
+	root := tb.TempDir()
+	for i := range n {
+		path := filepath.Join(root, fmt.Sprintf("area%d", i%10), fmt.Sprintf("note-%d.md", i))
+		content := fmt.Sprintf("---\ntitle: Note %d\ntags: [topic/%d, project]\n---\n\n# Note %d\n\n"+
+			"See [[note-%d]] and [[note-%d#Details]].\n\n## Details\n\n%s\n\n- [ ] follow up on %d\n",
+			i, i%20, i, (i+1)%n, (i+7)%n, strings.Repeat("The quick brown fox jumps over the lazy dog. ", 40), i)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			tb.Fatal(err)
+		}
+	}
+	return root
+}
+
+// BenchmarkRebuild indexes a 500-note vault from scratch, as startup does.
+func BenchmarkRebuild(b *testing.B) {
+	root := writeBenchVault(b, 500)
+	db, err := OpenMemory()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	idx := NewIndexer(db, root)
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := idx.Rebuild(nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkUpdate checks an unchanged 500-note vault, as `kopr index` does
+// from cron.
+func BenchmarkUpdate(b *testing.B) {
+	root := writeBenchVault(b, 500)
+	db, err := OpenMemory()
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	idx := NewIndexer(db, root)
+	if _, err := idx.Rebuild(nil); err != nil {
+		b.Fatal(err)
+	}
+
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := idx.Update(nil); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestBacklinksFilter(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	notes := map[string]string{
+		"hub.md":    "---\nstatus: draft\n---\n[[a]] [[b]] [[hub]]\n",
+		"a.md":      "---\nstatus: draft\n---\n[[b]] [[b]]\n",
+		"b.md":      "# B\n",
+		"orphan.md": "---\nstatus: draft\n---\n# Orphan\n",
+	}
+	for rel, content := range notes {
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewIndexer(db, root).IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		// hub links to itself, which doesn't count.
+		{"backlinks:0", []string{"hub.md", "orphan.md"}},
+		{"status:draft backlinks:0", []string{"hub.md", "orphan.md"}},
+		{"backlinks:1", []string{"a.md"}},
+		{"backlinks:>1", []string{"b.md"}},
+		{"backlinks:<2 status:draft", []string{"a.md", "hub.md", "orphan.md"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			results, err := db.SearchQuery(ParseQuery(tt.query), 50)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, r := range results {
+				got = append(got, r.Path)
+			}
+			sort.Strings(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+)
+
+// Drift is how the index differs from the vault, as found by Check.
+type Drift struct {
+	Corrupt    []string // problems SQLite's integrity checks reported
+	Unindexed  []string // vault files with no note row
+	Stale      []string // vault files changed since they were indexed
+	Missing    []string // note rows whose file is gone
+	MissingFTS []string // notes with no full-text row
+	OrphanFTS  int      // full-text rows with no note
+}
+
+// Clean reports whether Check found nothing to repair.
+func (d Drift) Clean() bool {
+	return len(d.Corrupt) == 0 && len(d.Unindexed) == 0 && len(d.Stale) == 0 &&
+		len(d.Missing) == 0 && len(d.MissingFTS) == 0 && d.OrphanFTS == 0
+}
+
+// Check compares the index with the vault's markdown files and runs SQLite's
+// integrity checks on the database and the full-text table. Paths in the
+// result are relative to the vault and sorted.
+func (idx *Indexer) Check() (Drift, error) {
+	var d Drift
+	corrupt, err := idx.db.integrityProblems()
+	if err != nil {
+		return d, err
+	}
+	d.Corrupt = corrupt
+
+	known, err := idx.db.NoteHashes()
+	if err != nil {
+		return d, err
+	}
+	paths, _, err := idx.vaultFiles()
+	if err != nil {
+		return d, err
+	}
+	external, err := idx.sourceFiles()
+	if err != nil {
+		return d, err
+	}
+	paths = append(paths, external...)
+	present := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		n, err := idx.readNote(p)
+		if err != nil {
+			return d, err
+		}
+		if n == nil {
+			continue
+		}
+		present[n.relPath] = true
+		switch hash, ok := known[n.relPath]; {
+		case !ok:
+			d.Unindexed = append(d.Unindexed, n.relPath)
+		case hash != n.hash:
+			d.Stale = append(d.Stale, n.relPath)
+		}
+	}
+	for rel := range known {
+		if !present[rel] {
+			d.Missing = append(d.Missing, rel)
+		}
+	}
+
+	noFTS, err := idx.db.notesWithoutFTS()
+	if err != nil {
+		return d, err
+	}
+	for _, rel := range noFTS {
+		if present[rel] {
+			d.MissingFTS = append(d.MissingFTS, rel)
+		}
+	}
+	if err := idx.db.q.QueryRow("SELECT COUNT(*) FROM notes_fts WHERE rowid NOT IN (SELECT id FROM notes)").Scan(&d.OrphanFTS); err != nil {
+		return d, err
+	}
+
+	slices.Sort(d.Unindexed)
+	slices.Sort(d.Stale)
+	slices.Sort(d.Missing)
+	slices.Sort(d.MissingFTS)
+	return d, nil
+}
+
+// Repair fixes the drift Check found in one transaction: rows for missing
+// files and orphaned full-text rows are deleted, and unindexed, stale and
+// full-text-less notes are indexed again. Corruption cannot be repaired in
+// place; the caller must delete the database and rebuild it.
+func (idx *Indexer) Repair(d Drift) error {
+	return idx.db.InTx(func(tx *DB) error {
+		for _, rel := range d.Missing {
+			if err := tx.DeleteNote(rel); err != nil {
+				return fmt.Errorf("remove %s: %w", rel, err)
+			}
+		}
+		if len(d.Missing) > 0 {
+			if err := resolveDangling(tx); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.reconcileFTS(); err != nil {
+			return err
+		}
+		for _, rel := range slices.Concat(d.Unindexed, d.Stale, d.MissingFTS) {
+			if err := idx.indexFile(tx, filepath.Join(idx.vaultRoot, rel)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// integrityProblems runs SQLite's integrity check and the FTS5 table's own
+// check, returning what they report wrong.
+func (db *DB) integrityProblems() ([]string, error) {
+	rows, err := db.q.Query("PRAGMA integrity_check")
+	if err != nil {
+		return nil, err
+	}
+	var problems []string
+	for rows.Next() {
+		var msg string
+		if err := rows.Scan(&msg); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		if msg != "ok" {
+			problems = append(problems, msg)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	// FTS5 reports a damaged index as an error from this special insert.
+	if _, err := db.q.Exec("INSERT INTO notes_fts(notes_fts) VALUES('integrity-check')"); err != nil {
+		problems = append(problems, fmt.Sprintf("notes_fts: %v", err))
+	}
+	return problems, nil
+}
+
+// reconcileFTS brings notes_fts back in step with notes: full-text rows
+// with no note are deleted, and notes with no full-text row get their hash
+// cleared, so the next indexing pass rewrites them instead of skipping them
+// as unchanged. It returns how many rows were out of step.
+func (db *DB) reconcileFTS() (int64, error) {
+	res, err := db.q.Exec("DELETE FROM notes_fts WHERE rowid NOT IN (SELECT id FROM notes)")
+	if err != nil {
+		return 0, fmt.Errorf("drop orphaned full-text rows: %w", err)
+	}
+	orphans, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	res, err = db.q.Exec("UPDATE notes SET hash = '' WHERE hash != '' AND id NOT IN (SELECT rowid FROM notes_fts)")
+	if err != nil {
+		return 0, fmt.Errorf("mark notes without full-text rows: %w", err)
+	}
+	missing, err := res.RowsAffected()
+	return orphans + missing, err
+}
+
+// notesWithoutFTS returns the paths of notes that have no notes_fts row.
+func (db *DB) notesWithoutFTS() ([]string, error) {
+	rows, err := db.q.Query("SELECT path FROM notes WHERE id NOT IN (SELECT rowid FROM notes_fts)")
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		paths = append(paths, path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
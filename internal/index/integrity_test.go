@@ -0,0 +1,88 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCheckRepair(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rel := range []string{"kept.md", "edited.md", "gone.md", "nofts.md"} {
+		write(rel, "# "+rel+"\n")
+	}
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := idx.Check(); err != nil || !d.Clean() {
+		t.Fatalf("Check() after IndexAll = %+v, %v; want clean", d, err)
+	}
+
+	// Drift the index away from the vault behind the indexer's back.
+	write("edited.md", "# edited\n\nnew text\n")
+	write("new.md", "# new\n")
+	if err := os.Remove(filepath.Join(root, "gone.md")); err != nil {
+		t.Fatal(err)
+	}
+	id, err := db.GetNoteIDByPath("nofts.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Conn().Exec("DELETE FROM notes_fts WHERE rowid = ?", id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Conn().Exec("INSERT INTO notes_fts(rowid, title, content, tags, headings, keywords) VALUES (9999, 'x', 'x', '', '', '')"); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := idx.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, pair := range map[string][2][]string{
+		"Corrupt":    {d.Corrupt, nil},
+		"Unindexed":  {d.Unindexed, {"new.md"}},
+		"Stale":      {d.Stale, {"edited.md"}},
+		"Missing":    {d.Missing, {"gone.md"}},
+		"MissingFTS": {d.MissingFTS, {"nofts.md"}},
+	} {
+		if !slices.Equal(pair[0], pair[1]) {
+			t.Errorf("%s = %v, want %v", name, pair[0], pair[1])
+		}
+	}
+	if d.OrphanFTS != 1 {
+		t.Errorf("OrphanFTS = %d, want 1", d.OrphanFTS)
+	}
+
+	if err := idx.Repair(d); err != nil {
+		t.Fatal(err)
+	}
+	if d, err := idx.Check(); err != nil || !d.Clean() {
+		t.Errorf("Check() after Repair = %+v, %v; want clean", d, err)
+	}
+	results, err := db.Search("new text", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Path != "edited.md" {
+		t.Errorf("search after repair = %+v", results)
+	}
+}
@@ -0,0 +1,168 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// LintProblem is something wrong in a note that the formatter can't fix.
+type LintProblem struct {
+	Path    string // relative to the vault
+	Line    int    // 1-based
+	Col     int    // 1-based, or 0 when the problem is the whole line
+	Message string
+}
+
+// String formats the problem as "path:line:col: message", the way
+// compilers do, so editors and CI logs can link to it.
+func (p LintProblem) String() string {
+	if p.Col == 0 {
+		return fmt.Sprintf("%s:%d: %s", p.Path, p.Line, p.Message)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s", p.Path, p.Line, p.Col, p.Message)
+}
+
+// Lint reports links to no note, links to a section their note doesn't
+// have, and merge conflicts left in the vault's notes, sorted by path and
+// position. External sources aren't linted. Run it on an up-to-date index.
+func (idx *Indexer) Lint() ([]LintProblem, error) {
+	var problems []LintProblem
+
+	broken, err := idx.db.GetBrokenLinks()
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range broken {
+		if vault.IsExternal(l.SourcePath) {
+			continue
+		}
+		problems = append(problems, LintProblem{
+			Path:    l.SourcePath,
+			Line:    l.Line,
+			Col:     l.Col + 1,
+			Message: "link to missing note " + linkText(l.Target, l.Section, l.Markdown),
+		})
+	}
+
+	sections, err := idx.lintSections()
+	if err != nil {
+		return nil, err
+	}
+	problems = append(problems, sections...)
+
+	paths, _, err := idx.vaultFiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range paths {
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range markdown.FindConflicts(strings.Split(string(content), "\n")) {
+			problems = append(problems, LintProblem{
+				Path:    idx.relPath(p),
+				Line:    c.Start + 1,
+				Message: "unresolved merge conflict",
+			})
+		}
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		a, b := problems[i], problems[j]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Col < b.Col
+	})
+	return problems, nil
+}
+
+// lintSections reports links whose #section names no heading in the note
+// they link to.
+func (idx *Indexer) lintSections() ([]LintProblem, error) {
+	rows, err := idx.db.q.Query(`
+		SELECT n.path, t.path, l.target_path, l.section, l.line, l.col, l.markdown
+		FROM links l
+		JOIN notes n ON n.id = l.source_id
+		JOIN notes t ON t.id = l.target_id
+		WHERE l.section != ''
+	`)
+	if err != nil {
+		return nil, err
+	}
+	type sectionLink struct {
+		source, target, targetPath, section string
+		line, col                           int
+		markdown                            bool
+	}
+	var links []sectionLink
+	for rows.Next() {
+		var l sectionLink
+		if err := rows.Scan(&l.source, &l.target, &l.targetPath, &l.section, &l.line, &l.col, &l.markdown); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		links = append(links, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	var problems []LintProblem
+	headings := make(map[string][]HeadingResult)
+	for _, l := range links {
+		if vault.IsExternal(l.source) {
+			continue
+		}
+		hs, ok := headings[l.target]
+		if !ok {
+			if hs, err = idx.db.GetHeadingsForNote(l.target); err != nil {
+				return nil, err
+			}
+			headings[l.target] = hs
+		}
+		found := false
+		for _, h := range hs {
+			if markdown.HeadingMatches(h.Text, l.section) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			problems = append(problems, LintProblem{
+				Path:    l.source,
+				Line:    l.line,
+				Col:     l.col + 1,
+				Message: "link to missing section " + linkText(l.targetPath, l.section, l.markdown),
+			})
+		}
+	}
+	return problems, nil
+}
+
+// linkText renders a link target the way it was likely written, as
+// [[name#section]] or (path.md#section).
+func linkText(target, section string, markdownLink bool) string {
+	if !markdownLink {
+		target = strings.TrimSuffix(target, ".md")
+	}
+	if section != "" {
+		target += "#" + section
+	}
+	if markdownLink {
+		return "(" + target + ")"
+	}
+	return "[[" + target + "]]"
+}
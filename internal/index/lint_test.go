@@ -0,0 +1,58 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(root, rel), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("guide.md", "# Guide\n\n## Setup\n")
+	write("a.md", "See [[guide#setup]] and [[guide#Teardown]].\n\n[[Nowhere]] or [x](gone.md)\n")
+	write("b.md", "# B\n\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> main\n")
+	write("clean.md", "[[guide]] and [[a]]\n")
+
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+	problems, err := idx.Lint()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"a.md:1:25: link to missing section [[guide#Teardown]]",
+		"a.md:3:1: link to missing note [[nowhere]]",
+		"a.md:3:16: link to missing note (gone.md)",
+		"b.md:3: unresolved merge conflict",
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Lint() =\n%q\nwant\n%q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("problem %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
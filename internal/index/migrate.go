@@ -0,0 +1,331 @@
+package index
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// migration is one step upgrading the index from one schema version to the
+// next.
+type migration struct {
+	name string
+	up   func(tx *DB) error
+}
+
+// migrations upgrade an index to the current schema, in order: an index at
+// version n has had the first n applied. New indexes are created straight
+// from schema at the latest version, so a schema change goes both into
+// schema and into a new step at the end of this list. Steps must never be
+// reordered or removed.
+//
+// Steps adding a column that indexing fills in use reparsing, so the next
+// IndexAll re-parses every note.
+//
+// The steps up to links.markdown predate schema_version. An index without
+// the table may already have any of them, so they check for their change
+// first.
+var migrations = []migration{
+	{"notes_fts content and keywords", migrateFTS},
+	{"notes.basename_key", func(tx *DB) error {
+		added, err := tx.addColumns("notes", "basename_key TEXT NOT NULL DEFAULT ''")
+		if err != nil || !added {
+			return err
+		}
+		return tx.backfillBasenameKeys()
+	}},
+	{"notes.summary", reparsing("notes", "summary TEXT NOT NULL DEFAULT ''")},
+	{"notes.created and notes.updated", reparsing("notes",
+		"created INTEGER NOT NULL DEFAULT 0",
+		"updated INTEGER NOT NULL DEFAULT 0")},
+	{"notes.reviewed", reparsing("notes", "reviewed INTEGER NOT NULL DEFAULT 0")},
+	{"notes.words", reparsing("notes", "words INTEGER NOT NULL DEFAULT 0")},
+	{"tags.parent_id", reparsing("tags", "parent_id INTEGER REFERENCES tags(id)")},
+	{"links.markdown", reparsing("links", "markdown INTEGER NOT NULL DEFAULT 0")},
+	{"lowercase links.target_path", func(tx *DB) error {
+		_, err := tx.q.Exec("UPDATE links SET target_path = lower(target_path)")
+		return err
+	}},
+	{"note_opens", func(tx *DB) error {
+		if _, err := tx.q.Exec(`
+			CREATE TABLE note_opens (
+				note_id INTEGER NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+				opened_at INTEGER NOT NULL
+			)`); err != nil {
+			return fmt.Errorf("create note_opens: %w", err)
+		}
+		// Each note's last open is all the history there is so far. Indexes
+		// older than note_visits have none.
+		visits, err := tx.tableNames("name = 'note_visits'")
+		if err != nil || len(visits) == 0 {
+			return err
+		}
+		_, err = tx.q.Exec("INSERT INTO note_opens (note_id, opened_at) SELECT note_id, last_opened FROM note_visits WHERE last_opened > 0")
+		return err
+	}},
+	{"notes_fts delete trigger", func(tx *DB) error {
+		if _, err := tx.q.Exec(`
+			CREATE TRIGGER IF NOT EXISTS notes_fts_delete AFTER DELETE ON notes BEGIN
+				DELETE FROM notes_fts WHERE rowid = old.id;
+			END`); err != nil {
+			return fmt.Errorf("create notes_fts_delete: %w", err)
+		}
+		_, err := tx.reconcileFTS()
+		return err
+	}},
+	{"notes.uid", reparsing("notes", "uid TEXT NOT NULL DEFAULT ''")},
+}
+
+// initSchema creates a new index at the latest schema version, or upgrades
+// an existing one step by step. An index written by a newer Kopr, whose
+// version this build doesn't know, is dropped and created afresh; notes are
+// re-indexed from the vault, but open counts are lost.
+func (db *DB) initSchema() error {
+	fresh, err := db.isEmpty()
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		version, err := db.schemaVersion()
+		if err != nil {
+			return err
+		}
+		if version > len(migrations) {
+			if err := db.dropAll(); err != nil {
+				return fmt.Errorf("drop index at unknown version %d: %w", version, err)
+			}
+			fresh = true
+		} else if err := db.migrate(version); err != nil {
+			return err
+		}
+	}
+
+	// Tables added since the index was created are made here.
+	if _, err := db.q.Exec(schema); err != nil {
+		return err
+	}
+	if fresh {
+		return db.setSchemaVersion(len(migrations))
+	}
+	return nil
+}
+
+// migrate applies the migrations after version, each in its own
+// transaction together with the version it brings the index to.
+func (db *DB) migrate(version int) error {
+	for i := version; i < len(migrations); i++ {
+		m := migrations[i]
+		err := db.InTx(func(tx *DB) error {
+			if err := m.up(tx); err != nil {
+				return err
+			}
+			return tx.setSchemaVersion(i + 1)
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", i+1, m.name, err)
+		}
+	}
+	return nil
+}
+
+// isEmpty reports whether the database has no index tables yet.
+func (db *DB) isEmpty() (bool, error) {
+	var n int
+	if err := db.q.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = 'notes'").Scan(&n); err != nil {
+		return false, fmt.Errorf("read tables: %w", err)
+	}
+	return n == 0, nil
+}
+
+// schemaVersion returns the index's schema version: 0 for an index from
+// before versions were recorded.
+func (db *DB) schemaVersion() (int, error) {
+	if _, err := db.q.Exec("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)"); err != nil {
+		return 0, fmt.Errorf("create schema_version: %w", err)
+	}
+	var version int
+	err := db.q.QueryRow("SELECT version FROM schema_version").Scan(&version)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return version, nil
+}
+
+func (db *DB) setSchemaVersion(version int) error {
+	if _, err := db.q.Exec("CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)"); err != nil {
+		return fmt.Errorf("create schema_version: %w", err)
+	}
+	if _, err := db.q.Exec("DELETE FROM schema_version"); err != nil {
+		return fmt.Errorf("clear schema version: %w", err)
+	}
+	if _, err := db.q.Exec("INSERT INTO schema_version (version) VALUES (?)", version); err != nil {
+		return fmt.Errorf("record schema version: %w", err)
+	}
+	return nil
+}
+
+// dropAll drops every table, virtual ones first so their shadow tables go
+// with them, and the rest newest first so tables go before the ones they
+// reference.
+func (db *DB) dropAll() error {
+	return db.InTx(func(tx *DB) error {
+		if _, err := tx.q.Exec("PRAGMA defer_foreign_keys = ON"); err != nil {
+			return err
+		}
+		for _, where := range []string{"sql LIKE 'CREATE VIRTUAL TABLE%'", "name NOT LIKE 'sqlite_%'"} {
+			names, err := tx.tableNames(where)
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				if _, err := tx.q.Exec(`DROP TABLE IF EXISTS "` + name + `"`); err != nil {
+					return fmt.Errorf("drop %s: %w", name, err)
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// tableNames returns the names of the tables matching where, newest first.
+func (db *DB) tableNames(where string) ([]string, error) {
+	rows, err := db.q.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND " + where + " ORDER BY rowid DESC")
+	if err != nil {
+		return nil, fmt.Errorf("read tables: %w", err)
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// migrateFTS recreates notes_fts when it is the old external-content table
+// over notes, which has no content column for highlight() and snippet() to
+// read back, or lacks the keywords column. IndexAll repopulates it.
+func migrateFTS(tx *DB) error {
+	var ftsSQL string
+	err := tx.q.QueryRow("SELECT sql FROM sqlite_master WHERE name = 'notes_fts'").Scan(&ftsSQL)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("read notes_fts schema: %w", err)
+	}
+	if err == nil && !strings.Contains(ftsSQL, "content=notes") && strings.Contains(ftsSQL, "keywords") {
+		return nil
+	}
+	if _, err := tx.q.Exec("DROP TABLE IF EXISTS notes_fts"); err != nil {
+		return fmt.Errorf("drop notes_fts: %w", err)
+	}
+	if _, err := tx.q.Exec(`
+		CREATE VIRTUAL TABLE notes_fts USING fts5(
+			title, content, tags, headings, keywords,
+			tokenize='` + ftsTokenize[TokenizerDefault] + `'
+		)`); err != nil {
+		return fmt.Errorf("create notes_fts: %w", err)
+	}
+	return nil
+}
+
+// reparsing returns a step adding columns to table that indexing fills in,
+// clearing the note hashes so the next IndexAll re-parses every note.
+func reparsing(table string, columns ...string) func(tx *DB) error {
+	return func(tx *DB) error {
+		added, err := tx.addColumns(table, columns...)
+		if err != nil || !added {
+			return err
+		}
+		if _, err := tx.q.Exec("UPDATE notes SET hash = ''"); err != nil {
+			return fmt.Errorf("reset note hashes: %w", err)
+		}
+		return nil
+	}
+}
+
+// addColumns adds columns, given as "name type...", to table, reporting
+// false without adding any when the first already exists.
+func (db *DB) addColumns(table string, columns ...string) (bool, error) {
+	first, _, _ := strings.Cut(columns[0], " ")
+	has, err := db.hasColumn(table, first)
+	if err != nil || has {
+		return false, err
+	}
+	for _, col := range columns {
+		if _, err := db.q.Exec("ALTER TABLE " + table + " ADD COLUMN " + col); err != nil {
+			name, _, _ := strings.Cut(col, " ")
+			return false, fmt.Errorf("add %s.%s: %w", table, name, err)
+		}
+	}
+	return true, nil
+}
+
+// backfillBasenameKeys fills in basename_key for rows indexed before the
+// column existed. Later keys are kept current by UpsertNote, and by
+// SetBasenameScope when the scope changes.
+func (db *DB) backfillBasenameKeys() error {
+	rows, err := db.q.Query("SELECT path FROM notes")
+	if err != nil {
+		return fmt.Errorf("read note paths: %w", err)
+	}
+	var paths []string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return errors.Join(fmt.Errorf("scan note path: %w", err), rows.Close())
+		}
+		paths = append(paths, p)
+	}
+	if err := rows.Err(); err != nil {
+		return errors.Join(fmt.Errorf("read note paths: %w", err), rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return fmt.Errorf("close note paths: %w", err)
+	}
+
+	seen := map[string]string{}
+	for _, p := range paths {
+		key := canonicalBasenameKey(p)
+		if other, ok := seen[key]; ok && other != p {
+			return fmt.Errorf("basename conflict during migration: %q and %q", other, p)
+		}
+		seen[key] = p
+		if _, err := db.q.Exec("UPDATE notes SET basename_key = ? WHERE path = ?", key, p); err != nil {
+			return fmt.Errorf("backfill basename_key for %q: %w", p, err)
+		}
+	}
+	return nil
+}
+
+func (db *DB) hasColumn(table, col string) (has bool, err error) {
+	rows, err := db.q.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return false, err
+	}
+	defer func() { err = errors.Join(err, rows.Close()) }()
+	for rows.Next() {
+		var cid int
+		var name, ctype string
+		var notnull int
+		var dflt sql.NullString
+		var pk int
+		if err := rows.Scan(&cid, &name, &ctype, &notnull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == col {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
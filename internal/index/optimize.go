@@ -0,0 +1,160 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ftsMergePages bounds the FTS segment merging a light Optimize does, so
+// running it on every exit stays quick.
+const ftsMergePages = 500
+
+// A light Optimize rewrites the database file to give space back once
+// 1/vacuumFraction of its pages are free.
+const vacuumFraction = 4
+
+// autoVacuumIncremental is PRAGMA auto_vacuum's value for incremental mode.
+const autoVacuumIncremental = 2
+
+// OptimizeStats reports what Optimize did.
+type OptimizeStats struct {
+	SizeBefore int64 // database size in bytes
+	SizeAfter  int64
+	Vacuumed   bool
+}
+
+// Optimize tidies the index. Updating notes fragments the FTS index into
+// many small segments and leaves free pages behind, so a long-lived index
+// gets slower and keeps growing. A light pass (full false) merges some FTS
+// segments, refreshes the query planner's statistics with PRAGMA optimize,
+// and vacuums only when at least a quarter of the file is free; short of
+// that, an index in incremental auto_vacuum mode gives its free pages back
+// with PRAGMA incremental_vacuum, which moves pages instead of rewriting the
+// file. It is meant for every exit and for a running session's periodic
+// pass. A full pass merges every FTS segment and always vacuums.
+func (db *DB) Optimize(full bool) (OptimizeStats, error) {
+	var stats OptimizeStats
+	var err error
+	if stats.SizeBefore, err = db.size(); err != nil {
+		return stats, err
+	}
+
+	fts := fmt.Sprintf("INSERT INTO notes_fts(notes_fts, rank) VALUES('merge', %d)", ftsMergePages)
+	if full {
+		fts = "INSERT INTO notes_fts(notes_fts) VALUES('optimize')"
+	}
+	if _, err := db.conn.Exec(fts); err != nil {
+		return stats, fmt.Errorf("merge fts segments: %w", err)
+	}
+	if _, err := db.conn.Exec("PRAGMA optimize"); err != nil {
+		return stats, fmt.Errorf("optimize: %w", err)
+	}
+
+	vacuum, incremental := full, false
+	if !vacuum {
+		var free, pages, mode int64
+		if err := db.conn.QueryRow("PRAGMA freelist_count").Scan(&free); err != nil {
+			return stats, fmt.Errorf("read free pages: %w", err)
+		}
+		if err := db.conn.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+			return stats, fmt.Errorf("read page count: %w", err)
+		}
+		if err := db.conn.QueryRow("PRAGMA auto_vacuum").Scan(&mode); err != nil {
+			return stats, fmt.Errorf("read auto_vacuum: %w", err)
+		}
+		vacuum = free > 0 && free*vacuumFraction >= pages
+		incremental = !vacuum && free > 0 && mode == autoVacuumIncremental
+	}
+	switch {
+	case vacuum:
+		if _, err := db.conn.Exec("VACUUM"); err != nil {
+			return stats, fmt.Errorf("vacuum: %w", err)
+		}
+		stats.Vacuumed = true
+	case incremental:
+		// The pragma frees a page per step, so read it to the end.
+		rows, err := db.conn.Query("PRAGMA incremental_vacuum")
+		if err == nil {
+			for rows.Next() {
+			}
+			err = errors.Join(rows.Err(), rows.Close())
+		}
+		if err != nil {
+			return stats, fmt.Errorf("incremental vacuum: %w", err)
+		}
+	}
+	if vacuum || incremental {
+		// Both go through the WAL; fold it back so the file shrinks.
+		if _, err := db.conn.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+			return stats, fmt.Errorf("checkpoint: %w", err)
+		}
+	}
+
+	stats.SizeAfter, err = db.size()
+	return stats, err
+}
+
+// SizeStats breaks down how much space the index takes.
+type SizeStats struct {
+	Size        int64 // database size in bytes, WAL excluded
+	Free        int64 // bytes in free pages, which a vacuum gives back
+	Search      int64 // bytes of full-text index data
+	Segments    int   // full-text index segments; merging lowers it
+	Incremental bool  // free pages are given back without a full VACUUM
+	Notes       int
+	Links       int
+	Attachments int
+}
+
+// SizeStats reports the index's size, free space and contents.
+func (db *DB) SizeStats() (SizeStats, error) {
+	var s SizeStats
+	var err error
+	if s.Size, err = db.size(); err != nil {
+		return s, err
+	}
+	var free, pageSize, mode int64
+	for _, q := range []struct {
+		pragma string
+		dest   *int64
+	}{
+		{"freelist_count", &free},
+		{"page_size", &pageSize},
+		{"auto_vacuum", &mode},
+	} {
+		if err := db.conn.QueryRow("PRAGMA " + q.pragma).Scan(q.dest); err != nil {
+			return s, fmt.Errorf("read %s: %w", q.pragma, err)
+		}
+	}
+	s.Free = free * pageSize
+	s.Incremental = mode == autoVacuumIncremental
+
+	// The FTS5 shadow table holds the index: one row per segment leaf, and
+	// the structure record at id 10.
+	err = db.conn.QueryRow(`
+		SELECT COALESCE(SUM(length(block)), 0), COUNT(DISTINCT id >> 37)
+		FROM notes_fts_data WHERE id > 10
+	`).Scan(&s.Search, &s.Segments)
+	if err != nil {
+		return s, fmt.Errorf("read search index size: %w", err)
+	}
+	err = db.conn.QueryRow(`
+		SELECT (SELECT COUNT(*) FROM notes), (SELECT COUNT(*) FROM links), (SELECT COUNT(*) FROM attachments)
+	`).Scan(&s.Notes, &s.Links, &s.Attachments)
+	if err != nil {
+		return s, fmt.Errorf("count notes: %w", err)
+	}
+	return s, nil
+}
+
+// size returns the size of the database in bytes.
+func (db *DB) size() (int64, error) {
+	var pages, pageSize int64
+	if err := db.conn.QueryRow("PRAGMA page_count").Scan(&pages); err != nil {
+		return 0, fmt.Errorf("read page count: %w", err)
+	}
+	if err := db.conn.QueryRow("PRAGMA page_size").Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("read page size: %w", err)
+	}
+	return pages * pageSize, nil
+}
@@ -0,0 +1,426 @@
+package index
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Query is a parsed finder query: structured filters plus free text.
+//
+// Operators:
+//   - tag:work       note has the tag or a subtag such as work/alpha
+//     (repeatable; all must match)
+//   - path:projects/ note path starts with the prefix (repeatable; any may match)
+//   - status:draft   frontmatter status equals the value (repeatable; any may match)
+//   - modified:7d    file modified within the last N days (or Nh hours, Nw
+//     weeks); modified:<7d is the same, modified:>7d means longer ago
+//   - modified:2026-01-31  file modified on that day; >date means after it,
+//     <date before it, and date..date an inclusive range (either end may be
+//     left open)
+//   - created:, updated:  the same, on the frontmatter created (or date) and
+//     updated dates; updated: falls back to the file's modification time,
+//     created: skips notes without one
+//   - backlinks:0    note is linked from exactly N other notes; backlinks:<3
+//     means fewer, backlinks:>3 more
+//   - is:archived    note is in the archive folder (see ArchiveDir)
+//
+// Everything else is free text for FTS (see ftsMatch): words match as
+// prefixes, "quoted phrases" as written, -word excludes notes with the word
+// and OR between words matches either. Operator values may be double-quoted
+// to include spaces (path:"work notes/").
+type Query struct {
+	Text     string
+	Tags     []string
+	Paths    []string
+	Statuses []string
+	Modified DateFilter // file modification time
+	Created  DateFilter // frontmatter created date
+	Updated  DateFilter // frontmatter updated date, else modification time
+	// Backlinks counts the other notes linking to the note.
+	Backlinks CountFilter
+	// Archived limits results to notes in ArchiveDir.
+	Archived bool
+	// ArchiveDir is the vault's archive folder, set by the caller rather
+	// than parsed. Notes in it are left out unless the query asks for them
+	// with is:archived or a path: inside it. Empty archives nothing.
+	ArchiveDir string
+}
+
+// CountFilter compares a count with N: Op is '=', '<' or '>'. The zero
+// value allows every count.
+type CountFilter struct {
+	Op byte
+	N  int
+}
+
+// IsZero reports whether the filter allows every count.
+func (f CountFilter) IsZero() bool {
+	return f.Op == 0
+}
+
+// DateFilter bounds a note date. Zero fields leave that side open.
+type DateFilter struct {
+	// Within limits results to dates within this long of now.
+	Within time.Duration
+	// OlderThan limits results to dates longer ago than this.
+	OlderThan time.Duration
+	// Since and Until bound the date to [since, until).
+	Since time.Time
+	Until time.Time
+}
+
+// IsZero reports whether the filter allows every date.
+func (f DateFilter) IsZero() bool {
+	return f == DateFilter{}
+}
+
+// ParseQuery splits raw finder input into operators and free text.
+func ParseQuery(raw string) Query {
+	var q Query
+	var text []string
+	for _, tok := range splitQuery(raw) {
+		key, val, ok := strings.Cut(tok, ":")
+		val = strings.Trim(val, `"`)
+		if !ok || val == "" {
+			text = append(text, tok)
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "tag":
+			q.Tags = append(q.Tags, strings.TrimPrefix(val, "#"))
+		case "path":
+			q.Paths = append(q.Paths, val)
+		case "status":
+			q.Statuses = append(q.Statuses, val)
+		case "is":
+			if !strings.EqualFold(val, "archived") {
+				text = append(text, tok)
+				break
+			}
+			q.Archived = true
+		case "backlinks":
+			f, ok := parseCountFilter(val)
+			if !ok {
+				text = append(text, tok)
+				break
+			}
+			q.Backlinks = f
+		case "modified", "created", "updated":
+			f, ok := parseDateFilter(val)
+			if !ok {
+				text = append(text, tok)
+				break
+			}
+			switch strings.ToLower(key) {
+			case "modified":
+				q.Modified = f
+			case "created":
+				q.Created = f
+			default:
+				q.Updated = f
+			}
+		default:
+			text = append(text, tok)
+		}
+	}
+	q.Text = strings.Join(text, " ")
+	return q
+}
+
+// HasFilters reports whether the query uses any operator.
+func (q Query) HasFilters() bool {
+	return len(q.Tags) > 0 || len(q.Paths) > 0 || len(q.Statuses) > 0 ||
+		!q.Modified.IsZero() || !q.Created.IsZero() || !q.Updated.IsZero() ||
+		!q.Backlinks.IsZero() || q.Archived
+}
+
+// parseCountFilter parses a count operator value: N, <N or >N. It reports
+// false when val is invalid.
+func parseCountFilter(val string) (CountFilter, bool) {
+	f := CountFilter{Op: '='}
+	if val[0] == '<' || val[0] == '>' {
+		f.Op, val = val[0], val[1:]
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil || n < 0 {
+		return CountFilter{}, false
+	}
+	f.N = n
+	return f, true
+}
+
+// parseDateFilter parses a date operator value: a relative age, day,
+// comparison or range. It reports false when val is invalid.
+func parseDateFilter(val string) (DateFilter, bool) {
+	var f DateFilter
+	if from, to, ok := strings.Cut(val, ".."); ok {
+		if from != "" {
+			day, ok := parseDay(from)
+			if !ok {
+				return f, false
+			}
+			f.Since = day
+		}
+		if to != "" {
+			day, ok := parseDay(to)
+			if !ok {
+				return f, false
+			}
+			f.Until = day.AddDate(0, 0, 1)
+		}
+		return f, !f.IsZero()
+	}
+
+	op := val[0]
+	if op == '<' || op == '>' {
+		val = val[1:]
+	}
+	if d, ok := parseAge(val); ok {
+		if op == '>' {
+			f.OlderThan = d
+		} else {
+			f.Within = d
+		}
+		return f, true
+	}
+	day, ok := parseDay(val)
+	if !ok {
+		return f, false
+	}
+	switch op {
+	case '>':
+		f.Since = day.AddDate(0, 0, 1)
+	case '<':
+		f.Until = day
+	default:
+		f.Since, f.Until = day, day.AddDate(0, 0, 1)
+	}
+	return f, true
+}
+
+// parseDay parses a YYYY-MM-DD date as the start of that day in local time.
+func parseDay(s string) (time.Time, bool) {
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	return t, err == nil
+}
+
+// parseAge parses a relative age such as "7d", "12h" or "2w".
+func parseAge(s string) (time.Duration, bool) {
+	if len(s) < 2 {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s[:len(s)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	switch s[len(s)-1] {
+	case 'h':
+		return time.Duration(n) * time.Hour, true
+	case 'd':
+		return time.Duration(n) * 24 * time.Hour, true
+	case 'w':
+		return time.Duration(n) * 7 * 24 * time.Hour, true
+	}
+	return 0, false
+}
+
+// splitQuery splits on whitespace outside double quotes. Quotes are kept so
+// free-text phrases still reach FTS as phrases.
+func splitQuery(s string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuote := false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+			cur.WriteRune(r)
+		case !inQuote && (r == ' ' || r == '\t'):
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteRune(r)
+		}
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens
+}
+
+// ftsMatch builds an FTS5 MATCH expression from free text, so nothing typed
+// can be an FTS syntax error. Each word becomes a quoted prefix query
+// ("plan"* matches planning); a "quoted phrase" is matched as written, or
+// as a prefix with a * after the closing quote. A word or phrase starting
+// with - excludes notes containing it, and an uppercase OR between two
+// terms matches either. Terms without letters or digits are dropped, since
+// the tokenizer would drop them too. It returns "" when nothing is left to
+// match, including when every term is excluded, which FTS5 can't express.
+func ftsMatch(text string) string {
+	var include, exclude []string
+	or := false
+	for _, tok := range splitQuery(text) {
+		if tok == "OR" {
+			or = len(include) > 0
+			continue
+		}
+		negate := false
+		if len(tok) > 1 && tok[0] == '-' {
+			negate, tok = true, tok[1:]
+		}
+		term, ok := ftsTerm(tok)
+		if !ok {
+			continue
+		}
+		switch {
+		case negate:
+			exclude = append(exclude, term)
+		case or:
+			include[len(include)-1] += " OR " + term
+		default:
+			include = append(include, term)
+		}
+		or = false
+	}
+	if len(include) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for i, term := range include {
+		if i > 0 {
+			b.WriteByte(' ')
+		}
+		if strings.Contains(term, " OR ") && (len(include) > 1 || len(exclude) > 0) {
+			term = "(" + term + ")"
+		}
+		b.WriteString(term)
+	}
+	for _, term := range exclude {
+		b.WriteString(" NOT " + term)
+	}
+	return b.String()
+}
+
+// ftsTerm quotes a word or "phrase" from the finder as an FTS5 string,
+// with a * for a prefix query. ok is false for terms with nothing to match.
+func ftsTerm(tok string) (term string, ok bool) {
+	prefix := true
+	if strings.HasPrefix(tok, `"`) {
+		tok, prefix = strings.TrimPrefix(tok, `"`), false
+		if before, after, found := strings.Cut(tok, `"`); found {
+			tok, prefix = before, after == "*"
+		}
+	} else {
+		tok = strings.TrimRight(tok, "*")
+	}
+	if !strings.ContainsFunc(tok, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) {
+		return "", false
+	}
+	term = `"` + strings.ReplaceAll(tok, `"`, `""`) + `"`
+	if prefix {
+		term += "*"
+	}
+	return term, true
+}
+
+// filterSQL renders the query's operators as SQL conditions on the notes
+// table (aliased n), each prefixed with " AND ".
+func (q Query) filterSQL() (string, []any) {
+	var b strings.Builder
+	var args []any
+
+	for _, tag := range q.Tags {
+		b.WriteString(` AND n.id IN (
+			WITH RECURSIVE sub(id) AS (
+				SELECT id FROM tags WHERE lower(name) = lower(?)
+				UNION SELECT t.id FROM tags t JOIN sub ON t.parent_id = sub.id
+			)
+			SELECT note_id FROM note_tags WHERE tag_id IN sub)`)
+		args = append(args, tag)
+	}
+
+	if len(q.Paths) > 0 {
+		conds := make([]string, len(q.Paths))
+		for i, p := range q.Paths {
+			conds[i] = `n.path LIKE ? ESCAPE '\'`
+			args = append(args, escapeLike(strings.TrimPrefix(p, "/"))+"%")
+		}
+		b.WriteString(" AND (" + strings.Join(conds, " OR ") + ")")
+	}
+
+	if len(q.Statuses) > 0 {
+		conds := make([]string, len(q.Statuses))
+		for i, s := range q.Statuses {
+			conds[i] = "lower(n.status) = lower(?)"
+			args = append(args, s)
+		}
+		b.WriteString(" AND (" + strings.Join(conds, " OR ") + ")")
+	}
+
+	switch archive := escapeLike(q.ArchiveDir+string(filepath.Separator)) + "%"; {
+	case q.Archived && q.ArchiveDir == "":
+		b.WriteString(" AND 0")
+	case q.Archived:
+		b.WriteString(` AND n.path LIKE ? ESCAPE '\'`)
+		args = append(args, archive)
+	case q.ArchiveDir != "" && !q.pathsInArchive():
+		b.WriteString(` AND n.path NOT LIKE ? ESCAPE '\'`)
+		args = append(args, archive)
+	}
+
+	args = q.Modified.writeSQL(&b, args, "n.mod_time")
+	if !q.Created.IsZero() {
+		b.WriteString(" AND n.created != 0")
+		args = q.Created.writeSQL(&b, args, "n.created")
+	}
+	args = q.Updated.writeSQL(&b, args, "COALESCE(NULLIF(n.updated, 0), n.mod_time)")
+
+	if !q.Backlinks.IsZero() {
+		b.WriteString(` AND (
+			SELECT count(DISTINCT l.source_id) FROM links l
+			WHERE l.target_id = n.id AND l.source_id != n.id
+		) ` + string(q.Backlinks.Op) + ` ?`)
+		args = append(args, q.Backlinks.N)
+	}
+
+	return b.String(), args
+}
+
+// pathsInArchive reports whether a path: filter names a folder inside
+// ArchiveDir, or the folder itself.
+func (q Query) pathsInArchive() bool {
+	for _, p := range q.Paths {
+		p = filepath.Clean(strings.TrimPrefix(p, "/"))
+		if p == q.ArchiveDir || strings.HasPrefix(p, q.ArchiveDir+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeSQL appends the filter's conditions on col, a Unix-seconds expression,
+// to b and returns args with their arguments added.
+func (f DateFilter) writeSQL(b *strings.Builder, args []any, col string) []any {
+	if f.Within > 0 {
+		b.WriteString(" AND " + col + " >= CAST(strftime('%s', 'now') AS INTEGER) - ?")
+		args = append(args, int64(f.Within/time.Second))
+	}
+	if f.OlderThan > 0 {
+		b.WriteString(" AND " + col + " < CAST(strftime('%s', 'now') AS INTEGER) - ?")
+		args = append(args, int64(f.OlderThan/time.Second))
+	}
+	if !f.Since.IsZero() {
+		b.WriteString(" AND " + col + " >= ?")
+		args = append(args, f.Since.Unix())
+	}
+	if !f.Until.IsZero() {
+		b.WriteString(" AND " + col + " < ?")
+		args = append(args, f.Until.Unix())
+	}
+	return args
+}
@@ -0,0 +1,97 @@
+package index
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestParseQuery(t *testing.T) {
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.Local) }
+	tests := []struct {
+		input string
+		want  Query
+	}{
+		{"meeting notes", Query{Text: "meeting notes"}},
+		{"tag:work", Query{Tags: []string{"work"}}},
+		{"tag:#work budget", Query{Text: "budget", Tags: []string{"work"}}},
+		{"path:projects/ status:draft plan", Query{Text: "plan", Paths: []string{"projects/"}, Statuses: []string{"draft"}}},
+		{`path:"work notes/" "exact phrase"`, Query{Text: `"exact phrase"`, Paths: []string{"work notes/"}}},
+		{"TAG:a tag:b", Query{Tags: []string{"a", "b"}}},
+		{"tag: other:x", Query{Text: "tag: other:x"}},
+		{"modified:7d plan", Query{Text: "plan", Modified: DateFilter{Within: 7 * 24 * time.Hour}}},
+		{"modified:2w", Query{Modified: DateFilter{Within: 14 * 24 * time.Hour}}},
+		{"modified:soon", Query{Text: "modified:soon"}},
+		{"modified:<3d", Query{Modified: DateFilter{Within: 3 * 24 * time.Hour}}},
+		{"modified:>30d", Query{Modified: DateFilter{OlderThan: 30 * 24 * time.Hour}}},
+		{"modified:2026-01-31", Query{Modified: DateFilter{Since: day(2026, 1, 31), Until: day(2026, 2, 1)}}},
+		{"modified:>2026-01-31", Query{Modified: DateFilter{Since: day(2026, 2, 1)}}},
+		{"modified:<2026-01-31", Query{Modified: DateFilter{Until: day(2026, 1, 31)}}},
+		{"modified:2026-01-01..2026-01-31", Query{Modified: DateFilter{Since: day(2026, 1, 1), Until: day(2026, 2, 1)}}},
+		{"modified:..2026-01-31", Query{Modified: DateFilter{Until: day(2026, 2, 1)}}},
+		{"modified:2026-13-01", Query{Text: "modified:2026-13-01"}},
+		{"modified:..", Query{Text: "modified:.."}},
+		{"modified:>", Query{Text: "modified:>"}},
+		{"created:2026-01-31 updated:<7d", Query{Created: DateFilter{Since: day(2026, 1, 31), Until: day(2026, 2, 1)}, Updated: DateFilter{Within: 7 * 24 * time.Hour}}},
+		{"Created:>30d", Query{Created: DateFilter{OlderThan: 30 * 24 * time.Hour}}},
+		{"updated:never", Query{Text: "updated:never"}},
+		{"backlinks:0 status:draft", Query{Statuses: []string{"draft"}, Backlinks: CountFilter{Op: '=', N: 0}}},
+		{"backlinks:>2", Query{Backlinks: CountFilter{Op: '>', N: 2}}},
+		{"backlinks:<1", Query{Backlinks: CountFilter{Op: '<', N: 1}}},
+		{"backlinks:many", Query{Text: "backlinks:many"}},
+		{"backlinks:-1", Query{Text: "backlinks:-1"}},
+		{"is:archived plan", Query{Text: "plan", Archived: true}},
+		{"is:pinned", Query{Text: "is:pinned"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := ParseQuery(tt.input)
+			if got.Text != tt.want.Text ||
+				!slices.Equal(got.Tags, tt.want.Tags) ||
+				!slices.Equal(got.Paths, tt.want.Paths) ||
+				!slices.Equal(got.Statuses, tt.want.Statuses) ||
+				!sameDateFilter(got.Modified, tt.want.Modified) ||
+				!sameDateFilter(got.Created, tt.want.Created) ||
+				!sameDateFilter(got.Updated, tt.want.Updated) ||
+				got.Backlinks != tt.want.Backlinks ||
+				got.Archived != tt.want.Archived {
+				t.Errorf("ParseQuery(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func sameDateFilter(a, b DateFilter) bool {
+	return a.Within == b.Within && a.OlderThan == b.OlderThan && a.Since.Equal(b.Since) && a.Until.Equal(b.Until)
+}
+
+func TestFTSMatch(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"plan", `"plan"*`},
+		{"meeting notes", `"meeting"* "notes"*`},
+		{`"exact phrase"`, `"exact phrase"`},
+		{`"exact phr"*`, `"exact phr"*`},
+		{`"unclosed phrase`, `"unclosed phrase"`},
+		{`say"hi`, `"say""hi"*`},
+		{"budget -draft", `"budget"* NOT "draft"*`},
+		{`notes -"old stuff"`, `"notes"* NOT "old stuff"`},
+		{"cats OR dogs", `"cats"* OR "dogs"*`},
+		{"cats OR dogs food", `("cats"* OR "dogs"*) "food"*`},
+		{"OR cats", `"cats"*`},
+		{"cats or dogs", `"cats"* "or"* "dogs"*`},
+		{"plan* c++", `"plan"* "c++"*`},
+		{"- ... \"", ""},
+		{"-draft", ""},
+		{"", ""},
+		{"NEAR(a b)", `"NEAR(a"* "b)"*`},
+	}
+	for _, tt := range tests {
+		if got := ftsMatch(tt.text); got != tt.want {
+			t.Errorf("ftsMatch(%q) = %s, want %s", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package index
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// readOnlyTimeout stops a runaway query, such as an unbounded recursive CTE,
+// from hanging the caller.
+const readOnlyTimeout = 5 * time.Second
+
+// TableResult holds the rows of a raw SQL query, each value as text.
+type TableResult struct {
+	Columns []string
+	Rows    [][]string
+	// Truncated reports that the query returned more rows than the limit.
+	Truncated bool
+}
+
+// IsSQL reports whether console input is raw SQL rather than a finder
+// query: it starts with SELECT or WITH.
+func IsSQL(input string) bool {
+	fields := strings.Fields(input)
+	if len(fields) == 0 {
+		return false
+	}
+	word := strings.ToUpper(fields[0])
+	return word == "SELECT" || word == "WITH"
+}
+
+// QueryReadOnly runs a single SQL statement against the index and returns
+// at most limit rows. It runs on the index's read-only connection, which SQL
+// cannot make writable, so statements that would write fail instead of
+// touching the index. Input holding more than one statement is rejected.
+func (db *DB) QueryReadOnly(query string, limit int) (res TableResult, err error) {
+	if query, err = singleStatement(query); err != nil {
+		return res, err
+	}
+	if limit <= 0 {
+		limit = 500
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), readOnlyTimeout)
+	defer cancel()
+
+	conn, release, err := db.readOnlyConn(ctx)
+	if err != nil {
+		return res, err
+	}
+	defer func() { err = errors.Join(err, release()) }()
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return res, err
+	}
+	if res.Columns, err = rows.Columns(); err != nil {
+		return res, errors.Join(err, rows.Close())
+	}
+	values := make([]any, len(res.Columns))
+	ptrs := make([]any, len(values))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if len(res.Rows) == limit {
+			res.Truncated = true
+			break
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return res, errors.Join(err, rows.Close())
+		}
+		row := make([]string, len(values))
+		for i, v := range values {
+			row[i] = formatValue(v)
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return res, errors.Join(err, rows.Close())
+	}
+	return res, rows.Close()
+}
+
+// readOnlyConn returns a connection for QueryReadOnly and the function
+// that gives it back. An index on disk has a connection opened with
+// mode=ro; an in-memory one, which a second connection can't see, falls
+// back to a pooled connection switched to query_only.
+func (db *DB) readOnlyConn(ctx context.Context) (*sql.Conn, func() error, error) {
+	if db.readOnly != nil {
+		conn, err := db.readOnly.Conn(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		return conn, conn.Close, nil
+	}
+	conn, err := db.conn.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("set query_only: %w", err), conn.Close())
+	}
+	return conn, func() error { return releaseReadOnly(conn) }, nil
+}
+
+// singleStatement returns query without its trailing semicolon, or an
+// error if any SQL follows the first statement. Semicolons inside string
+// literals, quoted identifiers and comments don't end the statement.
+func singleStatement(query string) (string, error) {
+	end := -1
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '-' && strings.HasPrefix(query[i:], "--"):
+			if j := strings.IndexByte(query[i:], '\n'); j >= 0 {
+				i += j
+			} else {
+				i = len(query)
+			}
+		case c == '/' && strings.HasPrefix(query[i:], "/*"):
+			if j := strings.Index(query[i+2:], "*/"); j >= 0 {
+				i += j + 3
+			} else {
+				i = len(query)
+			}
+		case end >= 0 && c == ';':
+			// An empty statement after the first.
+		case end >= 0 && !unicode.IsSpace(rune(c)):
+			return "", errors.New("only one SQL statement can be run at a time")
+		case c == ';':
+			end = i
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			closer := c
+			if c == '[' {
+				closer = ']'
+			}
+			// A doubled quote is an escaped one; scanning on from it
+			// reopens the literal, which ends at the next quote all the
+			// same.
+			if j := strings.IndexByte(query[i+1:], closer); j >= 0 {
+				i += j + 1
+			} else {
+				i = len(query)
+			}
+		}
+	}
+	if end < 0 {
+		return query, nil
+	}
+	return query[:end], nil
+}
+
+// releaseReadOnly resets query_only and returns conn to the pool. The reset
+// runs without the query's context so it still happens after a timeout; if
+// it fails anyway, the connection is discarded rather than pooled, since
+// every later write through it would fail.
+func releaseReadOnly(conn *sql.Conn) error {
+	if _, err := conn.ExecContext(context.Background(), "PRAGMA query_only = OFF"); err != nil {
+		discard := conn.Raw(func(any) error { return driver.ErrBadConn })
+		if errors.Is(discard, driver.ErrBadConn) {
+			discard = nil
+		}
+		return errors.Join(fmt.Errorf("reset query_only: %w", err), discard)
+	}
+	return conn.Close()
+}
+
+// formatValue renders a scanned SQLite value as text.
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case time.Time:
+		return v.Format(time.DateTime)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,118 @@
+package index
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIsSQL(t *testing.T) {
+	for input, want := range map[string]bool{
+		"SELECT path FROM notes":               true,
+		"  select 1":                           true,
+		"SELECT\npath FROM notes":              true,
+		"with x AS (SELECT 1) SELECT * FROM x": true,
+		"status:draft backlinks:0":             false,
+		"selection":                            false,
+		"":                                     false,
+	} {
+		if got := IsSQL(input); got != want {
+			t.Errorf("IsSQL(%q) = %v, want %v", input, got, want)
+		}
+	}
+}
+
+func TestQueryReadOnly(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	for _, p := range []string{"a.md", "b.md", "c.md"} {
+		if _, err := db.UpsertNote(p, p, p, "", "h", 1000, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := db.QueryReadOnly("SELECT path, size, NULL AS missing FROM notes ORDER BY path", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(res.Columns, []string{"path", "size", "missing"}) {
+		t.Errorf("Columns = %v", res.Columns)
+	}
+	if len(res.Rows) != 2 || !slices.Equal(res.Rows[0], []string{"a.md", "10", ""}) || !res.Truncated {
+		t.Errorf("Rows = %v, truncated %v; want a.md and b.md, truncated", res.Rows, res.Truncated)
+	}
+
+	if _, err := db.QueryReadOnly("DELETE FROM notes", 10); err == nil {
+		t.Error("DELETE succeeded on a read-only query")
+	}
+	if _, err := db.QueryReadOnly("SELECT nope FROM notes", 10); err == nil {
+		t.Error("invalid column succeeded")
+	}
+
+	// The connection is writable again afterwards.
+	if _, err := db.UpsertNote("d.md", "d", "d", "", "h", 1000, 10); err != nil {
+		t.Errorf("write after read-only query: %v", err)
+	}
+	res, err = db.QueryReadOnly("SELECT count(*) FROM notes", 10)
+	if err != nil || len(res.Rows) != 1 || res.Rows[0][0] != "4" {
+		t.Errorf("count = %v, %v; want 4", res.Rows, err)
+	}
+}
+
+func TestSingleStatement(t *testing.T) {
+	for input, want := range map[string]string{
+		"SELECT 1":                         "SELECT 1",
+		"SELECT 1;":                        "SELECT 1",
+		"SELECT 1; ; -- done\n":            "SELECT 1",
+		"SELECT 'a;b' AS \"c;d\" /* ; */":  "SELECT 'a;b' AS \"c;d\" /* ; */",
+		"SELECT 'it''s;' FROM [x;y]; /**/": "SELECT 'it''s;' FROM [x;y]",
+	} {
+		got, err := singleStatement(input)
+		if err != nil || got != want {
+			t.Errorf("singleStatement(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+	for _, input := range []string{
+		"SELECT 1; PRAGMA query_only = OFF; DELETE FROM notes",
+		"SELECT 1;DELETE FROM notes",
+		"SELECT 1; /* x */ 'y'",
+	} {
+		if _, err := singleStatement(input); err == nil {
+			t.Errorf("singleStatement(%q) accepted more than one statement", input)
+		}
+	}
+}
+
+func TestQueryReadOnlyOnDisk(t *testing.T) {
+	db, err := Open(filepath.Join(t.TempDir(), "index.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+	if _, err := db.UpsertNote("a.md", "a", "a", "", "h", 1000, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.QueryReadOnly("SELECT 1; PRAGMA query_only = OFF; DELETE FROM notes", 10); err == nil {
+		t.Error("QueryReadOnly ran several statements")
+	}
+	// Even reconfigured, the console's connection can't write.
+	if _, err := db.readOnly.Exec("PRAGMA query_only = OFF; DELETE FROM notes"); err == nil {
+		t.Error("read-only connection deleted notes")
+	}
+	res, err := db.QueryReadOnly("SELECT count(*) FROM notes", 10)
+	if err != nil || len(res.Rows) != 1 || res.Rows[0][0] != "1" {
+		t.Errorf("count = %v, %v; want 1", res.Rows, err)
+	}
+}
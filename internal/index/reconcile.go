@@ -0,0 +1,113 @@
+package index
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+// noteStamp is a note's modification time (Unix seconds) and size as the
+// index last saw them.
+type noteStamp struct {
+	modTime int64
+	size    int64
+}
+
+// Reconcile catches up on changes the watcher missed, as after a git pull
+// while kopr was busy, an rsync, or a laptop waking from sleep. Unlike
+// Update it reads only the notes whose modification time or size differs
+// from the index, so on an unchanged vault it costs a stat per file. A note
+// touched but not changed gets its new stamp recorded. onWrite, if set, is
+// called with the absolute path of each note rewritten.
+func (idx *Indexer) Reconcile(onWrite func(path string)) (IndexStats, error) {
+	var stats IndexStats
+	paths, attachments, err := idx.vaultFiles()
+	if err != nil {
+		return stats, err
+	}
+	external, err := idx.sourceFiles()
+	if err != nil {
+		return stats, err
+	}
+	paths = append(paths, external...)
+	stats.Files = len(paths)
+	stats.Attachments = len(attachments)
+
+	stamps, err := idx.db.NoteStamps()
+	if err != nil {
+		return stats, err
+	}
+	present := make(map[string]bool, len(paths))
+	var drifted []string
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			continue // gone since the walk; the next scan drops it
+		}
+		rel := idx.relPath(p)
+		present[rel] = true
+		if st, ok := stamps[rel]; ok && st.modTime == info.ModTime().Unix() && st.size == info.Size() {
+			continue
+		}
+		drifted = append(drifted, p)
+	}
+
+	var written []string
+	err = idx.db.InTx(func(tx *DB) error {
+		if err := tx.ReplaceAttachments(attachments); err != nil {
+			return fmt.Errorf("index attachments: %w", err)
+		}
+		for rel := range stamps {
+			if present[rel] {
+				continue
+			}
+			if err := tx.DeleteNote(rel); err != nil {
+				return fmt.Errorf("remove %s: %w", rel, err)
+			}
+			stats.Removed++
+		}
+		if stats.Removed > 0 {
+			if err := resolveDangling(tx); err != nil {
+				return err
+			}
+		}
+		for _, p := range drifted {
+			n, err := idx.readNote(p)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			switch {
+			case n == nil:
+			case n.encrypted:
+				if err := tx.DeleteNote(n.relPath); err != nil {
+					return err
+				}
+			case idx.unchanged(tx, n):
+				if err := tx.SetNoteStamp(n.relPath, n.modTime, n.size); err != nil {
+					return err
+				}
+			default:
+				n.parse(idx.parser)
+				if err := idx.writeNote(tx, n); err != nil {
+					return err
+				}
+				stats.Indexed++
+				written = append(written, p)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	if onWrite != nil {
+		for _, p := range written {
+			onWrite(p)
+		}
+	}
+	return stats, nil
+}
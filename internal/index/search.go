@@ -3,6 +3,12 @@ package index
 import (
 	"database/sql"
 	"errors"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 // SearchResult represents a single search result.
@@ -11,6 +17,29 @@ type SearchResult struct {
 	Path  string
 	Title string
 	Rank  float64
+	// TitleMatches holds the rune offsets of matched characters in Title,
+	// ascending. Empty when the result was not produced by a text match.
+	TitleMatches []int
+	// Snippet is an excerpt of the body around an FTS match, with the
+	// matched runes in SnippetMatches. Empty when the body did not match.
+	Snippet        string
+	SnippetMatches []int
+	// Summary is the note's frontmatter summary, or its first body line.
+	Summary string
+	// ModTime is the file's modification time when it was last indexed.
+	ModTime time.Time
+	// Created and Updated are the note's frontmatter dates, zero if unset.
+	Created time.Time
+	Updated time.Time
+}
+
+// Modified returns when the note was last changed: its frontmatter updated
+// date if it has one, else the file's modification time.
+func (r SearchResult) Modified() time.Time {
+	if !r.Updated.IsZero() {
+		return r.Updated
+	}
+	return r.ModTime
 }
 
 // BacklinkResult represents a backlink to a note.
@@ -37,20 +66,102 @@ type OutgoingLinkResult struct {
 	Resolved    bool
 }
 
+// BrokenLinkResult is a link whose target matches no note.
+type BrokenLinkResult struct {
+	SourcePath  string
+	SourceTitle string
+	Target      string // link target as stored, e.g. "missing note.md"
+	Section     string
+	Line        int
+	Col         int
+	Markdown    bool // a [text](path.md) link rather than a wiki link
+}
+
+// TaskResult represents a checklist item in a note.
+type TaskResult struct {
+	NotePath string
+	Text     string
+	Done     bool
+	Section  string
+	Line     int
+}
+
+// LinkTargetResult is a note with the names it can be mentioned by.
+type LinkTargetResult struct {
+	Path    string
+	Title   string
+	Aliases []string
+}
+
+// TagResult is a frontmatter tag and the number of notes carrying it.
+type TagResult struct {
+	Name  string
+	Notes int // notes tagged with exactly this tag
+	Total int // notes tagged with it or any of its subtags
+}
+
+// StaleNoteResult is a note due for review.
+type StaleNoteResult struct {
+	Path    string
+	Title   string
+	Touched time.Time // last modified, or updated or reviewed per frontmatter
+}
+
 // Search performs a full-text search across notes.
 func (db *DB) Search(query string, limit int) ([]SearchResult, error) {
+	return db.searchFTS(query, Query{}, limit)
+}
+
+// SearchFiles fuzzy-matches note titles/paths (for the note finder).
+// Whitespace-separated terms must all match. Results are ordered best first;
+// Rank holds the negated match score so lower is better, as with Search.
+func (db *DB) SearchFiles(query string, limit int) ([]SearchResult, error) {
+	return db.searchFuzzy(query, Query{}, limit)
+}
+
+// SearchQuery runs a parsed finder query. Operators narrow the candidate
+// notes; free text goes through FTS, falling back to fuzzy title/path
+// matching when FTS finds nothing or rejects the syntax. A query with only
+// operators lists the matching notes by path.
+func (db *DB) SearchQuery(q Query, limit int) ([]SearchResult, error) {
+	if strings.TrimSpace(q.Text) == "" {
+		return db.listFiltered(q, limit)
+	}
+	results, err := db.searchFTS(q.Text, q, limit)
+	if err == nil && len(results) > 0 {
+		return results, nil
+	}
+	return db.searchFuzzy(q.Text, q, limit)
+}
+
+// ftsRank scores FTS matches with bm25, weighting the columns title,
+// content, tags, headings and keywords. Frontmatter keywords exist to make a
+// note findable by shorthand, so a keyword hit outranks body text.
+const ftsRank = "bm25(notes_fts, 1.0, 1.0, 1.0, 1.0, 10.0)"
+
+// searchFTS runs an FTS MATCH on text, as built by ftsMatch, restricted by
+// the query's operators. Text with nothing to match finds nothing.
+func (db *DB) searchFTS(text string, filter Query, limit int) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 50
 	}
+	match := ftsMatch(text)
+	if match == "" {
+		return nil, nil
+	}
 
-	rows, err := db.conn.Query(`
-		SELECT n.id, n.path, n.title, rank
+	cond, args := filter.filterSQL()
+	rows, err := db.q.Query(`
+		SELECT n.id, n.path, n.summary, n.mod_time, n.created, n.updated,
+			highlight(notes_fts, 0, char(1), char(2)),
+			snippet(notes_fts, 1, char(1), char(2), '…', 12),
+			`+ftsRank+` AS score
 		FROM notes_fts
 		JOIN notes n ON n.id = notes_fts.rowid
-		WHERE notes_fts MATCH ?
-		ORDER BY rank
+		WHERE notes_fts MATCH ?`+cond+`
+		ORDER BY score
 		LIMIT ?
-	`, query, limit)
+	`, append(append([]any{match}, args...), limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -58,9 +169,19 @@ func (db *DB) Search(query string, limit int) ([]SearchResult, error) {
 	var results []SearchResult
 	for rows.Next() {
 		var r SearchResult
-		if err := rows.Scan(&r.ID, &r.Path, &r.Title, &r.Rank); err != nil {
+		var title, snippet string
+		var modTime, created, updated int64
+		if err := rows.Scan(&r.ID, &r.Path, &r.Summary, &modTime, &created, &updated, &title, &snippet, &r.Rank); err != nil {
 			return nil, errors.Join(err, rows.Close())
 		}
+		r.ModTime = time.Unix(modTime, 0)
+		r.Created, r.Updated = timeOrZero(created), timeOrZero(updated)
+		r.Title, r.TitleMatches = parseHighlight(title)
+		// snippet() falls back to the start of the body when only another
+		// column matched; that excerpt says nothing about the match.
+		if text, matches := parseHighlight(snippet); len(matches) > 0 {
+			r.Snippet, r.SnippetMatches = text, matches
+		}
 		results = append(results, r)
 	}
 	if err := rows.Err(); err != nil {
@@ -72,20 +193,95 @@ func (db *DB) Search(query string, limit int) ([]SearchResult, error) {
 	return results, nil
 }
 
-// SearchFiles searches note titles/paths (for fuzzy file finding).
-func (db *DB) SearchFiles(query string, limit int) ([]SearchResult, error) {
+// parseHighlight strips the \x01/\x02 markers that highlight() wraps around
+// matched tokens and returns the plain text with the rune offsets inside them.
+func parseHighlight(s string) (string, []int) {
+	var b strings.Builder
+	var matches []int
+	inMatch := false
+	n := 0
+	for _, r := range s {
+		switch r {
+		case '\x01':
+			inMatch = true
+		case '\x02':
+			inMatch = false
+		default:
+			if inMatch {
+				matches = append(matches, n)
+			}
+			b.WriteRune(r)
+			n++
+		}
+	}
+	return b.String(), matches
+}
+
+// searchFuzzy fuzzy-matches titles/paths of the notes allowed by the query's
+// operators.
+func (db *DB) searchFuzzy(text string, filter Query, limit int) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 50
 	}
 
-	pattern := "%" + query + "%"
-	rows, err := db.conn.Query(`
-		SELECT id, path, title, 0 as rank
-		FROM notes
-		WHERE path LIKE ? OR title LIKE ?
-		ORDER BY path
+	terms := strings.Fields(text)
+
+	cond, args := filter.filterSQL()
+	rows, err := db.q.Query(`SELECT n.id, n.path, n.title, n.summary, n.mod_time, n.created, n.updated FROM notes n WHERE 1=1`+cond, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var modTime, created, updated int64
+		if err := rows.Scan(&r.ID, &r.Path, &r.Title, &r.Summary, &modTime, &created, &updated); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		r.ModTime = time.Unix(modTime, 0)
+		r.Created, r.Updated = timeOrZero(created), timeOrZero(updated)
+		score, matches, ok := fuzzyScoreNote(terms, r.Path, r.Title)
+		if !ok {
+			continue
+		}
+		r.Rank = -float64(score)
+		r.TitleMatches = matches
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Rank != results[j].Rank {
+			return results[i].Rank < results[j].Rank
+		}
+		return results[i].Path < results[j].Path
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
+
+// listFiltered returns the notes allowed by the query's operators, by path.
+func (db *DB) listFiltered(filter Query, limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	cond, args := filter.filterSQL()
+	rows, err := db.q.Query(`
+		SELECT n.id, n.path, n.title, n.summary, n.mod_time, n.created, n.updated, 0 AS rank
+		FROM notes n
+		WHERE 1=1`+cond+`
+		ORDER BY n.path
 		LIMIT ?
-	`, pattern, pattern, limit)
+	`, append(args, limit)...)
 	if err != nil {
 		return nil, err
 	}
@@ -93,9 +289,12 @@ func (db *DB) SearchFiles(query string, limit int) ([]SearchResult, error) {
 	var results []SearchResult
 	for rows.Next() {
 		var r SearchResult
-		if err := rows.Scan(&r.ID, &r.Path, &r.Title, &r.Rank); err != nil {
+		var modTime, created, updated int64
+		if err := rows.Scan(&r.ID, &r.Path, &r.Title, &r.Summary, &modTime, &created, &updated, &r.Rank); err != nil {
 			return nil, errors.Join(err, rows.Close())
 		}
+		r.ModTime = time.Unix(modTime, 0)
+		r.Created, r.Updated = timeOrZero(created), timeOrZero(updated)
 		results = append(results, r)
 	}
 	if err := rows.Err(); err != nil {
@@ -107,16 +306,78 @@ func (db *DB) SearchFiles(query string, limit int) ([]SearchResult, error) {
 	return results, nil
 }
 
-// ListAllNotes returns all notes, sorted by path.
+// fuzzyScoreNote scores a note against every term, taking the better of the
+// path and title match for each. All terms must match. The returned offsets
+// are the title runes matched by any term.
+func fuzzyScoreNote(terms []string, path, title string) (int, []int, bool) {
+	total := 0
+	var matches []int
+	for _, term := range terms {
+		pathScore, _, pathOK := FuzzyMatch(term, path)
+		titleScore, titlePos, titleOK := FuzzyMatch(term, title)
+		switch {
+		case pathOK && titleOK:
+			total += max(pathScore, titleScore)
+		case pathOK:
+			total += pathScore
+		case titleOK:
+			total += titleScore
+		default:
+			return 0, nil, false
+		}
+		matches = append(matches, titlePos...)
+	}
+	slices.Sort(matches)
+	return total, slices.Compact(matches), true
+}
+
+// ListAllNotes returns up to limit notes (all of them when limit <= 0), most
+// recently modified first so the limit never cuts off recent work.
 func (db *DB) ListAllNotes(limit int) ([]SearchResult, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+
+	rows, err := db.q.Query(`
+		SELECT id, path, title, summary, mod_time, created, updated, 0 as rank
+		FROM notes
+		ORDER BY mod_time DESC, path
+		LIMIT ?
+	`, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []SearchResult
+	for rows.Next() {
+		var r SearchResult
+		var modTime, created, updated int64
+		if err := rows.Scan(&r.ID, &r.Path, &r.Title, &r.Summary, &modTime, &created, &updated, &r.Rank); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		r.ModTime = time.Unix(modTime, 0)
+		r.Created, r.Updated = timeOrZero(created), timeOrZero(updated)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ListNotesByModTime returns notes most recently modified first.
+func (db *DB) ListNotesByModTime(limit int) ([]SearchResult, error) {
 	if limit <= 0 {
 		limit = 200
 	}
 
-	rows, err := db.conn.Query(`
-		SELECT id, path, title, 0 as rank
+	rows, err := db.q.Query(`
+		SELECT id, path, title, summary, mod_time, created, updated
 		FROM notes
-		ORDER BY path
+		ORDER BY mod_time DESC, path
 		LIMIT ?
 	`, limit)
 	if err != nil {
@@ -126,9 +387,12 @@ func (db *DB) ListAllNotes(limit int) ([]SearchResult, error) {
 	var results []SearchResult
 	for rows.Next() {
 		var r SearchResult
-		if err := rows.Scan(&r.ID, &r.Path, &r.Title, &r.Rank); err != nil {
+		var modTime, created, updated int64
+		if err := rows.Scan(&r.ID, &r.Path, &r.Title, &r.Summary, &modTime, &created, &updated); err != nil {
 			return nil, errors.Join(err, rows.Close())
 		}
+		r.ModTime = time.Unix(modTime, 0)
+		r.Created, r.Updated = timeOrZero(created), timeOrZero(updated)
 		results = append(results, r)
 	}
 	if err := rows.Err(); err != nil {
@@ -141,16 +405,22 @@ func (db *DB) ListAllNotes(limit int) ([]SearchResult, error) {
 }
 
 // GetBacklinks returns all notes that link to the given path.
-// Matches by basename since target_path stores basenames.
+// Matches by basename since target_path stores basenames; with folder scope,
+// where a name may be shared, by the resolved target instead.
 func (db *DB) GetBacklinks(targetPath string) ([]BacklinkResult, error) {
-	basenameKey := canonicalBasenameKey(targetPath)
-	rows, err := db.conn.Query(`
+	match := "l.target_path = ?"
+	arg := db.noteKey(targetPath)
+	if db.folderScoped {
+		match = "l.target_id = (SELECT id FROM notes WHERE path = ?)"
+		arg = targetPath
+	}
+	rows, err := db.q.Query(`
 		SELECT n.path, n.title, l.line, l.col
 		FROM links l
 		JOIN notes n ON n.id = l.source_id
-		WHERE l.target_path = ?
+		WHERE `+match+`
 		ORDER BY n.path
-	`, basenameKey)
+	`, arg)
 	if err != nil {
 		return nil, err
 	}
@@ -172,15 +442,182 @@ func (db *DB) GetBacklinks(targetPath string) ([]BacklinkResult, error) {
 	return results, nil
 }
 
-// FindNoteByBasename returns the relative path of a note matching the given basename.
-// Basename matching is case-insensitive.
-// Returns empty string if no match is found.
-func (db *DB) FindNoteByBasename(basename string) (string, error) {
+// ResolveLink returns the relative path of the note a wiki link target (as
+// resolved by markdown.ResolveWikiLinkTarget) points at: the note with its
+// basename, or with folder scope the shortest note path ending in it.
+// Matching is case-insensitive. Returns empty string if no match is found.
+func (db *DB) ResolveLink(target string) (string, error) {
+	var path string
+	query, args := db.resolveLinkQuery(target)
+	err := db.q.QueryRow(query+" LIMIT 1", args...).Scan(&path)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return path, err
+}
+
+// LinkCandidates returns every note a wiki link target could mean, the one
+// ResolveLink picks first. Only with folder scope can there be more than one.
+func (db *DB) LinkCandidates(target string) ([]string, error) {
+	query, args := db.resolveLinkQuery(target)
+	rows, err := db.q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	var paths []string
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		paths = append(paths, path)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+// resolveLinkQuery returns the query selecting the paths of notes matching a
+// link target, best match first.
+func (db *DB) resolveLinkQuery(target string) (string, []any) {
+	key := db.linkKey(target)
+	if db.folderScoped {
+		return `
+			SELECT path FROM notes
+			WHERE basename_key = ? OR substr(basename_key, -length(?) - 1) = '/' || ?
+			ORDER BY length(basename_key), basename_key`, []any{key, key, key}
+	}
+	return `SELECT path FROM notes WHERE basename_key = ?`, []any{key}
+}
+
+// ResolveLinkLoosely finds the note a wiki link target means when
+// ResolveLink finds no exact match, e.g. [[My Note]] for my-note.md. Names
+// are compared ignoring case and treating spaces, hyphens and underscores
+// alike. It tries note basenames first, then titles, title slugs and
+// aliases, taking the shortest matching path within the first that
+// matches. With folder scope a folder qualifier must still match. Returns
+// empty string if nothing matches.
+func (db *DB) ResolveLinkLoosely(target string) (string, error) {
+	paths, err := db.LooseLinkCandidates(target)
+	if err != nil || len(paths) == 0 {
+		return "", err
+	}
+	return paths[0], nil
+}
+
+// LooseLinkCandidates returns every note matching a wiki link target the way
+// ResolveLinkLoosely does, within the first kind of name that matches any,
+// shortest path first. ResolveLinkLoosely picks the first.
+func (db *DB) LooseLinkCandidates(target string) ([]string, error) {
+	target = strings.TrimSuffix(filepath.ToSlash(strings.TrimSpace(target)), ".md")
+	dir, name := "", target
+	if i := strings.LastIndex(target, "/"); i >= 0 {
+		dir, name = strings.ToLower(target[:i+1]), target[i+1:]
+	}
+	norm := normalizeLinkName(name)
+	if norm == "" {
+		return nil, nil
+	}
+
+	notes, err := db.ListLinkTargets()
+	if err != nil {
+		return nil, err
+	}
+	matchers := []func(n LinkTargetResult) bool{
+		func(n LinkTargetResult) bool {
+			return normalizeLinkName(strings.TrimSuffix(filepath.Base(n.Path), ".md")) == norm
+		},
+		func(n LinkTargetResult) bool { return normalizeLinkName(n.Title) == norm },
+		func(n LinkTargetResult) bool { return slugify(n.Title) == slugify(name) && slugify(name) != "" },
+		func(n LinkTargetResult) bool {
+			return slices.ContainsFunc(n.Aliases, func(a string) bool { return normalizeLinkName(a) == norm })
+		},
+	}
+	for _, match := range matchers {
+		var paths []string
+		for _, n := range notes {
+			if db.folderScoped && dir != "" && !strings.HasSuffix("/"+strings.ToLower(filepath.ToSlash(filepath.Dir(n.Path)))+"/", "/"+strings.TrimPrefix(dir, "/")) {
+				continue
+			}
+			if match(n) {
+				paths = append(paths, n.Path)
+			}
+		}
+		if len(paths) > 0 {
+			slices.SortStableFunc(paths, func(a, b string) int { return len(a) - len(b) })
+			return paths, nil
+		}
+	}
+	return nil, nil
+}
+
+// SimilarNotes returns up to limit notes whose basename or title is close
+// to the wiki link target name, closest first: within a few typos of it, or
+// containing it or contained in it. Used to suggest what a broken link
+// meant.
+func (db *DB) SimilarNotes(target string, limit int) ([]string, error) {
+	target = strings.TrimSuffix(filepath.ToSlash(strings.TrimSpace(target)), ".md")
+	norm := normalizeLinkName(target[strings.LastIndex(target, "/")+1:])
+	if norm == "" {
+		return nil, nil
+	}
+	maxDist := max(1, utf8.RuneCountInString(norm)/4)
+
+	notes, err := db.ListLinkTargets()
+	if err != nil {
+		return nil, err
+	}
+	type candidate struct {
+		path string
+		dist int
+	}
+	var found []candidate
+	for _, n := range notes {
+		best := -1
+		for _, name := range []string{strings.TrimSuffix(filepath.Base(n.Path), ".md"), n.Title} {
+			name = normalizeLinkName(name)
+			d := EditDistance(norm, name)
+			if d > maxDist && len(name) >= 3 && len(norm) >= 3 &&
+				(strings.Contains(name, norm) || strings.Contains(norm, name)) {
+				d = maxDist
+			}
+			if d <= maxDist && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		if best >= 0 {
+			found = append(found, candidate{n.Path, best})
+		}
+	}
+	slices.SortStableFunc(found, func(a, b candidate) int { return a.dist - b.dist })
+	paths := make([]string, 0, min(limit, len(found)))
+	for _, c := range found[:min(limit, len(found))] {
+		paths = append(paths, c.path)
+	}
+	return paths, nil
+}
+
+// normalizeLinkName lowercases a note name and joins its words with single
+// hyphens, so "My Note", "my_note" and "my-note" compare equal.
+func normalizeLinkName(s string) string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '_'
+	})
+	return strings.Join(words, "-")
+}
+
+// ConflictingNote returns the path of an indexed note that a note at relPath
+// would clash with: one with the same basename, or with folder scope the
+// same path, ignoring case. Returns empty string if the name is free.
+func (db *DB) ConflictingNote(relPath string) (string, error) {
 	var path string
-	key := canonicalBasenameKey(basename)
-	err := db.conn.QueryRow(
+	err := db.q.QueryRow(
 		`SELECT path FROM notes WHERE basename_key = ? LIMIT 1`,
-		key,
+		db.noteKey(relPath),
 	).Scan(&path)
 	if err == sql.ErrNoRows {
 		return "", nil
@@ -188,10 +625,102 @@ func (db *DB) FindNoteByBasename(basename string) (string, error) {
 	return path, err
 }
 
+// LinkNames returns the shortest wiki link target naming each of paths
+// unambiguously among them: the basename without .md, qualified with as
+// many trailing folders as it takes to tell it apart from notes sharing its
+// name. Without folder scope names are unique, so that is the basename.
+func LinkNames(paths []string) map[string]string {
+	byName := map[string][]string{}
+	for _, p := range paths {
+		name := strings.ToLower(filepath.Base(p))
+		byName[name] = append(byName[name], p)
+	}
+	names := make(map[string]string, len(paths))
+	for _, p := range paths {
+		parts := strings.Split(filepath.ToSlash(strings.TrimSuffix(p, ".md")), "/")
+		others := byName[strings.ToLower(filepath.Base(p))]
+		for n := 1; n <= len(parts); n++ {
+			name := strings.Join(parts[len(parts)-n:], "/")
+			if n == len(parts) || !sharesSuffix(others, p, name) {
+				names[p] = name
+				break
+			}
+		}
+	}
+	return names
+}
+
+// sharesSuffix reports whether a path in others other than self ends with
+// the link name, ignoring case.
+func sharesSuffix(others []string, self, name string) bool {
+	suffix := "/" + strings.ToLower(name) + ".md"
+	for _, o := range others {
+		if o != self && strings.HasSuffix("/"+strings.ToLower(filepath.ToSlash(o)), suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// RandomNotePath returns the path of a note picked uniformly at random from
+// those matching filter's operators; its free text is ignored. Returns empty
+// string if no note matches.
+func (db *DB) RandomNotePath(filter Query) (string, error) {
+	cond, args := filter.filterSQL()
+	var path string
+	err := db.q.QueryRow(`
+		SELECT n.path FROM notes n
+		WHERE 1=1`+cond+`
+		ORDER BY random()
+		LIMIT 1
+	`, args...).Scan(&path)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return path, err
+}
+
+// StaleNotes returns up to limit notes neither modified nor updated or
+// reviewed per their frontmatter since before, least recently touched
+// first. Notes with status archived or under skipDir (e.g. "archive/") are
+// left out.
+func (db *DB) StaleNotes(before time.Time, skipDir string, limit int) ([]StaleNoteResult, error) {
+	rows, err := db.q.Query(`
+		SELECT path, title, max(mod_time, updated, reviewed) AS touched
+		FROM notes
+		WHERE max(mod_time, updated, reviewed) < ?
+			AND status != 'archived'
+			AND (? = '' OR substr(path, 1, length(?)) != ?)
+		ORDER BY touched, path
+		LIMIT ?
+	`, before.Unix(), skipDir, skipDir, skipDir, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []StaleNoteResult
+	for rows.Next() {
+		var r StaleNoteResult
+		var touched int64
+		if err := rows.Scan(&r.Path, &r.Title, &touched); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		r.Touched = time.Unix(touched, 0)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
 // GetNoteIDByPath returns the ID of a note by its path.
 func (db *DB) GetNoteIDByPath(path string) (int64, error) {
 	var id int64
-	err := db.conn.QueryRow("SELECT id FROM notes WHERE path = ?", path).Scan(&id)
+	err := db.q.QueryRow("SELECT id FROM notes WHERE path = ?", path).Scan(&id)
 	if err == sql.ErrNoRows {
 		return 0, nil
 	}
@@ -206,7 +735,7 @@ func (db *DB) GetOutgoingLinks(relPath string) ([]OutgoingLinkResult, error) {
 		return nil, err
 	}
 
-	rows, err := db.conn.Query(`
+	rows, err := db.q.Query(`
 		SELECT l.target_path, COALESCE(n.title, ''), l.target_id IS NOT NULL
 		FROM links l
 		LEFT JOIN notes n ON n.id = l.target_id
@@ -239,7 +768,7 @@ func (db *DB) GetOutgoingLinks(relPath string) ([]OutgoingLinkResult, error) {
 
 // GetHeadingsForNote returns all headings for a specific note, ordered by line.
 func (db *DB) GetHeadingsForNote(relPath string) ([]HeadingResult, error) {
-	rows, err := db.conn.Query(`
+	rows, err := db.q.Query(`
 		SELECT h.note_id, n.path, h.level, h.text, h.line
 		FROM headings h
 		JOIN notes n ON n.id = h.note_id
@@ -274,7 +803,7 @@ func (db *DB) SearchHeadings(query string, limit int) ([]HeadingResult, error) {
 	}
 
 	pattern := "%" + query + "%"
-	rows, err := db.conn.Query(`
+	rows, err := db.q.Query(`
 		SELECT h.note_id, n.path, h.level, h.text, h.line
 		FROM headings h
 		JOIN notes n ON n.id = h.note_id
@@ -302,3 +831,187 @@ func (db *DB) SearchHeadings(query string, limit int) ([]HeadingResult, error) {
 	}
 	return results, nil
 }
+
+// GetSectionTasks returns checklist items that appear under a heading named
+// section in notes whose path starts with dirPrefix. Section matching is
+// case-insensitive. Results are ordered by note path, then line.
+func (db *DB) GetSectionTasks(dirPrefix, section string) ([]TaskResult, error) {
+	rows, err := db.q.Query(`
+		SELECT n.path, t.text, t.done, t.section, t.line
+		FROM tasks t
+		JOIN notes n ON n.id = t.note_id
+		WHERE n.path LIKE ? ESCAPE '\' AND lower(t.section) = lower(?)
+		ORDER BY n.path, t.line
+	`, escapeLike(dirPrefix)+"%", section)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TaskResult
+	for rows.Next() {
+		var r TaskResult
+		if err := rows.Scan(&r.NotePath, &r.Text, &r.Done, &r.Section, &r.Line); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetOpenTasks returns every unchecked checklist item in the vault, ordered
+// by note path, then line.
+func (db *DB) GetOpenTasks() ([]TaskResult, error) {
+	rows, err := db.q.Query(`
+		SELECT n.path, t.text, t.done, t.section, t.line
+		FROM tasks t
+		JOIN notes n ON n.id = t.note_id
+		WHERE t.done = 0
+		ORDER BY n.path, t.line
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TaskResult
+	for rows.Next() {
+		var r TaskResult
+		if err := rows.Scan(&r.NotePath, &r.Text, &r.Done, &r.Section, &r.Line); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ListLinkTargets returns every note with its title and frontmatter aliases,
+// ordered by path.
+func (db *DB) ListLinkTargets() ([]LinkTargetResult, error) {
+	rows, err := db.q.Query(`
+		SELECT n.path, n.title, COALESCE(a.alias, '')
+		FROM notes n
+		LEFT JOIN aliases a ON a.note_id = n.id
+		ORDER BY n.path, a.alias
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []LinkTargetResult
+	for rows.Next() {
+		var path, title, alias string
+		if err := rows.Scan(&path, &title, &alias); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		if n := len(results); n == 0 || results[n-1].Path != path {
+			results = append(results, LinkTargetResult{Path: path, Title: title})
+		}
+		if alias != "" {
+			last := &results[len(results)-1]
+			last.Aliases = append(last.Aliases, alias)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetBrokenLinks returns every link whose target is not a known note, grouped
+// by source note and in line order within each note.
+func (db *DB) GetBrokenLinks() ([]BrokenLinkResult, error) {
+	rows, err := db.q.Query(`
+		SELECT n.path, n.title, l.target_path, l.section, l.line, l.col, l.markdown
+		FROM links l
+		JOIN notes n ON n.id = l.source_id
+		WHERE l.target_id IS NULL
+		ORDER BY n.path, l.line, l.col
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []BrokenLinkResult
+	for rows.Next() {
+		var r BrokenLinkResult
+		if err := rows.Scan(&r.SourcePath, &r.SourceTitle, &r.Target, &r.Section, &r.Line, &r.Col, &r.Markdown); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// ListTags returns every tag at least one note carries, directly or through
+// a subtag, with its note counts, most used first and then by name. Parent
+// segments of nested tags are listed even when no note carries them alone.
+func (db *DB) ListTags() ([]TagResult, error) {
+	rows, err := db.q.Query(`
+		WITH RECURSIVE sub(root, id) AS (
+			SELECT id, id FROM tags
+			UNION SELECT sub.root, t.id FROM tags t JOIN sub ON t.parent_id = sub.id
+		)
+		SELECT t.name,
+			(SELECT COUNT(*) FROM note_tags WHERE tag_id = t.id),
+			COUNT(DISTINCT nt.note_id) AS total
+		FROM tags t
+		JOIN sub ON sub.root = t.id
+		JOIN note_tags nt ON nt.tag_id = sub.id
+		GROUP BY t.id
+		ORDER BY total DESC, t.name
+	`)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []TagResult
+	for rows.Next() {
+		var r TagResult
+		if err := rows.Scan(&r.Name, &r.Notes, &r.Total); err != nil {
+			return nil, errors.Join(err, rows.Close())
+		}
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Join(err, rows.Close())
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// SortTagTree orders tags so each follows its parent, with siblings by
+// name ignoring case, for showing nested tags as a tree.
+func SortTagTree(tags []TagResult) {
+	slices.SortFunc(tags, func(x, y TagResult) int {
+		return slices.Compare(strings.Split(strings.ToLower(x.Name), "/"), strings.Split(strings.ToLower(y.Name), "/"))
+	})
+}
+
+// escapeLike escapes LIKE wildcards so s matches literally (with ESCAPE '\').
+func escapeLike(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, "%", `\%`)
+	return strings.ReplaceAll(s, "_", `\_`)
+}
@@ -2,8 +2,10 @@ package index
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -11,104 +13,438 @@ import (
 	"github.com/fsnotify/fsnotify"
 )
 
+// pollInterval is how often a polling watcher rescans the vault.
+const pollInterval = 2 * time.Second
+
+// wakeCheck is how often the reconcile loop checks the wall clock for time
+// spent asleep, which the monotonic clock behind tickers doesn't count.
+const wakeCheck = 30 * time.Second
+
+// debounceDelay is how long the watcher waits for a burst of events on a
+// path to settle, and for a renamed directory's new name to show up.
+const debounceDelay = 200 * time.Millisecond
+
 // Watcher monitors the vault for file changes and triggers re-indexing.
+//
+// It uses fsnotify where it can. When that fails, at startup or later (the
+// inotify watch limit, network file systems, some containers), it falls
+// back to rescanning the watched roots every pollInterval and comparing
+// modification times and sizes.
 type Watcher struct {
 	indexer  *Indexer
-	watcher  *fsnotify.Watcher
+	watcher  *fsnotify.Watcher // nil when polling
 	root     string
+	roots    []string        // root and the external sources watched
+	dirs     map[string]bool // directories watched with fsnotify
 	debounce map[string]*time.Timer
-	mu       sync.Mutex
-	onChange func()      // callback after index changes
-	onError  func(error) // callback on fatal errors
+
+	// moved is a watched directory renamed away, until its new name shows
+	// up or movedTimer drops it from the index.
+	moved      string
+	movedTimer *time.Timer
+	mu         sync.Mutex
+	onChange   func()      // callback after index changes
+	onError    func(error) // callback on fatal errors
+
+	onPolling  func(error)  // callback when falling back to polling, with the reason
+	onWrite    func(string) // callback with the path of a note written to
+	polling    bool
+	pollReason error
+	interval   time.Duration
+	files      map[string]fileStamp // last scan, when polling
+
+	reconcileEvery time.Duration // 0 disables the background scan
+
+	// pauses counts Pause calls not yet resumed. While paused, debounced
+	// reindexes and directory changes wait in held, by path, instead of
+	// running.
+	pauses   int
+	held     map[string]func()
+	onQueue  func(queued int, paused bool)
+	reported struct {
+		queued int
+		paused bool
+	}
+
+	done     chan struct{}
+	stopOnce sync.Once
 
 	closed bool
 }
 
-func NewWatcher(indexer *Indexer, root string, onChange func(), onError func(error)) (*Watcher, error) {
-	fw, err := fsnotify.NewWatcher()
-	if err != nil {
-		return nil, err
-	}
+// fileStamp is what a polling watcher compares to spot a changed file.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
 
+func NewWatcher(indexer *Indexer, root string, onChange func(), onError func(error)) (*Watcher, error) {
 	w := &Watcher{
 		indexer:  indexer,
-		watcher:  fw,
 		root:     root,
+		dirs:     make(map[string]bool),
 		debounce: make(map[string]*time.Timer),
+		held:     make(map[string]func()),
 		onChange: onChange,
 		onError:  onError,
+		interval: pollInterval,
+		done:     make(chan struct{}),
+	}
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		w.usePolling(err)
+	} else {
+		w.watcher = fw
 	}
 
 	// Add vault root and subdirectories
-	if err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+	if err := w.Watch(root); err != nil {
+		return nil, errors.Join(err, w.Stop())
+	}
+
+	return w, nil
+}
+
+// SetPollingHook registers fn to be called, from Start's goroutine, when the
+// watcher polls instead of using fsnotify. Call before Start.
+func (w *Watcher) SetPollingHook(fn func(reason error)) {
+	w.onPolling = fn
+}
+
+// SetWriteHook registers fn to be called with the absolute path of each note
+// written to, or created, once it is indexed. Call before Start.
+func (w *Watcher) SetWriteHook(fn func(path string)) {
+	w.onWrite = fn
+}
+
+// SetQueueHook registers fn to be called, from the watcher's goroutines,
+// when the number of changed files waiting to be reindexed changes or the
+// watcher is paused or resumed. Call before Start.
+func (w *Watcher) SetQueueHook(fn func(queued int, paused bool)) {
+	w.onQueue = fn
+}
+
+// Pause holds the reindexes of changed files and directories until Resume,
+// while something else, such as a full index, writes the index. Nothing is
+// lost: each path is reindexed once, with its latest change, when the
+// watcher resumes. Pauses nest.
+func (w *Watcher) Pause() {
+	w.mu.Lock()
+	w.pauses++
+	w.mu.Unlock()
+	w.notifyQueue()
+}
+
+// Resume undoes a Pause, and once none are left runs the held reindexes in
+// the background.
+func (w *Watcher) Resume() {
+	w.mu.Lock()
+	if w.pauses > 0 {
+		w.pauses--
+	}
+	w.mu.Unlock()
+	w.notifyQueue()
+	go w.runHeld()
+}
+
+// ResumeAndReconcile undoes a Pause like Resume, but once none are left it
+// drops the held reindexes and reconciles the index with the vault instead,
+// which is quicker after a sync or checkout touched thousands of files.
+func (w *Watcher) ResumeAndReconcile() (IndexStats, error) {
+	w.mu.Lock()
+	if w.pauses > 0 {
+		w.pauses--
+	}
+	resumed := w.pauses == 0
+	if resumed {
+		clear(w.held)
+	}
+	w.mu.Unlock()
+	w.notifyQueue()
+	if !resumed {
+		return IndexStats{}, nil
+	}
+	return w.Reconcile()
+}
+
+// run runs job now, or holds it under key until Resume while paused. It
+// reports the queue either way, as the caller may have just taken job off
+// it.
+func (w *Watcher) run(key string, job func()) {
+	w.mu.Lock()
+	if w.pauses > 0 {
+		w.held[key] = job
+		w.mu.Unlock()
+		w.notifyQueue()
+		return
+	}
+	w.mu.Unlock()
+	w.notifyQueue()
+	job()
+}
+
+// runHeld reindexes the files held while paused, one at a time, until none
+// are left or the watcher is paused again.
+func (w *Watcher) runHeld() {
+	for {
+		w.mu.Lock()
+		if w.pauses > 0 || w.closed || len(w.held) == 0 {
+			w.mu.Unlock()
+			return
+		}
+		var path string
+		var job func()
+		for path, job = range w.held {
+			break
+		}
+		delete(w.held, path)
+		w.mu.Unlock()
+		w.notifyQueue()
+		job()
+	}
+}
+
+// paused reports whether reindexes are held.
+func (w *Watcher) paused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.pauses > 0
+}
+
+// notifyQueue calls the queue hook if the number of waiting reindexes or
+// the pause state changed since it was last called.
+func (w *Watcher) notifyQueue() {
+	w.mu.Lock()
+	queued, paused := len(w.debounce)+len(w.held), w.pauses > 0
+	if w.onQueue == nil || (queued == w.reported.queued && paused == w.reported.paused) {
+		w.mu.Unlock()
+		return
+	}
+	w.reported.queued, w.reported.paused = queued, paused
+	onQueue := w.onQueue
+	w.mu.Unlock()
+	onQueue(queued, paused)
+}
+
+// SetReconcileInterval makes the watcher scan for changes it missed every
+// d, and after the machine wakes from sleep; 0, the default, scans only on
+// Reconcile. Call before Start.
+func (w *Watcher) SetReconcileInterval(d time.Duration) {
+	w.reconcileEvery = d
+}
+
+// Reconcile re-indexes the notes whose files changed without the watcher
+// noticing; see Indexer.Reconcile. It calls the write hook for each note
+// rewritten, and the change callback if anything changed.
+func (w *Watcher) Reconcile() (IndexStats, error) {
+	stats, err := w.indexer.Reconcile(w.onWrite)
+	if err == nil && stats.Indexed+stats.Removed > 0 && w.onChange != nil {
+		w.onChange()
+	}
+	return stats, err
+}
+
+// reconcileLoop runs Reconcile every reconcileEvery, and on the first check
+// after the machine slept. A polling watcher already rescans everything, so
+// it is skipped then.
+func (w *Watcher) reconcileLoop() {
+	tick := min(w.reconcileEvery, wakeCheck)
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	// Round(0) drops the monotonic reading, so these compare wall clock.
+	lastScan := time.Now().Round(0)
+	lastTick := lastScan
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		now := time.Now().Round(0)
+		slept := now.Sub(lastTick) > 2*tick
+		lastTick = now
+		if (!slept && now.Sub(lastScan) < w.reconcileEvery) || w.Polling() || w.paused() {
+			continue
+		}
+		lastScan = now
+		if _, err := w.Reconcile(); err != nil {
+			w.fatal(err)
+			return
+		}
+	}
+}
+
+// Polling reports whether the watcher rescans the vault instead of using
+// fsnotify.
+func (w *Watcher) Polling() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.polling
+}
+
+// Watch also watches root and its subdirectories, such as an external
+// source's. Hidden directories, and those the indexer skips or the vault's
+// IgnoreFile ignores, are left out. If fsnotify refuses a directory, the
+// watcher falls back to polling.
+func (w *Watcher) Watch(root string) error {
+	if _, err := os.Stat(root); err != nil {
+		return err
+	}
+	w.mu.Lock()
+	w.roots = append(w.roots, root)
+	w.mu.Unlock()
+	if w.Polling() {
+		return nil
+	}
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
-			if strings.HasPrefix(info.Name(), ".") && path != root {
+			if path != root && w.skipDir(path) {
 				return filepath.SkipDir
 			}
-			if err := fw.Add(path); err != nil {
-				return err
+			if err := w.watcher.Add(path); err != nil {
+				w.usePolling(fmt.Errorf("watch %s: %w", path, err))
+				return filepath.SkipAll
 			}
+			w.mu.Lock()
+			w.dirs[path] = true
+			w.mu.Unlock()
 		}
 		return nil
-	}); err != nil {
-		return nil, errors.Join(err, fw.Close())
+	})
+}
+
+// usePolling switches the watcher to polling, closing fsnotify.
+func (w *Watcher) usePolling(reason error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.polling {
+		return
 	}
+	w.polling = true
+	w.pollReason = reason
+	if w.watcher != nil {
+		if err := w.watcher.Close(); err != nil {
+			w.pollReason = errors.Join(reason, err)
+		}
+		w.watcher = nil
+	}
+}
 
-	return w, nil
+// skipDir reports whether the directory at path is left unwatched.
+func (w *Watcher) skipDir(path string) bool {
+	return strings.HasPrefix(filepath.Base(path), ".") || w.indexer.skipped(w.indexer.relPath(path), true)
 }
 
 // Start begins watching for changes. Blocks until Stop is called.
 func (w *Watcher) Start() {
+	if w.reconcileEvery > 0 {
+		go w.reconcileLoop()
+	}
+	w.mu.Lock()
+	fw := w.watcher
+	w.mu.Unlock()
+	if fw != nil {
+		err := w.watchEvents(fw)
+		if err == nil || w.isClosed() {
+			return
+		}
+		w.usePolling(err)
+		// Events may have been lost before fsnotify failed: catch up with
+		// an incremental index before the first scan.
+		if _, err := w.indexer.Update(nil); err != nil {
+			w.fatal(err)
+			return
+		}
+		if w.onChange != nil {
+			w.onChange()
+		}
+	}
+
+	w.mu.Lock()
+	reason := w.pollReason
+	w.mu.Unlock()
+	if w.onPolling != nil {
+		w.onPolling(reason)
+	}
+	w.poll()
+}
+
+// watchEvents handles fsnotify events until the watcher is closed, or
+// returns the error fsnotify failed with.
+func (w *Watcher) watchEvents(fw *fsnotify.Watcher) error {
 	for {
 		select {
-		case event, ok := <-w.watcher.Events:
+		case event, ok := <-fw.Events:
 			if !ok {
-				return
+				return nil
+			}
+			if err := w.handleEvent(fw, event); err != nil {
+				return err
 			}
-			w.handleEvent(event)
 
-		case err, ok := <-w.watcher.Errors:
+		case err, ok := <-fw.Errors:
 			if !ok {
-				return
+				return nil
 			}
-			w.fatal(err)
-			return
+			return err
 		}
 	}
 }
 
-func (w *Watcher) handleEvent(event fsnotify.Event) {
+func (w *Watcher) handleEvent(fw *fsnotify.Watcher, event fsnotify.Event) error {
 	path := event.Name
 
-	// Only care about markdown files
-	if !strings.HasSuffix(path, ".md") {
-		// But watch new directories
-		if event.Has(fsnotify.Create) {
-			info, err := os.Stat(path)
-			if err == nil && info.IsDir() && !strings.HasPrefix(info.Name(), ".") {
-				if err := w.watcher.Add(path); err != nil {
-					w.fatal(err)
-					return
-				}
+	// A watched directory renamed or deleted. A renamed one may show up
+	// again under its new name, as the next directory created; a deleted
+	// one is never paired, or its notes would move to whatever directory
+	// is created next.
+	if event.Has(fsnotify.Rename) || event.Has(fsnotify.Remove) {
+		w.mu.Lock()
+		watched := w.dirs[path]
+		w.mu.Unlock()
+		if watched {
+			if err := w.unwatchDirs(fw, path); err != nil {
+				return err
 			}
+			if event.Has(fsnotify.Rename) {
+				w.dirGone(path)
+			} else {
+				w.removeDir(path)
+			}
+			return nil
 		}
-		return
 	}
 
-	// Debounce: wait 200ms before processing
-	w.mu.Lock()
-	if timer, ok := w.debounce[path]; ok {
-		timer.Stop()
+	// Watch new directories
+	if event.Has(fsnotify.Create) && !strings.HasSuffix(path, ".md") {
+		info, err := os.Stat(path)
+		if err == nil && info.IsDir() {
+			if !w.skipDir(path) {
+				if err := w.watchDirs(fw, path); err != nil {
+					return err
+				}
+				w.dirArrived(path)
+			}
+			return nil
+		}
+	}
+	attachment := isAttachment(filepath.Base(path))
+	if !attachment && !strings.HasSuffix(path, ".md") {
+		return nil
 	}
-	w.debounce[path] = time.AfterFunc(200*time.Millisecond, func() {
-		w.mu.Lock()
-		delete(w.debounce, path)
-		w.mu.Unlock()
 
-		if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+	reindex := func() {
+		if attachment {
+			// Checks whether the file is still there, so it covers
+			// removals too.
+			if err := w.indexer.IndexAttachment(path); err != nil {
+				w.fatal(err)
+				return
+			}
+		} else if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
 			if err := w.indexer.RemoveFile(path); err != nil {
 				w.fatal(err)
 				return
@@ -118,19 +454,276 @@ func (w *Watcher) handleEvent(event fsnotify.Event) {
 				w.fatal(err)
 				return
 			}
+			if w.onWrite != nil {
+				w.onWrite(path)
+			}
 		}
 
 		if w.onChange != nil {
 			w.onChange()
 		}
+	}
+
+	// Debounce: wait 200ms before processing
+	w.mu.Lock()
+	if timer, ok := w.debounce[path]; ok {
+		timer.Stop()
+	}
+	w.debounce[path] = time.AfterFunc(debounceDelay, func() {
+		w.mu.Lock()
+		delete(w.debounce, path)
+		w.mu.Unlock()
+		w.run(path, reindex)
 	})
 	w.mu.Unlock()
+	w.notifyQueue()
+	return nil
+}
+
+// watchDirs watches dir and the directories under it, as watching a root
+// does.
+func (w *Watcher) watchDirs(fw *fsnotify.Watcher, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+		if path != dir && w.skipDir(path) {
+			return filepath.SkipDir
+		}
+		if err := fw.Add(path); err != nil {
+			return fmt.Errorf("watch %s: %w", path, err)
+		}
+		w.mu.Lock()
+		w.dirs[path] = true
+		w.mu.Unlock()
+		return nil
+	})
+}
+
+// unwatchDirs drops the watches on dir and the directories under it. After
+// a rename inotify still reports events from them, under the old paths.
+func (w *Watcher) unwatchDirs(fw *fsnotify.Watcher, dir string) error {
+	prefix := dir + string(filepath.Separator)
+	w.mu.Lock()
+	var stale []string
+	for d := range w.dirs {
+		if d == dir || strings.HasPrefix(d, prefix) {
+			stale = append(stale, d)
+			delete(w.dirs, d)
+		}
+	}
+	w.mu.Unlock()
+	for _, d := range stale {
+		// fsnotify already dropped the watches of deleted directories and
+		// of the renamed one itself.
+		if err := fw.Remove(d); err != nil && !errors.Is(err, fsnotify.ErrNonExistentWatch) {
+			return fmt.Errorf("unwatch %s: %w", d, err)
+		}
+	}
+	return nil
+}
+
+// dirGone records that the watched directory dir was renamed.
+// If no directory is created within debounceDelay to pair it with as its
+// new name, its notes and attachments are dropped from the index.
+func (w *Watcher) dirGone(dir string) {
+	w.mu.Lock()
+	prev := w.moved
+	if w.movedTimer != nil {
+		w.movedTimer.Stop()
+	}
+	w.moved = dir
+	w.movedTimer = time.AfterFunc(debounceDelay, func() {
+		w.mu.Lock()
+		if w.moved != dir {
+			w.mu.Unlock()
+			return
+		}
+		w.moved, w.movedTimer = "", nil
+		w.mu.Unlock()
+		w.removeDir(dir)
+	})
+	w.mu.Unlock()
+
+	// Only the latest rename is paired; an earlier one left waiting went
+	// somewhere unwatched.
+	if prev != "" && prev != dir {
+		w.removeDir(prev)
+	}
+}
+
+// dirArrived indexes the new directory dir: as the new name of the
+// directory renamed just before, or as one moved in or created.
+func (w *Watcher) dirArrived(dir string) {
+	w.mu.Lock()
+	from := w.moved
+	if w.movedTimer != nil {
+		w.movedTimer.Stop()
+	}
+	w.moved, w.movedTimer = "", nil
+	w.mu.Unlock()
+
+	w.run(dir, func() {
+		var err error
+		if from != "" {
+			err = w.indexer.MoveDir(from, dir)
+		} else {
+			err = w.indexer.IndexDir(dir)
+		}
+		if err != nil {
+			w.fatal(err)
+			return
+		}
+		if w.onChange != nil {
+			w.onChange()
+		}
+	})
+}
+
+func (w *Watcher) removeDir(dir string) {
+	w.run(dir, func() {
+		if err := w.indexer.RemoveDir(dir); err != nil {
+			w.fatal(err)
+			return
+		}
+		if w.onChange != nil {
+			w.onChange()
+		}
+	})
+}
+
+// poll rescans the watched roots every interval until Stop is called.
+func (w *Watcher) poll() {
+	w.files = w.scan(nil)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			// The first scan after Resume catches up.
+			if w.paused() {
+				continue
+			}
+			if err := w.rescan(); err != nil {
+				w.fatal(err)
+				return
+			}
+		}
+	}
+}
+
+// scan stamps the notes and attachments under the watched roots, skipping
+// what the fsnotify watcher would. A file or directory that cannot be read
+// keeps its stamps from prev, so a flaky mount is not mistaken for deleted
+// notes; the next scan that can read it sees any real change.
+func (w *Watcher) scan(prev map[string]fileStamp) map[string]fileStamp {
+	w.mu.Lock()
+	roots := slices.Clone(w.roots)
+	w.mu.Unlock()
+
+	files := make(map[string]fileStamp)
+	carry := func(path string) {
+		for p, stamp := range prev {
+			if p == path || strings.HasPrefix(p, path+string(filepath.Separator)) {
+				files[p] = stamp
+			}
+		}
+	}
+	for _, root := range roots {
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				carry(path)
+				if info != nil && info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if info.IsDir() {
+				if path != root && w.skipDir(path) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			name := info.Name()
+			if !info.Mode().IsRegular() || (!strings.HasSuffix(name, ".md") && !isAttachment(name)) {
+				return nil
+			}
+			if w.indexer.skipped(w.indexer.relPath(path), false) {
+				return nil
+			}
+			files[path] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+			return nil
+		})
+		if err != nil {
+			carry(root)
+		}
+	}
+	return files
+}
+
+// rescan indexes what changed since the last scan.
+func (w *Watcher) rescan() error {
+	files := w.scan(w.files)
+	changed := false
+	for path, stamp := range files {
+		if old, ok := w.files[path]; ok && old.modTime.Equal(stamp.modTime) && old.size == stamp.size {
+			continue
+		}
+		changed = true
+		if isAttachment(filepath.Base(path)) {
+			if err := w.indexer.IndexAttachment(path); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.indexer.IndexFile(path); err != nil {
+			return err
+		}
+		if w.onWrite != nil {
+			w.onWrite(path)
+		}
+	}
+	for path := range w.files {
+		if _, ok := files[path]; ok {
+			continue
+		}
+		changed = true
+		var err error
+		if isAttachment(filepath.Base(path)) {
+			err = w.indexer.IndexAttachment(path)
+		} else {
+			err = w.indexer.RemoveFile(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	w.files = files
+
+	if changed && w.onChange != nil {
+		w.onChange()
+	}
+	return nil
+}
+
+func (w *Watcher) isClosed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closed
 }
 
 // Stop stops the watcher.
 func (w *Watcher) Stop() error {
 	w.mu.Lock()
 	w.closed = true
+	fw := w.watcher
+	w.watcher = nil
 	w.mu.Unlock()
-	return w.watcher.Close()
+	w.stopOnce.Do(func() { close(w.done) })
+	if fw != nil {
+		return fw.Close()
+	}
+	return nil
 }
@@ -0,0 +1,427 @@
+package index
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestWatcherPolling(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	write := func(rel, content string) {
+		t.Helper()
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("keep.md", "# Keep\n")
+	write("gone.md", "# Gone\n")
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan struct{}, 16)
+	w, err := NewWatcher(idx, root, func() { changed <- struct{}{} }, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := w.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+	// Force the fallback fsnotify would take on, say, ENOSPC.
+	w.usePolling(errors.New("no inotify watches left"))
+	w.interval = 10 * time.Millisecond
+	reasons := make(chan error, 1)
+	w.SetPollingHook(func(err error) { reasons <- err })
+	written := make(chan string, 16)
+	w.SetWriteHook(func(path string) { written <- path })
+	go w.Start()
+
+	if err := <-reasons; err == nil || err.Error() != "no inotify watches left" {
+		t.Fatalf("polling reason = %v", err)
+	}
+	if !w.Polling() {
+		t.Fatal("Polling() = false")
+	}
+
+	// Let the first scan run before changing anything.
+	time.Sleep(50 * time.Millisecond)
+	write("new/added.md", "# Added\n")
+	if err := os.Remove(filepath.Join(root, "gone.md")); err != nil {
+		t.Fatal(err)
+	}
+	// The two changes may land in different scans.
+	indexed := func(path string) bool {
+		id, err := db.GetNoteIDByPath(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id != 0
+	}
+	timeout := time.After(5 * time.Second)
+	for !indexed("new/added.md") || indexed("gone.md") {
+		select {
+		case <-changed:
+		case <-timeout:
+			t.Fatal("changes not seen by polling")
+		}
+	}
+	if !indexed("keep.md") {
+		t.Error("keep.md dropped by a rescan")
+	}
+	select {
+	case path := <-written:
+		if path != filepath.Join(root, "new", "added.md") {
+			t.Errorf("write hook got %s, want new/added.md", path)
+		}
+	default:
+		t.Error("write hook not called for new/added.md")
+	}
+}
+
+func TestWatcherScanKeepsUnreadable(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "here.md"), []byte("# Here\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	w, err := NewWatcher(NewIndexer(db, root), root, nil, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := w.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+
+	// A root that cannot be read, such as a dropped mount, keeps its notes.
+	mount := filepath.Join(t.TempDir(), "mount")
+	w.roots = append(w.roots, mount)
+	stamp := fileStamp{modTime: time.Now(), size: 7}
+	prev := map[string]fileStamp{
+		filepath.Join(mount, "away.md"): stamp,
+		filepath.Join(root, "gone.md"):  stamp,
+	}
+	files := w.scan(prev)
+	if _, ok := files[filepath.Join(mount, "away.md")]; !ok {
+		t.Error("note under an unreadable root dropped")
+	}
+	if _, ok := files[filepath.Join(root, "here.md")]; !ok {
+		t.Error("here.md not scanned")
+	}
+	if _, ok := files[filepath.Join(root, "gone.md")]; ok {
+		t.Error("gone.md carried forward from a readable root")
+	}
+}
+
+func TestWatcherDirRename(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	for rel, content := range map[string]string{
+		"index.md":                  "See [[plan]].\n",
+		"projects/plan.md":          "# Plan\n",
+		"projects/sub/notes.md":     "# Notes\n",
+		"projects/sub/diagram.png":  "png",
+		"archive/old/forgotten.md":  "# Forgotten\n",
+		"archive/old/more/later.md": "# Later\n",
+	} {
+		abs := filepath.Join(root, rel)
+		if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	idx := NewIndexer(db, root)
+	if err := idx.IndexAll(); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.RecordVisit(filepath.FromSlash("projects/plan.md"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan struct{}, 16)
+	w, err := NewWatcher(idx, root, func() { changed <- struct{}{} }, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := w.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+	if w.Polling() {
+		t.Skip("fsnotify unavailable")
+	}
+	go w.Start()
+
+	indexed := func(rel string) bool {
+		id, err := db.GetNoteIDByPath(filepath.FromSlash(rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return id != 0
+	}
+	wait := func(done func() bool) {
+		t.Helper()
+		timeout := time.After(5 * time.Second)
+		for !done() {
+			select {
+			case <-changed:
+			case <-timeout:
+				t.Fatal("timed out waiting for the index")
+			}
+		}
+	}
+
+	// A rename within the vault re-paths the notes under it.
+	if err := os.Rename(filepath.Join(root, "projects"), filepath.Join(root, "work")); err != nil {
+		t.Fatal(err)
+	}
+	wait(func() bool { return indexed("work/sub/notes.md") && !indexed("projects/plan.md") })
+	if !indexed("work/plan.md") || indexed("projects/sub/notes.md") {
+		t.Error("notes under projects/ not moved to work/")
+	}
+	attachments, err := db.ListAttachments()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.ContainsFunc(attachments, func(a Attachment) bool { return a.Path == filepath.FromSlash("work/sub/diagram.png") }) {
+		t.Errorf("attachments = %v, want work/sub/diagram.png", attachments)
+	}
+	recent, err := db.RecentNotes(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recent) != 1 || recent[0].Path != filepath.FromSlash("work/plan.md") {
+		t.Errorf("recent notes = %v, want the visit to carry over to work/plan.md", recent)
+	}
+	backlinks, err := db.GetBacklinks(filepath.FromSlash("work/plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backlinks) != 1 || backlinks[0].SourcePath != "index.md" {
+		t.Errorf("backlinks of work/plan.md = %v", backlinks)
+	}
+
+	// The old subdirectory's watch is gone: a note written under the new
+	// name is indexed there, not under projects/.
+	if err := os.WriteFile(filepath.Join(root, "work", "sub", "new.md"), []byte("# New\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wait(func() bool { return indexed("work/sub/new.md") })
+	if indexed("projects/sub/new.md") {
+		t.Error("new note indexed under the old directory name")
+	}
+
+	// Moved out of the vault, a directory's notes are dropped.
+	if err := os.Rename(filepath.Join(root, "archive", "old"), filepath.Join(t.TempDir(), "old")); err != nil {
+		t.Fatal(err)
+	}
+	wait(func() bool { return !indexed("archive/old/forgotten.md") && !indexed("archive/old/more/later.md") })
+
+	// A deleted directory is not paired with one created right after it.
+	if err := os.RemoveAll(filepath.Join(root, "work", "sub")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "fresh"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wait(func() bool { return !indexed("work/sub/notes.md") })
+	time.Sleep(2 * debounceDelay)
+	if indexed("fresh/notes.md") || indexed("fresh/new.md") {
+		t.Error("deleted notes moved to the directory created next")
+	}
+
+	// Nor is one moved out of the vault: the directory created next holds
+	// none of its notes.
+	if err := os.Rename(filepath.Join(root, "work"), filepath.Join(t.TempDir(), "work")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "drafts"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	wait(func() bool { return !indexed("work/plan.md") })
+	time.Sleep(2 * debounceDelay)
+	if indexed("drafts/plan.md") {
+		t.Error("notes moved out of the vault re-pathed to the directory created next")
+	}
+}
+
+func TestWatcherPause(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	idx := NewIndexer(db, root)
+	changed := make(chan struct{}, 16)
+	w, err := NewWatcher(idx, root, func() { changed <- struct{}{} }, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := w.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+	if w.Polling() {
+		t.Skip("fsnotify unavailable")
+	}
+	type state struct {
+		queued int
+		paused bool
+	}
+	states := make(chan state, 16)
+	w.SetQueueHook(func(queued int, paused bool) { states <- state{queued, paused} })
+	go w.Start()
+
+	next := func() state {
+		t.Helper()
+		select {
+		case s := <-states:
+			return s
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for the queue hook")
+			return state{}
+		}
+	}
+
+	w.Pause()
+	if s := next(); s != (state{0, true}) {
+		t.Errorf("after Pause: %+v", s)
+	}
+	if err := os.WriteFile(filepath.Join(root, "note.md"), []byte("# Note\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if s := next(); s != (state{1, true}) {
+		t.Errorf("after a write: %+v", s)
+	}
+
+	// The debounced reindex is held, not run, and still counts as queued.
+	time.Sleep(2 * debounceDelay)
+	if id, err := db.GetNoteIDByPath("note.md"); err != nil || id != 0 {
+		t.Errorf("note indexed while paused: id %d, err %v", id, err)
+	}
+	select {
+	case s := <-states:
+		t.Errorf("queue changed while held: %+v", s)
+	default:
+	}
+
+	w.Resume()
+	if s := next(); s != (state{1, false}) {
+		t.Errorf("after Resume: %+v", s)
+	}
+	if s := next(); s != (state{0, false}) {
+		t.Errorf("after the held reindex: %+v", s)
+	}
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the index")
+	}
+	if id, err := db.GetNoteIDByPath("note.md"); err != nil || id == 0 {
+		t.Errorf("note not indexed after Resume: id %d, err %v", id, err)
+	}
+}
+
+func TestWatcherResumeAndReconcile(t *testing.T) {
+	db, err := OpenMemory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := db.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}()
+
+	root := t.TempDir()
+	idx := NewIndexer(db, root)
+	w, err := NewWatcher(idx, root, nil, func(err error) { t.Error(err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		if err := w.Stop(); err != nil {
+			t.Error(err)
+		}
+	}()
+	if w.Polling() {
+		t.Skip("fsnotify unavailable")
+	}
+	go w.Start()
+
+	// Nested pauses: only the last resume reconciles.
+	w.Pause()
+	w.Pause()
+	for _, name := range []string{"a.md", "b.md"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte("# "+name+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(2 * debounceDelay)
+	if stats, err := w.ResumeAndReconcile(); err != nil || stats.Files != 0 {
+		t.Errorf("first resume: stats %+v, err %v; want no scan while still paused", stats, err)
+	}
+	stats, err := w.ResumeAndReconcile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Indexed != 2 {
+		t.Errorf("reconcile indexed %d notes, want 2", stats.Indexed)
+	}
+	w.mu.Lock()
+	held := len(w.held)
+	w.mu.Unlock()
+	if held != 0 {
+		t.Errorf("%d reindexes still held after the reconcile", held)
+	}
+}
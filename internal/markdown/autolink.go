@@ -0,0 +1,223 @@
+package markdown
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// minMentionLen is the shortest title/alias (in runes) the auto-linker will
+// look for; shorter terms match too much ordinary prose.
+const minMentionLen = 3
+
+// LinkTarget is a note that plain-text mentions can be linked to.
+type LinkTarget struct {
+	Name  string   // link target written inside [[...]] (basename without .md, folder-qualified if shared)
+	Terms []string // title and aliases to look for in text
+}
+
+// LinkSuggestion is an unlinked mention of another note.
+type LinkSuggestion struct {
+	Line   int    // 1-based line number
+	Col    int    // 0-based byte offset of the mention in the line
+	Text   string // mention as written in the note
+	Target string // LinkTarget.Name to link to
+}
+
+// FindUnlinkedMentions scans the plain text of content for titles/aliases of
+// targets and returns one suggestion per target (its first mention).
+//
+// Frontmatter, headings, fenced code, inline code, existing wiki links,
+// markdown links and bare URLs are never touched. Targets the note already
+// links to are skipped, as is any term or target listed in ignore
+// (case-insensitive). Mentions must be whole words; longer terms win when
+// two terms overlap.
+func FindUnlinkedMentions(content []byte, targets []LinkTarget, ignore []string) []LinkSuggestion {
+	ignored := make(map[string]bool, len(ignore))
+	for _, s := range ignore {
+		ignored[strings.ToLower(strings.TrimSpace(s))] = true
+	}
+	linked := make(map[string]bool)
+	for _, l := range ExtractWikiLinks(content) {
+		linked[strings.ToLower(strings.TrimSuffix(l.Target, ".md"))] = true
+	}
+
+	type term struct {
+		re     *regexp.Regexp
+		target string
+		length int
+	}
+	var terms []term
+	for _, t := range targets {
+		key := strings.ToLower(t.Name)
+		if t.Name == "" || linked[key] || ignored[key] {
+			continue
+		}
+		for _, s := range t.Terms {
+			s = strings.TrimSpace(s)
+			if utf8.RuneCountInString(s) < minMentionLen || ignored[strings.ToLower(s)] {
+				continue
+			}
+			terms = append(terms, term{
+				re:     regexp.MustCompile(`(?i)` + regexp.QuoteMeta(s)),
+				target: t.Name,
+				length: len(s),
+			})
+		}
+	}
+	if len(terms) == 0 {
+		return nil
+	}
+	sort.SliceStable(terms, func(i, j int) bool { return terms[i].length > terms[j].length })
+
+	lines := strings.Split(string(content), "\n")
+	protected := make([][]bool, len(lines))
+	inFrontmatter, inFence := false, false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case i == 0 && trimmed == "---":
+			inFrontmatter = true
+			continue
+		case inFrontmatter:
+			if trimmed == "---" {
+				inFrontmatter = false
+			}
+			continue
+		case strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~"):
+			inFence = !inFence
+			continue
+		case inFence || isHeading(trimmed):
+			continue
+		}
+		protected[i] = protectedSpans(line)
+	}
+
+	var suggestions []LinkSuggestion
+	done := make(map[string]bool)
+	for _, tm := range terms {
+		if done[tm.target] {
+			continue
+		}
+		found := false
+		for i, line := range lines {
+			if protected[i] == nil {
+				continue
+			}
+			for _, loc := range tm.re.FindAllStringIndex(line, -1) {
+				if !isWordBounded(line, loc[0], loc[1]) || overlaps(protected[i], loc[0], loc[1]) {
+					continue
+				}
+				for j := loc[0]; j < loc[1]; j++ {
+					protected[i][j] = true
+				}
+				suggestions = append(suggestions, LinkSuggestion{
+					Line:   i + 1,
+					Col:    loc[0],
+					Text:   line[loc[0]:loc[1]],
+					Target: tm.target,
+				})
+				found = true
+				break
+			}
+			if found {
+				break
+			}
+		}
+		if found {
+			done[tm.target] = true
+		}
+	}
+
+	sort.Slice(suggestions, func(i, j int) bool {
+		if suggestions[i].Line != suggestions[j].Line {
+			return suggestions[i].Line < suggestions[j].Line
+		}
+		return suggestions[i].Col < suggestions[j].Col
+	})
+	return suggestions
+}
+
+// ApplyLinkSuggestions rewrites each suggested mention as a wiki link. The
+// written text is kept: [[Text]] when it already names the target
+// (case-insensitive), [[target|Text]] otherwise.
+func ApplyLinkSuggestions(content []byte, suggestions []LinkSuggestion) []byte {
+	lines := strings.Split(string(content), "\n")
+
+	// Apply right-to-left so earlier columns on the same line stay valid.
+	sorted := append([]LinkSuggestion(nil), suggestions...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Line != sorted[j].Line {
+			return sorted[i].Line > sorted[j].Line
+		}
+		return sorted[i].Col > sorted[j].Col
+	})
+
+	for _, s := range sorted {
+		idx := s.Line - 1
+		if idx < 0 || idx >= len(lines) {
+			continue
+		}
+		line := lines[idx]
+		end := s.Col + len(s.Text)
+		if s.Col < 0 || end > len(line) || line[s.Col:end] != s.Text {
+			continue // content changed since the suggestion was computed
+		}
+		link := "[[" + s.Target + "|" + s.Text + "]]"
+		if strings.EqualFold(s.Text, s.Target) {
+			link = "[[" + s.Text + "]]"
+		}
+		lines[idx] = line[:s.Col] + link + line[end:]
+	}
+	return []byte(strings.Join(lines, "\n"))
+}
+
+var (
+	inlineCodeRe = regexp.MustCompile("`[^`]*`")
+	wikiLinkRe   = regexp.MustCompile(`!?\[\[[^\]]*\]\]`)
+	mdLinkRe     = regexp.MustCompile(`!?\[[^\]]*\]\([^)]*\)`)
+	bareURLRe    = regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://\S+`)
+)
+
+// protectedSpans marks the bytes of line that belong to code, links or URLs.
+// The result is never nil so callers can tell scanned lines from skipped ones.
+func protectedSpans(line string) []bool {
+	mask := make([]bool, len(line))
+	for _, re := range []*regexp.Regexp{inlineCodeRe, wikiLinkRe, mdLinkRe, bareURLRe} {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			for j := loc[0]; j < loc[1]; j++ {
+				mask[j] = true
+			}
+		}
+	}
+	return mask
+}
+
+func overlaps(mask []bool, start, end int) bool {
+	for j := start; j < end; j++ {
+		if mask[j] {
+			return true
+		}
+	}
+	return false
+}
+
+// isWordBounded reports whether line[start:end] is not glued to a letter or
+// digit on either side.
+func isWordBounded(line string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(line[:start])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	if end < len(line) {
+		r, _ := utf8.DecodeRuneInString(line[end:])
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package markdown
+
+import "testing"
+
+func TestFindUnlinkedMentions(t *testing.T) {
+	targets := []LinkTarget{
+		{Name: "project-alpha", Terms: []string{"Project Alpha", "Alpha"}},
+		{Name: "go", Terms: []string{"go"}}, // too short
+		{Name: "kubernetes", Terms: []string{"Kubernetes", "k8s"}},
+		{Name: "beta", Terms: []string{"Beta"}},
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		ignore  []string
+		want    []LinkSuggestion
+	}{
+		{
+			name:    "first mention only, longest term wins",
+			content: "Working on project alpha today.\nMore Alpha later.",
+			want: []LinkSuggestion{
+				{Line: 1, Col: 11, Text: "project alpha", Target: "project-alpha"},
+			},
+		},
+		{
+			name:    "alias match",
+			content: "Deploying to k8s.",
+			want: []LinkSuggestion{
+				{Line: 1, Col: 13, Text: "k8s", Target: "kubernetes"},
+			},
+		},
+		{
+			name:    "whole words only",
+			content: "Alphabet and betamax.",
+			want:    nil,
+		},
+		{
+			name:    "skips code, links, headings and frontmatter",
+			content: "---\ntitle: Beta\n---\n# Beta\n`Beta` [Beta](beta.md) https://x.io/Beta\n```\nBeta\n```\nfinally Beta",
+			want: []LinkSuggestion{
+				{Line: 9, Col: 8, Text: "Beta", Target: "beta"},
+			},
+		},
+		{
+			name:    "already linked target is skipped",
+			content: "See [[Beta|the beta]]. Beta again.",
+			want:    nil,
+		},
+		{
+			name:    "ignore list by term or target",
+			content: "Beta and Kubernetes.",
+			ignore:  []string{"beta", "Kubernetes"},
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindUnlinkedMentions([]byte(tt.content), targets, tt.ignore)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("[%d] got %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestApplyLinkSuggestions(t *testing.T) {
+	content := "Beta meets project alpha.\nnothing here"
+	suggestions := []LinkSuggestion{
+		{Line: 1, Col: 0, Text: "Beta", Target: "beta"},
+		{Line: 1, Col: 11, Text: "project alpha", Target: "project-alpha"},
+		{Line: 2, Col: 0, Text: "stale", Target: "stale"}, // no longer matches
+	}
+	got := string(ApplyLinkSuggestions([]byte(content), suggestions))
+	want := "[[Beta]] meets [[project-alpha|project alpha]].\nnothing here"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,94 @@
+package markdown
+
+import "strings"
+
+// Conflict is a merge conflict git left in a note, from its "<<<<<<<"
+// marker to its ">>>>>>>" marker. Fields are 0-based line indexes.
+type Conflict struct {
+	Start int // "<<<<<<< ours" marker
+	Base  int // "||||||| base" marker of a diff3-style conflict, or -1
+	Mid   int // "=======" separator
+	End   int // ">>>>>>> theirs" marker
+}
+
+// Resolution picks which side of a conflict to keep.
+type Resolution int
+
+const (
+	KeepOurs Resolution = iota
+	KeepTheirs
+	KeepBoth // ours, then theirs
+)
+
+// FindConflicts returns the conflicts in lines, in order. A conflict
+// missing its separator or end marker is ignored, and "=======" outside a
+// conflict is left alone since it also underlines markdown headings.
+func FindConflicts(lines []string) []Conflict {
+	var conflicts []Conflict
+	open := false
+	var c Conflict
+	for i, line := range lines {
+		switch {
+		case isMarker(line, '<'):
+			open = true
+			c = Conflict{Start: i, Base: -1, Mid: -1}
+		case !open:
+		case isMarker(line, '|') && c.Mid < 0:
+			c.Base = i
+		case strings.TrimRight(line, " \t\r") == "=======" && c.Mid < 0:
+			c.Mid = i
+		case isMarker(line, '>') && c.Mid >= 0:
+			c.End = i
+			conflicts = append(conflicts, c)
+			open = false
+		}
+	}
+	return conflicts
+}
+
+// isMarker reports whether line is a conflict marker made of seven c's,
+// alone or followed by a space and a label.
+func isMarker(line string, c byte) bool {
+	marker := strings.Repeat(string(c), 7)
+	rest, ok := strings.CutPrefix(line, marker)
+	return ok && (rest == "" || rest[0] == ' ' || rest[0] == '\r')
+}
+
+// Ours returns our side of the conflict in lines.
+func (c Conflict) Ours(lines []string) []string {
+	end := c.Mid
+	if c.Base >= 0 {
+		end = c.Base
+	}
+	return lines[c.Start+1 : end]
+}
+
+// Theirs returns their side of the conflict in lines.
+func (c Conflict) Theirs(lines []string) []string {
+	return lines[c.Mid+1 : c.End]
+}
+
+// Resolve returns the lines that replace lines[c.Start:c.End+1] to keep
+// the side r picks, without markers.
+func (c Conflict) Resolve(lines []string, r Resolution) []string {
+	var out []string
+	if r != KeepTheirs {
+		out = append(out, c.Ours(lines)...)
+	}
+	if r != KeepOurs {
+		out = append(out, c.Theirs(lines)...)
+	}
+	return out
+}
+
+// ResolveAll returns lines with every conflict resolved by r.
+func ResolveAll(lines []string, r Resolution) []string {
+	var out []string
+	next := 0
+	for _, c := range FindConflicts(lines) {
+		out = append(out, lines[next:c.Start]...)
+		out = append(out, c.Resolve(lines, r)...)
+		next = c.End + 1
+	}
+	return append(out, lines[next:]...)
+}
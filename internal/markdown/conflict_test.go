@@ -0,0 +1,63 @@
+package markdown
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestFindConflicts(t *testing.T) {
+	input := `# Title
+=======
+<<<<<<< HEAD
+ours
+=======
+theirs
+>>>>>>> origin/main
+between
+<<<<<<< ours
+a
+||||||| base
+b
+=======
+c
+>>>>>>> theirs
+<<<<<<< unfinished
+x`
+	lines := strings.Split(input, "\n")
+	got := FindConflicts(lines)
+	want := []Conflict{
+		{Start: 2, Base: -1, Mid: 4, End: 6},
+		{Start: 8, Base: 10, Mid: 12, End: 14},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("FindConflicts = %+v, want %+v", got, want)
+	}
+
+	if ours := got[1].Ours(lines); !slices.Equal(ours, []string{"a"}) {
+		t.Errorf("Ours = %q, want [a]", ours)
+	}
+	if theirs := got[1].Theirs(lines); !slices.Equal(theirs, []string{"c"}) {
+		t.Errorf("Theirs = %q, want [c]", theirs)
+	}
+	if both := got[0].Resolve(lines, KeepBoth); !slices.Equal(both, []string{"ours", "theirs"}) {
+		t.Errorf("Resolve(KeepBoth) = %q", both)
+	}
+}
+
+func TestResolveAll(t *testing.T) {
+	lines := strings.Split("one\n<<<<<<< HEAD\nours\n=======\ntheirs\n>>>>>>> x\ntwo\n<<<<<<< HEAD\n=======\nnew\n>>>>>>> x", "\n")
+	tests := []struct {
+		r    Resolution
+		want string
+	}{
+		{KeepOurs, "one\nours\ntwo"},
+		{KeepTheirs, "one\ntheirs\ntwo\nnew"},
+		{KeepBoth, "one\nours\ntheirs\ntwo\nnew"},
+	}
+	for _, tt := range tests {
+		if got := strings.Join(ResolveAll(lines, tt.r), "\n"); got != tt.want {
+			t.Errorf("ResolveAll(%d) = %q, want %q", tt.r, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package markdown
+
+import "unicode/utf8"
+
+// maxDiffCells bounds the line diff's table. Beyond it the differing middle
+// of the buffer is replaced as one edit.
+const maxDiffCells = 1 << 22
+
+// TextEdit replaces the text from (StartRow, StartCol) to (EndRow, EndCol)
+// with Text, in the terms of nvim_buf_set_text: rows are 0-based, columns
+// are byte offsets, and Text holds the replacement's lines.
+type TextEdit struct {
+	StartRow, StartCol int
+	EndRow, EndCol     int
+	Text               []string
+}
+
+// Edits returns the edits that turn the lines of old into those of new,
+// touching as little as possible so marks and folds on the rest stay put.
+// Runs of changed lines are found with a line diff; a changed line paired
+// with one other line is edited between their common prefix and suffix.
+// The edits are in buffer order and don't overlap, so applied from last to
+// first each one's positions are still valid.
+func Edits(old, new []string) []TextEdit {
+	// A buffer always has a line, even when empty.
+	if len(old) == 0 {
+		old = []string{""}
+	}
+	if len(new) == 0 {
+		new = []string{""}
+	}
+
+	var edits []TextEdit
+	for _, h := range diffLines(old, new) {
+		edits = append(edits, hunkEdits(old, h)...)
+	}
+	return edits
+}
+
+// hunk replaces old lines [start, end) with lines.
+type hunk struct {
+	start, end int
+	lines      []string
+}
+
+// diffLines returns the runs of lines that differ between old and new, in
+// order, from a longest common subsequence of their lines.
+func diffLines(old, new []string) []hunk {
+	prefix := 0
+	for prefix < len(old) && prefix < len(new) && old[prefix] == new[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(old)-prefix && suffix < len(new)-prefix && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	a, b := old[prefix:len(old)-suffix], new[prefix:len(new)-suffix]
+	if len(a) == 0 && len(b) == 0 {
+		return nil
+	}
+	if len(a)*len(b) > maxDiffCells || len(a) == 0 || len(b) == 0 {
+		return []hunk{{start: prefix, end: prefix + len(a), lines: b}}
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:]
+	// and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var hunks []hunk
+	i, j := 0, 0
+	si, sj := 0, 0 // start of the current run of differences
+	flush := func() {
+		if si < i || sj < j {
+			hunks = append(hunks, hunk{start: prefix + si, end: prefix + i, lines: b[sj:j]})
+		}
+	}
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			flush()
+			i++
+			j++
+			si, sj = i, j
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			i++
+		default:
+			j++
+		}
+	}
+	i, j = len(a), len(b)
+	flush()
+	return hunks
+}
+
+// hunkEdits turns a hunk into edits of old.
+func hunkEdits(old []string, h hunk) []TextEdit {
+	switch {
+	case h.end-h.start == len(h.lines):
+		// Line for line: edit each line between what it shares.
+		var edits []TextEdit
+		for k, line := range h.lines {
+			if e, ok := lineEdit(h.start+k, old[h.start+k], line); ok {
+				edits = append(edits, e)
+			}
+		}
+		return edits
+	case h.end < len(old):
+		// Whole lines, ending before the next line kept.
+		return []TextEdit{{StartRow: h.start, EndRow: h.end, Text: append(h.lines[:len(h.lines):len(h.lines)], "")}}
+	case h.start == h.end:
+		// Lines added at the end, after the last line.
+		last := len(old) - 1
+		return []TextEdit{{
+			StartRow: last, StartCol: len(old[last]),
+			EndRow: last, EndCol: len(old[last]),
+			Text: append([]string{""}, h.lines...),
+		}}
+	case len(h.lines) > 0:
+		// Lines through the last replaced.
+		return []TextEdit{{StartRow: h.start, EndRow: h.end - 1, EndCol: len(old[h.end-1]), Text: h.lines}}
+	default:
+		// Lines removed from the end, with the newline before them. The
+		// buffer keeps a line, so start is past the first.
+		return []TextEdit{{
+			StartRow: h.start - 1, StartCol: len(old[h.start-1]),
+			EndRow: h.end - 1, EndCol: len(old[h.end-1]),
+			Text: []string{""},
+		}}
+	}
+}
+
+// lineEdit returns the edit turning line row from a into b, between their
+// common prefix and suffix, or false if they are equal.
+func lineEdit(row int, a, b string) (TextEdit, bool) {
+	if a == b {
+		return TextEdit{}, false
+	}
+	p := 0
+	for p < len(a) && p < len(b) && a[p] == b[p] {
+		p++
+	}
+	// Don't split a character.
+	for p > 0 && ((p < len(a) && !utf8.RuneStart(a[p])) || (p < len(b) && !utf8.RuneStart(b[p]))) {
+		p--
+	}
+	q := 0
+	for q < len(a)-p && q < len(b)-p && a[len(a)-1-q] == b[len(b)-1-q] {
+		q++
+	}
+	for q > 0 && !utf8.RuneStart(a[len(a)-q]) {
+		q--
+	}
+	return TextEdit{StartRow: row, StartCol: p, EndRow: row, EndCol: len(a) - q, Text: []string{b[p : len(b)-q]}}, true
+}
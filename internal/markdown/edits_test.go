@@ -0,0 +1,73 @@
+package markdown
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// applyEdits applies edits from last to first the way nvim_buf_set_text
+// would.
+func applyEdits(lines []string, edits []TextEdit) []string {
+	lines = append([]string(nil), lines...)
+	for k := len(edits) - 1; k >= 0; k-- {
+		e := edits[k]
+		text := append([]string(nil), e.Text...)
+		text[0] = lines[e.StartRow][:e.StartCol] + text[0]
+		text[len(text)-1] += lines[e.EndRow][e.EndCol:]
+		lines = append(lines[:e.StartRow], append(text, lines[e.EndRow+1:]...)...)
+	}
+	return lines
+}
+
+func TestEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		old, new string
+		want     []TextEdit // nil to only check the result
+	}{
+		{name: "equal", old: "a\nb", new: "a\nb", want: []TextEdit{}},
+		{
+			name: "one line changed",
+			old:  "# Title\nsome text   \nmore",
+			new:  "# Title\nsome text\nmore",
+			want: []TextEdit{{StartRow: 1, StartCol: 9, EndRow: 1, EndCol: 12, Text: []string{""}}},
+		},
+		{
+			name: "line inserted",
+			old:  "text\n# Heading\nbody",
+			new:  "text\n\n# Heading\nbody",
+			want: []TextEdit{{StartRow: 1, EndRow: 1, Text: []string{"", ""}}},
+		},
+		{
+			name: "lines removed",
+			old:  "A\n\n\n\n\nB",
+			new:  "A\n\n\nB",
+			want: []TextEdit{{StartRow: 3, EndRow: 5, Text: []string{""}}},
+		},
+		{name: "removed at the end", old: "a\nb\n\n", new: "a\nb"},
+		{name: "added at the end", old: "a", new: "a\n\nb"},
+		{name: "replaced at the end", old: "a\nb\nc", new: "a\nx"},
+		{name: "all removed", old: "a\nb", new: ""},
+		{name: "from empty", old: "", new: "a\nb"},
+		{name: "scattered", old: "x\na  \nb\nc\n#d\ne", new: "x\na\nb\nnew\nc\n\n# d\ne"},
+		{
+			name: "multibyte",
+			old:  "café au lait",
+			new:  "cafè au lait",
+			want: []TextEdit{{StartRow: 0, StartCol: 3, EndRow: 0, EndCol: 5, Text: []string{"è"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			old, new := strings.Split(tt.old, "\n"), strings.Split(tt.new, "\n")
+			edits := Edits(old, new)
+			if got := applyEdits(old, edits); !reflect.DeepEqual(got, new) {
+				t.Errorf("applied %+v: got %q, want %q", edits, got, new)
+			}
+			if tt.want != nil && (len(tt.want) > 0 || len(edits) > 0) && !reflect.DeepEqual(edits, tt.want) {
+				t.Errorf("Edits() = %+v, want %+v", edits, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package markdown
+
+import (
+	"bytes"
+	"strings"
+)
+
+// MaxEmbedDepth is how many levels of embeds within embeds InlineEmbeds
+// follows for export.
+const MaxEmbedDepth = 5
+
+// EmbedLoader returns the content of the note an embed target (as written
+// in ![[target]]) names, and a key identifying that note, such as its
+// path. ok is false for targets that aren't notes, like images.
+type EmbedLoader func(target string) (key string, content []byte, ok bool)
+
+// InlineEmbeds returns content, the note identified by key, with each
+// ![[note]] embed replaced by the embedded note's body, and ![[note#section]]
+// by just that section. Embedded notes are inlined the same way, up to
+// depth levels deep. Embeds that load can't resolve, that name a missing
+// section, that lie deeper than depth, or that would embed a note within
+// itself are left as written.
+func InlineEmbeds(key string, content []byte, depth int, load EmbedLoader) []byte {
+	return inlineEmbeds(content, depth, load, map[string]bool{key: true})
+}
+
+// inlineEmbeds is InlineEmbeds, skipping the notes in inlining, the ones
+// being inlined on the way down to content.
+func inlineEmbeds(content []byte, depth int, load EmbedLoader, inlining map[string]bool) []byte {
+	if depth <= 0 {
+		return content
+	}
+	return wikiLinkRe.ReplaceAllFunc(content, func(m []byte) []byte {
+		if m[0] != '!' {
+			return m
+		}
+		inner := strings.TrimSuffix(strings.TrimPrefix(string(m), "![["), "]]")
+		inner, _, _ = strings.Cut(inner, "|")
+		target, section, _ := strings.Cut(inner, "#")
+		key, embedded, ok := load(strings.TrimSpace(target))
+		if !ok || inlining[key] {
+			return m
+		}
+		body := StripFrontmatter(embedded)
+		if section = strings.TrimSpace(section); section != "" {
+			if body, ok = sectionOf(body, section); !ok {
+				return m
+			}
+		}
+		inlining[key] = true
+		body = inlineEmbeds(body, depth-1, load, inlining)
+		delete(inlining, key)
+		return bytes.Trim(body, "\n")
+	})
+}
+
+// sectionOf returns the part of body under the first heading matching
+// section, from the heading up to the next heading of the same or a
+// higher level.
+func sectionOf(body []byte, section string) ([]byte, bool) {
+	headings := ExtractHeadings(body)
+	for i, h := range headings {
+		if !HeadingMatches(h.Text, section) {
+			continue
+		}
+		end := -1
+		for _, next := range headings[i+1:] {
+			if next.Level <= h.Level {
+				end = next.Line
+				break
+			}
+		}
+		lines := bytes.SplitAfter(body, []byte("\n"))
+		if end == -1 {
+			end = len(lines) + 1
+		}
+		return bytes.Join(lines[h.Line-1:end-1], nil), true
+	}
+	return nil, false
+}
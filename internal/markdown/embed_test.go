@@ -0,0 +1,48 @@
+package markdown
+
+import "testing"
+
+func TestInlineEmbeds(t *testing.T) {
+	notes := map[string]string{
+		"intro":  "---\ntitle: Intro\n---\n\nHello from intro.\n![[detail]]\n",
+		"detail": "Detail text.\n",
+		"a":      "A embeds ![[b]]",
+		"b":      "B embeds ![[a]]",
+		"deep":   "![[deep2]]",
+		"deep2":  "![[detail]]",
+		"guide":  "# Guide\n\n## Setup\n\nInstall it.\n\n### Linux\n\nUse apt.\n\n## Usage\n\nRun it.\n",
+	}
+	load := func(target string) (string, []byte, bool) {
+		content, ok := notes[target]
+		return target, []byte(content), ok
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		depth   int
+		want    string
+	}{
+		{"whole note without frontmatter", "# Doc\n\n![[intro]]\n", MaxEmbedDepth,
+			"# Doc\n\nHello from intro.\nDetail text.\n"},
+		{"section up to the next sibling heading", "![[guide#setup]]\n", MaxEmbedDepth,
+			"## Setup\n\nInstall it.\n\n### Linux\n\nUse apt.\n"},
+		{"last section", "![[guide#Usage|how]]\n", MaxEmbedDepth, "## Usage\n\nRun it.\n"},
+		{"links and unknown targets kept", "[[intro]] ![[photo.png]] ![[guide#nope]]\n", MaxEmbedDepth,
+			"[[intro]] ![[photo.png]] ![[guide#nope]]\n"},
+		{"cycle stops at the repeated note", "![[a]]", MaxEmbedDepth, "A embeds B embeds ![[a]]"},
+		{"depth limit", "![[deep]]", 2, "![[detail]]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(InlineEmbeds("doc", []byte(tt.content), tt.depth, load)); got != tt.want {
+				t.Errorf("InlineEmbeds() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// A note embedding itself is left alone.
+	if got := string(InlineEmbeds("a", []byte(notes["a"]), MaxEmbedDepth, load)); got != "A embeds B embeds ![[a]]" {
+		t.Errorf("InlineEmbeds(a) = %q", got)
+	}
+}
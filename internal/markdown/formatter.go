@@ -1,9 +1,13 @@
 package markdown
 
 import (
-	"bufio"
-	"bytes"
+	"sort"
 	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
 )
 
 // Format applies deterministic CommonMark-compatible formatting to markdown.
@@ -13,47 +17,26 @@ import (
 //   - Trim trailing whitespace
 //   - Ensure single trailing newline
 //   - Normalize blank lines (max 2 consecutive)
-//   - Preserve frontmatter as-is
+//   - Preserve frontmatter, code and HTML blocks as-is
+//
+// Formatting is idempotent: formatting the result again changes nothing.
 func Format(content []byte) []byte {
-	scanner := bufio.NewScanner(bytes.NewReader(content))
-	var lines []string
-
-	inFrontmatter := false
-	frontmatterDone := false
-	lineNum := 0
+	// Line endings become \n; a lone \r ends a line too, as in CommonMark.
+	lines := strings.Split(lineEndings.Replace(string(content)), "\n")
 
-	for scanner.Scan() {
-		lineNum++
-		line := scanner.Text()
-
-		// Handle frontmatter
-		if lineNum == 1 && strings.TrimSpace(line) == "---" {
-			inFrontmatter = true
-			lines = append(lines, line)
-			continue
-		}
-		if inFrontmatter {
-			lines = append(lines, line)
-			if strings.TrimSpace(line) == "---" {
-				inFrontmatter = false
-				frontmatterDone = true
-			}
-			continue
-		}
-
-		// Trim trailing whitespace
-		line = strings.TrimRight(line, " \t")
-
-		// Normalize headings: ensure single space after #
-		if isHeading(line) {
-			line = normalizeHeading(line)
+	kinds := lineKinds(lines)
+	for i, line := range lines {
+		switch kinds[i] {
+		case lineVerbatim:
+		case lineHeading:
+			lines[i] = normalizeHeading(strings.TrimRight(line, " \t"))
+		default:
+			lines[i] = strings.TrimRight(line, " \t")
 		}
-
-		lines = append(lines, line)
 	}
 
 	// Normalize blank lines
-	lines = normalizeBlankLines(lines, frontmatterDone)
+	lines = normalizeBlankLines(lines, kinds)
 
 	// Ensure single trailing newline
 	result := strings.Join(lines, "\n")
@@ -62,67 +45,150 @@ func Format(content []byte) []byte {
 	return []byte(result)
 }
 
+var lineEndings = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// lineKind is what Format may do to a line.
+type lineKind int
+
+const (
+	lineText     lineKind = iota
+	lineHeading           // an ATX heading
+	lineVerbatim          // frontmatter, code or raw HTML, left as written
+)
+
+// lineKinds classifies lines by parsing them as CommonMark, so a # in a
+// code block or a #tag at the start of a paragraph isn't taken for a
+// heading.
+func lineKinds(lines []string) []lineKind {
+	kinds := make([]lineKind, len(lines))
+
+	// Frontmatter runs from a leading --- to the next, or to the end.
+	body := 0
+	if strings.TrimSpace(lines[0]) == "---" {
+		body = len(lines)
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				body = i + 1
+				break
+			}
+		}
+		for i := range body {
+			kinds[i] = lineVerbatim
+		}
+	}
+
+	// The parser sees the frontmatter as blank lines, so the body keeps its
+	// line numbers.
+	src := strings.Repeat("\n", body) + strings.Join(lines[body:], "\n")
+	starts := []int{0}
+	for i := range len(src) {
+		if src[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	lineOf := func(offset int) int {
+		return sort.SearchInts(starts, offset+1) - 1
+	}
+
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	doc := md.Parser().Parse(text.NewReader([]byte(src)))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) { //nolint:errcheck // the walker returns no errors
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+			segs := n.Lines()
+			for i := range segs.Len() {
+				kinds[lineOf(segs.At(i).Start)] = lineVerbatim
+			}
+			if html, ok := n.(*ast.HTMLBlock); ok && html.HasClosure() {
+				kinds[lineOf(html.ClosureLine.Start)] = lineVerbatim
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.Heading:
+			// Setext headings and those inside quotes or list markers
+			// don't start with the #s.
+			if segs := n.Lines(); segs.Len() == 1 {
+				if line := lineOf(segs.At(0).Start); headingLevel(lines[line]) > 0 {
+					kinds[line] = lineHeading
+				}
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return kinds
+}
+
 func isHeading(line string) bool {
 	trimmed := strings.TrimLeft(line, " ")
 	return strings.HasPrefix(trimmed, "#")
 }
 
-func normalizeHeading(line string) string {
+// headingLevel returns the level of the ATX heading a line starts, or 0:
+// up to three spaces, one to six #s, then a space, a tab or the end.
+func headingLevel(line string) int {
 	trimmed := strings.TrimLeft(line, " ")
-
-	level := 0
-	for _, ch := range trimmed {
-		if ch == '#' {
-			level++
-		} else {
-			break
-		}
+	if len(line)-len(trimmed) > 3 {
+		return 0
+	}
+	level := len(trimmed) - len(strings.TrimLeft(trimmed, "#"))
+	if level == 0 || level > 6 {
+		return 0
 	}
-	if level > 6 || level == 0 {
+	if rest := trimmed[level:]; rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0
+	}
+	return level
+}
+
+// normalizeHeading leaves one space between the #s and the text and drops
+// a closing sequence of #s, keeping the line's indentation.
+func normalizeHeading(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	indent := line[:len(line)-len(trimmed)]
+	level := headingLevel(line)
+	if level == 0 {
 		return line
 	}
 
-	text := strings.TrimSpace(trimmed[level:])
-	// Remove trailing # markers
-	text = strings.TrimRight(text, "# ")
-	text = strings.TrimSpace(text)
+	text := strings.Trim(trimmed[level:], " \t")
+	// Remove trailing # markers. They only close the heading after a space,
+	// as in "# C#", and stay when the text left would end in a # that the
+	// next format would take for one.
+	if closed := strings.TrimRight(text, "#"); closed == "" {
+		text = ""
+	} else if strings.HasSuffix(closed, " ") || strings.HasSuffix(closed, "\t") {
+		if t := strings.TrimRight(closed, " \t"); !strings.HasSuffix(t, "#") {
+			text = t
+		}
+	}
 
 	if text == "" {
-		return strings.Repeat("#", level)
+		return indent + strings.Repeat("#", level)
 	}
-	return strings.Repeat("#", level) + " " + text
+	return indent + strings.Repeat("#", level) + " " + text
 }
 
-func normalizeBlankLines(lines []string, hasFrontmatter bool) []string {
+func normalizeBlankLines(lines []string, kinds []lineKind) []string {
 	var result []string
 	consecutiveBlanks := 0
-	inFrontmatter := false
 
 	for i, line := range lines {
-		// Track frontmatter
-		if i == 0 && strings.TrimSpace(line) == "---" {
-			inFrontmatter = true
-			result = append(result, line)
-			consecutiveBlanks = 0
-			continue
-		}
-		if inFrontmatter {
+		if kinds[i] == lineVerbatim {
 			result = append(result, line)
-			if strings.TrimSpace(line) == "---" {
-				inFrontmatter = false
-			}
 			consecutiveBlanks = 0
 			continue
 		}
 
-		if strings.TrimSpace(line) == "" {
+		if line == "" {
 			consecutiveBlanks++
 			if consecutiveBlanks <= 2 {
 				result = append(result, line)
 			}
 		} else {
 			// Ensure blank line before headings (unless at start or after frontmatter)
-			if isHeading(line) && len(result) > 0 {
+			if kinds[i] == lineHeading && len(result) > 0 {
 				lastLine := result[len(result)-1]
 				if strings.TrimSpace(lastLine) != "" && !strings.HasPrefix(strings.TrimSpace(lastLine), "---") {
 					result = append(result, "")
@@ -1,6 +1,16 @@
 package markdown
 
-import "testing"
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
 
 func TestFormat(t *testing.T) {
 	tests := []struct {
@@ -33,6 +43,26 @@ func TestFormat(t *testing.T) {
 			input: "---\ntitle: Test  \ntags: [a, b]\n---\n\n# Content\n",
 			want:  "---\ntitle: Test  \ntags: [a, b]\n---\n\n# Content\n",
 		},
+		{
+			name:  "code fences kept as written",
+			input: "Text\n```sh\n#!/bin/sh\n# comment  \n\n\n\necho hi\n```\n",
+			want:  "Text\n```sh\n#!/bin/sh\n# comment  \n\n\n\necho hi\n```\n",
+		},
+		{
+			name:  "tag is not a heading",
+			input: "#project notes\n",
+			want:  "#project notes\n",
+		},
+		{
+			name:  "closing sequence",
+			input: "# C#\n\n## Title ##\n\n## a # #\n",
+			want:  "# C#\n\n## Title\n\n## a # #\n",
+		},
+		{
+			name:  "heading in a list item",
+			input: "- item\n  ##   Sub\n",
+			want:  "- item\n\n  ## Sub\n",
+		},
 		{
 			name:  "ensure trailing newline",
 			input: "Hello",
@@ -49,3 +79,86 @@ func TestFormat(t *testing.T) {
 		})
 	}
 }
+
+// outline is what formatting must not change in a note: its frontmatter,
+// headings, fenced code and links.
+type outline struct {
+	Frontmatter []string
+	Headings    []string
+	Code        []string
+	Links       []string
+}
+
+func outlineOf(content []byte) outline {
+	var o outline
+	// Format ends the note with a single newline.
+	lines := strings.Split(strings.TrimRight(lineEndings.Replace(string(content)), "\n"), "\n")
+	body := 0
+	if strings.TrimSpace(lines[0]) == "---" {
+		body = len(lines)
+		for i := 1; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "---" {
+				body = i + 1
+				break
+			}
+		}
+	}
+	o.Frontmatter = lines[:body]
+
+	src := []byte(strings.Repeat("\n", body) + strings.Join(lines[body:], "\n") + "\n")
+	doc := goldmark.New(goldmark.WithExtensions(extension.GFM)).Parser().Parse(text.NewReader(src))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) { //nolint:errcheck
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading:
+			// Trailing whitespace goes from every line, a setext
+			// heading's too.
+			var text []string
+			for i := range n.Lines().Len() {
+				seg := n.Lines().At(i)
+				text = append(text, strings.TrimRight(string(seg.Value(src)), " \t\n"))
+			}
+			o.Headings = append(o.Headings, strings.Repeat("#", n.Level)+" "+strings.TrimSpace(strings.Join(text, "\n")))
+		case *ast.FencedCodeBlock:
+			var b bytes.Buffer
+			for i := range n.Lines().Len() {
+				seg := n.Lines().At(i)
+				b.Write(seg.Value(src))
+			}
+			o.Code = append(o.Code, string(n.Language(src))+":"+b.String())
+		case *ast.Link:
+			o.Links = append(o.Links, string(n.Destination))
+		case *ast.AutoLink:
+			o.Links = append(o.Links, string(n.URL(src)))
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, l := range ExtractWikiLinks([]byte(strings.Join(lines, "\n"))) {
+		o.Links = append(o.Links, "[["+l.Target+"#"+l.Section+"|"+l.Alias+"]]")
+	}
+	return o
+}
+
+func FuzzFormat(f *testing.F) {
+	f.Add("# Title\n\nSee [[other#part|there]] and [a link](https://example.com).\n")
+	f.Add("---\ntitle: Test  \ntags: [a, b]\n---\n#  Content  #\ntext   \n\n\n\n## Next\n")
+	f.Add("Text\n```go\nfunc main() {  \n\n\n\n}\n```\n# After\n")
+	f.Add("- item\n  # heading\n\n      code\n> # quoted\n")
+	f.Add("<div>\n# not a heading   \n</div>\n\n#tag\n# C#\n## a # #\n")
+	f.Add("~~~\nunclosed\n\n\n")
+	f.Add("---")
+	f.Add("-- \n0\n  -")
+	f.Add("~~~0   ")
+	f.Add("a\r\r\nb\rc")
+	f.Fuzz(func(t *testing.T, in string) {
+		once := Format([]byte(in))
+		if twice := Format(once); !bytes.Equal(twice, once) {
+			t.Fatalf("Format not idempotent:\nonce:  %q\ntwice: %q", once, twice)
+		}
+		if before, after := outlineOf([]byte(in)), outlineOf(once); !reflect.DeepEqual(before, after) {
+			t.Fatalf("Format changed the note's outline:\nin:     %q\nout:    %q\nbefore: %+v\nafter:  %+v", in, once, before, after)
+		}
+	})
+}
@@ -3,16 +3,37 @@ package markdown
 import (
 	"bufio"
 	"bytes"
+	"slices"
 	"strings"
+	"time"
 )
 
 // Frontmatter represents YAML frontmatter.
 type Frontmatter struct {
 	Title   string
 	Tags    []string
-	Status  string
+	Aliases []string
+	// Keywords are extra search terms that rank the note highly without
+	// appearing in its title.
+	Keywords []string
+	Status   string
+	Summary  string
+	// UID is the note's stable identifier, which stays the same when the
+	// note is renamed or moved.
+	UID     string
 	Raw     map[string]string
 	EndLine int // line number where frontmatter ends (0-based)
+
+	// Created is the created: date, else date:; Updated is updated:;
+	// Reviewed is reviewed:, when the note was last checked in a review.
+	// Zero when absent or not a date.
+	Created  time.Time
+	Updated  time.Time
+	Reviewed time.Time
+
+	// AutolinkIgnore lists titles/aliases the auto-linker should never
+	// convert into links in this note.
+	AutolinkIgnore []string
 }
 
 // ExtractFrontmatter parses YAML frontmatter from markdown content.
@@ -57,15 +78,28 @@ func ExtractFrontmatter(content []byte) *Frontmatter {
 			fm.Title = val
 		case "status":
 			fm.Status = val
+		case "summary":
+			fm.Summary = strings.Trim(val, `"'`)
+		case "uid":
+			fm.UID = strings.Trim(val, `"'`)
 		case "tags":
-			// Parse [tag1, tag2] or tag1, tag2
-			val = strings.Trim(val, "[]")
-			for _, tag := range strings.Split(val, ",") {
-				tag = strings.TrimSpace(tag)
-				if tag != "" {
-					fm.Tags = append(fm.Tags, tag)
-				}
+			fm.Tags = parseInlineList(val)
+		case "aliases":
+			fm.Aliases = parseInlineList(val)
+		case "keywords":
+			fm.Keywords = parseInlineList(val)
+		case "autolink_ignore":
+			fm.AutolinkIgnore = parseInlineList(val)
+		case "created":
+			fm.Created = parseDate(val)
+		case "date":
+			if _, ok := fm.Raw["created"]; !ok {
+				fm.Created = parseDate(val)
 			}
+		case "updated":
+			fm.Updated = parseDate(val)
+		case "reviewed":
+			fm.Reviewed = parseDate(val)
 		}
 	}
 
@@ -75,3 +109,188 @@ func ExtractFrontmatter(content []byte) *Frontmatter {
 
 	return fm
 }
+
+// parseInlineList parses "[a, b]" or "a, b" into its trimmed, non-empty items.
+// Surrounding quotes on items are stripped.
+func parseInlineList(val string) []string {
+	var items []string
+	val = strings.Trim(val, "[]")
+	for _, item := range strings.Split(val, ",") {
+		item = strings.Trim(strings.TrimSpace(item), `"'`)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RenameTag returns content with oldTag, matched case-insensitively,
+// replaced by newTag in its frontmatter tags: line, and whether anything
+// changed. Subtags move with it: renaming project to work turns
+// project/alpha into work/alpha. A tag that ends up listed twice is dropped
+// the second time. The list keeps its brackets and each item its quotes.
+func RenameTag(content []byte, oldTag, newTag string) ([]byte, bool) {
+	fm := ExtractFrontmatter(content)
+	if fm == nil {
+		return content, false
+	}
+	lines := strings.Split(string(content), "\n")
+	for i := 1; i < fm.EndLine-1 && i < len(lines); i++ {
+		key, val, ok := strings.Cut(strings.TrimSuffix(lines[i], "\r"), ":")
+		if !ok || strings.TrimSpace(key) != "tags" {
+			continue
+		}
+		list, ok := renameTagItems(val, oldTag, newTag)
+		if !ok {
+			return content, false
+		}
+		cr := ""
+		if strings.HasSuffix(lines[i], "\r") {
+			cr = "\r"
+		}
+		if updated := key + ": " + list + cr; updated != lines[i] {
+			lines[i] = updated
+			return []byte(strings.Join(lines, "\n")), true
+		}
+		return content, false
+	}
+	return content, false
+}
+
+// renameTagItems rewrites an inline list as parsed by parseInlineList,
+// renaming oldTag and its subtags to newTag. A renamed item that duplicates
+// another, ignoring case, is dropped; duplicates the rename didn't touch
+// are left alone.
+func renameTagItems(val, oldTag, newTag string) (string, bool) {
+	val = strings.TrimSpace(val)
+	bracketed := strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]")
+	var items []string
+	renamed := map[string]bool{} // lowercased names kept, and whether renamed
+	found := false
+	for _, raw := range strings.Split(strings.Trim(val, "[]"), ",") {
+		raw = strings.TrimSpace(raw)
+		name := strings.Trim(raw, `"'`)
+		if name == "" {
+			continue
+		}
+		moved := false
+		if len(name) >= len(oldTag) && strings.EqualFold(name[:len(oldTag)], oldTag) &&
+			(len(name) == len(oldTag) || name[len(oldTag)] == '/') {
+			quote := raw[:len(raw)-len(strings.TrimLeft(raw, `"'`))]
+			name = newTag + name[len(oldTag):]
+			raw = quote + name + quote
+			moved, found = true, true
+		}
+		key := strings.ToLower(name)
+		if was, dup := renamed[key]; dup && (was || moved) {
+			continue
+		}
+		renamed[key] = renamed[key] || moved
+		items = append(items, raw)
+	}
+	if !found {
+		return "", false
+	}
+	list := strings.Join(items, ", ")
+	if bracketed {
+		list = "[" + list + "]"
+	}
+	return list, true
+}
+
+// SetFrontmatterField returns content with its frontmatter key set to
+// value: the key's line is replaced, or added before the closing ---. An
+// empty value removes the line instead. Content without frontmatter gets a
+// block holding just the key.
+func SetFrontmatterField(content []byte, key, value string) []byte {
+	fm := ExtractFrontmatter(content)
+	if fm == nil || fm.EndLine == 0 {
+		if value == "" {
+			return content
+		}
+		return append([]byte("---\n"+key+": "+value+"\n---\n"), content...)
+	}
+	lines := strings.Split(string(content), "\n")
+	cr := ""
+	if strings.HasSuffix(lines[0], "\r") {
+		cr = "\r"
+	}
+	end := fm.EndLine - 1 // index of the closing ---
+	for i := 1; i < end && i < len(lines); i++ {
+		k, _, ok := strings.Cut(lines[i], ":")
+		if !ok || strings.TrimSpace(k) != key {
+			continue
+		}
+		if value == "" {
+			lines = slices.Delete(lines, i, i+1)
+		} else {
+			lines[i] = key + ": " + value + cr
+		}
+		return []byte(strings.Join(lines, "\n"))
+	}
+	if value == "" {
+		return content
+	}
+	lines = slices.Insert(lines, end, key+": "+value+cr)
+	return []byte(strings.Join(lines, "\n"))
+}
+
+// AddTags returns content with tags appended to its frontmatter tags: list,
+// skipping ones it already has (ignoring case), and whether it changed. A
+// note without a tags: line gets one.
+func AddTags(content []byte, tags []string) ([]byte, bool) {
+	var existing []string
+	val := ""
+	if fm := ExtractFrontmatter(content); fm != nil {
+		existing = fm.Tags
+		val = strings.TrimSpace(fm.Raw["tags"])
+	}
+	bracketed := val == "" || strings.HasPrefix(val, "[") && strings.HasSuffix(val, "]")
+	var items []string
+	for _, raw := range strings.Split(strings.Trim(val, "[]"), ",") {
+		if raw = strings.TrimSpace(raw); raw != "" {
+			items = append(items, raw)
+		}
+	}
+	added := false
+	for _, tag := range tags {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+		if tag == "" || slices.ContainsFunc(existing, func(t string) bool { return strings.EqualFold(t, tag) }) {
+			continue
+		}
+		existing = append(existing, tag)
+		items = append(items, tag)
+		added = true
+	}
+	if !added {
+		return content, false
+	}
+	list := strings.Join(items, ", ")
+	if bracketed {
+		list = "[" + list + "]"
+	}
+	return SetFrontmatterField(content, "tags", list), true
+}
+
+// dateLayouts are the date formats accepted in frontmatter, most specific
+// first. Values without a zone are local time.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// parseDate parses a frontmatter date, returning the zero time when val is
+// not one.
+func parseDate(val string) time.Time {
+	val = strings.Trim(val, `"'`)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, val, time.Local); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
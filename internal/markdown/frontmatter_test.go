@@ -1,6 +1,10 @@
 package markdown
 
-import "testing"
+import (
+	"slices"
+	"testing"
+	"time"
+)
 
 func TestExtractFrontmatter(t *testing.T) {
 	tests := []struct {
@@ -24,6 +28,40 @@ func TestExtractFrontmatter(t *testing.T) {
 				Raw:     map[string]string{"title": "My Note", "tags": "[go, test]", "status": "draft"},
 			},
 		},
+		{
+			name:  "aliases and autolink ignore",
+			input: "---\naliases: [Go Lang, \"golang\"]\nautolink_ignore: Go\n---\n",
+			want: &Frontmatter{
+				Aliases:        []string{"Go Lang", "golang"},
+				AutolinkIgnore: []string{"Go"},
+				EndLine:        4,
+			},
+		},
+		{
+			name:  "dates",
+			input: "---\ndate: 2026-01-31\nupdated: \"2026-02-03 14:30\"\n---\n",
+			want: &Frontmatter{
+				Created: time.Date(2026, 1, 31, 0, 0, 0, 0, time.Local),
+				Updated: time.Date(2026, 2, 3, 14, 30, 0, 0, time.Local),
+				EndLine: 4,
+			},
+		},
+		{
+			name:  "created wins over date",
+			input: "---\ncreated: 2026-01-01T08:00:00Z\ndate: 2026-01-31\nupdated: someday\n---\n",
+			want: &Frontmatter{
+				Created: time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC),
+				EndLine: 5,
+			},
+		},
+		{
+			name:  "uid",
+			input: "---\nuid: \"4f1c2a\"\n---\n",
+			want: &Frontmatter{
+				UID:     "4f1c2a",
+				EndLine: 3,
+			},
+		},
 		{
 			name:  "unclosed frontmatter",
 			input: "---\ntitle: Unclosed\n",
@@ -49,9 +87,86 @@ func TestExtractFrontmatter(t *testing.T) {
 			if got.Status != tt.want.Status {
 				t.Errorf("status: got %q, want %q", got.Status, tt.want.Status)
 			}
+			if got.UID != tt.want.UID {
+				t.Errorf("uid: got %q, want %q", got.UID, tt.want.UID)
+			}
 			if len(got.Tags) != len(tt.want.Tags) {
 				t.Errorf("tags: got %v, want %v", got.Tags, tt.want.Tags)
 			}
+			if !slices.Equal(got.Aliases, tt.want.Aliases) {
+				t.Errorf("aliases: got %v, want %v", got.Aliases, tt.want.Aliases)
+			}
+			if !slices.Equal(got.AutolinkIgnore, tt.want.AutolinkIgnore) {
+				t.Errorf("autolink_ignore: got %v, want %v", got.AutolinkIgnore, tt.want.AutolinkIgnore)
+			}
+			if !got.Created.Equal(tt.want.Created) || !got.Updated.Equal(tt.want.Updated) {
+				t.Errorf("dates: got %v, %v, want %v, %v", got.Created, got.Updated, tt.want.Created, tt.want.Updated)
+			}
+		})
+	}
+}
+
+func TestRenameTag(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+		changed           bool
+	}{
+		{"bracketed", "---\ntags: [go, Work]\n---\nbody work\n", "---\ntags: [go, job]\n---\nbody work\n", true},
+		{"bare list", "---\ntitle: x\ntags: work,home\n---\n", "---\ntitle: x\ntags: job, home\n---\n", true},
+		{"quoted", "---\ntags: [\"work\", 'a']\n---\n", "---\ntags: [\"job\", 'a']\n---\n", true},
+		{"merges", "---\ntags: [job, work]\n---\n", "---\ntags: [job]\n---\n", true},
+		{"merges into earlier", "---\ntags: [work, job]\n---\n", "---\ntags: [job]\n---\n", true},
+		{"subtags", "---\ntags: [work/alpha, workshop, Work]\n---\n", "---\ntags: [job/alpha, workshop, job]\n---\n", true},
+		{"crlf", "---\r\ntags: [work]\r\n---\r\n", "---\r\ntags: [job]\r\n---\r\n", true},
+		{"untagged", "---\ntags: [home]\n---\n", "---\ntags: [home]\n---\n", false},
+		{"body only", "tags: [work]\n", "tags: [work]\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := RenameTag([]byte(tt.input), "work", "job")
+			if string(got) != tt.want || changed != tt.changed {
+				t.Errorf("RenameTag = %q, %v, want %q, %v", got, changed, tt.want, tt.changed)
+			}
+		})
+	}
+}
+
+func TestSetFrontmatterField(t *testing.T) {
+	tests := []struct {
+		name, input, key, value, want string
+	}{
+		{"replace", "---\ntitle: x\nstatus: inbox\n---\nbody\n", "status", "archived", "---\ntitle: x\nstatus: archived\n---\nbody\n"},
+		{"add", "---\ntitle: x\n---\nbody\n", "status", "active", "---\ntitle: x\nstatus: active\n---\nbody\n"},
+		{"remove", "---\ntitle: x\nstatus: inbox\n---\n", "status", "", "---\ntitle: x\n---\n"},
+		{"remove missing", "---\ntitle: x\n---\n", "status", "", "---\ntitle: x\n---\n"},
+		{"no frontmatter", "body\n", "status", "active", "---\nstatus: active\n---\nbody\n"},
+		{"crlf", "---\r\ntitle: x\r\n---\r\n", "status", "active", "---\r\ntitle: x\r\nstatus: active\r\n---\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SetFrontmatterField([]byte(tt.input), tt.key, tt.value); string(got) != tt.want {
+				t.Errorf("SetFrontmatterField = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddTags(t *testing.T) {
+	tests := []struct {
+		name, input, want string
+		changed           bool
+	}{
+		{"bracketed", "---\ntags: [inbox]\n---\n", "---\ntags: [inbox, work, ideas]\n---\n", true},
+		{"bare list", "---\ntags: inbox\n---\n", "---\ntags: inbox, work, ideas\n---\n", true},
+		{"no tags line", "---\ntitle: x\n---\n", "---\ntitle: x\ntags: [work, ideas]\n---\n", true},
+		{"already tagged", "---\ntags: [Work, ideas]\n---\n", "---\ntags: [Work, ideas]\n---\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed := AddTags([]byte(tt.input), []string{"work", " #ideas"})
+			if string(got) != tt.want || changed != tt.changed {
+				t.Errorf("AddTags = %q, %v, want %q, %v", got, changed, tt.want, tt.changed)
+			}
 		})
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"strings"
+	"unicode"
 )
 
 // Heading represents a markdown heading.
@@ -72,3 +73,25 @@ func ExtractHeadings(content []byte) []Heading {
 
 	return headings
 }
+
+// HeadingMatches reports whether a heading's text is the one a link's
+// #section names. Case is ignored, as are differences in spacing and
+// punctuation, so [[note#my-heading]] finds "## My Heading".
+func HeadingMatches(text, section string) bool {
+	if strings.EqualFold(text, section) {
+		return true
+	}
+	key := headingKey(section)
+	return key != "" && headingKey(text) == key
+}
+
+// headingKey reduces heading text to its lowercased letters and digits.
+func headingKey(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -39,3 +39,22 @@ Some text.
 		}
 	}
 }
+
+func TestHeadingMatches(t *testing.T) {
+	tests := []struct {
+		text, section string
+		want          bool
+	}{
+		{"My Heading", "My Heading", true},
+		{"My Heading", "my heading", true},
+		{"My Heading", "my-heading", true},
+		{"Q&A: Notes", "qa notes", true},
+		{"My Heading", "Other", false},
+		{"My Heading", "---", false},
+	}
+	for _, tt := range tests {
+		if got := HeadingMatches(tt.text, tt.section); got != tt.want {
+			t.Errorf("HeadingMatches(%q, %q) = %v, want %v", tt.text, tt.section, got, tt.want)
+		}
+	}
+}
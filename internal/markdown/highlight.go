@@ -0,0 +1,71 @@
+package markdown
+
+import (
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/lexers"
+)
+
+// TokenKind classifies a span of highlighted code.
+type TokenKind int
+
+const (
+	TokenText TokenKind = iota
+	TokenKeyword
+	TokenString
+	TokenNumber
+	TokenComment
+)
+
+// Token is a span of code and how to color it.
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+// kindOf maps a chroma token type onto the few kinds the theme colors.
+func kindOf(t chroma.TokenType) TokenKind {
+	switch {
+	case t.InCategory(chroma.Keyword):
+		return TokenKeyword
+	case t.InSubCategory(chroma.LiteralString):
+		return TokenString
+	case t.InSubCategory(chroma.LiteralNumber):
+		return TokenNumber
+	case t.InCategory(chroma.Comment):
+		return TokenComment
+	}
+	return TokenText
+}
+
+// Highlight splits code into keyword, string, number, comment and plain
+// text tokens with chroma's lexer for the language named by a fenced code
+// block's info string. It returns nil for a language chroma doesn't know,
+// so the block renders plain.
+func Highlight(lang, code string) []Token {
+	if lang == "" {
+		return nil
+	}
+	lexer := lexers.Get(lang)
+	if lexer == nil {
+		return nil
+	}
+	it, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		// A lexer that can't handle the code leaves it plain.
+		return nil
+	}
+
+	var toks []Token
+	for t := it(); t != chroma.EOF; t = it() {
+		if t.Value == "" {
+			continue
+		}
+		kind := kindOf(t.Type)
+		if n := len(toks); n > 0 && toks[n-1].Kind == kind {
+			toks[n-1].Text += t.Value
+			continue
+		}
+		toks = append(toks, Token{Kind: kind, Text: t.Value})
+	}
+	return toks
+}
@@ -0,0 +1,56 @@
+package markdown
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	code := "func f() {\n\t/* a\n\tb */ return \"x\\\"y\", 42 // done\n}\n"
+	want := []Token{
+		{TokenKeyword, "func"},
+		{TokenText, " f() {\n\t"},
+		{TokenComment, "/* a\n\tb */"},
+		{TokenText, " "},
+		{TokenKeyword, "return"},
+		{TokenText, " "},
+		{TokenString, "\"x\\\"y\""},
+		{TokenText, ", "},
+		{TokenNumber, "42"},
+		{TokenText, " "},
+		{TokenComment, "// done"},
+		{TokenText, "\n}\n"},
+	}
+	if got := Highlight("Go", code); !slices.Equal(got, want) {
+		t.Errorf("Highlight =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestHighlightLanguages(t *testing.T) {
+	tests := []struct {
+		lang, code string
+		kind       TokenKind
+		text       string
+	}{
+		{"sql", "SELECT x1 FROM t", TokenKeyword, "SELECT"},
+		{"python", "x = '''a\nb'''", TokenString, "'''a\nb'''"},
+		{"lua", "--[[ a\nb ]] x", TokenComment, "--[[ a\nb ]]"},
+		{"sh", "echo hi # note", TokenComment, "# note"},
+		{"json", `{"a": 1.5}`, TokenNumber, "1.5"},
+	}
+	for _, tt := range tests {
+		if got := Highlight(tt.lang, tt.code); !slices.Contains(got, Token{tt.kind, tt.text}) {
+			t.Errorf("Highlight(%q, %q) = %q, want a %v token %q", tt.lang, tt.code, got, tt.kind, tt.text)
+		}
+	}
+
+	// Digits inside identifiers aren't numbers.
+	for _, tok := range Highlight("go", "x1 := y2") {
+		if tok.Kind == TokenNumber {
+			t.Errorf("number token %q inside an identifier", tok.Text)
+		}
+	}
+	if got := Highlight("nosuchlang", "+++"); got != nil {
+		t.Errorf("unknown language = %q, want nil", got)
+	}
+}
@@ -0,0 +1,156 @@
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MarkdownLink represents a standard [text](path.md) link to another note.
+type MarkdownLink struct {
+	Text    string // link text between [ and ]
+	Target  string // destination as written, unescaped, without #section
+	Section string // #section (if present)
+	Line    int    // 1-based line number
+	Col     int    // 0-based column of the opening [
+}
+
+// ExtractMarkdownLinks finds the [text](destination) links in markdown
+// content that point at notes: relative or vault-absolute paths ending in
+// .md. Images, URLs and links within the same note are skipped.
+func ExtractMarkdownLinks(content []byte) []MarkdownLink {
+	var links []MarkdownLink
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	inFrontmatter := false
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		// Skip frontmatter
+		if lineNum == 1 && strings.TrimSpace(line) == "---" {
+			inFrontmatter = true
+			continue
+		}
+		if inFrontmatter {
+			if strings.TrimSpace(line) == "---" {
+				inFrontmatter = false
+			}
+			continue
+		}
+
+		col := 0
+		for col < len(line) {
+			idx := strings.IndexByte(line[col:], '[')
+			if idx == -1 {
+				break
+			}
+			start := col + idx
+			col = start + 1
+
+			// Wiki links and images aren't markdown note links.
+			if strings.HasPrefix(line[start:], "[[") {
+				col = start + 2
+				continue
+			}
+			if start > 0 && (line[start-1] == '!' || line[start-1] == '[') {
+				continue
+			}
+
+			textEnd := strings.IndexByte(line[start+1:], ']')
+			if textEnd == -1 {
+				break
+			}
+			textEnd += start + 1
+			if textEnd+1 >= len(line) || line[textEnd+1] != '(' {
+				continue
+			}
+			dest, end, ok := linkDestination(line, textEnd+2)
+			if !ok {
+				continue
+			}
+			col = end
+
+			target, section, _ := strings.Cut(dest, "#")
+			if !isNoteDestination(target) {
+				continue
+			}
+			links = append(links, MarkdownLink{
+				Text:    line[start+1 : textEnd],
+				Target:  unescape(target),
+				Section: unescape(section),
+				Line:    lineNum,
+				Col:     start,
+			})
+		}
+	}
+
+	return links
+}
+
+// unescape decodes %-escapes in a link destination, keeping it as written
+// if they're malformed.
+func unescape(s string) string {
+	if u, err := url.PathUnescape(s); err == nil {
+		return u
+	}
+	return s
+}
+
+// linkDestination reads the destination of a link whose "(" ends just
+// before pos: either <...> or text up to whitespace, followed by an
+// optional title and ")". It returns the destination and the position
+// after the ")".
+func linkDestination(line string, pos int) (dest string, end int, ok bool) {
+	rest := line[pos:]
+	var after string
+	if strings.HasPrefix(rest, "<") {
+		closeIdx := strings.IndexByte(rest, '>')
+		if closeIdx == -1 {
+			return "", 0, false
+		}
+		dest, after = rest[1:closeIdx], rest[closeIdx+1:]
+	} else {
+		n := strings.IndexAny(rest, " \t)")
+		if n == -1 {
+			return "", 0, false
+		}
+		dest, after = rest[:n], rest[n:]
+	}
+	closeIdx := strings.IndexByte(after, ')')
+	if closeIdx == -1 {
+		return "", 0, false
+	}
+	return dest, len(line) - len(after) + closeIdx + 1, true
+}
+
+// isNoteDestination reports whether a link destination, without its
+// #section, names a note file rather than a URL or another kind of file.
+func isNoteDestination(target string) bool {
+	if target == "" || strings.Contains(target, ":") {
+		return false
+	}
+	return strings.EqualFold(path.Ext(target), ".md")
+}
+
+// ResolveMarkdownLinkTarget returns the vault-relative path a markdown
+// link's target points at from the note at sourcePath. Targets starting
+// with "/" are relative to the vault root. Returns "" for a target outside
+// the vault.
+func ResolveMarkdownLinkTarget(sourcePath, target string) string {
+	var p string
+	if rooted, ok := strings.CutPrefix(target, "/"); ok {
+		p = path.Clean(rooted)
+	} else {
+		p = path.Join(path.Dir(filepath.ToSlash(sourcePath)), target)
+	}
+	if p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return ""
+	}
+	return p
+}
@@ -0,0 +1,44 @@
+package markdown
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractMarkdownLinks(t *testing.T) {
+	input := `---
+link: "[fm](skip.md)"
+---
+See [the plan](projects/plan.md) and [[wiki]].
+- [ ] read [Notes](<my notes.md> "title") and [spaced](my%20note.md#Part%20Two)
+![image](pic.md) [web](https://example.com/a.md) [pdf](doc.pdf) [here](#intro)
+[up](../outside.md)`
+
+	got := ExtractMarkdownLinks([]byte(input))
+	want := []MarkdownLink{
+		{Text: "the plan", Target: "projects/plan.md", Line: 4, Col: 4},
+		{Text: "Notes", Target: "my notes.md", Line: 5, Col: 11},
+		{Text: "spaced", Target: "my note.md", Section: "Part Two", Line: 5, Col: 46},
+		{Text: "up", Target: "../outside.md", Line: 7, Col: 0},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("ExtractMarkdownLinks =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestResolveMarkdownLinkTarget(t *testing.T) {
+	tests := []struct {
+		source, target, want string
+	}{
+		{"notes/a.md", "b.md", "notes/b.md"},
+		{"notes/a.md", "../projects/b.md", "projects/b.md"},
+		{"notes/a.md", "/projects/b.md", "projects/b.md"},
+		{"a.md", "./sub/b.md", "sub/b.md"},
+		{"a.md", "../b.md", ""},
+	}
+	for _, tt := range tests {
+		if got := ResolveMarkdownLinkTarget(tt.source, tt.target); got != tt.want {
+			t.Errorf("ResolveMarkdownLinkTarget(%q, %q) = %q, want %q", tt.source, tt.target, got, tt.want)
+		}
+	}
+}
@@ -30,6 +30,8 @@ func (p *Parser) Parse(content []byte) *ParsedNote {
 	note.Frontmatter = ExtractFrontmatter(content)
 	note.Headings = ExtractHeadings(content)
 	note.WikiLinks = ExtractWikiLinks(content)
+	note.MarkdownLinks = ExtractMarkdownLinks(content)
+	note.Tasks = ExtractTasks(content)
 
 	_ = doc // goldmark AST available for future use
 	return note
@@ -37,10 +39,12 @@ func (p *Parser) Parse(content []byte) *ParsedNote {
 
 // ParsedNote contains extracted metadata from a markdown file.
 type ParsedNote struct {
-	Content     []byte
-	Frontmatter *Frontmatter
-	Headings    []Heading
-	WikiLinks   []WikiLink
+	Content       []byte
+	Frontmatter   *Frontmatter
+	Headings      []Heading
+	WikiLinks     []WikiLink
+	MarkdownLinks []MarkdownLink
+	Tasks         []Task
 }
 
 // PlainContent returns the note content without frontmatter.
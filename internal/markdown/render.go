@@ -0,0 +1,168 @@
+package markdown
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// CodeColors are the CSS colors highlighted code blocks are exported with.
+// Only #rrggbb colors are used; anything else, like a terminal palette
+// index, leaves that part unstyled.
+type CodeColors struct {
+	Background string
+	Text       string
+	Keyword    string
+	String     string
+	Number     string
+	Comment    string
+}
+
+func (c CodeColors) of(kind TokenKind) string {
+	switch kind {
+	case TokenKeyword:
+		return c.Keyword
+	case TokenString:
+		return c.String
+	case TokenNumber:
+		return c.Number
+	case TokenComment:
+		return c.Comment
+	}
+	return ""
+}
+
+// RenderHTML renders a note to HTML for export. Frontmatter is dropped and
+// wiki links become their display text (the alias, else the target), since
+// they mean nothing outside the vault. Fenced code in a language Highlight
+// knows is colored inline with colors.
+func RenderHTML(content []byte, colors CodeColors) ([]byte, error) {
+	body := StripFrontmatter(content)
+	body = ReplaceWikiLinks(body, func(_, display string, _ bool) string {
+		return display
+	})
+
+	var buf bytes.Buffer
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM),
+		goldmark.WithRendererOptions(renderer.WithNodeRenderers(
+			util.Prioritized(codeRenderer{colors: colors}, 100),
+		)),
+	)
+	if err := md.Convert(body, &buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// codeRenderer renders fenced code blocks with Highlight's tokens wrapped
+// in colored spans, in place of goldmark's plain rendering.
+type codeRenderer struct {
+	colors CodeColors
+}
+
+func (r codeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindFencedCodeBlock, r.renderFencedCodeBlock)
+}
+
+func (r codeRenderer) renderFencedCodeBlock(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+	if !entering {
+		if _, err := w.WriteString("</code></pre>\n"); err != nil {
+			return ast.WalkStop, err
+		}
+		return ast.WalkContinue, nil
+	}
+	n, ok := node.(*ast.FencedCodeBlock)
+	if !ok {
+		return ast.WalkStop, fmt.Errorf("render fenced code block: unexpected node %s", node.Kind())
+	}
+	var code strings.Builder
+	lines := n.Lines()
+	for i := range lines.Len() {
+		seg := lines.At(i)
+		code.Write(seg.Value(source))
+	}
+	lang := string(n.Language(source))
+	toks := Highlight(lang, code.String())
+
+	// Built up first so the writer's error is checked once.
+	var b strings.Builder
+	b.WriteString("<pre")
+	if toks != nil {
+		var style []string
+		if c := cssColor(r.colors.Background); c != "" {
+			style = append(style, "background:"+c)
+		}
+		if c := cssColor(r.colors.Text); c != "" {
+			style = append(style, "color:"+c)
+		}
+		if len(style) > 0 {
+			fmt.Fprintf(&b, ` style="%s"`, strings.Join(style, ";"))
+		}
+	}
+	b.WriteString("><code")
+	if lang != "" {
+		fmt.Fprintf(&b, ` class="language-%s"`, html.EscapeString(lang))
+	}
+	b.WriteByte('>')
+
+	if toks == nil {
+		toks = []Token{{Kind: TokenText, Text: code.String()}}
+	}
+	for _, t := range toks {
+		text := html.EscapeString(t.Text)
+		if c := cssColor(r.colors.of(t.Kind)); c != "" {
+			fmt.Fprintf(&b, `<span style="color:%s">%s</span>`, c, text)
+		} else {
+			b.WriteString(text)
+		}
+	}
+	if _, err := w.WriteString(b.String()); err != nil {
+		return ast.WalkStop, err
+	}
+	return ast.WalkContinue, nil
+}
+
+// cssColor returns c if it is a #rrggbb color, else "".
+func cssColor(c string) string {
+	if len(c) != 7 || c[0] != '#' {
+		return ""
+	}
+	for _, r := range c[1:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return ""
+		}
+	}
+	return c
+}
+
+// ReplaceWikiLinks returns content with each [[wiki link]] replaced by what
+// repl returns for it. target is the link's note, display its alias or else
+// its target, and embed reports a ![[...]] embed.
+func ReplaceWikiLinks(content []byte, repl func(target, display string, embed bool) string) []byte {
+	return wikiLinkRe.ReplaceAllFunc(content, func(m []byte) []byte {
+		embed := m[0] == '!'
+		inner := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(string(m), "!"), "[["), "]]")
+		inner, alias, hasAlias := strings.Cut(inner, "|")
+		target, _, _ := strings.Cut(inner, "#")
+		target = strings.TrimSpace(target)
+		display := target
+		if hasAlias {
+			display = strings.TrimSpace(alias)
+		}
+		return []byte(repl(target, display, embed))
+	})
+}
+
+// StripFrontmatter returns content without its leading frontmatter block.
+func StripFrontmatter(content []byte) []byte {
+	pn := ParsedNote{Content: content, Frontmatter: ExtractFrontmatter(content)}
+	return []byte(pn.PlainContent())
+}
@@ -0,0 +1,79 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderHTML(t *testing.T) {
+	content := "---\ntitle: Trip\n---\n# Trip\n\nSee [[packing list]] and [[budget#Food|the food budget]].\n\n| a | b |\n|---|---|\n| 1 | 2 |\n"
+
+	out, err := RenderHTML([]byte(content), CodeColors{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(out)
+	for _, want := range []string{
+		"<h1>Trip</h1>",
+		"See packing list and the food budget.",
+		"<table>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("RenderHTML missing %q in:\n%s", want, html)
+		}
+	}
+	if strings.Contains(html, "title:") {
+		t.Error("frontmatter should not be rendered")
+	}
+}
+
+func TestRenderHTMLHighlightsCode(t *testing.T) {
+	content := "```go\nreturn \"<a>\"\n```\n\n```\nplain & simple\n```\n"
+	colors := CodeColors{Background: "#000000", Keyword: "#ff0000", String: "#00ff00", Number: "12"}
+
+	out, err := RenderHTML([]byte(content), colors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(out)
+	for _, want := range []string{
+		`<pre style="background:#000000"><code class="language-go">`,
+		`<span style="color:#ff0000">return</span>`,
+		`<span style="color:#00ff00">&#34;&lt;a&gt;&#34;</span>`,
+		"<pre><code>plain &amp; simple\n</code></pre>",
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("RenderHTML missing %q in:\n%s", want, html)
+		}
+	}
+
+	plain, err := RenderHTML([]byte(content), CodeColors{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(plain), "style=") {
+		t.Errorf("RenderHTML without colors styled code:\n%s", plain)
+	}
+}
+
+func TestStripFrontmatter(t *testing.T) {
+	if got := string(StripFrontmatter([]byte("---\na: b\n---\nbody\n"))); got != "body\n" {
+		t.Errorf("StripFrontmatter = %q, want %q", got, "body\n")
+	}
+	if got := string(StripFrontmatter([]byte("no frontmatter\n"))); got != "no frontmatter\n" {
+		t.Errorf("StripFrontmatter without frontmatter = %q", got)
+	}
+}
+
+func TestReplaceWikiLinks(t *testing.T) {
+	content := "[[a]] [[b#Sec]] [[c|Cee]] ![[img.png]]"
+	got := ReplaceWikiLinks([]byte(content), func(target, display string, embed bool) string {
+		if embed {
+			return "<" + target + ">"
+		}
+		return target + "=" + display
+	})
+	if want := "a=a b=b c=Cee <img.png>"; string(got) != want {
+		t.Errorf("ReplaceWikiLinks = %q, want %q", got, want)
+	}
+}
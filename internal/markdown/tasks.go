@@ -0,0 +1,110 @@
+package markdown
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+)
+
+// Task represents a markdown checklist item ("- [ ] text" or "- [x] text").
+type Task struct {
+	Text    string
+	Done    bool
+	Line    int    // 1-based line number
+	Section string // text of the nearest preceding heading ("" if none)
+}
+
+// ExtractTasks finds all checklist items in markdown content.
+// Supports "-", "*" and "+" bullets as well as numbered list items.
+func ExtractTasks(content []byte) []Task {
+	var tasks []Task
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+
+	inFrontmatter := false
+	lineNum := 0
+	section := ""
+
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+
+		// Skip frontmatter
+		if lineNum == 1 && strings.TrimSpace(line) == "---" {
+			inFrontmatter = true
+			continue
+		}
+		if inFrontmatter {
+			if strings.TrimSpace(line) == "---" {
+				inFrontmatter = false
+			}
+			continue
+		}
+
+		trimmed := strings.TrimLeft(line, " \t")
+		if isHeading(trimmed) {
+			// Track the enclosing heading so callers can group tasks by section.
+			section = strings.TrimSpace(strings.TrimLeft(normalizeHeading(trimmed), "#"))
+			continue
+		}
+
+		text, done, ok := parseTaskItem(trimmed)
+		if !ok {
+			continue
+		}
+		tasks = append(tasks, Task{
+			Text:    text,
+			Done:    done,
+			Line:    lineNum,
+			Section: section,
+		})
+	}
+
+	return tasks
+}
+
+// parseTaskItem parses a single (left-trimmed) list line as a checklist item.
+func parseTaskItem(line string) (text string, done, ok bool) {
+	rest, found := cutListMarker(line)
+	if !found {
+		return "", false, false
+	}
+	if len(rest) < 3 || rest[0] != '[' || rest[2] != ']' {
+		return "", false, false
+	}
+	switch rest[1] {
+	case ' ':
+		done = false
+	case 'x', 'X':
+		done = true
+	default:
+		return "", false, false
+	}
+	rest = rest[3:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return "", false, false
+	}
+	text = strings.TrimSpace(rest)
+	if text == "" {
+		return "", false, false
+	}
+	return text, done, true
+}
+
+// cutListMarker strips a bullet ("- ", "* ", "+ ") or ordered ("1. ", "1) ")
+// list marker and returns the remainder.
+func cutListMarker(line string) (string, bool) {
+	if len(line) >= 2 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ' {
+		return strings.TrimLeft(line[2:], " "), true
+	}
+	i := 0
+	for i < len(line) && line[i] >= '0' && line[i] <= '9' {
+		i++
+	}
+	if i == 0 || i+1 >= len(line) {
+		return "", false
+	}
+	if (line[i] == '.' || line[i] == ')') && line[i+1] == ' ' {
+		return strings.TrimLeft(line[i+2:], " "), true
+	}
+	return "", false
+}
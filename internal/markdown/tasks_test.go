@@ -0,0 +1,46 @@
+package markdown
+
+import "testing"
+
+func TestExtractTasks(t *testing.T) {
+	input := `---
+title: 2024-01-02
+tags: [daily]
+---
+
+- [ ] before any heading
+
+## Habits
+
+- [x] Exercise
+- [ ] Read
+* [X] Meditate
+1. [x] Journal
+- [ ]
+- [x]no space
+- plain item
+
+## Notes
+
+  - [ ] nested task
+`
+	tasks := ExtractTasks([]byte(input))
+
+	want := []Task{
+		{Text: "before any heading", Done: false, Line: 6, Section: ""},
+		{Text: "Exercise", Done: true, Line: 10, Section: "Habits"},
+		{Text: "Read", Done: false, Line: 11, Section: "Habits"},
+		{Text: "Meditate", Done: true, Line: 12, Section: "Habits"},
+		{Text: "Journal", Done: true, Line: 13, Section: "Habits"},
+		{Text: "nested task", Done: false, Line: 20, Section: "Notes"},
+	}
+
+	if len(tasks) != len(want) {
+		t.Fatalf("got %d tasks, want %d: %+v", len(tasks), len(want), tasks)
+	}
+	for i, tt := range want {
+		if tasks[i] != tt {
+			t.Errorf("[%d] got %+v, want %+v", i, tasks[i], tt)
+		}
+	}
+}
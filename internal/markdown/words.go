@@ -0,0 +1,30 @@
+package markdown
+
+import (
+	"strings"
+	"unicode"
+)
+
+// WordCount counts the words in text: whitespace-separated runs that hold a
+// letter or digit, so list markers, heading #s and rules don't count. Each
+// Chinese or Japanese character counts as a word, since those scripts don't
+// separate words with spaces.
+func WordCount(text string) int {
+	n := 0
+	for _, field := range strings.Fields(text) {
+		word := false
+		for _, r := range field {
+			switch {
+			case unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana):
+				n++
+				word = false
+			case unicode.IsLetter(r) || unicode.IsDigit(r):
+				if !word {
+					n++
+					word = true
+				}
+			}
+		}
+	}
+	return n
+}
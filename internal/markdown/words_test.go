@@ -0,0 +1,23 @@
+package markdown
+
+import "testing"
+
+func TestWordCount(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"one two  three\nfour", 4},
+		{"# Heading\n\n- item one\n- [ ] task\n\n---\n", 4},
+		{"don't stop well-known [[wiki link]]", 5},
+		{"see https://example.com, ok?", 3},
+		{"日本語 text", 4},
+		{"- * > | ---", 0},
+	}
+	for _, tt := range tests {
+		if got := WordCount(tt.text); got != tt.want {
+			t.Errorf("WordCount(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// Package metrics collects serve-mode statistics and exposes them in the
+// Prometheus text format.
+package metrics
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Session describes a connected SSH session.
+type Session struct {
+	User   string
+	Remote string
+	Vault  string
+}
+
+// Registry holds the metrics of one kopr server. All methods are safe for
+// concurrent use, and the recording methods do nothing on a nil Registry so
+// local (non-serve) mode can pass nil.
+type Registry struct {
+	indexPath string
+
+	mu       sync.Mutex
+	sessions map[int64]Session
+	nextID   int64
+
+	searches    atomic.Int64
+	searchNanos atomic.Int64
+	rpcErrors   atomic.Int64
+}
+
+// New returns a registry that reports the size of the index database at
+// indexPath.
+func New(indexPath string) *Registry {
+	return &Registry{
+		indexPath: indexPath,
+		sessions:  make(map[int64]Session),
+	}
+}
+
+// AddSession records a connected session and returns an id for
+// RemoveSession.
+func (r *Registry) AddSession(s Session) int64 {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextID++
+	r.sessions[r.nextID] = s
+	return r.nextID
+}
+
+// RemoveSession forgets a session added with AddSession.
+func (r *Registry) RemoveSession(id int64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.sessions, id)
+}
+
+// ObserveSearch records how long a finder search took.
+func (r *Registry) ObserveSearch(d time.Duration) {
+	if r == nil {
+		return
+	}
+	r.searches.Add(1)
+	r.searchNanos.Add(int64(d))
+}
+
+// RPCError counts a failed Neovim RPC call.
+func (r *Registry) RPCError(error) {
+	if r == nil {
+		return
+	}
+	r.rpcErrors.Add(1)
+}
+
+// indexSize returns the size of the index database including its WAL.
+func (r *Registry) indexSize() int64 {
+	var size int64
+	for _, path := range []string{r.indexPath, r.indexPath + "-wal"} {
+		if info, err := os.Stat(path); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+// WritePrometheus writes all metrics in the Prometheus text exposition
+// format.
+func (r *Registry) WritePrometheus(w io.Writer) error {
+	r.mu.Lock()
+	sessions := make([]Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		sessions = append(sessions, s)
+	}
+	r.mu.Unlock()
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Or(strings.Compare(a.User, b.User), strings.Compare(a.Remote, b.Remote))
+	})
+
+	var b strings.Builder
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("kopr_sessions_active", "gauge", "Connected SSH sessions.")
+	fmt.Fprintf(&b, "kopr_sessions_active %d\n", len(sessions))
+
+	metric("kopr_session_info", "gauge", "Connected SSH sessions and the vault each one serves.")
+	for _, s := range sessions {
+		fmt.Fprintf(&b, `kopr_session_info{user="%s",remote="%s",vault="%s"} 1`+"\n",
+			escapeLabel(s.User), escapeLabel(s.Remote), escapeLabel(s.Vault))
+	}
+
+	metric("kopr_index_size_bytes", "gauge", "Size of the search index database.")
+	fmt.Fprintf(&b, "kopr_index_size_bytes %d\n", r.indexSize())
+
+	metric("kopr_search_duration_seconds", "summary", "Finder search latency.")
+	fmt.Fprintf(&b, "kopr_search_duration_seconds_sum %g\n", time.Duration(r.searchNanos.Load()).Seconds())
+	fmt.Fprintf(&b, "kopr_search_duration_seconds_count %d\n", r.searches.Load())
+
+	metric("kopr_rpc_errors_total", "counter", "Failed Neovim RPC calls.")
+	fmt.Fprintf(&b, "kopr_rpc_errors_total %d\n", r.rpcErrors.Load())
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeLabel escapes backslashes, newlines and quotes in a label value as
+// the text format requires.
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+// Handler serves the metrics at any path.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := r.WritePrometheus(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), "index.db")
+	if err := os.WriteFile(indexPath, make([]byte, 4096), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New(indexPath)
+	alice := r.AddSession(Session{User: "alice", Remote: "10.0.0.1:5000", Vault: "/srv/team"})
+	r.AddSession(Session{User: `bob"x`, Remote: "10.0.0.2:5000", Vault: "/srv/bob"})
+	r.RemoveSession(alice)
+	r.ObserveSearch(20 * time.Millisecond)
+	r.ObserveSearch(30 * time.Millisecond)
+	r.RPCError(errors.New("broken pipe"))
+
+	var b strings.Builder
+	if err := r.WritePrometheus(&b); err != nil {
+		t.Fatal(err)
+	}
+	out := b.String()
+	for _, want := range []string{
+		"kopr_sessions_active 1\n",
+		`kopr_session_info{user="bob\"x",remote="10.0.0.2:5000",vault="/srv/bob"} 1` + "\n",
+		"kopr_index_size_bytes 4096\n",
+		"kopr_search_duration_seconds_sum 0.05\n",
+		"kopr_search_duration_seconds_count 2\n",
+		"kopr_rpc_errors_total 1\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "alice") {
+		t.Error("removed session should not be reported")
+	}
+}
+
+func TestNilRegistry(t *testing.T) {
+	var r *Registry
+	r.RemoveSession(r.AddSession(Session{User: "alice"}))
+	r.ObserveSearch(time.Second)
+	r.RPCError(nil)
+}
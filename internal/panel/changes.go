@@ -0,0 +1,243 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+// ChangeLine is one changed line in a file preview.
+type ChangeLine struct {
+	Line int // 1-based
+	Old  string
+	New  string
+}
+
+// ChangeFile is a file a bulk operation would modify.
+type ChangeFile struct {
+	Path  string
+	Lines []ChangeLine
+}
+
+// ChangePreviewApplyMsg is sent when the user applies the previewed changes.
+// Included lists the paths that were not excluded.
+type ChangePreviewApplyMsg struct {
+	Included []string
+}
+
+// ChangePreviewCancelledMsg is sent when the preview is dismissed.
+type ChangePreviewCancelledMsg struct{}
+
+// ChangePreview is an overlay that lists every file and changed line a bulk
+// operation would touch (diff-style) and lets the user exclude files before
+// applying.
+type ChangePreview struct {
+	title    string
+	files    []ChangeFile
+	excluded map[string]bool
+	cursor   int // index into files
+	scroll   int // first rendered line
+	width    int
+	height   int
+	visible  bool
+	theme    *theme.Theme
+}
+
+// SetTheme sets the color theme for the change preview.
+func (c *ChangePreview) SetTheme(th *theme.Theme) { c.theme = th }
+
+func NewChangePreview() ChangePreview {
+	return ChangePreview{}
+}
+
+// Show opens the preview with every file included.
+func (c *ChangePreview) Show(title string, files []ChangeFile) {
+	c.title = title
+	c.files = files
+	c.excluded = make(map[string]bool)
+	c.cursor = 0
+	c.scroll = 0
+	c.visible = true
+}
+
+func (c *ChangePreview) Hide() {
+	c.visible = false
+}
+
+func (c ChangePreview) Visible() bool {
+	return c.visible
+}
+
+func (c *ChangePreview) SetSize(width, height int) {
+	c.width = width
+	c.height = height
+}
+
+// Included returns the paths of files that are not excluded, in order.
+func (c ChangePreview) Included() []string {
+	var paths []string
+	for _, f := range c.files {
+		if !c.excluded[f.Path] {
+			paths = append(paths, f.Path)
+		}
+	}
+	return paths
+}
+
+func (c ChangePreview) Update(msg tea.Msg) (ChangePreview, tea.Cmd) {
+	if !c.visible {
+		return c, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return c, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc", "q":
+		c.visible = false
+		return c, func() tea.Msg { return ChangePreviewCancelledMsg{} }
+	case "enter":
+		included := c.Included()
+		c.visible = false
+		return c, func() tea.Msg { return ChangePreviewApplyMsg{Included: included} }
+	case "j", "down":
+		if c.cursor < len(c.files)-1 {
+			c.cursor++
+		}
+	case "k", "up":
+		if c.cursor > 0 {
+			c.cursor--
+		}
+	case " ", "x":
+		if c.cursor < len(c.files) {
+			path := c.files[c.cursor].Path
+			c.excluded[path] = !c.excluded[path]
+		}
+	case "a":
+		// Toggle all: include everything unless everything is already included.
+		allIncluded := len(c.Included()) == len(c.files)
+		for _, f := range c.files {
+			c.excluded[f.Path] = allIncluded
+		}
+	}
+	c.ensureCursorVisible()
+	return c, nil
+}
+
+// bodyHeight is the number of diff lines that fit in the overlay.
+func (c ChangePreview) bodyHeight() int {
+	// border (2) + title + blank + blank + footer
+	return max(min(c.height*4/5, c.height-2)-6, 5)
+}
+
+// fileOffsets returns the rendered line index of each file header.
+func (c ChangePreview) fileOffsets() []int {
+	offsets := make([]int, len(c.files))
+	line := 0
+	for i, f := range c.files {
+		offsets[i] = line
+		line += 1 + 2*len(f.Lines)
+	}
+	return offsets
+}
+
+func (c *ChangePreview) ensureCursorVisible() {
+	if len(c.files) == 0 {
+		return
+	}
+	offsets := c.fileOffsets()
+	top := offsets[c.cursor]
+	bottom := top + 1 + 2*len(c.files[c.cursor].Lines)
+	h := c.bodyHeight()
+	if top < c.scroll {
+		c.scroll = top
+	}
+	if bottom > c.scroll+h {
+		// Show the whole file if it fits, otherwise pin its header to the top.
+		c.scroll = min(bottom-h, top)
+	}
+}
+
+func (c ChangePreview) View() string {
+	if !c.visible {
+		return ""
+	}
+
+	th := c.theme
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Accent)
+	dim := lipgloss.NewStyle().Foreground(th.Dim)
+	text := lipgloss.NewStyle().Foreground(th.Text)
+	selected := lipgloss.NewStyle().Foreground(th.Accent).Bold(true)
+	removed := lipgloss.NewStyle().Foreground(th.Error)
+	added := lipgloss.NewStyle().Foreground(th.Accent2)
+
+	innerWidth := max(min(c.width*4/5, c.width-4), 40) - 4
+
+	truncate := func(s string, w int) string {
+		if lipgloss.Width(s) > w {
+			r := []rune(s)
+			for len(r) > 0 && lipgloss.Width(string(r)) > w-1 {
+				r = r[:len(r)-1]
+			}
+			return string(r) + "…"
+		}
+		return s
+	}
+
+	var body []string
+	for i, f := range c.files {
+		box := "[x]"
+		if c.excluded[f.Path] {
+			box = "[ ]"
+		}
+		header := truncate(fmt.Sprintf("%s %s (%d)", box, f.Path, len(f.Lines)), innerWidth)
+		switch {
+		case i == c.cursor:
+			body = append(body, selected.Render(header))
+		case c.excluded[f.Path]:
+			body = append(body, dim.Render(header))
+		default:
+			body = append(body, text.Render(header))
+		}
+		for _, l := range f.Lines {
+			num := fmt.Sprintf("%5d ", l.Line)
+			pad := strings.Repeat(" ", len(num))
+			if c.excluded[f.Path] {
+				body = append(body, dim.Render(truncate(num+"- "+l.Old, innerWidth)))
+				body = append(body, dim.Render(truncate(pad+"+ "+l.New, innerWidth)))
+				continue
+			}
+			body = append(body, dim.Render(num)+removed.Render(truncate("- "+l.Old, innerWidth-len(num))))
+			body = append(body, pad+added.Render(truncate("+ "+l.New, innerWidth-len(num))))
+		}
+	}
+
+	h := c.bodyHeight()
+	start := min(c.scroll, max(len(body)-1, 0))
+	end := min(start+h, len(body))
+	visible := body[start:end]
+	for len(visible) < h {
+		visible = append(visible, "")
+	}
+
+	lines := []string{titleStyle.Render(c.title)}
+	lines = append(lines, dim.Render(fmt.Sprintf("%d of %d files selected", len(c.Included()), len(c.files))))
+	lines = append(lines, "")
+	lines = append(lines, visible...)
+	lines = append(lines, "")
+	lines = append(lines, dim.Render("j/k: move  space: toggle  a: all  enter: apply  esc: cancel"))
+
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(th.Accent).
+		Padding(0, 1).
+		Width(innerWidth + 2)
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
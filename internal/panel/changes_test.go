@@ -0,0 +1,81 @@
+package panel
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+func TestChangePreviewExcludeAndApply(t *testing.T) {
+	c := NewChangePreview()
+	c.Show("Rename", []ChangeFile{
+		{Path: "a.md", Lines: []ChangeLine{{Line: 1, Old: "[[old]]", New: "[[new]]"}}},
+		{Path: "b.md", Lines: []ChangeLine{{Line: 3, Old: "[x](old.md)", New: "[x](new.md)"}}},
+	})
+
+	// Exclude the second file.
+	c, _ = c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	c, _ = c.Update(tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}})
+	if got := c.Included(); len(got) != 1 || got[0] != "a.md" {
+		t.Fatalf("Included() = %v, want [a.md]", got)
+	}
+
+	c, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	if c.Visible() {
+		t.Error("preview should close on apply")
+	}
+	msg, ok := cmd().(ChangePreviewApplyMsg)
+	if !ok {
+		t.Fatalf("expected ChangePreviewApplyMsg, got %T", cmd())
+	}
+	if len(msg.Included) != 1 || msg.Included[0] != "a.md" {
+		t.Errorf("Included = %v, want [a.md]", msg.Included)
+	}
+}
+
+func TestChangePreviewToggleAll(t *testing.T) {
+	c := NewChangePreview()
+	c.Show("Rename", []ChangeFile{{Path: "a.md"}, {Path: "b.md"}})
+
+	c, _ = c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	if got := c.Included(); len(got) != 0 {
+		t.Errorf("after toggle all, Included() = %v, want none", got)
+	}
+	c, _ = c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'a'}})
+	if got := c.Included(); len(got) != 2 {
+		t.Errorf("after second toggle all, Included() = %v, want both", got)
+	}
+}
+
+func TestChangePreviewCancel(t *testing.T) {
+	c := NewChangePreview()
+	c.Show("Rename", []ChangeFile{{Path: "a.md"}})
+
+	c, cmd := c.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if c.Visible() {
+		t.Error("preview should close on esc")
+	}
+	if _, ok := cmd().(ChangePreviewCancelledMsg); !ok {
+		t.Errorf("expected ChangePreviewCancelledMsg, got %T", cmd())
+	}
+}
+
+func TestChangePreviewView(t *testing.T) {
+	th := theme.DefaultTheme()
+	c := NewChangePreview()
+	c.SetTheme(&th)
+	c.SetSize(100, 30)
+	c.Show("Rename old.md → new.md", []ChangeFile{
+		{Path: "notes/a.md", Lines: []ChangeLine{{Line: 7, Old: "see [[old]]", New: "see [[new]]"}}},
+	})
+
+	view := c.View()
+	for _, want := range []string{"notes/a.md", "- see [[old]]", "+ see [[new]]", "1 of 1 files selected"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q", want)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+// ConsoleRunMsg is sent when the user runs a query in the console.
+type ConsoleRunMsg struct {
+	Query string
+}
+
+// ConsoleOpenMsg is sent when the user opens the note on a result row.
+type ConsoleOpenMsg struct {
+	Path string
+}
+
+// ConsoleResult is a table of query results. Paths holds the note on each
+// row, or "" for rows that aren't a note.
+type ConsoleResult struct {
+	Columns   []string
+	Rows      [][]string
+	Paths     []string
+	Truncated bool
+}
+
+// maxConsoleColumn caps a column's width so one long value doesn't push
+// the rest off the overlay.
+const maxConsoleColumn = 40
+
+// QueryConsole is an overlay for one-off questions about the vault: it runs
+// a finder query or read-only SQL against the index and lists the result
+// rows, opening the note on the row picked.
+type QueryConsole struct {
+	input     textinput.Model
+	result    ConsoleResult
+	errorMsg  string
+	cursor    int
+	offset    int  // first result row shown
+	listFocus bool // keys move through the results instead of editing
+	width     int
+	height    int
+	visible   bool
+	theme     *theme.Theme
+}
+
+// SetTheme sets the color theme for the query console.
+func (c *QueryConsole) SetTheme(th *theme.Theme) { c.theme = th }
+
+func NewQueryConsole() QueryConsole {
+	ti := textinput.New()
+	ti.Prompt = "> "
+	ti.Placeholder = "status:draft backlinks:0, or SELECT ... FROM notes"
+	ti.CharLimit = 2000
+	return QueryConsole{input: ti}
+}
+
+// Show opens the console with the last query kept for editing.
+func (c *QueryConsole) Show() {
+	c.visible = true
+	c.listFocus = false
+	c.input.Focus()
+}
+
+func (c *QueryConsole) Hide() {
+	c.visible = false
+	c.input.Blur()
+}
+
+func (c QueryConsole) Visible() bool {
+	return c.visible
+}
+
+func (c *QueryConsole) SetSize(width, height int) {
+	c.width = width
+	c.height = height
+	c.input.Width = max(c.innerWidth()-4, 10)
+}
+
+// SetResult shows the rows of the last query and moves focus to them.
+func (c *QueryConsole) SetResult(r ConsoleResult) {
+	c.result = r
+	c.errorMsg = ""
+	c.cursor = 0
+	c.offset = 0
+	c.listFocus = len(r.Rows) > 0
+	if c.listFocus {
+		c.input.Blur()
+	}
+}
+
+// SetError shows why the last query failed, keeping the previous results.
+func (c *QueryConsole) SetError(msg string) {
+	c.errorMsg = strings.TrimSpace(msg)
+	c.listFocus = false
+	c.input.Focus()
+}
+
+func (c QueryConsole) Update(msg tea.Msg) (QueryConsole, tea.Cmd) {
+	if !c.visible {
+		return c, nil
+	}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		var cmd tea.Cmd
+		c.input, cmd = c.input.Update(msg)
+		return c, cmd
+	}
+	if keyMsg.String() == "esc" {
+		c.Hide()
+		return c, nil
+	}
+	if c.listFocus {
+		return c.updateList(keyMsg)
+	}
+
+	switch keyMsg.String() {
+	case "enter":
+		query := strings.TrimSpace(c.input.Value())
+		if query == "" {
+			return c, nil
+		}
+		return c, func() tea.Msg { return ConsoleRunMsg{Query: query} }
+	case "tab", "down", "ctrl+n":
+		if len(c.result.Rows) > 0 {
+			c.listFocus = true
+			c.input.Blur()
+		}
+		return c, nil
+	}
+	c.errorMsg = ""
+	var cmd tea.Cmd
+	c.input, cmd = c.input.Update(msg)
+	return c, cmd
+}
+
+func (c QueryConsole) updateList(msg tea.KeyMsg) (QueryConsole, tea.Cmd) {
+	switch msg.String() {
+	case "j", "down", "ctrl+n":
+		c.move(1)
+	case "k", "up", "ctrl+p":
+		c.move(-1)
+	case "ctrl+d":
+		c.move(c.listHeight() / 2)
+	case "ctrl+u":
+		c.move(-c.listHeight() / 2)
+	case "g":
+		c.move(-len(c.result.Rows))
+	case "G":
+		c.move(len(c.result.Rows))
+	case "tab", "i", "/":
+		c.listFocus = false
+		c.input.Focus()
+	case "q":
+		c.Hide()
+	case "enter":
+		if c.cursor < len(c.result.Paths) && c.result.Paths[c.cursor] != "" {
+			path := c.result.Paths[c.cursor]
+			c.Hide()
+			return c, func() tea.Msg { return ConsoleOpenMsg{Path: path} }
+		}
+	}
+	return c, nil
+}
+
+// move moves the cursor delta rows, scrolling to keep it in view.
+func (c *QueryConsole) move(delta int) {
+	c.cursor = max(0, min(c.cursor+delta, len(c.result.Rows)-1))
+	rows := c.listHeight()
+	if c.cursor < c.offset {
+		c.offset = c.cursor
+	} else if c.cursor >= c.offset+rows {
+		c.offset = c.cursor - rows + 1
+	}
+}
+
+// innerWidth is the width of the overlay's content.
+func (c QueryConsole) innerWidth() int {
+	return min(max(c.width*4/5, 50), c.width-2) - 4
+}
+
+// listHeight is the number of result rows that fit in the overlay.
+func (c QueryConsole) listHeight() int {
+	// border (2) + title + input + blank + header + blank + footer, and the
+	// error line when shown
+	h := c.height*4/5 - 8
+	if c.errorMsg != "" {
+		h--
+	}
+	return max(h, 3)
+}
+
+// columnWidths fits each column to its widest value, capped at
+// maxConsoleColumn.
+func (c QueryConsole) columnWidths() []int {
+	widths := make([]int, len(c.result.Columns))
+	for i, col := range c.result.Columns {
+		widths[i] = min(ansi.StringWidth(col), maxConsoleColumn)
+	}
+	for _, row := range c.result.Rows {
+		for i, v := range row {
+			if i < len(widths) {
+				widths[i] = max(widths[i], min(ansi.StringWidth(firstLine(v)), maxConsoleColumn))
+			}
+		}
+	}
+	return widths
+}
+
+// formatRow lays out values in columns of widths, cut to the overlay width.
+func formatRow(values []string, widths []int, width int) string {
+	var b strings.Builder
+	for i, v := range values {
+		if i >= len(widths) {
+			break
+		}
+		if i > 0 {
+			b.WriteString("  ")
+		}
+		v = ansi.Truncate(firstLine(v), widths[i], "…")
+		b.WriteString(v)
+		if i < len(values)-1 {
+			b.WriteString(strings.Repeat(" ", widths[i]-ansi.StringWidth(v)))
+		}
+	}
+	return ansi.Truncate(b.String(), width, "…")
+}
+
+// firstLine returns s up to its first newline.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
+
+func (c QueryConsole) View() string {
+	if !c.visible {
+		return ""
+	}
+
+	th := c.theme
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Accent)
+	dim := lipgloss.NewStyle().Foreground(th.Dim)
+	header := lipgloss.NewStyle().Bold(true).Foreground(th.Dim)
+	text := lipgloss.NewStyle().Foreground(th.Text)
+	cursor := lipgloss.NewStyle().Foreground(th.Accent2).Bold(true)
+	width := c.innerWidth()
+
+	lines := []string{titleStyle.Render("Query console"), c.input.View()}
+	if c.errorMsg != "" {
+		errStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Error)
+		lines = append(lines, errStyle.Render(ansi.Truncate(c.errorMsg, width, "…")))
+	}
+	lines = append(lines, "")
+
+	rows := c.listHeight()
+	if len(c.result.Columns) > 0 {
+		widths := c.columnWidths()
+		lines = append(lines, header.Render("  "+formatRow(c.result.Columns, widths, width-2)))
+		end := min(c.offset+rows, len(c.result.Rows))
+		for i := c.offset; i < end; i++ {
+			line := formatRow(c.result.Rows[i], widths, width-2)
+			if c.listFocus && i == c.cursor {
+				lines = append(lines, cursor.Render("> "+line))
+			} else {
+				lines = append(lines, text.Render("  "+line))
+			}
+		}
+		for i := end - c.offset; i < rows; i++ {
+			lines = append(lines, "")
+		}
+	} else {
+		lines = append(lines, dim.Render(ansi.Truncate("Finder operators (tag:, status:, backlinks:0, ...) or read-only SQL", width, "…")))
+		for range rows {
+			lines = append(lines, "")
+		}
+	}
+
+	lines = append(lines, "")
+	var count string
+	switch n := len(c.result.Rows); {
+	case c.result.Truncated:
+		count = fmt.Sprintf("first %d rows", n)
+	case n == 1:
+		count = "1 row"
+	case len(c.result.Columns) > 0:
+		count = fmt.Sprintf("%d rows", n)
+	}
+	help := "enter: run  tab: results  esc: close"
+	if c.listFocus {
+		help = "enter: open note  j/k: move  tab: edit query  esc: close"
+	}
+	if count != "" {
+		help = count + "  " + help
+	}
+	lines = append(lines, dim.Render(ansi.Truncate(help, width, "…")))
+
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(th.Accent).
+		Padding(0, 1).
+		Width(width + 2)
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,63 @@
+package panel
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+func TestQueryConsole(t *testing.T) {
+	th := theme.DefaultTheme()
+	c := NewQueryConsole()
+	c.SetTheme(&th)
+	c.SetSize(100, 30)
+	c.Show()
+
+	for _, r := range "status:draft" {
+		c, _ = c.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}})
+	}
+	c, cmd := c.Update(key("enter"))
+	if msg, ok := cmd().(ConsoleRunMsg); !ok || msg.Query != "status:draft" {
+		t.Fatalf("enter sent %#v, want a run of status:draft", cmd())
+	}
+
+	c.SetResult(ConsoleResult{
+		Columns: []string{"path", "title"},
+		Rows:    [][]string{{"a.md", "Alpha"}, {"b.md", "Beta"}, {"", "not a note"}},
+		Paths:   []string{"a.md", "b.md", ""},
+	})
+	view := c.View()
+	for _, want := range []string{"path", "Alpha", "b.md", "3 rows"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q:\n%s", want, view)
+		}
+	}
+
+	// Results take focus, so j moves instead of typing.
+	c, _ = c.Update(key("j"))
+	c, _ = c.Update(key("j"))
+	if c, cmd = c.Update(key("enter")); cmd != nil {
+		t.Errorf("enter on a row without a note sent %#v", cmd())
+	}
+	c, _ = c.Update(key("k"))
+	c, cmd = c.Update(key("enter"))
+	if msg, ok := cmd().(ConsoleOpenMsg); !ok || msg.Path != "b.md" {
+		t.Fatalf("enter sent %#v, want b.md opened", cmd())
+	}
+	if c.Visible() {
+		t.Error("opening a note should close the console")
+	}
+
+	// Reopening keeps the query, and an error hands focus back to it.
+	c.Show()
+	c.SetError("no such column: nope")
+	if !strings.Contains(c.View(), "no such column") {
+		t.Errorf("view should show the error:\n%s", c.View())
+	}
+	if c.input.Value() != "status:draft" {
+		t.Errorf("query = %q, want status:draft kept", c.input.Value())
+	}
+}
@@ -76,6 +76,13 @@ func (c *ContextMenu) Show(x, y int, items []ContextMenuItem) {
 	c.height = len(items) + 2 // 2 border
 }
 
+// Center moves a shown menu to the middle of a screen of the given size, for
+// menus opened from the keyboard rather than at the mouse.
+func (c *ContextMenu) Center(screenW, screenH int) {
+	c.x = max((screenW-c.width)/2, 0)
+	c.y = max((screenH-c.height)/2, 0)
+}
+
 // Hide dismisses the context menu.
 func (c *ContextMenu) Hide() {
 	c.visible = false
@@ -2,7 +2,11 @@ package panel
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -17,6 +21,21 @@ type FinderItem struct {
 	Path  string
 	Extra string // e.g., heading text, tag
 	Line  int    // line number (0 = no line jump)
+	Query string // saved search: selecting runs this query instead of opening Path
+	Depth int    // nesting level in a tree, such as nested tags; indents the title
+
+	// ModTime is the note's modification time (its frontmatter updated date
+	// when it has one), used by FinderSortModified. Zero for items that are
+	// not notes.
+	ModTime time.Time
+	// Created is the note's frontmatter created date, used by
+	// FinderSortCreated. Zero when the note has none.
+	Created time.Time
+
+	// Rune offsets of the query matches in Title and Extra, highlighted in
+	// the results list.
+	TitleMatches []int
+	ExtraMatches []int
 }
 
 // FinderResultMsg is sent when a finder item is selected.
@@ -25,6 +44,48 @@ type FinderResultMsg struct {
 	Line int
 }
 
+// SavedSearchSelectedMsg is sent when a saved search is picked in the finder
+// or the info panel. The app runs Query through the note finder.
+type SavedSearchSelectedMsg struct {
+	Name  string
+	Query string
+}
+
+// FinderMultiResultMsg is sent when Enter is pressed with marked items.
+// Paths are unique, in the order they were marked.
+type FinderMultiResultMsg struct {
+	Paths []string
+}
+
+// FinderBatchOp is a bulk action requested on the marked finder items.
+type FinderBatchOp int
+
+const (
+	FinderBatchDelete FinderBatchOp = iota
+	FinderBatchMove
+)
+
+// FinderBatchMsg is sent when the user requests a bulk action on the marked
+// items (or the highlighted one when nothing is marked).
+type FinderBatchMsg struct {
+	Op    FinderBatchOp
+	Paths []string
+}
+
+// FinderActionMsg is sent when the user asks for the action menu on the
+// highlighted result (Ctrl+A). The finder closes; the app shows the menu.
+type FinderActionMsg struct {
+	Item FinderItem
+}
+
+// FinderQuickfixMsg is sent when the user sends the results to the search
+// results list (Ctrl+Q): the marked items, or every result when nothing is
+// marked. The finder closes.
+type FinderQuickfixMsg struct {
+	Query string
+	Items []FinderItem
+}
+
 // FinderCreateRequestMsg is sent when the user requests to create a new note
 // from the current finder query (typically when there are no results).
 //
@@ -37,9 +98,56 @@ type FinderCreateRequestMsg struct {
 // FinderClosedMsg is sent when the finder is dismissed.
 type FinderClosedMsg struct{}
 
+// FinderPreviewMsg carries note content loaded in the background for the
+// preview pane. Seq ties the result to the request so stale loads are dropped.
+type FinderPreviewMsg struct {
+	Seq     int
+	Content string
+}
+
+// FinderSort is the order of the finder results.
+type FinderSort int
+
+const (
+	FinderSortRelevance FinderSort = iota // as returned by the search func
+	FinderSortModified                    // most recently modified first
+	FinderSortCreated                     // most recently created first, undated last
+	FinderSortTitle
+	FinderSortPath
+)
+
+var finderSortNames = []string{"relevance", "modified", "created", "title", "path"}
+
+func (s FinderSort) String() string {
+	if s < 0 || int(s) >= len(finderSortNames) {
+		return finderSortNames[0]
+	}
+	return finderSortNames[s]
+}
+
+// ParseFinderSort returns the sort mode named name, or FinderSortRelevance
+// for unknown names.
+func ParseFinderSort(name string) FinderSort {
+	if i := slices.Index(finderSortNames, name); i >= 0 {
+		return FinderSort(i)
+	}
+	return FinderSortRelevance
+}
+
 // SearchFunc is called to get results for a query.
 type SearchFunc func(query string) []FinderItem
 
+// PagedSearchFunc returns at most limit results for a query. The finder asks
+// for the next page, with a larger limit, when the cursor reaches the end.
+type PagedSearchFunc func(query string, limit int) []FinderItem
+
+// finderPageSize is the number of results a paged search loads at a time.
+const finderPageSize = 50
+
+// finderQuickfixLimit caps the results a paged search sends to the search
+// results list.
+const finderQuickfixLimit = 1000
+
 // PreviewFunc returns the content of a note for preview.
 type PreviewFunc func(path string) string
 
@@ -52,16 +160,27 @@ type Finder struct {
 	height        int
 	visible       bool
 	searchFn      SearchFunc
+	pagedFn       PagedSearchFunc // set instead of searchFn's results when paging
+	limit         int             // results requested from pagedFn
+	hasMore       bool            // pagedFn filled the last page; more may follow
 	previewFn     PreviewFunc
 	preview       string
 	previewScroll int
-	theme         *theme.Theme
+	previewSeq    int
+	previewFocus  bool         // j/k scroll the preview instead of typing
+	marked        []FinderItem // Tab-marked items, in marking order
+	history       []string     // accepted queries, oldest first
+	historyIdx    int          // index into history while browsing, else len(history)
+	historyDraft  string       // query typed before browsing started
+	styles        *theme.StyleSet
 	title         string
 	canCreate     bool
+	groupByFolder bool       // results are clustered under folder headers
+	sortMode      FinderSort // cycled with Ctrl+S
 }
 
-// SetTheme sets the color theme for the finder panel.
-func (f *Finder) SetTheme(th *theme.Theme) { f.theme = th }
+// SetStyles sets the styles the finder panel renders with.
+func (f *Finder) SetStyles(st *theme.StyleSet) { f.styles = st }
 
 func NewFinder() Finder {
 	ti := textinput.New()
@@ -77,14 +196,95 @@ func NewFinder() Finder {
 	}
 }
 
+// SetGroupByFolder clusters results under a header per folder instead of
+// showing the folder after each title.
+func (f *Finder) SetGroupByFolder(group bool) {
+	f.groupByFolder = group
+}
+
+// SetSort sets the result order, e.g. the one restored from the session.
+func (f *Finder) SetSort(mode FinderSort) {
+	f.sortMode = mode
+}
+
+// Sort returns the current result order.
+func (f Finder) Sort() FinderSort {
+	return f.sortMode
+}
+
 func (f *Finder) SetSearchFunc(fn SearchFunc) {
 	f.searchFn = fn
+	f.pagedFn = nil
+}
+
+// SetPagedSearchFunc makes the finder load results a page at a time, for
+// searches that can match thousands of notes.
+func (f *Finder) SetPagedSearchFunc(fn PagedSearchFunc) {
+	f.searchFn = func(query string) []FinderItem { return fn(query, finderPageSize) }
+	f.pagedFn = fn
 }
 
 func (f *Finder) SetPreviewFunc(fn PreviewFunc) {
 	f.previewFn = fn
 }
 
+// SetHistory replaces the query history (oldest first), e.g. from disk.
+func (f *Finder) SetHistory(history []string) {
+	f.history = append([]string(nil), history...)
+	f.historyIdx = len(f.history)
+}
+
+// History returns the query history, oldest first.
+func (f Finder) History() []string {
+	return f.history
+}
+
+// recordQuery adds the current query to the history, moving a repeated query
+// to the end instead of storing it twice.
+func (f *Finder) recordQuery() {
+	q := strings.TrimSpace(f.input.Value())
+	if q == "" {
+		return
+	}
+	f.history = slices.DeleteFunc(f.history, func(h string) bool { return h == q })
+	f.history = append(f.history, q)
+	f.historyIdx = len(f.history)
+}
+
+// browsingHistory reports whether the input shows an unedited history entry.
+func (f Finder) browsingHistory() bool {
+	return f.historyIdx < len(f.history) && f.input.Value() == f.history[f.historyIdx]
+}
+
+// stepHistory moves through the history (-1 older, +1 newer) and re-runs the
+// search. Stepping past the newest entry restores the query typed before
+// browsing started.
+func (f *Finder) stepHistory(delta int) tea.Cmd {
+	if len(f.history) == 0 {
+		return nil
+	}
+	if !f.browsingHistory() {
+		f.historyDraft = f.input.Value()
+		f.historyIdx = len(f.history)
+	}
+	next := f.historyIdx + delta
+	if next < 0 || next > len(f.history) {
+		return nil
+	}
+	f.historyIdx = next
+	if next == len(f.history) {
+		f.input.SetValue(f.historyDraft)
+	} else {
+		f.input.SetValue(f.history[next])
+	}
+	f.input.CursorEnd()
+	if f.searchFn != nil {
+		f.search(f.input.Value())
+	}
+	f.cursor = 0
+	return f.requestPreview()
+}
+
 func (f *Finder) SetTitle(title string) {
 	f.title = title
 }
@@ -93,16 +293,155 @@ func (f *Finder) SetCanCreate(canCreate bool) {
 	f.canCreate = canCreate
 }
 
-func (f *Finder) Show() {
+// Show opens the finder with an empty query. The returned command loads the
+// preview for the first result.
+func (f *Finder) Show() tea.Cmd {
 	f.visible = true
 	f.input.SetValue("")
 	f.cursor = 0
 	f.previewScroll = 0
+	f.previewFocus = false
+	f.marked = nil
+	f.historyIdx = len(f.history)
 	f.input.Focus()
 	if f.searchFn != nil {
-		f.items = f.searchFn("")
+		f.search("")
+	}
+	return f.requestPreview()
+}
+
+// ShowQuery opens the finder with query already typed, e.g. to run a saved
+// search.
+func (f *Finder) ShowQuery(query string) tea.Cmd {
+	cmd := f.Show()
+	if query == "" {
+		return cmd
+	}
+	f.input.SetValue(query)
+	f.input.CursorEnd()
+	if f.searchFn != nil {
+		f.search(query)
+	}
+	return f.requestPreview()
+}
+
+// search replaces the results with the first page of those for query.
+func (f *Finder) search(query string) {
+	f.limit = finderPageSize
+	f.fetch(query)
+}
+
+// fetch runs the search func for query, up to f.limit results when paging.
+func (f *Finder) fetch(query string) {
+	if f.pagedFn != nil {
+		f.items = f.pagedFn(query, f.limit)
+		f.hasMore = len(f.items) >= f.limit
+	} else {
+		f.items = f.searchFn(query)
+		f.hasMore = false
+	}
+	f.items = sortItems(f.items, f.sortMode)
+	if f.groupByFolder {
+		f.items = groupByFolder(f.items)
 	}
-	f.updatePreview()
+}
+
+// loadMore fetches the next page of results, keeping the cursor on the same
+// item. It reports whether any new results arrived.
+func (f *Finder) loadMore() bool {
+	if !f.hasMore {
+		return false
+	}
+	var current FinderItem
+	if f.cursor < len(f.items) {
+		current = f.items[f.cursor]
+	}
+	n := len(f.items)
+	f.limit += finderPageSize
+	f.fetch(f.input.Value())
+	for i, item := range f.items {
+		if item.Path == current.Path && item.Line == current.Line {
+			f.cursor = i
+			break
+		}
+	}
+	return len(f.items) > n
+}
+
+// sortItems returns items ordered for mode, leaving the search func's slice
+// untouched. Ties keep the search order.
+func sortItems(items []FinderItem, mode FinderSort) []FinderItem {
+	if mode == FinderSortRelevance {
+		return items
+	}
+	items = slices.Clone(items)
+	switch mode {
+	case FinderSortModified:
+		slices.SortStableFunc(items, func(a, b FinderItem) int {
+			return b.ModTime.Compare(a.ModTime)
+		})
+	case FinderSortCreated:
+		slices.SortStableFunc(items, func(a, b FinderItem) int {
+			if a.Created.IsZero() != b.Created.IsZero() {
+				if a.Created.IsZero() {
+					return 1
+				}
+				return -1
+			}
+			return b.Created.Compare(a.Created)
+		})
+	case FinderSortTitle:
+		slices.SortStableFunc(items, func(a, b FinderItem) int {
+			return strings.Compare(strings.ToLower(itemTitle(a)), strings.ToLower(itemTitle(b)))
+		})
+	case FinderSortPath:
+		slices.SortStableFunc(items, func(a, b FinderItem) int {
+			return strings.Compare(a.Path, b.Path)
+		})
+	}
+	return items
+}
+
+// itemTitle returns the title shown for item, which falls back to its path.
+func itemTitle(item FinderItem) string {
+	if item.Title == "" {
+		return item.Path
+	}
+	return item.Title
+}
+
+// groupByFolder reorders items so each folder's results are adjacent. Folders
+// appear in the order of their best result, and results keep their relative
+// order within a folder.
+func groupByFolder(items []FinderItem) []FinderItem {
+	var order []string
+	groups := map[string][]FinderItem{}
+	for _, item := range items {
+		folder := itemFolder(item)
+		if _, ok := groups[folder]; !ok {
+			order = append(order, folder)
+		}
+		groups[folder] = append(groups[folder], item)
+	}
+	grouped := make([]FinderItem, 0, len(items))
+	for _, folder := range order {
+		grouped = append(grouped, groups[folder]...)
+	}
+	return grouped
+}
+
+// itemFolder returns the vault folder of a note result with a trailing slash,
+// or "" for notes at the vault root and items that are not vault notes
+// (saved searches, templates addressed by absolute path).
+func itemFolder(item FinderItem) string {
+	if item.Query != "" || item.Path == "" || filepath.IsAbs(item.Path) {
+		return ""
+	}
+	dir := path.Dir(filepath.ToSlash(item.Path))
+	if dir == "." {
+		return ""
+	}
+	return dir + "/"
 }
 
 func (f *Finder) Hide() {
@@ -114,15 +453,29 @@ func (f Finder) Visible() bool {
 	return f.visible
 }
 
-func (f *Finder) updatePreview() {
-	if f.previewFn != nil && f.cursor < len(f.items) {
-		f.preview = f.previewFn(f.items[f.cursor].Path)
-	} else {
+// requestPreview returns a command that reads the highlighted note off the UI
+// goroutine so typing stays responsive. The current preview stays on screen
+// until the new content arrives to avoid flicker.
+func (f *Finder) requestPreview() tea.Cmd {
+	f.previewSeq++
+	if f.previewFn == nil || f.cursor >= len(f.items) {
 		f.preview = ""
+		f.previewScroll = 0
+		f.previewFocus = false
+		return nil
 	}
+	seq, path, fn := f.previewSeq, f.items[f.cursor].Path, f.previewFn
+	return func() tea.Msg {
+		return FinderPreviewMsg{Seq: seq, Content: fn(path)}
+	}
+}
+
+// applyPreview installs loaded preview content, auto-scrolling to center the
+// matched line when the result has one.
+func (f *Finder) applyPreview(content string) {
+	f.preview = content
 	f.previewScroll = 0
 
-	// Auto-scroll to center the matched line in the preview pane.
 	if f.cursor < len(f.items) && f.items[f.cursor].Line > 0 && f.preview != "" {
 		target := f.items[f.cursor].Line - 1 // 0-indexed
 		ph := f.previewHeight()
@@ -149,16 +502,39 @@ func (f Finder) Update(msg tea.Msg) (Finder, tea.Cmd) {
 	}
 
 	switch msg := msg.(type) {
+	case FinderPreviewMsg:
+		if msg.Seq == f.previewSeq {
+			f.applyPreview(msg.Content)
+		}
+		return f, nil
+
 	case tea.KeyMsg:
+		if f.previewFocus {
+			return f.updatePreviewFocus(msg)
+		}
+
 		switch msg.String() {
 		case "esc":
 			f.visible = false
 			return f, func() tea.Msg { return FinderClosedMsg{} }
 
 		case "enter":
+			f.recordQuery()
+			if len(f.marked) > 0 {
+				paths := f.markedPaths()
+				f.visible = false
+				return f, func() tea.Msg {
+					return FinderMultiResultMsg{Paths: paths}
+				}
+			}
 			if f.cursor < len(f.items) {
 				item := f.items[f.cursor]
 				f.visible = false
+				if item.Query != "" {
+					return f, func() tea.Msg {
+						return SavedSearchSelectedMsg{Name: item.Title, Query: item.Query}
+					}
+				}
 				return f, func() tea.Msg {
 					return FinderResultMsg{Path: item.Path, Line: item.Line}
 				}
@@ -174,17 +550,98 @@ func (f Finder) Update(msg tea.Msg) (Finder, tea.Cmd) {
 			}
 			return f, nil
 
-		case "up", "ctrl+p", "ctrl+k":
+		case "tab", "shift+tab":
+			if f.cursor >= len(f.items) {
+				return f, nil
+			}
+			f.toggleMark(f.items[f.cursor])
+			if msg.String() == "tab" && f.cursor < len(f.items)-1 {
+				f.cursor++
+				return f, f.requestPreview()
+			}
+			if msg.String() == "shift+tab" && f.cursor > 0 {
+				f.cursor--
+				return f, f.requestPreview()
+			}
+			return f, nil
+
+		case "ctrl+a":
+			if f.cursor >= len(f.items) || f.items[f.cursor].Query != "" {
+				return f, nil // saved searches are not notes
+			}
+			item := f.items[f.cursor]
+			f.visible = false
+			return f, func() tea.Msg { return FinderActionMsg{Item: item} }
+
+		case "ctrl+q":
+			items := f.quickfixItems()
+			if len(items) == 0 {
+				return f, nil
+			}
+			f.recordQuery()
+			query := strings.TrimSpace(f.input.Value())
+			f.visible = false
+			return f, func() tea.Msg { return FinderQuickfixMsg{Query: query, Items: items} }
+
+		case "ctrl+s":
+			f.sortMode = (f.sortMode + 1) % FinderSort(len(finderSortNames))
+			if f.searchFn != nil {
+				f.search(f.input.Value())
+			}
+			f.cursor = 0
+			return f, f.requestPreview()
+
+		case "ctrl+l":
+			if f.preview != "" {
+				f.previewFocus = true
+				f.input.Blur()
+			}
+			return f, nil
+
+		case "ctrl+x", "alt+d":
+			op := FinderBatchMove
+			if msg.String() == "alt+d" {
+				op = FinderBatchDelete
+			}
+			paths := f.markedPaths()
+			if len(paths) == 0 && f.cursor < len(f.items) {
+				paths = []string{f.items[f.cursor].Path}
+			}
+			if len(paths) == 0 {
+				return f, nil
+			}
+			f.visible = false
+			return f, func() tea.Msg {
+				return FinderBatchMsg{Op: op, Paths: paths}
+			}
+
+		case "ctrl+p":
+			return f, f.stepHistory(-1)
+
+		case "ctrl+n":
+			return f, f.stepHistory(1)
+
+		case "up", "ctrl+k":
+			// Up at the top of an empty query starts browsing history.
+			if msg.String() == "up" && ((f.input.Value() == "" && f.cursor == 0) || f.browsingHistory()) {
+				return f, f.stepHistory(-1)
+			}
 			if f.cursor > 0 {
 				f.cursor--
-				f.updatePreview()
+				return f, f.requestPreview()
 			}
 			return f, nil
 
-		case "down", "ctrl+n", "ctrl+j":
+		case "down", "ctrl+j":
+			if msg.String() == "down" && f.browsingHistory() {
+				return f, f.stepHistory(1)
+			}
+			if f.cursor == len(f.items)-1 {
+				f.loadMore()
+			}
 			if f.cursor < len(f.items)-1 {
 				f.cursor++
-				f.updatePreview()
+				return f, f.requestPreview()
 			}
 			return f, nil
 
@@ -214,14 +671,107 @@ func (f Finder) Update(msg tea.Msg) (Finder, tea.Cmd) {
 
 	// Re-search on input change
 	if f.input.Value() != prevValue && f.searchFn != nil {
-		f.items = f.searchFn(f.input.Value())
+		f.search(f.input.Value())
 		f.cursor = 0
-		f.updatePreview()
+		return f, tea.Batch(cmd, f.requestPreview())
 	}
 
 	return f, cmd
 }
 
+// quickfixItems returns the marked items, or all results for the query when
+// nothing is marked, loading past the current page of a paged search. Saved
+// searches are left out.
+func (f Finder) quickfixItems() []FinderItem {
+	items := f.marked
+	if len(items) == 0 {
+		items = f.items
+		if f.pagedFn != nil && f.hasMore {
+			f.limit = finderQuickfixLimit
+			f.fetch(f.input.Value())
+			items = f.items
+		}
+	}
+	var out []FinderItem
+	for _, item := range items {
+		if item.Path != "" && item.Query == "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// toggleMark marks or unmarks a note. Items are identified by path and line
+// so content matches in the same note can be marked separately.
+func (f *Finder) toggleMark(item FinderItem) {
+	if item.Query != "" {
+		return // saved searches are not notes
+	}
+	for i, m := range f.marked {
+		if m.Path == item.Path && m.Line == item.Line {
+			f.marked = append(f.marked[:i], f.marked[i+1:]...)
+			return
+		}
+	}
+	f.marked = append(f.marked, item)
+}
+
+func (f Finder) isMarked(item FinderItem) bool {
+	for _, m := range f.marked {
+		if m.Path == item.Path && m.Line == item.Line {
+			return true
+		}
+	}
+	return false
+}
+
+// markedPaths returns the unique paths of the marked items in marking order.
+func (f Finder) markedPaths() []string {
+	var paths []string
+	seen := make(map[string]bool, len(f.marked))
+	for _, m := range f.marked {
+		if !seen[m.Path] {
+			seen[m.Path] = true
+			paths = append(paths, m.Path)
+		}
+	}
+	return paths
+}
+
+// updatePreviewFocus handles keys while the preview pane has focus: j/k
+// scroll line by line, ctrl+h, tab or esc hand focus back to the search input.
+func (f Finder) updatePreviewFocus(msg tea.KeyMsg) (Finder, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+h", "tab", "esc", "i":
+		f.previewFocus = false
+		f.input.Focus()
+	case "j", "down":
+		f.scrollPreview(1)
+	case "k", "up":
+		f.scrollPreview(-1)
+	case "ctrl+d":
+		f.scrollPreview(f.previewHeight() / 2)
+	case "ctrl+u":
+		f.scrollPreview(-f.previewHeight() / 2)
+	case "g":
+		f.previewScroll = 0
+	case "G":
+		f.scrollPreview(strings.Count(f.preview, "\n") + 1)
+	case "enter":
+		f.previewFocus = false
+		f.input.Focus()
+		f.recordQuery()
+		if f.cursor < len(f.items) {
+			item := f.items[f.cursor]
+			f.visible = false
+			return f, func() tea.Msg {
+				return FinderResultMsg{Path: item.Path, Line: item.Line}
+			}
+		}
+	}
+	return f, nil
+}
+
 func (f *Finder) scrollPreview(delta int) {
 	lines := strings.Split(f.preview, "\n")
 	maxScroll := max(len(lines)-f.previewHeight(), 0)
@@ -233,9 +783,9 @@ func (f Finder) View() string {
 		return ""
 	}
 
-	th := f.theme
+	st := f.styles
 
-	overlayWidth := min(max(f.width*4/5, 60), f.width-4)
+	overlayWidth := min(max(f.width*9/10, 60), f.width-2)
 	overlayH := f.overlayHeight()
 
 	// Inner width accounts for outer border (2) + padding (2)
@@ -247,21 +797,24 @@ func (f Finder) View() string {
 	contentHeight := overlayH - 4 // border top/bottom + title + input + blank
 
 	// --- Left column: search input + results ---
-	titleStyle := lipgloss.NewStyle().
-		Bold(true).
-		Foreground(th.Accent)
-
 	f.input.Width = leftWidth - 2
 
 	var leftLines []string
-	leftLines = append(leftLines, titleStyle.Render(f.title))
+	header := st.AccentBold.Render(f.title)
+	if f.sortMode != FinderSortRelevance {
+		header += st.Dim.Render("  by " + f.sortMode.String())
+	}
+	if len(f.marked) > 0 {
+		header += st.Dim.Render(fmt.Sprintf("  %d marked", len(f.marked)))
+	}
+	leftLines = append(leftLines, header)
 	leftLines = append(leftLines, f.input.View())
 	leftLines = append(leftLines, "")
 
-	maxResults := min(max(contentHeight-3, 3), len(f.items)) // title + input + blank
+	maxLines := max(contentHeight-3, 3) // title + input + blank
 
+	dim := st.Dim
 	if len(f.items) == 0 {
-		dim := lipgloss.NewStyle().Foreground(th.Dim)
 		leftLines = append(leftLines, dim.Render("No results"))
 
 		if f.canCreate {
@@ -273,34 +826,74 @@ func (f Finder) View() string {
 			}
 		}
 	} else {
-		for i := range maxResults {
+		start, rows := f.firstVisible(maxLines), maxLines
+		if start > 0 {
+			leftLines = append(leftLines, dim.Render(fmt.Sprintf("  %d above", start)))
+			rows--
+		}
+		end, used := start, 0
+		for i := start; i < len(f.items); i++ {
 			item := f.items[i]
-			prefix := "  "
-			style := lipgloss.NewStyle().Foreground(th.Text)
+			folder := itemFolder(item)
+			header := f.groupByFolder && (i == start || itemFolder(f.items[i-1]) != folder)
+			need := 1
+			if header {
+				need++
+			}
+			if used+need > rows {
+				break
+			}
+			if header {
+				label := folder
+				if label == "" {
+					label = "/"
+				}
+				leftLines = append(leftLines, renderHighlighted([]highlightSegment{{text: label, style: dim}}, leftWidth))
+			}
 
+			prefix := "  "
+			style, matchStyle := st.Text, st.Accent
 			if i == f.cursor {
 				prefix = "> "
-				style = lipgloss.NewStyle().Foreground(th.Accent).Bold(true)
+				style, matchStyle = st.AccentBold, st.MatchCursor
+			}
+			if f.isMarked(item) {
+				prefix = prefix[:1] + "+"
 			}
 
-			title := item.Title
+			title, titleMatches := item.Title, item.TitleMatches
 			if title == "" {
-				title = item.Path
+				title, titleMatches = item.Path, nil
 			}
 
-			line := fmt.Sprintf("%s%s", prefix, title)
-
-			// Truncate to left column width
-			if lipgloss.Width(line) > leftWidth {
-				line = line[:leftWidth-3] + "..."
+			segs := []highlightSegment{
+				{text: prefix + strings.Repeat("  ", item.Depth), style: style},
+				{text: title, matches: titleMatches, style: style, match: matchStyle},
+			}
+			// The folder tells apart notes that share a title; grouped
+			// results already show it in the header, and "path:line"
+			// titles contain it.
+			if folder != "" && !f.groupByFolder && !strings.HasPrefix(title, item.Path) {
+				segs = append(segs, highlightSegment{text: "  — " + folder, style: dim})
+			}
+			if item.Extra != "" {
+				segs = append(segs,
+					highlightSegment{text: "  ", style: dim},
+					highlightSegment{text: item.Extra, matches: item.ExtraMatches, style: dim, match: matchStyle},
+				)
 			}
 
-			leftLines = append(leftLines, style.Render(line))
+			leftLines = append(leftLines, renderHighlighted(segs, leftWidth))
+			end++
+			used += need
 		}
 
-		if len(f.items) > maxResults {
-			dim := lipgloss.NewStyle().Foreground(th.Dim)
-			leftLines = append(leftLines, dim.Render(fmt.Sprintf("  +%d more", len(f.items)-maxResults)))
+		if below := len(f.items) - end; below > 0 || f.hasMore {
+			more := fmt.Sprintf("  +%d more", below)
+			if f.hasMore {
+				more += "…"
+			}
+			leftLines = append(leftLines, dim.Render(more))
 		}
 	}
 
@@ -314,9 +907,6 @@ func (f Finder) View() string {
 		Render(strings.Join(leftLines, "\n"))
 
 	// --- Right column: preview ---
-	dim := lipgloss.NewStyle().Foreground(th.Dim)
-	highlightText := lipgloss.NewStyle().Foreground(th.Accent)
-	highlightGutter := lipgloss.NewStyle().Foreground(th.Accent)
 
 	// Determine the highlighted line (0-indexed) in the preview.
 	highlightLine := -1
@@ -339,7 +929,7 @@ func (f Finder) View() string {
 			}
 			absIdx := start + i
 			if absIdx == highlightLine {
-				rightLines = append(rightLines, highlightGutter.Render("▌")+highlightText.Render(l))
+				rightLines = append(rightLines, st.Accent.Render("▌")+st.Accent.Render(l))
 			} else {
 				rightLines = append(rightLines, " "+dim.Render(l))
 			}
@@ -351,23 +941,113 @@ func (f Finder) View() string {
 		rightLines = append(rightLines, "")
 	}
 
-	rightCol := lipgloss.NewStyle().
+	separator := st.Separator
+	if f.previewFocus {
+		separator = st.SeparatorFocused
+	}
+	rightCol := separator.
 		Width(rightWidth).
-		BorderLeft(true).
-		BorderStyle(lipgloss.NormalBorder()).
-		BorderForeground(th.Border).
-		PaddingLeft(1).
 		Render(strings.Join(rightLines, "\n"))
 
 	content := lipgloss.JoinHorizontal(lipgloss.Top, leftCol, rightCol)
 
-	borderStyle := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(th.Accent).
-		Padding(0, 1).
-		Width(innerWidth)
+	return st.Overlay.Width(innerWidth).Render(content)
+}
 
-	return borderStyle.Render(content)
+// firstVisible returns the index of the first result to draw so that the
+// cursor fits in maxLines rows, counting folder headers when grouping. One
+// row each is kept back for the "above" and "more" lines.
+func (f Finder) firstVisible(maxLines int) int {
+	budget := maxLines - 1
+	rows := 0
+	for i := f.cursor; i >= 0; i-- {
+		rows++
+		if f.groupByFolder && (i == 0 || itemFolder(f.items[i-1]) != itemFolder(f.items[i])) {
+			rows++
+		}
+		// Starting at i needs a header for i's folder even mid-group.
+		need := rows
+		if f.groupByFolder && i > 0 && itemFolder(f.items[i-1]) == itemFolder(f.items[i]) {
+			need++
+		}
+		if i > 0 {
+			need++ // "N above"
+		}
+		if need > budget {
+			return min(i+1, f.cursor)
+		}
+	}
+	return 0
+}
+
+// highlightSegment is a run of text whose runes at the given offsets are
+// rendered with match instead of style.
+type highlightSegment struct {
+	text    string
+	matches []int
+	style   lipgloss.Style
+	match   lipgloss.Style
+}
+
+// renderHighlighted renders segments on one line, truncating with "..." to
+// fit width. Consecutive runes with the same style are rendered together.
+func renderHighlighted(segs []highlightSegment, width int) string {
+	type styledRune struct {
+		r  rune
+		hl bool
+		s  *highlightSegment
+	}
+	var runes []styledRune
+	for i := range segs {
+		seg := &segs[i]
+		mi := 0
+		for j, r := range []rune(seg.text) {
+			for mi < len(seg.matches) && seg.matches[mi] < j {
+				mi++
+			}
+			hl := mi < len(seg.matches) && seg.matches[mi] == j
+			runes = append(runes, styledRune{r: r, hl: hl, s: seg})
+		}
+	}
+
+	plain := make([]rune, len(runes))
+	for i, sr := range runes {
+		plain[i] = sr.r
+	}
+	var ellipsis *highlightSegment
+	if lipgloss.Width(string(plain)) > width {
+		for len(plain) > 0 && lipgloss.Width(string(plain)) > width-3 {
+			plain = plain[:len(plain)-1]
+		}
+		runes = runes[:len(plain)]
+		if len(runes) > 0 {
+			ellipsis = runes[len(runes)-1].s
+		} else {
+			ellipsis = &segs[0]
+		}
+	}
+
+	var b strings.Builder
+	for start := 0; start < len(runes); {
+		end := start + 1
+		for end < len(runes) && runes[end].s == runes[start].s && runes[end].hl == runes[start].hl {
+			end++
+		}
+		text := make([]rune, 0, end-start)
+		for _, sr := range runes[start:end] {
+			text = append(text, sr.r)
+		}
+		st := runes[start].s.style
+		if runes[start].hl {
+			st = runes[start].s.match
+		}
+		b.WriteString(st.Render(string(text)))
+		start = end
+	}
+	if ellipsis != nil {
+		b.WriteString(ellipsis.style.Render("..."))
+	}
+	return b.String()
 }
 
 func (f *Finder) SetSize(width, height int) {
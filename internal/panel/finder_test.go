@@ -0,0 +1,465 @@
+package panel
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+func newTestFinder(items []FinderItem, previews map[string]string) Finder {
+	f := NewFinder()
+	st := theme.NewStyleSet(theme.DefaultTheme())
+	f.SetStyles(&st)
+	f.SetSize(120, 40)
+	f.SetSearchFunc(func(string) []FinderItem { return items })
+	f.SetPreviewFunc(func(path string) string { return previews[path] })
+	return f
+}
+
+// runPreview executes a preview command (possibly batched with others) and
+// feeds the result back to the finder.
+func runPreview(t *testing.T, f Finder, cmd tea.Cmd) Finder {
+	t.Helper()
+	if cmd == nil {
+		t.Fatal("expected a preview command")
+	}
+	switch msg := cmd().(type) {
+	case FinderPreviewMsg:
+		f, _ = f.Update(msg)
+		return f
+	case tea.BatchMsg:
+		for _, c := range msg {
+			if c == nil {
+				continue
+			}
+			if pm, ok := c().(FinderPreviewMsg); ok {
+				f, _ = f.Update(pm)
+				return f
+			}
+		}
+	}
+	t.Fatal("command produced no FinderPreviewMsg")
+	return f
+}
+
+func TestFinderPreviewLoadsAsync(t *testing.T) {
+	f := newTestFinder(
+		[]FinderItem{{Title: "a", Path: "a.md"}, {Title: "b", Path: "b.md"}},
+		map[string]string{"a.md": "alpha", "b.md": "bravo"},
+	)
+
+	cmd := f.Show()
+	if f.preview != "" {
+		t.Fatalf("preview should load asynchronously, got %q", f.preview)
+	}
+	f = runPreview(t, f, cmd)
+	if f.preview != "alpha" {
+		t.Errorf("preview = %q, want %q", f.preview, "alpha")
+	}
+
+	f, cmd = f.Update(specialKey(tea.KeyDown))
+	f = runPreview(t, f, cmd)
+	if f.preview != "bravo" {
+		t.Errorf("preview = %q, want %q", f.preview, "bravo")
+	}
+}
+
+func TestFinderPreviewDropsStale(t *testing.T) {
+	f := newTestFinder(
+		[]FinderItem{{Title: "a", Path: "a.md"}, {Title: "b", Path: "b.md"}},
+		map[string]string{"a.md": "alpha", "b.md": "bravo"},
+	)
+
+	stale := f.Show()
+	f, fresh := f.Update(specialKey(tea.KeyDown))
+
+	// The first load finishes after the cursor moved and must be ignored.
+	f, _ = f.Update(stale())
+	if f.preview == "alpha" {
+		t.Fatalf("stale preview applied: %q", f.preview)
+	}
+	f = runPreview(t, f, fresh)
+	if f.preview != "bravo" {
+		t.Errorf("preview = %q, want %q", f.preview, "bravo")
+	}
+}
+
+func TestFinderPreviewFocusScroll(t *testing.T) {
+	var lines []string
+	for i := range 200 {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	f := newTestFinder(
+		[]FinderItem{{Title: "a", Path: "a.md"}},
+		map[string]string{"a.md": strings.Join(lines, "\n")},
+	)
+	f = runPreview(t, f, f.Show())
+
+	// Without preview focus, j is typed into the query.
+	f, cmd := f.Update(key("j"))
+	f = runPreview(t, f, cmd)
+	if f.input.Value() != "j" || f.previewScroll != 0 {
+		t.Fatalf("j should type into the query, got input %q scroll %d", f.input.Value(), f.previewScroll)
+	}
+
+	f, _ = f.Update(specialKey(tea.KeyCtrlL))
+	if !f.previewFocus {
+		t.Fatal("ctrl+l should focus the preview")
+	}
+	f, _ = f.Update(key("j"))
+	f, _ = f.Update(key("j"))
+	f, _ = f.Update(key("k"))
+	if f.previewScroll != 1 {
+		t.Errorf("previewScroll = %d, want 1", f.previewScroll)
+	}
+	if f.input.Value() != "j" {
+		t.Errorf("query changed while preview focused: %q", f.input.Value())
+	}
+
+	f, _ = f.Update(specialKey(tea.KeyEsc))
+	if f.previewFocus || !f.Visible() {
+		t.Error("esc in preview should return focus to the input without closing")
+	}
+}
+
+func TestFinderMultiSelect(t *testing.T) {
+	f := newTestFinder([]FinderItem{
+		{Title: "a", Path: "a.md"},
+		{Title: "b", Path: "b.md"},
+		{Title: "c", Path: "c.md"},
+	}, nil)
+	f.Show()
+
+	// Tab marks and advances; shift+tab on a marked item unmarks it.
+	f, _ = f.Update(specialKey(tea.KeyTab))      // mark a, cursor b
+	f, _ = f.Update(specialKey(tea.KeyTab))      // mark b, cursor c
+	f, _ = f.Update(specialKey(tea.KeyTab))      // mark c, cursor stays
+	f, _ = f.Update(specialKey(tea.KeyShiftTab)) // unmark c, cursor b
+	if f.cursor != 1 {
+		t.Errorf("cursor = %d, want 1", f.cursor)
+	}
+	if !strings.Contains(f.View(), "2 marked") {
+		t.Error("view should show the marked count")
+	}
+
+	f, cmd := f.Update(specialKey(tea.KeyEnter))
+	if f.Visible() {
+		t.Error("finder should close on enter")
+	}
+	msg, ok := cmd().(FinderMultiResultMsg)
+	if !ok {
+		t.Fatalf("expected FinderMultiResultMsg, got %T", cmd())
+	}
+	if strings.Join(msg.Paths, ",") != "a.md,b.md" {
+		t.Errorf("Paths = %v, want [a.md b.md]", msg.Paths)
+	}
+
+	// Show starts with no marks.
+	f.Show()
+	if len(f.marked) != 0 {
+		t.Errorf("marks survived Show: %v", f.marked)
+	}
+}
+
+func TestFinderBatchDedupesPaths(t *testing.T) {
+	// Content matches in the same note are marked separately but the batch
+	// action sees each note once.
+	f := newTestFinder([]FinderItem{
+		{Title: "a.md:1", Path: "a.md", Line: 1},
+		{Title: "a.md:5", Path: "a.md", Line: 5},
+		{Title: "b.md:2", Path: "b.md", Line: 2},
+	}, nil)
+	f.Show()
+	f, _ = f.Update(specialKey(tea.KeyTab))
+	f, _ = f.Update(specialKey(tea.KeyTab))
+	if len(f.marked) != 2 {
+		t.Fatalf("marked = %d, want 2", len(f.marked))
+	}
+
+	f, cmd := f.Update(specialKey(tea.KeyCtrlX))
+	msg, ok := cmd().(FinderBatchMsg)
+	if !ok {
+		t.Fatalf("expected FinderBatchMsg, got %T", cmd())
+	}
+	if msg.Op != FinderBatchMove || len(msg.Paths) != 1 || msg.Paths[0] != "a.md" {
+		t.Errorf("got %+v, want move [a.md]", msg)
+	}
+	if f.Visible() {
+		t.Error("finder should close after a batch action")
+	}
+}
+
+func TestFinderSavedSearch(t *testing.T) {
+	f := newTestFinder([]FinderItem{{Title: "Inbox", Query: "status:inbox"}}, nil)
+	f.Show()
+
+	f, cmd := f.Update(specialKey(tea.KeyEnter))
+	msg, ok := cmd().(SavedSearchSelectedMsg)
+	if !ok || msg.Name != "Inbox" || msg.Query != "status:inbox" {
+		t.Fatalf("got %#v, want SavedSearchSelectedMsg for Inbox", cmd())
+	}
+
+	var searched string
+	f.SetSearchFunc(func(q string) []FinderItem {
+		searched = q
+		return []FinderItem{{Title: "a", Path: "a.md"}}
+	})
+	f.ShowQuery(msg.Query)
+	if !f.Visible() || f.input.Value() != "status:inbox" || searched != "status:inbox" {
+		t.Errorf("ShowQuery: visible=%v input=%q searched=%q", f.Visible(), f.input.Value(), searched)
+	}
+	if len(f.items) != 1 {
+		t.Errorf("items = %v, want the query results", f.items)
+	}
+}
+
+func TestFinderActionMenu(t *testing.T) {
+	f := newTestFinder([]FinderItem{{Title: "a", Path: "a.md", Line: 4}}, nil)
+	f.Show()
+
+	f, cmd := f.Update(specialKey(tea.KeyCtrlA))
+	if f.Visible() {
+		t.Error("finder should close when the action menu opens")
+	}
+	msg, ok := cmd().(FinderActionMsg)
+	if !ok || msg.Item.Path != "a.md" || msg.Item.Line != 4 {
+		t.Fatalf("got %#v, want FinderActionMsg for a.md", cmd())
+	}
+
+	f = newTestFinder([]FinderItem{{Title: "Inbox", Query: "status:inbox"}}, nil)
+	f.Show()
+	if f, cmd = f.Update(specialKey(tea.KeyCtrlA)); cmd != nil || !f.Visible() {
+		t.Error("saved searches have no action menu")
+	}
+}
+
+func TestRenderHighlightedTruncates(t *testing.T) {
+	plain := lipgloss.NewStyle()
+	segs := []highlightSegment{
+		{text: "> ", style: plain},
+		{text: "Home Budget", matches: []int{5, 6, 7}, style: plain, match: plain},
+		{text: "  groceries", style: plain},
+	}
+
+	if got := renderHighlighted(segs, 40); got != "> Home Budget  groceries" {
+		t.Errorf("got %q", got)
+	}
+	if got := renderHighlighted(segs, 12); got != "> Home Bu..." {
+		t.Errorf("truncated: got %q", got)
+	}
+}
+
+func TestFinderFolderSuffix(t *testing.T) {
+	f := newTestFinder([]FinderItem{
+		{Title: "Design", Path: "projects/kopr/design.md"},
+		{Title: "Design", Path: "design.md"},
+	}, nil)
+	f.Show()
+
+	view := f.View()
+	if !strings.Contains(view, "Design  — projects/kopr/") {
+		t.Error("view should show the folder of a nested note")
+	}
+	if strings.Count(view, "—") != 1 {
+		t.Error("notes at the vault root should have no folder suffix")
+	}
+
+	f = newTestFinder([]FinderItem{{Title: "projects/kopr/design.md:3", Path: "projects/kopr/design.md", Line: 3}}, nil)
+	f.Show()
+	if strings.Contains(f.View(), "—") {
+		t.Error("path:line titles already show the folder")
+	}
+}
+
+func TestFinderTreeIndent(t *testing.T) {
+	f := newTestFinder([]FinderItem{
+		{Title: "project", Query: "tag:project"},
+		{Title: "alpha", Query: "tag:project/alpha", Depth: 1},
+		{Title: "x", Query: "tag:project/alpha/x", Depth: 2},
+	}, nil)
+	f.Show()
+
+	view := f.View()
+	for _, want := range []string{"> project", "    alpha", "      x"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view missing %q:\n%s", want, view)
+		}
+	}
+}
+
+func TestFinderGroupByFolder(t *testing.T) {
+	items := []FinderItem{
+		{Title: "A", Path: "work/a.md"},
+		{Title: "B", Path: "home/b.md"},
+		{Title: "C", Path: "work/c.md"},
+		{Title: "D", Path: "d.md"},
+	}
+	f := newTestFinder(items, nil)
+	f.SetGroupByFolder(true)
+	f.Show()
+
+	var got []string
+	for _, item := range f.items {
+		got = append(got, item.Title)
+	}
+	if strings.Join(got, "") != "ACBD" {
+		t.Errorf("grouped order = %v, want [A C B D]", got)
+	}
+
+	view := f.View()
+	for _, want := range []string{"work/", "home/"} {
+		if strings.Count(view, want) != 1 {
+			t.Errorf("view should show header %q once", want)
+		}
+	}
+	if strings.Contains(view, "—") {
+		t.Error("grouped results should not repeat the folder per row")
+	}
+}
+
+func TestFinderSortModes(t *testing.T) {
+	items := []FinderItem{
+		{Title: "beta", Path: "z/beta.md", ModTime: time.Unix(100, 0), Created: time.Unix(50, 0)},
+		{Title: "Alpha", Path: "y/alpha.md", ModTime: time.Unix(300, 0)},
+		{Title: "gamma", Path: "x/gamma.md", ModTime: time.Unix(200, 0), Created: time.Unix(60, 0)},
+	}
+	f := newTestFinder(items, nil)
+	f.Show()
+
+	order := func() string {
+		var got []string
+		for _, item := range f.items {
+			got = append(got, item.Title)
+		}
+		return strings.Join(got, " ")
+	}
+	want := []string{
+		"Alpha gamma beta", // modified
+		"gamma beta Alpha", // created, undated last
+		"Alpha beta gamma", // title
+		"gamma Alpha beta", // path
+		"beta Alpha gamma", // back to relevance
+	}
+	for i, w := range want {
+		f, _ = f.Update(specialKey(tea.KeyCtrlS))
+		if got := order(); got != w {
+			t.Errorf("after %d ctrl+s (%s): %q, want %q", i+1, f.Sort(), got, w)
+		}
+	}
+
+	if ParseFinderSort("title") != FinderSortTitle || ParseFinderSort("bogus") != FinderSortRelevance {
+		t.Error("ParseFinderSort should map names and default to relevance")
+	}
+}
+
+func TestFinderPaging(t *testing.T) {
+	const total = 120
+	var limits []int
+	f := newTestFinder(nil, nil)
+	f.SetPagedSearchFunc(func(_ string, limit int) []FinderItem {
+		limits = append(limits, limit)
+		var items []FinderItem
+		for i := range min(limit, total) {
+			items = append(items, FinderItem{Title: fmt.Sprintf("note %03d", i), Path: fmt.Sprintf("n%03d.md", i)})
+		}
+		return items
+	})
+	f.Show()
+	if len(f.items) != finderPageSize {
+		t.Fatalf("first page = %d items, want %d", len(f.items), finderPageSize)
+	}
+	if !strings.Contains(f.View(), "more…") {
+		t.Error("view should say more results can be loaded")
+	}
+
+	for range total - 1 {
+		f, _ = f.Update(specialKey(tea.KeyDown))
+	}
+	if f.cursor != total-1 || len(f.items) != total {
+		t.Fatalf("after scrolling: cursor %d of %d items, want %d of %d", f.cursor, len(f.items), total-1, total)
+	}
+	if want := []int{50, 100, 150}; !slices.Equal(limits, want) {
+		t.Errorf("limits requested = %v, want %v", limits, want)
+	}
+	view := f.View()
+	if !strings.Contains(view, "note 119") || !strings.Contains(view, "above") {
+		t.Error("view should scroll to keep the cursor visible")
+	}
+	if strings.Contains(view, "more") {
+		t.Error("view should not offer more once the last page is short")
+	}
+}
+
+func TestFinderHistory(t *testing.T) {
+	f := newTestFinder([]FinderItem{{Title: "a", Path: "a.md"}}, nil)
+	f.SetHistory([]string{"budget", "tag:work"})
+	f.Show()
+
+	// Up on an empty query starts at the newest entry.
+	f, _ = f.Update(specialKey(tea.KeyUp))
+	if f.input.Value() != "tag:work" {
+		t.Fatalf("after up: %q, want %q", f.input.Value(), "tag:work")
+	}
+	f, _ = f.Update(specialKey(tea.KeyCtrlP))
+	if f.input.Value() != "budget" {
+		t.Fatalf("after ctrl+p: %q, want %q", f.input.Value(), "budget")
+	}
+	f, _ = f.Update(specialKey(tea.KeyCtrlP)) // already oldest
+	if f.input.Value() != "budget" {
+		t.Fatalf("ctrl+p past oldest: %q", f.input.Value())
+	}
+	f, _ = f.Update(specialKey(tea.KeyDown))
+	f, _ = f.Update(specialKey(tea.KeyCtrlN))
+	if f.input.Value() != "" {
+		t.Fatalf("stepping past newest should restore the draft, got %q", f.input.Value())
+	}
+
+	// Accepting a query records it; repeats move to the end.
+	f.input.SetValue("budget")
+	f, _ = f.Update(specialKey(tea.KeyEnter))
+	if got := strings.Join(f.History(), ","); got != "tag:work,budget" {
+		t.Errorf("History() = %q, want %q", got, "tag:work,budget")
+	}
+}
+
+func TestFinderQuickfix(t *testing.T) {
+	const total = 120
+	f := newTestFinder(nil, nil)
+	f.SetPagedSearchFunc(func(_ string, limit int) []FinderItem {
+		var items []FinderItem
+		for i := range min(limit, total) {
+			items = append(items, FinderItem{Title: fmt.Sprintf("n%03d.md:1", i), Path: fmt.Sprintf("n%03d.md", i), Line: 1})
+		}
+		return items
+	})
+	f.ShowQuery("todo")
+
+	// Without marks every result is sent, not just the loaded page.
+	f, cmd := f.Update(specialKey(tea.KeyCtrlQ))
+	if f.Visible() {
+		t.Error("finder should close on ctrl+q")
+	}
+	msg, ok := cmd().(FinderQuickfixMsg)
+	if !ok {
+		t.Fatalf("expected FinderQuickfixMsg, got %T", cmd())
+	}
+	if msg.Query != "todo" || len(msg.Items) != total {
+		t.Errorf("got query %q with %d items, want %q with %d", msg.Query, len(msg.Items), "todo", total)
+	}
+
+	// With marks only the marked results are sent.
+	f.ShowQuery("todo")
+	f, _ = f.Update(specialKey(tea.KeyTab))
+	_, cmd = f.Update(specialKey(tea.KeyCtrlQ))
+	msg = cmd().(FinderQuickfixMsg)
+	if len(msg.Items) != 1 || msg.Items[0].Path != "n000.md" {
+		t.Errorf("Items = %v, want the marked n000.md", msg.Items)
+	}
+}
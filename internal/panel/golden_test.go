@@ -0,0 +1,164 @@
+package panel
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/muesli/termenv"
+
+	"github.com/pfassina/kopr/internal/theme"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata/golden")
+
+func TestMain(m *testing.M) {
+	// Render the same whatever terminal runs the tests.
+	lipgloss.SetColorProfile(termenv.Ascii)
+	os.Exit(m.Run())
+}
+
+// golden compares a rendered view with testdata/golden/<name>.golden, or
+// rewrites the file when the tests run with -update. Views are compared as
+// plain text with trailing spaces trimmed, so the files read as the screen
+// does; styling is left to the other tests.
+func golden(t *testing.T, name, view string) {
+	t.Helper()
+	lines := strings.Split(ansi.Strip(view), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " ")
+	}
+	got := strings.TrimRight(strings.Join(lines, "\n"), "\n") + "\n"
+
+	path := filepath.Join("testdata", "golden", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test ./internal/panel -update to create it)", err)
+	}
+	if got != string(want) {
+		t.Errorf("%s differs from %s (run go test ./internal/panel -update if the change is intended)\ngot:\n%s\nwant:\n%s",
+			name, path, got, want)
+	}
+}
+
+func goldenStyles() *theme.StyleSet {
+	st := theme.NewStyleSet(theme.DefaultTheme())
+	return &st
+}
+
+func goldenTree() Tree {
+	tr := Tree{
+		allEntries: []vault.Entry{
+			{Name: "archive", Path: "archive", IsDir: true},
+			{Name: "old.md", Path: "archive/old.md", Depth: 1},
+			{Name: "projects", Path: "projects", IsDir: true},
+			{Name: "kopr", Path: "projects/kopr", IsDir: true, Depth: 1},
+			{Name: "design.md", Path: "projects/kopr/design.md", Depth: 2},
+			{Name: "a-very-long-note-name-that-gets-cut.md", Path: "projects/kopr/a-very-long-note-name-that-gets-cut.md", Depth: 2},
+			{Name: "roadmap.md", Path: "projects/roadmap.md", Depth: 1},
+			{Name: "inbox.md", Path: "inbox.md"},
+			{Name: "todo.md", Path: "todo.md"},
+		},
+		collapsed: map[string]bool{"archive": true},
+		selected:  map[string]bool{"todo.md": true},
+		clipboard: Clipboard{Paths: []string{"inbox.md"}, Op: ClipboardCut},
+		styles:    goldenStyles(),
+	}
+	tr.SetSize(30, 12)
+	tr.rebuildVisible()
+	return tr
+}
+
+func TestGoldenTree(t *testing.T) {
+	tr := goldenTree()
+	golden(t, "tree_blurred", tr.View())
+
+	tr.SetFocused(true)
+	tr.SetCursor(3)
+	golden(t, "tree_focused", tr.View())
+
+	tr.SetSize(40, 30)
+	tr, _ = tr.Update(key("?"))
+	golden(t, "tree_help", tr.View())
+}
+
+func TestGoldenFinder(t *testing.T) {
+	items := []FinderItem{
+		{Title: "Design notes", Path: "projects/kopr/design.md", TitleMatches: []int{0, 1}},
+		{Title: "Roadmap", Path: "projects/roadmap.md", Extra: "## Next quarter"},
+		{Title: "Inbox", Path: "inbox.md"},
+	}
+	f := NewFinder()
+	f.SetStyles(goldenStyles())
+	f.SetSize(100, 24)
+	f.SetSearchFunc(func(string) []FinderItem { return items })
+	f.SetPreviewFunc(func(path string) string { return "# " + path + "\n\nFirst paragraph of the note.\n" })
+
+	f = runPreview(t, f, f.Show())
+	golden(t, "finder_results", f.View())
+
+	f.SetSearchFunc(func(string) []FinderItem { return nil })
+	f.ShowQuery("nothing matches")
+	golden(t, "finder_empty", f.View())
+}
+
+func TestGoldenStatus(t *testing.T) {
+	s := NewStatus("/home/me/notes")
+	s.SetStyles(goldenStyles())
+	s.SetWidth(80)
+	golden(t, "status_vault", s.View())
+
+	s.SetMode("INSERT")
+	s.SetFile("projects/kopr/design.md")
+	s.SetProgress("Indexing 40%")
+	s.SetClipboard("2 cut")
+	golden(t, "status_file", s.View())
+
+	s.SetError("save: permission denied")
+	golden(t, "status_error", s.View())
+
+	// Neovim's messages and command line, with the UI protocol backend.
+	s.SetEditorMessage(`"design.md" 12L, 340B written`, false)
+	golden(t, "status_editor_message", s.View())
+
+	// Wider than the bar: it scrolls to keep the cursor, at the end, in view.
+	cmdline := ":%s/" + strings.Repeat("draft ", 12) + "/final/g"
+	s.SetCmdline(cmdline, len(cmdline))
+	golden(t, "status_cmdline", s.View())
+}
+
+func TestGoldenWhichKey(t *testing.T) {
+	th := theme.DefaultTheme()
+	w := NewWhichKey()
+	w.SetTheme(&th)
+	w.SetWidth(60)
+	w.SetEntries("", []WhichKeyEntry{
+		{Key: "f", Label: "+find"},
+		{Key: "n", Label: "+note"},
+		{Key: "v", Label: "+view"},
+		{Key: "i", Label: "+index"},
+		{Key: "q", Label: "Quit"},
+	})
+	golden(t, "whichkey_root", w.View())
+
+	w.SetWidth(30)
+	w.SetEntries("i", []WhichKeyEntry{
+		{Key: "o", Label: "Optimize index"},
+		{Key: "q", Label: "Query console"},
+	})
+	golden(t, "whichkey_narrow", w.View())
+}
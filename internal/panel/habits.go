@@ -0,0 +1,249 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+// HabitEntry is one habit checklist item from a daily note.
+type HabitEntry struct {
+	Date  time.Time
+	Habit string
+	Done  bool
+}
+
+// HabitClosedMsg is sent when the habit tracker is dismissed.
+type HabitClosedMsg struct{}
+
+// HabitTracker is an overlay that renders a month grid of habit completion
+// computed from checklist items in daily notes.
+type HabitTracker struct {
+	days    map[string]map[string]bool // date (YYYY-MM-DD) -> habit -> done
+	habits  []string                   // habit names in first-seen order
+	heading string
+	month   time.Time // first day of the displayed month
+	today   time.Time
+	width   int
+	visible bool
+	theme   *theme.Theme
+}
+
+// SetTheme sets the color theme for the habit tracker.
+func (h *HabitTracker) SetTheme(th *theme.Theme) { h.theme = th }
+
+func NewHabitTracker() HabitTracker {
+	return HabitTracker{}
+}
+
+// SetHeading sets the habit section heading shown in the empty-state hint.
+func (h *HabitTracker) SetHeading(heading string) {
+	h.heading = heading
+}
+
+// Show opens the tracker on the month containing today.
+func (h *HabitTracker) Show(entries []HabitEntry, today time.Time) {
+	h.days = make(map[string]map[string]bool)
+	h.habits = nil
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		key := e.Date.Format(dateKeyFormat)
+		if h.days[key] == nil {
+			h.days[key] = make(map[string]bool)
+		}
+		// A habit listed twice on the same day counts as done if either is checked.
+		h.days[key][e.Habit] = h.days[key][e.Habit] || e.Done
+		if !seen[e.Habit] {
+			seen[e.Habit] = true
+			h.habits = append(h.habits, e.Habit)
+		}
+	}
+	h.today = truncateDay(today)
+	h.month = firstOfMonth(h.today)
+	h.visible = true
+}
+
+func (h *HabitTracker) Hide() {
+	h.visible = false
+}
+
+func (h HabitTracker) Visible() bool {
+	return h.visible
+}
+
+func (h *HabitTracker) SetWidth(width int) {
+	h.width = width
+}
+
+func (h HabitTracker) Update(msg tea.Msg) (HabitTracker, tea.Cmd) {
+	if !h.visible {
+		return h, nil
+	}
+
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "q":
+			h.visible = false
+			return h, func() tea.Msg { return HabitClosedMsg{} }
+		case "h", "left", "[":
+			h.month = h.month.AddDate(0, -1, 0)
+		case "l", "right", "]":
+			h.month = h.month.AddDate(0, 1, 0)
+		case "t":
+			h.month = firstOfMonth(h.today)
+		}
+	}
+	return h, nil
+}
+
+// done reports whether habit was checked on day. ok is false when the day has
+// no daily note (or the note has no entry for the habit).
+func (h HabitTracker) done(habit string, day time.Time) (done, ok bool) {
+	habits, found := h.days[day.Format(dateKeyFormat)]
+	if !found {
+		return false, false
+	}
+	done, ok = habits[habit]
+	return done, ok
+}
+
+// CurrentStreak returns the number of consecutive days the habit was done,
+// counting back from end. If the habit isn't done yet on end, counting starts
+// from the previous day so an unfinished today doesn't reset the streak.
+func (h HabitTracker) CurrentStreak(habit string, end time.Time) int {
+	day := truncateDay(end)
+	if d, _ := h.done(habit, day); !d {
+		day = day.AddDate(0, 0, -1)
+	}
+	streak := 0
+	for {
+		d, _ := h.done(habit, day)
+		if !d {
+			return streak
+		}
+		streak++
+		day = day.AddDate(0, 0, -1)
+	}
+}
+
+// LongestStreak returns the longest run of consecutive done days for the
+// habit between from and to (inclusive).
+func (h HabitTracker) LongestStreak(habit string, from, to time.Time) int {
+	longest, run := 0, 0
+	for day := truncateDay(from); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if d, _ := h.done(habit, day); d {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	return longest
+}
+
+func (h HabitTracker) View() string {
+	if !h.visible {
+		return ""
+	}
+
+	th := h.theme
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Accent)
+	dim := lipgloss.NewStyle().Foreground(th.Dim)
+	text := lipgloss.NewStyle().Foreground(th.Text)
+	doneStyle := lipgloss.NewStyle().Foreground(th.Accent)
+	todayStyle := lipgloss.NewStyle().Foreground(th.Accent2).Bold(true)
+
+	lastDay := h.month.AddDate(0, 1, -1)
+	daysInMonth := lastDay.Day()
+
+	labelW := 6
+	for _, name := range h.habits {
+		labelW = max(labelW, lipgloss.Width(name))
+	}
+	labelW = min(labelW, 20)
+	if h.width > 0 {
+		// border + padding (4), two cells per day, streak columns (8).
+		labelW = min(labelW, max(h.width-4-daysInMonth*2-9, 6))
+	}
+
+	var lines []string
+	lines = append(lines, titleStyle.Render("Habits · "+h.month.Format("January 2006")))
+	lines = append(lines, "")
+
+	if len(h.habits) == 0 {
+		lines = append(lines, dim.Render(fmt.Sprintf("No checklist items under %q in daily notes", "## "+h.heading)))
+	} else {
+		// Day-of-month header (last digit, so the grid stays two cells per day).
+		var hdr strings.Builder
+		hdr.WriteString(strings.Repeat(" ", labelW+1))
+		for d := 1; d <= daysInMonth; d++ {
+			cell := fmt.Sprintf("%d ", d%10)
+			day := h.month.AddDate(0, 0, d-1)
+			if day.Equal(h.today) {
+				hdr.WriteString(todayStyle.Render(cell))
+			} else {
+				hdr.WriteString(dim.Render(cell))
+			}
+		}
+		hdr.WriteString(dim.Render(" cur max"))
+		lines = append(lines, hdr.String())
+
+		for _, name := range h.habits {
+			label := ansi.Truncate(name, labelW, "…")
+			label += strings.Repeat(" ", labelW-lipgloss.Width(label))
+
+			var row strings.Builder
+			row.WriteString(text.Render(label) + " ")
+			for d := 1; d <= daysInMonth; d++ {
+				day := h.month.AddDate(0, 0, d-1)
+				done, ok := h.done(name, day)
+				switch {
+				case day.After(h.today) || !ok:
+					row.WriteString("  ")
+				case done:
+					row.WriteString(doneStyle.Render("■ "))
+				default:
+					row.WriteString(dim.Render("· "))
+				}
+			}
+
+			cur := 0
+			if !h.month.After(h.today) {
+				end := lastDay
+				if end.After(h.today) {
+					end = h.today
+				}
+				cur = h.CurrentStreak(name, end)
+			}
+			longest := h.LongestStreak(name, h.month, lastDay)
+			row.WriteString(text.Render(fmt.Sprintf(" %3d %3d", cur, longest)))
+			lines = append(lines, row.String())
+		}
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, dim.Render("h/l: month  t: today  esc: close"))
+
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(th.Accent).
+		Padding(0, 1)
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
+
+const dateKeyFormat = "2006-01-02"
+
+func truncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local)
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+}
@@ -0,0 +1,102 @@
+package panel
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse(dateKeyFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestHabitTrackerStreaks(t *testing.T) {
+	h := NewHabitTracker()
+	h.Show([]HabitEntry{
+		{Date: day("2024-03-01"), Habit: "Run", Done: true},
+		{Date: day("2024-03-02"), Habit: "Run", Done: true},
+		{Date: day("2024-03-03"), Habit: "Run", Done: true},
+		{Date: day("2024-03-04"), Habit: "Run", Done: false},
+		{Date: day("2024-03-05"), Habit: "Run", Done: true},
+		{Date: day("2024-03-06"), Habit: "Run", Done: true},
+		{Date: day("2024-03-07"), Habit: "Run", Done: false}, // today, not done yet
+		{Date: day("2024-03-06"), Habit: "Read", Done: true},
+	}, day("2024-03-07"))
+
+	tests := []struct {
+		habit       string
+		wantCurrent int
+		wantLongest int
+	}{
+		{"Run", 2, 3},
+		{"Read", 1, 1},
+		{"Missing", 0, 0},
+	}
+
+	today := day("2024-03-07")
+	for _, tt := range tests {
+		if got := h.CurrentStreak(tt.habit, today); got != tt.wantCurrent {
+			t.Errorf("CurrentStreak(%q) = %d, want %d", tt.habit, got, tt.wantCurrent)
+		}
+		if got := h.LongestStreak(tt.habit, day("2024-03-01"), day("2024-03-31")); got != tt.wantLongest {
+			t.Errorf("LongestStreak(%q) = %d, want %d", tt.habit, got, tt.wantLongest)
+		}
+	}
+}
+
+func TestHabitTrackerMonthNavigation(t *testing.T) {
+	th := theme.DefaultTheme()
+	h := NewHabitTracker()
+	h.SetTheme(&th)
+	h.Show([]HabitEntry{{Date: day("2024-03-01"), Habit: "Run", Done: true}}, day("2024-03-15"))
+
+	if !strings.Contains(h.View(), "March 2024") {
+		t.Fatalf("expected current month in view")
+	}
+
+	h, _ = h.Update(key("h"))
+	if !strings.Contains(h.View(), "February 2024") {
+		t.Errorf("expected previous month after h")
+	}
+
+	h, _ = h.Update(key("t"))
+	if !strings.Contains(h.View(), "March 2024") {
+		t.Errorf("expected t to return to the current month")
+	}
+
+	h, cmd := h.Update(specialKey(tea.KeyEsc))
+	if h.Visible() {
+		t.Error("expected esc to hide the tracker")
+	}
+	if cmd == nil {
+		t.Fatal("expected close cmd")
+	}
+	if _, ok := cmd().(HabitClosedMsg); !ok {
+		t.Errorf("expected HabitClosedMsg")
+	}
+}
+
+func TestHabitTrackerTruncatesWideLabels(t *testing.T) {
+	th := theme.DefaultTheme()
+	h := NewHabitTracker()
+	h.SetTheme(&th)
+	h.SetWidth(80)
+	h.Show([]HabitEntry{{Date: day("2024-03-01"), Habit: "éééééééééé", Done: true}}, day("2024-03-15"))
+
+	view := h.View()
+	if !utf8.ValidString(view) {
+		t.Fatal("view is not valid UTF-8")
+	}
+	if !strings.Contains(view, "ééééé…") {
+		t.Errorf("expected the label cut to ééééé…, got:\n%s", view)
+	}
+}
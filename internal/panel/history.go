@@ -0,0 +1,247 @@
+package panel
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/git"
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+// HistoryDiffMsg carries a commit's diff loaded in the background. Seq ties
+// it to the request so stale loads are dropped.
+type HistoryDiffMsg struct {
+	Seq  int
+	Diff string
+}
+
+// HistoryRestoreMsg is sent when the user asks to restore the note to the
+// version in Commit.
+type HistoryRestoreMsg struct {
+	Commit git.Commit
+}
+
+// HistoryClosedMsg is sent when the history overlay is dismissed.
+type HistoryClosedMsg struct{}
+
+// DiffFunc returns the diff a commit made to the note.
+type DiffFunc func(c git.Commit) string
+
+// History is an overlay listing the commits that changed a note, with the
+// selected commit's diff beside them.
+type History struct {
+	title      string
+	commits    []git.Commit
+	cursor     int
+	scroll     int // first commit shown
+	diff       []string
+	diffScroll int
+	diffSeq    int
+	diffFn     DiffFunc
+	width      int
+	height     int
+	visible    bool
+	theme      *theme.Theme
+}
+
+// SetTheme sets the color theme for the history overlay.
+func (h *History) SetTheme(th *theme.Theme) { h.theme = th }
+
+func NewHistory() History {
+	return History{}
+}
+
+// Show opens the overlay on the newest commit and returns the command that
+// loads its diff through diffFn.
+func (h *History) Show(title string, commits []git.Commit, diffFn DiffFunc) tea.Cmd {
+	h.title = title
+	h.commits = commits
+	h.diffFn = diffFn
+	h.cursor = 0
+	h.scroll = 0
+	h.diff = nil
+	h.visible = true
+	return h.requestDiff()
+}
+
+func (h *History) Hide() {
+	h.visible = false
+}
+
+func (h History) Visible() bool {
+	return h.visible
+}
+
+func (h *History) SetSize(width, height int) {
+	h.width = width
+	h.height = height
+}
+
+// requestDiff returns a command that loads the selected commit's diff off
+// the UI goroutine. The old diff stays on screen until it arrives.
+func (h *History) requestDiff() tea.Cmd {
+	h.diffSeq++
+	if h.diffFn == nil || h.cursor >= len(h.commits) {
+		h.diff = nil
+		return nil
+	}
+	seq, c, fn := h.diffSeq, h.commits[h.cursor], h.diffFn
+	return func() tea.Msg {
+		return HistoryDiffMsg{Seq: seq, Diff: fn(c)}
+	}
+}
+
+func (h History) Update(msg tea.Msg) (History, tea.Cmd) {
+	if !h.visible {
+		return h, nil
+	}
+
+	switch msg := msg.(type) {
+	case HistoryDiffMsg:
+		if msg.Seq == h.diffSeq {
+			h.diff = strings.Split(strings.TrimRight(msg.Diff, "\n"), "\n")
+			h.diffScroll = 0
+		}
+		return h, nil
+
+	case tea.KeyMsg:
+		half := max(1, h.bodyHeight()/2)
+		switch msg.String() {
+		case "esc", "q":
+			h.visible = false
+			return h, func() tea.Msg { return HistoryClosedMsg{} }
+		case "r":
+			if h.cursor < len(h.commits) {
+				c := h.commits[h.cursor]
+				h.visible = false
+				return h, func() tea.Msg { return HistoryRestoreMsg{Commit: c} }
+			}
+		case "j", "down":
+			return h, h.moveCursor(1)
+		case "k", "up":
+			return h, h.moveCursor(-1)
+		case "g", "home":
+			return h, h.moveCursor(-len(h.commits))
+		case "G", "end":
+			return h, h.moveCursor(len(h.commits))
+		case "ctrl+d", "J":
+			h.scrollDiff(half)
+		case "ctrl+u", "K":
+			h.scrollDiff(-half)
+		}
+	}
+	return h, nil
+}
+
+// moveCursor selects the commit delta rows away and loads its diff.
+func (h *History) moveCursor(delta int) tea.Cmd {
+	cursor := max(0, min(h.cursor+delta, len(h.commits)-1))
+	if cursor == h.cursor {
+		return nil
+	}
+	h.cursor = cursor
+	rows := h.bodyHeight()
+	if h.cursor < h.scroll {
+		h.scroll = h.cursor
+	} else if h.cursor >= h.scroll+rows {
+		h.scroll = h.cursor - rows + 1
+	}
+	return h.requestDiff()
+}
+
+func (h *History) scrollDiff(delta int) {
+	h.diffScroll = max(0, min(h.diffScroll+delta, len(h.diff)-h.bodyHeight()))
+}
+
+// bodyHeight is the number of commit and diff rows that fit in the overlay.
+func (h History) bodyHeight() int {
+	// border (2) + title + blank + blank + footer
+	return max(h.height*4/5-6, 5)
+}
+
+func (h History) View() string {
+	if !h.visible {
+		return ""
+	}
+
+	th := h.theme
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Accent)
+	dim := lipgloss.NewStyle().Foreground(th.Dim)
+	text := lipgloss.NewStyle().Foreground(th.Text)
+	selected := lipgloss.NewStyle().Foreground(th.Accent).Bold(true)
+	removed := lipgloss.NewStyle().Foreground(th.Error)
+	added := lipgloss.NewStyle().Foreground(th.InsertMode)
+	hunk := lipgloss.NewStyle().Foreground(th.Accent2)
+
+	innerWidth := min(max(h.width*9/10, 60), h.width-2) - 4
+	leftWidth := max(innerWidth*35/100, 24)
+	rightWidth := innerWidth - leftWidth - 1 // -1 for the separator
+	rows := h.bodyHeight()
+
+	var left []string
+	end := min(h.scroll+rows, len(h.commits))
+	for i := h.scroll; i < end; i++ {
+		c := h.commits[i]
+		prefix, style := "  ", text
+		if i == h.cursor {
+			prefix, style = "> ", selected
+		}
+		line := dim.Render(c.Short+" "+c.Time.Format("2006-01-02")+" ") + style.Render(c.Subject)
+		left = append(left, ansi.Truncate(style.Render(prefix)+line, leftWidth, "…"))
+	}
+	for len(left) < rows {
+		left = append(left, "")
+	}
+
+	var right []string
+	if h.cursor < len(h.commits) {
+		c := h.commits[h.cursor]
+		right = append(right, ansi.Truncate(dim.Render(c.Author+", "+c.Time.Format("2006-01-02 15:04")), rightWidth-1, "…"))
+	}
+	diffEnd := min(h.diffScroll+rows-1, len(h.diff))
+	for _, l := range h.diff[min(h.diffScroll, diffEnd):diffEnd] {
+		l = strings.ReplaceAll(l, "\t", "    ")
+		style := text
+		switch {
+		case strings.HasPrefix(l, "+++"), strings.HasPrefix(l, "---"):
+			style = dim
+		case strings.HasPrefix(l, "+"):
+			style = added
+		case strings.HasPrefix(l, "-"):
+			style = removed
+		case strings.HasPrefix(l, "@@"):
+			style = hunk
+		case !strings.HasPrefix(l, " "):
+			style = dim // diff and index headers
+		}
+		right = append(right, style.Render(ansi.Truncate(l, rightWidth-1, "…")))
+	}
+	for len(right) < rows {
+		right = append(right, "")
+	}
+
+	leftCol := lipgloss.NewStyle().Width(leftWidth).Render(strings.Join(left, "\n"))
+	rightCol := lipgloss.NewStyle().
+		Width(rightWidth).
+		BorderLeft(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(th.Border).
+		PaddingLeft(1).
+		Render(strings.Join(right, "\n"))
+
+	lines := []string{titleStyle.Render(h.title), ""}
+	lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, leftCol, rightCol))
+	lines = append(lines, "")
+	lines = append(lines, dim.Render("j/k: commit  ctrl+d/u: scroll diff  r: restore this version  esc: close"))
+
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(th.Accent).
+		Padding(0, 1).
+		Width(innerWidth + 2)
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
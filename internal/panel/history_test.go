@@ -0,0 +1,51 @@
+package panel
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/git"
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+func TestHistoryLoadsDiffsAndRestores(t *testing.T) {
+	th := theme.DefaultTheme()
+	h := NewHistory()
+	h.SetTheme(&th)
+	h.SetSize(120, 40)
+
+	commits := []git.Commit{
+		{Hash: "bbb", Short: "bbb", Subject: "second"},
+		{Hash: "aaa", Short: "aaa", Subject: "first"},
+	}
+	cmd := h.Show("History of note.md", commits, func(c git.Commit) string {
+		return "+added in " + c.Subject
+	})
+	h, _ = h.Update(cmd())
+	if view := h.View(); !strings.Contains(view, "added in second") {
+		t.Errorf("view should show the newest commit's diff:\n%s", view)
+	}
+
+	// A diff that arrives after the selection moved on is dropped.
+	stale := cmd()
+	h, cmd = h.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'j'}})
+	h, _ = h.Update(cmd())
+	h, _ = h.Update(stale)
+	if view := h.View(); !strings.Contains(view, "added in first") {
+		t.Errorf("view should show the selected commit's diff:\n%s", view)
+	}
+
+	h, cmd = h.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'r'}})
+	if h.Visible() {
+		t.Error("history should close on restore")
+	}
+	msg, ok := cmd().(HistoryRestoreMsg)
+	if !ok {
+		t.Fatalf("expected HistoryRestoreMsg, got %T", cmd())
+	}
+	if msg.Commit.Hash != "aaa" {
+		t.Errorf("restore commit = %q, want aaa", msg.Commit.Hash)
+	}
+}
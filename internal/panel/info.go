@@ -5,7 +5,6 @@ import (
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 
 	"github.com/pfassina/kopr/internal/theme"
 )
@@ -15,12 +14,19 @@ type InfoGotoLineMsg struct {
 	Line int
 }
 
+// QuickfixSelectedMsg is emitted when the user picks an entry in the search
+// results list. Index is the entry's position in the list.
+type QuickfixSelectedMsg struct {
+	Index int
+}
+
 // InfoItem represents an item in the info panel.
 type InfoItem struct {
 	Title string
 	Path  string
 	Line  int
 	Level int
+	Query string // saved search query; selecting runs it in the finder
 }
 
 // section represents a collapsible section in the info panel.
@@ -29,8 +35,19 @@ type section struct {
 	items     []InfoItem
 	collapsed bool
 	emptyMsg  string
+	hideEmpty bool // omit the section entirely when it has no items
+	hidden    bool // omit the section entirely
 }
 
+// sectionGraph lists the open note's neighbors while the local graph is on.
+const sectionGraph = 3
+
+// Sections that outlive the open note.
+const (
+	sectionResults = 4 // search results sent from the finder
+	sectionSaved   = 5
+)
+
 // flatRowKind distinguishes section headers from items in the flat list.
 type flatRowKind int
 
@@ -51,23 +68,28 @@ type flatRow struct {
 type Info struct {
 	width    int
 	height   int
-	sections [3]section
+	sections [6]section
+	current  int // highlighted search result, -1 for none
 	cursor   int
 	offset   int
 	focused  bool
-	theme    *theme.Theme
+	styles   *theme.StyleSet
 }
 
-// SetTheme sets the color theme for the info panel.
-func (i *Info) SetTheme(th *theme.Theme) { i.theme = th }
+// SetStyles sets the styles the info panel renders with.
+func (i *Info) SetStyles(st *theme.StyleSet) { i.styles = st }
 
 func NewInfo() Info {
 	return Info{
-		sections: [3]section{
+		sections: [6]section{
 			{title: "Backlinks", emptyMsg: "No backlinks"},
 			{title: "Outgoing Links", emptyMsg: "No outgoing links"},
 			{title: "Outline", emptyMsg: "No headings"},
+			{title: "Local Graph", emptyMsg: "No linked notes", hidden: true},
+			{title: "Search Results", hideEmpty: true},
+			{title: "Saved Searches", hideEmpty: true},
 		},
+		current: -1,
 	}
 }
 
@@ -86,8 +108,73 @@ func (i *Info) SetOutline(items []InfoItem) {
 	i.clampCursor()
 }
 
+// SetGraph sets the local graph: the open note's neighbors at Level 1, each
+// followed by its own neighbors at Level 2.
+func (i *Info) SetGraph(items []InfoItem) {
+	i.sections[sectionGraph].items = items
+	i.clampCursor()
+}
+
+// ShowGraph shows or hides the local graph section. Showing it expands it
+// and moves the cursor to its header.
+func (i *Info) ShowGraph(show bool) {
+	sec := &i.sections[sectionGraph]
+	sec.hidden = !show
+	if !show {
+		i.clampCursor()
+		return
+	}
+	sec.collapsed = false
+	for idx, row := range i.flatList() {
+		if row.kind == rowHeader && row.sectionIdx == sectionGraph {
+			i.SetCursor(idx)
+			return
+		}
+	}
+}
+
+// SetSavedSearches sets the saved searches listed below the note sections.
+// They are not tied to the open note, so Clear keeps them.
+func (i *Info) SetSavedSearches(items []InfoItem) {
+	i.sections[sectionSaved].items = items
+	i.clampCursor()
+}
+
+// SetSearchResults replaces the search results list, titled after the query
+// that produced it. Like saved searches it survives Clear. The cursor moves
+// to the first result.
+func (i *Info) SetSearchResults(query string, items []InfoItem) {
+	sec := &i.sections[sectionResults]
+	sec.items = items
+	sec.collapsed = false
+	sec.title = "Search Results"
+	if query != "" {
+		sec.title = fmt.Sprintf("Results for %q", query)
+	}
+	i.current = -1
+	for idx, row := range i.flatList() {
+		if row.kind == rowItem && row.sectionIdx == sectionResults {
+			i.SetCursor(idx)
+			return
+		}
+	}
+	i.clampCursor()
+}
+
+// SetCurrentResult highlights the search result at index, e.g. after ]q
+// jumped to it, and scrolls it into view.
+func (i *Info) SetCurrentResult(index int) {
+	i.current = index
+	for idx, row := range i.flatList() {
+		if row.kind == rowItem && row.sectionIdx == sectionResults && row.itemIdx == index {
+			i.SetCursor(idx)
+			return
+		}
+	}
+}
+
 func (i *Info) Clear() {
-	for idx := range i.sections {
+	for idx := range i.sections[:sectionResults] {
 		i.sections[idx].items = nil
 	}
 	i.cursor = 0
@@ -98,6 +185,9 @@ func (i *Info) Clear() {
 func (i Info) flatList() []flatRow {
 	var rows []flatRow
 	for si := range i.sections {
+		if i.sections[si].hidden || i.sections[si].hideEmpty && len(i.sections[si].items) == 0 {
+			continue
+		}
 		if si > 0 {
 			rows = append(rows, flatRow{kind: rowSeparator})
 		}
@@ -182,19 +272,16 @@ func (i Info) Update(msg tea.Msg) (Info, tea.Cmd) {
 					i.sections[row.sectionIdx].collapsed = !i.sections[row.sectionIdx].collapsed
 					i.clampCursor()
 				} else {
-					item := i.sections[row.sectionIdx].items[row.itemIdx]
-					if item.Path != "" {
-						return i, func() tea.Msg {
-							return FileSelectedMsg{Path: item.Path}
-						}
-					}
-					if item.Line > 0 {
-						return i, func() tea.Msg {
-							return InfoGotoLineMsg{Line: item.Line}
-						}
-					}
+					return i, i.rowCmd(row)
 				}
 			}
+		case "x":
+			// Dismiss the search results list.
+			if i.cursor < len(rows) && rows[i.cursor].sectionIdx == sectionResults {
+				i.sections[sectionResults].items = nil
+				i.current = -1
+				i.clampCursor()
+			}
 		case "G":
 			i.cursor = len(rows) - 1
 			for i.cursor > 0 && rows[i.cursor].kind == rowSeparator {
@@ -254,24 +341,14 @@ func (i Info) View() string {
 		return ""
 	}
 
-	th := i.theme
 	rows := i.flatList()
 
 	var b strings.Builder
 
 	// Panel title
-	var titleStyle lipgloss.Style
+	titleStyle := i.styles.TitleBlurred
 	if i.focused {
-		titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(th.Accent).
-			Underline(true).
-			Padding(0, 1)
-	} else {
-		titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(th.Dim).
-			Padding(0, 1)
+		titleStyle = i.styles.TitleFocused
 	}
 	b.WriteString(titleStyle.Render("Info"))
 	b.WriteByte('\n')
@@ -289,15 +366,14 @@ func (i Info) View() string {
 			b.WriteString(i.renderHeader(row.sectionIdx, idx == i.cursor))
 		case rowItem:
 			item := i.sections[row.sectionIdx].items[row.itemIdx]
-			b.WriteString(i.renderItem(item, row.sectionIdx, idx == i.cursor))
+			b.WriteString(i.renderItem(item, row.sectionIdx, row.itemIdx, idx == i.cursor))
 		}
 		b.WriteByte('\n')
 	}
 
 	// If all sections are empty and nothing rendered, show a dim message.
 	if len(rows) == 0 {
-		dim := lipgloss.NewStyle().Foreground(th.Dim).Padding(0, 1)
-		b.WriteString(dim.Render("No items"))
+		b.WriteString(i.styles.Dim.Padding(0, 1).Render("No items"))
 		b.WriteByte('\n')
 	}
 
@@ -305,7 +381,6 @@ func (i Info) View() string {
 }
 
 func (i Info) renderHeader(sectionIdx int, selected bool) string {
-	th := i.theme
 	sec := i.sections[sectionIdx]
 
 	indicator := "▾"
@@ -322,28 +397,20 @@ func (i Info) renderHeader(sectionIdx int, selected bool) string {
 	}
 
 	if selected && i.focused {
-		return lipgloss.NewStyle().
-			Foreground(th.Accent2).
-			Bold(true).
-			Render(padded)
+		return i.styles.Cursor.Render(padded)
 	}
-
-	style := lipgloss.NewStyle().Bold(true)
 	if i.focused {
-		style = style.Foreground(th.Accent)
-	} else {
-		style = style.Foreground(th.Dim)
+		return i.styles.AccentBold.Render(padded)
 	}
-	return style.Render(padded)
+	return i.styles.DimBold.Render(padded)
 }
 
-func (i Info) renderItem(item InfoItem, sectionIdx int, selected bool) string {
-	th := i.theme
-
+func (i Info) renderItem(item InfoItem, sectionIdx, itemIdx int, selected bool) string {
 	title := item.Title
 	indent := "   "
-	// Outline items get extra indentation by heading level.
-	if sectionIdx == 2 && item.Level > 1 {
+	// Outline items get extra indentation by heading level, graph items by
+	// distance from the note.
+	if (sectionIdx == 2 || sectionIdx == sectionGraph) && item.Level > 1 {
 		indent += strings.Repeat("  ", item.Level-1)
 	}
 
@@ -359,10 +426,10 @@ func (i Info) renderItem(item InfoItem, sectionIdx int, selected bool) string {
 	}
 
 	if selected && i.focused {
-		style := lipgloss.NewStyle().
-			Foreground(th.Accent2).
-			Bold(true)
-		return style.Render(line)
+		return i.styles.Cursor.Render(line)
+	}
+	if sectionIdx == sectionResults && itemIdx == i.current {
+		return i.styles.Accent.Render(line)
 	}
 	return line
 }
@@ -421,15 +488,31 @@ func (i *Info) ActivateRow(idx int) tea.Cmd {
 		i.clampCursor()
 		return nil
 	case rowItem:
-		item := i.sections[row.sectionIdx].items[row.itemIdx]
-		if item.Path != "" {
-			path := item.Path
-			return func() tea.Msg { return FileSelectedMsg{Path: path} }
-		}
-		if item.Line > 0 {
-			line := item.Line
-			return func() tea.Msg { return InfoGotoLineMsg{Line: line} }
-		}
+		return i.rowCmd(row)
+	}
+	return nil
+}
+
+// rowCmd returns the action for an item row. Search results go through the
+// app so ]q/[q continue from the picked entry.
+func (i Info) rowCmd(row flatRow) tea.Cmd {
+	if row.sectionIdx == sectionResults {
+		idx := row.itemIdx
+		return func() tea.Msg { return QuickfixSelectedMsg{Index: idx} }
+	}
+	return itemCmd(i.sections[row.sectionIdx].items[row.itemIdx])
+}
+
+// itemCmd returns the action for selecting an item: run a saved search, open
+// a linked note, or jump to an outline heading.
+func itemCmd(item InfoItem) tea.Cmd {
+	switch {
+	case item.Query != "":
+		return func() tea.Msg { return SavedSearchSelectedMsg{Name: item.Title, Query: item.Query} }
+	case item.Path != "":
+		return func() tea.Msg { return FileSelectedMsg{Path: item.Path} }
+	case item.Line > 0:
+		return func() tea.Msg { return InfoGotoLineMsg{Line: item.Line} }
 	}
 	return nil
 }
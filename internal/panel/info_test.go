@@ -10,8 +10,8 @@ import (
 
 func newTestInfo(backlinks, outgoing, outline []InfoItem) Info {
 	info := NewInfo()
-	th := theme.DefaultTheme()
-	info.SetTheme(&th)
+	st := theme.NewStyleSet(theme.DefaultTheme())
+	info.SetStyles(&st)
 	info.SetSize(40, 20)
 	info.SetFocused(true)
 	info.SetBacklinks(backlinks)
@@ -325,3 +325,100 @@ func TestInfoViewNonEmpty(t *testing.T) {
 		t.Fatal("view should not be empty")
 	}
 }
+
+func TestInfoSavedSearches(t *testing.T) {
+	info := newTestInfo(nil, nil, nil)
+	before := len(info.flatList())
+
+	info.SetSavedSearches([]InfoItem{{Title: "Inbox", Query: "status:inbox"}})
+	rows := info.flatList()
+	// separator + header + item
+	if len(rows) != before+3 {
+		t.Fatalf("expected %d rows, got %d", before+3, len(rows))
+	}
+
+	cmd := info.ActivateRow(len(rows) - 1)
+	if cmd == nil {
+		t.Fatal("expected a command for the saved search row")
+	}
+	msg, ok := cmd().(SavedSearchSelectedMsg)
+	if !ok || msg.Name != "Inbox" || msg.Query != "status:inbox" {
+		t.Errorf("got %#v, want SavedSearchSelectedMsg for Inbox", cmd())
+	}
+
+	// Saved searches are not per-note; Clear keeps them.
+	info.Clear()
+	if len(info.flatList()) != before+3 {
+		t.Error("Clear should keep saved searches")
+	}
+}
+
+func TestInfoSearchResults(t *testing.T) {
+	info := newTestInfo(nil, nil, nil)
+	info.SetSearchResults("todo", []InfoItem{
+		{Title: "a.md:3  todo one", Path: "a.md", Line: 3},
+		{Title: "b.md:7  todo two", Path: "b.md", Line: 7},
+	})
+
+	// The cursor starts on the first result; j moves to the second.
+	info, _ = info.Update(key("j"))
+	info, cmd := info.Update(specialKey(tea.KeyEnter))
+	if cmd == nil {
+		t.Fatal("expected a command for the result row")
+	}
+	if msg, ok := cmd().(QuickfixSelectedMsg); !ok || msg.Index != 1 {
+		t.Errorf("got %#v, want QuickfixSelectedMsg{Index: 1}", cmd())
+	}
+
+	// Results are not per-note; Clear keeps them.
+	info.Clear()
+	if len(info.sections[sectionResults].items) != 2 {
+		t.Error("Clear should keep search results")
+	}
+
+	info.SetCurrentResult(0)
+	info, _ = info.Update(key("x"))
+	if len(info.sections[sectionResults].items) != 0 {
+		t.Error("x should dismiss the search results")
+	}
+}
+
+func TestInfoLocalGraph(t *testing.T) {
+	info := newTestInfo(nil, nil, nil)
+	before := len(info.flatList())
+	info.SetGraph([]InfoItem{
+		{Title: "->  b", Path: "b.md", Level: 1},
+		{Title: "<-  c", Path: "c.md", Level: 2},
+	})
+	if len(info.flatList()) != before {
+		t.Fatal("the graph section should stay hidden until shown")
+	}
+
+	info.ShowGraph(true)
+	rows := info.flatList()
+	if len(rows) != before+4 {
+		t.Fatalf("got %d rows, want separator, header and 2 items more", len(rows))
+	}
+	if row := rows[info.cursor]; row.kind != rowHeader || row.sectionIdx != sectionGraph {
+		t.Fatal("showing the graph should move the cursor to its header")
+	}
+
+	info, _ = info.Update(key("j"))
+	info, _ = info.Update(key("j"))
+	_, cmd := info.Update(specialKey(tea.KeyEnter))
+	if cmd == nil {
+		t.Fatal("expected a command for the graph row")
+	}
+	if msg, ok := cmd().(FileSelectedMsg); !ok || msg.Path != "c.md" {
+		t.Errorf("got %#v, want FileSelectedMsg for c.md", cmd())
+	}
+
+	info.Clear()
+	if len(info.sections[sectionGraph].items) != 0 {
+		t.Error("Clear should drop the graph with the note")
+	}
+	info.ShowGraph(false)
+	if len(info.flatList()) != before {
+		t.Error("hiding the graph should remove its section")
+	}
+}
@@ -0,0 +1,554 @@
+package panel
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/text"
+
+	"github.com/pfassina/kopr/internal/markdown"
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+// Preview shows a styled rendering of the open note, scrolled to follow the
+// editor's cursor line.
+type Preview struct {
+	width    int
+	height   int
+	source   []byte
+	rows     []string
+	lineRows []int // rendered row of each source line, 0-based
+	offset   int
+	line     int // 1-based editor line last synced to
+	focused  bool
+	theme    *theme.Theme
+}
+
+func NewPreview() Preview {
+	return Preview{}
+}
+
+// SetTheme sets the color theme and restyles the rendering.
+func (p *Preview) SetTheme(th *theme.Theme) {
+	p.theme = th
+	p.render()
+}
+
+func (p *Preview) SetSize(width, height int) {
+	resized := width != p.width
+	p.width = width
+	p.height = height
+	if resized {
+		p.render()
+	}
+	p.SyncToLine(p.line)
+}
+
+func (p *Preview) SetFocused(focused bool) {
+	p.focused = focused
+}
+
+// SetContent renders content, keeping the view on the last synced line.
+func (p *Preview) SetContent(content []byte) {
+	p.source = content
+	p.render()
+	p.SyncToLine(p.line)
+}
+
+// Clear empties the preview.
+func (p *Preview) Clear() {
+	p.source = nil
+	p.rows, p.lineRows = nil, nil
+	p.offset = 0
+	p.line = 0
+}
+
+// SyncToLine scrolls so the rendering of the 1-based source line sits a
+// third of the way down the panel.
+func (p *Preview) SyncToLine(line int) {
+	p.line = line
+	if line < 1 || len(p.lineRows) == 0 {
+		return
+	}
+	row := p.lineRows[min(line, len(p.lineRows))-1]
+	p.offset = row - p.viewHeight()/3
+	p.clampOffset()
+}
+
+func (p Preview) viewHeight() int {
+	return max(1, p.height-1) // -1 for the title
+}
+
+func (p *Preview) clampOffset() {
+	p.offset = max(0, min(p.offset, len(p.rows)-p.viewHeight()))
+}
+
+func (p Preview) Update(msg tea.Msg) (Preview, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		half := max(1, p.viewHeight()/2)
+		switch msg.String() {
+		case "j", "down":
+			p.offset++
+		case "k", "up":
+			p.offset--
+		case "ctrl+d":
+			p.offset += half
+		case "ctrl+u":
+			p.offset -= half
+		case "g", "home":
+			p.offset = 0
+		case "G", "end":
+			p.offset = len(p.rows)
+		}
+		p.clampOffset()
+	case tea.MouseMsg:
+		switch msg.Button {
+		case tea.MouseButtonWheelUp:
+			p.offset -= 3
+		case tea.MouseButtonWheelDown:
+			p.offset += 3
+		}
+		p.clampOffset()
+	}
+	return p, nil
+}
+
+func (p Preview) View() string {
+	if p.width == 0 || p.height == 0 {
+		return ""
+	}
+	th := p.theme
+
+	var b strings.Builder
+	var titleStyle lipgloss.Style
+	if p.focused {
+		titleStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(th.Accent).
+			Underline(true).
+			Padding(0, 1)
+	} else {
+		titleStyle = lipgloss.NewStyle().
+			Bold(true).
+			Foreground(th.Dim).
+			Padding(0, 1)
+	}
+	b.WriteString(titleStyle.Render("Preview"))
+	b.WriteByte('\n')
+
+	if len(p.rows) == 0 {
+		dim := lipgloss.NewStyle().Foreground(th.Dim).Padding(0, 1)
+		b.WriteString(dim.Render("No note open"))
+		b.WriteByte('\n')
+		return b.String()
+	}
+	end := min(p.offset+p.viewHeight(), len(p.rows))
+	for _, row := range p.rows[p.offset:end] {
+		b.WriteString(" " + row + "\n")
+	}
+	return b.String()
+}
+
+// render lays out the source for the current width and theme.
+func (p *Preview) render() {
+	if p.theme == nil || p.width < 4 || p.source == nil {
+		p.rows, p.lineRows = nil, nil
+		return
+	}
+	p.rows, p.lineRows = renderMarkdown(p.source, p.width-2, p.theme)
+	p.clampOffset()
+}
+
+// previewWikiScheme marks the markdown links wiki links are rewritten to
+// before parsing, so they can be styled apart from web links.
+const previewWikiScheme = "kopr-wiki:"
+
+// mdRow is one rendered row and the 1-based source line that starts on it,
+// 0 for none.
+type mdRow struct {
+	text string
+	line int
+}
+
+// mdRenderer renders a goldmark AST to styled, wrapped terminal rows.
+type mdRenderer struct {
+	src        []byte
+	lineStarts []int // byte offset of each source line
+	st         previewStyles
+}
+
+type previewStyles struct {
+	text, heading, subheading, code, quote, link, wiki, dim lipgloss.Style
+
+	tokens map[markdown.TokenKind]lipgloss.Style // highlighted code
+}
+
+// renderMarkdown renders source as rows at most width wide. lineRows maps
+// each 0-based source line to the row its block starts on. Frontmatter is
+// hidden and wiki links show as their alias or target.
+func renderMarkdown(source []byte, width int, th *theme.Theme) (rows []string, lineRows []int) {
+	src := blankFrontmatter(source)
+	src = markdown.ReplaceWikiLinks(src, func(target, display string, embed bool) string {
+		link := fmt.Sprintf("[%s](<%s%s>)", display, previewWikiScheme, target)
+		if embed {
+			return "!" + link
+		}
+		return link
+	})
+
+	r := mdRenderer{
+		src:        src,
+		lineStarts: []int{0},
+		st: previewStyles{
+			text:       lipgloss.NewStyle().Foreground(th.Text),
+			heading:    lipgloss.NewStyle().Foreground(th.Accent).Bold(true),
+			subheading: lipgloss.NewStyle().Foreground(th.Accent2).Bold(true),
+			code:       lipgloss.NewStyle().Foreground(th.Subtle),
+			quote:      lipgloss.NewStyle().Foreground(th.Subtle).Italic(true),
+			link:       lipgloss.NewStyle().Foreground(th.Accent2).Underline(true),
+			wiki:       lipgloss.NewStyle().Foreground(th.Accent),
+			dim:        lipgloss.NewStyle().Foreground(th.Dim),
+			tokens: map[markdown.TokenKind]lipgloss.Style{
+				markdown.TokenText:    lipgloss.NewStyle().Foreground(th.Text),
+				markdown.TokenKeyword: lipgloss.NewStyle().Foreground(th.Accent),
+				markdown.TokenString:  lipgloss.NewStyle().Foreground(th.InsertMode),
+				markdown.TokenNumber:  lipgloss.NewStyle().Foreground(th.VisualMode),
+				markdown.TokenComment: lipgloss.NewStyle().Foreground(th.Subtle).Italic(true),
+			},
+		},
+	}
+	for i, c := range src {
+		if c == '\n' {
+			r.lineStarts = append(r.lineStarts, i+1)
+		}
+	}
+
+	md := goldmark.New(goldmark.WithExtensions(extension.GFM))
+	doc := md.Parser().Parse(text.NewReader(src))
+	out := r.blocks(doc, width)
+
+	lineRows = make([]int, len(r.lineStarts))
+	for i := range lineRows {
+		lineRows[i] = -1
+	}
+	for i, row := range out {
+		rows = append(rows, row.text)
+		if row.line > 0 && lineRows[row.line-1] < 0 {
+			lineRows[row.line-1] = i
+		}
+	}
+	// Lines inside a block, or between blocks, take the row of the block
+	// before them.
+	last := 0
+	for i, row := range lineRows {
+		if row < 0 {
+			lineRows[i] = last
+		} else {
+			last = row
+		}
+	}
+	return rows, lineRows
+}
+
+// blankFrontmatter empties the frontmatter's lines, keeping line numbers.
+func blankFrontmatter(content []byte) []byte {
+	fm := markdown.ExtractFrontmatter(content)
+	if fm == nil || fm.EndLine == 0 {
+		return content
+	}
+	lines := bytes.Split(content, []byte("\n"))
+	for i := 0; i < fm.EndLine && i < len(lines); i++ {
+		lines[i] = nil
+	}
+	return bytes.Join(lines, []byte("\n"))
+}
+
+// lineOf returns the 1-based source line n starts on, from its first
+// descendant block with source lines; 0 if none has.
+func (r *mdRenderer) lineOf(n ast.Node) int {
+	for ; n != nil; n = n.FirstChild() {
+		if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+			return r.lineAt(n.Lines().At(0).Start)
+		}
+	}
+	return 0
+}
+
+func (r *mdRenderer) lineAt(offset int) int {
+	return sort.SearchInts(r.lineStarts, offset+1)
+}
+
+// blocks renders the children of n, separated by blank rows except inside
+// tight list items.
+func (r *mdRenderer) blocks(n ast.Node, width int) []mdRow {
+	tight := false
+	if item, ok := n.(*ast.ListItem); ok {
+		if list, ok := item.Parent().(*ast.List); ok {
+			tight = list.IsTight
+		}
+	}
+	var rows []mdRow
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		if c.PreviousSibling() != nil && !tight {
+			rows = append(rows, mdRow{})
+		}
+		rows = append(rows, r.block(c, width)...)
+	}
+	return rows
+}
+
+func (r *mdRenderer) block(n ast.Node, width int) []mdRow {
+	width = max(1, width)
+	line := r.lineOf(n)
+	switch n := n.(type) {
+	case *ast.Heading:
+		style := r.st.heading
+		if n.Level == 1 {
+			style = style.Underline(true)
+		} else if n.Level > 2 {
+			style = r.st.subheading
+		}
+		return r.wrap(r.inline(n, style), width, line)
+
+	case *ast.Paragraph, *ast.TextBlock:
+		return r.wrap(r.inline(n, r.st.text), width, line)
+
+	case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.HTMLBlock:
+		var rows []mdRow
+		lines := n.Lines()
+		styled := r.codeLines(n)
+		for i := range lines.Len() {
+			seg := lines.At(i)
+			wrapped := strings.Split(ansi.Hardwrap(styled[i], max(1, width-2), false), "\n")
+			for j, w := range wrapped {
+				row := mdRow{text: "  " + w}
+				if j == 0 {
+					row.line = r.lineAt(seg.Start)
+				}
+				rows = append(rows, row)
+			}
+		}
+		return rows
+
+	case *ast.Blockquote:
+		bar := r.st.quote.Render("│ ")
+		rows := r.blocks(n, width-2)
+		for i := range rows {
+			rows[i].text = bar + rows[i].text
+		}
+		return rows
+
+	case *ast.List:
+		var rows []mdRow
+		num := n.Start
+		for item := n.FirstChild(); item != nil; item = item.NextSibling() {
+			if item != n.FirstChild() && !n.IsTight {
+				rows = append(rows, mdRow{})
+			}
+			marker := "• "
+			if n.IsOrdered() {
+				marker = fmt.Sprintf("%d. ", num)
+				num++
+			}
+			indent := ansi.StringWidth(marker)
+			body := r.blocks(item, width-indent)
+			if len(body) == 0 {
+				body = []mdRow{{line: r.lineOf(item)}}
+			}
+			for i := range body {
+				prefix := strings.Repeat(" ", indent)
+				if i == 0 {
+					prefix = r.st.dim.Render(marker)
+				}
+				body[i].text = prefix + body[i].text
+			}
+			rows = append(rows, body...)
+		}
+		return rows
+
+	case *ast.ThematicBreak:
+		return []mdRow{{text: r.st.dim.Render(strings.Repeat("─", width)), line: line}}
+
+	case *east.Table:
+		return r.table(n, width)
+	}
+	return r.blocks(n, width)
+}
+
+// codeLines returns the styled lines of a code or HTML block, one per line
+// of n. Fenced code in a language markdown.Highlight knows is colored by
+// token; anything else takes a single muted style.
+func (r *mdRenderer) codeLines(n ast.Node) []string {
+	lines := n.Lines()
+	var b strings.Builder
+	for i := range lines.Len() {
+		seg := lines.At(i)
+		b.WriteString(strings.TrimRight(string(seg.Value(r.src)), "\r\n"))
+		b.WriteByte('\n')
+	}
+	code := strings.ReplaceAll(b.String(), "\t", "    ")
+
+	base, styles := r.st.code, r.st.tokens
+	var toks []markdown.Token
+	switch n := n.(type) {
+	case *ast.HTMLBlock:
+		base = r.st.dim
+	case *ast.FencedCodeBlock:
+		toks = markdown.Highlight(string(n.Language(r.src)), code)
+	}
+	if toks == nil {
+		toks = []markdown.Token{{Kind: markdown.TokenText, Text: code}}
+		styles = map[markdown.TokenKind]lipgloss.Style{markdown.TokenText: base}
+	}
+
+	// code ends with a newline, so one extra row collects nothing.
+	out := make([]string, lines.Len()+1)
+	row := 0
+	for _, t := range toks {
+		for i, part := range strings.Split(t.Text, "\n") {
+			if i > 0 {
+				row++
+			}
+			if part != "" {
+				out[row] += styles[t.Kind].Render(part)
+			}
+		}
+	}
+	return out[:lines.Len()]
+}
+
+// wrap word-wraps styled text to width; the first row starts line.
+func (r *mdRenderer) wrap(s string, width, line int) []mdRow {
+	var rows []mdRow
+	for i, w := range strings.Split(ansi.Wrap(s, width, ""), "\n") {
+		row := mdRow{text: w}
+		if i == 0 {
+			row.line = line
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// inline renders n's inline children in style.
+func (r *mdRenderer) inline(n ast.Node, style lipgloss.Style) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			b.WriteString(style.Render(string(c.Segment.Value(r.src))))
+			if c.HardLineBreak() {
+				b.WriteByte('\n')
+			} else if c.SoftLineBreak() {
+				b.WriteString(style.Render(" "))
+			}
+		case *ast.String:
+			b.WriteString(style.Render(string(c.Value)))
+		case *ast.Emphasis:
+			if c.Level >= 2 {
+				b.WriteString(r.inline(c, style.Bold(true)))
+			} else {
+				b.WriteString(r.inline(c, style.Italic(true)))
+			}
+		case *east.Strikethrough:
+			b.WriteString(r.inline(c, style.Strikethrough(true)))
+		case *ast.CodeSpan:
+			b.WriteString(r.st.code.Render(r.plain(c)))
+		case *ast.Link:
+			if bytes.HasPrefix(c.Destination, []byte(previewWikiScheme)) {
+				b.WriteString(r.inline(c, r.st.wiki))
+			} else {
+				b.WriteString(r.inline(c, r.st.link))
+			}
+		case *ast.AutoLink:
+			b.WriteString(r.st.link.Render(string(c.URL(r.src))))
+		case *ast.Image:
+			b.WriteString(r.st.dim.Render("[" + r.plain(c) + "]"))
+		case *ast.RawHTML:
+			for i := range c.Segments.Len() {
+				seg := c.Segments.At(i)
+				b.WriteString(r.st.dim.Render(string(seg.Value(r.src))))
+			}
+		case *east.TaskCheckBox:
+			box := "[ ] "
+			if c.IsChecked {
+				box = "[x] "
+			}
+			b.WriteString(r.st.dim.Render(box))
+		default:
+			b.WriteString(r.inline(c, style))
+		}
+	}
+	return b.String()
+}
+
+// plain returns the unstyled text of n's inline children.
+func (r *mdRenderer) plain(n ast.Node) string {
+	var b strings.Builder
+	for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+		switch c := c.(type) {
+		case *ast.Text:
+			b.Write(c.Segment.Value(r.src))
+		case *ast.String:
+			b.Write(c.Value)
+		default:
+			b.WriteString(r.plain(c))
+		}
+	}
+	return b.String()
+}
+
+// table renders a GFM table with padded columns, truncated to width.
+func (r *mdRenderer) table(t *east.Table, width int) []mdRow {
+	var cells [][]string
+	var lines []int
+	var widths []int
+	for row := t.FirstChild(); row != nil; row = row.NextSibling() {
+		var cs []string
+		for i, cell := 0, row.FirstChild(); cell != nil; i, cell = i+1, cell.NextSibling() {
+			style := r.st.text
+			if _, ok := row.(*east.TableHeader); ok {
+				style = style.Bold(true)
+			}
+			s := r.inline(cell, style)
+			cs = append(cs, s)
+			if i >= len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], ansi.StringWidth(s))
+		}
+		cells = append(cells, cs)
+		lines = append(lines, r.lineOf(row))
+	}
+
+	sep := r.st.dim.Render(" │ ")
+	var rows []mdRow
+	for i, cs := range cells {
+		var b strings.Builder
+		for j, s := range cs {
+			if j > 0 {
+				b.WriteString(sep)
+			}
+			b.WriteString(s + strings.Repeat(" ", widths[j]-ansi.StringWidth(s)))
+		}
+		rows = append(rows, mdRow{text: ansi.Truncate(b.String(), width, "…"), line: lines[i]})
+		if i == 0 {
+			total := 0
+			for _, w := range widths {
+				total += w + 3
+			}
+			rows = append(rows, mdRow{text: r.st.dim.Render(strings.Repeat("─", min(width, max(0, total-3))))})
+		}
+	}
+	return rows
+}
@@ -0,0 +1,135 @@
+package panel
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+const previewNote = `---
+title: Trip
+---
+# Trip
+
+See [[packing list]] and [[budget#Food|the food budget]], *soon*.
+
+- one
+- [ ] two
+
+> quoted
+
+` + "```" + `
+code line
+` + "```" + `
+
+| a | b |
+|---|---|
+| 1 | 22 |
+`
+
+func TestRenderMarkdown(t *testing.T) {
+	th := theme.DefaultTheme()
+	rows, lineRows := renderMarkdown([]byte(previewNote), 60, &th)
+	plain := make([]string, len(rows))
+	for i, r := range rows {
+		plain[i] = ansi.Strip(r)
+	}
+	want := []string{
+		"Trip",
+		"",
+		"See packing list and the food budget, soon.",
+		"",
+		"• one",
+		"• [ ] two",
+		"",
+		"│ quoted",
+		"",
+		"  code line",
+		"",
+		"a │ b ",
+		"──────",
+		"1 │ 22",
+	}
+	if strings.Join(plain, "\n") != strings.Join(want, "\n") {
+		t.Errorf("rows:\n%s\nwant:\n%s", strings.Join(plain, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Source lines map to the row their block starts on; frontmatter and
+	// blank lines take the row before them.
+	for line, row := range map[int]int{1: 0, 4: 0, 6: 2, 7: 2, 8: 4, 9: 5, 11: 7, 14: 9, 17: 11, 19: 13} {
+		if got := lineRows[line-1]; got != row {
+			t.Errorf("line %d on row %d, want %d", line, got, row)
+		}
+	}
+}
+
+func TestRenderMarkdownWraps(t *testing.T) {
+	th := theme.DefaultTheme()
+	rows, _ := renderMarkdown([]byte(strings.Repeat("word ", 40)), 20, &th)
+	if len(rows) < 10 {
+		t.Fatalf("got %d rows, want the paragraph wrapped", len(rows))
+	}
+	for _, r := range rows {
+		if w := ansi.StringWidth(r); w > 20 {
+			t.Errorf("row %q is %d wide", ansi.Strip(r), w)
+		}
+	}
+}
+
+func TestRenderMarkdownHighlightsCode(t *testing.T) {
+	th := theme.DefaultTheme()
+	rows, lineRows := renderMarkdown([]byte("```go\nfunc f() {}\n/* a\n\tb */\n```\nafter\n"), 40, &th)
+	plain := make([]string, len(rows))
+	for i, r := range rows {
+		plain[i] = ansi.Strip(r)
+	}
+	// A comment spanning lines stays on its own rows.
+	want := []string{"  func f() {}", "  /* a", "      b */", "", "after"}
+	if strings.Join(plain, "\n") != strings.Join(want, "\n") {
+		t.Errorf("rows:\n%s\nwant:\n%s", strings.Join(plain, "\n"), strings.Join(want, "\n"))
+	}
+	if lineRows[3] != 2 {
+		t.Errorf("line 4 on row %d, want 2", lineRows[3])
+	}
+}
+
+func TestPreviewSyncToLine(t *testing.T) {
+	th := theme.DefaultTheme()
+	p := NewPreview()
+	p.SetTheme(&th)
+	p.SetSize(40, 11) // 10 rows below the title
+
+	var b strings.Builder
+	for range 100 {
+		b.WriteString("paragraph\n\n")
+	}
+	p.SetContent([]byte(b.String()))
+
+	// Line 41 is the 21st paragraph, on row 40; a third of the way down.
+	p.SyncToLine(41)
+	if p.offset != 40-10/3 {
+		t.Errorf("offset = %d, want %d", p.offset, 40-10/3)
+	}
+	p.SyncToLine(1)
+	if p.offset != 0 {
+		t.Errorf("offset at top = %d, want 0", p.offset)
+	}
+	p.SyncToLine(1000)
+	if want := len(p.rows) - 10; p.offset != want {
+		t.Errorf("offset past the end = %d, want %d", p.offset, want)
+	}
+
+	// New content keeps following the last synced line.
+	p.SyncToLine(41)
+	p.SetContent([]byte(b.String()))
+	if p.offset != 40-10/3 {
+		t.Errorf("offset after reload = %d, want %d", p.offset, 40-10/3)
+	}
+
+	if p, _ = p.Update(key("G")); p.offset != len(p.rows)-10 {
+		t.Errorf("G: offset = %d, want %d", p.offset, len(p.rows)-10)
+	}
+}
@@ -26,8 +26,10 @@ type Prompt struct {
 	width         int
 	height        int
 	visible       bool
-	confirm       bool // true = yes/no confirm mode
-	confirmCursor int  // 0=Yes, 1=No
+	confirm       bool     // true = pick one of options
+	options       []string // Yes/No, or the choices given to ShowChoices
+	choices       bool     // enter returns the picked option, not "yes"
+	confirmCursor int      // index into options
 	theme         *theme.Theme
 }
 
@@ -56,11 +58,21 @@ func (p *Prompt) Show(title, placeholder string) {
 func (p *Prompt) ShowConfirm(title string) {
 	p.visible = true
 	p.confirm = true
+	p.options = []string{"Yes", "No"}
+	p.choices = false
 	p.confirmCursor = 0
 	p.title = title
 	p.errorMsg = ""
 }
 
+// ShowChoices shows title above a list of options. Enter sends a
+// PromptResultMsg carrying the picked option; Esc cancels.
+func (p *Prompt) ShowChoices(title string, options []string) {
+	p.ShowConfirm(title)
+	p.options = options
+	p.choices = true
+}
+
 func (p *Prompt) Hide() {
 	p.visible = false
 	p.confirm = false
@@ -113,12 +125,16 @@ func (p Prompt) updateConfirm(msg tea.Msg) (Prompt, tea.Cmd) {
 	case tea.KeyMsg:
 		switch msg.String() {
 		case "j", "down":
-			p.confirmCursor = 1
+			p.confirmCursor = min(p.confirmCursor+1, len(p.options)-1)
 		case "k", "up":
-			p.confirmCursor = 0
+			p.confirmCursor = max(p.confirmCursor-1, 0)
 		case "enter":
 			p.visible = false
 			p.confirm = false
+			if p.choices {
+				value := p.options[p.confirmCursor]
+				return p, func() tea.Msg { return PromptResultMsg{Value: value} }
+			}
 			if p.confirmCursor == 0 {
 				return p, func() tea.Msg { return PromptResultMsg{Value: "yes"} }
 			}
@@ -202,10 +218,9 @@ func (p Prompt) viewConfirm() string {
 	dimStyle := lipgloss.NewStyle().
 		Foreground(th.Dim)
 
-	options := [2]string{"Yes", "No"}
 	var lines []string
 	lines = append(lines, titleStyle.Render(p.title))
-	for i, opt := range options {
+	for i, opt := range p.options {
 		if i == p.confirmCursor {
 			lines = append(lines, accentStyle.Render("> "+opt))
 		} else {
@@ -0,0 +1,208 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+// ReviewAction is something to do with a stale note.
+type ReviewAction int
+
+const (
+	ReviewUpdate ReviewAction = iota // open the note to bring it up to date
+	ReviewArchive
+	ReviewSnooze // mark it reviewed as it is
+)
+
+// ReviewActionMsg is sent when the user picks an action for the note at
+// Path.
+type ReviewActionMsg struct {
+	Action ReviewAction
+	Path   string
+}
+
+// ReviewNote is a stale note in the review queue.
+type ReviewNote struct {
+	Path    string
+	Title   string
+	Touched time.Time // last modified or reviewed
+	Content string
+}
+
+// Review is an overlay that steps through notes due for review one at a
+// time, showing each one's content, with keys to update, archive or
+// snooze it.
+type Review struct {
+	notes   []ReviewNote
+	cursor  int
+	scroll  int // first content line shown
+	now     func() time.Time
+	width   int
+	height  int
+	visible bool
+	theme   *theme.Theme
+}
+
+// SetTheme sets the color theme for the review overlay.
+func (r *Review) SetTheme(th *theme.Theme) { r.theme = th }
+
+func NewReview() Review {
+	return Review{now: time.Now}
+}
+
+// Show opens the overlay on the first of notes.
+func (r *Review) Show(notes []ReviewNote) {
+	r.notes = notes
+	r.cursor = 0
+	r.scroll = 0
+	r.visible = true
+}
+
+func (r *Review) Hide() {
+	r.visible = false
+}
+
+func (r Review) Visible() bool {
+	return r.visible
+}
+
+func (r *Review) SetSize(width, height int) {
+	r.width = width
+	r.height = height
+}
+
+// Len returns the number of notes left to review.
+func (r Review) Len() int {
+	return len(r.notes)
+}
+
+// Remove drops a reviewed note from the queue. The next note takes its
+// place.
+func (r *Review) Remove(path string) {
+	for i, n := range r.notes {
+		if n.Path == path {
+			r.notes = append(r.notes[:i], r.notes[i+1:]...)
+			if r.cursor > i || r.cursor >= len(r.notes) {
+				r.cursor = max(0, r.cursor-1)
+			}
+			r.scroll = 0
+			return
+		}
+	}
+}
+
+func (r Review) Update(msg tea.Msg) (Review, tea.Cmd) {
+	if !r.visible || len(r.notes) == 0 {
+		return r, nil
+	}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return r, nil
+	}
+	path := r.notes[r.cursor].Path
+	action := func(a ReviewAction) tea.Cmd {
+		return func() tea.Msg { return ReviewActionMsg{Action: a, Path: path} }
+	}
+
+	switch keyMsg.String() {
+	case "esc", "q":
+		r.visible = false
+	case "u", "e", "enter":
+		r.visible = false
+		return r, action(ReviewUpdate)
+	case "a":
+		return r, action(ReviewArchive)
+	case "s":
+		return r, action(ReviewSnooze)
+	case "n", "j", "l", "right":
+		r.step(1)
+	case "N", "k", "h", "left":
+		r.step(-1)
+	case "ctrl+d", "J":
+		r.scroll = max(0, min(r.scroll+r.bodyHeight()/2, len(r.contentLines())-r.bodyHeight()))
+	case "ctrl+u", "K":
+		r.scroll = max(0, r.scroll-r.bodyHeight()/2)
+	}
+	return r, nil
+}
+
+// step moves to the note delta places away, wrapping around.
+func (r *Review) step(delta int) {
+	r.cursor = ((r.cursor+delta)%len(r.notes) + len(r.notes)) % len(r.notes)
+	r.scroll = 0
+}
+
+// innerWidth is the width of the overlay's content.
+func (r Review) innerWidth() int {
+	return min(max(r.width*7/10, 50), r.width-2) - 4
+}
+
+// bodyHeight is the number of content lines that fit in the overlay.
+func (r Review) bodyHeight() int {
+	// border (2) + title + path + blank + blank + footer
+	return max(r.height*4/5-7, 3)
+}
+
+// contentLines returns the current note's content wrapped to the overlay.
+func (r Review) contentLines() []string {
+	if r.cursor >= len(r.notes) {
+		return nil
+	}
+	content := strings.ReplaceAll(strings.TrimRight(r.notes[r.cursor].Content, "\n"), "\t", "    ")
+	return strings.Split(ansi.Wrap(content, r.innerWidth(), ""), "\n")
+}
+
+// age describes how long ago the note was last touched.
+func (r Review) age(touched time.Time) string {
+	days := int(r.now().Sub(touched).Hours() / 24)
+	if days == 1 {
+		return "untouched for 1 day"
+	}
+	return fmt.Sprintf("untouched for %d days", days)
+}
+
+func (r Review) View() string {
+	if !r.visible || len(r.notes) == 0 {
+		return ""
+	}
+
+	th := r.theme
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Accent)
+	dim := lipgloss.NewStyle().Foreground(th.Dim)
+	text := lipgloss.NewStyle().Foreground(th.Text)
+	width := r.innerWidth()
+	note := r.notes[r.cursor]
+
+	lines := []string{
+		titleStyle.Render(ansi.Truncate(fmt.Sprintf("Review %d/%d: %s", r.cursor+1, len(r.notes), note.Title), width, "…")),
+		dim.Render(ansi.Truncate(note.Path+", "+r.age(note.Touched), width, "…")),
+		"",
+	}
+	content := r.contentLines()
+	rows := r.bodyHeight()
+	end := min(r.scroll+rows, len(content))
+	for _, l := range content[min(r.scroll, end):end] {
+		lines = append(lines, text.Render(l))
+	}
+	for i := end - r.scroll; i < rows; i++ {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "")
+	lines = append(lines, dim.Render(ansi.Truncate(
+		"u: update  a: archive  s: snooze  n/N: next/prev  esc: close", width, "…")))
+
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(th.Accent).
+		Padding(0, 1).
+		Width(width + 2)
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
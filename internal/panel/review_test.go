@@ -0,0 +1,48 @@
+package panel
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+func TestReviewActions(t *testing.T) {
+	th := theme.DefaultTheme()
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	r := NewReview()
+	r.now = func() time.Time { return now }
+	r.SetTheme(&th)
+	r.SetSize(100, 30)
+	r.Show([]ReviewNote{
+		{Path: "a.md", Title: "A", Touched: now.AddDate(0, 0, -120), Content: "old plan"},
+		{Path: "b.md", Title: "B", Touched: now.AddDate(0, 0, -95), Content: "old idea"},
+	})
+
+	view := r.View()
+	for _, want := range []string{"Review 1/2: A", "untouched for 120 days", "old plan"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("view should contain %q:\n%s", want, view)
+		}
+	}
+
+	r, cmd := r.Update(key("s"))
+	if msg, ok := cmd().(ReviewActionMsg); !ok || msg.Action != ReviewSnooze || msg.Path != "a.md" {
+		t.Fatalf("s sent %#v, want snooze of a.md", cmd())
+	}
+
+	// A reviewed note makes way for the next one.
+	r.Remove("a.md")
+	if r.Len() != 1 || !strings.Contains(r.View(), "old idea") {
+		t.Errorf("after Remove, view should show the second note:\n%s", r.View())
+	}
+
+	r, cmd = r.Update(key("u"))
+	if msg, ok := cmd().(ReviewActionMsg); !ok || msg.Action != ReviewUpdate || msg.Path != "b.md" {
+		t.Errorf("u sent %#v, want update of b.md", cmd())
+	}
+	if r.Visible() {
+		t.Error("updating a note should close the review to edit it")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
 
 	"github.com/pfassina/kopr/internal/theme"
 )
@@ -14,14 +15,27 @@ type Status struct {
 	width     int
 	mode      string
 	file      string
+	modified  bool // the file has unsaved changes
 	vaultDir  string
 	clipboard string
+	progress  string // background work on the right, e.g. indexing
+	notice    string // persistent note on the right, e.g. an available update
 	errMsg    string
-	theme     *theme.Theme
+	message   string // informational; cleared when the file changes
+
+	// Neovim's command line and last message, with the UI protocol editor
+	// backend. The command line replaces everything left of the right side
+	// while shown.
+	cmdline       string
+	cmdlineCursor int
+	editorMsg     string
+	editorErr     bool
+
+	styles *theme.StyleSet
 }
 
-// SetTheme sets the color theme for the status bar.
-func (s *Status) SetTheme(th *theme.Theme) { s.theme = th }
+// SetStyles sets the styles the status bar renders with.
+func (s *Status) SetStyles(st *theme.StyleSet) { s.styles = st }
 
 func NewStatus(vaultDir string) Status {
 	return Status{
@@ -36,6 +50,14 @@ func (s *Status) SetMode(mode string) {
 
 func (s *Status) SetFile(file string) {
 	s.file = file
+	s.message = ""
+	s.editorMsg, s.editorErr = "", false
+}
+
+// SetModified marks the file as having unsaved changes, shown as a dot
+// after its name.
+func (s *Status) SetModified(modified bool) {
+	s.modified = modified
 }
 
 func (s *Status) SetWidth(width int) {
@@ -46,6 +68,17 @@ func (s *Status) SetClipboard(label string) {
 	s.clipboard = label
 }
 
+// SetProgress shows the state of background work on the right of the bar.
+// Empty hides it.
+func (s *Status) SetProgress(text string) {
+	s.progress = text
+}
+
+// SetNotice shows a message on the right of the bar until replaced.
+func (s *Status) SetNotice(msg string) {
+	s.notice = msg
+}
+
 func (s *Status) SetError(msg string) {
 	s.errMsg = msg
 }
@@ -54,73 +87,94 @@ func (s *Status) ClearError() {
 	s.errMsg = ""
 }
 
-func (s Status) View() string {
-	if s.width == 0 {
-		return ""
-	}
-
-	th := s.theme
+// SetMessage shows an informational message in place of the file name.
+func (s *Status) SetMessage(msg string) {
+	s.message = msg
+}
 
-	bgStyle := lipgloss.NewStyle().
-		Background(th.StatusBg)
+// SetCmdline shows Neovim's command line with the cursor at column cursor.
+// Empty hides it.
+func (s *Status) SetCmdline(text string, cursor int) {
+	s.cmdline, s.cmdlineCursor = text, cursor
+}
 
-	modeColors := map[string]lipgloss.Color{
-		"NORMAL":  th.NormalMode,
-		"INSERT":  th.InsertMode,
-		"VISUAL":  th.VisualMode,
-		"COMMAND": th.CmdMode,
-		"REPLACE": th.Error,
-	}
+// SetEditorMessage shows a message from Neovim in place of the file name,
+// styled as an error when isErr. Empty clears it.
+func (s *Status) SetEditorMessage(msg string, isErr bool) {
+	s.editorMsg, s.editorErr = msg, isErr
+}
 
-	color, ok := modeColors[s.mode]
-	if !ok {
-		color = th.Text
+func (s Status) View() string {
+	if s.width == 0 {
+		return ""
 	}
 
-	modeStyle := lipgloss.NewStyle().
-		Background(color).
-		Foreground(lipgloss.Color("0")).
-		Bold(true).
-		Padding(0, 1)
-
-	fileStyle := lipgloss.NewStyle().
-		Background(th.StatusBg).
-		Foreground(th.StatusFg).
-		Padding(0, 1)
-
-	mode := modeStyle.Render(s.mode)
+	st := s.styles
+	fileStyle := st.StatusText
+	mode := st.Mode(s.mode).Render(s.mode)
 
 	var fileSection string
-	if s.errMsg != "" {
-		errStyle := lipgloss.NewStyle().
-			Background(th.StatusBg).
-			Foreground(th.Error).
-			Padding(0, 1)
-		fileSection = errStyle.Render(s.errMsg)
+	if s.cmdline != "" {
+		// One space after the mode badge and the section's padding.
+		fileSection = s.cmdlineView(st, s.width-lipgloss.Width(mode)-3)
+	} else if s.editorMsg != "" && s.editorErr {
+		fileSection = st.StatusError.Render(s.editorMsg)
+	} else if s.editorMsg != "" {
+		fileSection = fileStyle.Render(s.editorMsg)
+	} else if s.errMsg != "" {
+		fileSection = st.StatusError.Render(s.errMsg)
+	} else if s.message != "" {
+		fileSection = fileStyle.Render(s.message)
 	} else {
 		file := s.file
 		if file == "" {
 			file = s.vaultDir
+		} else if s.modified {
+			file += " ●"
 		}
 		fileSection = fileStyle.Render(file)
 	}
 
 	left := fmt.Sprintf("%s %s", mode, fileSection)
 
+	// While the command line is typed, the right side waits.
 	right := ""
-	if s.clipboard != "" {
-		clipStyle := lipgloss.NewStyle().
-			Background(th.StatusBg).
-			Foreground(th.StatusFg).
-			Padding(0, 1)
-		right = clipStyle.Render(s.clipboard)
+	if s.cmdline == "" {
+		if s.progress != "" {
+			right = st.StatusText.Render(s.progress)
+		}
+		if s.notice != "" {
+			right += st.StatusNotice.Render(s.notice)
+		}
+		if s.clipboard != "" {
+			right += st.StatusText.Render(s.clipboard)
+		}
 	}
 
 	padLen := s.width - lipgloss.Width(left) - lipgloss.Width(right)
 	if padLen < 0 {
 		padLen = 0
 	}
-	padding := bgStyle.Render(strings.Repeat(" ", padLen))
+	padding := st.StatusBar.Render(strings.Repeat(" ", padLen))
 
 	return left + padding + right
 }
+
+// cmdlineView renders the command line in avail cells with a reverse-video
+// cursor, keeping the cursor in view when the line is wider.
+func (s Status) cmdlineView(st *theme.StyleSet, avail int) string {
+	avail = max(avail, 1)
+	text := s.cmdline + " "
+	cursor := min(max(s.cmdlineCursor, 0), ansi.StringWidth(text)-1)
+	if cut := cursor + 1 - avail; cut > 0 {
+		text = ansi.TruncateLeft(text, cut, "")
+		cursor -= cut
+	}
+	text = ansi.Truncate(text, avail, "")
+
+	// The section's padding goes around the whole line, not each piece.
+	plain := st.StatusText.UnsetPadding()
+	return plain.Render(" "+ansi.Truncate(text, cursor, "")) +
+		plain.Reverse(true).Render(ansi.Cut(text, cursor, cursor+1)) +
+		plain.Render(ansi.TruncateLeft(text, cursor+1, "")+" ")
+}
@@ -0,0 +1,230 @@
+package panel
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/theme"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+// TrashRestoreMsg is sent when the user asks to move Item back to where it
+// was deleted from.
+type TrashRestoreMsg struct {
+	Item vault.TrashItem
+}
+
+// TrashPurgeMsg is sent when the user asks to delete Items for good.
+type TrashPurgeMsg struct {
+	Items []vault.TrashItem
+}
+
+// TrashClosedMsg is sent when the trash overlay is dismissed.
+type TrashClosedMsg struct{}
+
+// TrashPreviewFunc returns the content of a note in the trash.
+type TrashPreviewFunc func(item vault.TrashItem) string
+
+// Trash is an overlay listing deleted notes, newest first, with the
+// selected note's content beside them. It stays open while notes are
+// restored or purged; the app refreshes it with SetItems.
+type Trash struct {
+	items         []vault.TrashItem
+	cursor        int
+	scroll        int // first item shown
+	preview       []string
+	previewScroll int
+	previewFn     TrashPreviewFunc
+	width         int
+	height        int
+	visible       bool
+	theme         *theme.Theme
+}
+
+// SetTheme sets the color theme for the trash overlay.
+func (t *Trash) SetTheme(th *theme.Theme) { t.theme = th }
+
+func NewTrash() Trash {
+	return Trash{}
+}
+
+// Show opens the overlay on the most recently deleted note.
+func (t *Trash) Show(items []vault.TrashItem, previewFn TrashPreviewFunc) {
+	t.previewFn = previewFn
+	t.cursor = 0
+	t.scroll = 0
+	t.visible = true
+	t.SetItems(items)
+}
+
+// SetItems replaces the listed notes after a restore or purge, keeping the
+// cursor in place where it can.
+func (t *Trash) SetItems(items []vault.TrashItem) {
+	t.items = items
+	t.cursor = max(0, min(t.cursor, len(items)-1))
+	t.scroll = max(0, min(t.scroll, t.cursor))
+	t.loadPreview()
+}
+
+func (t *Trash) Hide() {
+	t.visible = false
+}
+
+func (t Trash) Visible() bool {
+	return t.visible
+}
+
+func (t *Trash) SetSize(width, height int) {
+	t.width = width
+	t.height = height
+}
+
+func (t *Trash) loadPreview() {
+	t.preview = nil
+	t.previewScroll = 0
+	if t.previewFn == nil || t.cursor >= len(t.items) {
+		return
+	}
+	t.preview = strings.Split(strings.TrimRight(t.previewFn(t.items[t.cursor]), "\n"), "\n")
+}
+
+func (t Trash) Update(msg tea.Msg) (Trash, tea.Cmd) {
+	if !t.visible {
+		return t, nil
+	}
+
+	key, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return t, nil
+	}
+	half := max(1, t.bodyHeight()/2)
+	switch key.String() {
+	case "esc", "q":
+		t.visible = false
+		return t, func() tea.Msg { return TrashClosedMsg{} }
+	case "r", "enter":
+		if t.cursor < len(t.items) {
+			item := t.items[t.cursor]
+			return t, func() tea.Msg { return TrashRestoreMsg{Item: item} }
+		}
+	case "d":
+		if t.cursor < len(t.items) {
+			item := t.items[t.cursor]
+			return t, func() tea.Msg { return TrashPurgeMsg{Items: []vault.TrashItem{item}} }
+		}
+	case "D":
+		if len(t.items) > 0 {
+			items := t.items
+			return t, func() tea.Msg { return TrashPurgeMsg{Items: items} }
+		}
+	case "j", "down":
+		t.moveCursor(1)
+	case "k", "up":
+		t.moveCursor(-1)
+	case "g", "home":
+		t.moveCursor(-len(t.items))
+	case "G", "end":
+		t.moveCursor(len(t.items))
+	case "ctrl+d", "J":
+		t.scrollPreview(half)
+	case "ctrl+u", "K":
+		t.scrollPreview(-half)
+	}
+	return t, nil
+}
+
+// moveCursor selects the note delta rows away and shows its content.
+func (t *Trash) moveCursor(delta int) {
+	cursor := max(0, min(t.cursor+delta, len(t.items)-1))
+	if cursor == t.cursor {
+		return
+	}
+	t.cursor = cursor
+	rows := t.bodyHeight()
+	if t.cursor < t.scroll {
+		t.scroll = t.cursor
+	} else if t.cursor >= t.scroll+rows {
+		t.scroll = t.cursor - rows + 1
+	}
+	t.loadPreview()
+}
+
+func (t *Trash) scrollPreview(delta int) {
+	t.previewScroll = max(0, min(t.previewScroll+delta, len(t.preview)-t.bodyHeight()))
+}
+
+// bodyHeight is the number of note and preview rows that fit in the overlay.
+func (t Trash) bodyHeight() int {
+	// border (2) + title + blank + blank + footer
+	return max(t.height*4/5-6, 5)
+}
+
+func (t Trash) View() string {
+	if !t.visible {
+		return ""
+	}
+
+	th := t.theme
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Accent)
+	dim := lipgloss.NewStyle().Foreground(th.Dim)
+	text := lipgloss.NewStyle().Foreground(th.Text)
+	selected := lipgloss.NewStyle().Foreground(th.Accent).Bold(true)
+
+	innerWidth := min(max(t.width*9/10, 60), t.width-2) - 4
+	leftWidth := max(innerWidth*40/100, 24)
+	rightWidth := innerWidth - leftWidth - 1 // -1 for the separator
+	rows := t.bodyHeight()
+
+	var left []string
+	if len(t.items) == 0 {
+		left = append(left, dim.Render("  The trash is empty"))
+	}
+	end := min(t.scroll+rows, len(t.items))
+	for i := t.scroll; i < end; i++ {
+		item := t.items[i]
+		prefix, style := "  ", text
+		if i == t.cursor {
+			prefix, style = "> ", selected
+		}
+		line := dim.Render(item.Deleted.Format("2006-01-02 15:04")+" ") + style.Render(item.Path)
+		left = append(left, ansi.Truncate(style.Render(prefix)+line, leftWidth, "…"))
+	}
+	for len(left) < rows {
+		left = append(left, "")
+	}
+
+	var right []string
+	previewEnd := min(t.previewScroll+rows, len(t.preview))
+	for _, l := range t.preview[min(t.previewScroll, previewEnd):previewEnd] {
+		l = strings.ReplaceAll(l, "\t", "    ")
+		right = append(right, text.Render(ansi.Truncate(l, rightWidth-1, "…")))
+	}
+	for len(right) < rows {
+		right = append(right, "")
+	}
+
+	leftCol := lipgloss.NewStyle().Width(leftWidth).Render(strings.Join(left, "\n"))
+	rightCol := lipgloss.NewStyle().
+		Width(rightWidth).
+		BorderLeft(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(th.Border).
+		PaddingLeft(1).
+		Render(strings.Join(right, "\n"))
+
+	lines := []string{titleStyle.Render("Trash"), ""}
+	lines = append(lines, lipgloss.JoinHorizontal(lipgloss.Top, leftCol, rightCol))
+	lines = append(lines, "")
+	lines = append(lines, dim.Render("j/k: note  ctrl+d/u: scroll  r: restore  d: purge  D: purge all  esc: close"))
+
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(th.Accent).
+		Padding(0, 1).
+		Width(innerWidth + 2)
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
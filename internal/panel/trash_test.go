@@ -0,0 +1,76 @@
+package panel
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/theme"
+	"github.com/pfassina/kopr/internal/vault"
+)
+
+func TestTrashRestoresAndPurges(t *testing.T) {
+	th := theme.DefaultTheme()
+	tr := NewTrash()
+	tr.SetTheme(&th)
+	tr.SetSize(120, 40)
+
+	now := time.Now()
+	items := []vault.TrashItem{
+		{Path: "b.md", Trashed: ".kopr/trash/2/b.md", Deleted: now},
+		{Path: "a.md", Trashed: ".kopr/trash/1/a.md", Deleted: now.Add(-time.Hour)},
+	}
+	tr.Show(items, func(item vault.TrashItem) string {
+		return "content of " + item.Path
+	})
+	if view := tr.View(); !strings.Contains(view, "content of b.md") {
+		t.Errorf("view should show the newest note's content:\n%s", view)
+	}
+
+	key := func(k string) tea.Msg {
+		t.Helper()
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		if k == "esc" {
+			msg = tea.KeyMsg{Type: tea.KeyEsc}
+		}
+		var cmd tea.Cmd
+		tr, cmd = tr.Update(msg)
+		if cmd == nil {
+			return nil
+		}
+		return cmd()
+	}
+	key("j")
+	if view := tr.View(); !strings.Contains(view, "content of a.md") {
+		t.Errorf("view should show the selected note's content:\n%s", view)
+	}
+	if msg, ok := key("r").(TrashRestoreMsg); !ok || msg.Item.Path != "a.md" {
+		t.Errorf("r = %#v, want a restore of a.md", msg)
+	}
+	if !tr.Visible() {
+		t.Error("trash should stay open after a restore")
+	}
+
+	// The restored note leaves the list; the cursor stays on a note.
+	tr.SetItems(items[:1])
+	if msg, ok := key("d").(TrashPurgeMsg); !ok || len(msg.Items) != 1 || msg.Items[0].Path != "b.md" {
+		t.Errorf("d = %#v, want a purge of b.md", msg)
+	}
+	tr.SetItems(items)
+	if msg, ok := key("D").(TrashPurgeMsg); !ok || len(msg.Items) != 2 {
+		t.Errorf("D = %#v, want a purge of both notes", msg)
+	}
+
+	tr.SetItems(nil)
+	if !strings.Contains(tr.View(), "The trash is empty") {
+		t.Errorf("empty trash view:\n%s", tr.View())
+	}
+	if msg := key("r"); msg != nil {
+		t.Errorf("r in an empty trash = %#v", msg)
+	}
+	if _, ok := key("esc").(TrashClosedMsg); !ok || tr.Visible() {
+		t.Error("esc should close the trash")
+	}
+}
@@ -3,6 +3,7 @@ package panel
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -52,6 +53,11 @@ type TreeRenameNoteMsg struct {
 	Name string
 }
 
+// TreeDuplicateNoteMsg is sent when the user presses 'D' to duplicate a note.
+type TreeDuplicateNoteMsg struct {
+	Path string
+}
+
 // TreePasteMsg is sent when the user presses 'p' to paste.
 type TreePasteMsg struct {
 	Op      ClipboardOp
@@ -79,7 +85,7 @@ type Tree struct {
 	height     int
 	focused    bool
 	showHelp   bool
-	theme      *theme.Theme
+	styles     *theme.StyleSet
 }
 
 func NewTree(v *vault.Vault) Tree {
@@ -90,14 +96,18 @@ func NewTree(v *vault.Vault) Tree {
 	}
 }
 
-// SetTheme sets the color theme for the tree panel.
-func (t *Tree) SetTheme(th *theme.Theme) { t.theme = th }
+// SetStyles sets the styles the tree panel renders with.
+func (t *Tree) SetStyles(st *theme.StyleSet) { t.styles = st }
 
 func (t *Tree) Refresh() {
 	entries, err := t.vault.ListEntries()
 	if err != nil {
 		entries = nil
 	}
+	// External sources come last, under their own "External" directory.
+	if external, err := t.vault.ExternalEntries(); err == nil {
+		entries = append(entries, external...)
+	}
 	t.allEntries = entries
 	t.rebuildVisible()
 	t.pruneStale()
@@ -199,6 +209,16 @@ func (t *Tree) ClearClipboard() {
 	t.clipboard = Clipboard{}
 }
 
+// Selected returns the selected paths, sorted.
+func (t *Tree) Selected() []string {
+	paths := make([]string, 0, len(t.selected))
+	for p := range t.selected {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
 // ClearSelected resets selection state.
 func (t *Tree) ClearSelected() {
 	t.selected = make(map[string]bool)
@@ -344,6 +364,15 @@ func (t Tree) Update(msg tea.Msg) (Tree, tea.Cmd) {
 					}
 				}
 			}
+		case "D":
+			if t.cursor < len(t.entries) {
+				entry := t.entries[t.cursor]
+				if !entry.IsDir {
+					return t, func() tea.Msg {
+						return TreeDuplicateNoteMsg{Path: entry.Path}
+					}
+				}
+			}
 		case "?":
 			t.showHelp = !t.showHelp
 		}
@@ -357,20 +386,11 @@ func (t Tree) View() string {
 		return ""
 	}
 
-	th := t.theme
+	st := t.styles
 
-	var titleStyle lipgloss.Style
+	titleStyle := st.TitleBlurred
 	if t.focused {
-		titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(th.Accent).
-			Underline(true).
-			Padding(0, 1)
-	} else {
-		titleStyle = lipgloss.NewStyle().
-			Bold(true).
-			Foreground(th.Dim).
-			Padding(0, 1)
+		titleStyle = st.TitleFocused
 	}
 
 	var b strings.Builder
@@ -378,8 +398,7 @@ func (t Tree) View() string {
 	// Title row with optional ? hint
 	title := titleStyle.Render("Files")
 	if t.focused && !t.showHelp {
-		hintStyle := lipgloss.NewStyle().Foreground(th.Dim)
-		hint := hintStyle.Render("?")
+		hint := st.Dim.Render("?")
 		titleWidth := lipgloss.Width(title)
 		hintWidth := lipgloss.Width(hint)
 		gap := t.width - 2 - titleWidth - hintWidth
@@ -410,9 +429,9 @@ func (t Tree) View() string {
 		}
 	}
 
-	markerSelected := lipgloss.NewStyle().Foreground(th.Accent).Render("\u258e")
-	markerYanked := lipgloss.NewStyle().Foreground(th.Accent).Render("\u258e")
-	markerCut := lipgloss.NewStyle().Foreground(th.Dim).Render("\u258e")
+	markerSelected := st.Accent.Render("\u258e")
+	markerYanked := st.Accent.Render("\u258e")
+	markerCut := st.Dim.Render("\u258e")
 
 	for i := t.offset; i < len(t.entries) && i-t.offset < viewHeight; i++ {
 		entry := t.entries[i]
@@ -455,10 +474,7 @@ func (t Tree) View() string {
 		}
 
 		if i == t.cursor && t.focused {
-			style := lipgloss.NewStyle().
-				Foreground(th.Accent2).
-				Bold(true)
-			b.WriteString(marker + style.Render(line))
+			b.WriteString(marker + st.Cursor.Render(line))
 		} else {
 			b.WriteString(marker + line)
 		}
@@ -483,14 +499,9 @@ func (t Tree) isInClipboard(path string) bool {
 }
 
 func (t Tree) renderHelp() string {
-	th := t.theme
-	dim := lipgloss.NewStyle().Foreground(th.Dim)
-	key := lipgloss.NewStyle().Foreground(th.Accent).Bold(true)
-	border := lipgloss.NewStyle().
-		Border(lipgloss.RoundedBorder()).
-		BorderForeground(th.Border).
-		Padding(0, 1).
-		Width(t.width - 6)
+	dim := t.styles.Dim
+	key := t.styles.AccentBold
+	border := t.styles.HelpBox.Width(t.width - 6)
 
 	lines := []struct{ k, v string }{
 		{"j/k", "Navigate"},
@@ -503,6 +514,7 @@ func (t Tree) renderHelp() string {
 		{"p", "Paste"},
 		{"d", "Delete"},
 		{"r", "Rename note"},
+		{"D", "Duplicate note"},
 		{"g/G", "Top / Bottom"},
 		{"?", "Toggle help"},
 	}
@@ -547,6 +559,22 @@ func (t *Tree) ToggleCollapse(path string) {
 	t.rebuildVisible()
 }
 
+// Reveal expands the ancestors of path and moves the cursor to it. It reports
+// whether path is in the tree.
+func (t *Tree) Reveal(path string) bool {
+	for dir := filepath.Dir(path); dir != "."; dir = filepath.Dir(dir) {
+		delete(t.collapsed, dir)
+	}
+	t.rebuildVisible()
+	for i, e := range t.entries {
+		if e.Path == path {
+			t.SetCursor(i)
+			return true
+		}
+	}
+	return false
+}
+
 // SetCursor moves the cursor to the given index with bounds checking.
 func (t *Tree) SetCursor(idx int) {
 	if idx < 0 {
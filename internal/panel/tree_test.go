@@ -1,9 +1,14 @@
 package panel
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
 	"testing"
 
 	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/vault"
 )
 
 func TestTree_GKey_EmptyEntries(t *testing.T) {
@@ -53,3 +58,54 @@ func TestTree_JKey_EmptyEntries(t *testing.T) {
 		t.Errorf("cursor = %d after j on empty tree, want 0", result.cursor)
 	}
 }
+
+func TestTree_Reveal(t *testing.T) {
+	tr := Tree{
+		height: 20,
+		width:  30,
+		allEntries: []vault.Entry{
+			{Path: "a.md"},
+			{Path: "projects", IsDir: true},
+			{Path: "projects/kopr", IsDir: true},
+			{Path: "projects/kopr/design.md"},
+		},
+		collapsed: map[string]bool{"projects": true, "projects/kopr": true},
+		selected:  map[string]bool{},
+	}
+	tr.rebuildVisible()
+
+	if !tr.Reveal("projects/kopr/design.md") {
+		t.Fatal("Reveal should find a note inside collapsed folders")
+	}
+	if tr.collapsed["projects"] || tr.collapsed["projects/kopr"] {
+		t.Error("ancestors should be expanded")
+	}
+	if e, _ := tr.EntryAt(tr.cursor); e.Path != "projects/kopr/design.md" {
+		t.Errorf("cursor on %q, want the revealed note", e.Path)
+	}
+	if tr.Reveal("missing.md") {
+		t.Error("Reveal should report notes not in the tree")
+	}
+}
+
+func TestTree_RefreshListsExternalSourcesLast(t *testing.T) {
+	root, src := t.TempDir(), t.TempDir()
+	for _, p := range []string{filepath.Join(root, "zettel.md"), filepath.Join(src, "guide.md")} {
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	v := vault.New(root)
+	v.Sources = vault.Sources{{Name: "work", Root: src}}
+	tr := NewTree(v)
+	tr.Refresh()
+
+	var got []string
+	for _, e := range tr.entries {
+		got = append(got, filepath.ToSlash(e.Path))
+	}
+	want := []string{"zettel.md", "@external", "@external/work", "@external/work/guide.md"}
+	if !slices.Equal(got, want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
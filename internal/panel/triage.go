@@ -0,0 +1,228 @@
+package panel
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+// TriageAction is something to do with an inbox note.
+type TriageAction int
+
+const (
+	TriageArchive TriageAction = iota
+	TriageMove
+	TriageTag
+	TriageProject
+	TriageDelete
+	TriageOpen
+)
+
+// TriageActionMsg is sent when the user picks an action for the note at
+// Path. Delete is only sent once confirmed.
+type TriageActionMsg struct {
+	Action TriageAction
+	Path   string
+}
+
+// TriageNote is an inbox note being triaged.
+type TriageNote struct {
+	Path    string
+	Title   string
+	Content string
+}
+
+// Triage is an overlay that steps through inbox notes one at a time,
+// showing each one's content, with a key for each way of filing it.
+type Triage struct {
+	notes         []TriageNote
+	cursor        int
+	scroll        int // first content line shown
+	confirmDelete bool
+	width         int
+	height        int
+	visible       bool
+	theme         *theme.Theme
+}
+
+// SetTheme sets the color theme for the triage overlay.
+func (t *Triage) SetTheme(th *theme.Theme) { t.theme = th }
+
+func NewTriage() Triage {
+	return Triage{}
+}
+
+// Show opens the overlay on the first of notes.
+func (t *Triage) Show(notes []TriageNote) {
+	t.notes = notes
+	t.cursor = 0
+	t.scroll = 0
+	t.confirmDelete = false
+	t.visible = true
+}
+
+func (t *Triage) Hide() {
+	t.visible = false
+}
+
+func (t Triage) Visible() bool {
+	return t.visible
+}
+
+func (t *Triage) SetSize(width, height int) {
+	t.width = width
+	t.height = height
+}
+
+// Len returns the number of notes left to triage.
+func (t Triage) Len() int {
+	return len(t.notes)
+}
+
+// Remove drops a filed note from the list. The next note takes its place.
+func (t *Triage) Remove(path string) {
+	for i, n := range t.notes {
+		if n.Path == path {
+			t.notes = append(t.notes[:i], t.notes[i+1:]...)
+			if t.cursor > i || t.cursor >= len(t.notes) {
+				t.cursor = max(0, t.cursor-1)
+			}
+			t.scroll = 0
+			return
+		}
+	}
+}
+
+// Replace updates a note in the list, e.g. after tagging it.
+func (t *Triage) Replace(note TriageNote) {
+	for i, n := range t.notes {
+		if n.Path == note.Path {
+			t.notes[i] = note
+			return
+		}
+	}
+}
+
+func (t Triage) Update(msg tea.Msg) (Triage, tea.Cmd) {
+	if !t.visible || len(t.notes) == 0 {
+		return t, nil
+	}
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return t, nil
+	}
+	path := t.notes[t.cursor].Path
+	action := func(a TriageAction) tea.Cmd {
+		return func() tea.Msg { return TriageActionMsg{Action: a, Path: path} }
+	}
+
+	if t.confirmDelete {
+		t.confirmDelete = false
+		if keyMsg.String() == "y" {
+			return t, action(TriageDelete)
+		}
+		return t, nil
+	}
+
+	switch keyMsg.String() {
+	case "esc", "q":
+		t.visible = false
+	case "a":
+		return t, action(TriageArchive)
+	case "m":
+		return t, action(TriageMove)
+	case "t":
+		return t, action(TriageTag)
+	case "p":
+		return t, action(TriageProject)
+	case "d":
+		t.confirmDelete = true
+	case "e", "enter":
+		t.visible = false
+		return t, action(TriageOpen)
+	case "n", "j", "l", "right":
+		t.step(1)
+	case "N", "k", "h", "left":
+		t.step(-1)
+	case "ctrl+d", "J":
+		t.scroll = max(0, min(t.scroll+t.bodyHeight()/2, len(t.contentLines())-t.bodyHeight()))
+	case "ctrl+u", "K":
+		t.scroll = max(0, t.scroll-t.bodyHeight()/2)
+	}
+	return t, nil
+}
+
+// step moves to the note delta places away, wrapping around.
+func (t *Triage) step(delta int) {
+	t.cursor = ((t.cursor+delta)%len(t.notes) + len(t.notes)) % len(t.notes)
+	t.scroll = 0
+}
+
+// innerWidth is the width of the overlay's content.
+func (t Triage) innerWidth() int {
+	return min(max(t.width*7/10, 50), t.width-2) - 4
+}
+
+// bodyHeight is the number of content lines that fit in the overlay.
+func (t Triage) bodyHeight() int {
+	// border (2) + title + path + blank + blank + footer
+	return max(t.height*4/5-7, 3)
+}
+
+// contentLines returns the current note's content wrapped to the overlay.
+func (t Triage) contentLines() []string {
+	if t.cursor >= len(t.notes) {
+		return nil
+	}
+	content := strings.ReplaceAll(strings.TrimRight(t.notes[t.cursor].Content, "\n"), "\t", "    ")
+	return strings.Split(ansi.Wrap(content, t.innerWidth(), ""), "\n")
+}
+
+func (t Triage) View() string {
+	if !t.visible || len(t.notes) == 0 {
+		return ""
+	}
+
+	th := t.theme
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(th.Accent)
+	dim := lipgloss.NewStyle().Foreground(th.Dim)
+	text := lipgloss.NewStyle().Foreground(th.Text)
+	warn := lipgloss.NewStyle().Foreground(th.Error).Bold(true)
+	width := t.innerWidth()
+	note := t.notes[t.cursor]
+
+	lines := []string{
+		titleStyle.Render(ansi.Truncate(fmt.Sprintf("Inbox %d/%d: %s", t.cursor+1, len(t.notes), note.Title), width, "…")),
+		dim.Render(ansi.Truncate(note.Path, width, "…")),
+		"",
+	}
+	content := t.contentLines()
+	rows := t.bodyHeight()
+	end := min(t.scroll+rows, len(content))
+	for _, l := range content[min(t.scroll, end):end] {
+		lines = append(lines, text.Render(l))
+	}
+	for i := end - t.scroll; i < rows; i++ {
+		lines = append(lines, "")
+	}
+	lines = append(lines, "")
+	if t.confirmDelete {
+		lines = append(lines, warn.Render("Delete this note? y to confirm, any other key to keep it"))
+	} else {
+		lines = append(lines, dim.Render(ansi.Truncate(
+			"a: archive  m: move  t: tag  p: project  d: delete  e: open  n/N: next/prev  esc: close", width, "…")))
+	}
+
+	borderStyle := lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(th.Accent).
+		Padding(0, 1).
+		Width(width + 2)
+
+	return borderStyle.Render(strings.Join(lines, "\n"))
+}
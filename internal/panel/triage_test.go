@@ -0,0 +1,76 @@
+package panel
+
+import (
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/pfassina/kopr/internal/theme"
+)
+
+func newTestTriage() Triage {
+	th := theme.DefaultTheme()
+	tr := NewTriage()
+	tr.SetTheme(&th)
+	tr.SetSize(100, 30)
+	tr.Show([]TriageNote{
+		{Path: "inbox/a.md", Title: "A", Content: "first idea"},
+		{Path: "inbox/b.md", Title: "B", Content: "second idea"},
+	})
+	return tr
+}
+
+func TestTriageActions(t *testing.T) {
+	tr := newTestTriage()
+	if view := tr.View(); !strings.Contains(view, "Inbox 1/2: A") || !strings.Contains(view, "first idea") {
+		t.Errorf("view should show the first note:\n%s", view)
+	}
+
+	tr, cmd := tr.Update(key("a"))
+	msg, ok := cmd().(TriageActionMsg)
+	if !ok || msg.Action != TriageArchive || msg.Path != "inbox/a.md" {
+		t.Fatalf("a sent %#v, want archive of inbox/a.md", cmd())
+	}
+
+	// Filing a note brings up the next one.
+	tr.Remove("inbox/a.md")
+	if tr.Len() != 1 || !strings.Contains(tr.View(), "second idea") {
+		t.Errorf("after Remove, view should show the second note:\n%s", tr.View())
+	}
+
+	tr, cmd = tr.Update(key("m"))
+	if msg, ok := cmd().(TriageActionMsg); !ok || msg.Action != TriageMove {
+		t.Errorf("m sent %#v, want move", cmd())
+	}
+}
+
+func TestTriageDeleteNeedsConfirmation(t *testing.T) {
+	tr := newTestTriage()
+	tr, cmd := tr.Update(key("d"))
+	if cmd != nil {
+		t.Fatal("d alone should only ask for confirmation")
+	}
+	if !strings.Contains(tr.View(), "Delete this note?") {
+		t.Error("view should ask to confirm the delete")
+	}
+	tr, cmd = tr.Update(key("x"))
+	if cmd != nil {
+		t.Fatal("any key but y should keep the note")
+	}
+
+	tr, _ = tr.Update(key("n"))
+	tr, _ = tr.Update(key("d"))
+	_, cmd = tr.Update(key("y"))
+	if msg, ok := cmd().(TriageActionMsg); !ok || msg.Action != TriageDelete || msg.Path != "inbox/b.md" {
+		t.Errorf("dy sent %#v, want delete of inbox/b.md", cmd())
+	}
+}
+
+func TestTriageClose(t *testing.T) {
+	tr := newTestTriage()
+	tr, _ = tr.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if tr.Visible() {
+		t.Error("esc should close the triage")
+	}
+}
@@ -90,13 +90,17 @@ func (w WhichKey) View() string {
 		colWidth = width - 4
 	}
 
-	for i := 0; i < len(w.entries); i += 2 {
+	step := 2
+	if colWidth >= width-4 {
+		step = 1 // one column
+	}
+	for i := 0; i < len(w.entries); i += step {
 		left := fmt.Sprintf("%s %s",
 			keyStyle.Render(w.entries[i].Key),
 			labelStyle.Render(w.entries[i].Label),
 		)
 
-		if i+1 < len(w.entries) && colWidth < width-4 {
+		if step == 2 && i+1 < len(w.entries) {
 			right := fmt.Sprintf("%s %s",
 				keyStyle.Render(w.entries[i+1].Key),
 				labelStyle.Render(w.entries[i+1].Label),
@@ -0,0 +1,58 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+// MaxHistory is the number of finder queries kept on disk.
+const MaxHistory = 100
+
+// HistoryStore persists recent finder queries, oldest first.
+type HistoryStore struct {
+	path string
+}
+
+// NewHistoryStore creates a store that persists to the given vault directory.
+func NewHistoryStore(vaultPath string) *HistoryStore {
+	return &HistoryStore{
+		path: filepath.Join(vaultPath, ".kopr", "finder_history.json"),
+	}
+}
+
+// Load reads the query history from disk.
+func (s *HistoryStore) Load() ([]string, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var history []string
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+	return history, nil
+}
+
+// Save writes the query history to disk, keeping the newest MaxHistory entries.
+func (s *HistoryStore) Save(history []string) error {
+	if len(history) > MaxHistory {
+		history = history[len(history)-MaxHistory:]
+	}
+
+	dir := filepath.Dir(s.path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path, data, 0644)
+}
@@ -8,6 +8,7 @@ type State struct {
 	ShowInfo   bool     `json:"show_info"`
 	TreeWidth  int      `json:"tree_width,omitempty"`
 	InfoWidth  int      `json:"info_width,omitempty"`
+	FinderSort string   `json:"finder_sort,omitempty"`
 }
 
 // Default returns the default session state.
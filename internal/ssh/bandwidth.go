@@ -0,0 +1,86 @@
+package ssh
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/pfassina/kopr/internal/config"
+)
+
+// lowBandwidthFPS caps redraws for sessions using the low-bandwidth profile.
+const lowBandwidthFPS = 10
+
+// lowBandwidthEnv picks the profile for one connection, e.g.
+// ssh -o SetEnv=KOPR_LOW_BANDWIDTH=1. The command "low-bandwidth"
+// (ssh -t host low-bandwidth) does the same.
+const lowBandwidthEnv = "KOPR_LOW_BANDWIDTH"
+
+// rttTimeout bounds the round-trip measurement; a client slower than this
+// is treated as slow rather than holding up the session.
+const rttTimeout = 2 * time.Second
+
+// useLowBandwidth reports whether sess should use the low-bandwidth profile:
+// the client's own request wins, then cfg.LowBandwidth, where "auto"
+// measures the connection's round-trip time.
+func useLowBandwidth(sess ssh.Session, cfg config.Config) bool {
+	if on, ok := requestedLowBandwidth(sess.Environ(), sess.Command()); ok {
+		return on
+	}
+	switch cfg.LowBandwidth {
+	case "on":
+		return true
+	case "auto":
+		rtt, err := measureRTT(sess)
+		return err == nil && rtt >= cfg.LowBandwidthRTT
+	}
+	return false
+}
+
+// requestedLowBandwidth returns the profile the client asked for through
+// lowBandwidthEnv or the session command. ok is false when it asked for
+// neither.
+func requestedLowBandwidth(environ, command []string) (on, ok bool) {
+	if len(command) > 0 && command[0] == "low-bandwidth" {
+		return true, true
+	}
+	for _, kv := range environ {
+		name, value, found := strings.Cut(kv, "=")
+		if !found || name != lowBandwidthEnv {
+			continue
+		}
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b, true
+		}
+	}
+	return false, false
+}
+
+// measureRTT times a keepalive global request, which every client answers
+// (with a failure reply if it does not know it). A client that has not
+// answered within rttTimeout counts as taking rttTimeout.
+func measureRTT(sess ssh.Session) (time.Duration, error) {
+	conn, ok := sess.Context().Value(ssh.ContextKeyConn).(gossh.Conn)
+	if !ok {
+		return 0, errors.New("no ssh connection in session context")
+	}
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return 0, err
+		}
+		return time.Since(start), nil
+	case <-time.After(rttTimeout):
+		return rttTimeout, nil
+	}
+}
@@ -0,0 +1,27 @@
+package ssh
+
+import "testing"
+
+func TestRequestedLowBandwidth(t *testing.T) {
+	tests := []struct {
+		name    string
+		environ []string
+		command []string
+		on, ok  bool
+	}{
+		{"nothing", []string{"TERM=xterm"}, nil, false, false},
+		{"command", nil, []string{"low-bandwidth"}, true, true},
+		{"env on", []string{"KOPR_LOW_BANDWIDTH=1"}, nil, true, true},
+		{"env off", []string{"KOPR_LOW_BANDWIDTH=false"}, nil, false, true},
+		{"env invalid", []string{"KOPR_LOW_BANDWIDTH=maybe"}, nil, false, false},
+		{"other command", nil, []string{"ls"}, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			on, ok := requestedLowBandwidth(tt.environ, tt.command)
+			if on != tt.on || ok != tt.ok {
+				t.Errorf("got (%v, %v), want (%v, %v)", on, ok, tt.on, tt.ok)
+			}
+		})
+	}
+}
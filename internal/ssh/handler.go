@@ -3,24 +3,62 @@ package ssh
 import (
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
 	bts "github.com/charmbracelet/wish/bubbletea"
 
 	"github.com/pfassina/kopr/internal/app"
 	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/metrics"
 )
 
-// NewHandler returns a Bubble Tea handler for SSH sessions.
-func NewHandler(cfg config.Config) bts.Handler {
+// appKey stores a session's *app.App in its context for closeAppMiddleware.
+type appKey struct{}
+
+// NewHandler returns a Bubble Tea handler for SSH sessions. Each session is
+// tracked in m until its connection closes.
+func NewHandler(cfg config.Config, m *metrics.Registry) bts.Handler {
 	return func(sess ssh.Session) (tea.Model, []tea.ProgramOption) {
 		a := app.New(cfg)
 		a.SetOutput(sess)
+		a.SetMetrics(m)
+		a.SetColorProfile(bts.MakeRenderer(sess).ColorProfile())
+		lowBandwidth := useLowBandwidth(sess, cfg)
+		a.SetLowBandwidth(lowBandwidth)
+		sess.Context().SetValue(appKey{}, &a)
+
+		id := m.AddSession(metrics.Session{
+			User:   sess.User(),
+			Remote: sess.RemoteAddr().String(),
+			Vault:  cfg.VaultPath,
+		})
+		go func() {
+			<-sess.Context().Done()
+			m.RemoveSession(id)
+		}()
 
 		opts := []tea.ProgramOption{
 			tea.WithAltScreen(),
 			tea.WithMouseAllMotion(),
 		}
+		if lowBandwidth {
+			opts = append(opts, tea.WithFPS(lowBandwidthFPS))
+		}
 		opts = append(opts, bts.MakeOptions(sess)...)
 
 		return &a, opts
 	}
 }
+
+// closeAppMiddleware closes the session's app after its program exits, so a
+// dropped connection saves session state and stops its Neovim process just
+// like quitting does.
+func closeAppMiddleware() wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			next(sess)
+			if a, ok := sess.Context().Value(appKey{}).(*app.App); ok {
+				a.Close()
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package ssh
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/charmbracelet/keygen"
+	"github.com/charmbracelet/ssh"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// HostKey is the key the server identifies itself with.
+type HostKey struct {
+	Path        string
+	Type        string // SSH key type, e.g. "ssh-ed25519"
+	Fingerprint string // SHA256:..., as shown by ssh on first connect
+	Generated   bool   // created by this run
+	signer      gossh.Signer
+}
+
+// loadHostKey reads the host key at path, generating one of keyType (and its
+// .pub) when the file does not exist. An existing key is used whatever its
+// type, so changing the type only affects new keys.
+func loadHostKey(path, keyType string) (HostKey, error) {
+	var kt keygen.KeyType
+	switch keyType {
+	case "", "ed25519":
+		kt = keygen.Ed25519
+	case "rsa":
+		kt = keygen.RSA
+	case "ecdsa":
+		kt = keygen.ECDSA
+	default:
+		return HostKey{}, fmt.Errorf("host key type %q: want ed25519, rsa or ecdsa", keyType)
+	}
+
+	_, err := os.Stat(path)
+	generated := os.IsNotExist(err)
+	kp, err := keygen.New(path, keygen.WithKeyType(kt), keygen.WithWrite())
+	if err != nil {
+		return HostKey{}, fmt.Errorf("host key %s: %w", path, err)
+	}
+	signer := kp.Signer()
+	if signer == nil {
+		return HostKey{}, fmt.Errorf("host key %s: unsupported key", path)
+	}
+	return HostKey{
+		Path:        path,
+		Type:        signer.PublicKey().Type(),
+		Fingerprint: gossh.FingerprintSHA256(signer.PublicKey()),
+		Generated:   generated,
+		signer:      signer,
+	}, nil
+}
+
+// withHostKey makes the server present key.
+func withHostKey(key HostKey) ssh.Option {
+	return func(s *ssh.Server) error {
+		s.AddHostKey(key.signer)
+		return nil
+	}
+}
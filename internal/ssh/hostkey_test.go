@@ -0,0 +1,36 @@
+package ssh
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadHostKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys", "ssh_host_key")
+
+	key, err := loadHostKey(path, "ed25519")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !key.Generated || key.Type != "ssh-ed25519" || !strings.HasPrefix(key.Fingerprint, "SHA256:") {
+		t.Errorf("first load = %+v, want a generated ed25519 key", key)
+	}
+	if _, err := os.Stat(path + ".pub"); err != nil {
+		t.Errorf("public key not written: %v", err)
+	}
+
+	// Reloading keeps the key even when another type is configured.
+	again, err := loadHostKey(path, "ecdsa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Generated || again.Fingerprint != key.Fingerprint {
+		t.Errorf("reload = %+v, want the existing key %s", again, key.Fingerprint)
+	}
+
+	if _, err := loadHostKey(filepath.Join(t.TempDir(), "k"), "dsa"); err == nil {
+		t.Error("unknown key type should fail")
+	}
+}
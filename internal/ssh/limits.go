@@ -0,0 +1,73 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"sync"
+
+	"github.com/charmbracelet/ssh"
+	"github.com/charmbracelet/wish"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// sessionLimiter counts concurrent sessions per client identity.
+type sessionLimiter struct {
+	max    int // 0 means no limit
+	mu     sync.Mutex
+	active map[string]int
+}
+
+func newSessionLimiter(max int) *sessionLimiter {
+	return &sessionLimiter{max: max, active: make(map[string]int)}
+}
+
+// acquire reserves a session slot for key, reporting false when key already
+// has the maximum number of sessions.
+func (l *sessionLimiter) acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.max > 0 && l.active[key] >= l.max {
+		return false
+	}
+	l.active[key]++
+	return true
+}
+
+// release frees a slot taken with acquire.
+func (l *sessionLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active[key]--
+	if l.active[key] <= 0 {
+		delete(l.active, key)
+	}
+}
+
+// sessionKey identifies the client behind a session: its public key when it
+// authenticated with one, otherwise the user name and remote host.
+func sessionKey(sess ssh.Session) string {
+	if key := sess.PublicKey(); key != nil {
+		return gossh.FingerprintSHA256(key)
+	}
+	host := sess.RemoteAddr().String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return sess.User() + "@" + host
+}
+
+// limitMiddleware refuses a session when its client already has the maximum
+// number of sessions open, before any app or Neovim process is started.
+func limitMiddleware(l *sessionLimiter) wish.Middleware {
+	return func(next ssh.Handler) ssh.Handler {
+		return func(sess ssh.Session) {
+			key := sessionKey(sess)
+			if !l.acquire(key) {
+				wish.Fatalln(sess, fmt.Sprintf("kopr: too many sessions for %s (max %d)", key, l.max))
+				return
+			}
+			defer l.release(key)
+			next(sess)
+		}
+	}
+}
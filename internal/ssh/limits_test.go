@@ -0,0 +1,27 @@
+package ssh
+
+import "testing"
+
+func TestSessionLimiter(t *testing.T) {
+	l := newSessionLimiter(2)
+	if !l.acquire("alice") || !l.acquire("alice") {
+		t.Fatal("alice should get two sessions")
+	}
+	if l.acquire("alice") {
+		t.Error("third session for alice should be refused")
+	}
+	if !l.acquire("bob") {
+		t.Error("bob should not be limited by alice's sessions")
+	}
+	l.release("alice")
+	if !l.acquire("alice") {
+		t.Error("released slot should be reusable")
+	}
+
+	unlimited := newSessionLimiter(0)
+	for range 10 {
+		if !unlimited.acquire("alice") {
+			t.Fatal("limit 0 should allow any number of sessions")
+		}
+	}
+}
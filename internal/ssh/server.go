@@ -1,7 +1,12 @@
 package ssh
 
 import (
+	"errors"
 	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
 	"path/filepath"
 
 	"github.com/charmbracelet/ssh"
@@ -9,42 +14,106 @@ import (
 	"github.com/charmbracelet/wish/activeterm"
 	bts "github.com/charmbracelet/wish/bubbletea"
 	"github.com/charmbracelet/wish/logging"
+	gossh "golang.org/x/crypto/ssh"
 
 	"github.com/pfassina/kopr/internal/config"
+	"github.com/pfassina/kopr/internal/metrics"
 )
 
 // Server wraps a Wish SSH server.
 type Server struct {
-	server *ssh.Server
-	cfg    config.Config
+	server  *ssh.Server
+	cfg     config.Config
+	hostKey HostKey
+	metrics *metrics.Registry
+	http    *http.Server // serves metrics; nil unless cfg.MetricsListen is set
 }
 
-// New creates a new SSH server.
+// New creates a new SSH server, generating its host key on first use.
 func New(cfg config.Config) (*Server, error) {
-	hostKeyPath := filepath.Join(cfg.VaultPath, ".kopr", "ssh_host_key")
+	hostKey, err := loadHostKey(hostKeyPath(cfg), cfg.HostKeyType)
+	if err != nil {
+		return nil, err
+	}
+	m := metrics.New(filepath.Join(cfg.VaultPath, ".kopr", "index.db"))
 
+	// Middleware runs last to first: the session limit is checked before
+	// anything starts, and the app is closed once the program has exited.
 	s, err := wish.NewServer(
 		wish.WithAddress(cfg.Listen),
-		wish.WithHostKeyPath(hostKeyPath),
+		withHostKey(hostKey),
+		withMaxAuthTries(cfg.MaxAuthTries),
 		wish.WithMiddleware(
 			logging.Middleware(),
 			activeterm.Middleware(),
-			bts.Middleware(NewHandler(cfg)),
+			bts.Middleware(NewHandler(cfg, m)),
+			closeAppMiddleware(),
+			limitMiddleware(newSessionLimiter(cfg.MaxSessionsPerKey)),
 		),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("create ssh server: %w", err)
 	}
 
-	return &Server{server: s, cfg: cfg}, nil
+	srv := &Server{server: s, cfg: cfg, hostKey: hostKey, metrics: m}
+	if cfg.MetricsListen != "" {
+		srv.http = &http.Server{Addr: cfg.MetricsListen, Handler: m.Handler()}
+	}
+	return srv, nil
+}
+
+// hostKeyPath returns cfg.HostKeyPath, or else the default in the config
+// directory. Servers set up before the key moved there keep using the key in
+// the vault so their clients do not see a changed host key.
+func hostKeyPath(cfg config.Config) string {
+	if cfg.HostKeyPath != "" {
+		return cfg.HostKeyPath
+	}
+	legacy := filepath.Join(cfg.VaultPath, ".kopr", "ssh_host_key")
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy
+	}
+	return filepath.Join(config.ConfigDir(), "ssh_host_key")
+}
+
+// HostKey returns the key the server identifies itself with.
+func (s *Server) HostKey() HostKey {
+	return s.hostKey
 }
 
-// ListenAndServe starts the SSH server.
+// withMaxAuthTries drops a connection after n failed authentication
+// attempts.
+func withMaxAuthTries(n int) ssh.Option {
+	return func(s *ssh.Server) error {
+		s.ServerConfigCallback = func(ssh.Context) *gossh.ServerConfig {
+			return &gossh.ServerConfig{MaxAuthTries: n}
+		}
+		return nil
+	}
+}
+
+// ListenAndServe starts the metrics endpoint, if configured, and the SSH
+// server.
 func (s *Server) ListenAndServe() error {
+	if s.http != nil {
+		ln, err := net.Listen("tcp", s.http.Addr)
+		if err != nil {
+			return fmt.Errorf("metrics listen: %w", err)
+		}
+		go func() {
+			if err := s.http.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Printf("metrics server: %v", err)
+			}
+		}()
+	}
 	return s.server.ListenAndServe()
 }
 
-// Close stops the SSH server.
+// Close stops the SSH server and the metrics endpoint.
 func (s *Server) Close() error {
-	return s.server.Close()
+	err := s.server.Close()
+	if s.http != nil {
+		err = errors.Join(err, s.http.Close())
+	}
+	return err
 }
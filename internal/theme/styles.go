@@ -0,0 +1,106 @@
+package theme
+
+import "github.com/charmbracelet/lipgloss"
+
+// StyleSet holds the lipgloss styles the panels render with, built once
+// from a theme rather than on every View. The app owns one StyleSet and
+// rebuilds it in place whenever the theme changes, so panels sharing a
+// pointer to it always draw with the current colors.
+type StyleSet struct {
+	Theme Theme
+
+	Text       lipgloss.Style
+	Dim        lipgloss.Style
+	DimBold    lipgloss.Style
+	Accent     lipgloss.Style
+	AccentBold lipgloss.Style
+
+	// TitleFocused and TitleBlurred style a side panel's title.
+	TitleFocused lipgloss.Style
+	TitleBlurred lipgloss.Style
+	// Cursor styles the row under the cursor in a focused side panel.
+	Cursor lipgloss.Style
+	// MatchCursor styles matched characters in the finder's cursor row.
+	MatchCursor lipgloss.Style
+	// HelpBox frames a side panel's key help; callers set the width.
+	HelpBox lipgloss.Style
+
+	// Overlay frames the finder; callers set the width. Separator and
+	// SeparatorFocused draw the line before its preview column.
+	Overlay          lipgloss.Style
+	Separator        lipgloss.Style
+	SeparatorFocused lipgloss.Style
+
+	StatusBar    lipgloss.Style
+	StatusText   lipgloss.Style
+	StatusError  lipgloss.Style
+	StatusNotice lipgloss.Style
+	modes        map[string]lipgloss.Style
+	otherMode    lipgloss.Style
+}
+
+// NewStyleSet builds the styles for t.
+func NewStyleSet(t Theme) StyleSet {
+	s := StyleSet{
+		Theme:      t,
+		Text:       lipgloss.NewStyle().Foreground(t.Text),
+		Dim:        lipgloss.NewStyle().Foreground(t.Dim),
+		DimBold:    lipgloss.NewStyle().Foreground(t.Dim).Bold(true),
+		Accent:     lipgloss.NewStyle().Foreground(t.Accent),
+		AccentBold: lipgloss.NewStyle().Foreground(t.Accent).Bold(true),
+
+		TitleFocused: lipgloss.NewStyle().Bold(true).Foreground(t.Accent).Underline(true).Padding(0, 1),
+		TitleBlurred: lipgloss.NewStyle().Bold(true).Foreground(t.Dim).Padding(0, 1),
+		Cursor:       lipgloss.NewStyle().Foreground(t.Accent2).Bold(true),
+		MatchCursor:  lipgloss.NewStyle().Foreground(t.Accent).Bold(true).Underline(true),
+		HelpBox: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(t.Border).
+			Padding(0, 1),
+
+		Overlay: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(t.Accent).
+			Padding(0, 1),
+		Separator:        separator(t.Border),
+		SeparatorFocused: separator(t.Accent),
+
+		StatusBar:    lipgloss.NewStyle().Background(t.StatusBg),
+		StatusText:   statusSection(t, t.StatusFg),
+		StatusError:  statusSection(t, t.Error),
+		StatusNotice: statusSection(t, t.Accent),
+		otherMode:    modeBadge(t.Text),
+	}
+	s.modes = map[string]lipgloss.Style{
+		"NORMAL":  modeBadge(t.NormalMode),
+		"INSERT":  modeBadge(t.InsertMode),
+		"VISUAL":  modeBadge(t.VisualMode),
+		"COMMAND": modeBadge(t.CmdMode),
+		"REPLACE": modeBadge(t.Error),
+	}
+	return s
+}
+
+// Mode returns the status bar badge style for a Neovim mode name.
+func (s *StyleSet) Mode(mode string) lipgloss.Style {
+	if style, ok := s.modes[mode]; ok {
+		return style
+	}
+	return s.otherMode
+}
+
+func separator(c lipgloss.Color) lipgloss.Style {
+	return lipgloss.NewStyle().
+		BorderLeft(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderForeground(c).
+		PaddingLeft(1)
+}
+
+func statusSection(t Theme, fg lipgloss.Color) lipgloss.Style {
+	return lipgloss.NewStyle().Background(t.StatusBg).Foreground(fg).Padding(0, 1)
+}
+
+func modeBadge(bg lipgloss.Color) lipgloss.Style {
+	return lipgloss.NewStyle().Background(bg).Foreground(lipgloss.Color("0")).Bold(true).Padding(0, 1)
+}
@@ -1,10 +1,18 @@
 package theme
 
-import "github.com/charmbracelet/lipgloss"
+import (
+	"strconv"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
+
+	"github.com/pfassina/kopr/internal/markdown"
+)
 
 // Theme defines a color palette used by all TUI panels.
 // Panels hold a *Theme pointer so in-place mutations (e.g. after extracting
 // colors from Neovim) are visible on the next View() call.
+// The busiest panels render from a StyleSet built from it instead.
 type Theme struct {
 	Bg         lipgloss.Color
 	Accent     lipgloss.Color
@@ -41,3 +49,46 @@ func DefaultTheme() Theme {
 		CmdMode:    lipgloss.Color("#f38ba8"),
 	}
 }
+
+// ForProfile returns the theme with every color converted to the nearest one
+// the given terminal profile can show: 256-color and 16-color palette indexes,
+// or no color at all for Ascii. TrueColor leaves the theme unchanged.
+func (t Theme) ForProfile(p termenv.Profile) Theme {
+	if p == termenv.TrueColor {
+		return t
+	}
+	for _, c := range []*lipgloss.Color{
+		&t.Bg, &t.Accent, &t.Accent2, &t.Subtle, &t.Text, &t.Dim, &t.Border,
+		&t.StatusBg, &t.StatusFg, &t.Error,
+		&t.NormalMode, &t.InsertMode, &t.VisualMode, &t.CmdMode,
+	} {
+		*c = convertColor(*c, p)
+	}
+	return t
+}
+
+// CodeColors returns the colors highlighted code is exported with, so that
+// copied HTML matches the code in the preview.
+func (t Theme) CodeColors() markdown.CodeColors {
+	return markdown.CodeColors{
+		Background: string(t.Bg),
+		Text:       string(t.Text),
+		Keyword:    string(t.Accent),
+		String:     string(t.InsertMode),
+		Number:     string(t.VisualMode),
+		Comment:    string(t.Subtle),
+	}
+}
+
+// convertColor maps a hex or palette color to p, as a palette index that
+// lipgloss renders without further conversion.
+func convertColor(c lipgloss.Color, p termenv.Profile) lipgloss.Color {
+	switch v := p.Color(string(c)).(type) {
+	case termenv.ANSI256Color:
+		return lipgloss.Color(strconv.Itoa(int(v)))
+	case termenv.ANSIColor:
+		return lipgloss.Color(strconv.Itoa(int(v)))
+	default:
+		return ""
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 )
 
 func TestDefaultTheme(t *testing.T) {
@@ -34,3 +35,48 @@ func TestDefaultTheme(t *testing.T) {
 		}
 	}
 }
+
+func TestForProfile(t *testing.T) {
+	th := DefaultTheme()
+
+	if got := th.ForProfile(termenv.TrueColor); got != th {
+		t.Error("TrueColor should leave the theme unchanged")
+	}
+
+	// #cba6f7 is closest to 183 in the 256-color cube.
+	if got := th.ForProfile(termenv.ANSI256).Accent; got != "183" {
+		t.Errorf("ANSI256 Accent = %q, want %q", got, "183")
+	}
+	if got := th.ForProfile(termenv.ANSI).Accent; got == "" || got == th.Accent {
+		t.Errorf("ANSI Accent = %q, want a 16-color index", got)
+	}
+	if got := th.ForProfile(termenv.Ascii).Accent; got != "" {
+		t.Errorf("Ascii Accent = %q, want no color", got)
+	}
+}
+
+func TestNewStyleSet(t *testing.T) {
+	th := DefaultTheme()
+	st := NewStyleSet(th)
+
+	if got := st.Cursor.GetForeground(); got != th.Accent2 {
+		t.Errorf("Cursor foreground = %v, want %v", got, th.Accent2)
+	}
+	if got := st.StatusError.GetBackground(); got != th.StatusBg {
+		t.Errorf("StatusError background = %v, want %v", got, th.StatusBg)
+	}
+	if got := st.Mode("INSERT").GetBackground(); got != th.InsertMode {
+		t.Errorf("INSERT badge = %v, want %v", got, th.InsertMode)
+	}
+	if got := st.Mode("READ-ONLY").GetBackground(); got != th.Text {
+		t.Errorf("unknown mode badge = %v, want %v", got, th.Text)
+	}
+
+	// Rebuilding in place is seen through a shared pointer.
+	shared := &st
+	th.Accent2 = lipgloss.Color("#ffffff")
+	st = NewStyleSet(th)
+	if got := shared.Cursor.GetForeground(); got != th.Accent2 {
+		t.Errorf("Cursor foreground after rebuild = %v, want %v", got, th.Accent2)
+	}
+}
@@ -0,0 +1,236 @@
+// Package update checks GitHub releases for a newer kopr and replaces the
+// running binary with it.
+package update
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Version is the running kopr version, set at build time with
+// -ldflags "-X github.com/pfassina/kopr/internal/update.Version=v0.3.0".
+var Version = ""
+
+// latestURL is the GitHub API endpoint for the newest release.
+var latestURL = "https://api.github.com/repos/pfassina/kopr/releases/latest"
+
+// ChecksumsName is the release asset listing the SHA-256 of every binary in
+// sha256sum format.
+const ChecksumsName = "checksums.txt"
+
+// Release is a published kopr release.
+type Release struct {
+	Tag    string  `json:"tag_name"`
+	Assets []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Current returns the running version: the one set at build time, else the
+// module version recorded by `go install`, else "dev".
+func Current() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// Latest fetches the newest published release.
+func Latest(ctx context.Context, client *http.Client) (Release, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestURL, nil)
+	if err != nil {
+		return Release{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return Release{}, err
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only body
+	if resp.StatusCode != http.StatusOK {
+		return Release{}, fmt.Errorf("latest release: %s", resp.Status)
+	}
+	var rel Release
+	if err := json.NewDecoder(resp.Body).Decode(&rel); err != nil {
+		return Release{}, fmt.Errorf("latest release: %w", err)
+	}
+	return rel, nil
+}
+
+// Newer reports whether version latest is newer than current. Both are
+// vMAJOR.MINOR.PATCH tags; a version that does not parse (such as "dev")
+// is never newer or older than anything.
+func Newer(latest, current string) bool {
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	for i := range l {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// IsRelease reports whether v is a release version (vMAJOR.MINOR.PATCH)
+// rather than a development build.
+func IsRelease(v string) bool {
+	_, ok := parseVersion(v)
+	return ok
+}
+
+// parseVersion parses "v1.2.3", ignoring any pre-release or build suffix.
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	v, ok := strings.CutPrefix(v, "v")
+	if !ok {
+		return out, false
+	}
+	if i := strings.IndexAny(v, "-+"); i >= 0 {
+		v = v[:i]
+	}
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+// AssetName returns the release asset holding the binary for a platform,
+// e.g. "kopr_linux_amd64".
+func AssetName(goos, goarch string) string {
+	name := "kopr_" + goos + "_" + goarch
+	if goos == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+func (r Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Apply downloads the release's binary for this platform, checks it against
+// the release checksums and replaces the executable at exe with it. The new
+// binary is staged next to exe and renamed over it, so a failed download or
+// checksum mismatch leaves exe untouched.
+func Apply(ctx context.Context, client *http.Client, rel Release, exe string) error {
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	bin, ok := rel.asset(name)
+	if !ok {
+		return fmt.Errorf("release %s has no binary for %s/%s", rel.Tag, runtime.GOOS, runtime.GOARCH)
+	}
+	sums, ok := rel.asset(ChecksumsName)
+	if !ok {
+		return fmt.Errorf("release %s has no %s", rel.Tag, ChecksumsName)
+	}
+	want, err := fetchChecksum(ctx, client, sums.URL, name)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".kopr-update-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) //nolint:errcheck // gone after a successful rename
+
+	h := sha256.New()
+	if err := download(ctx, client, bin.URL, io.MultiWriter(tmp, h)); err != nil {
+		return errors.Join(err, tmp.Close())
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != want {
+		return fmt.Errorf("checksum mismatch for %s: got %s, want %s", name, got, want)
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	if runtime.GOOS != "windows" {
+		return os.Rename(tmp.Name(), exe)
+	}
+	// A running executable can be renamed but not replaced. Put it back if
+	// the new one can't take its place, so an executable is always left.
+	if err := os.Rename(exe, exe+".old"); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		if restoreErr := os.Rename(exe+".old", exe); restoreErr != nil {
+			return errors.Join(err, fmt.Errorf("restore %s: %w", exe, restoreErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// fetchChecksum returns the SHA-256 listed for name in a checksums file.
+func fetchChecksum(ctx context.Context, client *http.Client, url, name string) (string, error) {
+	var buf strings.Builder
+	if err := download(ctx, client, url, &buf); err != nil {
+		return "", err
+	}
+	sc := bufio.NewScanner(strings.NewReader(buf.String()))
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s lists no checksum for %s", ChecksumsName, name)
+}
+
+func download(ctx context.Context, client *http.Client, url string, w io.Writer) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close() //nolint:errcheck // read-only body
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("download %s: %s", url, resp.Status)
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
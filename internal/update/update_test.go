@@ -0,0 +1,114 @@
+package update
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"v0.4.0", "v0.3.9", true},
+		{"v1.0.0", "v0.12.0", true},
+		{"v0.3.10", "v0.3.9", true},
+		{"v0.3.0", "v0.3.0", false},
+		{"v0.2.0", "v0.3.0", false},
+		{"v0.4.0", "v0.4.0-rc1", false},
+		{"v0.4.0", "dev", false},
+		{"nightly", "v0.3.0", false},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+// releaseServer serves a release whose binary for this platform is body and
+// whose checksums file lists sum for it.
+func releaseServer(t *testing.T, body, sum string) {
+	t.Helper()
+	name := AssetName(runtime.GOOS, runtime.GOARCH)
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	mux.HandleFunc("/latest", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, `{"tag_name":"v9.9.9","assets":[{"name":%q,"browser_download_url":%q},{"name":%q,"browser_download_url":%q}]}`,
+			name, srv.URL+"/bin", ChecksumsName, srv.URL+"/sums")
+	})
+	mux.HandleFunc("/bin", func(w http.ResponseWriter, _ *http.Request) { fmt.Fprint(w, body) })
+	mux.HandleFunc("/sums", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintf(w, "%s  kopr_other_arch\n%s  %s\n", strings.Repeat("0", 64), sum, name)
+	})
+
+	old := latestURL
+	latestURL = srv.URL + "/latest"
+	t.Cleanup(func() { latestURL = old })
+}
+
+func TestApply(t *testing.T) {
+	const newBinary = "new kopr"
+	h := sha256.Sum256([]byte(newBinary))
+	releaseServer(t, newBinary, hex.EncodeToString(h[:]))
+
+	rel, err := Latest(context.Background(), http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Tag != "v9.9.9" {
+		t.Errorf("Tag = %q, want v9.9.9", rel.Tag)
+	}
+
+	exe := filepath.Join(t.TempDir(), "kopr")
+	if err := os.WriteFile(exe, []byte("old kopr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Apply(context.Background(), http.DefaultClient, rel, exe); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != newBinary {
+		t.Errorf("exe = %q, want %q", got, newBinary)
+	}
+	entries, err := os.ReadDir(filepath.Dir(exe))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("staging file left behind: %v", entries)
+	}
+}
+
+func TestApplyChecksumMismatch(t *testing.T) {
+	releaseServer(t, "tampered kopr", strings.Repeat("a", 64))
+
+	rel, err := Latest(context.Background(), http.DefaultClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exe := filepath.Join(t.TempDir(), "kopr")
+	if err := os.WriteFile(exe, []byte("old kopr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	err = Apply(context.Background(), http.DefaultClient, rel, exe)
+	if err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Apply() error = %v, want checksum mismatch", err)
+	}
+	if got, _ := os.ReadFile(exe); string(got) != "old kopr" {
+		t.Errorf("exe replaced despite mismatch: %q", got)
+	}
+}
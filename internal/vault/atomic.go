@@ -0,0 +1,168 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// FileRewrite is a pending replacement of one file's content.
+type FileRewrite struct {
+	Path    string // absolute path
+	Content []byte
+}
+
+// stagedFile tracks a rewrite through staging and commit.
+type stagedFile struct {
+	path     string
+	tmp      string // staged new content, "" once renamed into place
+	original []byte
+	mode     os.FileMode
+}
+
+// ApplyRewrites replaces the content of several files as a unit. Every new
+// version is first written to a temp file next to its target and fsynced;
+// only when all are staged are they renamed into place. If staging fails no
+// target is touched. If a rename fails, files already replaced are restored
+// from their original content, so callers see all-or-nothing.
+func ApplyRewrites(rewrites []FileRewrite) error {
+	staged := make([]stagedFile, 0, len(rewrites))
+	cleanup := func() error {
+		var errs []error
+		for _, s := range staged {
+			if s.tmp == "" {
+				continue
+			}
+			if err := os.Remove(s.tmp); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	for _, rw := range rewrites {
+		info, err := os.Stat(rw.Path)
+		if err != nil {
+			return errors.Join(fmt.Errorf("stage %s: %w", rw.Path, err), cleanup())
+		}
+		original, err := os.ReadFile(rw.Path)
+		if err != nil {
+			return errors.Join(fmt.Errorf("stage %s: %w", rw.Path, err), cleanup())
+		}
+		tmp, err := writeTemp(rw.Path, rw.Content, info.Mode().Perm())
+		if err != nil {
+			return errors.Join(fmt.Errorf("stage %s: %w", rw.Path, err), cleanup())
+		}
+		staged = append(staged, stagedFile{path: rw.Path, tmp: tmp, original: original, mode: info.Mode().Perm()})
+	}
+
+	for i := range staged {
+		if err := os.Rename(staged[i].tmp, staged[i].path); err != nil {
+			err = fmt.Errorf("commit %s: %w", staged[i].path, err)
+			return errors.Join(err, rollback(staged[:i]), cleanup())
+		}
+		staged[i].tmp = ""
+	}
+
+	return syncDirs(staged)
+}
+
+// rollback restores committed files to their original content, using the
+// same stage-then-rename path so a crash mid-rollback can't truncate a note.
+func rollback(committed []stagedFile) error {
+	var errs []error
+	for _, s := range committed {
+		tmp, err := writeTemp(s.path, s.original, s.mode)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rollback %s: %w", s.path, err))
+			continue
+		}
+		if err := os.Rename(tmp, s.path); err != nil {
+			errs = append(errs, fmt.Errorf("rollback %s: %w", s.path, err))
+			if rmErr := os.Remove(tmp); rmErr != nil {
+				errs = append(errs, rmErr)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// writeTemp writes content to a hidden temp file in target's directory (so
+// the final rename stays on one filesystem) and fsyncs it.
+func writeTemp(target string, content []byte, mode os.FileMode) (string, error) {
+	f, err := os.CreateTemp(filepath.Dir(target), "."+filepath.Base(target)+".kopr-tmp-*")
+	if err != nil {
+		return "", err
+	}
+	tmp := f.Name()
+	_, err = f.Write(content)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp, mode)
+	}
+	if err != nil {
+		return "", errors.Join(err, os.Remove(tmp))
+	}
+	return tmp, nil
+}
+
+// syncDirs fsyncs each parent directory once so the renames are durable.
+func syncDirs(files []stagedFile) error {
+	seen := make(map[string]bool)
+	var errs []error
+	for _, s := range files {
+		dir := filepath.Dir(s.path)
+		if seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		d, err := os.Open(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := d.Sync(); err != nil {
+			errs = append(errs, fmt.Errorf("sync %s: %w", dir, err))
+		}
+		if err := d.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LineChange is one line that differs between two versions of a file.
+type LineChange struct {
+	Line int // 1-based
+	Old  string
+	New  string
+}
+
+// ChangedLines compares two versions of a file line by line. Vault rewrites
+// replace text within lines, so lines are paired by position; when the line
+// counts differ, unmatched lines are reported against an empty counterpart.
+func ChangedLines(before, after []byte) []LineChange {
+	oldLines := strings.Split(string(before), "\n")
+	newLines := strings.Split(string(after), "\n")
+	var changes []LineChange
+	for i := range max(len(oldLines), len(newLines)) {
+		var o, n string
+		if i < len(oldLines) {
+			o = oldLines[i]
+		}
+		if i < len(newLines) {
+			n = newLines[i]
+		}
+		if o != n {
+			changes = append(changes, LineChange{Line: i + 1, Old: o, New: n})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,124 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != want {
+		t.Errorf("%s: got %q, want %q", filepath.Base(path), string(data), want)
+	}
+}
+
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".kopr-tmp-") {
+			t.Errorf("leftover temp file %s", e.Name())
+		}
+	}
+}
+
+func TestApplyRewrites(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "old a", "b.md": "old b"})
+	if err := os.Chmod(filepath.Join(dir, "b.md"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	err := ApplyRewrites([]FileRewrite{
+		{Path: filepath.Join(dir, "a.md"), Content: []byte("new a")},
+		{Path: filepath.Join(dir, "b.md"), Content: []byte("new b")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContent(t, filepath.Join(dir, "a.md"), "new a")
+	assertContent(t, filepath.Join(dir, "b.md"), "new b")
+	assertNoTempFiles(t, dir)
+
+	info, err := os.Stat(filepath.Join(dir, "b.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v, want 0600", info.Mode().Perm())
+	}
+}
+
+func TestApplyRewrites_StageFailureTouchesNothing(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "old a"})
+
+	err := ApplyRewrites([]FileRewrite{
+		{Path: filepath.Join(dir, "a.md"), Content: []byte("new a")},
+		{Path: filepath.Join(dir, "missing.md"), Content: []byte("new")},
+	})
+	if err == nil {
+		t.Fatal("expected error for missing file")
+	}
+
+	assertContent(t, filepath.Join(dir, "a.md"), "old a")
+	assertNoTempFiles(t, dir)
+}
+
+func TestRollback(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "new a", "b.md": "new b"})
+
+	err := rollback([]stagedFile{
+		{path: filepath.Join(dir, "a.md"), original: []byte("old a"), mode: 0644},
+		{path: filepath.Join(dir, "b.md"), original: []byte("old b"), mode: 0644},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertContent(t, filepath.Join(dir, "a.md"), "old a")
+	assertContent(t, filepath.Join(dir, "b.md"), "old b")
+	assertNoTempFiles(t, dir)
+}
+
+func TestChangedLines(t *testing.T) {
+	before := "a\n[[old]]\nc\nd"
+	after := "a\n[[new]]\nc\nd\ne"
+	got := ChangedLines([]byte(before), []byte(after))
+	want := []LineChange{
+		{Line: 2, Old: "[[old]]", New: "[[new]]"},
+		{Line: 5, Old: "", New: "e"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("[%d] got %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Headers of files encrypted at rest: git-crypt's, and age's in its binary
+// and armored forms.
+var encryptedHeaders = [][]byte{
+	[]byte("\x00GITCRYPT\x00"),
+	[]byte("age-encryption.org/v1\n"),
+	[]byte("-----BEGIN AGE ENCRYPTED FILE-----"),
+}
+
+// IsEncrypted reports whether content is a note still encrypted by
+// git-crypt or age, rather than markdown.
+func IsEncrypted(content []byte) bool {
+	for _, h := range encryptedHeaders {
+		if bytes.HasPrefix(content, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// EncryptedNotes returns the vault-relative paths of the notes that are
+// still encrypted, e.g. because the vault's git-crypt or age keys haven't
+// unlocked it yet. Only the start of each note is read.
+func (v *Vault) EncryptedNotes() ([]string, error) {
+	notes, err := v.ListNotes()
+	if err != nil {
+		return nil, err
+	}
+	var encrypted []string
+	head := make([]byte, 64)
+	for _, n := range notes {
+		f, err := os.Open(filepath.Join(v.Root, n.Path))
+		if err != nil {
+			return nil, err
+		}
+		k, err := io.ReadFull(f, head)
+		// Shorter notes just fill less of head.
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			err = nil
+		}
+		if err := errors.Join(err, f.Close()); err != nil {
+			return nil, err
+		}
+		if IsEncrypted(head[:k]) {
+			encrypted = append(encrypted, n.Path)
+		}
+	}
+	return encrypted, nil
+}
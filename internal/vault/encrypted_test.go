@@ -0,0 +1,33 @@
+package vault
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestEncryptedNotes(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"plain.md":          "# Plain\n",
+		"empty.md":          "",
+		"crypt/secret.md":   "\x00GITCRYPT\x00\x8f\x02binary",
+		"crypt/age.md":      "age-encryption.org/v1\n-> X25519 abc\n",
+		"crypt/armored.md":  "-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n",
+		"crypt/mentions.md": "The header is -----BEGIN AGE ENCRYPTED FILE-----\n",
+		"crypt/image.png":   "\x00GITCRYPT\x00",
+	})
+
+	got, err := New(root).EncryptedNotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []string
+	for _, p := range []string{"crypt/age.md", "crypt/armored.md", "crypt/secret.md"} {
+		want = append(want, filepath.FromSlash(p))
+	}
+	slices.Sort(got)
+	if !slices.Equal(got, want) {
+		t.Errorf("EncryptedNotes = %v, want %v", got, want)
+	}
+}
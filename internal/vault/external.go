@@ -0,0 +1,130 @@
+package vault
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ExternalDir is the virtual directory external sources are listed under.
+// A note in one is addressed as ExternalDir/<source name>/<path in source>.
+const ExternalDir = "@external"
+
+// Source is a read-only directory of markdown notes outside the vault, such
+// as a work repo's docs/.
+type Source struct {
+	Name string
+	Root string // absolute
+}
+
+// Sources are the external sources of a vault.
+type Sources []Source
+
+// IsExternal reports whether relPath is the virtual path of something in
+// an external source.
+func IsExternal(relPath string) bool {
+	relPath = filepath.ToSlash(relPath)
+	return relPath == ExternalDir || strings.HasPrefix(relPath, ExternalDir+"/")
+}
+
+// Rel returns the virtual path of absPath if it lies in one of the sources.
+func (s Sources) Rel(absPath string) (string, bool) {
+	for _, src := range s {
+		rel, err := filepath.Rel(src.Root, absPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if rel == "." {
+			return filepath.Join(ExternalDir, src.Name), true
+		}
+		return filepath.Join(ExternalDir, src.Name, rel), true
+	}
+	return "", false
+}
+
+// Abs returns the absolute path a virtual path refers to, if it names one
+// of the sources.
+func (s Sources) Abs(relPath string) (string, bool) {
+	rest, ok := strings.CutPrefix(filepath.ToSlash(relPath), ExternalDir+"/")
+	if !ok {
+		return "", false
+	}
+	name, inner, _ := strings.Cut(rest, "/")
+	for _, src := range s {
+		if src.Name == name {
+			return filepath.Join(src.Root, filepath.FromSlash(inner)), true
+		}
+	}
+	return "", false
+}
+
+// AbsPath returns the absolute path of a vault-relative path, or of a
+// virtual path in an external source.
+func (v *Vault) AbsPath(relPath string) string {
+	if abs, ok := v.Sources.Abs(relPath); ok {
+		return abs
+	}
+	return filepath.Join(v.Root, relPath)
+}
+
+// checkWritable refuses writes to external sources, which Kopr only reads.
+func checkWritable(relPath string) error {
+	if IsExternal(relPath) {
+		return fmt.Errorf("%s is in a read-only external source", relPath)
+	}
+	return nil
+}
+
+// ExternalEntries lists the directories and notes of the external sources
+// under a virtual "External" directory, each source in its own directory
+// named after it, in the order they were configured. It is empty without
+// sources.
+func (v *Vault) ExternalEntries() ([]Entry, error) {
+	if len(v.Sources) == 0 {
+		return nil, nil
+	}
+	entries := []Entry{{Name: "External", Path: ExternalDir, IsDir: true}}
+	for _, src := range v.Sources {
+		prefix := filepath.Join(ExternalDir, src.Name)
+		entries = append(entries, Entry{Name: src.Name, Path: prefix, IsDir: true, Depth: 1})
+
+		var inner []Entry
+		err := filepath.Walk(src.Root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil // skip errors
+			}
+			rel, err := filepath.Rel(src.Root, path)
+			if err != nil || rel == "." {
+				return nil
+			}
+			name := info.Name()
+			if strings.HasPrefix(name, ".") {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir() && !strings.HasSuffix(name, ".md") {
+				return nil
+			}
+			p := filepath.Join(prefix, rel)
+			inner = append(inner, Entry{
+				Name:  name,
+				Path:  p,
+				IsDir: info.IsDir(),
+				Depth: strings.Count(p, string(filepath.Separator)),
+			})
+			return nil
+		})
+		if err != nil {
+			return entries, fmt.Errorf("source %s: %w", src.Name, err)
+		}
+		sort.Slice(inner, func(i, j int) bool {
+			return entryLess(inner[i], inner[j])
+		})
+		entries = append(entries, inner...)
+	}
+	return entries, nil
+}
@@ -0,0 +1,91 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExternalEntries(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{
+		"guide.md":         "g",
+		"api/auth.md":      "a",
+		"api/diagram.png":  "ignored",
+		".drafts/draft.md": "hidden",
+	})
+
+	v := New(t.TempDir())
+	if entries, err := v.ExternalEntries(); err != nil || entries != nil {
+		t.Fatalf("without sources got %+v, %v", entries, err)
+	}
+
+	v.Sources = Sources{{Name: "work", Root: src}}
+	entries, err := v.ExternalEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Name: "External", Path: "@external", IsDir: true},
+		{Name: "work", Path: filepath.FromSlash("@external/work"), IsDir: true, Depth: 1},
+		{Name: "api", Path: filepath.FromSlash("@external/work/api"), IsDir: true, Depth: 2},
+		{Name: "auth.md", Path: filepath.FromSlash("@external/work/api/auth.md"), Depth: 3},
+		{Name: "guide.md", Path: filepath.FromSlash("@external/work/guide.md"), Depth: 2},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("got %+v, want %+v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("[%d] got %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+}
+
+func TestSourcesPaths(t *testing.T) {
+	src := t.TempDir()
+	v := New(t.TempDir())
+	v.Sources = Sources{{Name: "work", Root: src}}
+
+	rel, ok := v.Sources.Rel(filepath.Join(src, "api", "auth.md"))
+	if want := filepath.FromSlash("@external/work/api/auth.md"); !ok || rel != want {
+		t.Errorf("Rel = %q, %v, want %q", rel, ok, want)
+	}
+	if _, ok := v.Sources.Rel(filepath.Join(v.Root, "note.md")); ok {
+		t.Error("Rel of a vault note should fail")
+	}
+
+	if got, want := v.AbsPath("@external/work/api/auth.md"), filepath.Join(src, "api", "auth.md"); got != want {
+		t.Errorf("AbsPath(external) = %q, want %q", got, want)
+	}
+	if got, want := v.AbsPath("note.md"), filepath.Join(v.Root, "note.md"); got != want {
+		t.Errorf("AbsPath(note) = %q, want %q", got, want)
+	}
+	if got, want := v.AbsPath("@external/other/x.md"), filepath.Join(v.Root, "@external", "other", "x.md"); got != want {
+		t.Errorf("AbsPath(unknown source) = %q, want %q", got, want)
+	}
+}
+
+func TestExternalSourcesAreReadOnly(t *testing.T) {
+	src := t.TempDir()
+	writeFiles(t, src, map[string]string{"guide.md": "g"})
+	v := New(t.TempDir())
+	v.Sources = Sources{{Name: "work", Root: src}}
+
+	if _, err := v.CreateNote("@external/work/new.md", "x"); err == nil {
+		t.Error("CreateNote in an external source succeeded")
+	}
+	if _, err := v.DeleteNote("@external/work/guide.md"); err == nil {
+		t.Error("DeleteNote in an external source succeeded")
+	}
+	if err := v.MoveNote("@external/work/guide.md", "notes"); err == nil {
+		t.Error("MoveNote out of an external source succeeded")
+	}
+	// Copying a note into the vault only reads the source.
+	if err := v.CopyNote("@external/work/guide.md", "notes"); err != nil {
+		t.Fatalf("CopyNote into the vault: %v", err)
+	}
+	if got, err := os.ReadFile(filepath.Join(v.Root, "notes", "guide.md")); err != nil || string(got) != "g" {
+		t.Errorf("copied content = %q, %v, want %q", got, err, "g")
+	}
+}
@@ -0,0 +1,148 @@
+package vault
+
+import (
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is the gitignore-style file at the vault root listing paths
+// left out of the tree, the index and the watcher.
+const IgnoreFile = ".koprignore"
+
+// Ignore matches vault-relative paths against the patterns of an
+// IgnoreFile. It follows gitignore: blank lines and lines starting with #
+// are skipped, a pattern ending in / matches only directories, a pattern
+// with a / anywhere else is relative to the vault root and otherwise
+// matches a name at any depth, * ? and [...] match within a name and **
+// across directories, and ! re-includes what an earlier pattern excluded.
+// As in git, nothing inside an ignored directory can be re-included.
+//
+// The nil *Ignore ignores nothing.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+type ignoreRule struct {
+	segments []string // pattern split at /
+	negate   bool
+	dirOnly  bool
+	anchored bool // matched against the whole path rather than the name
+}
+
+// ParseIgnore parses the contents of an IgnoreFile.
+func ParseIgnore(content string) *Ignore {
+	ig := &Ignore{}
+	for _, line := range strings.Split(content, "\n") {
+		if r, ok := parseIgnoreRule(line); ok {
+			ig.rules = append(ig.rules, r)
+		}
+	}
+	return ig
+}
+
+// ReadIgnore reads the IgnoreFile at the root of the vault. A vault without
+// one ignores nothing.
+func ReadIgnore(root string) (*Ignore, error) {
+	content, err := os.ReadFile(filepath.Join(root, IgnoreFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseIgnore(string(content)), nil
+}
+
+func parseIgnoreRule(line string) (ignoreRule, bool) {
+	var r ignoreRule
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return r, false
+	}
+	if strings.HasPrefix(line, "!") {
+		r.negate, line = true, line[1:]
+	}
+	// \# and \! start patterns with a literal # or !.
+	line = strings.TrimPrefix(line, `\`)
+	if strings.HasSuffix(line, "/") {
+		r.dirOnly, line = true, strings.TrimRight(line, "/")
+	}
+	if strings.Contains(line, "/") {
+		r.anchored, line = true, strings.TrimPrefix(line, "/")
+	}
+	if line == "" {
+		return r, false
+	}
+	r.segments = strings.Split(line, "/")
+	return r, true
+}
+
+// Ignored reports whether the vault-relative path rel, a directory when
+// isDir, is ignored itself or lies in an ignored directory.
+func (ig *Ignore) Ignored(rel string, isDir bool) bool {
+	if ig == nil || len(ig.rules) == 0 {
+		return false
+	}
+	segments := strings.Split(filepath.ToSlash(rel), "/")
+	for i := 1; i <= len(segments); i++ {
+		if ig.match(segments[:i], i < len(segments) || isDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// match applies the rules to one path; the last rule matching it wins.
+func (ig *Ignore) match(segments []string, isDir bool) bool {
+	ignored := false
+	for _, r := range ig.rules {
+		if r.match(segments, isDir) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+func (r ignoreRule) match(segments []string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		return matchName(r.segments[0], segments[len(segments)-1])
+	}
+	return matchSegments(r.segments, segments)
+}
+
+// matchSegments matches a path against a pattern, segment by segment, with
+// ** standing for any number of directories.
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				// A trailing /** matches everything inside, not the
+				// directory itself.
+				return len(segments) > 0
+			}
+			for i := range len(segments) + 1 {
+				if matchSegments(rest, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 || !matchName(pattern[0], segments[0]) {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+func matchName(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
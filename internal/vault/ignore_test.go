@@ -0,0 +1,95 @@
+package vault
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	ig := ParseIgnore(`
+# comments and blank lines are skipped
+
+node_modules
+archive/
+/drafts/*.md
+!/drafts/keep.md
+**/build/**
+docs/**/secret.md
+*.tmp
+\#hash.md
+`)
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"node_modules", true, true},
+		{"projects/web/node_modules", true, true},
+		{"projects/web/node_modules/pkg/README.md", false, true},
+		{"archive", true, true},
+		{"archive/old.md", false, true},
+		{"notes/archive", true, true},
+		{"archive", false, false}, // a file named like the directory
+		{"drafts/idea.md", false, true},
+		{"drafts/keep.md", false, false},
+		{"notes/drafts/idea.md", false, false}, // anchored to the root
+		{"drafts/sub/idea.md", false, false},   // * stays within a name
+		{"build", true, false},                 // /** matches only inside
+		{"app/build/out.md", false, true},
+		{"docs/secret.md", false, true},
+		{"docs/a/b/secret.md", false, true},
+		{"scratch.tmp", false, true},
+		{"#hash.md", false, true},
+		{"notes/plan.md", false, false},
+	}
+	for _, tt := range tests {
+		if got := ig.Ignored(filepath.FromSlash(tt.path), tt.isDir); got != tt.want {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+
+	// Nothing inside an ignored directory can be re-included.
+	ig = ParseIgnore("archive/\n!archive/keep.md\n")
+	if !ig.Ignored(filepath.FromSlash("archive/keep.md"), false) {
+		t.Error("archive/keep.md re-included from an ignored directory")
+	}
+
+	var none *Ignore
+	if none.Ignored("anything.md", false) {
+		t.Error("nil Ignore ignored a path")
+	}
+}
+
+func TestListEntriesIgnore(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		IgnoreFile:                       "archive/\nnode_modules\n",
+		"a.md":                           "a",
+		"archive/old.md":                 "o",
+		"web/node_modules/pkg/readme.md": "r",
+		"web/index.md":                   "i",
+	})
+
+	v := New(root)
+	ig, err := ReadIgnore(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.Ignore = ig
+	entries, err := v.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, e := range entries {
+		got = append(got, filepath.ToSlash(e.Path))
+	}
+	if want := []string{"web", "web/index.md", "a.md"}; !slices.Equal(got, want) {
+		t.Errorf("ListEntries = %v, want %v", got, want)
+	}
+
+	if ig, err := ReadIgnore(t.TempDir()); err != nil || ig != nil {
+		t.Errorf("ReadIgnore without a file = %v, %v; want nil", ig, err)
+	}
+}
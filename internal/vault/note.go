@@ -5,8 +5,16 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"github.com/pfassina/kopr/internal/markdown"
 )
 
+// DailyDir is the vault-relative directory that holds daily notes.
+const DailyDir = "daily"
+
+// DailyDateFormat is the time layout used for daily note basenames.
+const DailyDateFormat = "2006-01-02"
+
 // Note represents a note in the vault.
 type Note struct {
 	Path    string
@@ -14,8 +22,12 @@ type Note struct {
 	Content string
 }
 
-// CreateNote creates a new note file with the given content.
+// CreateNote creates a new note file with the given content, giving it a
+// uid first if AssignUIDs is set.
 func (v *Vault) CreateNote(relPath, content string) (string, error) {
+	if err := checkWritable(relPath); err != nil {
+		return "", err
+	}
 	absPath := filepath.Join(v.Root, relPath)
 
 	// Ensure parent directory exists
@@ -29,7 +41,11 @@ func (v *Vault) CreateNote(relPath, content string) (string, error) {
 		return absPath, nil
 	}
 
-	if err := os.WriteFile(absPath, []byte(content), 0644); err != nil {
+	data := []byte(content)
+	if v.AssignUIDs && noteUID(data) == "" {
+		data = markdown.SetFrontmatterField(data, "uid", newUUID())
+	}
+	if err := os.WriteFile(absPath, data, 0644); err != nil {
 		return "", fmt.Errorf("write note: %w", err)
 	}
 
@@ -39,8 +55,8 @@ func (v *Vault) CreateNote(relPath, content string) (string, error) {
 // CreateDailyNote creates a daily note with today's date.
 func (v *Vault) CreateDailyNote() (string, error) {
 	now := time.Now()
-	date := now.Format("2006-01-02")
-	relPath := filepath.Join("daily", date+".md")
+	date := now.Format(DailyDateFormat)
+	relPath := filepath.Join(DailyDir, date+".md")
 
 	content := fmt.Sprintf(`---
 title: %s
@@ -55,14 +71,27 @@ tags: [daily]
 	return v.CreateNote(relPath, content)
 }
 
-// DeleteNote removes a note file from the vault.
-func (v *Vault) DeleteNote(relPath string) error {
-	absPath := filepath.Join(v.Root, relPath)
-	return os.Remove(absPath)
+// DeleteNote moves a note file, or a directory of them, to the trash, from
+// where RestoreTrash brings it back.
+func (v *Vault) DeleteNote(relPath string) (TrashItem, error) {
+	if err := checkWritable(relPath); err != nil {
+		return TrashItem{}, err
+	}
+	now := time.Now()
+	trashed, err := v.trash(relPath, now)
+	if err != nil {
+		return TrashItem{}, err
+	}
+	return TrashItem{Path: relPath, Trashed: trashed, Deleted: now.Truncate(time.Second)}, nil
 }
 
 // RenameNote renames a note file within the vault.
 func (v *Vault) RenameNote(oldRel, newRel string) error {
+	for _, rel := range []string{oldRel, newRel} {
+		if err := checkWritable(rel); err != nil {
+			return err
+		}
+	}
 	oldAbs := filepath.Join(v.Root, oldRel)
 	newAbs := filepath.Join(v.Root, newRel)
 
@@ -81,6 +110,9 @@ func (v *Vault) RenameNote(oldRel, newRel string) error {
 
 // CreateDir creates a directory inside the vault.
 func (v *Vault) CreateDir(relPath string) error {
+	if err := checkWritable(relPath); err != nil {
+		return err
+	}
 	absPath := filepath.Join(v.Root, relPath)
 	return os.MkdirAll(absPath, 0755)
 }
@@ -92,9 +124,18 @@ func (v *Vault) MoveNote(oldRel, newDir string) error {
 }
 
 // CopyNote copies a note to a new directory, keeping the same filename.
+// The note may come from an external source.
 func (v *Vault) CopyNote(srcRel, destDir string) error {
-	srcAbs := filepath.Join(v.Root, srcRel)
-	destRel := filepath.Join(destDir, filepath.Base(srcRel))
+	return v.CopyNoteTo(srcRel, filepath.Join(destDir, filepath.Base(srcRel)))
+}
+
+// CopyNoteTo copies a note to destRel, which must not exist yet. A copy of
+// a note with a uid gets a uid of its own.
+func (v *Vault) CopyNoteTo(srcRel, destRel string) error {
+	srcAbs := v.AbsPath(srcRel)
+	if err := checkWritable(destRel); err != nil {
+		return err
+	}
 	destAbs := filepath.Join(v.Root, destRel)
 
 	if err := os.MkdirAll(filepath.Dir(destAbs), 0755); err != nil {
@@ -109,10 +150,47 @@ func (v *Vault) CopyNote(srcRel, destDir string) error {
 	if err != nil {
 		return fmt.Errorf("read source: %w", err)
 	}
+	if noteUID(data) != "" {
+		data = markdown.SetFrontmatterField(data, "uid", newUUID())
+	}
 
 	return os.WriteFile(destAbs, data, 0644)
 }
 
+// EnsureUID returns the uid of the note at relPath, first giving it one if
+// it has none.
+func (v *Vault) EnsureUID(relPath string) (string, error) {
+	absPath := v.AbsPath(relPath)
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return "", err
+	}
+	if uid := noteUID(data); uid != "" {
+		return uid, nil
+	}
+	if err := checkWritable(relPath); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(absPath)
+	if err != nil {
+		return "", err
+	}
+	uid := newUUID()
+	data = markdown.SetFrontmatterField(data, "uid", uid)
+	if err := os.WriteFile(absPath, data, info.Mode().Perm()); err != nil {
+		return "", fmt.Errorf("write note: %w", err)
+	}
+	return uid, nil
+}
+
+// noteUID returns the uid in a note's frontmatter, or "" if it has none.
+func noteUID(content []byte) string {
+	if fm := markdown.ExtractFrontmatter(content); fm != nil {
+		return fm.UID
+	}
+	return ""
+}
+
 // CreateInboxNote creates a quick inbox note.
 func (v *Vault) CreateInboxNote() (string, error) {
 	now := time.Now()
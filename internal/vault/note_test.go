@@ -0,0 +1,73 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pfassina/kopr/internal/markdown"
+)
+
+func TestNoteUIDs(t *testing.T) {
+	v := New(t.TempDir())
+	read := func(rel string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(v.Root, rel))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	uidOf := func(rel string) string {
+		t.Helper()
+		return noteUID([]byte(read(rel)))
+	}
+
+	if _, err := v.CreateNote("plain.md", "# Plain\n"); err != nil {
+		t.Fatal(err)
+	}
+	if uid := uidOf("plain.md"); uid != "" {
+		t.Errorf("note created without AssignUIDs has uid %q", uid)
+	}
+
+	v.AssignUIDs = true
+	if _, err := v.CreateNote("a.md", "---\ntitle: A\n---\n# A\n"); err != nil {
+		t.Fatal(err)
+	}
+	uid := uidOf("a.md")
+	if uid == "" || !strings.HasPrefix(read("a.md"), "---\ntitle: A\nuid: ") {
+		t.Errorf("a.md = %q, want a uid: line in its frontmatter", read("a.md"))
+	}
+	if _, err := v.CreateNote("b.md", "---\nuid: mine\n---\n"); err != nil {
+		t.Fatal(err)
+	}
+	if got := read("b.md"); got != "---\nuid: mine\n---\n" {
+		t.Errorf("b.md = %q, want its own uid kept", got)
+	}
+
+	// The uid moves with the note; a copy gets one of its own.
+	if err := v.MoveNote("a.md", "sub"); err != nil {
+		t.Fatal(err)
+	}
+	if got := uidOf("sub/a.md"); got != uid {
+		t.Errorf("uid after move = %q, want %q", got, uid)
+	}
+	if err := v.CopyNoteTo("sub/a.md", "a-copy.md"); err != nil {
+		t.Fatal(err)
+	}
+	if got := uidOf("a-copy.md"); got == "" || got == uid {
+		t.Errorf("copy's uid = %q, want a new one (original %q)", got, uid)
+	}
+
+	got, err := v.EnsureUID("plain.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fm := markdown.ExtractFrontmatter([]byte(read("plain.md"))); fm == nil || fm.UID != got || got == "" {
+		t.Errorf("EnsureUID = %q; plain.md = %q", got, read("plain.md"))
+	}
+	if again, err := v.EnsureUID("plain.md"); err != nil || again != got {
+		t.Errorf("EnsureUID again = %q, %v; want %q", again, err, got)
+	}
+}
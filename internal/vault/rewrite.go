@@ -2,13 +2,15 @@ package vault
 
 import (
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 )
 
 // replaceWikiLinkTargets replaces wiki link targets matching oldName with newName.
 // Handles: [[old]], [[old.md]], [[old#section]], [[old|alias]], [[old#section|alias]],
-// [[old.md#section]], [[old.md|alias]], [[old.md#section|alias]].
+// [[old.md#section]], [[old.md|alias]], [[old.md#section|alias]], and the same
+// forms as embeds (![[old]]).
 func replaceWikiLinkTargets(content, oldName, newName string) string {
 	// Match [[oldName]] with optional .md, #section, and |alias
 	// The pattern captures: [[ + oldName + optional .md + optional #section + optional |alias + ]]
@@ -46,25 +48,164 @@ func replaceWikiLinkTargets(content, oldName, newName string) string {
 	})
 }
 
-// RewriteLinksInNote reads a note file, replaces wiki link targets from oldName
-// to newName, and writes it back if any changes were made.
-// Returns true if the file was modified.
-func RewriteLinksInNote(absPath, oldName, newName string) (bool, error) {
-	data, err := os.ReadFile(absPath)
-	if err != nil {
-		return false, err
+// replaceMarkdownLinkTargets replaces inline markdown link destinations that
+// point at oldName.md with newName.md. Handles [text](old.md), [text](dir/old.md),
+// [text](old.md#section), [text](old.md "title"), [text](<old name.md>),
+// [text](old%20name.md) and image-style embeds (![alt](old.md)). Only the
+// basename changes, so relative directories stay intact. External URLs are
+// left alone.
+func replaceMarkdownLinkTargets(content, oldName, newName string) string {
+	return replaceMarkdownLinkTargetsIn(content, oldName, newName, nil)
+}
+
+// replaceMarkdownLinkTargetsIn is replaceMarkdownLinkTargets limited to
+// destinations whose directory part (e.g. "../b/", or "" for none) inDir
+// accepts. A nil inDir accepts every directory.
+func replaceMarkdownLinkTargetsIn(content, oldName, newName string, inDir func(dir string) bool) string {
+	content = replaceMarkdownLinkName(content, oldName, newName, inDir)
+	if escaped := escapeLinkSpaces(oldName); escaped != oldName {
+		content = replaceMarkdownLinkName(content, escaped, escapeLinkSpaces(newName), inDir)
+	}
+	return content
+}
+
+func replaceMarkdownLinkName(content, oldName, newName string, inDir func(dir string) bool) string {
+	// ]( + optional < + optional dir/ + oldName.md + optional #frag + optional > + optional "title" + )
+	pattern := `\]\((<?)([^()<>\s]*/)?` + regexp.QuoteMeta(oldName) + `\.md([#?][^()<>\s]*)?(>?)((?:\s+"[^"]*")?)\)`
+	re := regexp.MustCompile(pattern)
+
+	return re.ReplaceAllStringFunc(content, func(match string) string {
+		m := re.FindStringSubmatch(match)
+		lt, dir, frag, gt, title := m[1], m[2], m[3], m[4], m[5]
+		if strings.Contains(dir, "://") || (lt == "<") != (gt == ">") {
+			return match
+		}
+		if inDir != nil && !inDir(dir) {
+			return match
+		}
+		name := newName
+		if lt == "" {
+			// Bare destinations can't contain spaces.
+			name = escapeLinkSpaces(name)
+		}
+		return "](" + lt + dir + name + ".md" + frag + gt + title + ")"
+	})
+}
+
+// replaceQualifiedWikiLinkTargets replaces wiki link targets naming the note
+// at oldRel with newName, keeping any folder qualifier: [[a/old]] becomes
+// [[a/new]] when oldRel is in a folder ending in a/. Unqualified [[old]]
+// links change only if bare is set, for vaults where the name alone may
+// mean another note.
+func replaceQualifiedWikiLinkTargets(content, oldRel, newName string, bare bool) string {
+	oldName := strings.TrimSuffix(filepath.Base(oldRel), ".md")
+	oldDir := "/"
+	if d := filepath.ToSlash(filepath.Dir(oldRel)); d != "." {
+		oldDir = "/" + strings.ToLower(d) + "/"
 	}
+	pattern := `\[\[([^\[\]|#]*/)?` + regexp.QuoteMeta(oldName) + `(\.md)?([#|][^\]]*?)?\]\]`
+	re := regexp.MustCompile(pattern)
+
+	return re.ReplaceAllStringFunc(content, func(match string) string {
+		m := re.FindStringSubmatch(match)
+		qualifier, ext, suffix := m[1], m[2], m[3]
+		if qualifier == "" && !bare {
+			return match
+		}
+		if qualifier != "" && !strings.HasSuffix(oldDir, "/"+strings.ToLower(strings.TrimPrefix(qualifier, "/"))) {
+			return match
+		}
+		return "[[" + qualifier + newName + ext + suffix + "]]"
+	})
+}
 
-	original := string(data)
-	updated := replaceWikiLinkTargets(original, oldName, newName)
+// escapeLinkSpaces percent-encodes spaces the way markdown link destinations
+// commonly write them.
+func escapeLinkSpaces(name string) string {
+	return strings.ReplaceAll(name, " ", "%20")
+}
 
-	if updated == original {
-		return false, nil
+// rewriteLinks applies every link form rewrite from oldName to newName.
+func rewriteLinks(content, oldName, newName string) string {
+	content = replaceWikiLinkTargets(content, oldName, newName)
+	return replaceMarkdownLinkTargets(content, oldName, newName)
+}
+
+// PlanLinkRewrites computes the link rewrites from oldName to newName for the
+// given notes without writing anything. Only notes that change are returned;
+// pass the result to ApplyRewrites to commit them together.
+func PlanLinkRewrites(absPaths []string, oldName, newName string) ([]FileRewrite, error) {
+	var rewrites []FileRewrite
+	for _, p := range absPaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		original := string(data)
+		updated := rewriteLinks(original, oldName, newName)
+		if updated != original {
+			rewrites = append(rewrites, FileRewrite{Path: p, Content: []byte(updated)})
+		}
 	}
+	return rewrites, nil
+}
 
-	if err := os.WriteFile(absPath, []byte(updated), 0644); err != nil {
-		return false, err
+// PlanScopedLinkRewrites is PlanLinkRewrites for vaults with folder-scoped
+// basenames, where other notes may share the name of the note at oldRel
+// (relative to root): only links that point at it change. Wiki links match
+// through their folder qualifier, or bare as for
+// replaceQualifiedWikiLinkTargets; markdown links when they resolve,
+// relative to the linking note, to oldRel.
+func PlanScopedLinkRewrites(root string, absPaths []string, oldRel, newName string, bare bool) ([]FileRewrite, error) {
+	oldName := strings.TrimSuffix(filepath.Base(oldRel), ".md")
+	oldDir := filepath.Dir(oldRel)
+	var rewrites []FileRewrite
+	for _, p := range absPaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		srcDir := "."
+		if rel, err := filepath.Rel(root, p); err == nil {
+			srcDir = filepath.Dir(rel)
+		}
+		inDir := func(dir string) bool {
+			dir = strings.ReplaceAll(dir, "%20", " ")
+			if strings.HasPrefix(dir, "/") {
+				return filepath.Clean(dir[1:]) == oldDir
+			}
+			return filepath.Join(srcDir, dir) == oldDir
+		}
+		original := string(data)
+		updated := replaceQualifiedWikiLinkTargets(original, oldRel, newName, bare)
+		updated = replaceMarkdownLinkTargetsIn(updated, oldName, newName, inDir)
+		if updated != original {
+			rewrites = append(rewrites, FileRewrite{Path: p, Content: []byte(updated)})
+		}
 	}
+	return rewrites, nil
+}
 
-	return true, nil
+// PlanReplace computes a literal, case-sensitive replacement of find with
+// replace across the given notes without writing anything. Only notes that
+// change are returned; pass the result to ApplyRewrites to commit them
+// together.
+func PlanReplace(absPaths []string, find, replace string) ([]FileRewrite, error) {
+	if find == "" {
+		return nil, nil
+	}
+	var rewrites []FileRewrite
+	for _, p := range absPaths {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		original := string(data)
+		if !strings.Contains(original, find) {
+			continue
+		}
+		updated := strings.ReplaceAll(original, find, replace)
+		rewrites = append(rewrites, FileRewrite{Path: p, Content: []byte(updated)})
+	}
+	return rewrites, nil
 }
@@ -1,7 +1,6 @@
 package vault
 
 import (
-	"os"
 	"path/filepath"
 	"testing"
 )
@@ -91,6 +90,13 @@ func TestReplaceWikiLinkTargets(t *testing.T) {
 			newName: "renamed-note",
 			want:    "See [[my-note-extra]] for details.",
 		},
+		{
+			name:    "embed",
+			content: "![[my-note]] and ![[my-note#intro]]",
+			oldName: "my-note",
+			newName: "renamed-note",
+			want:    "![[renamed-note]] and ![[renamed-note#intro]]",
+		},
 	}
 
 	for _, tt := range tests {
@@ -103,48 +109,171 @@ func TestReplaceWikiLinkTargets(t *testing.T) {
 	}
 }
 
-func TestRewriteLinksInNote(t *testing.T) {
+func TestReplaceMarkdownLinkTargets(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		oldName string
+		newName string
+		want    string
+	}{
+		{
+			name:    "simple link",
+			content: "See [the note](my-note.md).",
+			oldName: "my-note",
+			newName: "renamed-note",
+			want:    "See [the note](renamed-note.md).",
+		},
+		{
+			name:    "relative directory preserved",
+			content: "See [x](../projects/my-note.md) and [y](./my-note.md).",
+			oldName: "my-note",
+			newName: "renamed-note",
+			want:    "See [x](../projects/renamed-note.md) and [y](./renamed-note.md).",
+		},
+		{
+			name:    "fragment and title",
+			content: `See [x](my-note.md#intro "Intro").`,
+			oldName: "my-note",
+			newName: "renamed-note",
+			want:    `See [x](renamed-note.md#intro "Intro").`,
+		},
+		{
+			name:    "image embed",
+			content: "![preview](my-note.md)",
+			oldName: "my-note",
+			newName: "renamed-note",
+			want:    "![preview](renamed-note.md)",
+		},
+		{
+			name:    "angle brackets keep spaces",
+			content: "[x](<my note.md>)",
+			oldName: "my note",
+			newName: "new note",
+			want:    "[x](<new note.md>)",
+		},
+		{
+			name:    "percent-encoded spaces",
+			content: "[x](my%20note.md)",
+			oldName: "my note",
+			newName: "new note",
+			want:    "[x](new%20note.md)",
+		},
+		{
+			name:    "bare destination gets encoded spaces",
+			content: "[x](my-note.md)",
+			oldName: "my-note",
+			newName: "new note",
+			want:    "[x](new%20note.md)",
+		},
+		{
+			name:    "external URL untouched",
+			content: "[x](https://example.com/my-note.md)",
+			oldName: "my-note",
+			newName: "renamed-note",
+			want:    "[x](https://example.com/my-note.md)",
+		},
+		{
+			name:    "partial name no match",
+			content: "[x](not-my-note.md) [y](my-note-extra.md)",
+			oldName: "my-note",
+			newName: "renamed-note",
+			want:    "[x](not-my-note.md) [y](my-note-extra.md)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := replaceMarkdownLinkTargets(tt.content, tt.oldName, tt.newName)
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanLinkRewrites(t *testing.T) {
 	dir := t.TempDir()
-	notePath := filepath.Join(dir, "source.md")
+	writeFiles(t, dir, map[string]string{
+		"a.md": "See [[old-name]].",
+		"b.md": "Nothing here.",
+		"c.md": "[x](old-name.md)",
+	})
 
-	content := "# Source\n\nLinks to [[old-name]] and [[old-name#section]].\n"
-	if err := os.WriteFile(notePath, []byte(content), 0644); err != nil {
+	paths := []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md"), filepath.Join(dir, "c.md")}
+	rewrites, err := PlanLinkRewrites(paths, "old-name", "new-name")
+	if err != nil {
 		t.Fatal(err)
 	}
+	if len(rewrites) != 2 {
+		t.Fatalf("expected 2 rewrites, got %d", len(rewrites))
+	}
+	if string(rewrites[0].Content) != "See [[new-name]]." || string(rewrites[1].Content) != "[x](new-name.md)" {
+		t.Errorf("unexpected rewrites: %q, %q", rewrites[0].Content, rewrites[1].Content)
+	}
 
-	changed, err := RewriteLinksInNote(notePath, "old-name", "new-name")
+	// Planning must not write.
+	assertContent(t, filepath.Join(dir, "a.md"), "See [[old-name]].")
+}
+
+func TestPlanScopedLinkRewrites(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"index.md":            "[[a/notes]] [[b/notes|B]] [[projects/a/notes#x]] [[notes]]",
+		"projects/a/notes.md": "[x](notes.md) [y](../b/notes.md)",
+		"projects/b/notes.md": "[a](../a/notes.md) [[notes]]",
+	})
+
+	var paths []string
+	for _, rel := range []string{"index.md", "projects/a/notes.md", "projects/b/notes.md"} {
+		paths = append(paths, filepath.Join(dir, rel))
+	}
+
+	rewrites, err := PlanScopedLinkRewrites(dir, paths, "projects/a/notes.md", "todo", false)
 	if err != nil {
 		t.Fatal(err)
 	}
-	if !changed {
-		t.Error("expected file to be changed")
+	got := map[string]string{}
+	for _, rw := range rewrites {
+		got[rw.Path] = string(rw.Content)
+	}
+	want := map[string]string{
+		paths[0]: "[[a/todo]] [[b/notes|B]] [[projects/a/todo#x]] [[notes]]",
+		paths[1]: "[x](todo.md) [y](../b/notes.md)",
+		paths[2]: "[a](../a/todo.md) [[notes]]",
+	}
+	for p, w := range want {
+		if got[p] != w {
+			t.Errorf("%s: got %q, want %q", p, got[p], w)
+		}
 	}
 
-	data, err := os.ReadFile(notePath)
+	// Bare links change when the name alone resolves to the renamed note.
+	rewrites, err = PlanScopedLinkRewrites(dir, paths[:1], "projects/a/notes.md", "todo", true)
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	want := "# Source\n\nLinks to [[new-name]] and [[new-name#section]].\n"
-	if string(data) != want {
-		t.Errorf("got %q, want %q", string(data), want)
+	if len(rewrites) != 1 || string(rewrites[0].Content) != "[[a/todo]] [[b/notes|B]] [[projects/a/todo#x]] [[todo]]" {
+		t.Errorf("bare rewrite: %+v", rewrites)
 	}
 }
 
-func TestRewriteLinksInNote_NoChange(t *testing.T) {
+func TestPlanReplace(t *testing.T) {
 	dir := t.TempDir()
-	notePath := filepath.Join(dir, "source.md")
+	writeFiles(t, dir, map[string]string{
+		"a.md": "Kubernetes and kubernetes.\nMore Kubernetes.",
+		"b.md": "Nothing here.",
+	})
 
-	content := "# Source\n\nLinks to [[other-note]].\n"
-	if err := os.WriteFile(notePath, []byte(content), 0644); err != nil {
-		t.Fatal(err)
-	}
-
-	changed, err := RewriteLinksInNote(notePath, "old-name", "new-name")
+	paths := []string{filepath.Join(dir, "a.md"), filepath.Join(dir, "b.md")}
+	rewrites, err := PlanReplace(paths, "Kubernetes", "K8s")
 	if err != nil {
 		t.Fatal(err)
 	}
-	if changed {
-		t.Error("expected file to not be changed")
+	if len(rewrites) != 1 || string(rewrites[0].Content) != "K8s and kubernetes.\nMore K8s." {
+		t.Fatalf("unexpected rewrites: %+v", rewrites)
 	}
+
+	// Planning must not write.
+	assertContent(t, filepath.Join(dir, "a.md"), "Kubernetes and kubernetes.\nMore Kubernetes.")
 }
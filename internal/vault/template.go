@@ -1,9 +1,14 @@
 package vault
 
 import (
+	"crypto/rand"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -11,78 +16,176 @@ import (
 // Template represents a note template.
 type Template struct {
 	Name    string
+	Group   string // subfolder of the templates directory ("" at the top level)
 	Path    string
 	Content string
 }
 
-// LoadTemplates loads all templates from the vault's templates directory.
+// LoadTemplates loads all templates from the vault's templates directory,
+// including nested subfolders. Templates are ordered by group, then name.
 func (v *Vault) LoadTemplates() ([]Template, error) {
-	templateDir := filepath.Join(v.Root, "templates")
+	templateDir := v.TemplatesPath()
 
 	if _, err := os.Stat(templateDir); os.IsNotExist(err) {
 		return nil, nil
 	}
 
 	var templates []Template
-	entries, err := os.ReadDir(templateDir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
-			continue
+	err := filepath.WalkDir(templateDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(d.Name(), ".") && path != templateDir {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !strings.HasSuffix(d.Name(), ".md") {
+			return nil
 		}
 
-		path := filepath.Join(templateDir, entry.Name())
 		content, err := os.ReadFile(path)
 		if err != nil {
-			continue
+			return fmt.Errorf("read template: %w", err)
 		}
 
-		name := strings.TrimSuffix(entry.Name(), ".md")
+		rel, err := filepath.Rel(templateDir, path)
+		if err != nil {
+			return err
+		}
+		group := filepath.Dir(rel)
+		if group == "." {
+			group = ""
+		}
 		templates = append(templates, Template{
-			Name:    name,
+			Name:    strings.TrimSuffix(d.Name(), ".md"),
+			Group:   filepath.ToSlash(group),
 			Path:    path,
 			Content: string(content),
 		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
+	sort.SliceStable(templates, func(i, j int) bool {
+		if templates[i].Group != templates[j].Group {
+			return templates[i].Group < templates[j].Group
+		}
+		return templates[i].Name < templates[j].Name
+	})
 	return templates, nil
 }
 
+// TemplateContext supplies the values template variables expand to.
+type TemplateContext struct {
+	Title     string
+	Now       time.Time
+	Clipboard string // text last yanked in the editor
+	// UID is what {{uid}} expands to; when empty, ExpandTemplate makes up
+	// one, the same for every {{uid}} in the template.
+	UID string
+}
+
+// templateVar matches {{name}} with an optional date offset such as +7d.
+var templateVar = regexp.MustCompile(`\{\{(\w+)(?:([+-]\d+)([dwmy]))?\}\}`)
+
 // ExpandTemplate expands template variables in content.
 // Variables:
 //
 //	{{title}}     - Note title
-//	{{date}}      - Current date (YYYY-MM-DD)
-//	{{datetime}}  - Current datetime (YYYY-MM-DD HH:MM:SS)
-//	{{time}}      - Current time (HH:MM:SS)
 //	{{slug}}      - Slugified title
-func ExpandTemplate(content, title string) string {
-	now := time.Now()
-
-	replacements := map[string]string{
-		"{{title}}":    title,
-		"{{date}}":     now.Format("2006-01-02"),
-		"{{datetime}}": now.Format("2006-01-02 15:04:05"),
-		"{{time}}":     now.Format("15:04:05"),
-		"{{slug}}":     Slugify(title),
-	}
+//	{{date}}      - Date (YYYY-MM-DD)
+//	{{datetime}}  - Datetime (YYYY-MM-DD HH:MM:SS)
+//	{{time}}      - Time (HH:MM:SS)
+//	{{weekday}}   - Weekday name (Monday)
+//	{{week}}      - ISO week (2006-W01)
+//	{{uuid}}      - Random UUID (v4)
+//	{{uid}}       - The note's uid (see Vault.AssignUIDs)
+//	{{clipboard}} - Text last yanked in the editor
+//
+// Date variables accept an offset in days, weeks, months or years, e.g.
+// {{date+7d}}, {{date-1w}}, {{weekday+1d}}, {{week+1w}}. Unknown variables
+// are left as written.
+func ExpandTemplate(content string, ctx TemplateContext) string {
+	return templateVar.ReplaceAllStringFunc(content, func(match string) string {
+		m := templateVar.FindStringSubmatch(match)
+		name, offset, unit := m[1], m[2], m[3]
+
+		t := ctx.Now
+		if offset != "" {
+			n, err := strconv.Atoi(offset)
+			if err != nil {
+				return match
+			}
+			t = shiftDate(t, n, unit)
+		}
 
-	result := content
-	for placeholder, value := range replacements {
-		result = strings.ReplaceAll(result, placeholder, value)
+		switch name {
+		case "date":
+			return t.Format("2006-01-02")
+		case "datetime":
+			return t.Format("2006-01-02 15:04:05")
+		case "time":
+			return t.Format("15:04:05")
+		case "weekday":
+			return t.Weekday().String()
+		case "week":
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}
+		if offset != "" {
+			return match // offsets only apply to date variables
+		}
+		switch name {
+		case "title":
+			return ctx.Title
+		case "slug":
+			return Slugify(ctx.Title)
+		case "uuid":
+			return newUUID()
+		case "uid":
+			if ctx.UID == "" {
+				ctx.UID = newUUID()
+			}
+			return ctx.UID
+		case "clipboard":
+			return ctx.Clipboard
+		}
+		return match
+	})
+}
+
+// shiftDate moves t by n units of d(ays), w(eeks), m(onths) or y(ears).
+func shiftDate(t time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "w":
+		return t.AddDate(0, 0, 7*n)
+	case "m":
+		return t.AddDate(0, n, 0)
+	case "y":
+		return t.AddDate(n, 0, 0)
 	}
-	return result
+	return t.AddDate(0, 0, n)
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	rand.Read(b[:]) //nolint:errcheck // crypto/rand.Read never returns an error
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
 }
 
 // CreateFromTemplate creates a new note from a template.
-func (v *Vault) CreateFromTemplate(template Template, title string) (string, error) {
-	slug := Slugify(title)
+func (v *Vault) CreateFromTemplate(template Template, ctx TemplateContext) (string, error) {
+	slug := Slugify(ctx.Title)
 	relPath := slug + ".md"
 
-	content := ExpandTemplate(template.Content, title)
+	content := ExpandTemplate(template.Content, ctx)
 
 	absPath, err := v.CreateNote(relPath, content)
 	if err != nil {
@@ -0,0 +1,111 @@
+package vault
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadTemplatesNested(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"tpl/zettel.md":              "z",
+		"tpl/meetings/standup.md":    "s",
+		"tpl/meetings/1on1.md":       "o",
+		"tpl/meetings/notes.txt":     "ignored",
+		"tpl/.drafts/wip.md":         "hidden",
+		"tpl/reviews/weekly/week.md": "w",
+	})
+
+	v := New(root)
+	v.TemplateDir = "tpl"
+	templates, err := v.LoadTemplates()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct{ group, name string }{
+		{"", "zettel"},
+		{"meetings", "1on1"},
+		{"meetings", "standup"},
+		{"reviews/weekly", "week"},
+	}
+	if len(templates) != len(want) {
+		t.Fatalf("got %d templates, want %d: %+v", len(templates), len(want), templates)
+	}
+	for i, w := range want {
+		if templates[i].Group != w.group || templates[i].Name != w.name {
+			t.Errorf("[%d] got %s/%s, want %s/%s", i, templates[i].Group, templates[i].Name, w.group, w.name)
+		}
+	}
+}
+
+func TestListEntriesHidesTemplates(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"note.md":            "n",
+		"templates/daily.md": "d",
+	})
+
+	v := New(root)
+	entries, err := v.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		if e.Path == "templates" || e.Path == filepath.Join("templates", "daily.md") {
+			t.Errorf("templates should be hidden by default, got %q", e.Path)
+		}
+	}
+
+	v.ShowTemplates = true
+	entries, err = v.ListEntries()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Errorf("with ShowTemplates got %d entries, want 3", len(entries))
+	}
+}
+
+func TestExpandTemplate(t *testing.T) {
+	ctx := TemplateContext{
+		Title:     "Weekly Review",
+		Now:       time.Date(2024, 12, 30, 9, 5, 0, 0, time.UTC), // Monday, ISO week 2025-W01
+		Clipboard: "pasted",
+	}
+
+	tests := []struct {
+		in, want string
+	}{
+		{"# {{title}} ({{slug}})", "# Weekly Review (weekly-review)"},
+		{"{{date}} {{time}}", "2024-12-30 09:05:00"},
+		{"{{weekday}} {{week}}", "Monday 2025-W01"},
+		{"{{date+7d}} {{date-1w}}", "2025-01-06 2024-12-23"},
+		{"{{date+2m}} {{date-1y}}", "2025-03-02 2023-12-30"},
+		{"{{weekday+1d}} {{week-1w}}", "Tuesday 2024-W52"},
+		{"{{clipboard}}", "pasted"},
+		{"{{unknown}} {{title+1d}}", "{{unknown}} {{title+1d}}"},
+	}
+	for _, tt := range tests {
+		if got := ExpandTemplate(tt.in, ctx); got != tt.want {
+			t.Errorf("ExpandTemplate(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	uuid := ExpandTemplate("{{uuid}}", ctx)
+	if !regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(uuid) {
+		t.Errorf("{{uuid}} = %q, not a v4 UUID", uuid)
+	}
+
+	// {{uid}} is the note's one uid however often it appears.
+	if got := ExpandTemplate("{{uid}} {{uid}}", TemplateContext{UID: "abc"}); got != "abc abc" {
+		t.Errorf("{{uid}} with a UID = %q, want %q", got, "abc abc")
+	}
+	uid, again, _ := strings.Cut(ExpandTemplate("{{uid}} {{uid}}", ctx), " ")
+	if uid == "" || uid != again {
+		t.Errorf("{{uid}} expanded to %q and %q, want one made-up uid", uid, again)
+	}
+}
@@ -0,0 +1,147 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TrashDir is where deleted notes go, relative to the vault root. Each
+// delete gets a directory named after its time, holding the notes under
+// their vault paths.
+const TrashDir = ".kopr/trash"
+
+// trashStamp names a delete's directory in the trash.
+const trashStamp = "20060102-150405"
+
+// TrashItem is a deleted note in the trash.
+type TrashItem struct {
+	Path    string    // where the note was, relative to the vault root
+	Trashed string    // where it is now, relative to the vault root
+	Deleted time.Time // when it was deleted
+}
+
+// trash moves a note, or a directory of them, into the trash and returns
+// where it went, relative to the vault root.
+func (v *Vault) trash(relPath string, now time.Time) (string, error) {
+	base := filepath.Join(TrashDir, now.Format(trashStamp))
+	dir := base
+	// Two deletes of a path in the same second each get their own directory.
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(filepath.Join(v.Root, dir, relPath)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		dir = base + "-" + strconv.Itoa(n)
+	}
+	trashed := filepath.Join(dir, relPath)
+	abs := filepath.Join(v.Root, trashed)
+	if err := os.MkdirAll(filepath.Dir(abs), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(filepath.Join(v.Root, relPath), abs); err != nil {
+		return "", errors.Join(err, v.removeEmptyTrashDirs(abs))
+	}
+	return trashed, nil
+}
+
+// ListTrash returns the notes in the trash, most recently deleted first.
+func (v *Vault) ListTrash() ([]TrashItem, error) {
+	root := filepath.Join(v.Root, TrashDir)
+	dirs, err := os.ReadDir(root)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var items []TrashItem
+	for _, d := range dirs {
+		if !d.IsDir() || len(d.Name()) < len(trashStamp) {
+			continue
+		}
+		deleted, err := time.ParseInLocation(trashStamp, d.Name()[:len(trashStamp)], time.Local)
+		if err != nil {
+			continue
+		}
+		dir := filepath.Join(root, d.Name())
+		err = filepath.WalkDir(dir, func(path string, e fs.DirEntry, err error) error {
+			if err != nil || e.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(dir, path)
+			if err != nil {
+				return err
+			}
+			items = append(items, TrashItem{
+				Path:    rel,
+				Trashed: filepath.Join(TrashDir, d.Name(), rel),
+				Deleted: deleted,
+			})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].Deleted.Equal(items[j].Deleted) {
+			return items[i].Deleted.After(items[j].Deleted)
+		}
+		return items[i].Trashed < items[j].Trashed
+	})
+	return items, nil
+}
+
+// RestoreTrash moves a note out of the trash back to where it was. It
+// refuses to overwrite a note created there since.
+func (v *Vault) RestoreTrash(item TrashItem) error {
+	dest := filepath.Join(v.Root, item.Path)
+	if _, err := os.Lstat(dest); err == nil {
+		return fmt.Errorf("%s already exists", item.Path)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	src := filepath.Join(v.Root, item.Trashed)
+	if err := os.Rename(src, dest); err != nil {
+		return err
+	}
+	return v.removeEmptyTrashDirs(src)
+}
+
+// PurgeTrash deletes a note in the trash for good.
+func (v *Vault) PurgeTrash(item TrashItem) error {
+	abs := filepath.Join(v.Root, item.Trashed)
+	if err := os.Remove(abs); err != nil {
+		return err
+	}
+	return v.removeEmptyTrashDirs(abs)
+}
+
+// removeEmptyTrashDirs removes the directories left empty above a path
+// moved out of the trash, up to the trash itself.
+func (v *Vault) removeEmptyTrashDirs(path string) error {
+	root := filepath.Join(v.Root, TrashDir)
+	for dir := filepath.Dir(path); dir != root && len(dir) > len(root); dir = filepath.Dir(dir) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if len(entries) > 0 {
+			return nil
+		}
+		if err := os.Remove(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package vault
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestTrashRestoreAndPurge(t *testing.T) {
+	v := New(t.TempDir())
+	write := func(rel, content string) {
+		t.Helper()
+		path := filepath.Join(v.Root, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("projects/plan.md", "# Plan v1\n")
+
+	deleted, err := v.DeleteNote("projects/plan.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(v.Root, "projects/plan.md")); !os.IsNotExist(err) {
+		t.Fatalf("deleted note still in place: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(v.Root, deleted.Trashed)); err != nil {
+		t.Fatalf("DeleteNote() = %+v, not in the trash: %v", deleted, err)
+	}
+
+	// The same path deleted again in the same second keeps both versions.
+	now := time.Now()
+	write("projects/plan.md", "# Plan v2\n")
+	if _, err := v.trash("projects/plan.md", now); err != nil {
+		t.Fatal(err)
+	}
+	write("projects/plan.md", "# Plan v3\n")
+	if _, err := v.trash("projects/plan.md", now); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := v.ListTrash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("ListTrash() = %+v, want 3 items", items)
+	}
+	for _, item := range items {
+		if item.Path != filepath.FromSlash("projects/plan.md") {
+			t.Errorf("item path = %q, want projects/plan.md", item.Path)
+		}
+	}
+	if !slices.ContainsFunc(items, func(item TrashItem) bool {
+		return item.Trashed == deleted.Trashed && item.Deleted.Equal(deleted.Deleted)
+	}) {
+		t.Errorf("ListTrash() = %+v, want the item DeleteNote returned, %+v", items, deleted)
+	}
+
+	// A note recreated since isn't overwritten.
+	write("projects/plan.md", "# New plan\n")
+	if err := v.RestoreTrash(items[0]); err == nil {
+		t.Error("RestoreTrash() overwrote an existing note")
+	}
+	if err := os.Remove(filepath.Join(v.Root, "projects/plan.md")); err != nil {
+		t.Fatal(err)
+	}
+	want, err := os.ReadFile(filepath.Join(v.Root, items[0].Trashed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := v.RestoreTrash(items[0]); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(v.Root, "projects/plan.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(want) {
+		t.Errorf("restored content = %q, want %q", data, want)
+	}
+
+	for _, item := range items[1:] {
+		if err := v.PurgeTrash(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if items, err := v.ListTrash(); err != nil || len(items) != 0 {
+		t.Errorf("ListTrash() after purging = %+v, %v; want empty", items, err)
+	}
+	// Emptied delete directories go with their notes.
+	if entries, err := os.ReadDir(filepath.Join(v.Root, TrashDir)); err != nil || len(entries) != 0 {
+		t.Errorf("trash left %d entries, %v", len(entries), err)
+	}
+
+	// The trash stays out of the note list.
+	notes, err := v.ListNotes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 {
+		t.Errorf("ListNotes() = %v, want only the restored note", notes)
+	}
+}
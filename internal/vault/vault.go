@@ -15,19 +15,65 @@ type Entry struct {
 	Depth int
 }
 
+// DefaultTemplateDir is the vault-relative directory that holds templates.
+const DefaultTemplateDir = "templates"
+
 // Vault represents a knowledge vault directory.
 type Vault struct {
 	Root string
+
+	// TemplateDir is the templates directory, relative to Root or absolute.
+	TemplateDir string
+
+	// ShowTemplates lists the templates directory in ListEntries (and so in
+	// the tree and ListNotes). Off by default.
+	ShowTemplates bool
+
+	// Sources are read-only note directories outside the vault, listed by
+	// ExternalEntries rather than ListEntries.
+	Sources Sources
+
+	// Ignore holds the patterns of the vault's IgnoreFile, whose matches
+	// ListEntries leaves out. Nil ignores nothing.
+	Ignore *Ignore
+
+	// AssignUIDs gives each note CreateNote writes a uid: frontmatter key,
+	// unless it already has one. The uid stays with the note when it is
+	// renamed or moved, so other tools can refer to the note by it.
+	AssignUIDs bool
 }
 
 func New(root string) *Vault {
-	return &Vault{Root: root}
+	return &Vault{Root: root, TemplateDir: DefaultTemplateDir}
+}
+
+// TemplatesPath returns the absolute path of the templates directory.
+func (v *Vault) TemplatesPath() string {
+	dir := v.TemplateDir
+	if dir == "" {
+		dir = DefaultTemplateDir
+	}
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(v.Root, dir)
+}
+
+// TemplatesRel returns the templates directory relative to Root, or "" when
+// it lies outside the vault.
+func (v *Vault) TemplatesRel() string {
+	rel, err := filepath.Rel(v.Root, v.TemplatesPath())
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return ""
+	}
+	return rel
 }
 
 // ListEntries returns a flat list of all files/directories in the vault,
 // sorted with directories first, then alphabetically.
 func (v *Vault) ListEntries() ([]Entry, error) {
 	var entries []Entry
+	templates := v.TemplatesRel()
 
 	err := filepath.Walk(v.Root, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -50,6 +96,15 @@ func (v *Vault) ListEntries() ([]Entry, error) {
 			}
 			return nil
 		}
+		if info.IsDir() && !v.ShowTemplates && rel == templates {
+			return filepath.SkipDir
+		}
+		if v.Ignore.Ignored(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
 
 		depth := strings.Count(rel, string(filepath.Separator))
 